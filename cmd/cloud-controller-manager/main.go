@@ -2,14 +2,22 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 	cloudprovider "k8s.io/cloud-provider"
 	"k8s.io/cloud-provider/app"
 	cloudcontrollerconfig "k8s.io/cloud-provider/app/config"
@@ -17,12 +25,16 @@ import (
 	"k8s.io/cloud-provider/options"
 	cliflag "k8s.io/component-base/cli/flag"
 	"k8s.io/component-base/logs"
+	_ "k8s.io/component-base/logs/json/register"
 	_ "k8s.io/component-base/metrics/prometheus/clientgo"
 	_ "k8s.io/component-base/metrics/prometheus/version"
+	genericcontrollermanager "k8s.io/controller-manager/app"
+	"k8s.io/controller-manager/controller"
 	"k8s.io/klog/v2"
 
-	_ "github.com/stackitcloud/cloud-provider-stackit/pkg/ccm"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/ccm"
 	"github.com/stackitcloud/cloud-provider-stackit/pkg/metrics"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/tracing"
 )
 
 const (
@@ -30,7 +42,14 @@ const (
 )
 
 var (
-	metricsAddressFlag *string
+	nodeMetadataLabelsIntervalFlag *time.Duration
+	loadBalancerStatusIntervalFlag *time.Duration
+	loadBalancerOptInFlag          *bool
+	metricsAddressFlag             *string
+	pprofFlag                      *bool
+	pprofTokenFileFlag             *string
+	leaderElectKubeconfigFlag      *string
+	tracingOpts                    tracing.Options
 )
 
 func main() {
@@ -41,6 +60,32 @@ func main() {
 
 	fmt.Println("starting Controller")
 	controllerInitializers := app.DefaultInitFuncConstructors
+	controllerInitializers[ccm.ServerGroupLabelsControllerName] = app.ControllerInitFuncConstructor{
+		InitContext: app.ControllerInitContext{ClientName: "server-group-labels-controller"},
+		Constructor: startServerGroupLabelsControllerWrapper,
+	}
+	controllerInitializers[ccm.EndpointTargetsControllerName] = app.ControllerInitFuncConstructor{
+		InitContext: app.ControllerInitContext{ClientName: "endpoint-targets-controller"},
+		Constructor: startEndpointTargetsControllerWrapper,
+	}
+	controllerInitializers[ccm.NodeMetadataLabelsControllerName] = app.ControllerInitFuncConstructor{
+		InitContext: app.ControllerInitContext{ClientName: "node-metadata-labels-controller"},
+		Constructor: startNodeMetadataLabelsControllerWrapper,
+	}
+	controllerInitializers[ccm.IPReservationControllerName] = app.ControllerInitFuncConstructor{
+		InitContext: app.ControllerInitContext{ClientName: "ip-reservation-controller"},
+		Constructor: startIPReservationControllerWrapper,
+	}
+	controllerInitializers[ccm.DNSControllerName] = app.ControllerInitFuncConstructor{
+		InitContext: app.ControllerInitContext{ClientName: "dns-controller"},
+		Constructor: startDNSControllerWrapper,
+	}
+	controllerInitializers[ccm.LoadBalancerStatusControllerName] = app.ControllerInitFuncConstructor{
+		InitContext: app.ControllerInitContext{ClientName: "load-balancer-status-controller"},
+		Constructor: startLoadBalancerStatusControllerWrapper,
+	}
+	app.ControllersDisabledByDefault.Insert(ccm.NodeMetadataLabelsControllerName, ccm.IPReservationControllerName, ccm.DNSControllerName,
+		ccm.LoadBalancerStatusControllerName)
 	controllerAliases := names.CCMControllerAliases()
 
 	additionalFlags := cliflag.NamedFlagSets{}
@@ -51,8 +96,26 @@ func main() {
 
 	// setup metrics
 	metricsAddressFlag = additionalFlags.FlagSet("metrics").String("metrics-address", defaultMetricsAddress, "set the prometheus metrics endpoint")
+	pprofFlag = additionalFlags.FlagSet("metrics").Bool("metrics-pprof", false, "serve the pprof handlers under /debug/pprof/ on the metrics endpoint")
+	pprofTokenFileFlag = additionalFlags.FlagSet("metrics").String("metrics-pprof-token-file", "",
+		"file containing a bearer token that is required to access the pprof handlers")
+
+	tracingOpts.AddFlags(additionalFlags.FlagSet("tracing"))
+
+	nodeMetadataLabelsIntervalFlag = additionalFlags.FlagSet("node metadata labels").Duration("node-metadata-labels-interval",
+		ccm.DefaultNodeMetadataLabelsInterval, "the interval in which the node-metadata-labels controller refreshes the labels of all nodes")
+
+	loadBalancerStatusIntervalFlag = additionalFlags.FlagSet("load balancer").Duration("load-balancer-status-interval",
+		ccm.DefaultLoadBalancerStatusInterval, "the interval in which the load-balancer-status controller lists the load balancers")
+	loadBalancerOptInFlag = additionalFlags.FlagSet("load balancer").Bool("load-balancer-opt-in", false,
+		"only reconcile services with the annotation lb.stackit.cloud/enabled=true and ignore all other services")
+
+	leaderElectKubeconfigFlag = additionalFlags.FlagSet("leader election").String("leader-elect-kubeconfig", "",
+		"path to a kubeconfig of the cluster that holds the leader election lease, e.g. the management cluster of a hosted control plane. "+
+			"Defaults to the cluster of --kubeconfig")
 
 	command := app.NewCloudControllerManagerCommand(ccmOptions, cloudInitializer(ctx), controllerInitializers, controllerAliases, additionalFlags, wait.NeverStop)
+	command.AddCommand(newValidateConfigCommand())
 	pflag.CommandLine.SetNormalizeFunc(cliflag.WordSepNormalizeFunc)
 	logs.InitLogs()
 	defer logs.FlushLogs()
@@ -63,6 +126,36 @@ func main() {
 	}
 }
 
+func newValidateConfigCommand() *cobra.Command {
+	var path string
+	cmd := &cobra.Command{
+		Use:   "validate-config",
+		Short: "Validate the cloud-config against the STACKIT API and print a report",
+		Args:  cobra.NoArgs,
+		// A failed validation is not a usage error.
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			report := ccm.ValidateCloudConfig(cmd.Context(), path)
+			report.Print(cmd.OutOrStdout())
+			if report.Failed() {
+				return errors.New("cloud-config is invalid")
+			}
+			return nil
+		},
+	}
+
+	// The cloud-controller-manager command prints its own flags in the help, restore the defaults.
+	defaults := &cobra.Command{}
+	cmd.SetUsageFunc(defaults.UsageFunc())
+	cmd.SetHelpFunc(defaults.HelpFunc())
+
+	cmd.Flags().StringVar(&path, "cloud-config", "", "The path to the cloud provider configuration file to validate")
+	if err := cmd.MarkFlagRequired("cloud-config"); err != nil {
+		klog.Fatalf("Unable to mark flag cloud-config to be required: %v", err)
+	}
+	return cmd
+}
+
 func cloudInitializer(ctx context.Context) func(config *cloudcontrollerconfig.CompletedConfig) cloudprovider.Interface {
 	return func(config *cloudcontrollerconfig.CompletedConfig) cloudprovider.Interface {
 		// The metrics goroutine must be started in the initializer to make sure the cli flags were parsed and
@@ -71,14 +164,46 @@ func cloudInitializer(ctx context.Context) func(config *cloudcontrollerconfig.Co
 			klog.Fatal("The CLI flag metrics-address is not parsed yet!")
 		}
 		metricsAddress := *metricsAddressFlag
+		serverOpts, err := metrics.NewServerOptions(*pprofFlag, *pprofTokenFileFlag)
+		if err != nil {
+			klog.Fatalf("Invalid metrics server options: %v", err)
+		}
 		metricsExporter := metrics.NewExporter()
 		prometheus.MustRegister(metricsExporter)
 		go func() {
-			if err := metrics.Run(ctx, metricsAddress); err != nil {
+			if err := metrics.Run(ctx, metricsAddress, serverOpts); err != nil {
 				klog.Fatalf("Run metrics returned an error: %v", err)
 			}
 		}()
 
+		shutdownTracing, err := tracing.Setup(ctx, tracingOpts, "stackit-cloud-controller-manager")
+		if err != nil {
+			klog.Fatalf("Failed to set up tracing: %v", err)
+		}
+		go func() {
+			<-ctx.Done()
+			if err := shutdownTracing(context.Background()); err != nil {
+				klog.ErrorS(err, "Failed to flush traces")
+			}
+		}()
+
+		var leading <-chan struct{}
+		leaderElection := &config.ComponentConfig.Generic.LeaderElection
+		if *leaderElectKubeconfigFlag != "" && leaderElection.LeaderElect {
+			restConfig, err := clientcmd.BuildConfigFromFlags("", *leaderElectKubeconfigFlag)
+			if err != nil {
+				klog.Fatalf("Failed to load the leader election kubeconfig: %v", err)
+			}
+			client := kubernetes.NewForConfigOrDie(rest.AddUserAgent(restConfig, "leader-election"))
+			leading, err = ccm.StartLeaderElection(ctx, client, *leaderElection)
+			if err != nil {
+				klog.Fatalf("Failed to start the leader election: %v", err)
+			}
+			// The cloud-provider app serves /healthz right away and starts the controllers once this instance holds the
+			// lease, see SetLeading.
+			leaderElection.LeaderElect = false
+		}
+
 		cloudConfig := config.ComponentConfig.KubeCloudShared.CloudProvider
 		// initialize cloud provider with the cloud provider name and config file provided
 		cloud, err := cloudprovider.InitCloudProvider(cloudConfig.Name, cloudConfig.CloudConfigFile)
@@ -88,10 +213,16 @@ func cloudInitializer(ctx context.Context) func(config *cloudcontrollerconfig.Co
 		if cloud == nil {
 			klog.Fatalf("Cloud provider is nil")
 		}
+		if stackitCloud, ok := cloud.(*ccm.CloudControllerManager); ok {
+			stackitCloud.SetLoadBalancerOptIn(*loadBalancerOptInFlag)
+			if leading != nil {
+				stackitCloud.SetLeading(leading)
+			}
+		}
 
 		if !cloud.HasClusterID() {
 			if config.ComponentConfig.KubeCloudShared.AllowUntaggedCloud {
-				klog.Warning("Detected a cluster without a ClusterID. A ClusterID will be required in the future. Please tag your cluster to avoid any future issues")
+				klog.InfoS("Detected a cluster without a ClusterID. A ClusterID will be required in the future. Please tag your cluster to avoid any future issues")
 			} else {
 				klog.Fatalf(
 					"no ClusterID found. A ClusterID is required for the cloud provider to function properly. " +
@@ -102,3 +233,141 @@ func cloudInitializer(ctx context.Context) func(config *cloudcontrollerconfig.Co
 		return cloud
 	}
 }
+
+func startServerGroupLabelsControllerWrapper(
+	initContext app.ControllerInitContext,
+	completedConfig *cloudcontrollerconfig.CompletedConfig,
+	cloud cloudprovider.Interface,
+) app.InitFunc {
+	return func(ctx context.Context, _ genericcontrollermanager.ControllerContext) (controller.Interface, bool, error) {
+		c, err := ccm.NewServerGroupLabelsController(
+			completedConfig.SharedInformers.Core().V1().Nodes(),
+			completedConfig.ClientBuilder.ClientOrDie(initContext.ClientName),
+			cloud,
+		)
+		if err != nil {
+			klog.InfoS("Failed to start controller", "controller", ccm.ServerGroupLabelsControllerName, "err", err)
+			return nil, false, nil
+		}
+
+		go c.Run(ctx, int(completedConfig.ComponentConfig.NodeController.ConcurrentNodeSyncs))
+
+		return nil, true, nil
+	}
+}
+
+func startNodeMetadataLabelsControllerWrapper(
+	initContext app.ControllerInitContext,
+	completedConfig *cloudcontrollerconfig.CompletedConfig,
+	cloud cloudprovider.Interface,
+) app.InitFunc {
+	return func(ctx context.Context, _ genericcontrollermanager.ControllerContext) (controller.Interface, bool, error) {
+		c, err := ccm.NewNodeMetadataLabelsController(
+			completedConfig.SharedInformers.Core().V1().Nodes(),
+			completedConfig.ClientBuilder.ClientOrDie(initContext.ClientName),
+			cloud,
+			*nodeMetadataLabelsIntervalFlag,
+		)
+		if err != nil {
+			klog.InfoS("Failed to start controller", "controller", ccm.NodeMetadataLabelsControllerName, "err", err)
+			return nil, false, nil
+		}
+
+		go c.Run(ctx, int(completedConfig.ComponentConfig.NodeController.ConcurrentNodeSyncs))
+
+		return nil, true, nil
+	}
+}
+
+func startEndpointTargetsControllerWrapper(
+	_ app.ControllerInitContext,
+	completedConfig *cloudcontrollerconfig.CompletedConfig,
+	cloud cloudprovider.Interface,
+) app.InitFunc {
+	return func(ctx context.Context, _ genericcontrollermanager.ControllerContext) (controller.Interface, bool, error) {
+		c, err := ccm.NewEndpointTargetsController(
+			completedConfig.SharedInformers.Core().V1().Services(),
+			completedConfig.SharedInformers.Discovery().V1().EndpointSlices(),
+			completedConfig.SharedInformers.Core().V1().Nodes(),
+			cloud,
+		)
+		if err != nil {
+			klog.InfoS("Failed to start controller", "controller", ccm.EndpointTargetsControllerName, "err", err)
+			return nil, false, nil
+		}
+
+		go c.Run(ctx, int(completedConfig.ComponentConfig.ServiceController.ConcurrentServiceSyncs))
+
+		return nil, true, nil
+	}
+}
+
+func startIPReservationControllerWrapper(
+	initContext app.ControllerInitContext,
+	completedConfig *cloudcontrollerconfig.CompletedConfig,
+	cloud cloudprovider.Interface,
+) app.InitFunc {
+	return func(ctx context.Context, _ genericcontrollermanager.ControllerContext) (controller.Interface, bool, error) {
+		dynamicClient := dynamic.NewForConfigOrDie(completedConfig.ClientBuilder.ConfigOrDie(initContext.ClientName))
+		reservationInformers := dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, 0)
+		c, err := ccm.NewIPReservationController(
+			reservationInformers.ForResource(ccm.IPReservationResource),
+			completedConfig.SharedInformers.Core().V1().Services(),
+			dynamicClient,
+			cloud,
+		)
+		if err != nil {
+			klog.InfoS("Failed to start controller", "controller", ccm.IPReservationControllerName, "err", err)
+			return nil, false, nil
+		}
+		reservationInformers.Start(ctx.Done())
+
+		go c.Run(ctx, int(completedConfig.ComponentConfig.ServiceController.ConcurrentServiceSyncs))
+
+		return nil, true, nil
+	}
+}
+
+func startDNSControllerWrapper(
+	initContext app.ControllerInitContext,
+	completedConfig *cloudcontrollerconfig.CompletedConfig,
+	cloud cloudprovider.Interface,
+) app.InitFunc {
+	return func(ctx context.Context, _ genericcontrollermanager.ControllerContext) (controller.Interface, bool, error) {
+		c, err := ccm.NewDNSController(
+			completedConfig.SharedInformers.Core().V1().Services(),
+			completedConfig.ClientBuilder.ClientOrDie(initContext.ClientName),
+			cloud,
+		)
+		if err != nil {
+			klog.InfoS("Failed to start controller", "controller", ccm.DNSControllerName, "err", err)
+			return nil, false, nil
+		}
+
+		go c.Run(ctx, int(completedConfig.ComponentConfig.ServiceController.ConcurrentServiceSyncs))
+
+		return nil, true, nil
+	}
+}
+
+func startLoadBalancerStatusControllerWrapper(
+	_ app.ControllerInitContext,
+	completedConfig *cloudcontrollerconfig.CompletedConfig,
+	cloud cloudprovider.Interface,
+) app.InitFunc {
+	return func(ctx context.Context, _ genericcontrollermanager.ControllerContext) (controller.Interface, bool, error) {
+		c, err := ccm.NewLoadBalancerStatusController(
+			completedConfig.SharedInformers.Core().V1().Services(),
+			cloud,
+			*loadBalancerStatusIntervalFlag,
+		)
+		if err != nil {
+			klog.InfoS("Failed to start controller", "controller", ccm.LoadBalancerStatusControllerName, "err", err)
+			return nil, false, nil
+		}
+
+		go c.Run(ctx)
+
+		return nil, true, nil
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/cobra"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/ccm"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/metrics"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/orphangc"
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/version"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/component-base/cli"
+	"k8s.io/component-base/logs"
+	logsapi "k8s.io/component-base/logs/api/v1"
+	_ "k8s.io/component-base/logs/json/register"
+	"k8s.io/klog/v2"
+)
+
+var (
+	cloudConfig    string
+	kubeconfig     string
+	clusterID      string
+	dryRun         bool
+	minAge         time.Duration
+	maxDeletions   int
+	interval       time.Duration
+	metricsAddress string
+)
+
+func main() {
+	logOptions := logs.NewOptions()
+
+	cmd := &cobra.Command{
+		Use:   "orphan-gc",
+		Short: "Delete load balancers and volumes of a cluster whose Service or PersistentVolume no longer exists",
+		Args:  cobra.NoArgs,
+		PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
+			return logsapi.ValidateAndApply(logOptions, nil)
+		},
+		RunE: func(_ *cobra.Command, _ []string) error {
+			ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+			defer cancel()
+
+			return run(ctx)
+		},
+		Version: version.Version,
+	}
+
+	logsapi.AddFlags(logOptions, cmd.PersistentFlags())
+
+	cmd.Flags().StringVar(&cloudConfig, "cloud-config", "", "Path to the cloud config of the cloud-controller-manager.")
+	if err := cmd.MarkFlagRequired("cloud-config"); err != nil {
+		klog.Fatalf("Unable to mark flag cloud-config to be required: %v", err)
+	}
+	cmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Path to a kubeconfig. Only required if out-of-cluster.")
+	cmd.Flags().StringVar(&clusterID, "cluster-id", "",
+		"The cluster ID of the resource labels. Defaults to global.clusterId of the cloud config.")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", true, "Only log and count the orphans without deleting them.")
+	cmd.Flags().DurationVar(&minAge, "min-age", orphangc.DefaultMinAge, "Minimum age of volumes before they are considered orphans.")
+	cmd.Flags().IntVar(&maxDeletions, "max-deletions", orphangc.DefaultMaxDeletions,
+		"Abort a run without deleting anything if more orphans are found. 0 disables the limit.")
+	cmd.Flags().DurationVar(&interval, "interval", 0, "Interval between runs. 0 runs once and exits.")
+	cmd.Flags().StringVar(&metricsAddress, "metrics-address", "",
+		"The TCP network address where the HTTP server for providing metrics will listen (example: `:8080`). "+
+			"The default is empty string, which means the server is disabled.")
+
+	code := cli.Run(cmd)
+	os.Exit(code)
+}
+
+func run(ctx context.Context) error {
+	f, err := os.Open(cloudConfig)
+	if err != nil {
+		return fmt.Errorf("failed to open cloud config: %w", err)
+	}
+	cfg, err := ccm.GetConfig(f)
+	_ = f.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read cloud config: %w", err)
+	}
+	if clusterID == "" {
+		clusterID = cfg.Global.ClusterID
+	}
+
+	lbClient, iaasClient, err := ccm.NewClients(&cfg)
+	if err != nil {
+		return err
+	}
+	auditor := stackitclient.NewAuditor(cfg.Global.Audit, "orphan-gc", cfg.Global.Region, cfg.Global.ProjectID)
+	defer func() {
+		// ctx is already cancelled if the command was stopped by a signal.
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), stackitclient.AuditShutdownTimeout)
+		defer cancel()
+		if err := auditor.Shutdown(shutdownCtx); err != nil {
+			klog.ErrorS(err, "Failed to flush audit events")
+		}
+	}()
+
+	restConfig, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return fmt.Errorf("failed to create kubeconfig: %w", err)
+	}
+	kubeClient, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+
+	collector, err := orphangc.NewCollector(
+		stackitclient.NewAuditedLoadBalancingClient(lbClient, auditor),
+		stackitclient.NewAuditedIaaSClient(iaasClient, auditor),
+		kubeClient,
+		orphangc.Options{
+			ClusterID:    clusterID,
+			DryRun:       dryRun,
+			MinAge:       minAge,
+			MaxDeletions: maxDeletions,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	if metricsAddress != "" {
+		prometheus.MustRegister(metrics.NewExporter())
+		serverOpts, err := metrics.NewServerOptions(false, "")
+		if err != nil {
+			return err
+		}
+		go func() {
+			if err := metrics.Run(ctx, metricsAddress, serverOpts); err != nil {
+				klog.Fatalf("Run metrics returned an error: %v", err)
+			}
+		}()
+	}
+
+	if interval == 0 {
+		_, err := collector.Run(ctx)
+		return err
+	}
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if _, err := collector.Run(ctx); err != nil {
+			klog.ErrorS(err, "Failed to collect orphaned resources")
+		}
+	}, interval)
+	return nil
+}
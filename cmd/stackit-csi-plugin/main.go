@@ -2,9 +2,12 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
 	"syscall"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -13,12 +16,20 @@ import (
 	"github.com/stackitcloud/cloud-provider-stackit/pkg/csi"
 	"github.com/stackitcloud/cloud-provider-stackit/pkg/csi/blockstorage"
 	"github.com/stackitcloud/cloud-provider-stackit/pkg/csi/util/mount"
+	_ "github.com/stackitcloud/cloud-provider-stackit/pkg/features"
 	"github.com/stackitcloud/cloud-provider-stackit/pkg/metrics"
 	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
 	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/metadata"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/validation"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/tracing"
 	"github.com/stackitcloud/cloud-provider-stackit/pkg/version"
-	sdkconfig "github.com/stackitcloud/stackit-sdk-go/core/config"
+	"go.opentelemetry.io/otel/attribute"
+	k8svalidation "k8s.io/apimachinery/pkg/util/validation"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
 	"k8s.io/component-base/cli"
+	"k8s.io/component-base/logs"
+	logsapi "k8s.io/component-base/logs/api/v1"
+	_ "k8s.io/component-base/logs/json/register"
 	"k8s.io/klog/v2"
 )
 
@@ -27,13 +38,25 @@ var (
 	cloudConfig              string
 	cluster                  string
 	metricsAddress           string
+	metricsPprof             bool
+	metricsPprofTokenFile    string
+	debugAddress             string
 	provideControllerService bool
 	provideNodeService       bool
+	checkNodePrivileges      bool
 	legacyStorageMode        bool
 	legacyVolumeCreation     bool
+	nodeID                   string
+	nodeZone                 string
+	nodeFlavor               string
+	fsGroupPolicy            string
+	topologyKey              string
+	tracingOpts              tracing.Options
 )
 
 func main() {
+	logOptions := logs.NewOptions()
+
 	cmd := &cobra.Command{
 		Use:   "stackit-csi-plugin",
 		Short: "STACKIT block-storage CSI plugin",
@@ -44,6 +67,20 @@ func main() {
 			handle(ctx)
 		},
 		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			// Activate logging as soon as possible, e.g. to switch to --logging-format=json.
+			if err := logsapi.ValidateAndApply(logOptions, nil); err != nil {
+				return err
+			}
+
+			if !slices.Contains(blockstorage.FSGroupPolicies, blockstorage.FSGroupPolicy(fsGroupPolicy)) {
+				return fmt.Errorf("invalid --fsgroup-policy %q, must be one of %v", fsGroupPolicy, blockstorage.FSGroupPolicies)
+			}
+			if topologyKey != "" {
+				if errs := k8svalidation.IsQualifiedName(topologyKey); len(errs) > 0 {
+					return fmt.Errorf("invalid --topology-key %q: %s", topologyKey, strings.Join(errs, ", "))
+				}
+			}
+
 			f := cmd.Flags()
 
 			if !provideControllerService {
@@ -65,9 +102,10 @@ func main() {
 	}
 
 	csi.AddPVCFlags(cmd)
+	logsapi.AddFlags(logOptions, cmd.PersistentFlags())
 
-	cmd.PersistentFlags().StringVar(&endpoint, "endpoint", "", "CSI endpoint")
-	if err := cmd.MarkPersistentFlagRequired("endpoint"); err != nil {
+	cmd.Flags().StringVar(&endpoint, "endpoint", "", "CSI endpoint")
+	if err := cmd.MarkFlagRequired("endpoint"); err != nil {
 		klog.Fatalf("Unable to mark flag endpoint to be required: %v", err)
 	}
 
@@ -78,35 +116,128 @@ func main() {
 		"The TCP network address where the HTTP server for providing metrics for diagnostics, will listen (example: `:8080`)."+
 			"The default is empty string, which means the server is disabled.")
 
+	cmd.PersistentFlags().BoolVar(&metricsPprof, "metrics-pprof", false,
+		"If set to true then the pprof handlers are served under /debug/pprof/ on the metrics address (default: false)")
+	cmd.PersistentFlags().StringVar(&metricsPprofTokenFile, "metrics-pprof-token-file", "",
+		"File containing a bearer token that is required to access the pprof handlers. If empty, the handlers are not protected.")
+
+	cmd.PersistentFlags().StringVar(&debugAddress, "debug-address", "",
+		"The TCP network address where the HTTP server exposing the staged volume inventory of the node service will listen (example: `:8081`). "+
+			"The default is empty string, which means the server is disabled.")
+
 	cmd.PersistentFlags().BoolVar(&provideControllerService, "provide-controller-service", true,
 		"If set to true then the CSI driver does provide the controller service (default: true)")
 	cmd.PersistentFlags().BoolVar(&provideNodeService, "provide-node-service", true,
 		"If set to true then the CSI driver does provide the node service (default: true)")
+	cmd.PersistentFlags().BoolVar(&checkNodePrivileges, "check-node-privileges", true,
+		"If set to true then the node service fails to start without CAP_SYS_ADMIN, which is required to mount volumes. "+
+			"Disable it to run the node service unprivileged, e.g. in tests with a fake mounter (default: true)")
 	cmd.PersistentFlags().BoolVar(&legacyStorageMode, "legacy-storage-mode", false,
 		"Configures the CSI to listen to the legacy storage driverName cinder.csi.openstack.org instead")
 	cmd.PersistentFlags().BoolVar(&legacyVolumeCreation, "legacy-volume-creation", true, "Enable or disable support for creating volumes with the old driverName (cinder.csi.openstack.org)")
 
+	cmd.PersistentFlags().StringVar(&nodeID, "node-id", os.Getenv("CSI_NODE_ID"),
+		"The STACKIT server ID of the node, used if neither the metadata service nor the config drive are available. Defaults to $CSI_NODE_ID.")
+	cmd.PersistentFlags().StringVar(&nodeZone, "node-zone", os.Getenv("CSI_NODE_ZONE"),
+		"The availability zone of the node, used if neither the metadata service nor the config drive are available. Defaults to $CSI_NODE_ZONE.")
+	cmd.PersistentFlags().StringVar(&nodeFlavor, "node-flavor", os.Getenv("CSI_NODE_FLAVOR"),
+		"The flavor of the node, used if the metadata service is not available. Defaults to $CSI_NODE_FLAVOR.")
+	cmd.PersistentFlags().StringVar(&fsGroupPolicy, "fsgroup-policy", string(blockstorage.FSGroupPolicyKubelet),
+		"Who applies the fsGroup of pods to volumes: Kubelet (according to the CSIDriver), File (the node plugin, only if the volume root doesn't match) or None (ignored).")
+	cmd.PersistentFlags().StringVar(&topologyKey, "topology-key", "",
+		"Topology key of the zone of provisioned volumes, e.g. topology.kubernetes.io/zone. Nodes publish it in addition to the topology key of the driver name. Defaults to the topology key of the driver name.")
+
+	tracingOpts.AddFlags(cmd.PersistentFlags())
+
+	utilfeature.DefaultMutableFeatureGate.AddFlag(cmd.PersistentFlags())
+
+	cmd.AddCommand(newValidateConfigCommand(logOptions))
+
 	stackitclient.AddExtraFlags(pflag.CommandLine)
 
 	code := cli.Run(cmd)
 	os.Exit(code)
 }
 
+func newValidateConfigCommand(logOptions *logs.Options) *cobra.Command {
+	var path string
+	cmd := &cobra.Command{
+		Use:   "validate-config",
+		Short: "Validate the cloud-config against the STACKIT API and print a report",
+		Args:  cobra.NoArgs,
+		// A failed validation is not a usage error.
+		SilenceUsage: true,
+		PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
+			return logsapi.ValidateAndApply(logOptions, nil)
+		},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+			defer cancel()
+
+			report := validation.CSI(ctx, path)
+			report.Print(cmd.OutOrStdout())
+			if report.Failed() {
+				return errors.New("cloud-config is invalid")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&path, "cloud-config", "", "CSI driver cloud config to validate")
+	if err := cmd.MarkFlagRequired("cloud-config"); err != nil {
+		klog.Fatalf("Unable to mark flag cloud-config to be required: %v", err)
+	}
+	return cmd
+}
+
 func handle(ctx context.Context) {
 	if metricsAddress != "" {
+		serverOpts, err := metrics.NewServerOptions(metricsPprof, metricsPprofTokenFile)
+		if err != nil {
+			klog.Fatalf("Invalid metrics server options: %v", err)
+		}
 		metricsExporter := metrics.NewExporter()
 		prometheus.MustRegister(metricsExporter)
 		go func() {
-			if err := metrics.Run(ctx, metricsAddress); err != nil {
+			if err := metrics.Run(ctx, metricsAddress, serverOpts); err != nil {
 				klog.Fatalf("Run metrics returned an error: %v", err)
 			}
 		}()
 	}
+	cfg, err := stackitclient.GetConfigFromFile(cloudConfig)
+	if err != nil {
+		klog.Fatal(err)
+	}
+
+	shutdownTracing, err := tracing.Setup(ctx, tracingOpts, "stackit-csi-plugin",
+		attribute.String(tracing.AttributeProjectID, cfg.Global.ProjectID))
+	if err != nil {
+		klog.Fatalf("Failed to set up tracing: %v", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			klog.ErrorS(err, "Failed to flush traces")
+		}
+	}()
+
 	// Initialize cloud
 	driverOpts := &blockstorage.DriverOpts{
-		Endpoint:  endpoint,
-		ClusterID: cluster,
-		PVCLister: csi.GetPVCLister(),
+		Endpoint:       endpoint,
+		ClusterID:      cluster,
+		PVCLister:      csi.GetPVCLister(),
+		FSGroupPolicy:  blockstorage.FSGroupPolicy(fsGroupPolicy),
+		TopologyKey:    topologyKey,
+		EventRecorder:  csi.GetEventRecorder("stackit-csi-plugin"),
+		ResourceLabels: stackitclient.ResourceLabels(cfg.Global, "stackit-csi-plugin"),
+		WaitTimeout:    cfg.Global.APITimeouts.Wait.Duration,
+	}
+	if provideControllerService {
+		driverOpts.BackupInformers = csi.GetBackupScheduleInformers()
+		driverOpts.PVLister = csi.GetPVLister()
+		driverOpts.VolumeSnapshotAnnotations = csi.GetVolumeSnapshotAnnotationsFunc()
+		driverOpts.NodeFailover = csi.GetNodeFailover()
+		driverOpts.AttachmentCapacity = csi.GetAttachmentCapacity()
+		driverOpts.LeaderElection = csi.GetLeaderElectionFunc()
 	}
 
 	if legacyStorageMode {
@@ -120,42 +251,57 @@ func handle(ctx context.Context) {
 	d := blockstorage.NewDriver(driverOpts)
 
 	if provideControllerService {
-		var err error
-		cfg, err := stackitclient.GetConfigFromFile(cloudConfig)
-		if err != nil {
-			klog.Fatal(err)
-		}
-
-		iaasHTTPClient := metrics.NewInstrumentedHTTPClient(metrics.APINameIaaS)
-		iaasOpts := []sdkconfig.ConfigurationOption{
-			sdkconfig.WithHTTPClient(iaasHTTPClient),
+		if err := validation.BlockStorageOpts(cfg.BlockStorage); err != nil {
+			klog.Fatalf("Invalid cloud-config: %v", err)
 		}
 
-		if cfg.Global.APIEndpoints.IaasAPI != "" {
-			iaasOpts = append(iaasOpts, sdkconfig.WithEndpoint(cfg.Global.APIEndpoints.IaasAPI))
+		iaasOpts, err := stackitclient.ConfigurationOptions(metrics.APINameIaaS, cfg.Global.APIEndpoints.IaasAPI, cfg.Global.APIEndpoints)
+		if err != nil {
+			klog.Fatalf("Failed to configure IaaS client: %v", err)
 		}
 
-		iaasClient, err := stackitclient.New(cfg.Global.Region, cfg.Global.ProjectID).IaaS(iaasOpts)
+		iaasClient, err := stackitclient.New(cfg.Global.Region, cfg.Global.ProjectID, cfg.Global.APITimeouts).IaaS(iaasOpts)
 		if err != nil {
 			klog.Fatalf("Failed to create STACKIT provider: %v", err)
 		}
 
-		d.SetupControllerService(iaasClient)
+		auditor := stackitclient.NewAuditor(cfg.Global.Audit, "stackit-csi-plugin", cfg.Global.Region, cfg.Global.ProjectID)
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), stackitclient.AuditShutdownTimeout)
+			defer cancel()
+			if err := auditor.Shutdown(shutdownCtx); err != nil {
+				klog.ErrorS(err, "Failed to flush audit events")
+			}
+		}()
+		d.SetupControllerService(stackitclient.NewAuditedIaaSClient(iaasClient, auditor), cfg.BlockStorage)
 	}
 
 	if provideNodeService {
+		if checkNodePrivileges {
+			if err := mount.CheckPrivileges(); err != nil {
+				klog.Fatalf("Insufficient privileges for the node service: %v", err)
+			}
+		}
+
 		// Initialize mount
 		mountProvider := mount.GetMountProvider()
 
-		cfg, err := stackitclient.GetConfigFromFile(cloudConfig)
-		if err != nil {
-			klog.Fatal(err)
-		}
-
 		// Initialize Metadata
-		metadataProvider := metadata.GetMetadataProvider(fmt.Sprintf("%s,%s", metadata.MetadataID, metadata.ConfigDriveID))
+		metadataProvider := metadata.WithFallback(
+			metadata.GetMetadataProvider(fmt.Sprintf("%s,%s", metadata.MetadataID, metadata.ConfigDriveID)),
+			metadata.Static{InstanceID: nodeID, AvailabilityZone: nodeZone, Flavor: nodeFlavor},
+		)
 
 		d.SetupNodeService(mountProvider, metadataProvider, cfg.BlockStorage)
+
+		if debugAddress != "" {
+			go func() {
+				if err := d.RunDebugServer(ctx, debugAddress); err != nil {
+					klog.Fatalf("Run debug server returned an error: %v", err)
+				}
+			}()
+		}
 	}
 
 	d.Run()
@@ -0,0 +1,366 @@
+package ccm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	cloudprovider "k8s.io/cloud-provider"
+	"k8s.io/klog/v2"
+
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+)
+
+const (
+	// DNSControllerName is the name of the controller that registers the IPs of load balancers in STACKIT DNS.
+	DNSControllerName = "dns"
+
+	// dnsNameAnnotation contains the hostnames that are registered for the IPs of the load balancer, separated by commas.
+	dnsNameAnnotation = "lb.stackit.cloud/dns-name"
+	// dnsRegisteredNamesAnnotation is set by the CCM and contains the hostnames it registered for the service, so the
+	// records can be removed after the hostnames in dnsNameAnnotation changed.
+	dnsRegisteredNamesAnnotation = "lb.stackit.cloud/dns-registered-names"
+	// dnsFinalizer removes the records of a service before it is deleted.
+	dnsFinalizer = "lb.stackit.cloud/dns"
+
+	dnsRecordTypeA = "A"
+	defaultDNSTTL  = 60
+
+	// EventReasonDNSRecordConflict is a reason for sending an event when a record exists that wasn't created for the service
+	EventReasonDNSRecordConflict = "DNSRecordConflict"
+	// EventReasonInvalidDNSName is a reason for sending an event when the annotation dnsNameAnnotation is invalid
+	EventReasonInvalidDNSName = "InvalidDNSName"
+)
+
+// DNSController creates A records in a STACKIT DNS zone for the hostnames in dnsNameAnnotation of services of type
+// LoadBalancer and removes them when the service is deleted or the hostnames change.
+// Records are marked with a comment that contains the service, existing records of other owners are never changed.
+type DNSController struct {
+	kubeClient     kubernetes.Interface
+	serviceLister  corelisters.ServiceLister
+	servicesSynced cache.InformerSynced
+	dnsClient      stackitclient.DNSClient
+	zoneID         string
+	ttl            int64
+	loadBalancer   *LoadBalancer
+	queue          workqueue.TypedRateLimitingInterface[string]
+}
+
+// NewDNSController creates the controller from the STACKIT cloud provider, which must be configured with a DNS zone.
+func NewDNSController(
+	serviceInformer coreinformers.ServiceInformer,
+	kubeClient kubernetes.Interface,
+	cloud cloudprovider.Interface,
+) (*DNSController, error) {
+	stackitCloud, ok := cloud.(*CloudControllerManager)
+	if !ok {
+		return nil, fmt.Errorf("cloud provider %T is not supported by the %s controller", cloud, DNSControllerName)
+	}
+	if stackitCloud.dnsClient == nil {
+		return nil, fmt.Errorf("the %s controller requires dns.zoneId in the cloud-config", DNSControllerName)
+	}
+	ttl := stackitCloud.dnsOpts.TTL
+	if ttl <= 0 {
+		ttl = defaultDNSTTL
+	}
+
+	c := &DNSController{
+		kubeClient:     kubeClient,
+		serviceLister:  serviceInformer.Lister(),
+		servicesSynced: serviceInformer.Informer().HasSynced,
+		dnsClient:      stackitCloud.dnsClient,
+		zoneID:         stackitCloud.dnsOpts.ZoneID,
+		ttl:            ttl,
+		loadBalancer:   stackitCloud.loadBalancer,
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.DefaultTypedControllerRateLimiter[string](),
+			workqueue.TypedRateLimitingQueueConfig[string]{Name: DNSControllerName},
+		),
+	}
+
+	_, err := serviceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueue,
+		UpdateFunc: func(_, newObj any) { c.enqueue(newObj) },
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add service event handler: %w", err)
+	}
+
+	return c, nil
+}
+
+func (c *DNSController) enqueue(obj any) {
+	service, ok := obj.(*corev1.Service)
+	if !ok {
+		utilruntime.HandleError(fmt.Errorf("unexpected object of type %T", obj))
+		return
+	}
+	// Services without hostnames and records are skipped to avoid listing the records of all services.
+	if service.Annotations[dnsNameAnnotation] == "" && service.Annotations[dnsRegisteredNamesAnnotation] == "" &&
+		!slices.Contains(service.Finalizers, dnsFinalizer) {
+		return
+	}
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.queue.Add(key)
+}
+
+// Run starts the workers and blocks until ctx is cancelled.
+func (c *DNSController) Run(ctx context.Context, workers int) {
+	defer utilruntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	klog.InfoS("Starting controller", "controller", DNSControllerName)
+	defer klog.InfoS("Shutting down controller", "controller", DNSControllerName)
+
+	if !cache.WaitForCacheSync(ctx.Done(), c.servicesSynced) {
+		return
+	}
+
+	for range workers {
+		go wait.UntilWithContext(ctx, c.runWorker, time.Second)
+	}
+
+	<-ctx.Done()
+}
+
+func (c *DNSController) runWorker(ctx context.Context) {
+	for c.processNextItem(ctx) {
+	}
+}
+
+func (c *DNSController) processNextItem(ctx context.Context) bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	if err := c.syncService(ctx, key); err != nil {
+		utilruntime.HandleError(fmt.Errorf("failed to sync DNS records of service %q: %w", key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *DNSController) syncService(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	service, err := c.serviceLister.Services(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	desired, err := desiredDNSNames(service)
+	if err != nil {
+		c.loadBalancer.recorder.Event(service, corev1.EventTypeWarning, EventReasonInvalidDNSName, err.Error())
+		// Records of hostnames that were registered before are kept until the annotation is fixed.
+		return nil
+	}
+	ips := loadBalancerIPs(service)
+
+	var errs []error
+	registered := splitDNSNames(service.Annotations[dnsRegisteredNamesAnnotation])
+	var stillRegistered []string
+	for _, hostname := range registered {
+		if slices.Contains(desired, hostname) && len(ips) > 0 {
+			continue
+		}
+		if err := c.deleteRecords(ctx, service, hostname); err != nil {
+			errs = append(errs, err)
+			stillRegistered = append(stillRegistered, hostname)
+		}
+	}
+
+	if len(ips) > 0 {
+		if len(desired) > 0 && !slices.Contains(service.Finalizers, dnsFinalizer) {
+			// The finalizer is added before the first record is created, so no record outlives the service.
+			if service, err = c.updateService(ctx, service, func(s *corev1.Service) {
+				s.Finalizers = append(s.Finalizers, dnsFinalizer)
+			}); err != nil {
+				return err
+			}
+		}
+		for _, hostname := range desired {
+			if err := c.ensureRecords(ctx, service, hostname, ips); err != nil {
+				errs = append(errs, err)
+				if !slices.Contains(registered, hostname) {
+					continue
+				}
+			}
+			stillRegistered = append(stillRegistered, hostname)
+		}
+	}
+
+	slices.Sort(stillRegistered)
+	stillRegistered = slices.Compact(stillRegistered)
+	_, err = c.updateService(ctx, service, func(s *corev1.Service) {
+		if len(stillRegistered) == 0 {
+			delete(s.Annotations, dnsRegisteredNamesAnnotation)
+			s.Finalizers = slices.DeleteFunc(s.Finalizers, func(f string) bool { return f == dnsFinalizer })
+			return
+		}
+		if s.Annotations == nil {
+			s.Annotations = map[string]string{}
+		}
+		s.Annotations[dnsRegisteredNamesAnnotation] = strings.Join(stillRegistered, ",")
+	})
+	return errors.Join(append(errs, err)...)
+}
+
+// ensureRecords creates or updates the A records of hostname, unless they belong to another owner.
+func (c *DNSController) ensureRecords(ctx context.Context, service *corev1.Service, hostname string, ips []string) error {
+	desired := stackitclient.DNSRecordSet{
+		Name:    hostname,
+		Type:    dnsRecordTypeA,
+		TTL:     c.ttl,
+		Comment: c.ownerComment(service),
+	}
+	for _, ip := range ips {
+		desired.Records = append(desired.Records, stackitclient.DNSRecord{Content: ip})
+	}
+
+	recordSets, err := c.dnsClient.ListRecordSets(ctx, c.zoneID, hostname, dnsRecordTypeA)
+	if err != nil {
+		return fmt.Errorf("failed to list records of %s: %w", hostname, err)
+	}
+	if len(recordSets) == 0 {
+		if _, err := c.dnsClient.CreateRecordSet(ctx, c.zoneID, desired); err != nil {
+			return fmt.Errorf("failed to create records of %s: %w", hostname, err)
+		}
+		klog.InfoS("Created DNS records", "service", klog.KObj(service), "hostname", hostname, "ips", ips)
+		return nil
+	}
+
+	current := recordSets[0]
+	if current.Comment != desired.Comment {
+		msg := fmt.Sprintf("The A records of %s exist already and were not created for this service, they are not changed", hostname)
+		c.loadBalancer.recorder.Event(service, corev1.EventTypeWarning, EventReasonDNSRecordConflict, msg)
+		return fmt.Errorf("records of %s belong to another owner", hostname)
+	}
+	if current.TTL == desired.TTL && slices.Equal(recordContents(current.Records), ips) {
+		return nil
+	}
+	if err := c.dnsClient.UpdateRecordSet(ctx, c.zoneID, current.ID, desired); err != nil {
+		return fmt.Errorf("failed to update records of %s: %w", hostname, err)
+	}
+	klog.InfoS("Updated DNS records", "service", klog.KObj(service), "hostname", hostname, "ips", ips)
+	return nil
+}
+
+// deleteRecords deletes the A records of hostname if they were created for the service.
+func (c *DNSController) deleteRecords(ctx context.Context, service *corev1.Service, hostname string) error {
+	recordSets, err := c.dnsClient.ListRecordSets(ctx, c.zoneID, hostname, dnsRecordTypeA)
+	if err != nil {
+		return fmt.Errorf("failed to list records of %s: %w", hostname, err)
+	}
+	for _, recordSet := range recordSets {
+		if recordSet.Comment != c.ownerComment(service) {
+			continue
+		}
+		if err := c.dnsClient.DeleteRecordSet(ctx, c.zoneID, recordSet.ID); err != nil {
+			return fmt.Errorf("failed to delete records of %s: %w", hostname, err)
+		}
+		klog.InfoS("Deleted DNS records", "service", klog.KObj(service), "hostname", hostname)
+	}
+	return nil
+}
+
+// ownerComment marks the records of the service.
+func (c *DNSController) ownerComment(service *corev1.Service) string {
+	return fmt.Sprintf("managed by stackit-cloud-controller-manager for service %s/%s in cluster %s",
+		service.Namespace, service.Name, c.loadBalancer.clusterID)
+}
+
+// updateService applies mutate to a copy of the service and updates it if it changed.
+func (c *DNSController) updateService(ctx context.Context, service *corev1.Service, mutate func(*corev1.Service)) (*corev1.Service, error) {
+	updated := service.DeepCopy()
+	mutate(updated)
+	if slices.Equal(updated.Finalizers, service.Finalizers) &&
+		updated.Annotations[dnsRegisteredNamesAnnotation] == service.Annotations[dnsRegisteredNamesAnnotation] {
+		return service, nil
+	}
+	updated, err := c.kubeClient.CoreV1().Services(service.Namespace).Update(ctx, updated, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update service: %w", err)
+	}
+	return updated, nil
+}
+
+// desiredDNSNames returns the fully qualified hostnames of dnsNameAnnotation. Services that are deleted or aren't
+// of type LoadBalancer anymore don't get any records.
+func desiredDNSNames(service *corev1.Service) ([]string, error) {
+	if service.DeletionTimestamp != nil || service.Spec.Type != corev1.ServiceTypeLoadBalancer {
+		return nil, nil
+	}
+	hostnames := splitDNSNames(service.Annotations[dnsNameAnnotation])
+	for _, hostname := range hostnames {
+		if errs := validation.IsDNS1123Subdomain(strings.TrimSuffix(hostname, ".")); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid hostname %q in annotation %s: %s", hostname, dnsNameAnnotation, strings.Join(errs, ", "))
+		}
+	}
+	return hostnames, nil
+}
+
+// splitDNSNames splits a list of hostnames separated by commas and returns them sorted, lower case and fully qualified.
+func splitDNSNames(value string) []string {
+	var hostnames []string
+	for hostname := range strings.SplitSeq(value, ",") {
+		hostname = strings.ToLower(strings.TrimSpace(hostname))
+		if hostname == "" {
+			continue
+		}
+		if !strings.HasSuffix(hostname, ".") {
+			hostname += "."
+		}
+		hostnames = append(hostnames, hostname)
+	}
+	slices.Sort(hostnames)
+	return slices.Compact(hostnames)
+}
+
+// loadBalancerIPs returns the sorted IPv4 addresses in the status of the service.
+func loadBalancerIPs(service *corev1.Service) []string {
+	var ips []string
+	for _, ingress := range service.Status.LoadBalancer.Ingress {
+		if ip, err := netip.ParseAddr(ingress.IP); err == nil && ip.Is4() {
+			ips = append(ips, ip.String())
+		}
+	}
+	slices.Sort(ips)
+	return slices.Compact(ips)
+}
+
+func recordContents(records []stackitclient.DNSRecord) []string {
+	contents := make([]string, 0, len(records))
+	for _, record := range records {
+		contents = append(contents, record.Content)
+	}
+	slices.Sort(contents)
+	return contents
+}
@@ -0,0 +1,162 @@
+package ccm
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+)
+
+var _ = Describe("DNSController", func() {
+	const (
+		zoneID   = "zone"
+		hostname = "app.example.com."
+		owner    = "managed by stackit-cloud-controller-manager for service default/my-service in cluster my-cluster"
+	)
+
+	var (
+		dnsMock    *stackitclientmock.MockDNSClient
+		kubeClient *fake.Clientset
+		recorder   *record.FakeRecorder
+		controller *DNSController
+		service    *corev1.Service
+	)
+
+	BeforeEach(func() {
+		ctrl := gomock.NewController(GinkgoT())
+		dnsMock = stackitclientmock.NewMockDNSClient(ctrl)
+		recorder = record.NewFakeRecorder(10)
+
+		service = &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "default",
+				Name:        "my-service",
+				Annotations: map[string]string{dnsNameAnnotation: "App.example.com"},
+			},
+			Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+			Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{
+				Ingress: []corev1.LoadBalancerIngress{{IP: "1.2.3.4"}},
+			}},
+		}
+	})
+
+	start := func() {
+		kubeClient = fake.NewClientset(service)
+		serviceInformer := informers.NewSharedInformerFactory(kubeClient, 0).Core().V1().Services()
+		var err error
+		controller, err = NewDNSController(serviceInformer, kubeClient, &CloudControllerManager{
+			loadBalancer: controllerLoadBalancer(recorder),
+			dnsClient:    dnsMock,
+			dnsOpts:      stackitconfig.DNSOpts{ZoneID: zoneID},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(serviceInformer.Informer().GetIndexer().Add(service)).To(Succeed())
+	}
+
+	get := func() *corev1.Service {
+		s, err := kubeClient.CoreV1().Services(service.Namespace).Get(context.Background(), service.Name, metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		return s
+	}
+
+	recordSet := func(comment string, ips ...string) stackitclient.DNSRecordSet {
+		rs := stackitclient.DNSRecordSet{ID: "rrset", Name: hostname, Type: "A", TTL: defaultDNSTTL, Comment: comment}
+		for _, ip := range ips {
+			rs.Records = append(rs.Records, stackitclient.DNSRecord{Content: ip})
+		}
+		return rs
+	}
+
+	It("should require a DNS zone", func() {
+		_, err := NewDNSController(informers.NewSharedInformerFactory(fake.NewClientset(), 0).Core().V1().Services(),
+			fake.NewClientset(), &CloudControllerManager{loadBalancer: controllerLoadBalancer(recorder)})
+		Expect(err).To(MatchError(ContainSubstring("requires dns.zoneId")))
+	})
+
+	It("should create the records and the finalizer", func() {
+		start()
+		dnsMock.EXPECT().ListRecordSets(gomock.Any(), zoneID, hostname, "A").Return(nil, nil)
+		rs := recordSet(owner, "1.2.3.4")
+		rs.ID = ""
+		dnsMock.EXPECT().CreateRecordSet(gomock.Any(), zoneID, rs).Return(&rs, nil)
+
+		Expect(controller.syncService(context.Background(), "default/my-service")).To(Succeed())
+		s := get()
+		Expect(s.Finalizers).To(ConsistOf(dnsFinalizer))
+		Expect(s.Annotations).To(HaveKeyWithValue(dnsRegisteredNamesAnnotation, hostname))
+	})
+
+	It("should update the records when the IP changes", func() {
+		start()
+		dnsMock.EXPECT().ListRecordSets(gomock.Any(), zoneID, hostname, "A").
+			Return([]stackitclient.DNSRecordSet{recordSet(owner, "5.6.7.8")}, nil)
+		rs := recordSet(owner, "1.2.3.4")
+		rs.ID = ""
+		dnsMock.EXPECT().UpdateRecordSet(gomock.Any(), zoneID, "rrset", rs).Return(nil)
+
+		Expect(controller.syncService(context.Background(), "default/my-service")).To(Succeed())
+	})
+
+	It("should not touch records of other owners", func() {
+		start()
+		dnsMock.EXPECT().ListRecordSets(gomock.Any(), zoneID, hostname, "A").
+			Return([]stackitclient.DNSRecordSet{recordSet("someone else", "5.6.7.8")}, nil)
+
+		Expect(controller.syncService(context.Background(), "default/my-service")).To(MatchError(ContainSubstring("another owner")))
+		Expect(recorder.Events).To(Receive(ContainSubstring(EventReasonDNSRecordConflict)))
+		Expect(get().Annotations).NotTo(HaveKey(dnsRegisteredNamesAnnotation))
+	})
+
+	It("should reject invalid hostnames", func() {
+		service.Annotations[dnsNameAnnotation] = "not_a_hostname"
+		start()
+
+		Expect(controller.syncService(context.Background(), "default/my-service")).To(Succeed())
+		Expect(recorder.Events).To(Receive(ContainSubstring(EventReasonInvalidDNSName)))
+	})
+
+	It("should delete the records of removed hostnames", func() {
+		service.Annotations[dnsNameAnnotation] = ""
+		service.Annotations[dnsRegisteredNamesAnnotation] = hostname
+		service.Finalizers = []string{dnsFinalizer}
+		start()
+		dnsMock.EXPECT().ListRecordSets(gomock.Any(), zoneID, hostname, "A").
+			Return([]stackitclient.DNSRecordSet{recordSet(owner, "1.2.3.4")}, nil)
+		dnsMock.EXPECT().DeleteRecordSet(gomock.Any(), zoneID, "rrset").Return(nil)
+
+		Expect(controller.syncService(context.Background(), "default/my-service")).To(Succeed())
+		s := get()
+		Expect(s.Finalizers).To(BeEmpty())
+		Expect(s.Annotations).NotTo(HaveKey(dnsRegisteredNamesAnnotation))
+	})
+
+	It("should keep the finalizer of a deleted service until the records are deleted", func() {
+		service.Annotations[dnsRegisteredNamesAnnotation] = hostname
+		service.Finalizers = []string{dnsFinalizer}
+		service.DeletionTimestamp = new(metav1.Now())
+		start()
+		dnsMock.EXPECT().ListRecordSets(gomock.Any(), zoneID, hostname, "A").
+			Return([]stackitclient.DNSRecordSet{recordSet(owner, "1.2.3.4")}, nil)
+		dnsMock.EXPECT().DeleteRecordSet(gomock.Any(), zoneID, "rrset").Return(errors.New("injected error"))
+
+		Expect(controller.syncService(context.Background(), "default/my-service")).To(MatchError(ContainSubstring("injected error")))
+		Expect(get().Finalizers).To(ConsistOf(dnsFinalizer))
+	})
+})
+
+// controllerLoadBalancer returns a load balancer implementation that only provides the recorder and cluster ID to
+// controllers.
+func controllerLoadBalancer(recorder record.EventRecorder) *LoadBalancer {
+	return &LoadBalancer{recorder: recorder, clusterID: "my-cluster"}
+}
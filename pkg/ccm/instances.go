@@ -20,11 +20,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"regexp"
 	"slices"
 	"strings"
 
 	"github.com/stackitcloud/cloud-provider-stackit/pkg/labels"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/providerid"
 	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
 	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
 	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
@@ -40,18 +40,13 @@ const (
 	instanceStopping = "STOPPING"
 )
 
-// If makeInstanceID is changed, the regexp should be changed too.
-var providerIDRegexp = regexp.MustCompile(`^` + ProviderName + `://([^/]+)$`)
-
-// TODO(migration): remove old provider support after migration
-var oldProviderIDRegexp = regexp.MustCompile(`^` + oldProviderName + `://([^/]*)/([^/]+)$`)
-
 // Instances encapsulates an implementation of Instances for OpenStack.
 type Instances struct {
 	regionProviderID bool
 	iaasClient       stackitclient.IaaSClient
 	region           string
-	defaultNetwork   string
+	// networkPriority contains network names or IDs in the order their NICs are published as node addresses
+	networkPriority []string
 }
 
 func NewInstance(client stackitclient.IaaSClient, region string, opts config.InstanceOpts) (*Instances, error) {
@@ -59,7 +54,7 @@ func NewInstance(client stackitclient.IaaSClient, region string, opts config.Ins
 		iaasClient:       client,
 		region:           region,
 		regionProviderID: false,
-		defaultNetwork:   opts.DefaultNetwork,
+		networkPriority:  networkPriority(opts),
 	}, nil
 }
 
@@ -67,7 +62,7 @@ func NewInstance(client stackitclient.IaaSClient, region string, opts config.Ins
 func (i *Instances) InstanceExists(ctx context.Context, node *corev1.Node) (bool, error) {
 	_, err := i.getInstance(ctx, node)
 	if errors.Is(err, cloudprovider.InstanceNotFound) {
-		klog.V(6).Infof("Instance not found for node: %s", node.Name)
+		klog.V(6).InfoS("Instance not found for node", "node", klog.KObj(node))
 		return false, nil
 	}
 	if err != nil {
@@ -96,7 +91,7 @@ func (i *Instances) InstanceShutdown(ctx context.Context, node *corev1.Node) (bo
 func (i *Instances) InstanceMetadata(ctx context.Context, node *corev1.Node) (*cloudprovider.InstanceMetadata, error) {
 	server, err := i.getInstance(ctx, node)
 	if errors.Is(err, cloudprovider.InstanceNotFound) {
-		klog.V(6).Infof("Instance not found for node: %s", node.Name)
+		klog.V(6).InfoS("Instance not found for node", "node", klog.KObj(node))
 		return nil, nil
 	}
 	if err != nil {
@@ -108,7 +103,7 @@ func (i *Instances) InstanceMetadata(ctx context.Context, node *corev1.Node) (*c
 		return nil, fmt.Errorf("server has no network interfaces")
 	}
 
-	nics := sortNics(server.GetNics(), i.defaultNetwork)
+	nics := sortNics(server.GetNics(), i.networkPriority)
 	for i := range nics {
 		nic := &nics[i]
 		if nic.HasIpv4() {
@@ -145,7 +140,7 @@ func (i *Instances) InstanceMetadata(ctx context.Context, node *corev1.Node) (*c
 	availabilityZone := labels.Sanitize(server.GetAvailabilityZone())
 
 	return &cloudprovider.InstanceMetadata{
-		ProviderID:    i.makeInstanceID(server),
+		ProviderID:    providerid.New(server.GetAvailabilityZone(), server.GetId()),
 		InstanceType:  server.GetMachineType(),
 		NodeAddresses: addresses,
 		Zone:          availabilityZone,
@@ -153,37 +148,48 @@ func (i *Instances) InstanceMetadata(ctx context.Context, node *corev1.Node) (*c
 	}, nil
 }
 
-func (i *Instances) makeInstanceID(server *iaas.Server) string {
-	return fmt.Sprintf("%s://%s", ProviderName, server.GetId())
+// networkPriority returns the configured network priority. The default network always takes precedence.
+func networkPriority(opts config.InstanceOpts) []string {
+	priority := slices.Clone(opts.NetworkPriority)
+	if opts.DefaultNetwork != "" {
+		priority = slices.DeleteFunc(priority, func(network string) bool { return network == opts.DefaultNetwork })
+		priority = slices.Insert(priority, 0, opts.DefaultNetwork)
+	}
+	return priority
 }
 
 // sortNics sorts a slice of server network interfaces alphabetically by their network name
-// to ensure a deterministic order. If a non-empty defaultNetwork is provided (matching either
-// the NetworkName or NetworkId), that specific network interface is moved to the front (index 0)
-// of the returned slice.
-func sortNics(nics []iaas.ServerNetwork, defaultNetwork string) []iaas.ServerNetwork {
+// to ensure a deterministic order. Network interfaces in one of the networks in networkPriority
+// (matching either the NetworkName or NetworkId) are moved to the front of the returned slice,
+// in the order of networkPriority.
+func sortNics(nics []iaas.ServerNetwork, networkPriority []string) []iaas.ServerNetwork {
 	// nics are returned by IaaS API in a non-deterministic order
 	// Sort by network name so that every time we use the same order for node addresses
 	slices.SortFunc(nics, func(a, b iaas.ServerNetwork) int {
 		return strings.Compare(a.NetworkName, b.NetworkName)
 	})
 
-	if defaultNetwork == "" {
+	if len(networkPriority) == 0 {
 		return nics
 	}
 
-	idx := slices.IndexFunc(nics, func(nic iaas.ServerNetwork) bool {
-		return nic.NetworkName == defaultNetwork || nic.NetworkId == defaultNetwork
-	})
-	// network not found
-	if idx == -1 {
-		klog.Infof("no NIC found for default network %s", defaultNetwork)
-		return nics
+	rank := func(nic iaas.ServerNetwork) int {
+		idx := slices.IndexFunc(networkPriority, func(network string) bool {
+			return nic.NetworkName == network || nic.NetworkId == network
+		})
+		if idx == -1 {
+			return len(networkPriority)
+		}
+		return idx
+	}
+	for _, network := range networkPriority {
+		if !slices.ContainsFunc(nics, func(nic iaas.ServerNetwork) bool { return nic.NetworkName == network || nic.NetworkId == network }) {
+			klog.InfoS("No NIC found for network", "network", network)
+		}
 	}
-	defaultNic := nics[idx]
-	nics = slices.Delete(nics, idx, idx+1)
-	// prepend default nic
-	nics = slices.Insert(nics, 0, defaultNic)
+	slices.SortStableFunc(nics, func(a, b iaas.ServerNetwork) int {
+		return rank(a) - rank(b)
+	})
 	return nics
 }
 
@@ -204,37 +210,6 @@ func addToNodeAddresses(addresses *[]corev1.NodeAddress, addAddresses ...corev1.
 	}
 }
 
-// instanceIDFromProviderID splits a provider's id and return instanceID.
-// A providerID is build out of '${ProviderName}:///${instance-id}' which contains ':///'.
-// or '${ProviderName}://${region}/${instance-id}' which contains '://'.
-// See cloudprovider.GetInstanceProviderID and Instances.InstanceID.
-// TODO(migration): rework function once openstack:/// is no longer used
-func instanceIDFromProviderID(providerID string) (instanceID, region string, err error) {
-	// https://github.com/kubernetes/kubernetes/issues/85731
-	if providerID != "" && !strings.Contains(providerID, "://") {
-		providerID = ProviderName + "://" + providerID
-	}
-
-	switch {
-	// TODO(migration): remove old provider support after migration
-	case strings.HasPrefix(providerID, "openstack://"):
-		matches := oldProviderIDRegexp.FindStringSubmatch(providerID)
-		if len(matches) != 3 {
-			return "", "", fmt.Errorf("ProviderID \"%s\" didn't match expected format \"%s://region/InstanceID\"", oldProviderName, providerID)
-		}
-		return matches[2], matches[1], nil
-	case strings.HasPrefix(providerID, "stackit://"):
-		matches := providerIDRegexp.FindStringSubmatch(providerID)
-		if len(matches) != 2 {
-			return "", "", fmt.Errorf("ProviderID \"%s\" didn't match expected format \"%s://InstanceID\"", ProviderName, providerID)
-		}
-		// The new stackit:// doesn't use the old regional providerID anymore and strictly follows the spec
-		return matches[1], "", nil
-	default:
-		return "", "", fmt.Errorf("unknown ProviderName")
-	}
-}
-
 func getServerByName(ctx context.Context, client stackitclient.IaaSClient, name string) (*iaas.Server, error) {
 	servers, err := client.ListServers(ctx)
 	if err != nil {
@@ -262,16 +237,16 @@ func (i *Instances) getInstance(ctx context.Context, node *corev1.Node) (*iaas.S
 		return getServerByName(ctx, i.iaasClient, node.Name)
 	}
 
-	instanceID, instanceRegion, err := instanceIDFromProviderID(node.Spec.ProviderID)
+	providerID, err := providerid.Parse(node.Spec.ProviderID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get instance ID from Provider ID: %w", err)
 	}
 
-	if instanceRegion != "" && instanceRegion != i.region {
+	if providerID.Region != "" && providerID.Region != i.region {
 		return nil, fmt.Errorf("ProviderID \"%s\" didn't match supported region \"%s\"", node.Spec.ProviderID, i.region)
 	}
 
-	server, err := i.iaasClient.GetServerWithDetails(ctx, instanceID)
+	server, err := i.iaasClient.GetServerWithDetails(ctx, providerID.ServerID)
 	if stackiterrors.IsNotFound(err) {
 		return nil, cloudprovider.InstanceNotFound
 	}
@@ -54,18 +54,6 @@ var _ = Describe("Node Controller", func() {
 		Expect(err).NotTo(HaveOccurred())
 	})
 
-	DescribeTable("instanceIDFromProviderID",
-		func(providerID string, expectedInstanceID string, expectedRegion string) {
-			instance, region, err := instanceIDFromProviderID(providerID)
-			Expect(err).NotTo(HaveOccurred())
-			Expect(instance).To(Equal(expectedInstanceID))
-			Expect(region).To(Equal(expectedRegion))
-		},
-		Entry("new providerID", "stackit://hello-server", "hello-server", ""),
-		Entry("old providerID", "openstack:///hello-server", "hello-server", ""),
-		Entry("old regional providerID", "openstack://eu01/hello-server", "hello-server", "eu01"),
-	)
-
 	Describe("InstanceExists", func() {
 		It("does not error if instance not found", func() {
 			nodeMockClient.EXPECT().ListServers(gomock.Any()).Return(&[]iaas.Server{}, nil)
@@ -112,6 +100,23 @@ var _ = Describe("Node Controller", func() {
 			Expect(exist).To(BeTrue())
 		})
 
+		It("successfully get the instance when canonical provider ID is there", func() {
+			nodeMockClient.EXPECT().GetServerWithDetails(gomock.Any(), serverID).Return(&iaas.Server{
+				Name: "foo",
+			}, nil)
+
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+				Spec: corev1.NodeSpec{
+					ProviderID: fmt.Sprintf("stackit:///eu01-1/%s", serverID),
+				},
+			}
+
+			exist, err := instance.InstanceExists(context.Background(), node)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exist).To(BeTrue())
+		})
+
 		It("successfully get the instance when old provider ID is there", func() {
 			nodeMockClient.EXPECT().GetServerWithDetails(gomock.Any(), serverID).Return(&iaas.Server{
 				Name: "foo",
@@ -270,6 +275,26 @@ var _ = Describe("Node Controller", func() {
 			Expect(metadata.Region).To(Equal("eu01"))
 		})
 
+		It("returns the canonical provider ID with the availability zone", func() {
+			nodeMockClient.EXPECT().ListServers(gomock.Any()).Return(&[]iaas.Server{
+				{
+					Name:             "foo",
+					Id:               new(serverID),
+					AvailabilityZone: new("eu01-1"),
+					Nics:             []iaas.ServerNetwork{{Ipv4: new("10.10.100.24")}},
+				},
+			}, nil)
+
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+			}
+
+			metadata, err := instance.InstanceMetadata(context.Background(), node)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(metadata.ProviderID).To(Equal(fmt.Sprintf("stackit:///eu01-1/%s", serverID)))
+			Expect(metadata.Zone).To(Equal("eu01-1"))
+		})
+
 		It("errors when list server fails", func() {
 			nodeMockClient.EXPECT().ListServers(gomock.Any()).Return(nil, fmt.Errorf("failed due to some reason"))
 
@@ -303,18 +328,46 @@ var _ = Describe("Node Controller", func() {
 				},
 			}
 			By("with network name")
-			newNics := sortNics(nics, "default")
+			newNics := sortNics(nics, []string{"default"})
 			Expect(newNics).To(HaveLen(3))
 			Expect(newNics[0].NetworkName).To(Equal("default"))
 			Expect(newNics[1].NetworkName).To(Equal("abc"))
 			Expect(newNics[2].NetworkName).To(Equal("foo"))
 
 			By("with network id")
-			newNics = sortNics(nics, "123")
+			newNics = sortNics(nics, []string{"123"})
 			Expect(newNics).To(HaveLen(3))
 			Expect(newNics[0].NetworkId).To(Equal("123"))
 			Expect(newNics[1].NetworkId).To(Equal("69"))
 			Expect(newNics[2].NetworkId).To(Equal("69"))
 		})
+
+		It("should order the nics by network priority", func() {
+			nics := []iaas.ServerNetwork{
+				{NetworkName: "abc", NetworkId: "1"},
+				{NetworkName: "def", NetworkId: "2"},
+				{NetworkName: "ghi", NetworkId: "3"},
+				{NetworkName: "jkl", NetworkId: "4"},
+			}
+			newNics := sortNics(nics, []string{"ghi", "2", "unknown"})
+			Expect(newNics).To(HaveLen(4))
+			Expect(newNics[0].NetworkName).To(Equal("ghi"))
+			Expect(newNics[1].NetworkName).To(Equal("def"))
+			Expect(newNics[2].NetworkName).To(Equal("abc"))
+			Expect(newNics[3].NetworkName).To(Equal("jkl"))
+		})
+	})
+
+	Describe("#networkPriority", func() {
+		It("should put the default network first", func() {
+			Expect(networkPriority(config.InstanceOpts{
+				DefaultNetwork:  "b",
+				NetworkPriority: []string{"a", "b", "c"},
+			})).To(Equal([]string{"b", "a", "c"}))
+		})
+
+		It("should return the network priority without default network", func() {
+			Expect(networkPriority(config.InstanceOpts{NetworkPriority: []string{"a"}})).To(Equal([]string{"a"}))
+		})
 	})
 })
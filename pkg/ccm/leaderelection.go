@@ -0,0 +1,62 @@
+package ccm
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	componentbaseconfig "k8s.io/component-base/config"
+	"k8s.io/klog/v2"
+)
+
+// StartLeaderElection runs the leader election with the lease in the cluster of client instead of the workload
+// cluster, which is required if the CCM runs in a hosted control plane. It doesn't block, the returned channel is closed
+// once this instance is the leader. The process exits once the lease is lost, like the leader election of the
+// cloud-provider app.
+func StartLeaderElection(ctx context.Context, client kubernetes.Interface, opts componentbaseconfig.LeaderElectionConfiguration) (<-chan struct{}, error) {
+	// Identity used to distinguish between multiple cloud controller manager instances
+	id, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	id = id + "_" + string(uuid.NewUUID())
+
+	lock, err := resourcelock.New(opts.ResourceLock, opts.ResourceNamespace, opts.ResourceName,
+		client.CoreV1(), client.CoordinationV1(), resourcelock.ResourceLockConfig{Identity: id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create leader election lock: %w", err)
+	}
+
+	leading := make(chan struct{})
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: opts.LeaseDuration.Duration,
+		RenewDeadline: opts.RenewDeadline.Duration,
+		RetryPeriod:   opts.RetryPeriod.Duration,
+		Name:          opts.ResourceName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) {
+				klog.InfoS("Acquired leadership", "namespace", opts.ResourceNamespace, "name", opts.ResourceName)
+				close(leading)
+			},
+			OnStoppedLeading: func() {
+				if ctx.Err() != nil {
+					return
+				}
+				klog.ErrorS(nil, "leaderelection lost")
+				klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create leader elector: %w", err)
+	}
+
+	klog.InfoS("Waiting for leadership", "namespace", opts.ResourceNamespace, "name", opts.ResourceName, "identity", id)
+	go elector.Run(ctx)
+	return leading, nil
+}
@@ -0,0 +1,66 @@
+package ccm
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	componentbaseconfig "k8s.io/component-base/config"
+)
+
+var _ = Describe("StartLeaderElection", func() {
+	var (
+		kubeClient *fake.Clientset
+		opts       componentbaseconfig.LeaderElectionConfiguration
+	)
+
+	BeforeEach(func() {
+		kubeClient = fake.NewClientset()
+		opts = componentbaseconfig.LeaderElectionConfiguration{
+			LeaderElect:       true,
+			ResourceLock:      "leases",
+			ResourceName:      "stackit-cloud-controller-manager",
+			ResourceNamespace: "shoot--my-cluster",
+			LeaseDuration:     metav1.Duration{Duration: 15 * time.Second},
+			RenewDeadline:     metav1.Duration{Duration: 10 * time.Second},
+			RetryPeriod:       metav1.Duration{Duration: 100 * time.Millisecond},
+		}
+	})
+
+	It("should acquire the lease in the configured namespace", func(ctx SpecContext) {
+		ctx2, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		leading, err := StartLeaderElection(ctx2, kubeClient, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(leading).Should(BeClosed())
+
+		lease, err := kubeClient.CoordinationV1().Leases("shoot--my-cluster").Get(ctx, "stackit-cloud-controller-manager", metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lease.Spec.HolderIdentity).NotTo(BeNil())
+	}, SpecTimeout(5*time.Second))
+
+	It("should wait while another instance holds the lease", func(ctx SpecContext) {
+		_, err := kubeClient.CoordinationV1().Leases("shoot--my-cluster").Create(ctx, &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: "stackit-cloud-controller-manager", Namespace: "shoot--my-cluster"},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       new("other"),
+				LeaseDurationSeconds: new(int32(60)),
+				AcquireTime:          &metav1.MicroTime{Time: time.Now()},
+				RenewTime:            &metav1.MicroTime{Time: time.Now()},
+			},
+		}, metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx2, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		leading, err := StartLeaderElection(ctx2, kubeClient, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Consistently(leading, 500*time.Millisecond).ShouldNot(BeClosed())
+	}, SpecTimeout(5*time.Second))
+})
@@ -2,29 +2,87 @@ package ccm
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/stackitcloud/cloud-provider-stackit/pkg/cmp"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/metrics"
 	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
 	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
 	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/tracing"
 	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
 	corev1 "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
 	cloudprovider "k8s.io/cloud-provider"
 	"k8s.io/cloud-provider/api"
+	"k8s.io/klog/v2"
 )
 
 const (
 	retryDuration = 10 * time.Second
+	// reconcileTimeout bounds a single reconciliation of a service,
+	// so that a stuck API call doesn't block a worker of the service controller forever.
+	reconcileTimeout = 5 * time.Minute
+	// maxConcurrentAPICalls limits the API calls that a single reconciliation issues in parallel,
+	// e.g. when updating the target pools of all ports after the nodes changed.
+	maxConcurrentAPICalls = 4
+
+	// loadBalancerNamePrefix is the prefix of all load balancers and observability credentials created by the CCM
+	loadBalancerNamePrefix = "k8s-svc-"
+	// maxCredentialsNameLength is the maximum length of the display name of observability credentials
+	maxCredentialsNameLength = 63
 
 	// EventReasonSelectedPlanID is a reason for sending an event when plan ID is selected via a flavor
 	EventReasonSelectedPlanID = "SelectedPlanID"
+	// EventReasonRolledBack is a reason for sending an event when a load balancer that never became ready is deleted
+	// because of listener errors
+	EventReasonRolledBack = "RolledBackLoadBalancer"
+	// EventReasonInvalidSpec is a reason for sending an event that lists all invalid options of a service
+	EventReasonInvalidSpec = "InvalidLoadBalancerSpec"
+	// EventReasonServicePlanChanged is a reason for sending an event when the CCM changes the plan of a load balancer
+	EventReasonServicePlanChanged = "ServicePlanChanged"
+	// EventReasonQuotaExceeded is a reason for sending an event when a load balancer can't be created or updated
+	// because of a quota of the project
+	EventReasonQuotaExceeded = "LoadBalancerQuotaExceeded"
+	// EventReasonRejected is a reason for sending an event when the API rejects the load balancer as invalid
+	EventReasonRejected = "LoadBalancerRejected"
+	// EventReasonAPIMaintenance is a reason for sending an event when the API is unavailable because of a maintenance
+	EventReasonAPIMaintenance = "CloudAPIMaintenance"
+	// EventReasonUpdating is a reason for sending an event that lists the fields of a load balancer that are updated
+	EventReasonUpdating = "UpdatingLoadBalancer"
+	// EventReasonDrainingTargets is a reason for sending an event when removed nodes are kept as targets until their
+	// deregistration delay is over
+	EventReasonDrainingTargets = "DrainingTargets"
+	// EventReasonRecreating is a reason for sending an event when a load balancer is recreated to apply a change of an
+	// immutable field
+	EventReasonRecreating = "RecreatingLoadBalancer"
+	// EventReasonPaused is a reason for sending an event when the reconciliation of a load balancer is paused
+	EventReasonPaused = "LoadBalancerPaused"
 )
 
+// listenerErrorTypes are load balancer errors that are caused by the configuration of a listener and
+// can therefore be fixed by changing the service.
+var listenerErrorTypes = []loadbalancer.LoadBalancerErrorType{
+	loadbalancer.LOADBALANCERERRORTYPE_TYPE_PORT_NOT_CONFIGURED,
+	loadbalancer.LOADBALANCERERRORTYPE_TYPE_VM_PORT_NOT_CONFIGURED,
+}
+
 type Event struct {
 	Type    string
 	Message string
@@ -39,21 +97,69 @@ type MetricsRemoteWrite struct {
 
 // LoadBalancer is used for creating and maintaining load balancers.
 type LoadBalancer struct {
-	client   stackitclient.LoadBalancingClient
-	recorder record.EventRecorder // set in CloudControllerManager.Initialize
-	opts     stackitconfig.LoadBalancerOpts
+	client stackitclient.LoadBalancingClient
+	// iaasClient manages the node port rules in LoadBalancerOpts.NodeSecurityGroupID
+	iaasClient stackitclient.IaaSClient
+	recorder   record.EventRecorder // set in CloudControllerManager.Initialize
+	// kubeClient persists the reconcile backoff of services, set in CloudControllerManager.Initialize
+	kubeClient kubernetes.Interface
+	opts       stackitconfig.LoadBalancerOpts
 	// metricsRemoteWrite setting this enables remote writing of metrics and nil means it is disabled
 	metricsRemoteWrite *MetricsRemoteWrite
+	// planRecommender is nil if plan recommendations are disabled
+	planRecommender *planRecommender
+	// clusterID scopes the display names of observability credentials to the cluster, set in NewCloudControllerManager
+	clusterID string
+	// projectID is only used in events, set in NewCloudControllerManager
+	projectID string
+	// resourceLabels are added to all load balancers, nil if disabled, set in NewCloudControllerManager
+	resourceLabels map[string]string
+	// endpointSlices provides the targets of services whose targets depend on their pods, set in
+	// NewEndpointTargetsController while the service controller may already reconcile load balancers
+	endpointSlices atomic.Pointer[endpointSliceSource]
+	// ipReservationLister provides the LoadBalancerIPReservations claimed by services, set in NewIPReservationController
+	ipReservationLister cache.GenericLister
+	// optIn restricts the reconciliation to services with enabledAnnotation, see CloudControllerManager.SetLoadBalancerOptIn
+	optIn bool
+	// maintenanceNotified contains the UIDs of services that got an event about the current maintenance of the API
+	maintenanceNotified sync.Map
+	// pausedNotified contains the UIDs of services that got an event about the pause of their reconciliation
+	pausedNotified sync.Map
+	// autoPlanNotified contains the UIDs of services that got an event about automatic plan changes requiring plan
+	// recommendations
+	autoPlanNotified sync.Map
+	// publishedSpecs maps the UIDs of services to the spec that was last published in their ConfigMap, see publishSpec
+	publishedSpecs sync.Map
+	// drainingMu guards draining and drainingTimers
+	drainingMu sync.Mutex
+	// draining maps load balancer names to the IPs of their targets that wait for their deregistration delay
+	draining map[string]map[string]drainingTarget
+	// drainingTimers remove the drained targets of load balancers once their deregistration delay is over
+	drainingTimers map[string]*time.Timer
+	// recreateAt maps the names of load balancers that are recreated because of an immutable change to the time they
+	// are deleted, see allowRecreateAnnotation
+	recreateAt sync.Map
+	// rolledBack maps the UIDs of services whose load balancer was deleted because of listener errors to the rendered
+	// listeners of that load balancer, see handleErrorState
+	rolledBack sync.Map
+	now        func() time.Time
 }
 
 var _ cloudprovider.LoadBalancer = (*LoadBalancer)(nil)
 
-func NewLoadBalancer(client stackitclient.LoadBalancingClient, opts stackitconfig.LoadBalancerOpts, metricsRemoteWrite *MetricsRemoteWrite) (*LoadBalancer, error) { //nolint:lll // looks weird when shortened
+func NewLoadBalancer(client stackitclient.LoadBalancingClient, iaasClient stackitclient.IaaSClient, opts stackitconfig.LoadBalancerOpts, metricsRemoteWrite *MetricsRemoteWrite) (*LoadBalancer, error) { //nolint:lll // looks weird when shortened
+	planRecommender, err := newPlanRecommender(opts.PlanRecommendation, client)
+	if err != nil {
+		return nil, err
+	}
 	// LoadBalancer.recorder is set in CloudControllerManager.Initialize
 	return &LoadBalancer{
 		client:             client,
+		iaasClient:         iaasClient,
 		opts:               opts,
 		metricsRemoteWrite: metricsRemoteWrite,
+		planRecommender:    planRecommender,
+		now:                time.Now,
 	}, nil
 }
 
@@ -64,6 +170,10 @@ func NewLoadBalancer(client stackitclient.LoadBalancingClient, opts stackitconfi
 func (l *LoadBalancer) GetLoadBalancer(ctx context.Context, clusterName string, service *corev1.Service) (
 	status *corev1.LoadBalancerStatus, exists bool, err error,
 ) {
+	if !l.reconciles(service) {
+		// Ignored services must not adopt existing load balancers, e.g. of a previous CCM.
+		return nil, false, nil
+	}
 	lb, err := l.client.GetLoadBalancer(ctx, l.GetLoadBalancerName(ctx, clusterName, service))
 	switch {
 	case stackiterrors.IsNotFound(err):
@@ -75,10 +185,21 @@ func (l *LoadBalancer) GetLoadBalancer(ctx context.Context, clusterName string,
 	return loadBalancerStatus(lb, service), true, nil
 }
 
+// reconciles returns whether the CCM manages the load balancer of the service. In opt-in mode, only services with
+// lb.stackit.cloud/enabled=true are managed. The service controller skips services for which the methods of
+// cloudprovider.LoadBalancer return cloudprovider.ImplementedElsewhere.
+func (l *LoadBalancer) reconciles(service *corev1.Service) bool {
+	if !l.optIn {
+		return true
+	}
+	enabled, _ := strconv.ParseBool(service.Annotations[enabledAnnotation])
+	return enabled
+}
+
 // GetLoadBalancerName returns the name of the load balancer. Implementations must treat the
 // *v1.Service parameter as read-only and not modify it.
 func (l *LoadBalancer) GetLoadBalancerName(_ context.Context, _ string, service *corev1.Service) string {
-	name := fmt.Sprintf("k8s-svc-%s-", service.UID)
+	name := fmt.Sprintf("%s%s-", loadBalancerNamePrefix, service.UID)
 	avail := 63 - len(name)
 	if len(service.Name) <= avail {
 		name += service.Name
@@ -102,20 +223,81 @@ func (l *LoadBalancer) GetLoadBalancerName(_ context.Context, _ string, service
 // load balancer is not ready yet (e.g., it is still being provisioned) and
 // polling at a fixed rate is preferred over backing off exponentially in
 // order to minimize latency.
-func (l *LoadBalancer) EnsureLoadBalancer( //nolint:gocyclo // not really complex
+func (l *LoadBalancer) EnsureLoadBalancer(
+	ctx context.Context,
+	clusterName string,
+	service *corev1.Service,
+	nodes []*corev1.Node,
+) (*corev1.LoadBalancerStatus, error) {
+	if !l.reconciles(service) {
+		return nil, cloudprovider.ImplementedElsewhere
+	}
+	ctx, cancel := context.WithTimeout(ctx, reconcileTimeout)
+	defer cancel()
+	ctx, span := l.startSpan(ctx, "EnsureLoadBalancer", clusterName, service)
+	if paused(service) {
+		status, err := l.pausedLoadBalancerStatus(ctx, clusterName, service)
+		tracing.End(span, err)
+		return status, err
+	}
+	l.reportResumed(ctx, service)
+	status, err := l.withReconcileBackoff(ctx, service, func() (*corev1.LoadBalancerStatus, error) {
+		status, err := l.ensureLoadBalancer(ctx, clusterName, service, nodes)
+		return status, l.handleMaintenance(service, err)
+	})
+	tracing.End(span, err)
+	return status, err
+}
+
+// startSpan starts the span of a reconciliation of the load balancer of service, see tracing.Setup.
+func (l *LoadBalancer) startSpan(ctx context.Context, operation, clusterName string, service *corev1.Service) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "LoadBalancer."+operation, trace.WithAttributes(
+		attribute.String(tracing.AttributeProjectID, l.projectID),
+		attribute.String(tracing.AttributeServiceNamespace, service.Namespace),
+		attribute.String(tracing.AttributeServiceName, service.Name),
+		attribute.String(tracing.AttributeServiceUID, string(service.UID)),
+		attribute.String(tracing.AttributeLoadBalancer, l.GetLoadBalancerName(ctx, clusterName, service)),
+	))
+}
+
+func (l *LoadBalancer) ensureLoadBalancer( //nolint:gocyclo // not really complex
 	ctx context.Context,
 	clusterName string,
 	service *corev1.Service,
 	nodes []*corev1.Node,
 ) (*corev1.LoadBalancerStatus, error) {
 	name := l.GetLoadBalancerName(ctx, clusterName, service)
-	lb, err := l.client.GetLoadBalancer(ctx, name)
-	if err != nil && !stackiterrors.IsNotFound(err) {
+
+	// The load balancer and the listener network are independent reads, so they are fetched in parallel.
+	// The observability credentials depend on the credentials referenced by the load balancer.
+	var (
+		lb         *loadbalancer.LoadBalancer
+		lbNotFound bool
+	)
+	g, gCtx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		var err error
+		lb, err = l.client.GetLoadBalancer(gCtx, name)
+		if stackiterrors.IsNotFound(err) {
+			lbNotFound = true
+			return nil
+		}
+		return err
+	})
+	g.Go(func() error {
+		return l.checkListenerNetwork(gCtx, service)
+	})
+	if err := g.Wait(); err != nil {
 		return nil, err
 	}
-	if stackiterrors.IsNotFound(err) {
+	if lbNotFound {
 		return l.createLoadBalancer(ctx, clusterName, service, nodes)
 	}
+	// The load balancer of a service that was switched to another type and back is still being deleted.
+	// It can't be updated anymore, so it is recreated once it is gone.
+	if lb.Status != nil && *lb.Status == loadbalancer.LOADBALANCERSTATUS_STATUS_TERMINATING {
+		return nil, api.NewRetryError("waiting for the deletion of the previous load balancer to finish before recreating it", retryDuration)
+	}
 
 	observabilityOptions, err := l.reconcileObservabilityCredentials(ctx, lb, name)
 	if err != nil {
@@ -123,23 +305,53 @@ func (l *LoadBalancer) EnsureLoadBalancer( //nolint:gocyclo // not really comple
 	}
 
 	spec, events, err := lbSpecFromService(service, nodes, l.opts, observabilityOptions)
+	l.reportSpecValidity(ctx, service, err)
 	if err != nil {
 		return nil, fmt.Errorf("invalid load balancer specification: %w", err)
 	}
+	l.applyResourceLabels(service, spec)
+	if err := l.applyEndpointTargets(service, spec); err != nil {
+		return nil, err
+	}
+	if err := l.applyIPReservation(service, spec); err != nil {
+		return nil, err
+	}
+	keepAddressForLoadBalancerIP(service, spec, lb)
+	if err := l.applyRetainedIP(ctx, service, spec, lb); err != nil {
+		return nil, err
+	}
+	l.applyDeregistrationDelay(service, name, lb.TargetPools, spec)
 
 	for _, event := range events {
 		l.recorder.Event(service, event.Type, event.Reason, event.Message)
 	}
 
-	fulfills, immutableChanged := compareLBwithSpec(lb, spec)
+	if err := l.reconcileNodePortRules(ctx, name, spec); err != nil {
+		return nil, fmt.Errorf("reconcile node port security group rules: %w", err)
+	}
+
+	// The spec contains the smallest fitting plan if the plan is chosen automatically. Errors were already reported above.
+	if bounds, _ := autoPlanBoundsFromService(service); bounds != nil {
+		spec.PlanId = new(l.autoPlan(ctx, service, lb, *bounds, *spec.PlanId))
+	}
+
+	l.publishSpec(ctx, service, name, spec)
+
+	diffs, immutableChanged := compareLBwithSpec(lb, spec)
 	if immutableChanged != nil {
 		changeStr := fmt.Sprintf("%q", immutableChanged.field)
 		if immutableChanged.annotation != "" {
 			changeStr += fmt.Sprintf(" (%q)", immutableChanged.annotation)
 		}
+		if allowsRecreate(service) {
+			return nil, l.recreateLoadBalancer(ctx, service, name, changeStr)
+		}
+		l.forgetRecreation(name)
 		return nil, fmt.Errorf("update to load balancer cannot be fulfilled: API doesn't support changing %s", changeStr)
 	}
-	if !fulfills {
+	l.forgetRecreation(name)
+	if len(diffs) > 0 {
+		l.recordUpdate(service, name, diffs)
 		credentialsRefBeforeUpdate := getMetricsRemoteWriteRef(lb)
 		// We create the update payload from a new spec.
 		// However, we need to copy over the version because it is required on every update.
@@ -160,6 +372,10 @@ func (l *LoadBalancer) EnsureLoadBalancer( //nolint:gocyclo // not really comple
 		}
 		lb, err = l.client.UpdateLoadBalancer(ctx, name, updatePayload)
 		if err != nil {
+			l.recordAPIError(service, err)
+			if errors.Is(err, stackiterrors.ErrConflict) {
+				return nil, fmt.Errorf("failed to update load balancer because it was changed concurrently: %w", err)
+			}
 			return nil, fmt.Errorf("failed to update load balancer: %w", err)
 		}
 		// Clean up observability credentials if Argus extension is enabled.
@@ -175,15 +391,143 @@ func (l *LoadBalancer) EnsureLoadBalancer( //nolint:gocyclo // not really comple
 	}
 
 	if lb.Status != nil && *lb.Status == loadbalancer.LOADBALANCERSTATUS_STATUS_ERROR {
-		return nil, fmt.Errorf("the load balancer is in an error state")
+		return nil, l.handleErrorState(ctx, service, name, lb, spec.Listeners)
 	}
 	if lb.Status == nil || *lb.Status != loadbalancer.LOADBALANCERSTATUS_STATUS_READY {
 		return nil, api.NewRetryError("waiting for load balancer to become ready. This error is normal while the load balancer starts.", retryDuration)
 	}
 
+	if err := l.labelRetainedIP(ctx, service, lb); err != nil {
+		return nil, err
+	}
+
+	if l.planRecommender != nil {
+		l.planRecommender.recommend(ctx, l.recorder, service, lb)
+	}
+
 	return loadBalancerStatus(lb, service), nil
 }
 
+// keepAddressForLoadBalancerIP keeps the external address of an existing load balancer if the deprecated field
+// spec.loadBalancerIP is its only source and differs from it. The field is only applied when the load balancer is
+// created, because it used to be ignored and the address of a load balancer can't be changed by an update.
+func keepAddressForLoadBalancerIP(service *corev1.Service, spec *loadbalancer.CreateLoadBalancerPayload, lb *loadbalancer.LoadBalancer) {
+	loadBalancerIP := service.Spec.LoadBalancerIP
+	if loadBalancerIP == "" || cmp.UnpackPtr(spec.Options.PrivateNetworkOnly) || cmp.UnpackPtr(lb.ExternalAddress) == loadBalancerIP {
+		return
+	}
+	if _, found := service.Annotations[externalIPAnnotation]; found {
+		return
+	}
+	if _, found := service.Annotations[yawolExistingFloatingIPAnnotation]; found {
+		return
+	}
+	klog.V(2).InfoS("Ignoring spec.loadBalancerIP that differs from the address of the existing load balancer",
+		"service", klog.KObj(service), "loadBalancerIP", loadBalancerIP, "address", cmp.UnpackPtr(lb.ExternalAddress))
+	if cmp.UnpackPtr(cmp.UnpackPtr(lb.Options).EphemeralAddress) {
+		spec.ExternalAddress = nil
+		spec.Options.EphemeralAddress = new(true)
+		return
+	}
+	spec.ExternalAddress = lb.ExternalAddress
+	spec.Options.EphemeralAddress = new(false)
+}
+
+// autoPlan returns the plan for a load balancer whose plan is chosen automatically.
+// The plan is never smaller than the fitting plan, i.e. the smallest plan within the bounds that fits the listeners
+// and targets of the spec. Apart from that, the plan is only changed if the plan recommender is enabled and recommends
+// a different plan within the bounds. Otherwise, a warning is recorded once per service.
+func (l *LoadBalancer) autoPlan(
+	ctx context.Context, service *corev1.Service, lb *loadbalancer.LoadBalancer, bounds planBounds, fitting string,
+) string {
+	current := cmp.UnpackPtr(lb.PlanId)
+	plan := bounds.clamp(current)
+	reason := "to keep it within its bounds"
+	if largerPlan(plan, fitting) != plan {
+		plan = fitting
+		reason = "to fit its listeners and targets"
+	}
+	if l.planRecommender == nil {
+		if _, notified := l.autoPlanNotified.LoadOrStore(service.UID, struct{}{}); !notified {
+			l.recorder.Eventf(service, corev1.EventTypeWarning, EventReasonServicePlanChanged,
+				"Annotation %s requires plan recommendations to be enabled in the cloud config, the plan is only kept within its bounds",
+				servicePlanAutoAnnotation)
+		}
+	} else if lb.Status != nil && *lb.Status == loadbalancer.LOADBALANCERSTATUS_STATUS_READY {
+		recommended, connections, ok := l.planRecommender.evaluate(ctx, service, lb)
+		if recommended = largerPlan(bounds.clamp(recommended), fitting); ok && recommended != plan {
+			plan = recommended
+			reason = fmt.Sprintf("because of up to %.0f concurrent connections", connections)
+		}
+	}
+	if plan != current {
+		l.recorder.Eventf(service, corev1.EventTypeNormal, EventReasonServicePlanChanged,
+			"Changing the plan of the load balancer from %s to %s %s", current, plan, reason)
+	}
+	return plan
+}
+
+// handleErrorState returns the error that describes why the load balancer is in an error state.
+// If the load balancer never became ready and the errors are caused by its listeners, the load balancer is deleted,
+// so that it is created from scratch after the service has been fixed. The desired listeners are remembered, so that
+// createLoadBalancer doesn't recreate the same broken load balancer, see checkRolledBack.
+func (l *LoadBalancer) handleErrorState(
+	ctx context.Context, service *corev1.Service, name string, lb *loadbalancer.LoadBalancer, listeners []loadbalancer.Listener,
+) error {
+	var listenerErrs, otherErrs []string
+	for _, lbErr := range lb.Errors {
+		msg := fmt.Sprintf("%s: %s", cmp.UnpackPtr(lbErr.Type), cmp.UnpackPtr(lbErr.Description))
+		if lbErr.Type != nil && slices.Contains(listenerErrorTypes, *lbErr.Type) {
+			listenerErrs = append(listenerErrs, msg)
+		} else {
+			otherErrs = append(otherErrs, msg)
+		}
+	}
+
+	// The ingress is only set once EnsureLoadBalancer succeeded, i.e. once the load balancer was ready.
+	neverReady := len(service.Status.LoadBalancer.Ingress) == 0
+	if len(listenerErrs) == 0 || len(otherErrs) > 0 || !neverReady {
+		if len(lb.Errors) == 0 {
+			return fmt.Errorf("the load balancer is in an error state")
+		}
+		return fmt.Errorf("the load balancer is in an error state: %s", strings.Join(append(listenerErrs, otherErrs...), "; "))
+	}
+
+	if err := l.client.DeleteLoadBalancer(ctx, name); err != nil {
+		return fmt.Errorf("failed to delete load balancer with listener errors (%s): %w", strings.Join(listenerErrs, "; "), err)
+	}
+	msg := fmt.Sprintf("Deleted load balancer because it never became ready due to listener errors, fix the service to retry: %s", strings.Join(listenerErrs, "; "))
+	if rendered, err := json.Marshal(listeners); err == nil {
+		l.rolledBack.Store(service.UID, rolledBackLoadBalancer{listeners: string(rendered), message: msg})
+	}
+	l.recorder.Event(service, corev1.EventTypeWarning, EventReasonRolledBack, msg)
+	return errors.New(msg)
+}
+
+// rolledBackLoadBalancer is a load balancer that was deleted by handleErrorState.
+type rolledBackLoadBalancer struct {
+	// listeners are the rendered listeners of the deleted load balancer
+	listeners string
+	// message describes the listener errors of the deleted load balancer
+	message string
+}
+
+// checkRolledBack returns an error if the load balancer of the service was deleted because of listener errors and
+// the service still results in the same listeners. Without it, the load balancer would be created, fail and be deleted
+// again in every reconciliation. The error makes the service controller retry with backoff until the service changed.
+func (l *LoadBalancer) checkRolledBack(service *corev1.Service, listeners []loadbalancer.Listener) error {
+	value, ok := l.rolledBack.Load(service.UID)
+	if !ok {
+		return nil
+	}
+	rolledBack := value.(rolledBackLoadBalancer)
+	if rendered, err := json.Marshal(listeners); err == nil && string(rendered) == rolledBack.listeners {
+		return fmt.Errorf("not recreating the load balancer until its listeners are changed: %s", rolledBack.message)
+	}
+	l.rolledBack.Delete(service.UID)
+	return nil
+}
+
 func getMetricsRemoteWriteRef(lb *loadbalancer.LoadBalancer) *string {
 	if lb.Options != nil && lb.Options.Observability != nil && lb.Options.Observability.Metrics != nil && lb.Options.Observability.Metrics.CredentialsRef != nil {
 		return lb.Options.Observability.Metrics.CredentialsRef
@@ -191,9 +535,17 @@ func getMetricsRemoteWriteRef(lb *loadbalancer.LoadBalancer) *string {
 	return nil
 }
 
-func (l *LoadBalancer) createLoadBalancer(ctx context.Context, clusterName string, service *corev1.Service, nodes []*corev1.Node) (
-	*corev1.LoadBalancerStatus, error,
-) {
+func (l *LoadBalancer) createLoadBalancer(
+	ctx context.Context,
+	clusterName string,
+	service *corev1.Service,
+	nodes []*corev1.Node,
+) (*corev1.LoadBalancerStatus, error) {
+	// The listener network was already checked by ensureLoadBalancer.
+	if err := l.checkLoadBalancerQuota(ctx, service); err != nil {
+		return nil, err
+	}
+
 	name := l.GetLoadBalancerName(ctx, clusterName, service)
 	metricsRemoteWrite, err := l.reconcileObservabilityCredentials(ctx, nil, name)
 	if err != nil {
@@ -201,19 +553,36 @@ func (l *LoadBalancer) createLoadBalancer(ctx context.Context, clusterName strin
 	}
 
 	spec, events, err := lbSpecFromService(service, nodes, l.opts, metricsRemoteWrite)
+	l.reportSpecValidity(ctx, service, err)
 	if err != nil {
 		return nil, fmt.Errorf("invalid load balancer specification: %w", err)
 	}
-	if l.opts.ExtraLabels != nil {
-		spec.Labels = new(l.opts.ExtraLabels)
+	l.applyResourceLabels(service, spec)
+	if err := l.applyEndpointTargets(service, spec); err != nil {
+		return nil, err
+	}
+	if err := l.applyIPReservation(service, spec); err != nil {
+		return nil, err
+	}
+	if err := l.applyRetainedIP(ctx, service, spec, nil); err != nil {
+		return nil, err
 	}
 	for _, event := range events {
 		l.recorder.Event(service, event.Type, event.Reason, event.Message)
 	}
+	if err := l.checkRolledBack(service, spec.Listeners); err != nil {
+		return nil, err
+	}
 	spec.Name = &name
+	l.publishSpec(ctx, service, name, spec)
+
+	if err := l.reconcileNodePortRules(ctx, name, spec); err != nil {
+		return nil, fmt.Errorf("reconcile node port security group rules: %w", err)
+	}
 
 	lb, createErr := l.client.CreateLoadBalancer(ctx, spec)
 	if createErr != nil {
+		l.recordAPIError(service, createErr)
 		return nil, createErr
 	}
 
@@ -231,24 +600,79 @@ func (l *LoadBalancer) createLoadBalancer(ctx context.Context, clusterName strin
 //
 // It is not called on controller start-up. EnsureLoadBalancer must also ensure to update targets.
 func (l *LoadBalancer) UpdateLoadBalancer(ctx context.Context, clusterName string, service *corev1.Service, nodes []*corev1.Node) error {
+	if !l.reconciles(service) {
+		return cloudprovider.ImplementedElsewhere
+	}
+	// Static targets don't depend on the nodes, they are only changed by EnsureLoadBalancer.
+	// Paused services already got their event in EnsureLoadBalancer.
+	if hasStaticTargets(service) || paused(service) {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, reconcileTimeout)
+	defer cancel()
+	ctx, span := l.startSpan(ctx, "UpdateLoadBalancer", clusterName, service)
+	err := l.updateLoadBalancer(ctx, clusterName, service, nodes)
+	tracing.End(span, err)
+	return err
+}
+
+func (l *LoadBalancer) updateLoadBalancer(ctx context.Context, clusterName string, service *corev1.Service, nodes []*corev1.Node) error {
 	// only TargetPools are used from spec
 	spec, events, err := lbSpecFromService(service, nodes, l.opts, nil)
+	l.reportSpecValidity(ctx, service, err)
 	if err != nil {
 		return fmt.Errorf("invalid service: %w", err)
 	}
+	if err := l.applyEndpointTargets(service, spec); err != nil {
+		return err
+	}
 
 	for _, event := range events {
 		l.recorder.Event(service, event.Type, event.Reason, event.Message)
 	}
 
-	for _, pool := range spec.TargetPools {
-		err := l.client.UpdateTargetPool(ctx, l.GetLoadBalancerName(ctx, clusterName, service), *pool.Name, loadbalancer.UpdateTargetPoolPayload(pool))
+	name := l.GetLoadBalancerName(ctx, clusterName, service)
+	// The current targets are only required to keep removed nodes until their deregistration delay is over.
+	if l.deregistrationDelay(service) > 0 {
+		lb, err := l.client.GetLoadBalancer(ctx, name)
 		if err != nil {
-			return fmt.Errorf("failed to update target pool %q: %w", *pool.Name, err)
+			return l.handleMaintenance(service, fmt.Errorf("failed to get load balancer: %w", err))
 		}
+		l.applyDeregistrationDelay(service, name, lb.TargetPools, spec)
 	}
 
-	return nil
+	return l.handleMaintenance(service, l.updateTargetPools(ctx, name, spec.TargetPools))
+}
+
+// targetPoolConflictBackoff is the backoff for updates of target pools that conflict with parallel updates of other
+// target pools of the same load balancer.
+var targetPoolConflictBackoff = wait.Backoff{Steps: 5, Duration: 200 * time.Millisecond, Factor: 2, Jitter: 0.5}
+
+// updateTargetPools updates the target pools of a load balancer in parallel, e.g. after a node rollout.
+// Every update changes the version of the load balancer, so the API may reject parallel updates with a conflict. They
+// are retried with a jittered backoff. All pools are updated even if some updates fail.
+func (l *LoadBalancer) updateTargetPools(ctx context.Context, lbName string, pools []loadbalancer.TargetPool) error {
+	var (
+		g    errgroup.Group
+		mu   sync.Mutex
+		errs []error
+	)
+	g.SetLimit(maxConcurrentAPICalls)
+	for _, pool := range pools {
+		g.Go(func() error {
+			err := retry.OnError(targetPoolConflictBackoff, stackiterrors.IsConflict, func() error {
+				return l.client.UpdateTargetPool(ctx, lbName, *pool.Name, loadbalancer.UpdateTargetPoolPayload(pool))
+			})
+			if err != nil {
+				mu.Lock()
+				defer mu.Unlock()
+				errs = append(errs, fmt.Errorf("failed to update target pool %q: %w", *pool.Name, err))
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+	return utilerrors.NewAggregate(errs)
 }
 
 // EnsureLoadBalancerDeleted deletes the specified load balancer if it
@@ -262,16 +686,44 @@ func (l *LoadBalancer) UpdateLoadBalancer(ctx context.Context, clusterName strin
 func (l *LoadBalancer) EnsureLoadBalancerDeleted(
 	ctx context.Context, clusterName string, service *corev1.Service,
 ) error {
+	ctx, cancel := context.WithTimeout(ctx, reconcileTimeout)
+	defer cancel()
+	if !l.reconciles(service) {
+		// A service that opted out after its load balancer was created would leak the load balancer. Its name contains
+		// the UID of the service, so it is still deleted if it exists.
+		_, err := l.client.GetLoadBalancer(ctx, l.GetLoadBalancerName(ctx, clusterName, service))
+		switch {
+		case stackiterrors.IsNotFound(err):
+			return cloudprovider.ImplementedElsewhere
+		case err != nil:
+			return err
+		}
+		klog.InfoS("Deleting load balancer of service that opted out", "service", klog.KObj(service))
+	}
+	ctx, span := l.startSpan(ctx, "EnsureLoadBalancerDeleted", clusterName, service)
+	err := l.handleMaintenance(service, l.ensureLoadBalancerDeleted(ctx, clusterName, service))
+	tracing.End(span, err)
+	return err
+}
+
+func (l *LoadBalancer) ensureLoadBalancerDeleted(ctx context.Context, clusterName string, service *corev1.Service) error {
 	name := l.GetLoadBalancerName(ctx, clusterName, service)
 
+	l.rolledBack.Delete(service.UID)
+	l.autoPlanNotified.Delete(service.UID)
+	l.unpublishSpec(ctx, service)
+	if err := l.cleanUpLoadBalancer(ctx, name); err != nil {
+		return err
+	}
+
 	lb, err := l.client.GetLoadBalancer(ctx, name)
 	switch {
 	case stackiterrors.IsNotFound(err):
-		return nil
+		return l.releaseRetainedIP(ctx, service)
 	case err != nil:
 		return err
 	case lb.Status != nil && *lb.Status == loadbalancer.LOADBALANCERSTATUS_STATUS_TERMINATING:
-		return nil
+		return l.releaseRetainedIP(ctx, service)
 	}
 
 	credentialsRef := getMetricsRemoteWriteRef(lb)
@@ -331,13 +783,13 @@ func (l *LoadBalancer) EnsureLoadBalancerDeleted(
 		return err
 	}
 
-	return nil
+	return l.releaseRetainedIP(ctx, service)
 }
 
-// reconcileObservabilityCredentials update observability credentials if lb has metrics shipping enabled.
-// Otherwise it creates new credentials and returns the observability options that must be injected into the load balancer by the caller.
-//
-// lb can be nil to signal that the load balancer does not exist yet.
+// reconcileObservabilityCredentials creates or updates the credentials the load balancer uses to push metrics and
+// returns the observability options that must be injected into the load balancer by the caller. The credentials are
+// updated if lb already references credentials and created otherwise. lb is nil if the load balancer does not exist yet.
+// All credentials of the project are only listed when new credentials are created, to clean up orphaned ones first.
 func (l *LoadBalancer) reconcileObservabilityCredentials(
 	ctx context.Context,
 	lb *loadbalancer.LoadBalancer,
@@ -350,65 +802,101 @@ func (l *LoadBalancer) reconcileObservabilityCredentials(
 	if lb != nil && lb.Options != nil && lb.Options.Observability != nil && lb.Options.Observability.Metrics != nil {
 		credentialsRef = lb.Options.Observability.Metrics.CredentialsRef
 	}
-	if credentialsRef == nil {
-		// If previous reconciliation left credentials behind that are not referenced, we delete them and start fresh.
-		err := l.cleanUpCredentials(ctx, lbName)
-		if err != nil {
-			return nil, fmt.Errorf("failed to clean up orphaned observability credentials: %w", err)
-		}
-
-		// create
-		payload := loadbalancer.CreateCredentialsPayload{
-			DisplayName: &lbName,
+	if credentialsRef != nil {
+		// update
+		// This also migrates credentials that were created before the cluster ID was configured to the scoped display name.
+		payload := loadbalancer.UpdateCredentialsPayload{
+			DisplayName: new(l.credentialsName(cmp.UnpackPtr(lb.Name))),
 			Username:    &l.metricsRemoteWrite.username,
 			Password:    &l.metricsRemoteWrite.password,
 		}
-		c, err := l.client.CreateCredentials(ctx, payload)
-		if err != nil {
-			return nil, fmt.Errorf("create credentials: %w", err)
+		err := l.client.UpdateCredentials(ctx, *credentialsRef, payload)
+		switch {
+		case err == nil:
+			return &loadbalancer.LoadbalancerOptionObservability{
+				Metrics: &loadbalancer.LoadbalancerOptionMetrics{
+					CredentialsRef: credentialsRef,
+					PushUrl:        &l.metricsRemoteWrite.endpoint,
+				},
+			}, nil
+		case !stackiterrors.IsNotFound(err):
+			return nil, fmt.Errorf("update credentials %q: %w", *credentialsRef, err)
 		}
-		return &loadbalancer.LoadbalancerOptionObservability{
-			Metrics: &loadbalancer.LoadbalancerOptionMetrics{
-				CredentialsRef: c.Credential.CredentialsRef,
-				PushUrl:        &l.metricsRemoteWrite.endpoint,
-			},
-		}, nil
+		// The referenced credentials were deleted outside of the CCM, new ones are created and referenced instead.
+		klog.InfoS("Referenced observability credentials not found, creating new ones", "loadBalancer", lbName, "credentialsRef", *credentialsRef)
+	}
+
+	// If previous reconciliation left credentials behind that are not referenced, we delete them and start fresh.
+	if err := l.cleanUpCredentials(ctx, lbName); err != nil {
+		return nil, fmt.Errorf("failed to clean up orphaned observability credentials: %w", err)
 	}
 
-	// update
-	payload := loadbalancer.UpdateCredentialsPayload{
-		DisplayName: lb.Name,
+	// create
+	payload := loadbalancer.CreateCredentialsPayload{
+		DisplayName: new(l.credentialsName(lbName)),
 		Username:    &l.metricsRemoteWrite.username,
 		Password:    &l.metricsRemoteWrite.password,
 	}
-	if err := l.client.UpdateCredentials(ctx, *credentialsRef, payload); err != nil {
-		return nil, fmt.Errorf("update credentials %q: %w", *credentialsRef, err)
+	c, err := l.client.CreateCredentials(ctx, payload)
+	if err != nil {
+		return nil, fmt.Errorf("create credentials: %w", err)
 	}
 	return &loadbalancer.LoadbalancerOptionObservability{
 		Metrics: &loadbalancer.LoadbalancerOptionMetrics{
-			CredentialsRef: credentialsRef,
+			CredentialsRef: c.Credential.CredentialsRef,
 			PushUrl:        &l.metricsRemoteWrite.endpoint,
 		},
 	}, nil
 }
 
-// cleanUpCredentials removes all credentials from then API whose displayName matches name.
-// This call is expensive.
+// cleanUpCredentials removes all credentials of the load balancer name from the API.
+// Credentials whose display name is not scoped to the cluster yet are deleted as well,
+// because they were created before the cluster ID was configured.
+// This call is expensive, because the API returns all credentials of the project at once.
 // Make sure that no credentials are referenced, otherwise the deletion fails.
 func (l *LoadBalancer) cleanUpCredentials(ctx context.Context, name string) error {
-	res, err := l.client.ListCredentials(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to list credentials: %w", err)
-	}
-	for _, credentials := range res.Credentials {
-		if credentials.DisplayName != nil && *credentials.DisplayName == name {
-			err = l.client.DeleteCredentials(ctx, *credentials.CredentialsRef)
-			if err != nil {
-				return fmt.Errorf("failed to delete credentials %q: %w", *credentials.CredentialsRef, err)
-			}
+	scopedName := l.credentialsName(name)
+	return stackitclient.ForEachCredentials(ctx, l.client, func(credentials *loadbalancer.CredentialsResponse) error {
+		if credentials.DisplayName == nil || (*credentials.DisplayName != scopedName && *credentials.DisplayName != name) {
+			return nil
+		}
+		if err := l.client.DeleteCredentials(ctx, *credentials.CredentialsRef); err != nil {
+			return fmt.Errorf("failed to delete credentials %q: %w", *credentials.CredentialsRef, err)
 		}
+		return nil
+	})
+}
+
+// credentialsName returns the display name of the observability credentials of the load balancer name.
+// It is prefixed with the cluster ID if configured, so that clusters sharing a project don't touch each other's credentials.
+func (l *LoadBalancer) credentialsName(lbName string) string {
+	if l.clusterID == "" {
+		return lbName
 	}
-	return nil
+	name := l.clusterID + "-" + lbName
+	if len(name) > maxCredentialsNameLength {
+		// The service UID in the load balancer name is kept, because the cluster ID is short.
+		name = strings.TrimRight(name[:maxCredentialsNameLength], "-")
+	}
+	return name
+}
+
+// credentialsPrefix returns the prefix of the display names of all observability credentials created by this CCM.
+func (l *LoadBalancer) credentialsPrefix() string {
+	if l.clusterID == "" {
+		return loadBalancerNamePrefix
+	}
+	return l.clusterID + "-" + loadBalancerNamePrefix
+}
+
+// applyResourceLabels adds the resource labels and the UID of the service to the labels of the load balancer.
+func (l *LoadBalancer) applyResourceLabels(service *corev1.Service, spec *loadbalancer.CreateLoadBalancerPayload) {
+	if l.resourceLabels == nil {
+		return
+	}
+	labels := stackitclient.WithResourceLabels(cmp.UnpackPtr(spec.Labels), l.resourceLabels)
+	labels[stackitclient.ServiceUIDLabel] = string(service.UID)
+	spec.Labels = &labels
 }
 
 func loadBalancerStatus(lb *loadbalancer.LoadBalancer, svc *corev1.Service) *corev1.LoadBalancerStatus {
@@ -430,3 +918,35 @@ func loadBalancerStatus(lb *loadbalancer.LoadBalancer, svc *corev1.Service) *cor
 		Ingress: ingresses,
 	}
 }
+
+// recordAPIError records an event if the API refused to create or update the load balancer for a reason
+// that the user has to fix, i.e. a quota of the project or an invalid load balancer.
+func (l *LoadBalancer) recordAPIError(service *corev1.Service, err error) {
+	switch {
+	case errors.Is(err, stackiterrors.ErrQuotaExceeded):
+		l.recorder.Eventf(service, corev1.EventTypeWarning, EventReasonQuotaExceeded,
+			"Load balancer quota exceeded in project %s, delete unused load balancers or request a quota increase: %v", l.projectID, err)
+	case errors.Is(err, stackiterrors.ErrValidation):
+		l.recorder.Eventf(service, corev1.EventTypeWarning, EventReasonRejected,
+			"The load balancer was rejected by the API: %v", err)
+	}
+}
+
+// recordUpdate logs the fields and values that triggered an update of the load balancer, reports the fields in an event
+// and counts them in the update metric.
+func (l *LoadBalancer) recordUpdate(service *corev1.Service, name string, diffs []specDiff) {
+	changes := make([]any, 0, 2*len(diffs))
+	for _, diff := range diffs {
+		changes = append(changes, diff.field, diff.current+" -> "+diff.desired)
+		metrics.LoadBalancerUpdates.WithLabelValues(diffFieldLabel(diff.field)).Inc()
+	}
+	klog.InfoS("Updating load balancer", append([]any{"service", klog.KObj(service), "loadBalancer", name}, changes...)...)
+	l.recorder.Event(service, corev1.EventTypeNormal, EventReasonUpdating,
+		"Updating the load balancer because these fields changed: "+strings.Join(diffFields(diffs), ", "))
+}
+
+// recordInvalidSpec reports all validation errors of the service in a single event.
+func (l *LoadBalancer) recordInvalidSpec(service *corev1.Service, messages []string) {
+	l.recorder.Event(service, corev1.EventTypeWarning, EventReasonInvalidSpec,
+		"The service has invalid load balancer options: "+strings.Join(messages, "; "))
+}
@@ -0,0 +1,126 @@
+package ccm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cloud-provider/api"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// reconcileBackoffAnnotation is set by the CCM and holds the number of consecutive failed reconciliations of a
+	// service and the time of the last failure. It is stored on the service so that the backoff survives restarts.
+	reconcileBackoffAnnotation = "lb.stackit.cloud/reconcile-backoff"
+
+	// The same delays as the backoff of the service controller are used.
+	reconcileBackoffBase = 5 * time.Second
+	reconcileBackoffMax  = 5 * time.Minute
+)
+
+type reconcileBackoff struct {
+	Failures    int       `json:"failures"`
+	LastFailure time.Time `json:"lastFailure"`
+}
+
+// reconcileBackoffFromService returns the backoff state of the service or nil if it has none or it can't be parsed.
+func reconcileBackoffFromService(service *corev1.Service) *reconcileBackoff {
+	value, ok := service.Annotations[reconcileBackoffAnnotation]
+	if !ok {
+		return nil
+	}
+	backoff := &reconcileBackoff{}
+	if err := json.Unmarshal([]byte(value), backoff); err != nil || backoff.Failures <= 0 {
+		klog.V(2).InfoS("Ignoring invalid reconcile backoff annotation", "service", klog.KObj(service), "value", value)
+		return nil
+	}
+	return backoff
+}
+
+// delay returns the time to wait after the last failure before the service is reconciled again.
+func (b *reconcileBackoff) delay() time.Duration {
+	delay := reconcileBackoffBase
+	for i := 1; i < b.Failures && delay < reconcileBackoffMax; i++ {
+		delay *= 2
+	}
+	return min(delay, reconcileBackoffMax)
+}
+
+// withReconcileBackoff calls reconcile unless the service is still backing off from previous failures.
+// Failures are recorded on the service and reset once reconcile succeeds.
+// Errors of type api.RetryError don't count as failures, they are returned while waiting for the load balancer.
+func (l *LoadBalancer) withReconcileBackoff(
+	ctx context.Context,
+	service *corev1.Service,
+	reconcile func() (*corev1.LoadBalancerStatus, error),
+) (*corev1.LoadBalancerStatus, error) {
+	backoff := reconcileBackoffFromService(service)
+	if backoff != nil {
+		if remaining := backoff.LastFailure.Add(backoff.delay()).Sub(l.now()); remaining > 0 {
+			return nil, api.NewRetryError(
+				fmt.Sprintf("backing off for %s after %d failed reconciliations", remaining.Round(time.Second), backoff.Failures),
+				remaining,
+			)
+		}
+	}
+
+	status, err := reconcile()
+
+	var retryErr *api.RetryError
+	switch {
+	case errors.As(err, &retryErr):
+		return status, err
+	case err != nil:
+		next := &reconcileBackoff{Failures: 1, LastFailure: l.now().UTC()}
+		if backoff != nil {
+			next.Failures = backoff.Failures + 1
+		}
+		if patchErr := l.patchReconcileBackoff(ctx, service, next); patchErr != nil {
+			klog.ErrorS(patchErr, "Failed to persist reconcile backoff", "service", klog.KObj(service))
+		}
+		return status, err
+	case backoff != nil:
+		if patchErr := l.patchReconcileBackoff(ctx, service, nil); patchErr != nil {
+			klog.ErrorS(patchErr, "Failed to reset reconcile backoff", "service", klog.KObj(service))
+		}
+	}
+	return status, nil
+}
+
+// patchReconcileBackoff stores backoff in the annotation of the service or removes it if backoff is nil.
+func (l *LoadBalancer) patchReconcileBackoff(ctx context.Context, service *corev1.Service, backoff *reconcileBackoff) error {
+	var value *string
+	if backoff != nil {
+		data, err := json.Marshal(backoff)
+		if err != nil {
+			return err
+		}
+		value = new(string(data))
+	}
+	return l.patchServiceAnnotation(ctx, service, reconcileBackoffAnnotation, value)
+}
+
+// patchServiceAnnotation sets the annotation key of the service to value or removes it if value is nil.
+// The CCM stores state on the service this way, so that it survives restarts.
+func (l *LoadBalancer) patchServiceAnnotation(ctx context.Context, service *corev1.Service, key string, value *string) error {
+	if l.kubeClient == nil {
+		return nil
+	}
+
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]any{key: value},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = l.kubeClient.CoreV1().Services(service.Namespace).Patch(ctx, service.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
@@ -0,0 +1,117 @@
+package ccm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/cloud-provider/api"
+
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+)
+
+var _ = Describe("Reconcile backoff", func() {
+	var (
+		mockClient *stackitclientmock.MockLoadBalancingClient
+		kubeClient *fake.Clientset
+		lb         *LoadBalancer
+		svc        *corev1.Service
+		now        time.Time
+	)
+
+	BeforeEach(func() {
+		ctrl := gomock.NewController(GinkgoT())
+		mockClient = stackitclientmock.NewMockLoadBalancingClient(ctrl)
+		var err error
+		lb, err = NewLoadBalancer(mockClient, stackitclientmock.NewMockIaaSClient(ctrl), stackitconfig.LoadBalancerOpts{NetworkID: "my-network"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		now = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+		lb.now = func() time.Time { return now }
+
+		svc = minimalLoadBalancerService()
+		svc.Name = "my-service"
+		svc.Namespace = "default"
+		kubeClient = fake.NewClientset(svc)
+		lb.kubeClient = kubeClient
+	})
+
+	getBackoff := func() *reconcileBackoff {
+		s, err := kubeClient.CoreV1().Services(svc.Namespace).Get(context.Background(), svc.Name, metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		return reconcileBackoffFromService(s)
+	}
+
+	setBackoff := func(backoff reconcileBackoff) {
+		data, err := json.Marshal(backoff)
+		Expect(err).NotTo(HaveOccurred())
+		svc.Annotations[reconcileBackoffAnnotation] = string(data)
+	}
+
+	It("should record failed reconciliations on the service", func() {
+		mockClient.EXPECT().GetLoadBalancer(gomock.Any(), gomock.Any()).Return(nil, errors.New("injected error"))
+
+		_, err := lb.EnsureLoadBalancer(context.Background(), "my-cluster", svc, nil)
+		Expect(err).To(MatchError(ContainSubstring("injected error")))
+		Expect(getBackoff()).To(Equal(&reconcileBackoff{Failures: 1, LastFailure: now}))
+	})
+
+	It("should not call the API while backing off", func() {
+		setBackoff(reconcileBackoff{Failures: 3, LastFailure: now.Add(-5 * time.Second)})
+
+		_, err := lb.EnsureLoadBalancer(context.Background(), "my-cluster", svc, nil)
+		var retryErr *api.RetryError
+		Expect(errors.As(err, &retryErr)).To(BeTrue())
+		// The third failure results in a delay of 20s.
+		Expect(retryErr.RetryAfter()).To(Equal(15 * time.Second))
+	})
+
+	It("should increase the failure count after the backoff expired", func() {
+		setBackoff(reconcileBackoff{Failures: 3, LastFailure: now.Add(-time.Minute)})
+		mockClient.EXPECT().GetLoadBalancer(gomock.Any(), gomock.Any()).Return(nil, errors.New("injected error"))
+
+		_, err := lb.EnsureLoadBalancer(context.Background(), "my-cluster", svc, nil)
+		Expect(err).To(MatchError(ContainSubstring("injected error")))
+		Expect(getBackoff()).To(Equal(&reconcileBackoff{Failures: 4, LastFailure: now}))
+	})
+
+	It("should reset the backoff once the reconciliation succeeds", func() {
+		setBackoff(reconcileBackoff{Failures: 3, LastFailure: now.Add(-time.Minute)})
+		_, err := kubeClient.CoreV1().Services(svc.Namespace).Update(context.Background(), svc, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		status, err := lb.withReconcileBackoff(context.Background(), svc, func() (*corev1.LoadBalancerStatus, error) {
+			return &corev1.LoadBalancerStatus{}, nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).NotTo(BeNil())
+		Expect(getBackoff()).To(BeNil())
+	})
+
+	It("should not count retry errors as failures", func() {
+		_, err := lb.withReconcileBackoff(context.Background(), svc, func() (*corev1.LoadBalancerStatus, error) {
+			return nil, api.NewRetryError("waiting", retryDuration)
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(getBackoff()).To(BeNil())
+	})
+
+	It("should cap the delay", func() {
+		Expect((&reconcileBackoff{Failures: 1}).delay()).To(Equal(5 * time.Second))
+		Expect((&reconcileBackoff{Failures: 2}).delay()).To(Equal(10 * time.Second))
+		Expect((&reconcileBackoff{Failures: 100}).delay()).To(Equal(5 * time.Minute))
+	})
+
+	It("should ignore invalid annotations", func() {
+		svc.Annotations[reconcileBackoffAnnotation] = "invalid"
+		Expect(reconcileBackoffFromService(svc)).To(BeNil())
+	})
+})
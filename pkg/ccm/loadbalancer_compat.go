@@ -0,0 +1,158 @@
+package ccm
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// Annotations of other cloud providers that select an internal load balancer.
+	betaInternalLBAnnotation          = "service.beta.kubernetes.io/load-balancer-internal"
+	betaOpenStackInternalLBAnnotation = "service.beta.kubernetes.io/openstack-internal-load-balancer"
+	betaAWSInternalLBAnnotation       = "service.beta.kubernetes.io/aws-load-balancer-internal"
+	betaAzureInternalLBAnnotation     = "service.beta.kubernetes.io/azure-load-balancer-internal"
+	// Annotations of other cloud providers that enable the proxy protocol.
+	betaAWSProxyProtocolAnnotation   = "service.beta.kubernetes.io/aws-load-balancer-proxy-protocol"
+	openStackProxyProtocolAnnotation = "loadbalancer.openstack.org/proxy-protocol"
+	// Annotations of other cloud providers that set the idle timeout of TCP connections.
+	betaAWSIdleTimeoutAnnotation     = "service.beta.kubernetes.io/aws-load-balancer-connection-idle-timeout"
+	openStackClientTimeoutAnnotation = "loadbalancer.openstack.org/timeout-client-data"
+
+	eventReasonCompatAnnotationPresent = "CompatibilityAnnotationPresent"
+)
+
+// compatAnnotation is an annotation of another cloud provider that is translated to a STACKIT annotation,
+// so that manifests written for several clouds work without changes.
+type compatAnnotation struct {
+	name   string
+	target string
+	// translate returns the value of the target annotation.
+	translate func(value string) (string, error)
+}
+
+var compatAnnotations = []compatAnnotation{
+	{name: betaInternalLBAnnotation, target: internalLBAnnotation, translate: translateBool},
+	{name: betaOpenStackInternalLBAnnotation, target: internalLBAnnotation, translate: translateBool},
+	{name: betaAWSInternalLBAnnotation, target: internalLBAnnotation, translate: translateAWSInternal},
+	{name: betaAzureInternalLBAnnotation, target: internalLBAnnotation, translate: translateBool},
+	{name: betaAWSProxyProtocolAnnotation, target: tcpProxyProtocolEnabledAnnotation, translate: translateAWSProxyProtocol},
+	{name: openStackProxyProtocolAnnotation, target: tcpProxyProtocolEnabledAnnotation, translate: translateOpenStackProxyProtocol},
+	{name: betaAWSIdleTimeoutAnnotation, target: tcpIdleTimeoutAnnotation, translate: translateTimeout(time.Second)},
+	{name: openStackClientTimeoutAnnotation, target: tcpIdleTimeoutAnnotation, translate: translateTimeout(time.Millisecond)},
+}
+
+// translateCompatAnnotations returns a copy of the service in which the annotations of other cloud providers are
+// replaced by their STACKIT counterpart and a warning event that lists them. If the service has no such annotations,
+// the service itself is returned. Annotations whose values contradict each other are rejected.
+func translateCompatAnnotations(service *corev1.Service) (*corev1.Service, *Event, []error) {
+	var (
+		translated  *corev1.Service
+		used        []string
+		errs        []error
+		translators = map[string]string{}
+	)
+	for _, a := range compatAnnotations {
+		value, found := service.Annotations[a.name]
+		if !found {
+			continue
+		}
+		targetValue, err := a.translate(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid value %q for annotation %s: %w", value, a.name, err))
+			continue
+		}
+		if translated == nil {
+			translated = service.DeepCopy()
+		}
+		used = append(used, fmt.Sprintf("%s (%s)", a.name, a.target))
+
+		current, found := translated.Annotations[a.target]
+		if !found {
+			translated.Annotations[a.target] = targetValue
+			translators[a.target] = a.name
+			continue
+		}
+		if !sameAnnotationValue(a.target, current, targetValue) {
+			source := a.target
+			if translator, found := translators[a.target]; found {
+				source = translator
+			}
+			errs = append(errs, fmt.Errorf("incompatible values for annotations %s and %s", a.name, source))
+		}
+	}
+	if translated == nil {
+		return service, nil, errs
+	}
+
+	// The maximum event size is 1024 characters, which all annotations together don't exceed.
+	return translated, &Event{
+		Type:   corev1.EventTypeWarning,
+		Reason: eventReasonCompatAnnotationPresent,
+		Message: "The following annotations of other cloud providers are translated to their STACKIT counterpart in parentheses, " +
+			"use the STACKIT annotations instead: " + strings.Join(used, ", "),
+	}, errs
+}
+
+// sameAnnotationValue returns whether two values of the STACKIT annotation target are equivalent.
+func sameAnnotationValue(target, a, b string) bool {
+	switch target {
+	case internalLBAnnotation, tcpProxyProtocolEnabledAnnotation:
+		boolA, errA := strconv.ParseBool(a)
+		boolB, errB := strconv.ParseBool(b)
+		return errA == nil && errB == nil && boolA == boolB
+	case tcpIdleTimeoutAnnotation:
+		durationA, errA := time.ParseDuration(a)
+		durationB, errB := time.ParseDuration(b)
+		return errA == nil && errB == nil && durationA == durationB
+	}
+	return a == b
+}
+
+func translateBool(value string) (string, error) {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatBool(b), nil
+}
+
+// translateAWSInternal accepts the legacy value 0.0.0.0/0 of the AWS provider in addition to booleans.
+func translateAWSInternal(value string) (string, error) {
+	if value == "0.0.0.0/0" {
+		return "true", nil
+	}
+	return translateBool(value)
+}
+
+// translateAWSProxyProtocol accepts "*", the only value supported by the AWS provider, which enables the proxy protocol
+// for all ports.
+func translateAWSProxyProtocol(value string) (string, error) {
+	if value != "*" {
+		return "", fmt.Errorf(`only "*" is supported`)
+	}
+	return "true", nil
+}
+
+// translateOpenStackProxyProtocol accepts booleans and v1, the version of the proxy protocol of STACKIT load balancers.
+func translateOpenStackProxyProtocol(value string) (string, error) {
+	if slices.Contains([]string{"v1", "V1"}, value) {
+		return "true", nil
+	}
+	return translateBool(value)
+}
+
+// translateTimeout returns a translator of integer timeouts in the given unit.
+func translateTimeout(unit time.Duration) func(string) (string, error) {
+	return func(value string) (string, error) {
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return "", err
+		}
+		return (time.Duration(n) * unit).String(), nil
+	}
+}
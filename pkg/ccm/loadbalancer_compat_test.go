@@ -0,0 +1,91 @@
+package ccm
+
+import (
+	"maps"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+	corev1 "k8s.io/api/core/v1"
+
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+)
+
+var _ = Describe("Compatibility annotations", func() {
+	var (
+		svc    *corev1.Service
+		lbOpts stackitconfig.LoadBalancerOpts
+	)
+
+	BeforeEach(func() {
+		svc = minimalLoadBalancerService()
+		svc.Spec.Ports = []corev1.ServicePort{{Name: "http", Protocol: corev1.ProtocolTCP, Port: 80, NodePort: 30080}}
+		lbOpts = stackitconfig.LoadBalancerOpts{NetworkID: "my-network"}
+	})
+
+	DescribeTable("should translate annotations of other providers",
+		func(annotations map[string]string, target, expected string) {
+			for k, v := range annotations {
+				svc.Annotations[k] = v
+			}
+			original := maps.Clone(svc.Annotations)
+			translated, event, errs := translateCompatAnnotations(svc)
+			Expect(errs).To(BeEmpty())
+			Expect(translated.Annotations).To(HaveKeyWithValue(target, expected))
+			Expect(event).NotTo(BeNil())
+			Expect(event.Reason).To(Equal(eventReasonCompatAnnotationPresent))
+			// The service must not be modified.
+			Expect(svc.Annotations).To(Equal(original))
+		},
+		Entry("generic internal", map[string]string{betaInternalLBAnnotation: "true"}, internalLBAnnotation, "true"),
+		Entry("OpenStack internal", map[string]string{betaOpenStackInternalLBAnnotation: "1"}, internalLBAnnotation, "true"),
+		Entry("AWS internal", map[string]string{betaAWSInternalLBAnnotation: "0.0.0.0/0"}, internalLBAnnotation, "true"),
+		Entry("Azure internal", map[string]string{betaAzureInternalLBAnnotation: "false"}, internalLBAnnotation, "false"),
+		Entry("AWS proxy protocol", map[string]string{betaAWSProxyProtocolAnnotation: "*"}, tcpProxyProtocolEnabledAnnotation, "true"),
+		Entry("OpenStack proxy protocol", map[string]string{openStackProxyProtocolAnnotation: "v1"}, tcpProxyProtocolEnabledAnnotation, "true"),
+		Entry("AWS idle timeout", map[string]string{betaAWSIdleTimeoutAnnotation: "120"}, tcpIdleTimeoutAnnotation, "2m0s"),
+		Entry("OpenStack idle timeout", map[string]string{openStackClientTimeoutAnnotation: "50000"}, tcpIdleTimeoutAnnotation, "50s"),
+		Entry("matching STACKIT annotation", map[string]string{betaInternalLBAnnotation: "true", internalLBAnnotation: "1"},
+			internalLBAnnotation, "1"),
+	)
+
+	It("should return the service without annotations of other providers", func() {
+		translated, event, errs := translateCompatAnnotations(svc)
+		Expect(translated).To(BeIdenticalTo(svc))
+		Expect(event).To(BeNil())
+		Expect(errs).To(BeEmpty())
+	})
+
+	DescribeTable("should reject invalid or conflicting annotations",
+		func(annotations map[string]string, expected string) {
+			for k, v := range annotations {
+				svc.Annotations[k] = v
+			}
+			_, _, err := lbSpecFromService(svc, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).To(MatchError(ContainSubstring(expected)))
+		},
+		Entry("invalid bool", map[string]string{betaAzureInternalLBAnnotation: "maybe"},
+			"invalid value \"maybe\" for annotation "+betaAzureInternalLBAnnotation),
+		Entry("unsupported AWS proxy protocol", map[string]string{betaAWSProxyProtocolAnnotation: "80"}, `only "*" is supported`),
+		Entry("unsupported OpenStack proxy protocol", map[string]string{openStackProxyProtocolAnnotation: "v2"},
+			"annotation "+openStackProxyProtocolAnnotation),
+		Entry("conflict with STACKIT annotation", map[string]string{betaInternalLBAnnotation: "true", internalLBAnnotation: "false"},
+			"incompatible values for annotations "+betaInternalLBAnnotation+" and "+internalLBAnnotation),
+		Entry("conflict between providers", map[string]string{betaAWSIdleTimeoutAnnotation: "60", openStackClientTimeoutAnnotation: "1000"},
+			"incompatible values for annotations "+openStackClientTimeoutAnnotation+" and "+betaAWSIdleTimeoutAnnotation),
+	)
+
+	It("should apply translated annotations to the load balancer and report them", func() {
+		svc.Annotations[betaAWSProxyProtocolAnnotation] = "*"
+		svc.Annotations[betaInternalLBAnnotation] = "true"
+		spec, events, err := lbSpecFromService(svc, []*corev1.Node{}, lbOpts, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(spec.Options.PrivateNetworkOnly).To(HaveValue(BeTrue()))
+		Expect(spec.Listeners).To(HaveLen(1))
+		Expect(spec.Listeners[0].Protocol).To(HaveValue(Equal(loadbalancer.LISTENERPROTOCOL_PROTOCOL_TCP_PROXY)))
+		Expect(events).To(ContainElement(And(
+			HaveField("Reason", eventReasonCompatAnnotationPresent),
+			HaveField("Message", And(ContainSubstring(betaAWSProxyProtocolAnnotation), ContainSubstring(betaInternalLBAnnotation))),
+		)))
+	})
+})
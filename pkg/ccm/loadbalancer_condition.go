@@ -0,0 +1,97 @@
+package ccm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	servicehelper "k8s.io/cloud-provider/service/helpers"
+	"k8s.io/klog/v2"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/metrics"
+)
+
+const (
+	// ConditionTypeLoadBalancerSpecValid is set on the status of services and tells whether the load balancer options
+	// of the service, i.e. its annotations and fields, are valid. This allows GitOps tooling to detect misconfigurations
+	// without watching events.
+	ConditionTypeLoadBalancerSpecValid = "LoadBalancerSpecValid"
+
+	ConditionReasonValid       = "Valid"
+	ConditionReasonInvalidSpec = "InvalidSpec"
+
+	// maxConditionMessageLength caps the message of the condition to the maximum size of events, so that a service with
+	// many invalid options doesn't bloat its status.
+	maxConditionMessageLength = 1024
+)
+
+// reportSpecValidity reports the result of the validation of the load balancer options of the service as a warning
+// event and metric if it is invalid and as the LoadBalancerSpecValid condition on the service.
+func (l *LoadBalancer) reportSpecValidity(ctx context.Context, service *corev1.Service, err error) {
+	condition := metav1.Condition{
+		Type:               ConditionTypeLoadBalancerSpecValid,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: service.Generation,
+		Reason:             ConditionReasonValid,
+		Message:            "The load balancer options of the service are valid",
+	}
+	if err != nil {
+		messages := specErrorMessages(err)
+		metrics.LoadBalancerInvalidSpecs.Add(float64(len(messages)))
+		l.recordInvalidSpec(service, messages)
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = ConditionReasonInvalidSpec
+		condition.Message = truncateMessage(strings.Join(messages, "; "), maxConditionMessageLength)
+	}
+
+	if patchErr := l.patchServiceCondition(ctx, service, condition); patchErr != nil {
+		klog.ErrorS(patchErr, "Failed to set condition on service", "service", klog.KObj(service), "condition", condition.Type)
+	}
+}
+
+// specErrorMessages returns the messages of all validation errors contained in err.
+func specErrorMessages(err error) []string {
+	var aggregate utilerrors.Aggregate
+	if !errors.As(err, &aggregate) {
+		return []string{err.Error()}
+	}
+	messages := make([]string, 0, len(aggregate.Errors()))
+	for _, e := range aggregate.Errors() {
+		messages = append(messages, e.Error())
+	}
+	return messages
+}
+
+// patchServiceCondition sets the condition on the status of the service. The API is only called if the condition
+// changed, the transition time is only updated if its status changed.
+func (l *LoadBalancer) patchServiceCondition(ctx context.Context, service *corev1.Service, condition metav1.Condition) error {
+	if l.kubeClient == nil {
+		return nil
+	}
+
+	updated := service.DeepCopy()
+	condition.LastTransitionTime = metav1.NewTime(l.now())
+	if !meta.SetStatusCondition(&updated.Status.Conditions, condition) {
+		return nil
+	}
+	_, err := servicehelper.PatchService(l.kubeClient.CoreV1(), service, updated)
+	return err
+}
+
+// truncateMessage cuts message to at most maxLength bytes without splitting a character.
+func truncateMessage(message string, maxLength int) string {
+	const ellipsis = "..."
+	if len(message) <= maxLength {
+		return message
+	}
+	cut := maxLength - len(ellipsis)
+	for cut > 0 && !utf8.RuneStart(message[cut]) {
+		cut--
+	}
+	return message[:cut] + ellipsis
+}
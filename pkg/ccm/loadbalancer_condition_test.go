@@ -0,0 +1,110 @@
+package ccm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+)
+
+var _ = Describe("LoadBalancerSpecValid condition", func() {
+	var (
+		kubeClient *fake.Clientset
+		recorder   *record.FakeRecorder
+		lb         *LoadBalancer
+		svc        *corev1.Service
+		now        time.Time
+	)
+
+	BeforeEach(func() {
+		var err error
+		lb, err = NewLoadBalancer(nil, nil, stackitconfig.LoadBalancerOpts{NetworkID: "my-network"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		recorder = record.NewFakeRecorder(10)
+		lb.recorder = recorder
+		now = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+		lb.now = func() time.Time { return now }
+
+		svc = minimalLoadBalancerService()
+		svc.Name = "my-service"
+		svc.Namespace = "default"
+		svc.Generation = 3
+		kubeClient = fake.NewClientset(svc)
+		lb.kubeClient = kubeClient
+	})
+
+	getCondition := func() *metav1.Condition {
+		GinkgoHelper()
+		s, err := kubeClient.CoreV1().Services(svc.Namespace).Get(context.Background(), svc.Name, metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		svc = s
+		return meta.FindStatusCondition(s.Status.Conditions, ConditionTypeLoadBalancerSpecValid)
+	}
+
+	It("should set the condition to false with all errors", func() {
+		lb.reportSpecValidity(context.Background(), svc, utilerrors.NewAggregate([]error{
+			errors.New("first error"), errors.New("second error"),
+		}))
+
+		condition := getCondition()
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(ConditionReasonInvalidSpec))
+		Expect(condition.Message).To(Equal("first error; second error"))
+		Expect(condition.ObservedGeneration).To(Equal(int64(3)))
+		Expect(recorder.Events).To(Receive(And(ContainSubstring(EventReasonInvalidSpec), ContainSubstring("first error; second error"))))
+	})
+
+	It("should set the condition to true once the options are fixed", func() {
+		lb.reportSpecValidity(context.Background(), svc, errors.New("invalid"))
+		Expect(getCondition().Status).To(Equal(metav1.ConditionFalse))
+
+		now = now.Add(time.Minute)
+		lb.reportSpecValidity(context.Background(), svc, nil)
+		condition := getCondition()
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal(ConditionReasonValid))
+		Expect(condition.LastTransitionTime.Time).To(BeTemporally("==", now))
+		Expect(recorder.Events).To(HaveLen(1))
+	})
+
+	It("should not patch the service if the condition didn't change", func() {
+		lb.reportSpecValidity(context.Background(), svc, nil)
+		getCondition()
+		kubeClient.ClearActions()
+
+		lb.reportSpecValidity(context.Background(), svc, nil)
+		Expect(kubeClient.Actions()).To(BeEmpty())
+	})
+
+	It("should cap the message", func() {
+		lb.reportSpecValidity(context.Background(), svc, errors.New(strings.Repeat("ä", maxConditionMessageLength)))
+
+		message := getCondition().Message
+		Expect(len(message)).To(BeNumerically("<=", maxConditionMessageLength))
+		Expect(message).To(HaveSuffix("ä..."))
+	})
+
+	It("should keep other conditions", func() {
+		svc.Status.Conditions = []metav1.Condition{{
+			Type: "Other", Status: metav1.ConditionTrue, Reason: "Other", LastTransitionTime: metav1.NewTime(now),
+		}}
+		_, err := kubeClient.CoreV1().Services(svc.Namespace).UpdateStatus(context.Background(), svc, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		lb.reportSpecValidity(context.Background(), svc, nil)
+		getCondition()
+		Expect(svc.Status.Conditions).To(ContainElement(HaveField("Type", "Other")))
+	})
+})
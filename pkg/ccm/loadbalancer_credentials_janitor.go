@@ -0,0 +1,88 @@
+package ccm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/cmp"
+)
+
+// credentialsJanitorInterval is the interval in which all observability credentials of the project are checked for orphans.
+const credentialsJanitorInterval = 30 * time.Minute
+
+// runCredentialsJanitor periodically deletes observability credentials whose load balancer no longer exists.
+// EnsureLoadBalancerDeleted cleans up credentials on a best effort basis,
+// they are left behind if the CCM crashes between updating and deleting the load balancer.
+// It blocks until ctx is done.
+func (l *LoadBalancer) runCredentialsJanitor(ctx context.Context) {
+	// suspects contains the references of orphaned credentials found in the previous run.
+	// Credentials are only deleted if they are orphaned in two consecutive runs,
+	// because new load balancers are created after their credentials.
+	suspects := map[string]bool{}
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		var err error
+		suspects, err = l.cleanUpOrphanedCredentials(ctx, suspects)
+		if err != nil {
+			klog.ErrorS(err, "Failed to clean up orphaned observability credentials")
+		}
+	}, credentialsJanitorInterval)
+}
+
+// cleanUpOrphanedCredentials deletes the orphaned credentials that are contained in suspects
+// and returns the references of all credentials that are orphaned but not deleted yet.
+// Only credentials with the prefix of this cluster are considered.
+func (l *LoadBalancer) cleanUpOrphanedCredentials(ctx context.Context, suspects map[string]bool) (map[string]bool, error) {
+	res, err := l.client.ListCredentials(ctx)
+	if err != nil {
+		return suspects, fmt.Errorf("failed to list credentials: %w", err)
+	}
+	lbs, err := l.client.ListLoadBalancers(ctx)
+	if err != nil {
+		return suspects, fmt.Errorf("failed to list load balancers: %w", err)
+	}
+	inUse := map[string]bool{}
+	for _, lb := range lbs {
+		inUse[l.credentialsName(cmp.UnpackPtr(lb.Name))] = true
+	}
+
+	var (
+		g       errgroup.Group
+		mu      sync.Mutex
+		orphans = map[string]bool{}
+		errs    []error
+	)
+	g.SetLimit(maxConcurrentAPICalls)
+	for _, credentials := range res.Credentials {
+		if credentials.CredentialsRef == nil || credentials.DisplayName == nil ||
+			!strings.HasPrefix(*credentials.DisplayName, l.credentialsPrefix()) || inUse[*credentials.DisplayName] {
+			continue
+		}
+		ref := *credentials.CredentialsRef
+		if !suspects[ref] {
+			orphans[ref] = true
+			continue
+		}
+		g.Go(func() error {
+			err := l.client.DeleteCredentials(ctx, ref)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to delete credentials %q: %w", ref, err))
+				orphans[ref] = true
+				return nil
+			}
+			klog.InfoS("Deleted orphaned observability credentials", "credentialsRef", ref, "displayName", *credentials.DisplayName)
+			return nil
+		})
+	}
+	_ = g.Wait()
+	return orphans, utilerrors.NewAggregate(errs)
+}
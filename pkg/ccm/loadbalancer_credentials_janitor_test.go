@@ -0,0 +1,79 @@
+package ccm
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+	"go.uber.org/mock/gomock"
+
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+)
+
+var _ = Describe("Credentials janitor", func() {
+	var (
+		mockClient *stackitclientmock.MockLoadBalancingClient
+		lb         *LoadBalancer
+	)
+
+	BeforeEach(func() {
+		ctrl := gomock.NewController(GinkgoT())
+		mockClient = stackitclientmock.NewMockLoadBalancingClient(ctrl)
+		var err error
+		lb, err = NewLoadBalancer(mockClient, stackitclientmock.NewMockIaaSClient(ctrl), stackitconfig.LoadBalancerOpts{}, &MetricsRemoteWrite{})
+		Expect(err).NotTo(HaveOccurred())
+
+		mockClient.EXPECT().ListCredentials(gomock.Any()).Return(&loadbalancer.ListCredentialsResponse{
+			Credentials: []loadbalancer.CredentialsResponse{
+				{CredentialsRef: new(sampleCredentialsRef), DisplayName: new(sampleLBName)},
+				{CredentialsRef: new("credentials-other"), DisplayName: new("not-managed-by-the-ccm")},
+			},
+		}, nil).AnyTimes()
+	})
+
+	It("should only mark orphaned credentials in the first run", func() {
+		mockClient.EXPECT().ListLoadBalancers(gomock.Any()).Return(nil, nil)
+
+		orphans, err := lb.cleanUpOrphanedCredentials(context.Background(), map[string]bool{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(orphans).To(Equal(map[string]bool{sampleCredentialsRef: true}))
+	})
+
+	It("should delete credentials that are orphaned in two consecutive runs", func() {
+		mockClient.EXPECT().ListLoadBalancers(gomock.Any()).Return([]loadbalancer.LoadBalancer{{Name: new("other-lb")}}, nil)
+		mockClient.EXPECT().DeleteCredentials(gomock.Any(), sampleCredentialsRef).Return(nil)
+
+		orphans, err := lb.cleanUpOrphanedCredentials(context.Background(), map[string]bool{sampleCredentialsRef: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(orphans).To(BeEmpty())
+	})
+
+	It("should keep credentials of existing load balancers", func() {
+		mockClient.EXPECT().ListLoadBalancers(gomock.Any()).Return([]loadbalancer.LoadBalancer{{Name: new(sampleLBName)}}, nil)
+
+		orphans, err := lb.cleanUpOrphanedCredentials(context.Background(), map[string]bool{sampleCredentialsRef: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(orphans).To(BeEmpty())
+	})
+
+	It("should keep suspects if the load balancers can't be listed", func() {
+		mockClient.EXPECT().ListLoadBalancers(gomock.Any()).Return(nil, errors.New("injected error"))
+
+		orphans, err := lb.cleanUpOrphanedCredentials(context.Background(), map[string]bool{sampleCredentialsRef: true})
+		Expect(err).To(MatchError(ContainSubstring("injected error")))
+		Expect(orphans).To(Equal(map[string]bool{sampleCredentialsRef: true}))
+	})
+
+	It("should only consider credentials of the own cluster", func() {
+		lb.clusterID = "my-cluster"
+		mockClient.EXPECT().ListLoadBalancers(gomock.Any()).Return(nil, nil)
+
+		orphans, err := lb.cleanUpOrphanedCredentials(context.Background(), map[string]bool{})
+		Expect(err).NotTo(HaveOccurred())
+		// sampleCredentialsRef has the display name of a different cluster or of the time before the cluster ID was set.
+		Expect(orphans).To(BeEmpty())
+	})
+})
@@ -0,0 +1,250 @@
+package ccm
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/cmp"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
+)
+
+// maxDeregistrationDelay is the maximum time a removed node is kept as a target.
+const maxDeregistrationDelay = time.Hour
+
+// drainingTarget is a target whose node was removed from the load balancer, e.g. because it is drained or excluded
+// from load balancers, but which is kept in the target pools until its deregistration delay is over.
+type drainingTarget struct {
+	displayName string
+	until       time.Time
+}
+
+// deregistrationDelayFromService returns the deregistration delay of the service, which defaults to the
+// deregistrationDelay of the cloud config.
+func deregistrationDelayFromService(service *corev1.Service, opts stackitconfig.LoadBalancerOpts) (time.Duration, error) {
+	value, found := service.Annotations[deregistrationDelayAnnotation]
+	if !found {
+		return opts.DeregistrationDelay.Duration, nil
+	}
+	delay, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid format for annotation %s: %w", deregistrationDelayAnnotation, err)
+	}
+	if delay < 0 || delay > maxDeregistrationDelay {
+		return 0, fmt.Errorf("annotation %s must be between 0s and %s", deregistrationDelayAnnotation, maxDeregistrationDelay)
+	}
+	return delay, nil
+}
+
+// deregistrationDelay returns the deregistration delay of the node targets of the service.
+// Targets that don't depend on the nodes are removed immediately.
+func (l *LoadBalancer) deregistrationDelay(service *corev1.Service) time.Duration {
+	if usesEndpointTargets(service) || hasStaticTargets(service) {
+		return 0
+	}
+	// An invalid delay is reported by lbSpecFromService.
+	delay, _ := deregistrationDelayFromService(service, l.opts)
+	return delay
+}
+
+// applyDeregistrationDelay adds the targets of the current target pools that are missing in spec back to spec until
+// their deregistration delay is over, so that open connections to removed nodes can finish.
+// An event is emitted when targets start draining. The service controller doesn't update the load balancer again once
+// the delay is over, therefore a timer removes the targets.
+func (l *LoadBalancer) applyDeregistrationDelay(
+	service *corev1.Service, name string, current []loadbalancer.TargetPool, spec *loadbalancer.CreateLoadBalancerPayload,
+) {
+	delay := l.deregistrationDelay(service)
+
+	l.drainingMu.Lock()
+	defer l.drainingMu.Unlock()
+	if delay == 0 {
+		l.forgetDrainingTargetsLocked(name)
+		return
+	}
+
+	desired := sets.New[string]()
+	for _, pool := range spec.TargetPools {
+		for _, target := range pool.Targets {
+			desired.Insert(cmp.UnpackPtr(target.Ip))
+		}
+	}
+
+	now := l.now()
+	draining := l.draining[name]
+	if draining == nil {
+		draining = map[string]drainingTarget{}
+	}
+	seen := sets.New[string]()
+	var started []string
+	for i := range spec.TargetPools {
+		pool := &spec.TargetPools[i]
+		currentPool := findTargetPoolByName(current, cmp.UnpackPtr(pool.Name))
+		if currentPool == nil {
+			continue
+		}
+		for _, target := range currentPool.Targets {
+			ip := cmp.UnpackPtr(target.Ip)
+			if desired.Has(ip) {
+				continue
+			}
+			seen.Insert(ip)
+			t, found := draining[ip]
+			if !found {
+				t = drainingTarget{displayName: cmp.UnpackPtr(target.DisplayName), until: now.Add(delay)}
+				draining[ip] = t
+				started = append(started, t.displayName)
+			}
+			if now.Before(t.until) {
+				pool.Targets = append(pool.Targets, target)
+			}
+		}
+	}
+	// Targets that were already removed or that are desired again, e.g. because the node was uncordoned, don't drain.
+	for ip := range draining {
+		if !seen.Has(ip) {
+			delete(draining, ip)
+		}
+	}
+	if len(draining) == 0 {
+		l.forgetDrainingTargetsLocked(name)
+		return
+	}
+	if l.draining == nil {
+		l.draining = map[string]map[string]drainingTarget{}
+	}
+	l.draining[name] = draining
+
+	if len(started) > 0 {
+		slices.Sort(started)
+		klog.InfoS("Draining targets of load balancer", "service", klog.KObj(service), "loadBalancer", name,
+			"targets", started, "delay", delay)
+		l.recorder.Eventf(service, corev1.EventTypeNormal, EventReasonDrainingTargets,
+			"Draining targets %s for %s before removing them from load balancer %s", strings.Join(started, ", "), delay, name)
+	}
+	l.scheduleDrainedTargetsRemovalLocked(name, 0)
+}
+
+// removeDrainedTargets removes all targets whose deregistration delay is over from the target pools of a load balancer.
+func (l *LoadBalancer) removeDrainedTargets(ctx context.Context, name string) error {
+	l.drainingMu.Lock()
+	now := l.now()
+	expired := sets.New[string]()
+	for ip, t := range l.draining[name] {
+		if !now.Before(t.until) {
+			expired.Insert(ip)
+		}
+	}
+	l.drainingMu.Unlock()
+	if expired.Len() == 0 {
+		return nil
+	}
+
+	lb, err := l.client.GetLoadBalancer(ctx, name)
+	if stackiterrors.IsNotFound(err) {
+		l.forgetDrainingTargets(name)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get load balancer: %w", err)
+	}
+	var pools []loadbalancer.TargetPool
+	for _, pool := range lb.TargetPools {
+		targets := slices.DeleteFunc(slices.Clone(pool.Targets), func(target loadbalancer.Target) bool {
+			return expired.Has(cmp.UnpackPtr(target.Ip))
+		})
+		if len(targets) != len(pool.Targets) {
+			pool.Targets = targets
+			pools = append(pools, pool)
+		}
+	}
+	if err := l.updateTargetPools(ctx, name, pools); err != nil {
+		return err
+	}
+	klog.InfoS("Removed drained targets from load balancer", "loadBalancer", name, "targets", expired.Len())
+
+	l.drainingMu.Lock()
+	defer l.drainingMu.Unlock()
+	for ip := range expired {
+		delete(l.draining[name], ip)
+	}
+	if len(l.draining[name]) == 0 {
+		delete(l.draining, name)
+	}
+	return nil
+}
+
+// scheduleDrainedTargetsRemovalLocked starts a timer that removes the drained targets of a load balancer once the
+// earliest deregistration delay is over. It replaces a previous timer of the load balancer.
+// Targets whose delay is already over are being removed by the caller and only retried after retryAfter, if set.
+// drainingMu must be held.
+func (l *LoadBalancer) scheduleDrainedTargetsRemovalLocked(name string, retryAfter time.Duration) {
+	if timer, found := l.drainingTimers[name]; found {
+		timer.Stop()
+		delete(l.drainingTimers, name)
+	}
+	now := l.now()
+	var after time.Duration
+	for _, t := range l.draining[name] {
+		remaining := t.until.Sub(now)
+		if remaining <= 0 {
+			remaining = retryAfter
+		}
+		if remaining > 0 && (after == 0 || remaining < after) {
+			after = remaining
+		}
+	}
+	if after == 0 {
+		return
+	}
+
+	if l.drainingTimers == nil {
+		l.drainingTimers = map[string]*time.Timer{}
+	}
+	l.drainingTimers[name] = time.AfterFunc(after, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), reconcileTimeout)
+		defer cancel()
+		retryAfter := time.Duration(0)
+		if err := l.removeDrainedTargets(ctx, name); err != nil {
+			klog.ErrorS(err, "Failed to remove drained targets from load balancer", "loadBalancer", name)
+			retryAfter = retryDuration
+		}
+		l.drainingMu.Lock()
+		defer l.drainingMu.Unlock()
+		l.scheduleDrainedTargetsRemovalLocked(name, retryAfter)
+	})
+}
+
+// forgetDrainingTargets stops draining the targets of a load balancer, e.g. because it was deleted.
+func (l *LoadBalancer) forgetDrainingTargets(name string) {
+	l.drainingMu.Lock()
+	defer l.drainingMu.Unlock()
+	l.forgetDrainingTargetsLocked(name)
+}
+
+// forgetDrainingTargetsLocked is forgetDrainingTargets while drainingMu is held.
+func (l *LoadBalancer) forgetDrainingTargetsLocked(name string) {
+	if timer, found := l.drainingTimers[name]; found {
+		timer.Stop()
+		delete(l.drainingTimers, name)
+	}
+	delete(l.draining, name)
+}
+
+// findTargetPoolByName returns the target pool with the given name or nil if there is none.
+func findTargetPoolByName(pools []loadbalancer.TargetPool, name string) *loadbalancer.TargetPool {
+	for i := range pools {
+		if cmp.UnpackPtr(pools[i].Name) == name {
+			return &pools[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package ccm
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/metadata"
+)
+
+var _ = Describe("Deregistration delay", func() {
+	const clusterName = "my-cluster"
+
+	var (
+		mockClient *stackitclientmock.MockLoadBalancingClient
+		recorder   *record.FakeRecorder
+		lb         *LoadBalancer
+		svc        *corev1.Service
+		name       string
+		now        time.Time
+	)
+
+	node := func(name, ip string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     corev1.NodeStatus{Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: ip}}},
+		}
+	}
+	target := func(name, ip string) loadbalancer.Target {
+		return loadbalancer.Target{DisplayName: new(name), Ip: new(ip)}
+	}
+	currentLB := func(targets ...loadbalancer.Target) *loadbalancer.LoadBalancer {
+		return &loadbalancer.LoadBalancer{
+			TargetPools: []loadbalancer.TargetPool{{Name: new("http"), TargetPort: new(int32(30080)), Targets: targets}},
+		}
+	}
+	expectTargets := func(targets ...loadbalancer.Target) {
+		mockClient.EXPECT().UpdateTargetPool(gomock.Any(), name, "http", gomock.Any()).
+			DoAndReturn(func(_ context.Context, _, _ string, payload loadbalancer.UpdateTargetPoolPayload) error {
+				Expect(payload.Targets).To(ConsistOf(targets))
+				return nil
+			})
+	}
+
+	BeforeEach(func() {
+		mockClient = stackitclientmock.NewMockLoadBalancingClient(gomock.NewController(GinkgoT()))
+		var err error
+		lb, err = NewLoadBalancer(mockClient, nil, config.LoadBalancerOpts{
+			NetworkID:           "my-network",
+			DeregistrationDelay: metadata.Duration{Duration: 5 * time.Minute},
+		}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		recorder = record.NewFakeRecorder(10)
+		lb.recorder = recorder
+		now = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		lb.now = func() time.Time { return now }
+		DeferCleanup(func() { lb.forgetDrainingTargets(name) })
+
+		svc = minimalLoadBalancerService()
+		svc.Spec.Ports = []corev1.ServicePort{{Name: "http", Protocol: corev1.ProtocolTCP, Port: 80, NodePort: 30080}}
+		name = lb.GetLoadBalancerName(context.Background(), clusterName, svc)
+	})
+
+	DescribeTable("deregistrationDelayFromService",
+		func(annotation string, expected time.Duration, expectedErr string) {
+			if annotation != "" {
+				svc.Annotations[deregistrationDelayAnnotation] = annotation
+			}
+			delay, err := deregistrationDelayFromService(svc, lb.opts)
+			if expectedErr != "" {
+				Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(delay).To(Equal(expected))
+		},
+		Entry("should default to the cloud config", "", 5*time.Minute, ""),
+		Entry("should use the annotation", "30s", 30*time.Second, ""),
+		Entry("should allow disabling the delay", "0s", time.Duration(0), ""),
+		Entry("should reject invalid durations", "soon", time.Duration(0), "invalid format"),
+		Entry("should reject negative durations", "-1s", time.Duration(0), "must be between"),
+		Entry("should reject durations above the maximum", "2h", time.Duration(0), "must be between"),
+	)
+
+	It("should report an invalid annotation as invalid spec", func() {
+		svc.Annotations[deregistrationDelayAnnotation] = "soon"
+		_, _, err := lbSpecFromService(svc, []*corev1.Node{node("node-a", "10.0.0.1")}, lb.opts, nil)
+		Expect(err).To(MatchError(ContainSubstring(deregistrationDelayAnnotation)))
+	})
+
+	Describe("UpdateLoadBalancer", func() {
+		It("should keep removed nodes until the delay is over", func() {
+			mockClient.EXPECT().GetLoadBalancer(gomock.Any(), name).
+				Return(currentLB(target("node-a", "10.0.0.1"), target("node-b", "10.0.0.2")), nil).Times(2)
+			expectTargets(target("node-a", "10.0.0.1"), target("node-b", "10.0.0.2"))
+			Expect(lb.UpdateLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{node("node-a", "10.0.0.1")})).
+				To(Succeed())
+			Expect(recorder.Events).To(Receive(And(
+				ContainSubstring(EventReasonDrainingTargets), ContainSubstring("node-b"), ContainSubstring("5m0s"),
+			)))
+
+			// The delay is only started once.
+			now = now.Add(time.Minute)
+			expectTargets(target("node-a", "10.0.0.1"), target("node-b", "10.0.0.2"))
+			Expect(lb.UpdateLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{node("node-a", "10.0.0.1")})).
+				To(Succeed())
+			Expect(recorder.Events).NotTo(Receive())
+
+			now = now.Add(5 * time.Minute)
+			mockClient.EXPECT().GetLoadBalancer(gomock.Any(), name).
+				Return(currentLB(target("node-a", "10.0.0.1"), target("node-b", "10.0.0.2")), nil)
+			expectTargets(target("node-a", "10.0.0.1"))
+			Expect(lb.UpdateLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{node("node-a", "10.0.0.1")})).
+				To(Succeed())
+		})
+
+		It("should stop draining nodes that are added again", func() {
+			mockClient.EXPECT().GetLoadBalancer(gomock.Any(), name).
+				Return(currentLB(target("node-a", "10.0.0.1"), target("node-b", "10.0.0.2")), nil).Times(2)
+			expectTargets(target("node-a", "10.0.0.1"), target("node-b", "10.0.0.2"))
+			Expect(lb.UpdateLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{node("node-a", "10.0.0.1")})).
+				To(Succeed())
+
+			expectTargets(target("node-a", "10.0.0.1"), target("node-b", "10.0.0.2"))
+			Expect(lb.UpdateLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{
+				node("node-a", "10.0.0.1"), node("node-b", "10.0.0.2"),
+			})).To(Succeed())
+			Expect(lb.draining).NotTo(HaveKey(name))
+			Expect(lb.drainingTimers).NotTo(HaveKey(name))
+		})
+
+		It("should remove nodes immediately if the delay is disabled", func() {
+			svc.Annotations[deregistrationDelayAnnotation] = "0s"
+			// The mock fails on any call to GetLoadBalancer.
+			expectTargets(target("node-a", "10.0.0.1"))
+			Expect(lb.UpdateLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{node("node-a", "10.0.0.1")})).
+				To(Succeed())
+		})
+
+		It("should not delay static targets", func() {
+			svc.Annotations[staticTargetsAnnotation] = "gateway-1=10.0.0.10"
+			// The mock fails on any call to the API.
+			Expect(lb.UpdateLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})).To(Succeed())
+		})
+	})
+
+	Describe("removeDrainedTargets", func() {
+		BeforeEach(func() {
+			mockClient.EXPECT().GetLoadBalancer(gomock.Any(), name).
+				Return(currentLB(target("node-a", "10.0.0.1"), target("node-b", "10.0.0.2")), nil)
+			expectTargets(target("node-a", "10.0.0.1"), target("node-b", "10.0.0.2"))
+			Expect(lb.UpdateLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{node("node-a", "10.0.0.1")})).
+				To(Succeed())
+		})
+
+		It("should not update the load balancer before the delay is over", func() {
+			// The mock fails on any call to the API.
+			Expect(lb.removeDrainedTargets(context.Background(), name)).To(Succeed())
+			Expect(lb.draining[name]).To(HaveKey("10.0.0.2"))
+		})
+
+		It("should remove targets whose delay is over", func() {
+			now = now.Add(5 * time.Minute)
+			mockClient.EXPECT().GetLoadBalancer(gomock.Any(), name).
+				Return(currentLB(target("node-a", "10.0.0.1"), target("node-b", "10.0.0.2")), nil)
+			expectTargets(target("node-a", "10.0.0.1"))
+
+			Expect(lb.removeDrainedTargets(context.Background(), name)).To(Succeed())
+			Expect(lb.draining).NotTo(HaveKey(name))
+		})
+
+		It("should forget the targets when the load balancer is deleted", func() {
+			lb.forgetDrainingTargets(name)
+			Expect(lb.draining).NotTo(HaveKey(name))
+			Expect(lb.drainingTimers).NotTo(HaveKey(name))
+		})
+	})
+})
@@ -0,0 +1,411 @@
+package ccm
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	discoveryinformers "k8s.io/client-go/informers/discovery/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	discoverylisters "k8s.io/client-go/listers/discovery/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	cloudprovider "k8s.io/cloud-provider"
+	"k8s.io/cloud-provider/api"
+	"k8s.io/klog/v2"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/cmp"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/features"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
+)
+
+const (
+	// EndpointTargetsControllerName is the name of the controller that keeps the targets of load balancers in sync with
+	// the EndpointSlices of their services, if the targets depend on the pods of the service.
+	EndpointTargetsControllerName = "endpoint-targets"
+
+	// endpointTargetsResync is the interval in which all EndpointSlices are checked again.
+	endpointTargetsResync = 10 * time.Minute
+)
+
+// endpointSliceSource provides the EndpointSlices of services to the load balancer implementation.
+type endpointSliceSource struct {
+	lister    discoverylisters.EndpointSliceLister
+	hasSynced cache.InformerSynced
+}
+
+// usesEndpointTargets returns whether the targets of the service depend on its EndpointSlices.
+// This is the case in pod target mode and for services with externalTrafficPolicy Local if the
+// LocalTrafficPolicyTargets feature gate is enabled, unless the service has static targets.
+func usesEndpointTargets(service *corev1.Service) bool {
+	if hasStaticTargets(service) {
+		return false
+	}
+	if mode, _ := targetModeFromService(service); mode == targetModePod {
+		return true
+	}
+	return usesLocalTrafficTargets(service)
+}
+
+// usesLocalTrafficTargets returns whether only nodes with ready pods of the service are targeted.
+func usesLocalTrafficTargets(service *corev1.Service) bool {
+	return service.Spec.ExternalTrafficPolicy == corev1.ServiceExternalTrafficPolicyLocal &&
+		utilfeature.DefaultFeatureGate.Enabled(features.LocalTrafficPolicyTargets)
+}
+
+// applyEndpointTargets adjusts the targets in spec to the EndpointSlices of the service if its targets depend on them.
+// An invalid target mode was already reported by lbSpecFromService.
+func (l *LoadBalancer) applyEndpointTargets(service *corev1.Service, spec *loadbalancer.CreateLoadBalancerPayload) error {
+	if !usesEndpointTargets(service) {
+		return nil
+	}
+	var err error
+	switch mode, _ := targetModeFromService(service); {
+	case mode == targetModePod:
+		err = l.applyPodTargets(service, spec)
+	case usesLocalTrafficTargets(service):
+		err = l.applyLocalTrafficTargets(service, spec)
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to determine targets from EndpointSlices: %w", err)
+	}
+	return nil
+}
+
+// listEndpointSlices returns all EndpointSlices of the service. It returns an api.RetryError until the EndpointSlices
+// are synced, because targets determined from an incomplete cache would remove the targets of the load balancer.
+func (l *LoadBalancer) listEndpointSlices(service *corev1.Service) ([]*discoveryv1.EndpointSlice, error) {
+	source := l.endpointSlices.Load()
+	if source == nil {
+		return nil, fmt.Errorf("the targets of the service depend on its EndpointSlices, which requires the %s controller",
+			EndpointTargetsControllerName)
+	}
+	if !source.hasSynced() {
+		return nil, api.NewRetryError("waiting for the EndpointSlices to be synced", retryDuration)
+	}
+	endpointSlices, err := source.lister.EndpointSlices(service.Namespace).List(
+		labels.SelectorFromSet(labels.Set{discoveryv1.LabelServiceName: service.Name}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list EndpointSlices: %w", err)
+	}
+	return endpointSlices, nil
+}
+
+// applyPodTargets replaces the node targets of all target pools in spec with the ready endpoints of the service.
+// The target port is the port of the pods, i.e. the resolved target port of the service port.
+func (l *LoadBalancer) applyPodTargets(service *corev1.Service, spec *loadbalancer.CreateLoadBalancerPayload) error {
+	endpointSlices, err := l.listEndpointSlices(service)
+	if err != nil {
+		return err
+	}
+
+	for i := range service.Spec.Ports {
+		port := service.Spec.Ports[i]
+		pool := findTargetPool(spec.TargetPools, port)
+		if pool == nil {
+			// The port is invalid and already reported by lbSpecFromService.
+			continue
+		}
+		targetPort, targets, err := podTargetsForPort(endpointSlices, port)
+		if err != nil {
+			return err
+		}
+		pool.TargetPort = new(targetPort)
+		pool.Targets = targets
+	}
+	return nil
+}
+
+// applyLocalTrafficTargets removes all nodes without ready endpoints of the service from the targets in spec.
+// Otherwise the load balancer would send traffic to nodes that drop it because of externalTrafficPolicy Local.
+func (l *LoadBalancer) applyLocalTrafficTargets(service *corev1.Service, spec *loadbalancer.CreateLoadBalancerPayload) error {
+	endpointSlices, err := l.listEndpointSlices(service)
+	if err != nil {
+		return err
+	}
+
+	nodes := sets.New[string]()
+	for _, slice := range endpointSlices {
+		for _, endpoint := range slice.Endpoints {
+			if isReadyEndpoint(endpoint) && endpoint.NodeName != nil {
+				nodes.Insert(sanitizeNodeName(*endpoint.NodeName))
+			}
+		}
+	}
+	for i := range spec.TargetPools {
+		spec.TargetPools[i].Targets = slices.DeleteFunc(spec.TargetPools[i].Targets, func(target loadbalancer.Target) bool {
+			return !nodes.Has(cmp.UnpackPtr(target.DisplayName))
+		})
+	}
+	return nil
+}
+
+// isReadyEndpoint returns whether the endpoint can receive traffic.
+// Endpoints without ready condition must be interpreted as ready.
+func isReadyEndpoint(endpoint discoveryv1.Endpoint) bool {
+	return (endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready) && len(endpoint.Addresses) > 0
+}
+
+// findTargetPool returns the target pool of the service port or nil if there is none.
+func findTargetPool(pools []loadbalancer.TargetPool, port corev1.ServicePort) *loadbalancer.TargetPool {
+	return findTargetPoolByName(pools, targetPoolName(port))
+}
+
+// podTargetsForPort returns the port and the addresses of all ready IPv4 endpoints of the service port.
+// All pods must use the same port, because a target pool only has a single target port.
+func podTargetsForPort(endpointSlices []*discoveryv1.EndpointSlice, port corev1.ServicePort) (int32, []loadbalancer.Target, error) {
+	var targetPort int32
+	targets := []loadbalancer.Target{}
+	for _, slice := range endpointSlices {
+		if slice.AddressType != discoveryv1.AddressTypeIPv4 {
+			continue
+		}
+		var slicePort int32
+		for _, p := range slice.Ports {
+			if cmp.UnpackPtr(p.Name) == port.Name && cmp.UnpackPtr(p.Protocol) == port.Protocol && p.Port != nil {
+				slicePort = *p.Port
+				break
+			}
+		}
+		if slicePort == 0 {
+			continue
+		}
+		for _, endpoint := range slice.Endpoints {
+			if !isReadyEndpoint(endpoint) {
+				continue
+			}
+			if targetPort != 0 && targetPort != slicePort {
+				return 0, nil, fmt.Errorf("the pods of port %d listen on different ports %d and %d, which isn't supported in target mode %q",
+					port.Port, targetPort, slicePort, targetModePod)
+			}
+			targetPort = slicePort
+			name := endpoint.Addresses[0]
+			if endpoint.TargetRef != nil && endpoint.TargetRef.Name != "" {
+				name = endpoint.TargetRef.Name
+			}
+			for _, address := range endpoint.Addresses {
+				targets = append(targets, loadbalancer.Target{
+					DisplayName: new(sanitizeNodeName(name)),
+					Ip:          new(address),
+				})
+			}
+		}
+	}
+	if targetPort == 0 {
+		// There are no ready pods. The target pool keeps the target port of the service,
+		// so that the load balancer is valid until pods become ready.
+		targetPort = int32(port.TargetPort.IntValue())
+		if targetPort == 0 {
+			targetPort = port.Port
+		}
+	}
+	return targetPort, targets, nil
+}
+
+// EndpointTargetsController updates the targets of load balancers whose targets depend on the pods of their service,
+// see usesEndpointTargets, when the EndpointSlices of their services change. The service controller of the cloud
+// provider only reconciles load balancers on service and node changes.
+type EndpointTargetsController struct {
+	loadBalancer         *LoadBalancer
+	serviceLister        corelisters.ServiceLister
+	nodeLister           corelisters.NodeLister
+	servicesSynced       cache.InformerSynced
+	nodesSynced          cache.InformerSynced
+	endpointSlicesSynced cache.InformerSynced
+	queue                workqueue.TypedRateLimitingInterface[string]
+}
+
+// NewEndpointTargetsController creates the controller from the STACKIT cloud provider.
+// It also provides the EndpointSlices to the load balancer implementation.
+func NewEndpointTargetsController(
+	serviceInformer coreinformers.ServiceInformer,
+	endpointSliceInformer discoveryinformers.EndpointSliceInformer,
+	nodeInformer coreinformers.NodeInformer,
+	cloud cloudprovider.Interface,
+) (*EndpointTargetsController, error) {
+	stackitCloud, ok := cloud.(*CloudControllerManager)
+	if !ok {
+		return nil, fmt.Errorf("cloud provider %T is not supported by the %s controller", cloud, EndpointTargetsControllerName)
+	}
+
+	c := &EndpointTargetsController{
+		loadBalancer:         stackitCloud.loadBalancer,
+		serviceLister:        serviceInformer.Lister(),
+		nodeLister:           nodeInformer.Lister(),
+		servicesSynced:       serviceInformer.Informer().HasSynced,
+		nodesSynced:          nodeInformer.Informer().HasSynced,
+		endpointSlicesSynced: endpointSliceInformer.Informer().HasSynced,
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.DefaultTypedControllerRateLimiter[string](),
+			workqueue.TypedRateLimitingQueueConfig[string]{Name: EndpointTargetsControllerName},
+		),
+	}
+	stackitCloud.loadBalancer.endpointSlices.Store(&endpointSliceSource{
+		lister:    endpointSliceInformer.Lister(),
+		hasSynced: endpointSliceInformer.Informer().HasSynced,
+	})
+
+	_, err := endpointSliceInformer.Informer().AddEventHandlerWithResyncPeriod(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueue,
+		UpdateFunc: func(_, newObj any) { c.enqueue(newObj) },
+		DeleteFunc: c.enqueue,
+	}, endpointTargetsResync)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add EndpointSlice event handler: %w", err)
+	}
+
+	return c, nil
+}
+
+// enqueue adds the service of an EndpointSlice to the queue.
+func (c *EndpointTargetsController) enqueue(obj any) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	slice, ok := obj.(*discoveryv1.EndpointSlice)
+	if !ok {
+		utilruntime.HandleError(fmt.Errorf("unexpected object of type %T", obj))
+		return
+	}
+	serviceName := slice.Labels[discoveryv1.LabelServiceName]
+	if serviceName == "" {
+		return
+	}
+	c.queue.Add(slice.Namespace + "/" + serviceName)
+}
+
+// Run starts the workers and blocks until ctx is cancelled.
+func (c *EndpointTargetsController) Run(ctx context.Context, workers int) {
+	defer utilruntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	klog.InfoS("Starting controller", "controller", EndpointTargetsControllerName)
+	defer klog.InfoS("Shutting down controller", "controller", EndpointTargetsControllerName)
+
+	if !cache.WaitForCacheSync(ctx.Done(), c.servicesSynced, c.nodesSynced, c.endpointSlicesSynced) {
+		return
+	}
+
+	for range workers {
+		go wait.UntilWithContext(ctx, c.runWorker, time.Second)
+	}
+
+	<-ctx.Done()
+}
+
+func (c *EndpointTargetsController) runWorker(ctx context.Context) {
+	for c.processNextItem(ctx) {
+	}
+}
+
+func (c *EndpointTargetsController) processNextItem(ctx context.Context) bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	if err := c.syncService(ctx, key); err != nil {
+		utilruntime.HandleError(fmt.Errorf("failed to sync endpoint targets of service %q: %w", key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+// syncService updates the target pools of the load balancer of a service whose targets depend on its EndpointSlices.
+// Load balancers that don't exist yet are created by the service controller.
+func (c *EndpointTargetsController) syncService(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	service, err := c.serviceLister.Services(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if service.Spec.Type != corev1.ServiceTypeLoadBalancer {
+		return nil
+	}
+	if !usesEndpointTargets(service) || !c.loadBalancer.reconciles(service) {
+		return nil
+	}
+
+	l := c.loadBalancer
+	lbName := l.GetLoadBalancerName(ctx, "", service)
+	lb, err := l.client.GetLoadBalancer(ctx, lbName)
+	if stackiterrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get load balancer: %w", err)
+	}
+
+	nodes, err := c.readyNodes()
+	if err != nil {
+		return err
+	}
+	spec, _, err := lbSpecFromService(service, nodes, l.opts, nil)
+	if err != nil {
+		// Invalid services are reported by the service controller.
+		klog.V(4).InfoS("Skipping endpoint targets of invalid service", "service", klog.KObj(service), "err", err)
+		return nil
+	}
+	if err := l.applyEndpointTargets(service, spec); err != nil {
+		return err
+	}
+
+	var outdated []loadbalancer.TargetPool
+	for _, pool := range spec.TargetPools {
+		if !targetPoolUpToDate(lb.TargetPools, pool) {
+			outdated = append(outdated, pool)
+		}
+	}
+	if len(outdated) == 0 {
+		return nil
+	}
+	klog.V(2).InfoS("Updating endpoint targets of load balancer", "service", klog.KObj(service), "targetPools", len(outdated))
+	return l.updateTargetPools(ctx, lbName, outdated)
+}
+
+// readyNodes returns all nodes with a ready condition, which are candidates for targets in node target mode.
+// Nodes that are excluded from load balancers are skipped by lbSpecFromService.
+func (c *EndpointTargetsController) readyNodes() ([]*corev1.Node, error) {
+	nodes, err := c.nodeLister.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	return slices.DeleteFunc(nodes, func(node *corev1.Node) bool { return !nodeReady(node) }), nil
+}
+
+// targetPoolUpToDate returns whether pools contain a target pool with the same target port and targets as desired.
+func targetPoolUpToDate(pools []loadbalancer.TargetPool, desired loadbalancer.TargetPool) bool {
+	for _, pool := range pools {
+		if !cmp.PtrValEqual(pool.Name, desired.Name) {
+			continue
+		}
+		return cmp.PtrValEqual(pool.TargetPort, desired.TargetPort) &&
+			cmp.SliceEqualUnordered(pool.Targets, desired.Targets, func(a, b loadbalancer.Target) bool {
+				return cmp.PtrValEqual(a.DisplayName, b.DisplayName) && cmp.PtrValEqual(a.Ip, b.Ip)
+			})
+	}
+	return false
+}
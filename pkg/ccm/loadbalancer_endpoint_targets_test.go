@@ -0,0 +1,307 @@
+package ccm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	oapiError "github.com/stackitcloud/stackit-sdk-go/core/oapierror"
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/cloud-provider/api"
+	featuregatetesting "k8s.io/component-base/featuregate/testing"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/features"
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+)
+
+var _ = Describe("Endpoint targets", func() {
+	var (
+		mockClient      *stackitclientmock.MockLoadBalancingClient
+		lb              *LoadBalancer
+		controller      *EndpointTargetsController
+		informerFactory informers.SharedInformerFactory
+		svc             *corev1.Service
+	)
+
+	newSlice := func(name string, port int32, endpoints ...discoveryv1.Endpoint) *discoveryv1.EndpointSlice {
+		return &discoveryv1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: svc.Namespace,
+				Labels:    map[string]string{discoveryv1.LabelServiceName: svc.Name},
+			},
+			AddressType: discoveryv1.AddressTypeIPv4,
+			Ports:       []discoveryv1.EndpointPort{{Name: new("http"), Protocol: new(corev1.ProtocolTCP), Port: new(port)}},
+			Endpoints:   endpoints,
+		}
+	}
+	endpoint := func(pod, ip string, ready bool) discoveryv1.Endpoint {
+		return discoveryv1.Endpoint{
+			Addresses:  []string{ip},
+			Conditions: discoveryv1.EndpointConditions{Ready: new(ready)},
+			TargetRef:  &corev1.ObjectReference{Kind: "Pod", Name: pod},
+		}
+	}
+	localEndpoint := func(node, ip string) discoveryv1.Endpoint {
+		return discoveryv1.Endpoint{
+			Addresses:  []string{ip},
+			Conditions: discoveryv1.EndpointConditions{Ready: new(true)},
+			NodeName:   new(node),
+		}
+	}
+	readyNode := func(name, ip string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: corev1.NodeStatus{
+				Addresses:  []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: ip}},
+				Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+			},
+		}
+	}
+	addSlices := func(slices ...*discoveryv1.EndpointSlice) {
+		for _, slice := range slices {
+			Expect(informerFactory.Discovery().V1().EndpointSlices().Informer().GetIndexer().Add(slice)).To(Succeed())
+		}
+	}
+
+	BeforeEach(func() {
+		mockClient = stackitclientmock.NewMockLoadBalancingClient(gomock.NewController(GinkgoT()))
+		var err error
+		lb, err = NewLoadBalancer(mockClient, nil, config.LoadBalancerOpts{NetworkID: "my-network"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		svc = minimalLoadBalancerService()
+		svc.Name = "my-service"
+		svc.Namespace = "default"
+		svc.Annotations[targetModeAnnotation] = "pod"
+		svc.Spec.Ports = []corev1.ServicePort{{
+			Name: "http", Protocol: corev1.ProtocolTCP, Port: 80, TargetPort: intstr.FromString("web"), NodePort: 30080,
+		}}
+
+		informerFactory = informers.NewSharedInformerFactory(fake.NewClientset(), 0)
+		controller, err = NewEndpointTargetsController(
+			informerFactory.Core().V1().Services(),
+			informerFactory.Discovery().V1().EndpointSlices(),
+			informerFactory.Core().V1().Nodes(),
+			&CloudControllerManager{loadBalancer: lb},
+		)
+		Expect(err).NotTo(HaveOccurred())
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		informerFactory.Start(ctx.Done())
+		informerFactory.WaitForCacheSync(ctx.Done())
+		Expect(informerFactory.Core().V1().Services().Informer().GetIndexer().Add(svc)).To(Succeed())
+	})
+
+	Describe("applyPodTargets", func() {
+		It("should target the ready pods of all EndpointSlices", func() {
+			addSlices(
+				newSlice("slice-a", 8080, endpoint("pod-a", "100.64.0.1", true), endpoint("pod-b", "100.64.0.2", false)),
+				newSlice("slice-b", 8080, endpoint("pod-c", "100.64.1.1", true)),
+			)
+			spec, _, err := lbSpecFromService(svc, nil, lb.opts, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(lb.applyPodTargets(svc, spec)).To(Succeed())
+			Expect(spec.TargetPools).To(HaveLen(1))
+			Expect(spec.TargetPools[0].TargetPort).To(PointTo(BeEquivalentTo(8080)))
+			Expect(spec.TargetPools[0].Targets).To(ConsistOf(
+				loadbalancer.Target{DisplayName: new("pod-a"), Ip: new("100.64.0.1")},
+				loadbalancer.Target{DisplayName: new("pod-c"), Ip: new("100.64.1.1")},
+			))
+		})
+
+		It("should reject pods that listen on different ports", func() {
+			addSlices(
+				newSlice("slice-a", 8080, endpoint("pod-a", "100.64.0.1", true)),
+				newSlice("slice-b", 9090, endpoint("pod-b", "100.64.0.2", true)),
+			)
+			spec, _, err := lbSpecFromService(svc, nil, lb.opts, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(lb.applyPodTargets(svc, spec)).To(MatchError(ContainSubstring("listen on different ports")))
+		})
+
+		It("should fail without the pod targets controller", func() {
+			lb.endpointSlices.Store(nil)
+			spec, _, err := lbSpecFromService(svc, nil, lb.opts, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(lb.applyPodTargets(svc, spec)).To(MatchError(ContainSubstring("requires the endpoint-targets controller")))
+		})
+
+		It("should retry until the EndpointSlices are synced", func() {
+			unsyncedFactory := informers.NewSharedInformerFactory(fake.NewClientset(), 0)
+			_, err := NewEndpointTargetsController(
+				unsyncedFactory.Core().V1().Services(),
+				unsyncedFactory.Discovery().V1().EndpointSlices(),
+				unsyncedFactory.Core().V1().Nodes(),
+				&CloudControllerManager{loadBalancer: lb},
+			)
+			Expect(err).NotTo(HaveOccurred())
+			spec, _, err := lbSpecFromService(svc, nil, lb.opts, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			var retryErr *api.RetryError
+			Expect(errors.As(lb.applyPodTargets(svc, spec), &retryErr)).To(BeTrue())
+		})
+	})
+
+	Describe("applyLocalTrafficTargets", func() {
+		It("should only target nodes with ready pods", func() {
+			svc.Spec.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyLocal
+			notReady := localEndpoint("node-c", "100.64.2.1")
+			notReady.Conditions.Ready = new(false)
+			addSlices(newSlice("slice-a", 8080, localEndpoint("node-a", "100.64.0.1"), notReady))
+			spec, _, err := lbSpecFromService(svc, []*corev1.Node{
+				readyNode("node-a", "10.0.0.1"), readyNode("node-b", "10.0.0.2"), readyNode("node-c", "10.0.0.3"),
+			}, lb.opts, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(lb.applyLocalTrafficTargets(svc, spec)).To(Succeed())
+			Expect(spec.TargetPools[0].TargetPort).To(PointTo(BeEquivalentTo(30080)))
+			Expect(spec.TargetPools[0].Targets).To(ConsistOf(loadbalancer.Target{DisplayName: new("node-a"), Ip: new("10.0.0.1")}))
+		})
+
+		It("should keep the nodes and retry until the EndpointSlices are synced", func() {
+			svc.Spec.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyLocal
+			unsyncedFactory := informers.NewSharedInformerFactory(fake.NewClientset(), 0)
+			_, err := NewEndpointTargetsController(
+				unsyncedFactory.Core().V1().Services(),
+				unsyncedFactory.Discovery().V1().EndpointSlices(),
+				unsyncedFactory.Core().V1().Nodes(),
+				&CloudControllerManager{loadBalancer: lb},
+			)
+			Expect(err).NotTo(HaveOccurred())
+			spec, _, err := lbSpecFromService(svc, []*corev1.Node{readyNode("node-a", "10.0.0.1")}, lb.opts, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			var retryErr *api.RetryError
+			Expect(errors.As(lb.applyLocalTrafficTargets(svc, spec), &retryErr)).To(BeTrue())
+			Expect(spec.TargetPools[0].Targets).To(ConsistOf(loadbalancer.Target{DisplayName: new("node-a"), Ip: new("10.0.0.1")}))
+		})
+	})
+
+	Describe("syncService", func() {
+		It("should update target pools whose pods changed", func() {
+			addSlices(newSlice("slice-a", 8080, endpoint("pod-a", "100.64.0.1", true)))
+			name := lb.GetLoadBalancerName(context.Background(), "", svc)
+			mockClient.EXPECT().GetLoadBalancer(gomock.Any(), name).Return(&loadbalancer.LoadBalancer{
+				TargetPools: []loadbalancer.TargetPool{{
+					Name:       new("http"),
+					TargetPort: new(int32(8080)),
+					Targets:    []loadbalancer.Target{{DisplayName: new("pod-old"), Ip: new("100.64.9.9")}},
+				}},
+			}, nil)
+			mockClient.EXPECT().UpdateTargetPool(gomock.Any(), name, "http", gomock.Any()).
+				DoAndReturn(func(_ context.Context, _, _ string, payload loadbalancer.UpdateTargetPoolPayload) error {
+					Expect(payload.Targets).To(ConsistOf(loadbalancer.Target{DisplayName: new("pod-a"), Ip: new("100.64.0.1")}))
+					return nil
+				})
+
+			Expect(controller.syncService(context.Background(), "default/my-service")).To(Succeed())
+		})
+
+		It("should not update target pools that are up to date", func() {
+			addSlices(newSlice("slice-a", 8080, endpoint("pod-a", "100.64.0.1", true)))
+			mockClient.EXPECT().GetLoadBalancer(gomock.Any(), gomock.Any()).Return(&loadbalancer.LoadBalancer{
+				TargetPools: []loadbalancer.TargetPool{{
+					Name:       new("http"),
+					TargetPort: new(int32(8080)),
+					Targets:    []loadbalancer.Target{{DisplayName: new("pod-a"), Ip: new("100.64.0.1")}},
+				}},
+			}, nil)
+
+			Expect(controller.syncService(context.Background(), "default/my-service")).To(Succeed())
+		})
+
+		It("should leave load balancers that don't exist yet to the service controller", func() {
+			mockClient.EXPECT().GetLoadBalancer(gomock.Any(), gomock.Any()).
+				Return(nil, &oapiError.GenericOpenAPIError{StatusCode: http.StatusNotFound})
+
+			Expect(controller.syncService(context.Background(), "default/my-service")).To(Succeed())
+		})
+
+		It("should update the nodes of services with externalTrafficPolicy Local", func() {
+			featuregatetesting.SetFeatureGateDuringTest(GinkgoT(), utilfeature.DefaultFeatureGate, features.LocalTrafficPolicyTargets, true)
+			delete(svc.Annotations, targetModeAnnotation)
+			svc.Spec.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyLocal
+			for _, node := range []*corev1.Node{readyNode("node-a", "10.0.0.1"), readyNode("node-b", "10.0.0.2")} {
+				Expect(informerFactory.Core().V1().Nodes().Informer().GetIndexer().Add(node)).To(Succeed())
+			}
+			addSlices(newSlice("slice-a", 8080, localEndpoint("node-b", "100.64.0.1")))
+			mockClient.EXPECT().GetLoadBalancer(gomock.Any(), gomock.Any()).Return(&loadbalancer.LoadBalancer{
+				TargetPools: []loadbalancer.TargetPool{{
+					Name:       new("http"),
+					TargetPort: new(int32(30080)),
+					Targets: []loadbalancer.Target{
+						{DisplayName: new("node-a"), Ip: new("10.0.0.1")},
+						{DisplayName: new("node-b"), Ip: new("10.0.0.2")},
+					},
+				}},
+			}, nil)
+			mockClient.EXPECT().UpdateTargetPool(gomock.Any(), gomock.Any(), "http", gomock.Any()).
+				DoAndReturn(func(_ context.Context, _, _ string, payload loadbalancer.UpdateTargetPoolPayload) error {
+					Expect(payload.TargetPort).To(PointTo(BeEquivalentTo(30080)))
+					Expect(payload.Targets).To(ConsistOf(loadbalancer.Target{DisplayName: new("node-b"), Ip: new("10.0.0.2")}))
+					return nil
+				})
+
+			Expect(controller.syncService(context.Background(), "default/my-service")).To(Succeed())
+		})
+
+		It("should not target nodes that are excluded from load balancers", func() {
+			featuregatetesting.SetFeatureGateDuringTest(GinkgoT(), utilfeature.DefaultFeatureGate, features.LocalTrafficPolicyTargets, true)
+			delete(svc.Annotations, targetModeAnnotation)
+			svc.Spec.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyLocal
+			excluded := readyNode("node-b", "10.0.0.2")
+			excluded.Labels = map[string]string{corev1.LabelNodeExcludeBalancers: "true"}
+			for _, node := range []*corev1.Node{readyNode("node-a", "10.0.0.1"), excluded} {
+				Expect(informerFactory.Core().V1().Nodes().Informer().GetIndexer().Add(node)).To(Succeed())
+			}
+			addSlices(newSlice("slice-a", 8080, localEndpoint("node-a", "100.64.0.1"), localEndpoint("node-b", "100.64.0.2")))
+			mockClient.EXPECT().GetLoadBalancer(gomock.Any(), gomock.Any()).Return(&loadbalancer.LoadBalancer{
+				TargetPools: []loadbalancer.TargetPool{{
+					Name:       new("http"),
+					TargetPort: new(int32(30080)),
+					Targets: []loadbalancer.Target{
+						{DisplayName: new("node-a"), Ip: new("10.0.0.1")},
+						{DisplayName: new("node-b"), Ip: new("10.0.0.2")},
+					},
+				}},
+			}, nil)
+			mockClient.EXPECT().UpdateTargetPool(gomock.Any(), gomock.Any(), "http", gomock.Any()).
+				DoAndReturn(func(_ context.Context, _, _ string, payload loadbalancer.UpdateTargetPoolPayload) error {
+					Expect(payload.Targets).To(ConsistOf(loadbalancer.Target{DisplayName: new("node-a"), Ip: new("10.0.0.1")}))
+					return nil
+				})
+
+			Expect(controller.syncService(context.Background(), "default/my-service")).To(Succeed())
+		})
+
+		It("should ignore services with externalTrafficPolicy Local if the feature gate is disabled", func() {
+			delete(svc.Annotations, targetModeAnnotation)
+			svc.Spec.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyLocal
+
+			Expect(controller.syncService(context.Background(), "default/my-service")).To(Succeed())
+		})
+
+		It("should ignore services in node target mode", func() {
+			delete(svc.Annotations, targetModeAnnotation)
+
+			Expect(controller.syncService(context.Background(), "default/my-service")).To(Succeed())
+		})
+	})
+})
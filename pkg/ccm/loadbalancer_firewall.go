@@ -0,0 +1,134 @@
+package ccm
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/cmp"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+	"k8s.io/klog/v2"
+)
+
+const (
+	securityGroupRuleDirectionIngress = "ingress"
+	securityGroupRuleEthertypeIPv4    = "IPv4"
+	securityGroupRuleEthertypeIPv6    = "IPv6"
+	securityGroupRuleAnyIPv4          = "0.0.0.0/0"
+)
+
+// nodePortRule is an ingress rule that opens a single node port of a load balancer for one source range.
+type nodePortRule struct {
+	protocol string
+	port     int64
+	ipRange  string
+}
+
+// nodePortRules returns the rules required to reach the node ports of all listeners in spec.
+// The rules are limited to the allowed source ranges of the load balancer, if any.
+func nodePortRules(spec *loadbalancer.CreateLoadBalancerPayload) []nodePortRule {
+	ipRanges := cmp.UnpackPtr(cmp.UnpackPtr(spec.Options).AccessControl).AllowedSourceRanges
+	if len(ipRanges) == 0 {
+		ipRanges = []string{securityGroupRuleAnyIPv4}
+	}
+
+	var rules []nodePortRule
+	for _, listener := range spec.Listeners {
+		poolIdx := slices.IndexFunc(spec.TargetPools, func(pool loadbalancer.TargetPool) bool {
+			return cmp.PtrValEqual(pool.Name, listener.TargetPool)
+		})
+		if poolIdx < 0 || spec.TargetPools[poolIdx].TargetPort == nil {
+			continue
+		}
+		protocol := "tcp"
+		if cmp.UnpackPtr(listener.Protocol) == loadbalancer.LISTENERPROTOCOL_PROTOCOL_UDP {
+			protocol = "udp"
+		}
+		for _, ipRange := range ipRanges {
+			rule := nodePortRule{protocol: protocol, port: int64(*spec.TargetPools[poolIdx].TargetPort), ipRange: ipRange}
+			if !slices.Contains(rules, rule) {
+				rules = append(rules, rule)
+			}
+		}
+	}
+	return rules
+}
+
+// ethertype returns the ethertype of security group rules for the address family of ipRange.
+func (r nodePortRule) ethertype() string {
+	if prefix, err := netip.ParsePrefix(r.ipRange); err == nil && prefix.Addr().Is6() {
+		return securityGroupRuleEthertypeIPv6
+	}
+	return securityGroupRuleEthertypeIPv4
+}
+
+// ruleFromSecurityGroupRule converts an existing rule. ok is false for rules that don't open exactly one port.
+func ruleFromSecurityGroupRule(rule *iaas.SecurityGroupRule) (r nodePortRule, ok bool) {
+	if rule.PortRange == nil || rule.PortRange.Min != rule.PortRange.Max {
+		return nodePortRule{}, false
+	}
+	return nodePortRule{
+		protocol: cmp.UnpackPtr(cmp.UnpackPtr(rule.Protocol).Name),
+		port:     rule.PortRange.Min,
+		ipRange:  cmp.UnpackPtr(rule.IpRange),
+	}, true
+}
+
+// reconcileNodePortRules ensures that the node security group contains exactly the rules for the node ports of spec.
+// Rules are owned by the load balancer via their description, which is set to the load balancer name.
+// It is a no-op if no node security group is configured.
+func (l *LoadBalancer) reconcileNodePortRules(ctx context.Context, name string, spec *loadbalancer.CreateLoadBalancerPayload) error {
+	securityGroupID := l.opts.NodeSecurityGroupID
+	if securityGroupID == "" {
+		return nil
+	}
+
+	existing, err := l.iaasClient.ListSecurityGroupRules(ctx, securityGroupID)
+	if err != nil {
+		return fmt.Errorf("failed to list rules of security group %q: %w", securityGroupID, err)
+	}
+
+	desired := nodePortRules(spec)
+	var present []nodePortRule
+	for i := range existing {
+		rule := &existing[i]
+		if cmp.UnpackPtr(rule.Description) != name {
+			continue
+		}
+		r, ok := ruleFromSecurityGroupRule(rule)
+		if ok && slices.Contains(desired, r) && !slices.Contains(present, r) {
+			present = append(present, r)
+			continue
+		}
+		klog.V(4).InfoS("Deleting security group rule of load balancer", "rule", cmp.UnpackPtr(rule.Id), "loadBalancer", name)
+		if err := l.iaasClient.DeleteSecurityGroupRule(ctx, securityGroupID, *rule.Id); stackiterrors.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete security group rule %q: %w", *rule.Id, err)
+		}
+	}
+
+	for _, r := range desired {
+		if slices.Contains(present, r) {
+			continue
+		}
+		payload := iaas.CreateSecurityGroupRulePayload{
+			Description: new(name),
+			Direction:   securityGroupRuleDirectionIngress,
+			Ethertype:   new(r.ethertype()),
+			IpRange:     new(r.ipRange),
+			PortRange:   &iaas.PortRange{Min: r.port, Max: r.port},
+			Protocol:    new(iaas.StringAsCreateProtocol(new(r.protocol))),
+		}
+		if _, err := l.iaasClient.CreateSecurityGroupRule(ctx, securityGroupID, payload); err != nil {
+			return fmt.Errorf("failed to create security group rule for node port %d/%s: %w", r.port, r.protocol, err)
+		}
+	}
+	return nil
+}
+
+// deleteNodePortRules removes all rules of the load balancer from the node security group.
+func (l *LoadBalancer) deleteNodePortRules(ctx context.Context, name string) error {
+	return l.reconcileNodePortRules(ctx, name, &loadbalancer.CreateLoadBalancerPayload{})
+}
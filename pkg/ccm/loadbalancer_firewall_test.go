@@ -0,0 +1,127 @@
+package ccm
+
+import (
+	"context"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+	oapiError "github.com/stackitcloud/stackit-sdk-go/core/oapierror"
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("Node port security group rules", func() {
+	const securityGroupID = "node-security-group"
+
+	var (
+		mockIaaSClient *stackitclientmock.MockIaaSClient
+		lb             *LoadBalancer
+		spec           *loadbalancer.CreateLoadBalancerPayload
+	)
+
+	rule := func(id, description, protocol string, port int64, ipRange string) iaas.SecurityGroupRule {
+		return iaas.SecurityGroupRule{
+			Id:          new(id),
+			Description: new(description),
+			Direction:   "ingress",
+			IpRange:     new(ipRange),
+			PortRange:   &iaas.PortRange{Min: port, Max: port},
+			Protocol:    &iaas.Protocol{Name: new(protocol)},
+		}
+	}
+
+	BeforeEach(func() {
+		ctrl := gomock.NewController(GinkgoT())
+		mockIaaSClient = stackitclientmock.NewMockIaaSClient(ctrl)
+		var err error
+		lb, err = NewLoadBalancer(stackitclientmock.NewMockLoadBalancingClient(ctrl), mockIaaSClient, stackitconfig.LoadBalancerOpts{
+			NetworkID:           "my-network",
+			NodeSecurityGroupID: securityGroupID,
+		}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		spec = &loadbalancer.CreateLoadBalancerPayload{
+			Listeners: []loadbalancer.Listener{
+				{TargetPool: new("port-tcp-80"), Protocol: new(loadbalancer.LISTENERPROTOCOL_PROTOCOL_TCP)},
+				{TargetPool: new("port-udp-53"), Protocol: new(loadbalancer.LISTENERPROTOCOL_PROTOCOL_UDP)},
+			},
+			TargetPools: []loadbalancer.TargetPool{
+				{Name: new("port-tcp-80"), TargetPort: new(int32(30080))},
+				{Name: new("port-udp-53"), TargetPort: new(int32(30053))},
+			},
+			Options: &loadbalancer.LoadBalancerOptions{
+				AccessControl: &loadbalancer.LoadbalancerOptionAccessControl{
+					AllowedSourceRanges: []string{"10.0.0.0/8"},
+				},
+			},
+		}
+	})
+
+	It("should open only the node ports of the listeners for the allowed source ranges", func() {
+		Expect(nodePortRules(spec)).To(ConsistOf(
+			nodePortRule{protocol: "tcp", port: 30080, ipRange: "10.0.0.0/8"},
+			nodePortRule{protocol: "udp", port: 30053, ipRange: "10.0.0.0/8"},
+		))
+	})
+
+	It("should open the node ports for everyone if no source ranges are set", func() {
+		spec.Options = nil
+		Expect(nodePortRules(spec)).To(ContainElement(nodePortRule{protocol: "tcp", port: 30080, ipRange: "0.0.0.0/0"}))
+	})
+
+	It("should create missing rules and delete rules of removed listeners", func() {
+		mockIaaSClient.EXPECT().ListSecurityGroupRules(gomock.Any(), securityGroupID).Return([]iaas.SecurityGroupRule{
+			rule("keep", sampleLBName, "tcp", 30080, "10.0.0.0/8"),
+			rule("stale", sampleLBName, "tcp", 30443, "10.0.0.0/8"),
+			rule("foreign", "other-lb", "tcp", 30999, "10.0.0.0/8"),
+		}, nil)
+		mockIaaSClient.EXPECT().DeleteSecurityGroupRule(gomock.Any(), securityGroupID, "stale").Return(nil)
+		mockIaaSClient.EXPECT().CreateSecurityGroupRule(gomock.Any(), securityGroupID, gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ string, payload iaas.CreateSecurityGroupRulePayload) (*iaas.SecurityGroupRule, error) {
+				Expect(*payload.Description).To(Equal(sampleLBName))
+				Expect(payload.Direction).To(Equal("ingress"))
+				Expect(*payload.IpRange).To(Equal("10.0.0.0/8"))
+				Expect(*payload.PortRange).To(Equal(iaas.PortRange{Min: 30053, Max: 30053}))
+				Expect(*payload.Protocol.String).To(Equal("udp"))
+				Expect(*payload.Ethertype).To(Equal("IPv4"))
+				return &iaas.SecurityGroupRule{Id: new("new")}, nil
+			})
+
+		Expect(lb.reconcileNodePortRules(context.Background(), sampleLBName, spec)).To(Succeed())
+	})
+
+	It("should create rules for IPv6 source ranges with the IPv6 ethertype", func() {
+		spec.Listeners = spec.Listeners[:1]
+		spec.Options.AccessControl.AllowedSourceRanges = []string{"2001:db8::/32"}
+		mockIaaSClient.EXPECT().ListSecurityGroupRules(gomock.Any(), securityGroupID).Return(nil, nil)
+		mockIaaSClient.EXPECT().CreateSecurityGroupRule(gomock.Any(), securityGroupID, gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ string, payload iaas.CreateSecurityGroupRulePayload) (*iaas.SecurityGroupRule, error) {
+				Expect(*payload.IpRange).To(Equal("2001:db8::/32"))
+				Expect(*payload.Ethertype).To(Equal("IPv6"))
+				return &iaas.SecurityGroupRule{Id: new("new")}, nil
+			})
+
+		Expect(lb.reconcileNodePortRules(context.Background(), sampleLBName, spec)).To(Succeed())
+	})
+
+	It("should delete all rules of the load balancer", func() {
+		mockIaaSClient.EXPECT().ListSecurityGroupRules(gomock.Any(), securityGroupID).Return([]iaas.SecurityGroupRule{
+			rule("first", sampleLBName, "tcp", 30080, "10.0.0.0/8"),
+			rule("second", sampleLBName, "udp", 30053, "10.0.0.0/8"),
+		}, nil)
+		mockIaaSClient.EXPECT().DeleteSecurityGroupRule(gomock.Any(), securityGroupID, "first").Return(nil)
+		mockIaaSClient.EXPECT().DeleteSecurityGroupRule(gomock.Any(), securityGroupID, "second").
+			Return(&oapiError.GenericOpenAPIError{StatusCode: http.StatusNotFound})
+
+		Expect(lb.deleteNodePortRules(context.Background(), sampleLBName)).To(Succeed())
+	})
+
+	It("should not touch security groups if no node security group is configured", func() {
+		lb.opts.NodeSecurityGroupID = ""
+		Expect(lb.reconcileNodePortRules(context.Background(), sampleLBName, spec)).To(Succeed())
+	})
+})
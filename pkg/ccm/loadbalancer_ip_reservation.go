@@ -0,0 +1,406 @@
+package ccm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/informers"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	cloudprovider "k8s.io/cloud-provider"
+	"k8s.io/cloud-provider/api"
+	"k8s.io/klog/v2"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/cmp"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/labels"
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
+)
+
+const (
+	// IPReservationControllerName is the name of the controller that reserves the IPs of LoadBalancerIPReservations.
+	IPReservationControllerName = "ip-reservation"
+
+	// ipReservationFinalizer releases the public IP of a LoadBalancerIPReservation once it isn't claimed anymore.
+	ipReservationFinalizer = "lb.stackit.cloud/ip-reservation"
+	// ipReservationLabel contains the name of the LoadBalancerIPReservation that created a public IP.
+	ipReservationLabel = "k8s-ip-reservation"
+)
+
+// applyIPReservation sets the IP of the LoadBalancerIPReservation that the service claims with ipReservationAnnotation
+// as static external address of spec. It returns an api.RetryError until the reservation is claimed by the service.
+func (l *LoadBalancer) applyIPReservation(service *corev1.Service, spec *loadbalancer.CreateLoadBalancerPayload) error {
+	name := service.Annotations[ipReservationAnnotation]
+	if name == "" {
+		return nil
+	}
+	if cmp.UnpackPtr(spec.Options.PrivateNetworkOnly) {
+		return fmt.Errorf("annotation %s can't be used for internal load balancers", ipReservationAnnotation)
+	}
+	if cmp.UnpackPtr(spec.ExternalAddress) != "" {
+		return fmt.Errorf("annotation %s can't be combined with %s or spec.loadBalancerIP", ipReservationAnnotation, externalIPAnnotation)
+	}
+	if l.ipReservationLister == nil {
+		return fmt.Errorf("annotation %s requires the %s controller", ipReservationAnnotation, IPReservationControllerName)
+	}
+
+	obj, err := l.ipReservationLister.Get(name)
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("LoadBalancerIPReservation %s not found", name)
+	}
+	if err != nil {
+		return err
+	}
+	reservation, err := ipReservationFromObject(obj)
+	if err != nil {
+		return err
+	}
+	if !reservation.allowsNamespace(service.Namespace) {
+		return fmt.Errorf("LoadBalancerIPReservation %s doesn't allow services in namespace %s", name, service.Namespace)
+	}
+	if key := service.Namespace + "/" + service.Name; reservation.Status.ClaimedBy != key || reservation.Status.IP == "" {
+		return api.NewRetryError(fmt.Sprintf("waiting for LoadBalancerIPReservation %s to be claimed by the service, phase %q, claimed by %q",
+			name, reservation.Status.Phase, reservation.Status.ClaimedBy), retryDuration)
+	}
+
+	spec.ExternalAddress = new(reservation.Status.IP)
+	spec.Options.EphemeralAddress = new(false)
+	return nil
+}
+
+// IPReservationController reserves the public IPs of LoadBalancerIPReservations and decides which service claims
+// them. A reservation is claimed by the oldest service that references it, until that service is deleted or stops
+// referencing it. Then the next service takes over the IP.
+type IPReservationController struct {
+	client             dynamic.NamespaceableResourceInterface
+	reservationLister  cache.GenericLister
+	serviceLister      corelisters.ServiceLister
+	reservationsSynced cache.InformerSynced
+	servicesSynced     cache.InformerSynced
+	iaasClient         stackitclient.IaaSClient
+	clusterID          string
+	queue              workqueue.TypedRateLimitingInterface[string]
+}
+
+// NewIPReservationController creates the controller from the STACKIT cloud provider.
+// It also provides the reservations to the load balancer implementation.
+func NewIPReservationController(
+	reservationInformer informers.GenericInformer,
+	serviceInformer coreinformers.ServiceInformer,
+	dynamicClient dynamic.Interface,
+	cloud cloudprovider.Interface,
+) (*IPReservationController, error) {
+	stackitCloud, ok := cloud.(*CloudControllerManager)
+	if !ok {
+		return nil, fmt.Errorf("cloud provider %T is not supported by the %s controller", cloud, IPReservationControllerName)
+	}
+
+	c := &IPReservationController{
+		client:             dynamicClient.Resource(IPReservationResource),
+		reservationLister:  reservationInformer.Lister(),
+		serviceLister:      serviceInformer.Lister(),
+		reservationsSynced: reservationInformer.Informer().HasSynced,
+		servicesSynced:     serviceInformer.Informer().HasSynced,
+		iaasClient:         stackitCloud.loadBalancer.iaasClient,
+		clusterID:          stackitCloud.loadBalancer.clusterID,
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.DefaultTypedControllerRateLimiter[string](),
+			workqueue.TypedRateLimitingQueueConfig[string]{Name: IPReservationControllerName},
+		),
+	}
+	stackitCloud.loadBalancer.ipReservationLister = reservationInformer.Lister()
+
+	_, err := reservationInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueue,
+		UpdateFunc: func(_, newObj any) { c.enqueue(newObj) },
+		DeleteFunc: c.enqueue,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add LoadBalancerIPReservation event handler: %w", err)
+	}
+	_, err = serviceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueServiceReservation,
+		UpdateFunc: func(oldObj, newObj any) {
+			c.enqueueServiceReservation(oldObj)
+			c.enqueueServiceReservation(newObj)
+		},
+		DeleteFunc: c.enqueueServiceReservation,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add service event handler: %w", err)
+	}
+
+	return c, nil
+}
+
+func (c *IPReservationController) enqueue(obj any) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.queue.Add(key)
+}
+
+// enqueueServiceReservation adds the reservation that a service references to the queue.
+func (c *IPReservationController) enqueueServiceReservation(obj any) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	service, ok := obj.(*corev1.Service)
+	if !ok {
+		utilruntime.HandleError(fmt.Errorf("unexpected object of type %T", obj))
+		return
+	}
+	if name := service.Annotations[ipReservationAnnotation]; name != "" {
+		c.queue.Add(name)
+	}
+}
+
+// Run starts the workers and blocks until ctx is cancelled.
+func (c *IPReservationController) Run(ctx context.Context, workers int) {
+	defer utilruntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	klog.InfoS("Starting controller", "controller", IPReservationControllerName)
+	defer klog.InfoS("Shutting down controller", "controller", IPReservationControllerName)
+
+	if !cache.WaitForCacheSync(ctx.Done(), c.reservationsSynced, c.servicesSynced) {
+		return
+	}
+
+	for range workers {
+		go wait.UntilWithContext(ctx, c.runWorker, time.Second)
+	}
+
+	<-ctx.Done()
+}
+
+func (c *IPReservationController) runWorker(ctx context.Context) {
+	for c.processNextItem(ctx) {
+	}
+}
+
+func (c *IPReservationController) processNextItem(ctx context.Context) bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	if err := c.syncReservation(ctx, key); err != nil {
+		utilruntime.HandleError(fmt.Errorf("failed to sync LoadBalancerIPReservation %q: %w", key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *IPReservationController) syncReservation(ctx context.Context, name string) error {
+	obj, err := c.reservationLister.Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	reservation, err := ipReservationFromObject(obj)
+	if err != nil {
+		return err
+	}
+
+	if reservation.DeletionTimestamp != nil {
+		return c.release(ctx, reservation)
+	}
+	if !slices.Contains(reservation.Finalizers, ipReservationFinalizer) {
+		reservation.Finalizers = append(reservation.Finalizers, ipReservationFinalizer)
+		if reservation, err = c.update(ctx, reservation); err != nil {
+			return err
+		}
+	}
+
+	status := reservation.Status
+	reserveErr := c.reserve(ctx, reservation, &status)
+	if reserveErr == nil {
+		if err := c.claim(reservation, &status); err != nil {
+			return err
+		}
+	}
+	if status != reservation.Status {
+		reservation.Status = status
+		if _, err := c.updateStatus(ctx, reservation); err != nil {
+			return errors.Join(reserveErr, err)
+		}
+	}
+	return reserveErr
+}
+
+// reserve sets the public IP of the reservation in status. It adopts the IP of the spec or creates a public IP.
+func (c *IPReservationController) reserve(ctx context.Context, reservation *LoadBalancerIPReservation, status *LoadBalancerIPReservationStatus) error {
+	if status.PublicIPID != "" {
+		return nil
+	}
+
+	var publicIP *iaas.PublicIp
+	if reservation.Spec.IP != "" {
+		publicIPs, err := c.iaasClient.ListPublicIPs(ctx, "")
+		if err != nil {
+			return fmt.Errorf("failed to list public IPs: %w", err)
+		}
+		idx := slices.IndexFunc(publicIPs, func(publicIP iaas.PublicIp) bool { return publicIP.GetIp() == reservation.Spec.IP })
+		if idx < 0 {
+			status.Phase = IPReservationFailed
+			status.Message = fmt.Sprintf("public IP %s not found in the project", reservation.Spec.IP)
+			return errors.New(status.Message)
+		}
+		publicIP = &publicIPs[idx]
+	} else {
+		reservationLabels := c.reservationLabels(reservation)
+		// The IP was created before if the status update failed afterwards.
+		publicIPs, err := c.iaasClient.ListPublicIPs(ctx, labelSelector(reservationLabels))
+		if err != nil {
+			return fmt.Errorf("failed to list public IPs: %w", err)
+		}
+		if len(publicIPs) > 0 {
+			publicIP = &publicIPs[0]
+		} else {
+			payload := iaas.CreatePublicIPPayload{Labels: map[string]any{}}
+			for key, value := range reservationLabels {
+				payload.Labels[key] = value
+			}
+			publicIP, err = c.iaasClient.CreatePublicIP(ctx, payload)
+			if err != nil {
+				status.Phase = IPReservationFailed
+				status.Message = fmt.Sprintf("failed to create public IP: %v", err)
+				return fmt.Errorf("failed to create public IP: %w", err)
+			}
+			klog.InfoS("Created public IP for LoadBalancerIPReservation", "reservation", reservation.Name, "ip", publicIP.GetIp())
+		}
+	}
+
+	status.IP = publicIP.GetIp()
+	status.PublicIPID = publicIP.GetId()
+	return nil
+}
+
+// claim sets the service that claims the reservation in status. The current claim is kept as long as the service
+// references the reservation, otherwise the oldest service that references it takes over.
+func (c *IPReservationController) claim(reservation *LoadBalancerIPReservation, status *LoadBalancerIPReservationStatus) error {
+	services, err := c.claimingServices(reservation)
+	if err != nil {
+		return err
+	}
+
+	status.ClaimedBy = ""
+	status.Phase = IPReservationAvailable
+	status.Message = ""
+	for _, service := range services {
+		if key := service.Namespace + "/" + service.Name; key == reservation.Status.ClaimedBy || status.ClaimedBy == "" {
+			status.ClaimedBy = key
+		}
+	}
+	if status.ClaimedBy != "" {
+		status.Phase = IPReservationClaimed
+	}
+	return nil
+}
+
+// claimingServices returns the services that can claim the reservation, the oldest first.
+func (c *IPReservationController) claimingServices(reservation *LoadBalancerIPReservation) ([]*corev1.Service, error) {
+	services, err := c.serviceLister.List(k8slabels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	services = slices.DeleteFunc(services, func(service *corev1.Service) bool {
+		return service.Annotations[ipReservationAnnotation] != reservation.Name ||
+			service.Spec.Type != corev1.ServiceTypeLoadBalancer ||
+			service.DeletionTimestamp != nil ||
+			!reservation.allowsNamespace(service.Namespace)
+	})
+	slices.SortFunc(services, func(a, b *corev1.Service) int {
+		if a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+			return strings.Compare(a.Namespace+"/"+a.Name, b.Namespace+"/"+b.Name)
+		}
+		if a.CreationTimestamp.Before(&b.CreationTimestamp) {
+			return -1
+		}
+		return 1
+	})
+	return services, nil
+}
+
+// release deletes the public IP of a deleted reservation once no service claims it anymore and removes the finalizer.
+// Adopted public IPs are kept.
+func (c *IPReservationController) release(ctx context.Context, reservation *LoadBalancerIPReservation) error {
+	if !slices.Contains(reservation.Finalizers, ipReservationFinalizer) {
+		return nil
+	}
+	services, err := c.claimingServices(reservation)
+	if err != nil {
+		return err
+	}
+	if len(services) > 0 {
+		return fmt.Errorf("IP is still claimed by service %s/%s", services[0].Namespace, services[0].Name)
+	}
+
+	if reservation.Spec.IP == "" && reservation.Status.PublicIPID != "" {
+		err := c.iaasClient.DeletePublicIP(ctx, reservation.Status.PublicIPID)
+		if err != nil && !stackiterrors.IsNotFound(err) {
+			// The deletion conflicts while a load balancer that is being deleted still uses the IP.
+			return fmt.Errorf("failed to delete public IP %s: %w", reservation.Status.IP, err)
+		}
+		klog.InfoS("Deleted public IP of LoadBalancerIPReservation", "reservation", reservation.Name, "ip", reservation.Status.IP)
+	}
+
+	reservation.Finalizers = slices.DeleteFunc(reservation.Finalizers, func(f string) bool { return f == ipReservationFinalizer })
+	_, err = c.update(ctx, reservation)
+	return err
+}
+
+// reservationLabels returns the labels of the public IP created for the reservation.
+func (c *IPReservationController) reservationLabels(reservation *LoadBalancerIPReservation) map[string]string {
+	return map[string]string{
+		retainedIPClusterLabel: labels.Sanitize(c.clusterID),
+		ipReservationLabel:     reservation.Name,
+	}
+}
+
+func (c *IPReservationController) update(ctx context.Context, reservation *LoadBalancerIPReservation) (*LoadBalancerIPReservation, error) {
+	u, err := reservation.toUnstructured()
+	if err != nil {
+		return nil, err
+	}
+	u, err = c.client.Update(ctx, u, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update LoadBalancerIPReservation: %w", err)
+	}
+	return ipReservationFromObject(u)
+}
+
+func (c *IPReservationController) updateStatus(ctx context.Context, reservation *LoadBalancerIPReservation) (*LoadBalancerIPReservation, error) {
+	u, err := reservation.toUnstructured()
+	if err != nil {
+		return nil, err
+	}
+	u, err = c.client.UpdateStatus(ctx, u, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update status of LoadBalancerIPReservation: %w", err)
+	}
+	return ipReservationFromObject(u)
+}
@@ -0,0 +1,244 @@
+package ccm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/cloud-provider/api"
+
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
+)
+
+var _ = Describe("IPReservationController", func() {
+	const (
+		address    = "1.2.3.4"
+		publicIPID = "7ab7e2b0-0d2d-4f46-9c3b-6e3c9bdc4a53"
+	)
+
+	var (
+		iaasMock           *stackitclientmock.MockIaaSClient
+		lb                 *LoadBalancer
+		dynamicClient      *dynamicfake.FakeDynamicClient
+		reservationIndexer cache.Indexer
+		serviceIndexer     cache.Indexer
+		controller         *IPReservationController
+		reservation        *LoadBalancerIPReservation
+	)
+
+	BeforeEach(func() {
+		ctrl := gomock.NewController(GinkgoT())
+		iaasMock = stackitclientmock.NewMockIaaSClient(ctrl)
+		var err error
+		lb, err = NewLoadBalancer(stackitclientmock.NewMockLoadBalancingClient(ctrl), iaasMock,
+			stackitconfig.LoadBalancerOpts{NetworkID: "my-network"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		lb.clusterID = "my-cluster"
+
+		reservation = &LoadBalancerIPReservation{ObjectMeta: metav1.ObjectMeta{Name: "my-reservation"}}
+	})
+
+	// start creates the controller with the reservation and services.
+	start := func(services ...*corev1.Service) {
+		u, err := reservation.toUnstructured()
+		Expect(err).NotTo(HaveOccurred())
+		dynamicClient = dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+			map[schema.GroupVersionResource]string{IPReservationResource: "LoadBalancerIPReservationList"}, u)
+		reservationInformer := dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, 0).ForResource(IPReservationResource)
+		serviceInformer := informers.NewSharedInformerFactory(fake.NewClientset(), 0).Core().V1().Services()
+
+		controller, err = NewIPReservationController(reservationInformer, serviceInformer, dynamicClient, &CloudControllerManager{loadBalancer: lb})
+		Expect(err).NotTo(HaveOccurred())
+		reservationIndexer = reservationInformer.Informer().GetIndexer()
+		serviceIndexer = serviceInformer.Informer().GetIndexer()
+		Expect(reservationIndexer.Add(u)).To(Succeed())
+		for _, service := range services {
+			Expect(serviceIndexer.Add(service)).To(Succeed())
+		}
+	}
+
+	get := func() *LoadBalancerIPReservation {
+		u, err := dynamicClient.Resource(IPReservationResource).Get(context.Background(), reservation.Name, metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		r, err := ipReservationFromObject(u)
+		Expect(err).NotTo(HaveOccurred())
+		return r
+	}
+
+	service := func(namespace, name string, age time.Duration) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:         namespace,
+				Name:              name,
+				CreationTimestamp: metav1.NewTime(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC).Add(-age)),
+				Annotations:       map[string]string{ipReservationAnnotation: reservation.Name},
+			},
+			Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+		}
+	}
+
+	It("should create a public IP and let the oldest service claim it", func() {
+		start(service("a", "new", time.Minute), service("b", "old", time.Hour))
+		iaasMock.EXPECT().ListPublicIPs(gomock.Any(), "k8s-cluster-id=my-cluster,k8s-ip-reservation=my-reservation").Return(nil, nil)
+		iaasMock.EXPECT().CreatePublicIP(gomock.Any(), iaas.CreatePublicIPPayload{Labels: map[string]any{
+			retainedIPClusterLabel: "my-cluster",
+			ipReservationLabel:     "my-reservation",
+		}}).Return(&iaas.PublicIp{Id: new(publicIPID), Ip: new(address)}, nil)
+
+		Expect(controller.syncReservation(context.Background(), reservation.Name)).To(Succeed())
+		r := get()
+		Expect(r.Finalizers).To(ConsistOf(ipReservationFinalizer))
+		Expect(r.Status).To(Equal(LoadBalancerIPReservationStatus{
+			Phase:      IPReservationClaimed,
+			IP:         address,
+			PublicIPID: publicIPID,
+			ClaimedBy:  "b/old",
+		}))
+	})
+
+	It("should adopt an existing public IP", func() {
+		reservation.Spec.IP = address
+		start()
+		iaasMock.EXPECT().ListPublicIPs(gomock.Any(), "").Return([]iaas.PublicIp{{Id: new(publicIPID), Ip: new(address)}}, nil)
+
+		Expect(controller.syncReservation(context.Background(), reservation.Name)).To(Succeed())
+		Expect(get().Status).To(Equal(LoadBalancerIPReservationStatus{
+			Phase:      IPReservationAvailable,
+			IP:         address,
+			PublicIPID: publicIPID,
+		}))
+	})
+
+	It("should fail if the adopted public IP doesn't exist", func() {
+		reservation.Spec.IP = address
+		start()
+		iaasMock.EXPECT().ListPublicIPs(gomock.Any(), "").Return(nil, nil)
+
+		Expect(controller.syncReservation(context.Background(), reservation.Name)).To(MatchError(ContainSubstring("not found")))
+		Expect(get().Status.Phase).To(Equal(IPReservationFailed))
+	})
+
+	It("should keep the claim and hand it over once the service is deleted", func() {
+		reservation.Finalizers = []string{ipReservationFinalizer}
+		reservation.Status = LoadBalancerIPReservationStatus{
+			Phase: IPReservationClaimed, IP: address, PublicIPID: publicIPID, ClaimedBy: "a/new",
+		}
+		claimant := service("a", "new", time.Minute)
+		start(claimant, service("b", "old", time.Hour), service("c", "forbidden", 2*time.Hour))
+		reservation.Spec.AllowedNamespaces = []string{"a", "b"}
+		u, err := reservation.toUnstructured()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reservationIndexer.Update(u)).To(Succeed())
+
+		Expect(controller.syncReservation(context.Background(), reservation.Name)).To(Succeed())
+		Expect(get().Status.ClaimedBy).To(Equal("a/new"))
+
+		Expect(serviceIndexer.Delete(claimant)).To(Succeed())
+		Expect(controller.syncReservation(context.Background(), reservation.Name)).To(Succeed())
+		Expect(get().Status.ClaimedBy).To(Equal("b/old"))
+	})
+
+	Describe("deletion", func() {
+		BeforeEach(func() {
+			reservation.Finalizers = []string{ipReservationFinalizer}
+			reservation.DeletionTimestamp = new(metav1.Now())
+			reservation.Status = LoadBalancerIPReservationStatus{
+				Phase: IPReservationClaimed, IP: address, PublicIPID: publicIPID, ClaimedBy: "a/new",
+			}
+		})
+
+		It("should wait until no service claims the IP", func() {
+			start(service("a", "new", time.Minute))
+
+			Expect(controller.syncReservation(context.Background(), reservation.Name)).To(MatchError(ContainSubstring("still claimed")))
+			Expect(get().Finalizers).To(ConsistOf(ipReservationFinalizer))
+		})
+
+		It("should delete the created public IP and remove the finalizer", func() {
+			start()
+			iaasMock.EXPECT().DeletePublicIP(gomock.Any(), publicIPID).Return(nil)
+
+			Expect(controller.syncReservation(context.Background(), reservation.Name)).To(Succeed())
+			Expect(get().Finalizers).To(BeEmpty())
+		})
+
+		It("should retry while the public IP is still in use", func() {
+			start()
+			iaasMock.EXPECT().DeletePublicIP(gomock.Any(), publicIPID).Return(fmt.Errorf("in use: %w", stackiterrors.ErrConflict))
+
+			Expect(controller.syncReservation(context.Background(), reservation.Name)).To(MatchError(stackiterrors.ErrConflict))
+			Expect(get().Finalizers).To(ConsistOf(ipReservationFinalizer))
+		})
+
+		It("should keep an adopted public IP", func() {
+			reservation.Spec.IP = address
+			start()
+
+			Expect(controller.syncReservation(context.Background(), reservation.Name)).To(Succeed())
+			Expect(get().Finalizers).To(BeEmpty())
+		})
+	})
+
+	Describe("applyIPReservation", func() {
+		var spec *loadbalancer.CreateLoadBalancerPayload
+
+		BeforeEach(func() {
+			spec = &loadbalancer.CreateLoadBalancerPayload{
+				Options: &loadbalancer.LoadBalancerOptions{EphemeralAddress: new(true), PrivateNetworkOnly: new(false)},
+			}
+			reservation.Spec.AllowedNamespaces = []string{"a"}
+			reservation.Status = LoadBalancerIPReservationStatus{
+				Phase: IPReservationClaimed, IP: address, PublicIPID: publicIPID, ClaimedBy: "a/new",
+			}
+		})
+
+		It("should use the IP of the reservation claimed by the service", func() {
+			start()
+
+			Expect(lb.applyIPReservation(service("a", "new", 0), spec)).To(Succeed())
+			Expect(spec.ExternalAddress).To(HaveValue(Equal(address)))
+			Expect(spec.Options.EphemeralAddress).To(HaveValue(BeFalse()))
+		})
+
+		It("should wait while another service claims the reservation", func() {
+			start()
+
+			var retryErr *api.RetryError
+			Expect(lb.applyIPReservation(service("a", "other", 0), spec)).To(BeAssignableToTypeOf(retryErr))
+			Expect(spec.ExternalAddress).To(BeNil())
+		})
+
+		It("should reject services in other namespaces", func() {
+			start()
+
+			Expect(lb.applyIPReservation(service("b", "new", 0), spec)).To(MatchError(ContainSubstring("doesn't allow services in namespace b")))
+		})
+
+		It("should fail without the controller", func() {
+			Expect(lb.applyIPReservation(service("a", "new", 0), spec)).To(MatchError(ContainSubstring("requires the ip-reservation controller")))
+		})
+
+		It("should reject combining the annotation with an external address", func() {
+			start()
+			spec.ExternalAddress = new("5.6.7.8")
+
+			Expect(lb.applyIPReservation(service("a", "new", 0), spec)).To(MatchError(ContainSubstring("can't be combined")))
+		})
+	})
+})
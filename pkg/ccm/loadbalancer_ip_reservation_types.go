@@ -0,0 +1,92 @@
+package ccm
+
+import (
+	"fmt"
+	"slices"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// IPReservationResource is the resource of LoadBalancerIPReservations.
+// The CRD is deployed with the cloud controller manager, see deploy/cloud-controller-manager.
+var IPReservationResource = schema.GroupVersionResource{
+	Group:    "lb.stackit.cloud",
+	Version:  "v1alpha1",
+	Resource: "loadbalanceripreservations",
+}
+
+// LoadBalancerIPReservation reserves a public IP of the project that services in any namespace can claim by name with
+// ipReservationAnnotation. It is cluster-scoped, so the IP outlives the services that use it.
+type LoadBalancerIPReservation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   LoadBalancerIPReservationSpec   `json:"spec,omitempty"`
+	Status LoadBalancerIPReservationStatus `json:"status,omitempty"`
+}
+
+type LoadBalancerIPReservationSpec struct {
+	// IP adopts an existing public IP of the project, which is kept when the reservation is deleted.
+	// If empty, a public IP is created, which is deleted together with the reservation.
+	IP string `json:"ip,omitempty"`
+	// AllowedNamespaces restricts the namespaces of the services that can claim the IP. All namespaces are allowed if
+	// it is empty.
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+}
+
+type LoadBalancerIPReservationStatus struct {
+	Phase IPReservationPhase `json:"phase,omitempty"`
+	// Message explains the phase, e.g. why the reservation failed.
+	Message string `json:"message,omitempty"`
+	// IP is the reserved public IP.
+	IP string `json:"ip,omitempty"`
+	// PublicIPID is the ID of the reserved public IP.
+	PublicIPID string `json:"publicIPID,omitempty"`
+	// ClaimedBy is the namespace/name of the service that uses the IP.
+	ClaimedBy string `json:"claimedBy,omitempty"`
+}
+
+type IPReservationPhase string
+
+const (
+	// IPReservationPending means that the IP is not reserved yet.
+	IPReservationPending IPReservationPhase = "Pending"
+	// IPReservationAvailable means that the IP is reserved and can be claimed by a service.
+	IPReservationAvailable IPReservationPhase = "Available"
+	// IPReservationClaimed means that the IP is reserved for the service in LoadBalancerIPReservationStatus.ClaimedBy.
+	IPReservationClaimed IPReservationPhase = "Claimed"
+	// IPReservationFailed means that the IP can't be reserved, see LoadBalancerIPReservationStatus.Message.
+	IPReservationFailed IPReservationPhase = "Failed"
+)
+
+// allowsNamespace returns whether services in namespace can claim the IP.
+func (r *LoadBalancerIPReservation) allowsNamespace(namespace string) bool {
+	return len(r.Spec.AllowedNamespaces) == 0 || slices.Contains(r.Spec.AllowedNamespaces, namespace)
+}
+
+// ipReservationFromObject converts an object of the dynamic informer to a LoadBalancerIPReservation.
+func ipReservationFromObject(obj runtime.Object) (*LoadBalancerIPReservation, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object of type %T", obj)
+	}
+	reservation := &LoadBalancerIPReservation{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, reservation); err != nil {
+		return nil, fmt.Errorf("failed to convert LoadBalancerIPReservation %s: %w", u.GetName(), err)
+	}
+	return reservation, nil
+}
+
+// toUnstructured converts the reservation for the dynamic client.
+func (r *LoadBalancerIPReservation) toUnstructured() (*unstructured.Unstructured, error) {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert LoadBalancerIPReservation %s: %w", r.Name, err)
+	}
+	u := &unstructured.Unstructured{Object: obj}
+	u.SetGroupVersionKind(IPReservationResource.GroupVersion().WithKind("LoadBalancerIPReservation"))
+	return u, nil
+}
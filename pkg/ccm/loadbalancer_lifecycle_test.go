@@ -0,0 +1,192 @@
+package ccm
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	stackitclientfake "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/fake"
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	cloudprovider "k8s.io/cloud-provider"
+	"k8s.io/cloud-provider/api"
+)
+
+var _ = Describe("LoadBalancer lifecycle", func() {
+	const clusterName = "my-cluster"
+
+	var (
+		client       *stackitclientfake.LoadBalancingClient
+		loadBalancer *LoadBalancer
+		service      *corev1.Service
+		name         string
+	)
+
+	BeforeEach(func() {
+		client = stackitclientfake.NewLoadBalancingClient()
+		var err error
+		loadBalancer, err = NewLoadBalancer(
+			client,
+			stackitclientmock.NewMockIaaSClient(gomock.NewController(GinkgoT())),
+			stackitconfig.LoadBalancerOpts{NetworkID: "my-network"},
+			nil,
+		)
+		Expect(err).NotTo(HaveOccurred())
+		loadBalancer.recorder = record.NewFakeRecorder(10)
+		service = minimalLoadBalancerService()
+		service.Spec.Ports = []corev1.ServicePort{{Name: "http", Protocol: corev1.ProtocolTCP, Port: 80, NodePort: 30080}}
+		name = loadBalancer.GetLoadBalancerName(context.Background(), clusterName, service)
+	})
+
+	ensure := func() (*corev1.LoadBalancerStatus, error) {
+		return loadBalancer.EnsureLoadBalancer(context.Background(), clusterName, service, []*corev1.Node{})
+	}
+
+	It("should report the load balancer once it became ready", func() {
+		client.PendingGets = 1
+
+		_, err := ensure()
+		Expect(err).To(MatchError(notYetReadyError))
+		Expect(client.LoadBalancer(name).GetStatus()).To(Equal(loadbalancer.LOADBALANCERSTATUS_STATUS_PENDING))
+
+		_, err = ensure()
+		Expect(err).To(MatchError(notYetReadyError))
+
+		status, err := ensure()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Ingress).To(ConsistOf(HaveField("IP", "123.124.88.99")))
+		Expect(client.Calls()).To(Equal([]string{
+			"GetLoadBalancer " + name,
+			"GetQuota",
+			"CreateLoadBalancer " + name,
+			"GetLoadBalancer " + name,
+			"GetLoadBalancer " + name,
+		}))
+	})
+
+	It("should update the load balancer after a concurrent change", func() {
+		_, err := ensure()
+		Expect(err).NotTo(HaveOccurred())
+
+		service.Spec.Ports[0].Port = 8080
+		client.FailNext(stackitclientfake.OpUpdateLoadBalancer, stackitclientfake.APIError(http.StatusConflict, "version conflict"))
+		_, err = ensure()
+		Expect(err).To(MatchError(stackiterrors.ErrConflict))
+
+		_, err = ensure()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.LoadBalancer(name).Listeners).To(ConsistOf(HaveField("Port", new(int32(8080)))))
+	})
+
+	It("should not create the load balancer if the quota is exhausted", func() {
+		client.MaxLoadBalancers = 1
+		other := minimalLoadBalancerService()
+		other.Name = "other"
+		_, err := loadBalancer.EnsureLoadBalancer(context.Background(), clusterName, other, []*corev1.Node{})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = ensure()
+		Expect(err).To(MatchError(stackiterrors.ErrQuotaExceeded))
+		Expect(err).To(MatchError(ContainSubstring("1/1 load balancers used")))
+		Expect(client.LoadBalancer(name)).To(BeNil())
+		Expect(client.Calls()).NotTo(ContainElement("CreateLoadBalancer " + name))
+		Expect(loadBalancer.recorder.(*record.FakeRecorder).Events).To(Receive(
+			ContainSubstring(EventReasonQuotaExceeded + " Load balancer quota exceeded in project"),
+		))
+	})
+
+	It("should create the load balancer if the quota can't be read", func() {
+		client.FailNext(stackitclientfake.OpGetQuota, stackitclientfake.APIError(http.StatusInternalServerError, "internal error"))
+
+		_, err := ensure()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.LoadBalancer(name)).NotTo(BeNil())
+	})
+
+	It("should retry with a single event while the API is in maintenance", func() {
+		maintenance := func() error {
+			return stackiterrors.WithRetryAfter(
+				stackiterrors.Classify(stackitclientfake.APIError(http.StatusServiceUnavailable, "maintenance")), "300", time.Now(),
+			)
+		}
+		client.FailNext(stackitclientfake.OpGetLoadBalancer, maintenance(), maintenance())
+		events := loadBalancer.recorder.(*record.FakeRecorder).Events
+
+		for range 2 {
+			_, err := ensure()
+			var retryErr *api.RetryError
+			Expect(err).To(BeAssignableToTypeOf(retryErr))
+			Expect(err.(*api.RetryError).RetryAfter()).To(Equal(5 * time.Minute))
+		}
+		Expect(events).To(Receive(ContainSubstring(EventReasonAPIMaintenance)))
+		Expect(events).NotTo(Receive())
+
+		_, err := ensure()
+		Expect(err).NotTo(HaveOccurred())
+
+		client.FailNext(stackitclientfake.OpGetLoadBalancer, maintenance())
+		_, err = ensure()
+		Expect(err).To(HaveOccurred())
+		Expect(events).To(Receive(ContainSubstring(EventReasonAPIMaintenance)))
+	})
+
+	Context("in opt-in mode", func() {
+		BeforeEach(func() {
+			loadBalancer.optIn = true
+		})
+
+		It("should ignore services without the annotation", func() {
+			client.AddLoadBalancer(&loadbalancer.LoadBalancer{Name: new(name)})
+
+			_, exists, err := loadBalancer.GetLoadBalancer(context.Background(), clusterName, service)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exists).To(BeFalse())
+			_, err = ensure()
+			Expect(err).To(MatchError(cloudprovider.ImplementedElsewhere))
+			Expect(loadBalancer.UpdateLoadBalancer(context.Background(), clusterName, service, nil)).
+				To(MatchError(cloudprovider.ImplementedElsewhere))
+			Expect(client.Calls()).To(BeEmpty())
+			Expect(client.LoadBalancer(name)).NotTo(BeNil())
+		})
+
+		It("should not delete anything for services without the annotation and load balancer", func() {
+			Expect(loadBalancer.EnsureLoadBalancerDeleted(context.Background(), clusterName, service)).
+				To(MatchError(cloudprovider.ImplementedElsewhere))
+		})
+
+		It("should delete the load balancer of a service that opted out", func() {
+			service.Annotations = map[string]string{enabledAnnotation: "true"}
+			_, err := ensure()
+			Expect(err).NotTo(HaveOccurred())
+
+			service.Annotations = nil
+			Expect(loadBalancer.EnsureLoadBalancerDeleted(context.Background(), clusterName, service)).To(Succeed())
+			Expect(client.LoadBalancer(name)).To(BeNil())
+		})
+
+		It("should reconcile services with the annotation", func() {
+			service.Annotations = map[string]string{enabledAnnotation: "true"}
+
+			_, err := ensure()
+			Expect(err).NotTo(HaveOccurred())
+			_, exists, err := loadBalancer.GetLoadBalancer(context.Background(), clusterName, service)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exists).To(BeTrue())
+		})
+	})
+
+	It("should delete the load balancer", func() {
+		_, err := ensure()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(loadBalancer.EnsureLoadBalancerDeleted(context.Background(), clusterName, service)).To(Succeed())
+		Expect(client.LoadBalancer(name)).To(BeNil())
+	})
+})
@@ -0,0 +1,28 @@
+package ccm
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/cloud-provider/api"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
+)
+
+// handleMaintenance converts an error of an API that is unavailable and sent a Retry-After header, e.g. during a
+// planned maintenance, to an api.RetryError with the delay suggested by the API.
+// The service only gets a single event per maintenance: the event is recorded again only after the service was
+// reconciled without running into the maintenance. Other errors are returned unchanged.
+func (l *LoadBalancer) handleMaintenance(service *corev1.Service, err error) error {
+	retryAfter, ok := stackiterrors.RetryAfter(err)
+	if !ok {
+		l.maintenanceNotified.Delete(service.UID)
+		return err
+	}
+
+	if _, notified := l.maintenanceNotified.LoadOrStore(service.UID, struct{}{}); !notified {
+		l.recorder.Eventf(service, corev1.EventTypeWarning, EventReasonAPIMaintenance,
+			"The cloud API is in maintenance, the load balancer is reconciled again in %s", retryAfter)
+	}
+	return api.NewRetryError(fmt.Sprintf("cloud API in maintenance, retrying in %s: %v", retryAfter, err), retryAfter)
+}
@@ -0,0 +1,143 @@
+package ccm
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
+)
+
+const (
+	// defaultNodePoolLabel is the label of nodes containing their node pool in Gardener and SKE clusters.
+	defaultNodePoolLabel = "worker.gardener.cloud/pool"
+
+	eventReasonNodesInOtherNetworks   = "NodesInOtherNetworks"
+	eventReasonInvalidListenerNetwork = "InvalidListenerNetwork"
+)
+
+// nodeNetworkID returns the ID of the network of the node: the network of its node pool if configured, the detected
+// network of its primary NIC if auto-detection is enabled, and the configured network otherwise.
+func nodeNetworkID(node *corev1.Node, opts stackitconfig.LoadBalancerOpts) string {
+	if len(opts.NodePoolNetworks) > 0 {
+		poolLabel := cmp.Or(opts.NodePoolLabel, defaultNodePoolLabel)
+		if networkID, ok := opts.NodePoolNetworks[node.Labels[poolLabel]]; ok {
+			return networkID
+		}
+	}
+	if opts.NetworkAutoDetection {
+		if networkID := node.Labels[LabelNetworkID]; networkID != "" {
+			return networkID
+		}
+	}
+	return opts.NetworkID
+}
+
+// targetNetwork returns the network of the load balancer targets and the nodes in it. Load balancers support only
+// a single target network, so if the nodes span multiple networks, the network with the most nodes is used and
+// the other nodes are returned as skipped. Ties are broken in favor of the configured network.
+// Nodes with an unknown network are always skipped.
+func targetNetwork(nodes []*corev1.Node, opts stackitconfig.LoadBalancerOpts) (networkID string, targetNodes []*corev1.Node, skipped []string) {
+	byNetwork := map[string][]*corev1.Node{}
+	for _, node := range nodes {
+		networkID := nodeNetworkID(node, opts)
+		byNetwork[networkID] = append(byNetwork[networkID], node)
+	}
+
+	networkIDs := make([]string, 0, len(byNetwork))
+	for networkID := range byNetwork {
+		if networkID != "" {
+			networkIDs = append(networkIDs, networkID)
+		}
+	}
+	slices.SortFunc(networkIDs, func(a, b string) int {
+		if c := cmp.Compare(len(byNetwork[b]), len(byNetwork[a])); c != 0 {
+			return c
+		}
+		switch opts.NetworkID {
+		case a:
+			return -1
+		case b:
+			return 1
+		}
+		return strings.Compare(a, b)
+	})
+
+	networkID = opts.NetworkID
+	if len(networkIDs) > 0 {
+		networkID = networkIDs[0]
+	}
+	for id, networkNodes := range byNetwork {
+		if id == networkID {
+			continue
+		}
+		for _, node := range networkNodes {
+			skipped = append(skipped, node.Name)
+		}
+	}
+	slices.Sort(skipped)
+	return networkID, byNetwork[networkID], skipped
+}
+
+func nodesInOtherNetworksEvent(networkID string, skipped []string) Event {
+	return Event{
+		Type:   corev1.EventTypeWarning,
+		Reason: eventReasonNodesInOtherNetworks,
+		Message: fmt.Sprintf("Load balancers support a single target network, nodes outside of network %s or with an unknown network are not targeted: %s",
+			networkID, strings.Join(skipped, ", ")),
+	}
+}
+
+// validateNetworks resolves the networks configured for load balancers, so that a typo in the cloud-config fails the
+// start of the cloud controller manager instead of the creation of every load balancer.
+// Other errors, e.g. an unavailable API, are only logged to not block the start.
+func validateNetworks(ctx context.Context, client stackitclient.IaaSClient, opts stackitconfig.LoadBalancerOpts) error {
+	type network struct{ id, option string }
+	var networks []network
+	if opts.NetworkID != "" {
+		networks = append(networks, network{opts.NetworkID, "networkId"})
+	}
+	for _, pool := range slices.Sorted(maps.Keys(opts.NodePoolNetworks)) {
+		networks = append(networks, network{opts.NodePoolNetworks[pool], fmt.Sprintf("nodePoolNetworks[%s]", pool)})
+	}
+
+	for _, n := range networks {
+		_, err := client.GetNetwork(ctx, n.id)
+		switch {
+		case err == nil:
+		case stackiterrors.IsNotFound(err) || stackiterrors.IsInvalidError(err):
+			return fmt.Errorf("network %q configured in loadBalancer.%s doesn't exist in the project: %w", n.id, n.option, err)
+		default:
+			klog.ErrorS(err, "Failed to validate network of load balancers", "networkID", n.id, "option", n.option)
+		}
+	}
+	return nil
+}
+
+// checkListenerNetwork ensures that the network in the listener-network annotation of the service exists.
+// Otherwise, the load balancer would only fail on creation with an unspecific error.
+func (l *LoadBalancer) checkListenerNetwork(ctx context.Context, service *corev1.Service) error {
+	networkID := service.Annotations[listenerNetworkAnnotation]
+	if networkID == "" {
+		return nil
+	}
+	_, err := l.iaasClient.GetNetwork(ctx, networkID)
+	switch {
+	case err == nil:
+		return nil
+	case stackiterrors.IsNotFound(err) || stackiterrors.IsInvalidError(err):
+		l.recorder.Eventf(service, corev1.EventTypeWarning, eventReasonInvalidListenerNetwork,
+			"The network %s in %s doesn't exist in project %s", networkID, listenerNetworkAnnotation, l.projectID)
+		return fmt.Errorf("listener network %q doesn't exist: %w", networkID, err)
+	default:
+		return fmt.Errorf("failed to get listener network %q: %w", networkID, err)
+	}
+}
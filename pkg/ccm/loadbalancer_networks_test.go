@@ -0,0 +1,219 @@
+package ccm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	oapiError "github.com/stackitcloud/stackit-sdk-go/core/oapierror"
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+)
+
+var _ = Describe("Load balancer networks", func() {
+	var opts stackitconfig.LoadBalancerOpts
+
+	node := func(name, ip string, labels map[string]string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+			Status: corev1.NodeStatus{Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: ip},
+			}},
+		}
+	}
+	names := func(nodes []*corev1.Node) []string {
+		var result []string
+		for _, n := range nodes {
+			result = append(result, n.Name)
+		}
+		return result
+	}
+
+	BeforeEach(func() {
+		opts = stackitconfig.LoadBalancerOpts{NetworkID: "default-network"}
+	})
+
+	Describe("nodeNetworkID", func() {
+		It("should use the configured network", func() {
+			Expect(nodeNetworkID(node("a", "10.0.0.1", map[string]string{LabelNetworkID: "detected"}), opts)).To(Equal("default-network"))
+		})
+
+		It("should use the detected network if enabled", func() {
+			opts.NetworkAutoDetection = true
+			Expect(nodeNetworkID(node("a", "10.0.0.1", map[string]string{LabelNetworkID: "detected"}), opts)).To(Equal("detected"))
+			Expect(nodeNetworkID(node("b", "10.0.0.2", nil), opts)).To(Equal("default-network"))
+		})
+
+		It("should prefer the network of the node pool", func() {
+			opts.NetworkAutoDetection = true
+			opts.NodePoolNetworks = map[string]string{"pool-a": "pool-network"}
+			Expect(nodeNetworkID(node("a", "10.0.0.1", map[string]string{
+				defaultNodePoolLabel: "pool-a",
+				LabelNetworkID:       "detected",
+			}), opts)).To(Equal("pool-network"))
+
+			opts.NodePoolLabel = "pool"
+			Expect(nodeNetworkID(node("a", "10.0.0.1", map[string]string{"pool": "pool-a"}), opts)).To(Equal("pool-network"))
+		})
+	})
+
+	Describe("targetNetwork", func() {
+		BeforeEach(func() {
+			opts.NetworkAutoDetection = true
+		})
+
+		It("should use the network with the most nodes", func() {
+			networkID, nodes, skipped := targetNetwork([]*corev1.Node{
+				node("a", "10.0.0.1", map[string]string{LabelNetworkID: "network-a"}),
+				node("b", "10.1.0.1", map[string]string{LabelNetworkID: "network-b"}),
+				node("c", "10.1.0.2", map[string]string{LabelNetworkID: "network-b"}),
+			}, opts)
+			Expect(networkID).To(Equal("network-b"))
+			Expect(names(nodes)).To(Equal([]string{"b", "c"}))
+			Expect(skipped).To(Equal([]string{"a"}))
+		})
+
+		It("should prefer the configured network on ties", func() {
+			networkID, _, skipped := targetNetwork([]*corev1.Node{
+				node("a", "10.0.0.1", map[string]string{LabelNetworkID: "network-a"}),
+				node("b", "10.1.0.1", nil),
+			}, opts)
+			Expect(networkID).To(Equal("default-network"))
+			Expect(skipped).To(Equal([]string{"a"}))
+		})
+
+		It("should use the configured network without nodes", func() {
+			networkID, nodes, skipped := targetNetwork(nil, opts)
+			Expect(networkID).To(Equal("default-network"))
+			Expect(nodes).To(BeEmpty())
+			Expect(skipped).To(BeEmpty())
+		})
+
+		It("should skip nodes with an unknown network", func() {
+			opts.NetworkID = ""
+			networkID, nodes, skipped := targetNetwork([]*corev1.Node{
+				node("a", "10.0.0.1", map[string]string{LabelNetworkID: "network-a"}),
+				node("b", "10.1.0.1", nil),
+			}, opts)
+			Expect(networkID).To(Equal("network-a"))
+			Expect(names(nodes)).To(Equal([]string{"a"}))
+			Expect(skipped).To(Equal([]string{"b"}))
+		})
+	})
+
+	Describe("lbSpecFromService", func() {
+		It("should register the targets in the detected network", func() {
+			opts.NetworkAutoDetection = true
+			spec, events, err := lbSpecFromService(&corev1.Service{
+				Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 80, Protocol: corev1.ProtocolTCP, NodePort: 30080}}},
+			}, []*corev1.Node{
+				node("a", "10.0.0.1", map[string]string{LabelNetworkID: "network-a"}),
+				node("b", "10.1.0.1", map[string]string{LabelNetworkID: "network-b"}),
+				node("c", "10.1.0.2", map[string]string{LabelNetworkID: "network-b"}),
+			}, opts, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(spec.Networks).To(Equal([]loadbalancer.Network{{
+				Role:      new(loadbalancer.NETWORKROLE_ROLE_LISTENERS_AND_TARGETS),
+				NetworkId: new("network-b"),
+			}}))
+			Expect(spec.TargetPools).To(HaveLen(1))
+			Expect(spec.TargetPools[0].Targets).To(ConsistOf(
+				loadbalancer.Target{DisplayName: new("b"), Ip: new("10.1.0.1")},
+				loadbalancer.Target{DisplayName: new("c"), Ip: new("10.1.0.2")},
+			))
+			Expect(events).To(ContainElement(nodesInOtherNetworksEvent("network-b", []string{"a"})))
+		})
+
+		It("should fail if no network is known", func() {
+			opts.NetworkID = ""
+			_, _, err := lbSpecFromService(&corev1.Service{}, nil, opts, nil)
+			Expect(err).To(MatchError(ContainSubstring("the network of the nodes is unknown")))
+		})
+	})
+
+	Describe("validateNetworks", func() {
+		var iaasClient *stackitclientmock.MockIaaSClient
+
+		BeforeEach(func() {
+			iaasClient = stackitclientmock.NewMockIaaSClient(gomock.NewController(GinkgoT()))
+			opts.NodePoolNetworks = map[string]string{"pool-b": "network-b", "pool-a": "network-a"}
+		})
+
+		It("should resolve all configured networks", func() {
+			gomock.InOrder(
+				iaasClient.EXPECT().GetNetwork(gomock.Any(), "default-network").Return(&iaas.Network{}, nil),
+				iaasClient.EXPECT().GetNetwork(gomock.Any(), "network-a").Return(&iaas.Network{}, nil),
+				iaasClient.EXPECT().GetNetwork(gomock.Any(), "network-b").Return(&iaas.Network{}, nil),
+			)
+			Expect(validateNetworks(context.Background(), iaasClient, opts)).To(Succeed())
+		})
+
+		It("should fail if a network doesn't exist", func() {
+			iaasClient.EXPECT().GetNetwork(gomock.Any(), "default-network").Return(&iaas.Network{}, nil)
+			iaasClient.EXPECT().GetNetwork(gomock.Any(), "network-a").Return(nil, &oapiError.GenericOpenAPIError{StatusCode: http.StatusNotFound})
+
+			Expect(validateNetworks(context.Background(), iaasClient, opts)).To(MatchError(ContainSubstring(
+				`network "network-a" configured in loadBalancer.nodePoolNetworks[pool-a] doesn't exist in the project`)))
+		})
+
+		It("should not fail if the API is unavailable", func() {
+			iaasClient.EXPECT().GetNetwork(gomock.Any(), gomock.Any()).Return(nil, errors.New("connection refused")).Times(3)
+			Expect(validateNetworks(context.Background(), iaasClient, opts)).To(Succeed())
+		})
+	})
+
+	Describe("checkListenerNetwork", func() {
+		var (
+			iaasClient *stackitclientmock.MockIaaSClient
+			recorder   *record.FakeRecorder
+			lb         *LoadBalancer
+			svc        *corev1.Service
+		)
+
+		BeforeEach(func() {
+			ctrl := gomock.NewController(GinkgoT())
+			iaasClient = stackitclientmock.NewMockIaaSClient(ctrl)
+			lbClient := stackitclientmock.NewMockLoadBalancingClient(ctrl)
+			var err error
+			lb, err = NewLoadBalancer(lbClient, iaasClient, opts, nil)
+			Expect(err).NotTo(HaveOccurred())
+			recorder = record.NewFakeRecorder(10)
+			lb.recorder = recorder
+			lb.projectID = "my-project"
+			svc = &corev1.Service{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{listenerNetworkAnnotation: "listener-network"}}}
+		})
+
+		It("should not check services without a listener network", func() {
+			Expect(lb.checkListenerNetwork(context.Background(), &corev1.Service{})).To(Succeed())
+		})
+
+		It("should accept an existing network", func() {
+			iaasClient.EXPECT().GetNetwork(gomock.Any(), "listener-network").Return(&iaas.Network{}, nil)
+			Expect(lb.checkListenerNetwork(context.Background(), svc)).To(Succeed())
+			Expect(recorder.Events).NotTo(Receive())
+		})
+
+		It("should record an event for an unknown network", func() {
+			iaasClient.EXPECT().GetNetwork(gomock.Any(), "listener-network").Return(nil, &oapiError.GenericOpenAPIError{StatusCode: http.StatusNotFound})
+			Expect(lb.checkListenerNetwork(context.Background(), svc)).To(MatchError(ContainSubstring(`listener network "listener-network" doesn't exist`)))
+			Expect(recorder.Events).To(Receive(Equal(
+				"Warning InvalidListenerNetwork The network listener-network in lb.stackit.cloud/listener-network doesn't exist in project my-project")))
+		})
+
+		It("should not record an event for other errors", func() {
+			iaasClient.EXPECT().GetNetwork(gomock.Any(), "listener-network").Return(nil, errors.New("connection refused"))
+			Expect(lb.checkListenerNetwork(context.Background(), svc)).To(MatchError(ContainSubstring("connection refused")))
+			Expect(recorder.Events).NotTo(Receive())
+		})
+	})
+})
@@ -0,0 +1,82 @@
+package ccm
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
+)
+
+const (
+	// ConditionTypeLoadBalancerPaused is set on the status of services with pausedAnnotation and tells whether the
+	// CCM currently leaves their load balancer untouched.
+	ConditionTypeLoadBalancerPaused = "LoadBalancerPaused"
+
+	ConditionReasonPaused  = "Paused"
+	ConditionReasonResumed = "Resumed"
+)
+
+// paused returns whether the reconciliation of the load balancer of the service is paused by pausedAnnotation.
+func paused(service *corev1.Service) bool {
+	p, _ := strconv.ParseBool(service.Annotations[pausedAnnotation])
+	return p
+}
+
+// pausedLoadBalancerStatus returns the status of the existing load balancer of a paused service without changing it.
+// The service gets a single Paused event per pause. A load balancer that doesn't exist yet isn't created while the
+// service is paused.
+func (l *LoadBalancer) pausedLoadBalancerStatus(ctx context.Context, clusterName string, service *corev1.Service) (*corev1.LoadBalancerStatus, error) {
+	l.reportPaused(ctx, service)
+
+	lb, err := l.client.GetLoadBalancer(ctx, l.GetLoadBalancerName(ctx, clusterName, service))
+	switch {
+	case stackiterrors.IsNotFound(err):
+		return nil, fmt.Errorf("the load balancer doesn't exist and isn't created while %s is set", pausedAnnotation)
+	case err != nil:
+		return nil, fmt.Errorf("failed to get load balancer: %w", err)
+	}
+	return loadBalancerStatus(lb, service), nil
+}
+
+// reportPaused records the Paused event once per pause and sets the LoadBalancerPaused condition.
+func (l *LoadBalancer) reportPaused(ctx context.Context, service *corev1.Service) {
+	if _, notified := l.pausedNotified.LoadOrStore(service.UID, struct{}{}); !notified {
+		l.recorder.Eventf(service, corev1.EventTypeNormal, EventReasonPaused,
+			"The reconciliation of the load balancer is paused by %s, changes of the service aren't applied", pausedAnnotation)
+	}
+	l.setPausedCondition(ctx, service, metav1.Condition{
+		Type:               ConditionTypeLoadBalancerPaused,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: service.Generation,
+		Reason:             ConditionReasonPaused,
+		Message:            "The load balancer isn't reconciled until " + pausedAnnotation + " is removed",
+	})
+}
+
+// reportResumed sets the LoadBalancerPaused condition to false on services that were paused before.
+// Services that were never paused don't get the condition.
+func (l *LoadBalancer) reportResumed(ctx context.Context, service *corev1.Service) {
+	l.pausedNotified.Delete(service.UID)
+	if meta.FindStatusCondition(service.Status.Conditions, ConditionTypeLoadBalancerPaused) == nil {
+		return
+	}
+	l.setPausedCondition(ctx, service, metav1.Condition{
+		Type:               ConditionTypeLoadBalancerPaused,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: service.Generation,
+		Reason:             ConditionReasonResumed,
+		Message:            "The load balancer is reconciled",
+	})
+}
+
+func (l *LoadBalancer) setPausedCondition(ctx context.Context, service *corev1.Service, condition metav1.Condition) {
+	if err := l.patchServiceCondition(ctx, service, condition); err != nil {
+		klog.ErrorS(err, "Failed to set condition on service", "service", klog.KObj(service), "condition", condition.Type)
+	}
+}
@@ -0,0 +1,106 @@
+package ccm
+
+import (
+	"context"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	oapiError "github.com/stackitcloud/stackit-sdk-go/core/oapierror"
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+)
+
+var _ = Describe("Paused load balancers", func() {
+	const clusterName = "my-cluster"
+
+	var (
+		mockClient *stackitclientmock.MockLoadBalancingClient
+		kubeClient *fake.Clientset
+		recorder   *record.FakeRecorder
+		lb         *LoadBalancer
+		svc        *corev1.Service
+		name       string
+	)
+
+	BeforeEach(func() {
+		mockClient = stackitclientmock.NewMockLoadBalancingClient(gomock.NewController(GinkgoT()))
+		var err error
+		lb, err = NewLoadBalancer(mockClient, nil, config.LoadBalancerOpts{NetworkID: "my-network"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		recorder = record.NewFakeRecorder(10)
+		lb.recorder = recorder
+
+		svc = minimalLoadBalancerService()
+		svc.Name = "my-service"
+		svc.Namespace = "default"
+		svc.Annotations[pausedAnnotation] = "true"
+		kubeClient = fake.NewClientset(svc)
+		lb.kubeClient = kubeClient
+		name = lb.GetLoadBalancerName(context.Background(), clusterName, svc)
+	})
+
+	getCondition := func() *metav1.Condition {
+		GinkgoHelper()
+		s, err := kubeClient.CoreV1().Services(svc.Namespace).Get(context.Background(), svc.Name, metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		svc = s
+		return meta.FindStatusCondition(s.Status.Conditions, ConditionTypeLoadBalancerPaused)
+	}
+
+	It("should return the status of the existing load balancer without updating it", func() {
+		mockClient.EXPECT().GetLoadBalancer(gomock.Any(), name).Return(&loadbalancer.LoadBalancer{
+			Name:            new(name),
+			ExternalAddress: new("1.2.3.4"),
+			Status:          new(loadbalancer.LOADBALANCERSTATUS_STATUS_READY),
+		}, nil).Times(2)
+
+		status, err := lb.EnsureLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Ingress).To(ConsistOf(HaveField("IP", "1.2.3.4")))
+		Expect(getCondition()).To(HaveField("Status", metav1.ConditionTrue))
+
+		_, err = lb.EnsureLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorder.Events).To(HaveLen(1))
+		Expect(<-recorder.Events).To(ContainSubstring(EventReasonPaused))
+	})
+
+	It("should not create a missing load balancer", func() {
+		mockClient.EXPECT().GetLoadBalancer(gomock.Any(), name).Return(nil, &oapiError.GenericOpenAPIError{StatusCode: http.StatusNotFound})
+
+		_, err := lb.EnsureLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})
+		Expect(err).To(MatchError(ContainSubstring(pausedAnnotation)))
+	})
+
+	It("should not update the target pools", func() {
+		Expect(lb.UpdateLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})).To(Succeed())
+	})
+
+	It("should set the condition to false once the service is resumed", func() {
+		mockClient.EXPECT().GetLoadBalancer(gomock.Any(), name).Return(&loadbalancer.LoadBalancer{Name: new(name)}, nil)
+		_, err := lb.EnsureLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(getCondition()).NotTo(BeNil())
+
+		delete(svc.Annotations, pausedAnnotation)
+		lb.reportResumed(context.Background(), svc)
+		condition := getCondition()
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Reason).To(Equal(ConditionReasonResumed))
+	})
+
+	It("should not add the condition to services that were never paused", func() {
+		delete(svc.Annotations, pausedAnnotation)
+		lb.reportResumed(context.Background(), svc)
+		Expect(kubeClient.Actions()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,248 @@
+package ccm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/cmp"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+)
+
+// EventReasonPlanRecommendation is a reason for sending an event when a different plan fits the load of a load balancer better
+const EventReasonPlanRecommendation = "PlanRecommendation"
+
+const (
+	defaultPlanRecommendationInterval = time.Hour
+	// planUpscaleThreshold is the utilization of the connection limit of a plan above which a bigger plan is recommended.
+	planUpscaleThreshold = 0.8
+	// planDownscaleThreshold is the utilization of the connection limit of a smaller plan below which it is recommended.
+	// The gap to planUpscaleThreshold avoids alternating recommendations.
+	planDownscaleThreshold = 0.5
+)
+
+type planCapacity struct {
+	planID         string
+	maxConnections float64
+}
+
+// planLister returns the plans of the region, see stackitclient.LoadBalancingClient.
+type planLister interface {
+	ListPlans(ctx context.Context) ([]loadbalancer.PlanDetails, error)
+}
+
+// connectionsQuerier returns the peak number of concurrent connections of a load balancer.
+type connectionsQuerier interface {
+	PeakConnections(ctx context.Context, lbName string) (float64, error)
+}
+
+// planRecommender emits events that recommend a different plan for load balancers.
+type planRecommender struct {
+	querier  connectionsQuerier
+	plans    planLister
+	interval time.Duration
+	now      func() time.Time
+
+	mu sync.Mutex
+	// lastChecked contains the time of the last recommendation per load balancer name.
+	lastChecked map[string]time.Time
+	// capacities is the maximum number of concurrent connections of each plan as reported by the API, ordered by size.
+	// It is loaded once by planCapacities.
+	capacities []planCapacity
+}
+
+// newPlanRecommender returns nil if plan recommendations are disabled.
+func newPlanRecommender(opts stackitconfig.PlanRecommendationOpts, plans planLister) (*planRecommender, error) {
+	if !opts.Enabled {
+		return nil, nil
+	}
+	if opts.PrometheusURL == "" || opts.ConnectionsQuery == "" {
+		return nil, fmt.Errorf("plan recommendations require prometheusUrl and connectionsQuery")
+	}
+	if _, err := url.Parse(opts.PrometheusURL); err != nil {
+		return nil, fmt.Errorf("invalid prometheusUrl: %w", err)
+	}
+	interval := opts.Interval.Duration
+	if interval <= 0 {
+		interval = defaultPlanRecommendationInterval
+	}
+	return &planRecommender{
+		querier: &prometheusQuerier{
+			baseURL: strings.TrimSuffix(opts.PrometheusURL, "/"),
+			query:   opts.ConnectionsQuery,
+			client:  &http.Client{Timeout: 10 * time.Second},
+		},
+		plans:       plans,
+		interval:    interval,
+		now:         time.Now,
+		lastChecked: map[string]time.Time{},
+	}, nil
+}
+
+// recommend emits an event if the load of the load balancer fits a different plan.
+// Errors are only logged, because recommendations must never fail the reconciliation.
+func (r *planRecommender) recommend(ctx context.Context, recorder record.EventRecorder, service *corev1.Service, lb *loadbalancer.LoadBalancer) {
+	recommended, connections, ok := r.evaluate(ctx, service, lb)
+	if !ok {
+		return
+	}
+	recorder.Eventf(service, corev1.EventTypeNormal, EventReasonPlanRecommendation,
+		"The load balancer had up to %.0f concurrent connections, plan %s fits better than %s. Set the annotation %s to change the plan.",
+		connections, recommended, cmp.UnpackPtr(lb.PlanId), servicePlanAnnotation)
+}
+
+// evaluate returns the plan that fits the load of the load balancer better than its current plan.
+// ok is false if the current plan fits, the load balancer has been evaluated within the interval or the query failed.
+func (r *planRecommender) evaluate(ctx context.Context, service *corev1.Service, lb *loadbalancer.LoadBalancer) (
+	plan string, connections float64, ok bool,
+) {
+	name := cmp.UnpackPtr(lb.Name)
+	r.mu.Lock()
+	if last, found := r.lastChecked[name]; found && r.now().Sub(last) < r.interval {
+		r.mu.Unlock()
+		return "", 0, false
+	}
+	r.lastChecked[name] = r.now()
+	r.mu.Unlock()
+
+	connections, err := r.querier.PeakConnections(ctx, name)
+	if err != nil {
+		klog.V(2).InfoS("Failed to query connections for plan recommendation", "service", klog.KObj(service), "loadBalancer", name, "err", err)
+		return "", 0, false
+	}
+	capacities, err := r.planCapacities(ctx)
+	if err != nil {
+		klog.V(2).InfoS("Failed to list plans for plan recommendation", "service", klog.KObj(service), "loadBalancer", name, "err", err)
+		return "", 0, false
+	}
+	plan, ok = recommendPlan(capacities, cmp.UnpackPtr(lb.PlanId), connections)
+	return plan, connections, ok
+}
+
+// planCapacities returns the maximum number of concurrent connections of each plan. The limits are taken from the
+// plans API on first use, plans without a limit are never recommended. Failed calls are retried on the next use.
+func (r *planRecommender) planCapacities(ctx context.Context) ([]planCapacity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.capacities != nil {
+		return r.capacities, nil
+	}
+	plans, err := r.plans.ListPlans(ctx)
+	if err != nil {
+		return nil, err
+	}
+	capacities := []planCapacity{}
+	for _, planID := range availablePlanIDs {
+		i := slices.IndexFunc(plans, func(p loadbalancer.PlanDetails) bool { return p.GetPlanId() == planID })
+		if i >= 0 && plans[i].GetMaxConnections() > 0 {
+			capacities = append(capacities, planCapacity{planID: planID, maxConnections: float64(plans[i].GetMaxConnections())})
+		}
+	}
+	r.capacities = capacities
+	return capacities, nil
+}
+
+// forget removes the state of a deleted load balancer.
+func (r *planRecommender) forget(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.lastChecked, name)
+}
+
+// recommendPlan returns the plan that fits the number of connections if it differs from currentPlan.
+// capacities must be ordered by size.
+func recommendPlan(capacities []planCapacity, currentPlan string, connections float64) (string, bool) {
+	current := slices.IndexFunc(capacities, func(p planCapacity) bool { return p.planID == currentPlan })
+	if current < 0 {
+		return "", false
+	}
+	if connections > planUpscaleThreshold*capacities[current].maxConnections {
+		for _, p := range capacities[current+1:] {
+			if connections <= planUpscaleThreshold*p.maxConnections {
+				return p.planID, true
+			}
+		}
+		// Recommend the biggest plan even if it doesn't have enough headroom.
+		if last := capacities[len(capacities)-1]; last.planID != currentPlan {
+			return last.planID, true
+		}
+		return "", false
+	}
+	// Recommend the smallest plan that has plenty of headroom.
+	for _, p := range capacities[:current] {
+		if connections < planDownscaleThreshold*p.maxConnections {
+			return p.planID, true
+		}
+	}
+	return "", false
+}
+
+// prometheusQuerier runs an instant query against the Prometheus HTTP API.
+type prometheusQuerier struct {
+	baseURL string
+	query   string
+	client  *http.Client
+}
+
+type prometheusResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		Result []struct {
+			Value []any `json:"value"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+func (q *prometheusQuerier) PeakConnections(ctx context.Context, lbName string) (float64, error) {
+	query := strings.ReplaceAll(q.query, "{{name}}", lbName)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, q.baseURL+"/api/v1/query?"+url.Values{"query": {query}}.Encode(), http.NoBody)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := q.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var result prometheusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("decode response with status %d: %w", resp.StatusCode, err)
+	}
+	if result.Status != "success" {
+		return 0, fmt.Errorf("query failed: %s", result.Error)
+	}
+	if len(result.Data.Result) == 0 {
+		return 0, fmt.Errorf("query returned no samples")
+	}
+	peak := 0.0
+	for _, sample := range result.Data.Result {
+		// An instant vector sample is a pair of timestamp and value, where the value is a string.
+		if len(sample.Value) != 2 {
+			return 0, fmt.Errorf("unexpected sample %v", sample.Value)
+		}
+		valueStr, ok := sample.Value[1].(string)
+		if !ok {
+			return 0, fmt.Errorf("unexpected sample value %v", sample.Value[1])
+		}
+		value, err := strconv.ParseFloat(valueStr, 64)
+		if err != nil {
+			return 0, fmt.Errorf("parse sample value: %w", err)
+		}
+		peak = max(peak, value)
+	}
+	return peak, nil
+}
@@ -0,0 +1,178 @@
+package ccm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	stackitclientfake "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/fake"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/metadata"
+)
+
+type fakeConnectionsQuerier struct {
+	connections float64
+	err         error
+	calls       int
+}
+
+func (f *fakeConnectionsQuerier) PeakConnections(_ context.Context, _ string) (float64, error) {
+	f.calls++
+	return f.connections, f.err
+}
+
+// testPlanCapacities are the connection limits of the plans in the tests.
+var testPlanCapacities = []planCapacity{
+	{p10, 10_000},
+	{p50, 50_000},
+	{p250, 250_000},
+	{p750, 750_000},
+}
+
+var _ = Describe("Plan recommendation", func() {
+	DescribeTable("recommendPlan", func(currentPlan string, connections float64, expectedPlan string, expectedOK bool) {
+		plan, ok := recommendPlan(testPlanCapacities, currentPlan, connections)
+		Expect(ok).To(Equal(expectedOK))
+		Expect(plan).To(Equal(expectedPlan))
+	},
+		Entry("keep plan with moderate load", p50, 30_000.0, "", false),
+		Entry("upscale to next plan", p10, 9_000.0, p50, true),
+		Entry("upscale by multiple plans", p10, 100_000.0, p250, true),
+		Entry("upscale to biggest plan without headroom", p50, 1_000_000.0, p750, true),
+		Entry("keep biggest plan", p750, 1_000_000.0, "", false),
+		Entry("downscale to smallest plan with headroom", p250, 4_000.0, p10, true),
+		Entry("downscale to next plan", p250, 20_000.0, p50, true),
+		Entry("keep plan within the hysteresis", p50, 6_000.0, "", false),
+		Entry("ignore unknown plans", "p1000", 1.0, "", false),
+	)
+
+	Describe("recommend", func() {
+		var (
+			querier     *fakeConnectionsQuerier
+			recommender *planRecommender
+			recorder    *record.FakeRecorder
+			svc         *corev1.Service
+			lb          *loadbalancer.LoadBalancer
+			now         time.Time
+		)
+
+		BeforeEach(func() {
+			now = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+			querier = &fakeConnectionsQuerier{connections: 45_000}
+			recommender = &planRecommender{
+				querier:     querier,
+				interval:    time.Hour,
+				now:         func() time.Time { return now },
+				lastChecked: map[string]time.Time{},
+				capacities:  testPlanCapacities,
+			}
+			recorder = record.NewFakeRecorder(10)
+			svc = minimalLoadBalancerService()
+			lb = &loadbalancer.LoadBalancer{Name: new("my-lb"), PlanId: new(p10)}
+		})
+
+		It("should emit an event with the recommended plan", func() {
+			recommender.recommend(context.Background(), recorder, svc, lb)
+			Expect(recorder.Events).To(Receive(And(
+				ContainSubstring(EventReasonPlanRecommendation),
+				ContainSubstring("plan p250 fits better than p10"),
+			)))
+		})
+
+		It("should only query once per interval", func() {
+			recommender.recommend(context.Background(), recorder, svc, lb)
+			now = now.Add(30 * time.Minute)
+			recommender.recommend(context.Background(), recorder, svc, lb)
+			Expect(querier.calls).To(Equal(1))
+
+			now = now.Add(time.Hour)
+			recommender.recommend(context.Background(), recorder, svc, lb)
+			Expect(querier.calls).To(Equal(2))
+		})
+
+		It("should not emit events if the query fails", func() {
+			querier.err = errors.New("injected error")
+			recommender.recommend(context.Background(), recorder, svc, lb)
+			Expect(recorder.Events).NotTo(Receive())
+		})
+
+		It("should take the connection limits from the plans API", func() {
+			client := stackitclientfake.NewLoadBalancingClient()
+			client.Plans = []loadbalancer.PlanDetails{
+				{PlanId: new(p250), MaxConnections: new(int32(100_000))},
+				{PlanId: new(p10), MaxConnections: new(int32(20_000))},
+				{PlanId: new(p50), MaxConnections: new(int32(60_000))},
+				{PlanId: new("p1000")},
+			}
+			recommender.plans = client
+			recommender.capacities = nil
+
+			recommender.recommend(context.Background(), recorder, svc, lb)
+			Expect(recorder.Events).To(Receive(ContainSubstring("plan p50 fits better than p10")))
+			Expect(recommender.capacities).To(Equal([]planCapacity{{p10, 20_000}, {p50, 60_000}, {p250, 100_000}}))
+
+			now = now.Add(2 * time.Hour)
+			recommender.recommend(context.Background(), recorder, svc, lb)
+			Expect(client.Calls()).To(Equal([]string{stackitclientfake.OpListPlans}))
+		})
+
+		It("should not emit events if the plans can't be listed", func() {
+			client := stackitclientfake.NewLoadBalancingClient()
+			client.FailNext(stackitclientfake.OpListPlans, stackitclientfake.APIError(http.StatusInternalServerError, "internal error"))
+			recommender.plans = client
+			recommender.capacities = nil
+
+			recommender.recommend(context.Background(), recorder, svc, lb)
+			Expect(recorder.Events).NotTo(Receive())
+			Expect(recommender.capacities).To(BeNil())
+		})
+	})
+
+	Describe("prometheusQuerier", func() {
+		It("should return the highest sample", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				Expect(r.URL.Path).To(Equal("/api/v1/query"))
+				Expect(r.URL.Query().Get("query")).To(Equal(`max(connections{lb="my-lb"})`))
+				_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[` +
+					`{"metric":{},"value":[1767323045,"1200"]},{"metric":{},"value":[1767323045,"3400.5"]}]}}`))
+			}))
+			DeferCleanup(server.Close)
+
+			recommender, err := newPlanRecommender(stackitconfig.PlanRecommendationOpts{
+				Enabled:          true,
+				PrometheusURL:    server.URL + "/",
+				ConnectionsQuery: `max(connections{lb="{{name}}"})`,
+				Interval:         metadata.Duration{Duration: time.Minute},
+			}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recommender.interval).To(Equal(time.Minute))
+			Expect(recommender.querier.PeakConnections(context.Background(), "my-lb")).To(Equal(3400.5))
+		})
+
+		It("should fail on empty results", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`))
+			}))
+			DeferCleanup(server.Close)
+
+			querier := &prometheusQuerier{baseURL: server.URL, query: "up", client: server.Client()}
+			_, err := querier.PeakConnections(context.Background(), "my-lb")
+			Expect(err).To(MatchError(ContainSubstring("no samples")))
+		})
+	})
+
+	It("should be disabled by default", func() {
+		Expect(newPlanRecommender(stackitconfig.PlanRecommendationOpts{}, nil)).To(BeNil())
+		_, err := newPlanRecommender(stackitconfig.PlanRecommendationOpts{Enabled: true}, nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,122 @@
+package ccm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// specConfigMapPrefix is the prefix of the ConfigMaps that contain the desired load balancer of a service,
+	// followed by the name of the service.
+	specConfigMapPrefix = "stackit-lb-spec-"
+	// specConfigMapKey is the key of the desired load balancer in the ConfigMap, rendered as JSON.
+	specConfigMapKey = "spec.json"
+	// specServiceLabel is the label of the ConfigMaps that contains the name of their service.
+	specServiceLabel = "lb.stackit.cloud/service-name"
+
+	// EventReasonSpecConfigMapConflict is a reason for sending an event when the ConfigMap for the published spec of a
+	// service already exists, but isn't owned by the service
+	EventReasonSpecConfigMapConflict = "SpecConfigMapConflict"
+)
+
+// publishSpec renders the desired load balancer of the service into a ConfigMap if enabled by
+// LoadBalancerOpts.PublishSpec. The ConfigMap is owned by the service, so it is deleted together with it.
+// The API is only called if the spec changed since it was published last. Errors are logged and don't fail the
+// reconciliation.
+func (l *LoadBalancer) publishSpec(ctx context.Context, service *corev1.Service, name string, spec *loadbalancer.CreateLoadBalancerPayload) {
+	if !l.opts.PublishSpec || l.kubeClient == nil {
+		return
+	}
+
+	desired := *spec
+	desired.Name = &name
+	rendered, err := json.MarshalIndent(desired, "", "  ")
+	if err != nil {
+		klog.ErrorS(err, "Failed to render load balancer spec", "service", klog.KObj(service))
+		return
+	}
+	if published, ok := l.publishedSpecs.Load(service.UID); ok && published == string(rendered) {
+		return
+	}
+
+	if err := l.applySpecConfigMap(ctx, service, string(rendered)); err != nil {
+		klog.ErrorS(err, "Failed to publish load balancer spec", "service", klog.KObj(service))
+		return
+	}
+	l.publishedSpecs.Store(service.UID, string(rendered))
+}
+
+// applySpecConfigMap creates or updates the ConfigMap containing the rendered spec of the service.
+// ConfigMaps with the same name that aren't owned by the service are left alone and reported in an event.
+func (l *LoadBalancer) applySpecConfigMap(ctx context.Context, service *corev1.Service, rendered string) error {
+	configMaps := l.kubeClient.CoreV1().ConfigMaps(service.Namespace)
+	configMap, err := configMaps.Get(ctx, specConfigMapPrefix+service.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      specConfigMapPrefix + service.Name,
+				Namespace: service.Namespace,
+				Labels:    map[string]string{specServiceLabel: service.Name},
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: "v1",
+					Kind:       "Service",
+					Name:       service.Name,
+					UID:        service.UID,
+				}},
+			},
+			Data: map[string]string{specConfigMapKey: rendered},
+		}
+		_, err = configMaps.Create(ctx, configMap, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if !ownedByService(configMap, service) {
+		l.recorder.Eventf(service, corev1.EventTypeWarning, EventReasonSpecConfigMapConflict,
+			"Not publishing the load balancer spec, ConfigMap %s already exists and isn't owned by the service", configMap.Name)
+		return fmt.Errorf("ConfigMap %s isn't owned by the service", configMap.Name)
+	}
+	if configMap.Data[specConfigMapKey] == rendered {
+		return nil
+	}
+	configMap = configMap.DeepCopy()
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	configMap.Data[specConfigMapKey] = rendered
+	_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{})
+	return err
+}
+
+// unpublishSpec deletes the ConfigMap of a service whose load balancer is deleted, e.g. because it was switched to
+// another type. The ConfigMap of a deleted service is also garbage collected because of its owner reference.
+func (l *LoadBalancer) unpublishSpec(ctx context.Context, service *corev1.Service) {
+	l.publishedSpecs.Delete(service.UID)
+	if !l.opts.PublishSpec || l.kubeClient == nil {
+		return
+	}
+	configMaps := l.kubeClient.CoreV1().ConfigMaps(service.Namespace)
+	configMap, err := configMaps.Get(ctx, specConfigMapPrefix+service.Name, metav1.GetOptions{})
+	if err == nil && ownedByService(configMap, service) {
+		err = configMaps.Delete(ctx, configMap.Name, metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &configMap.UID}})
+	}
+	if err != nil && !apierrors.IsNotFound(err) {
+		klog.ErrorS(err, "Failed to delete published load balancer spec", "service", klog.KObj(service))
+	}
+}
+
+// ownedByService returns whether the ConfigMap was created for the published spec of the service.
+func ownedByService(configMap *corev1.ConfigMap, service *corev1.Service) bool {
+	return slices.ContainsFunc(configMap.OwnerReferences, func(ref metav1.OwnerReference) bool {
+		return ref.Kind == "Service" && ref.UID == service.UID
+	})
+}
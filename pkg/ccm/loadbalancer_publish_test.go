@@ -0,0 +1,105 @@
+package ccm
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+)
+
+var _ = Describe("Publishing load balancer specs", func() {
+	var (
+		kubeClient *fake.Clientset
+		recorder   *record.FakeRecorder
+		lb         *LoadBalancer
+		svc        *corev1.Service
+		spec       *loadbalancer.CreateLoadBalancerPayload
+	)
+
+	BeforeEach(func() {
+		var err error
+		lb, err = NewLoadBalancer(nil, nil, stackitconfig.LoadBalancerOpts{NetworkID: "my-network", PublishSpec: true}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		svc = minimalLoadBalancerService()
+		svc.Name = "my-service"
+		svc.Namespace = "default"
+		kubeClient = fake.NewClientset(svc)
+		lb.kubeClient = kubeClient
+		recorder = record.NewFakeRecorder(10)
+		lb.recorder = recorder
+
+		spec, _, err = lbSpecFromService(svc, []*corev1.Node{}, lb.opts, nil)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	getConfigMap := func() *corev1.ConfigMap {
+		GinkgoHelper()
+		configMap, err := kubeClient.CoreV1().ConfigMaps(svc.Namespace).Get(context.Background(), "stackit-lb-spec-my-service", metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		return configMap
+	}
+
+	It("should render the spec into a ConfigMap owned by the service", func() {
+		lb.publishSpec(context.Background(), svc, "my-lb", spec)
+
+		configMap := getConfigMap()
+		Expect(configMap.OwnerReferences).To(ConsistOf(HaveField("UID", svc.UID)))
+		Expect(configMap.Labels).To(HaveKeyWithValue(specServiceLabel, "my-service"))
+		Expect(configMap.Data[specConfigMapKey]).To(ContainSubstring(`"name": "my-lb"`))
+		Expect(configMap.Data[specConfigMapKey]).To(ContainSubstring(`"externalAddress": "123.124.88.99"`))
+		Expect(spec.Name).To(BeNil())
+	})
+
+	It("should update the ConfigMap only if the spec changed", func() {
+		lb.publishSpec(context.Background(), svc, "my-lb", spec)
+		kubeClient.ClearActions()
+
+		lb.publishSpec(context.Background(), svc, "my-lb", spec)
+		Expect(kubeClient.Actions()).To(BeEmpty())
+
+		spec.PlanId = new("p50")
+		lb.publishSpec(context.Background(), svc, "my-lb", spec)
+		Expect(getConfigMap().Data[specConfigMapKey]).To(ContainSubstring(`"planId": "p50"`))
+	})
+
+	It("should delete the ConfigMap together with the load balancer", func() {
+		lb.publishSpec(context.Background(), svc, "my-lb", spec)
+		lb.unpublishSpec(context.Background(), svc)
+
+		_, err := kubeClient.CoreV1().ConfigMaps(svc.Namespace).Get(context.Background(), "stackit-lb-spec-my-service", metav1.GetOptions{})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should not touch ConfigMaps that aren't owned by the service", func() {
+		foreign := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "stackit-lb-spec-my-service", Namespace: svc.Namespace},
+			Data:       map[string]string{"config": "foreign"},
+		}
+		_, err := kubeClient.CoreV1().ConfigMaps(svc.Namespace).Create(context.Background(), foreign, metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		lb.publishSpec(context.Background(), svc, "my-lb", spec)
+		Expect(getConfigMap().Data).To(Equal(foreign.Data))
+		Expect(recorder.Events).To(Receive(And(
+			ContainSubstring(EventReasonSpecConfigMapConflict),
+			ContainSubstring("isn't owned by the service"),
+		)))
+
+		lb.unpublishSpec(context.Background(), svc)
+		Expect(getConfigMap().Data).To(Equal(foreign.Data))
+	})
+
+	It("should not publish the spec if disabled", func() {
+		lb.opts.PublishSpec = false
+		lb.publishSpec(context.Background(), svc, "my-lb", spec)
+		Expect(kubeClient.Actions()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,38 @@
+package ccm
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/metrics"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
+)
+
+// checkLoadBalancerQuota checks that another load balancer can be created in the project.
+// If the quota is exhausted, it records an event with the usage and returns an error wrapping
+// stackiterrors.ErrQuotaExceeded, so the create call isn't attempted.
+// The check is best effort: if the quota can't be read, the load balancer is created anyway.
+func (l *LoadBalancer) checkLoadBalancerQuota(ctx context.Context, service *corev1.Service) error {
+	quota, err := l.client.GetQuota(ctx)
+	if err != nil {
+		klog.ErrorS(err, "Failed to get load balancer quota, creating load balancer anyway", "projectID", l.projectID, "service", klog.KObj(service))
+		return nil
+	}
+	if quota.MaxLoadBalancers == nil || quota.UsedLoadBalancers == nil {
+		return nil
+	}
+
+	maxLoadBalancers, used := *quota.MaxLoadBalancers, *quota.UsedLoadBalancers
+	metrics.LoadBalancerQuotaRemaining.Set(float64(max(maxLoadBalancers-used, 0)))
+	if used < maxLoadBalancers {
+		return nil
+	}
+
+	l.recorder.Eventf(service, corev1.EventTypeWarning, EventReasonQuotaExceeded,
+		"Load balancer quota exceeded in project %s: %d/%d load balancers used, delete unused load balancers or request a quota increase",
+		l.projectID, used, maxLoadBalancers)
+	return fmt.Errorf("%w: %d/%d load balancers used", stackiterrors.ErrQuotaExceeded, used, maxLoadBalancers)
+}
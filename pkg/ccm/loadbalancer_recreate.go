@@ -0,0 +1,87 @@
+package ccm
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/cloud-provider/api"
+	"k8s.io/klog/v2"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
+)
+
+// defaultRecreateDelay is the time the CCM waits before it deletes a load balancer to recreate it, unless the
+// recreateDelay of the cloud config is set.
+const defaultRecreateDelay = 5 * time.Minute
+
+// allowsRecreate returns whether the load balancer of the service may be recreated to apply changes of immutable
+// fields, see allowRecreateAnnotation.
+func allowsRecreate(service *corev1.Service) bool {
+	allow, _ := strconv.ParseBool(service.Annotations[allowRecreateAnnotation])
+	return allow
+}
+
+// recreateDelay returns the time between detecting an immutable change and deleting the load balancer.
+func (l *LoadBalancer) recreateDelay() time.Duration {
+	if l.opts.RecreateDelay.Duration > 0 {
+		return l.opts.RecreateDelay.Duration
+	}
+	return defaultRecreateDelay
+}
+
+// recreateLoadBalancer deletes the load balancer of the service once the recreate delay since the first detection of
+// the immutable change is over, so that it is created again with the new specification by the next reconciliation.
+// Until then, and while the load balancer is being deleted, a RetryError is returned.
+func (l *LoadBalancer) recreateLoadBalancer(ctx context.Context, service *corev1.Service, name, change string) error {
+	now := l.now()
+	delay := l.recreateDelay()
+	value, scheduled := l.recreateAt.LoadOrStore(name, now.Add(delay))
+	at := value.(time.Time)
+	if !scheduled {
+		klog.InfoS("Scheduled recreation of load balancer", "service", klog.KObj(service), "loadBalancer", name,
+			"change", change, "delay", delay)
+		l.recorder.Eventf(service, corev1.EventTypeWarning, EventReasonRecreating,
+			"Recreating load balancer %s in %s because %s can't be updated. Its external address changes unless it is retained or "+
+				"set via %s. Revert the change or remove the annotation %s to cancel",
+			name, delay, change, externalIPAnnotation, allowRecreateAnnotation)
+	}
+	if remaining := at.Sub(now); remaining > 0 {
+		return api.NewRetryError(
+			fmt.Sprintf("waiting %s before recreating the load balancer because %s can't be updated", remaining.Round(time.Second), change),
+			remaining,
+		)
+	}
+
+	if err := l.client.DeleteLoadBalancer(ctx, name); err != nil && !stackiterrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete load balancer to recreate it: %w", err)
+	}
+	// The observability credentials are still referenced until the deletion finished, createLoadBalancer replaces them.
+	if err := l.cleanUpLoadBalancer(ctx, name); err != nil {
+		return err
+	}
+	l.recorder.Eventf(service, corev1.EventTypeNormal, EventReasonRecreating,
+		"Deleted load balancer %s to recreate it because %s can't be updated", name, change)
+	return api.NewRetryError("waiting for the deletion of the load balancer to finish before recreating it", retryDuration)
+}
+
+// cleanUpLoadBalancer drops the state kept for the load balancer name and removes its node port rules, once the load
+// balancer is deleted or recreated.
+func (l *LoadBalancer) cleanUpLoadBalancer(ctx context.Context, name string) error {
+	if l.planRecommender != nil {
+		l.planRecommender.forget(name)
+	}
+	l.forgetDrainingTargets(name)
+	l.forgetRecreation(name)
+	if err := l.deleteNodePortRules(ctx, name); err != nil {
+		return fmt.Errorf("delete node port security group rules: %w", err)
+	}
+	return nil
+}
+
+// forgetRecreation cancels a scheduled recreation of a load balancer, e.g. because the change was reverted.
+func (l *LoadBalancer) forgetRecreation(name string) {
+	l.recreateAt.Delete(name)
+}
@@ -0,0 +1,151 @@
+package ccm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	oapiError "github.com/stackitcloud/stackit-sdk-go/core/oapierror"
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/cloud-provider/api"
+
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/metadata"
+)
+
+var _ = Describe("Recreating load balancers", func() {
+	const clusterName = "my-cluster"
+
+	var (
+		mockClient *stackitclientmock.MockLoadBalancingClient
+		recorder   *record.FakeRecorder
+		lb         *LoadBalancer
+		svc        *corev1.Service
+		currentLB  *loadbalancer.LoadBalancer
+		name       string
+		now        time.Time
+	)
+
+	expectRetryError := func(err error, substring string) {
+		GinkgoHelper()
+		var retryErr *api.RetryError
+		Expect(errors.As(err, &retryErr)).To(BeTrue(), "expected a RetryError, got %v", err)
+		Expect(err).To(MatchError(ContainSubstring(substring)))
+	}
+	ensure := func() error {
+		_, err := lb.EnsureLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})
+		return err
+	}
+
+	BeforeEach(func() {
+		mockClient = stackitclientmock.NewMockLoadBalancingClient(gomock.NewController(GinkgoT()))
+		var err error
+		lb, err = NewLoadBalancer(mockClient, nil, config.LoadBalancerOpts{
+			NetworkID:     "my-network",
+			RecreateDelay: metadata.Duration{Duration: 2 * time.Minute},
+		}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		recorder = record.NewFakeRecorder(10)
+		lb.recorder = recorder
+		now = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		lb.now = func() time.Time { return now }
+
+		svc = minimalLoadBalancerService()
+		name = lb.GetLoadBalancerName(context.Background(), clusterName, svc)
+		spec, _, err := lbSpecFromService(svc, []*corev1.Node{}, lb.opts, nil)
+		Expect(err).NotTo(HaveOccurred())
+		currentLB = &loadbalancer.LoadBalancer{
+			ExternalAddress: spec.ExternalAddress,
+			Listeners:       spec.Listeners,
+			Name:            new(name),
+			Networks:        spec.Networks,
+			Options:         spec.Options,
+			Status:          new(loadbalancer.LOADBALANCERSTATUS_STATUS_READY),
+			TargetPools:     spec.TargetPools,
+			Version:         new("current-version"),
+			PlanId:          spec.PlanId,
+		}
+
+		// Switching to an internal load balancer can't be applied by an update.
+		svc.Annotations[internalLBAnnotation] = "true"
+		svc.Annotations[allowRecreateAnnotation] = "true"
+	})
+
+	It("should fail without the annotation", func() {
+		delete(svc.Annotations, allowRecreateAnnotation)
+		mockClient.EXPECT().GetLoadBalancer(gomock.Any(), name).Return(currentLB, nil)
+
+		Expect(ensure()).To(MatchError(ContainSubstring("cannot be fulfilled")))
+	})
+
+	It("should delete the load balancer after the delay and recreate it", func() {
+		mockClient.EXPECT().GetLoadBalancer(gomock.Any(), name).Return(currentLB, nil).Times(2)
+		expectRetryError(ensure(), "waiting 2m0s before recreating")
+		Expect(recorder.Events).To(Receive(And(
+			ContainSubstring(EventReasonRecreating), ContainSubstring(internalLBAnnotation), ContainSubstring("2m0s"),
+		)))
+
+		// The delay isn't restarted by later reconciliations.
+		now = now.Add(time.Minute)
+		expectRetryError(ensure(), "waiting 1m0s before recreating")
+		Expect(recorder.Events).NotTo(Receive())
+
+		now = now.Add(time.Minute)
+		mockClient.EXPECT().GetLoadBalancer(gomock.Any(), name).Return(currentLB, nil)
+		mockClient.EXPECT().DeleteLoadBalancer(gomock.Any(), name).Return(nil)
+		expectRetryError(ensure(), "waiting for the deletion")
+		Expect(recorder.Events).To(Receive(ContainSubstring("Deleted load balancer")))
+
+		mockClient.EXPECT().GetLoadBalancer(gomock.Any(), name).Return(nil, &oapiError.GenericOpenAPIError{StatusCode: http.StatusNotFound})
+		mockClient.EXPECT().GetQuota(gomock.Any()).Return(&loadbalancer.GetQuotaResponse{
+			MaxLoadBalancers: new(int32(10)), UsedLoadBalancers: new(int32(0)),
+		}, nil)
+		mockClient.EXPECT().CreateLoadBalancer(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, payload *loadbalancer.CreateLoadBalancerPayload) (*loadbalancer.LoadBalancer, error) {
+				Expect(payload.Options.PrivateNetworkOnly).To(HaveValue(BeTrue()))
+				return &loadbalancer.LoadBalancer{}, nil
+			})
+		Expect(ensure()).To(MatchError(notYetReadyError))
+	})
+
+	It("should drop the state of the deleted load balancer", func() {
+		lb.planRecommender = &planRecommender{
+			querier:     &fakeConnectionsQuerier{},
+			interval:    time.Hour,
+			now:         func() time.Time { return now },
+			lastChecked: map[string]time.Time{name: now},
+			capacities:  testPlanCapacities,
+		}
+		mockClient.EXPECT().GetLoadBalancer(gomock.Any(), name).Return(currentLB, nil).Times(2)
+		expectRetryError(ensure(), "before recreating")
+
+		now = now.Add(2 * time.Minute)
+		mockClient.EXPECT().DeleteLoadBalancer(gomock.Any(), name).Return(nil)
+		expectRetryError(ensure(), "waiting for the deletion")
+		Expect(lb.planRecommender.lastChecked).NotTo(HaveKey(name))
+		_, scheduled := lb.recreateAt.Load(name)
+		Expect(scheduled).To(BeFalse())
+	})
+
+	It("should cancel the recreation if the change is reverted", func() {
+		mockClient.EXPECT().GetLoadBalancer(gomock.Any(), name).Return(currentLB, nil).Times(2)
+		expectRetryError(ensure(), "before recreating")
+
+		delete(svc.Annotations, internalLBAnnotation)
+		Expect(ensure()).To(Succeed())
+		_, scheduled := lb.recreateAt.Load(name)
+		Expect(scheduled).To(BeFalse())
+	})
+
+	It("should default the delay", func() {
+		lb.opts.RecreateDelay = metadata.Duration{}
+		Expect(lb.recreateDelay()).To(Equal(defaultRecreateDelay))
+	})
+})
@@ -0,0 +1,188 @@
+package ccm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/cloud-provider/api"
+	"k8s.io/klog/v2"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/cmp"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/labels"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
+)
+
+const (
+	// retainedIPAnnotation is set by the CCM and holds the external IP that it retains for the service.
+	// It is used to keep the IP on the load balancer, since static IPs can't be demoted, and to release the IP when
+	// the service is deleted after retainIPAnnotation was removed.
+	retainedIPAnnotation = "lb.stackit.cloud/retained-ip"
+
+	// The labels of a retained public IP identify the service it is reused for.
+	retainedIPClusterLabel   = "k8s-cluster-id"
+	retainedIPNamespaceLabel = "k8s-service-namespace"
+	retainedIPNameLabel      = "k8s-service-name"
+
+	// EventReasonRetainedIP is a reason for sending an event when the external IP of a service is retained or reused
+	EventReasonRetainedIP = "RetainedExternalIP"
+)
+
+// retainsIP returns whether the service asks to retain its external IP across recreation, see retainIPAnnotation.
+func retainsIP(service *corev1.Service) bool {
+	retain, _ := strconv.ParseBool(service.Annotations[retainIPAnnotation])
+	return retain
+}
+
+// retainedIPLabels returns the labels of the public IP that is retained for the service.
+func (l *LoadBalancer) retainedIPLabels(service *corev1.Service) map[string]string {
+	return map[string]string{
+		retainedIPClusterLabel:   labels.Sanitize(l.clusterID),
+		retainedIPNamespaceLabel: service.Namespace,
+		retainedIPNameLabel:      service.Name,
+	}
+}
+
+// findRetainedIP returns the public IP that is retained for the service or nil if there is none.
+// If address is not empty, only a public IP with this address is returned.
+func (l *LoadBalancer) findRetainedIP(ctx context.Context, service *corev1.Service, address string) (*iaas.PublicIp, error) {
+	publicIPs, err := l.iaasClient.ListPublicIPs(ctx, labelSelector(l.retainedIPLabels(service)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list retained public IPs: %w", err)
+	}
+	for i := range publicIPs {
+		if address == "" || publicIPs[i].GetIp() == address {
+			return &publicIPs[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// labelSelector returns the label selector of the IaaS API that matches all labels, e.g. "key=value,other=value".
+func labelSelector(labels map[string]string) string {
+	var selector []string
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		selector = append(selector, key+"="+labels[key])
+	}
+	return strings.Join(selector, ",")
+}
+
+// applyRetainedIP sets the retained IP as static external address of spec.
+// On creation (lb is nil), a public IP retained for a previous service with the same namespace and name is reused.
+// Afterwards, the ephemeral IP of the load balancer is promoted to a static IP that stays on the load balancer,
+// even if the annotation is removed, because the API doesn't support demoting it.
+// Internal load balancers and load balancers with an explicit external address are not changed.
+func (l *LoadBalancer) applyRetainedIP(
+	ctx context.Context,
+	service *corev1.Service,
+	spec *loadbalancer.CreateLoadBalancerPayload,
+	lb *loadbalancer.LoadBalancer,
+) error {
+	if !cmp.UnpackPtr(spec.Options.EphemeralAddress) {
+		return nil
+	}
+
+	var address string
+	switch {
+	case lb == nil:
+		if !retainsIP(service) {
+			return nil
+		}
+		publicIP, err := l.findRetainedIP(ctx, service, "")
+		if err != nil || publicIP == nil {
+			return err
+		}
+		address = publicIP.GetIp()
+		l.recorder.Eventf(service, corev1.EventTypeNormal, EventReasonRetainedIP, "Reusing the retained external IP %s", address)
+	case cmp.UnpackPtr(cmp.UnpackPtr(lb.Options).EphemeralAddress):
+		address = cmp.UnpackPtr(lb.ExternalAddress)
+		if !retainsIP(service) || address == "" {
+			return nil
+		}
+		l.recorder.Eventf(service, corev1.EventTypeNormal, EventReasonRetainedIP,
+			"Promoting the ephemeral external IP %s to a static IP that is retained when the service is recreated", address)
+	default:
+		address = cmp.UnpackPtr(lb.ExternalAddress)
+		if !retainsIP(service) && service.Annotations[retainedIPAnnotation] != address {
+			return nil
+		}
+	}
+
+	spec.ExternalAddress = &address
+	spec.Options.EphemeralAddress = new(false)
+	return nil
+}
+
+// labelRetainedIP labels the static IP of a load balancer that retains its IP, so it can be found when the service
+// is recreated, and stores the address on the service.
+func (l *LoadBalancer) labelRetainedIP(ctx context.Context, service *corev1.Service, lb *loadbalancer.LoadBalancer) error {
+	address := cmp.UnpackPtr(lb.ExternalAddress)
+	if !retainsIP(service) || address == "" || cmp.UnpackPtr(cmp.UnpackPtr(lb.Options).EphemeralAddress) ||
+		service.Annotations[retainedIPAnnotation] == address {
+		return nil
+	}
+
+	publicIPs, err := l.iaasClient.ListPublicIPs(ctx, "")
+	if err != nil {
+		return fmt.Errorf("failed to list public IPs: %w", err)
+	}
+	idx := slices.IndexFunc(publicIPs, func(publicIP iaas.PublicIp) bool { return publicIP.GetIp() == address })
+	if idx < 0 {
+		return fmt.Errorf("public IP %s of the load balancer not found", address)
+	}
+	publicIP := publicIPs[idx]
+
+	retainedLabels := l.retainedIPLabels(service)
+	if !labelsMatch(publicIP.Labels, retainedLabels) {
+		// The API merges the labels of the update into the labels of the public IP.
+		payload := iaas.UpdatePublicIPPayload{Labels: make(map[string]any, len(retainedLabels))}
+		for key, value := range retainedLabels {
+			payload.Labels[key] = value
+		}
+		if _, err := l.iaasClient.UpdatePublicIP(ctx, publicIP.GetId(), payload); err != nil {
+			return fmt.Errorf("failed to label retained public IP %s: %w", address, err)
+		}
+	}
+	return l.patchServiceAnnotation(ctx, service, retainedIPAnnotation, &address)
+}
+
+// labelsMatch returns whether all desired labels are set.
+func labelsMatch(current map[string]any, desired map[string]string) bool {
+	for key, value := range desired {
+		if current[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// releaseRetainedIP deletes the public IP retained for the service once the service doesn't ask for it anymore.
+// It must be called after the load balancer was deleted and returns an api.RetryError while the load balancer still
+// uses the IP.
+func (l *LoadBalancer) releaseRetainedIP(ctx context.Context, service *corev1.Service) error {
+	address := service.Annotations[retainedIPAnnotation]
+	if retainsIP(service) || address == "" {
+		return nil
+	}
+
+	publicIP, err := l.findRetainedIP(ctx, service, address)
+	if err != nil || publicIP == nil {
+		return err
+	}
+	err = l.iaasClient.DeletePublicIP(ctx, publicIP.GetId())
+	switch {
+	case errors.Is(err, stackiterrors.ErrConflict):
+		return api.NewRetryError(fmt.Sprintf("waiting for the load balancer to release the retained IP %s", address), retryDuration)
+	case err != nil && !stackiterrors.IsNotFound(err):
+		return fmt.Errorf("failed to release retained public IP %s: %w", address, err)
+	}
+	klog.InfoS("Released retained public IP", "service", klog.KObj(service), "ip", address)
+	return nil
+}
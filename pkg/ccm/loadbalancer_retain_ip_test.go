@@ -0,0 +1,190 @@
+package ccm
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/cloud-provider/api"
+
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
+)
+
+var _ = Describe("Retained IPs", func() {
+	const (
+		address  = "1.2.3.4"
+		ipID     = "7ab7e2b0-0d2d-4f46-9c3b-6e3c9bdc4a53"
+		selector = "k8s-cluster-id=my-cluster,k8s-service-name=my-service,k8s-service-namespace=default"
+	)
+
+	var (
+		mockIaaSClient *stackitclientmock.MockIaaSClient
+		kubeClient     *fake.Clientset
+		recorder       *record.FakeRecorder
+		lb             *LoadBalancer
+		svc            *corev1.Service
+		spec           *loadbalancer.CreateLoadBalancerPayload
+	)
+
+	BeforeEach(func() {
+		ctrl := gomock.NewController(GinkgoT())
+		mockIaaSClient = stackitclientmock.NewMockIaaSClient(ctrl)
+		var err error
+		lb, err = NewLoadBalancer(stackitclientmock.NewMockLoadBalancingClient(ctrl), mockIaaSClient,
+			stackitconfig.LoadBalancerOpts{NetworkID: "my-network"}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		lb.clusterID = "my-cluster"
+		recorder = record.NewFakeRecorder(10)
+		lb.recorder = recorder
+
+		svc = &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "my-service",
+				Namespace:   "default",
+				Annotations: map[string]string{retainIPAnnotation: "true"},
+			},
+			Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+		}
+		kubeClient = fake.NewClientset(svc)
+		lb.kubeClient = kubeClient
+
+		spec = &loadbalancer.CreateLoadBalancerPayload{
+			Options: &loadbalancer.LoadBalancerOptions{EphemeralAddress: new(true)},
+		}
+	})
+
+	retainedIP := func() iaas.PublicIp {
+		return iaas.PublicIp{
+			Id: new(ipID),
+			Ip: new(address),
+			Labels: map[string]any{
+				retainedIPClusterLabel:   "my-cluster",
+				retainedIPNamespaceLabel: "default",
+				retainedIPNameLabel:      "my-service",
+			},
+		}
+	}
+
+	Describe("applyRetainedIP", func() {
+		It("should reuse a retained IP on creation", func() {
+			mockIaaSClient.EXPECT().ListPublicIPs(gomock.Any(), selector).Return([]iaas.PublicIp{retainedIP()}, nil)
+
+			Expect(lb.applyRetainedIP(context.Background(), svc, spec, nil)).To(Succeed())
+			Expect(spec.ExternalAddress).To(HaveValue(Equal(address)))
+			Expect(spec.Options.EphemeralAddress).To(HaveValue(BeFalse()))
+			Expect(recorder.Events).To(Receive(ContainSubstring(EventReasonRetainedIP)))
+		})
+
+		It("should create an ephemeral IP if none is retained", func() {
+			mockIaaSClient.EXPECT().ListPublicIPs(gomock.Any(), selector).Return(nil, nil)
+
+			Expect(lb.applyRetainedIP(context.Background(), svc, spec, nil)).To(Succeed())
+			Expect(spec.ExternalAddress).To(BeNil())
+			Expect(spec.Options.EphemeralAddress).To(HaveValue(BeTrue()))
+		})
+
+		It("should promote the ephemeral IP of an existing load balancer", func() {
+			existing := &loadbalancer.LoadBalancer{
+				ExternalAddress: new(address),
+				Options:         &loadbalancer.LoadBalancerOptions{EphemeralAddress: new(true)},
+			}
+
+			Expect(lb.applyRetainedIP(context.Background(), svc, spec, existing)).To(Succeed())
+			Expect(spec.ExternalAddress).To(HaveValue(Equal(address)))
+			Expect(spec.Options.EphemeralAddress).To(HaveValue(BeFalse()))
+			Expect(recorder.Events).To(Receive(ContainSubstring("Promoting the ephemeral external IP")))
+		})
+
+		It("should keep the retained IP after the annotation was removed", func() {
+			delete(svc.Annotations, retainIPAnnotation)
+			svc.Annotations[retainedIPAnnotation] = address
+			existing := &loadbalancer.LoadBalancer{
+				ExternalAddress: new(address),
+				Options:         &loadbalancer.LoadBalancerOptions{EphemeralAddress: new(false)},
+			}
+
+			Expect(lb.applyRetainedIP(context.Background(), svc, spec, existing)).To(Succeed())
+			Expect(spec.ExternalAddress).To(HaveValue(Equal(address)))
+			Expect(spec.Options.EphemeralAddress).To(HaveValue(BeFalse()))
+		})
+
+		It("should not change services without the annotation", func() {
+			delete(svc.Annotations, retainIPAnnotation)
+
+			Expect(lb.applyRetainedIP(context.Background(), svc, spec, nil)).To(Succeed())
+			Expect(spec.ExternalAddress).To(BeNil())
+			Expect(spec.Options.EphemeralAddress).To(HaveValue(BeTrue()))
+		})
+	})
+
+	Describe("labelRetainedIP", func() {
+		var existing *loadbalancer.LoadBalancer
+
+		BeforeEach(func() {
+			existing = &loadbalancer.LoadBalancer{
+				ExternalAddress: new(address),
+				Options:         &loadbalancer.LoadBalancerOptions{EphemeralAddress: new(false)},
+			}
+		})
+
+		It("should label the IP and store it on the service", func() {
+			publicIP := retainedIP()
+			publicIP.Labels = map[string]any{"other": "label"}
+			mockIaaSClient.EXPECT().ListPublicIPs(gomock.Any(), "").Return([]iaas.PublicIp{publicIP}, nil)
+			mockIaaSClient.EXPECT().UpdatePublicIP(gomock.Any(), ipID, iaas.UpdatePublicIPPayload{Labels: map[string]any{
+				retainedIPClusterLabel:   "my-cluster",
+				retainedIPNamespaceLabel: "default",
+				retainedIPNameLabel:      "my-service",
+			}}).Return(&publicIP, nil)
+
+			Expect(lb.labelRetainedIP(context.Background(), svc, existing)).To(Succeed())
+			updated, err := kubeClient.CoreV1().Services(svc.Namespace).Get(context.Background(), svc.Name, metav1.GetOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(updated.Annotations).To(HaveKeyWithValue(retainedIPAnnotation, address))
+		})
+
+		It("should not call the API if the IP was already retained", func() {
+			svc.Annotations[retainedIPAnnotation] = address
+
+			Expect(lb.labelRetainedIP(context.Background(), svc, existing)).To(Succeed())
+		})
+	})
+
+	Describe("releaseRetainedIP", func() {
+		BeforeEach(func() {
+			delete(svc.Annotations, retainIPAnnotation)
+			svc.Annotations[retainedIPAnnotation] = address
+		})
+
+		It("should delete the IP once the annotation was removed", func() {
+			mockIaaSClient.EXPECT().ListPublicIPs(gomock.Any(), selector).Return([]iaas.PublicIp{retainedIP()}, nil)
+			mockIaaSClient.EXPECT().DeletePublicIP(gomock.Any(), ipID).Return(nil)
+
+			Expect(lb.releaseRetainedIP(context.Background(), svc)).To(Succeed())
+		})
+
+		It("should retry while the IP is still in use", func() {
+			mockIaaSClient.EXPECT().ListPublicIPs(gomock.Any(), selector).Return([]iaas.PublicIp{retainedIP()}, nil)
+			mockIaaSClient.EXPECT().DeletePublicIP(gomock.Any(), ipID).Return(fmt.Errorf("in use: %w", stackiterrors.ErrConflict))
+
+			var retryErr *api.RetryError
+			Expect(lb.releaseRetainedIP(context.Background(), svc)).To(BeAssignableToTypeOf(retryErr))
+		})
+
+		It("should keep the IP while the service retains it", func() {
+			svc.Annotations[retainIPAnnotation] = "true"
+
+			Expect(lb.releaseRetainedIP(context.Background(), svc)).To(Succeed())
+		})
+	})
+})
@@ -2,7 +2,10 @@ package ccm
 
 import (
 	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"net/netip"
 	"regexp"
 	"slices"
@@ -10,9 +13,13 @@ import (
 	"strings"
 	"time"
 
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/features"
 	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
 	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
 	corev1 "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
 
 	"github.com/stackitcloud/cloud-provider-stackit/pkg/cmp"
 )
@@ -27,9 +34,14 @@ const (
 	// When the service is deleted, the floating IP will not be deleted.
 	// The IP is ignored if the load balancer internal.
 	externalIPAnnotation = "lb.stackit.cloud/external-address"
+
+	// The TCP proxy protocol and the idle timeouts can be overridden for individual ports by appending the port to the
+	// annotations below, e.g. lb.stackit.cloud/tcp-idle-timeout-443=30m. Overrides take precedence over the annotation without a port.
+
 	// tcpProxyProtocolEnabledAnnotation enables the TCP proxy protocol for TCP ports.
 	tcpProxyProtocolEnabledAnnotation = "lb.stackit.cloud/tcp-proxy-protocol"
 	// tcpProxyProtocolPortFilterAnnotation defines which port use the TCP proxy protocol.
+	// The value is a comma-separated list of ports and port ranges, e.g. "80,8000-8100". The wildcard "*" matches all ports.
 	// Only takes effect if TCP proxy protocol is enabled.
 	// If the annotation is not present then all TCP ports use the TCP proxy protocol.
 	// Has no effect on UDP ports.
@@ -38,8 +50,16 @@ const (
 	tcpIdleTimeoutAnnotation = "lb.stackit.cloud/tcp-idle-timeout"
 	// udpIdleTimeoutAnnotation defines the idle timeout for all UDP ports.
 	udpIdleTimeoutAnnotation = "lb.stackit.cloud/udp-idle-timeout"
+
 	// servicePlanAnnotation defines the service plan to be used when creating an LB
 	servicePlanAnnotation = "lb.stackit.cloud/service-plan-id"
+	// servicePlanAutoAnnotation lets the CCM change the service plan based on the observed load of the load balancer.
+	// It can't be combined with servicePlanAnnotation. New load balancers start with the minimum plan.
+	servicePlanAutoAnnotation = "lb.stackit.cloud/service-plan-auto"
+	// servicePlanMinAnnotation is the smallest plan the CCM chooses if servicePlanAutoAnnotation is set. Defaults to p10.
+	servicePlanMinAnnotation = "lb.stackit.cloud/service-plan-min"
+	// servicePlanMaxAnnotation is the biggest plan the CCM chooses if servicePlanAutoAnnotation is set. Defaults to p750.
+	servicePlanMaxAnnotation = "lb.stackit.cloud/service-plan-max"
 	// ipModeProxyAnnotation defines whether the service status should reflect that the load balancer is of type proxy.
 	ipModeProxyAnnotation = "lb.stackit.cloud/ip-mode-proxy"
 	// sessionPersistenceWithSourceIP defines whether the load balancer should use the source IP address for load balancing.
@@ -54,6 +74,89 @@ const (
 	// The annotation can neither be changed nor be added or removed after service creation.
 	// This annotation is currently not supported by STACKIT and only works in very specific circumstances.
 	listenerNetworkAnnotation = "lb.stackit.cloud/listener-network"
+	// healthCheckProtocolAnnotation defines how the targets of TCP ports are probed: "tcp" or "http".
+	// Defaults to "http" if healthCheckPathAnnotation or healthCheckExpectedStatusAnnotation is set, otherwise "tcp".
+	healthCheckProtocolAnnotation = "lb.stackit.cloud/health-check-protocol"
+	// healthCheckPathAnnotation defines the path of HTTP health checks, e.g. "/healthz".
+	healthCheckPathAnnotation = "lb.stackit.cloud/health-check-path"
+	// healthCheckExpectedStatusAnnotation is a comma-separated list of HTTP status codes, e.g. "200,204".
+	// If set, the targets of TCP ports are probed with HTTP health checks that only accept these status codes.
+	healthCheckExpectedStatusAnnotation = "lb.stackit.cloud/health-check-expected-status"
+	// healthCheckHostHeaderAnnotation defines the Host header of HTTP health checks.
+	// It is currently not supported by the load balancer API and therefore rejected.
+	healthCheckHostHeaderAnnotation = "lb.stackit.cloud/health-check-host-header"
+	// deniedSourceRangesAnnotation is a comma-separated list of IPv4 CIDRs that must not reach the load balancer.
+	// The load balancer API only supports allow-lists, therefore the denied ranges are removed from the allowed ranges.
+	deniedSourceRangesAnnotation = "lb.stackit.cloud/denied-source-ranges"
+	// tlsModeAnnotation defines how TCP ports handle TLS: "none" (default), "passthrough" or "termination".
+	// It can be overridden for individual ports like the TCP proxy protocol.
+	// Requires the TLSListeners feature gate.
+	tlsModeAnnotation = "lb.stackit.cloud/tls-mode"
+	// tlsCertificateIDsAnnotation is a comma-separated list of certificate references used by ports with TLS termination.
+	tlsCertificateIDsAnnotation = "lb.stackit.cloud/tls-certificate-ids"
+	// targetModeAnnotation defines whether the load balancer targets the nodes ("node", default) or the pods ("pod").
+	// In pod mode, the ready endpoints of the service are the targets, which requires a routable pod network.
+	targetModeAnnotation = "lb.stackit.cloud/target-mode"
+	// staticTargetsAnnotation is a comma-separated list of name=ip pairs that replaces the nodes as targets, e.g. for
+	// gateway VMs outside the cluster. The load balancer is then not updated when the nodes change.
+	staticTargetsAnnotation = "lb.stackit.cloud/static-targets"
+	// portRangeTCPAnnotation and portRangeUDPAnnotation add a listener for every port of the range "start-end", e.g.
+	// "10000-10100", in addition to the ports of the service. The ports are forwarded to the same ports of the targets,
+	// or to consecutive ports starting at a base port, e.g. "10000-10100:30000".
+	portRangeTCPAnnotation = "lb.stackit.cloud/port-range-tcp"
+	portRangeUDPAnnotation = "lb.stackit.cloud/port-range-udp"
+	// deregistrationDelayAnnotation is the time nodes are kept as targets after they were removed from the load
+	// balancer, e.g. because they are drained or excluded from load balancers, so that open connections can finish.
+	// Defaults to loadBalancer.deregistrationDelay of the cloud config.
+	deregistrationDelayAnnotation = "lb.stackit.cloud/deregistration-delay"
+	// retainIPAnnotation promotes the ephemeral IP of the load balancer to a static IP that is kept when the service is
+	// deleted and reused when a service with the same namespace and name is created again.
+	// The IP is released when the service is deleted after the annotation was removed.
+	retainIPAnnotation = "lb.stackit.cloud/retain-ip"
+	// ipReservationAnnotation claims the IP of the LoadBalancerIPReservation with the given name.
+	// It can't be combined with externalIPAnnotation.
+	ipReservationAnnotation = "lb.stackit.cloud/ip-reservation"
+	// enabledAnnotation opts a service in to be reconciled if the CCM runs with --load-balancer-opt-in.
+	// Without the flag, the annotation is ignored and all services are reconciled.
+	enabledAnnotation = "lb.stackit.cloud/enabled"
+	// allowRecreateAnnotation allows the CCM to delete and recreate the load balancer if a change of the service can't
+	// be applied by an update, e.g. switching to an internal load balancer. The load balancer is deleted after the
+	// recreateDelay of the cloud config.
+	allowRecreateAnnotation = "lb.stackit.cloud/allow-recreate"
+	// pausedAnnotation stops the CCM from creating or updating the load balancer, so that operators can change it
+	// manually without the CCM reverting their changes. The load balancer is still deleted with the service.
+	pausedAnnotation = "lb.stackit.cloud/paused"
+)
+
+type healthCheckProtocol string
+
+const (
+	healthCheckProtocolTCP  healthCheckProtocol = "tcp"
+	healthCheckProtocolHTTP healthCheckProtocol = "http"
+)
+
+type targetMode string
+
+const (
+	targetModeNode targetMode = "node"
+	targetModePod  targetMode = "pod"
+)
+
+type tlsMode string
+
+const (
+	tlsModeNone        tlsMode = "none"
+	tlsModePassthrough tlsMode = "passthrough"
+	tlsModeTermination tlsMode = "termination"
+)
+
+const (
+	// listenerProtocolTLSTermination is not part of the load balancer API yet.
+	listenerProtocolTLSTermination loadbalancer.ListenerProtocol = "PROTOCOL_TLS_TERMINATION"
+	// listenerTLSProperty and listenerCertificateIDsProperty contain the certificates of a listener with TLS termination.
+	// They are sent as additional properties of the listener until the load balancer API client supports them.
+	listenerTLSProperty            = "tls"
+	listenerCertificateIDsProperty = "certificateIds"
 )
 
 const (
@@ -67,9 +170,18 @@ const (
 	defaultUDPIdleTimeout = 2 * time.Minute
 )
 
-const eventReasonYawolAnnotationPresent = "YawolAnnotationPresent"
+const (
+	eventReasonYawolAnnotationPresent  = "YawolAnnotationPresent"
+	eventReasonConflictingSourceRanges = "ConflictingSourceRanges"
+)
 
 const (
+	// maxPortRangeSize is the maximum number of ports of a single port range annotation.
+	maxPortRangeSize = 1000
+	// maxListeners is the maximum number of listeners of a load balancer, including the ports of both port range
+	// annotations. It applies even if LoadBalancerOpts.MaxListenersPerPlan has no entry for the plan.
+	maxListeners = 1000
+
 	p10  = "p10"
 	p50  = "p50"
 	p250 = "p250"
@@ -205,16 +317,23 @@ var appoximateFlavorsMap = map[string]string{
 var (
 	// invalidTargetDisplayNameCharsRegexp matches any character that is NOT alphanumeric or a hyphen
 	invalidTargetDisplayNameCharsRegexp = regexp.MustCompile(`[^a-zA-Z0-9-]`)
+	// targetDisplayNameRegexp matches the display names of targets accepted by the load balancer API.
+	targetDisplayNameRegexp = regexp.MustCompile(`^[0-9a-zA-Z](?:(?:[0-9a-zA-Z]|-){0,61}[0-9a-zA-Z])?$`)
 )
 
+// portRange is an inclusive range of ports.
+type portRange struct {
+	min, max uint16
+}
+
 // proxyProtocolEnableForPort determines whether portNumber should use the TCP proxy protocol (instead of TCP).
-func proxyProtocolEnableForPort(tcpProxyProtocolEnabled bool, tcpProxyProtocolPortFilter []uint16, portNumber int32) bool {
+func proxyProtocolEnableForPort(tcpProxyProtocolEnabled bool, tcpProxyProtocolPortFilter []portRange, portNumber int32) bool {
 	if !tcpProxyProtocolEnabled {
 		return false
 	}
 	if tcpProxyProtocolPortFilter != nil {
-		for _, port := range tcpProxyProtocolPortFilter {
-			if int32(port) == portNumber {
+		for _, r := range tcpProxyProtocolPortFilter {
+			if int32(r.min) <= portNumber && portNumber <= int32(r.max) {
 				return true
 			}
 		}
@@ -223,6 +342,161 @@ func proxyProtocolEnableForPort(tcpProxyProtocolEnabled bool, tcpProxyProtocolPo
 	return true
 }
 
+// parsePortFilter parses a comma-separated list of ports and port ranges (e.g. "80,8000-8100").
+// It returns nil if the filter contains the wildcard "*", i.e. all ports match.
+func parsePortFilter(filter, annotation string) ([]portRange, error) {
+	ranges := []portRange{}
+	if strings.TrimSpace(filter) == "" {
+		return ranges, nil
+	}
+	var errs []error
+	wildcard := false
+	for i, portStr := range strings.Split(filter, ",") {
+		portStr = strings.TrimSpace(portStr)
+		if portStr == "*" {
+			wildcard = true
+			continue
+		}
+		minStr, maxStr, isRange := strings.Cut(portStr, "-")
+		if !isRange {
+			maxStr = minStr
+		}
+		minPort, err := strconv.ParseUint(strings.TrimSpace(minStr), 10, 16)
+		if err == nil {
+			var maxPort uint64
+			maxPort, err = strconv.ParseUint(strings.TrimSpace(maxStr), 10, 16)
+			if err == nil && maxPort < minPort {
+				err = fmt.Errorf("end of range is lower than start")
+			}
+			if err == nil {
+				ranges = append(ranges, portRange{min: uint16(minPort), max: uint16(maxPort)})
+				continue
+			}
+		}
+		errs = append(errs, fmt.Errorf("invalid port %q at position %d in annotation %q: %w", portStr, i, annotation, err))
+	}
+	if len(errs) > 0 {
+		return nil, utilerrors.NewAggregate(errs)
+	}
+	if wildcard {
+		return nil, nil
+	}
+	return ranges, nil
+}
+
+// perPortAnnotations parses all annotations of the form <annotation>-<port> into a map keyed by port.
+// Ports must be part of the service. Other suffixes (like in tcp-proxy-protocol-ports-filter) are ignored.
+func perPortAnnotations[T any](service *corev1.Service, annotation string, parse func(string) (T, error)) (map[int32]T, error) {
+	overrides := map[int32]T{}
+	for key, value := range service.Annotations {
+		portStr, found := strings.CutPrefix(key, annotation+"-")
+		if !found {
+			continue
+		}
+		port, err := strconv.ParseUint(portStr, 10, 16)
+		if err != nil {
+			continue
+		}
+		if !slices.ContainsFunc(service.Spec.Ports, func(p corev1.ServicePort) bool { return p.Port == int32(port) }) {
+			return nil, fmt.Errorf("port %d in annotation %s is not a port of the service", port, key)
+		}
+		parsed, err := parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for annotation %s: %w", key, err)
+		}
+		overrides[int32(port)] = parsed
+	}
+	return overrides, nil
+}
+
+// planBounds are the smallest and biggest plan the CCM may choose for a load balancer.
+type planBounds struct {
+	min, max string
+}
+
+// clamp returns the closest plan to planID within the bounds.
+func (b planBounds) clamp(planID string) string {
+	idx := slices.Index(availablePlanIDs, planID)
+	switch {
+	case idx < 0 || idx < slices.Index(availablePlanIDs, b.min):
+		return b.min
+	case idx > slices.Index(availablePlanIDs, b.max):
+		return b.max
+	}
+	return planID
+}
+
+// largerPlan returns the larger of both plans.
+func largerPlan(a, b string) string {
+	if slices.Index(availablePlanIDs, a) < slices.Index(availablePlanIDs, b) {
+		return b
+	}
+	return a
+}
+
+// fittingPlan returns the smallest plan whose limits in MaxListenersPerPlan and MaxTargetsPerPlan allow the given
+// number of listeners and targets per target pool. If no plan fits, the biggest plan is returned.
+func fittingPlan(opts stackitconfig.LoadBalancerOpts, listeners, targets int) string {
+	for _, planID := range availablePlanIDs {
+		if limit, found := opts.MaxListenersPerPlan[planID]; found && listeners > limit {
+			continue
+		}
+		if limit, found := opts.MaxTargetsPerPlan[planID]; found && targets > limit {
+			continue
+		}
+		return planID
+	}
+	return availablePlanIDs[len(availablePlanIDs)-1]
+}
+
+// maxTargetsPerPool returns the number of targets of the biggest target pool.
+func maxTargetsPerPool(pools []loadbalancer.TargetPool) int {
+	targets := 0
+	for _, pool := range pools {
+		targets = max(targets, len(pool.Targets))
+	}
+	return targets
+}
+
+// autoPlanBoundsFromService returns the plan bounds if the service has automatic plan changes enabled or nil otherwise.
+func autoPlanBoundsFromService(service *corev1.Service) (*planBounds, error) {
+	autoStr, found := service.Annotations[servicePlanAutoAnnotation]
+	if !found {
+		return nil, nil
+	}
+	auto, err := strconv.ParseBool(autoStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bool value for annotation %s: %w", servicePlanAutoAnnotation, err)
+	}
+	if !auto {
+		return nil, nil
+	}
+	for _, a := range []string{servicePlanAnnotation, yawolFlavorIDAnnotation} {
+		if _, found := service.Annotations[a]; found {
+			return nil, fmt.Errorf("annotation %s can't be combined with %s", servicePlanAutoAnnotation, a)
+		}
+	}
+
+	bounds := &planBounds{min: availablePlanIDs[0], max: availablePlanIDs[len(availablePlanIDs)-1]}
+	if minPlan, found := service.Annotations[servicePlanMinAnnotation]; found {
+		bounds.min = minPlan
+	}
+	if maxPlan, found := service.Annotations[servicePlanMaxAnnotation]; found {
+		bounds.max = maxPlan
+	}
+	minIdx, maxIdx := slices.Index(availablePlanIDs, bounds.min), slices.Index(availablePlanIDs, bounds.max)
+	switch {
+	case minIdx < 0:
+		return nil, fmt.Errorf("unsupported plan ID value %q in annotation %s, supported values are %v", bounds.min, servicePlanMinAnnotation, availablePlanIDs)
+	case maxIdx < 0:
+		return nil, fmt.Errorf("unsupported plan ID value %q in annotation %s, supported values are %v", bounds.max, servicePlanMaxAnnotation, availablePlanIDs)
+	case minIdx > maxIdx:
+		return nil, fmt.Errorf("plan %s in annotation %s is bigger than plan %s in annotation %s",
+			bounds.min, servicePlanMinAnnotation, bounds.max, servicePlanMaxAnnotation)
+	}
+	return bounds, nil
+}
+
 // getPlanId returns the plan ID from the service annotations
 // if no plan id or flavor ID annotations are found then default p10 plan is used
 func getPlanID(service *corev1.Service) (planID *string, msgs []string, err error) {
@@ -251,7 +525,7 @@ func getPlanID(service *corev1.Service) (planID *string, msgs []string, err erro
 
 // lbSpecFromService returns a load balancer specification in the form of a create payload matching the specification of the service, nodes and network.
 // The property name will be empty and must be set by the caller to produce a valid payload for the API.
-// An error is returned if the service has invalid options.
+// If the service has invalid options, an aggregated error of all of them is returned.
 //
 //nolint:gocyclo,funlen // main function to create a lb from a service, this includes many options and is therefore complex.
 func lbSpecFromService(
@@ -260,12 +534,13 @@ func lbSpecFromService(
 	opts stackitconfig.LoadBalancerOpts,
 	observability *loadbalancer.LoadbalancerOptionObservability,
 ) (*loadbalancer.CreateLoadBalancerPayload, []Event, error) {
+	networkID, targetNodes, skippedNodes := targetNetwork(filterTargetNodes(nodes, opts), opts)
 	lb := &loadbalancer.CreateLoadBalancerPayload{
 		Options: &loadbalancer.LoadBalancerOptions{},
 		Networks: []loadbalancer.Network{
 			{
 				Role:      new(loadbalancer.NETWORKROLE_ROLE_LISTENERS_AND_TARGETS),
-				NetworkId: &opts.NetworkID,
+				NetworkId: &networkID,
 			},
 		},
 	}
@@ -274,7 +549,7 @@ func lbSpecFromService(
 		lb.Networks = []loadbalancer.Network{
 			{
 				Role:      new(loadbalancer.NETWORKROLE_ROLE_TARGETS),
-				NetworkId: &opts.NetworkID,
+				NetworkId: &networkID,
 			}, {
 				Role:      new(loadbalancer.NETWORKROLE_ROLE_LISTENERS),
 				NetworkId: &listenerNetwork,
@@ -284,7 +559,7 @@ func lbSpecFromService(
 		lb.Networks = []loadbalancer.Network{
 			{
 				Role:      new(loadbalancer.NETWORKROLE_ROLE_LISTENERS_AND_TARGETS),
-				NetworkId: &opts.NetworkID,
+				NetworkId: &networkID,
 			},
 		}
 	}
@@ -303,6 +578,21 @@ func lbSpecFromService(
 	lb.Options.Observability = observability
 
 	events := make([]Event, 0)
+	// All validation errors are collected so that users can fix them at once.
+	var errs []error
+
+	service, compatEvent, compatErrs := translateCompatAnnotations(service)
+	errs = append(errs, compatErrs...)
+	if compatEvent != nil {
+		events = append(events, *compatEvent)
+	}
+
+	if networkID == "" {
+		errs = append(errs, errors.New("the network of the nodes is unknown, configure networkId in the cloud-config"))
+	}
+	if len(skippedNodes) > 0 {
+		events = append(events, nodesInOtherNetworksEvent(networkID, skippedNodes))
+	}
 
 	// Parse private network from annotations.
 	// TODO: Split into separate function.
@@ -314,7 +604,7 @@ func lbSpecFromService(
 		i, err := strconv.ParseBool(internalStr)
 		internal = &i
 		if err != nil {
-			return nil, nil, fmt.Errorf("invalid bool value %q for annotation %q: %w", internalStr, internalLBAnnotation, err)
+			errs = append(errs, fmt.Errorf("invalid bool value %q for annotation %q: %w", internalStr, internalLBAnnotation, err))
 		}
 		lb.Options.PrivateNetworkOnly = internal
 	}
@@ -324,16 +614,22 @@ func lbSpecFromService(
 		lb.Options.PrivateNetworkOnly = yawolInternal
 	}
 	if yawolInternal != nil && internal != nil && *yawolInternal != *internal {
-		return nil, nil, fmt.Errorf("incompatible values for annotations %s and %s", yawolInternalLBAnnotation, internalLBAnnotation)
+		errs = append(errs, fmt.Errorf("incompatible values for annotations %s and %s", yawolInternalLBAnnotation, internalLBAnnotation))
 	}
 
 	// process service-plan-id annotation
 	planID, msgs, err := getPlanID(service)
 	if err != nil {
-		return nil, nil, fmt.Errorf("getPlanId: %w", err)
+		errs = append(errs, fmt.Errorf("getPlanId: %w", err))
 	}
 	lb.PlanId = planID
 
+	// process service-plan-auto annotations
+	bounds, err := autoPlanBoundsFromService(service)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
 	for _, msg := range msgs {
 		events = append(events, Event{
 			Type:    corev1.EventTypeWarning,
@@ -347,26 +643,35 @@ func lbSpecFromService(
 	externalIP, found := service.Annotations[externalIPAnnotation]
 	yawolExternalIP, yawolFound := service.Annotations[yawolExistingFloatingIPAnnotation]
 	if found && yawolFound && externalIP != yawolExternalIP {
-		return nil, nil, fmt.Errorf(
+		errs = append(errs, fmt.Errorf(
 			"incompatible values for annotations %s and %s", yawolExistingFloatingIPAnnotation, externalIPAnnotation,
-		)
+		))
+	}
+	externalIPSource := externalIPAnnotation
+	if !found && yawolFound {
+		externalIP, found, externalIPSource = yawolExternalIP, true, yawolExistingFloatingIPAnnotation
+	}
+	// The deprecated field spec.loadBalancerIP is still set by many charts, it is used like the annotation.
+	if loadBalancerIP := service.Spec.LoadBalancerIP; loadBalancerIP != "" {
+		if found && externalIP != loadBalancerIP {
+			errs = append(errs, fmt.Errorf("incompatible values for annotation %s and spec.loadBalancerIP", externalIPSource))
+		}
+		externalIP, found = loadBalancerIP, true
 	}
 	lb.Options.EphemeralAddress = new(false)
-	if !found && !yawolFound && !*lb.Options.PrivateNetworkOnly {
+	if !found && !*lb.Options.PrivateNetworkOnly {
 		lb.Options.EphemeralAddress = new(true)
 	}
-	if !found && yawolFound {
-		externalIP = yawolExternalIP
-	}
 	if !*lb.Options.PrivateNetworkOnly && !*lb.Options.EphemeralAddress {
 		ip, err := netip.ParseAddr(externalIP)
-		if err != nil {
-			return nil, nil, fmt.Errorf("invalid format for external IP: %w", err)
-		}
-		if ip.Is6() {
-			return nil, nil, fmt.Errorf("external IP must be an IPv4 address")
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("invalid format for external IP: %w", err))
+		case ip.Is6():
+			errs = append(errs, fmt.Errorf("external IP must be an IPv4 address"))
+		default:
+			lb.ExternalAddress = &externalIP
 		}
-		lb.ExternalAddress = &externalIP
 	}
 
 	// Parse TCP idle timeout from annotations.
@@ -379,7 +684,7 @@ func lbSpecFromService(
 		var err error
 		tcpIdleTimeout, err = time.ParseDuration(service.Annotations[tcpIdleTimeoutAnnotation])
 		if err != nil {
-			return nil, nil, fmt.Errorf("invalid format for annotation %s: %w", tcpIdleTimeoutAnnotation, err)
+			errs = append(errs, fmt.Errorf("invalid format for annotation %s: %w", tcpIdleTimeoutAnnotation, err))
 		}
 	}
 	if yawolFound {
@@ -391,7 +696,7 @@ func lbSpecFromService(
 		}
 	}
 	if found && yawolFound && tcpIdleTimeout != yawolTCPIdleTimeout {
-		return nil, nil, fmt.Errorf("incompatible values for annotations %s and %s", tcpIdleTimeoutAnnotation, yawolTCPIdleTimeoutAnnotation)
+		errs = append(errs, fmt.Errorf("incompatible values for annotations %s and %s", tcpIdleTimeoutAnnotation, yawolTCPIdleTimeoutAnnotation))
 	}
 
 	// Parse UDP idle timeout from annotations.
@@ -404,7 +709,7 @@ func lbSpecFromService(
 		var err error
 		udpIdleTimeout, err = time.ParseDuration(service.Annotations[udpIdleTimeoutAnnotation])
 		if err != nil {
-			return nil, nil, fmt.Errorf("invalid format for annotation %s: %w", udpIdleTimeoutAnnotation, err)
+			errs = append(errs, fmt.Errorf("invalid format for annotation %s: %w", udpIdleTimeoutAnnotation, err))
 		}
 	}
 	if yawolFound {
@@ -416,7 +721,7 @@ func lbSpecFromService(
 		}
 	}
 	if found && yawolFound && udpIdleTimeout != yawolUDPIdleTimeout {
-		return nil, nil, fmt.Errorf("incompatible values for annotations %s and %s", udpIdleTimeoutAnnotation, yawolUDPIdleTimeoutAnnotation)
+		errs = append(errs, fmt.Errorf("incompatible values for annotations %s and %s", udpIdleTimeoutAnnotation, yawolUDPIdleTimeoutAnnotation))
 	}
 
 	// Parse PROXY protocol from annotations.
@@ -424,14 +729,14 @@ func lbSpecFromService(
 	tcpProxyProtocolEnabled := false
 	yawolTCPProxyProtocolEnabled := false
 	// tcpProxyProtocolPortFilter allows all ports if nil.
-	var tcpProxyProtocolPortFilter []uint16
+	var tcpProxyProtocolPortFilter []portRange
 	_, found = service.Annotations[tcpProxyProtocolEnabledAnnotation]
 	_, yawolFound = service.Annotations[yawolTCPProxyProtocolEnabledAnnotation]
 	if found {
 		var err error
 		tcpProxyProtocolEnabled, err = strconv.ParseBool(service.Annotations[tcpProxyProtocolEnabledAnnotation])
 		if err != nil {
-			return nil, nil, fmt.Errorf("invalid bool value for annotation %s: %w", tcpProxyProtocolEnabledAnnotation, err)
+			errs = append(errs, fmt.Errorf("invalid bool value for annotation %s: %w", tcpProxyProtocolEnabledAnnotation, err))
 		}
 	}
 	if yawolFound {
@@ -440,9 +745,9 @@ func lbSpecFromService(
 		yawolTCPProxyProtocolEnabled = e
 	}
 	if found && yawolFound && yawolTCPProxyProtocolEnabled != tcpProxyProtocolEnabled {
-		return nil, nil, fmt.Errorf(
+		errs = append(errs, fmt.Errorf(
 			"incompatible values for annotations %s and %s", yawolTCPProxyProtocolEnabledAnnotation, tcpProxyProtocolEnabledAnnotation,
-		)
+		))
 	}
 	if yawolFound && !found {
 		tcpProxyProtocolEnabled = yawolTCPProxyProtocolEnabled
@@ -452,25 +757,18 @@ func lbSpecFromService(
 		yawolProxyPorts, yawolFound := service.Annotations[yawolTCPProxyProtocolPortFilterAnnotation]
 		// We compare the ports string-based for simplicity.
 		if found && yawolFound && proxyPorts != yawolProxyPorts {
-			return nil, nil, fmt.Errorf(
+			errs = append(errs, fmt.Errorf(
 				"incompatible values for annotations %s and %s", yawolTCPProxyProtocolPortFilterAnnotation, tcpProxyProtocolPortFilterAnnotation,
-			)
+			))
 		}
 		if yawolFound && !found {
 			proxyPorts = yawolProxyPorts
 		}
 		if found || yawolFound {
-			tcpProxyProtocolPortFilter = []uint16{}
-			if strings.TrimSpace(proxyPorts) != "" {
-				for i, portStr := range strings.Split(proxyPorts, ",") {
-					port, err := strconv.ParseUint(strings.TrimSpace(portStr), 10, 16)
-					if err != nil {
-						return nil, nil, fmt.Errorf(
-							"invalid port %q at position %d in annotation %q: %w", portStr, i, tcpProxyProtocolPortFilterAnnotation, err,
-						)
-					}
-					tcpProxyProtocolPortFilter = append(tcpProxyProtocolPortFilter, uint16(port))
-				}
+			var err error
+			tcpProxyProtocolPortFilter, err = parsePortFilter(proxyPorts, tcpProxyProtocolPortFilterAnnotation)
+			if err != nil {
+				errs = append(errs, err)
 			}
 		}
 	}
@@ -480,14 +778,38 @@ func lbSpecFromService(
 	if val, found := service.Annotations[sessionPersistenceWithSourceIP]; found {
 		parsed, err := strconv.ParseBool(val)
 		if err != nil {
-			return nil, nil, fmt.Errorf("invalid bool value for annotation %s: %w", sessionPersistenceWithSourceIP, err)
+			errs = append(errs, fmt.Errorf("invalid bool value for annotation %s: %w", sessionPersistenceWithSourceIP, err))
 		}
 		useSourceIP = parsed
 	}
 
+	// Parse per-port overrides from annotations.
+	tcpProxyProtocolOverrides, err := perPortAnnotations(service, tcpProxyProtocolEnabledAnnotation, strconv.ParseBool)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	tcpIdleTimeoutOverrides, err := perPortAnnotations(service, tcpIdleTimeoutAnnotation, time.ParseDuration)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	udpIdleTimeoutOverrides, err := perPortAnnotations(service, udpIdleTimeoutAnnotation, time.ParseDuration)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	healthCheck, err := healthCheckFromAnnotations(service)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	defaultTLSMode, tlsModeOverrides, certificateIDs, err := tlsFromAnnotations(service, utilfeature.DefaultFeatureGate.Enabled(features.TLSListeners))
+	if err != nil {
+		errs = append(errs, err)
+	}
+
 	targets := []loadbalancer.Target{}
-	for i := range nodes {
-		node := nodes[i]
+	for i := range targetNodes {
+		node := targetNodes[i]
 		for j := range node.Status.Addresses {
 			address := node.Status.Addresses[j]
 			if address.Type == corev1.NodeInternalIP {
@@ -501,39 +823,109 @@ func lbSpecFromService(
 		}
 	}
 
+	targetMode, err := targetModeFromService(service)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	staticTargets, err := staticTargetsFromService(service)
+	switch {
+	case err != nil:
+		errs = append(errs, err)
+	case staticTargets != nil && targetMode == targetModePod:
+		errs = append(errs, fmt.Errorf("annotation %s can't be combined with target mode %q", staticTargetsAnnotation, targetModePod))
+	case staticTargets != nil:
+		targets = staticTargets
+	}
+
+	rangePorts, err := portRangePorts(service)
+	switch {
+	case err != nil:
+		errs = append(errs, err)
+	case len(rangePorts) > 0 && targetMode == targetModePod:
+		errs = append(errs, fmt.Errorf("port ranges can't be combined with target mode %q", targetModePod))
+	}
+	if _, err := deregistrationDelayFromService(service, opts); err != nil {
+		errs = append(errs, err)
+	}
+
+	// Port ranges are handled like additional ports of the service.
+	ports := append(slices.Clone(service.Spec.Ports), rangePorts...)
+
 	listeners := []loadbalancer.Listener{}
 	targetPools := []loadbalancer.TargetPool{}
-	for i := range service.Spec.Ports {
-		port := service.Spec.Ports[i]
-		name := port.Name
-		if name == "" {
-			// Use a descriptive name for a port without name. This only applies for
-			// services with a single port. A service with more than one port must
-			// have names set for all ports.
-			name = fmt.Sprintf("port-%s-%d", strings.ToLower(string(port.Protocol)), port.Port)
-		}
+	for i := range ports {
+		port := ports[i]
+		name := targetPoolName(port)
 
 		var protocol loadbalancer.ListenerProtocol
 		var tcpOptions *loadbalancer.OptionsTCP
 		var udpOptions *loadbalancer.OptionsUDP
+		var activeHealthCheck *loadbalancer.ActiveHealthCheck
+		var additionalProperties map[string]any
 
 		switch port.Protocol {
 		case corev1.ProtocolTCP:
-			if proxyProtocolEnableForPort(tcpProxyProtocolEnabled, tcpProxyProtocolPortFilter, port.Port) {
+			proxyProtocol := proxyProtocolEnableForPort(tcpProxyProtocolEnabled, tcpProxyProtocolPortFilter, port.Port)
+			if override, found := tcpProxyProtocolOverrides[port.Port]; found {
+				proxyProtocol = override
+			}
+			mode := defaultTLSMode
+			if override, found := tlsModeOverrides[port.Port]; found {
+				mode = override
+			}
+			switch {
+			case mode != tlsModeNone && proxyProtocol:
+				errs = append(errs, fmt.Errorf("port %d can't use the TCP proxy protocol and TLS %s at the same time", port.Port, mode))
+				continue
+			case mode == tlsModePassthrough:
+				protocol = loadbalancer.LISTENERPROTOCOL_PROTOCOL_TLS_PASSTHROUGH
+			case mode == tlsModeTermination:
+				if len(certificateIDs) == 0 {
+					errs = append(errs, fmt.Errorf("port %d uses TLS termination, but %s is not set", port.Port, tlsCertificateIDsAnnotation))
+					continue
+				}
+				protocol = listenerProtocolTLSTermination
+				additionalProperties = map[string]any{
+					listenerTLSProperty: map[string]any{listenerCertificateIDsProperty: certificateIDs},
+				}
+			case proxyProtocol:
 				protocol = loadbalancer.LISTENERPROTOCOL_PROTOCOL_TCP_PROXY
-			} else {
+			default:
 				protocol = loadbalancer.LISTENERPROTOCOL_PROTOCOL_TCP
 			}
+			idleTimeout := tcpIdleTimeout
+			if override, found := tcpIdleTimeoutOverrides[port.Port]; found {
+				idleTimeout = override
+			}
 			tcpOptions = &loadbalancer.OptionsTCP{
-				IdleTimeout: new(fmt.Sprintf("%.0fs", tcpIdleTimeout.Seconds())),
+				IdleTimeout: new(fmt.Sprintf("%.0fs", idleTimeout.Seconds())),
 			}
+			activeHealthCheck = healthCheck
 		case corev1.ProtocolUDP:
 			protocol = loadbalancer.LISTENERPROTOCOL_PROTOCOL_UDP
+			idleTimeout := udpIdleTimeout
+			if override, found := udpIdleTimeoutOverrides[port.Port]; found {
+				idleTimeout = override
+			}
 			udpOptions = &loadbalancer.OptionsUDP{
-				IdleTimeout: new(fmt.Sprintf("%.0fs", udpIdleTimeout.Seconds())),
+				IdleTimeout: new(fmt.Sprintf("%.0fs", idleTimeout.Seconds())),
 			}
 		default:
-			return nil, nil, fmt.Errorf("unsupported protocol %q for port %q", port.Protocol, port.Name)
+			errs = append(errs, fmt.Errorf("unsupported protocol %q for port %q", port.Protocol, port.Name))
+			continue
+		}
+
+		// Without a node port, the target pool would point to port 0 of the nodes.
+		// Node ports can still be set explicitly if their allocation is disabled.
+		// Pods are targeted directly in pod target mode, the target pools are completed by applyPodTargets.
+		// Static targets are not nodes and receive the traffic on the target port.
+		if port.NodePort == 0 && targetMode == targetModeNode && staticTargets == nil &&
+			service.Spec.AllocateLoadBalancerNodePorts != nil && !*service.Spec.AllocateLoadBalancerNodePorts {
+			errs = append(errs, fmt.Errorf(
+				"port %d has no node port: load balancers require node ports, set allocateLoadBalancerNodePorts to true or specify the node port", port.Port,
+			))
+			continue
 		}
 
 		listeners = append(listeners, loadbalancer.Listener{
@@ -543,40 +935,236 @@ func lbSpecFromService(
 			Protocol:    new(protocol),
 			Tcp:         tcpOptions,
 			Udp:         udpOptions,
+
+			AdditionalProperties: additionalProperties,
 		})
 
+		targetPort := port.NodePort
+		if staticTargets != nil {
+			targetPort = staticTargetPort(port)
+		}
 		targetPools = append(targetPools, loadbalancer.TargetPool{
 			Name:       &name,
-			TargetPort: new(port.NodePort),
+			TargetPort: new(targetPort),
 			Targets:    targets,
 			SessionPersistence: &loadbalancer.SessionPersistence{
 				UseSourceIpAddress: new(useSourceIP),
 			},
+			ActiveHealthCheck: activeHealthCheck,
 		})
 	}
 	lb.Listeners = listeners
 	lb.TargetPools = targetPools
 
-	lb.Options.AccessControl = &loadbalancer.LoadbalancerOptionAccessControl{}
-	// For backwards-compatibility, the spec takes precedence over the annotation.
-	if sourceRanges, found := service.Annotations[yawolLoadBalancerSourceRangesAnnotation]; found {
-		r := strings.Split(sourceRanges, ",")
-		lb.Options.AccessControl.AllowedSourceRanges = r
+	// Automatic plans start with the smallest plan within the bounds that fits the listeners and targets.
+	if bounds != nil {
+		lb.PlanId = new(bounds.clamp(fittingPlan(opts, len(listeners), maxTargetsPerPool(targetPools))))
 	}
-	if len(service.Spec.LoadBalancerSourceRanges) > 0 {
-		lb.Options.AccessControl.AllowedSourceRanges = service.Spec.LoadBalancerSourceRanges
+
+	if len(listeners) > maxListeners {
+		errs = append(errs, fmt.Errorf("the load balancer has %d listeners, but at most %d are supported", len(listeners), maxListeners))
+	} else if limit, found := opts.MaxListenersPerPlan[cmp.UnpackPtr(lb.PlanId)]; found && len(listeners) > limit {
+		errs = append(errs, fmt.Errorf("the load balancer has %d listeners, but plan %s allows at most %d",
+			len(listeners), cmp.UnpackPtr(lb.PlanId), limit))
 	}
 
+	accessControl, accessControlEvents, err := accessControlFromService(service, *lb.Options.PrivateNetworkOnly)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	lb.Options.AccessControl = accessControl
+	events = append(events, accessControlEvents...)
+
 	if event := checkUnsupportedAnnotations(service); event != nil {
 		events = append(events, *event)
 	}
 
+	if len(errs) > 0 {
+		return nil, nil, utilerrors.NewAggregate(errs)
+	}
+
 	if events != nil {
 		return lb, events, nil
 	}
 	return lb, nil, nil
 }
 
+// healthCheckFromAnnotations returns the active health check for TCP target pools or nil if the defaults should be used.
+func healthCheckFromAnnotations(service *corev1.Service) (*loadbalancer.ActiveHealthCheck, error) {
+	if _, found := service.Annotations[healthCheckHostHeaderAnnotation]; found {
+		return nil, fmt.Errorf("annotation %s is not supported by the load balancer API yet", healthCheckHostHeaderAnnotation)
+	}
+
+	protocol, protocolFound := service.Annotations[healthCheckProtocolAnnotation]
+	path, pathFound := service.Annotations[healthCheckPathAnnotation]
+	expectedStatus, expectedStatusFound := service.Annotations[healthCheckExpectedStatusAnnotation]
+	if !protocolFound {
+		if !pathFound && !expectedStatusFound {
+			return nil, nil
+		}
+		protocol = string(healthCheckProtocolHTTP)
+	}
+
+	switch healthCheckProtocol(protocol) {
+	case healthCheckProtocolTCP:
+		if pathFound || expectedStatusFound {
+			return nil, fmt.Errorf("annotations %s and %s require health check protocol %q, but %s is %q",
+				healthCheckPathAnnotation, healthCheckExpectedStatusAnnotation, healthCheckProtocolHTTP, healthCheckProtocolAnnotation, protocol)
+		}
+		return nil, nil
+	case healthCheckProtocolHTTP:
+	default:
+		return nil, fmt.Errorf("unsupported health check protocol %q in annotation %s, must be %q or %q",
+			protocol, healthCheckProtocolAnnotation, healthCheckProtocolTCP, healthCheckProtocolHTTP)
+	}
+
+	httpHealthChecks := &loadbalancer.HttpHealthChecks{}
+	if pathFound {
+		if !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("invalid health check path %q in annotation %s, must start with /", path, healthCheckPathAnnotation)
+		}
+		httpHealthChecks.Path = &path
+	}
+	if expectedStatusFound {
+		for i, statusStr := range strings.Split(expectedStatus, ",") {
+			statusStr = strings.TrimSpace(statusStr)
+			code, err := strconv.Atoi(statusStr)
+			if err != nil || code < 100 || code > 599 {
+				return nil, fmt.Errorf("invalid HTTP status code %q at position %d in annotation %q", statusStr, i, healthCheckExpectedStatusAnnotation)
+			}
+			httpHealthChecks.OkStatuses = append(httpHealthChecks.OkStatuses, statusStr)
+		}
+	}
+
+	return &loadbalancer.ActiveHealthCheck{
+		HttpHealthChecks: httpHealthChecks,
+	}, nil
+}
+
+// privateRanges are the address ranges clients of internal load balancers can come from.
+var privateRanges = []netip.Prefix{
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+}
+
+// accessControlFromService returns the access control options of the load balancer.
+// The allowed source ranges are taken from the spec or the yawol annotation, the denied source ranges are removed from
+// them. Conflicting or ineffective configurations are reported as events.
+func accessControlFromService(service *corev1.Service, privateNetworkOnly bool) (
+	*loadbalancer.LoadbalancerOptionAccessControl, []Event, error,
+) {
+	var events []Event
+	conflict := func(format string, args ...any) {
+		events = append(events, Event{
+			Type:    corev1.EventTypeWarning,
+			Reason:  eventReasonConflictingSourceRanges,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	// For backwards-compatibility, the spec takes precedence over the annotation.
+	var allowedStrs []string
+	yawolRanges, yawolFound := service.Annotations[yawolLoadBalancerSourceRangesAnnotation]
+	if yawolFound {
+		allowedStrs = strings.Split(yawolRanges, ",")
+	}
+	if len(service.Spec.LoadBalancerSourceRanges) > 0 {
+		if yawolFound && !slices.Equal(allowedStrs, service.Spec.LoadBalancerSourceRanges) {
+			conflict("Annotation %s is ignored because it differs from spec.loadBalancerSourceRanges", yawolLoadBalancerSourceRangesAnnotation)
+		}
+		allowedStrs = service.Spec.LoadBalancerSourceRanges
+	}
+	allowed, err := parseSourceRanges(allowedStrs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid allowed source ranges: %w", err)
+	}
+
+	if privateNetworkOnly {
+		var public []string
+		for _, prefix := range allowed {
+			if !slices.ContainsFunc(privateRanges, prefix.Overlaps) {
+				public = append(public, prefix.String())
+			}
+		}
+		if len(public) > 0 {
+			conflict("The load balancer is internal, allowed source ranges without private addresses have no effect: %s", strings.Join(public, ", "))
+		}
+	}
+
+	deniedStr, found := service.Annotations[deniedSourceRangesAnnotation]
+	if !found {
+		return &loadbalancer.LoadbalancerOptionAccessControl{AllowedSourceRanges: allowedStrs}, events, nil
+	}
+	denied, err := parseSourceRanges(strings.Split(deniedStr, ","))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid value for annotation %s: %w", deniedSourceRangesAnnotation, err)
+	}
+	for _, prefix := range denied {
+		if !prefix.Addr().Is4() {
+			return nil, nil, fmt.Errorf("annotation %s only supports IPv4 ranges, got %s", deniedSourceRangesAnnotation, prefix)
+		}
+	}
+
+	if len(allowed) == 0 {
+		allowed = []netip.Prefix{netip.MustParsePrefix(securityGroupRuleAnyIPv4)}
+	}
+	var result []string
+	for _, allowedPrefix := range allowed {
+		remaining := []netip.Prefix{allowedPrefix}
+		for _, deniedPrefix := range denied {
+			var next []netip.Prefix
+			for _, p := range remaining {
+				next = append(next, subtractPrefix(p, deniedPrefix)...)
+			}
+			remaining = next
+		}
+		if len(remaining) == 0 {
+			conflict("Allowed source range %s is entirely denied by annotation %s", allowedPrefix, deniedSourceRangesAnnotation)
+		}
+		for _, p := range remaining {
+			result = append(result, p.String())
+		}
+	}
+	if len(result) == 0 {
+		// An empty list would allow all source ranges.
+		return nil, nil, fmt.Errorf("annotation %s denies all allowed source ranges", deniedSourceRangesAnnotation)
+	}
+	return &loadbalancer.LoadbalancerOptionAccessControl{AllowedSourceRanges: result}, events, nil
+}
+
+// parseSourceRanges parses CIDRs and ignores surrounding whitespace.
+func parseSourceRanges(ranges []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(ranges))
+	for _, r := range ranges {
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(r))
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// subtractPrefix returns the smallest set of prefixes that covers all addresses of p that are not in denied.
+func subtractPrefix(p, denied netip.Prefix) []netip.Prefix {
+	switch {
+	case !p.Overlaps(denied):
+		return []netip.Prefix{p}
+	case denied.Bits() <= p.Bits():
+		// denied contains p.
+		return nil
+	}
+	// Split p into halves, only one of them overlaps with denied.
+	lower := netip.PrefixFrom(p.Addr(), p.Bits()+1)
+	upperAddr := p.Addr().AsSlice()
+	upperAddr[p.Bits()/8] |= 0x80 >> (p.Bits() % 8)
+	addr, _ := netip.AddrFromSlice(upperAddr)
+	upper := netip.PrefixFrom(addr, p.Bits()+1)
+	return slices.Concat(subtractPrefix(lower, denied), subtractPrefix(upper, denied))
+}
+
 func checkUnsupportedAnnotations(service *corev1.Service) *Event {
 	usedAnnotations := []string{}
 	for _, a := range yawolUnsupportedAnnotations {
@@ -604,21 +1192,69 @@ type resultImmutableChanged struct {
 	annotation string
 }
 
+// specDiff is a property of the load balancer that differs from the specification and requires an update.
+type specDiff struct {
+	// field is the path of the property, e.g. ".listeners[0].port".
+	field string
+	// current and desired are the JSON encoded values of the load balancer and the specification.
+	current string
+	desired string
+}
+
+func newSpecDiff(field string, current, desired any) specDiff {
+	return specDiff{field: field, current: diffValue(current), desired: diffValue(desired)}
+}
+
+func diffValue(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+// diffFields returns the fields of diffs.
+func diffFields(diffs []specDiff) []string {
+	fields := make([]string, 0, len(diffs))
+	for _, diff := range diffs {
+		fields = append(fields, diff.field)
+	}
+	return fields
+}
+
+// diffFieldLabel returns the field without indices and map keys, e.g. ".listeners.port", to keep the cardinality of
+// the update metric low.
+func diffFieldLabel(field string) string {
+	var b strings.Builder
+	depth := 0
+	for _, r := range field {
+		switch {
+		case r == '[':
+			depth++
+		case r == ']':
+			depth--
+		case depth == 0:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
 // compareLBwithSpec checks whether the load balancer fulfills the specification.
 // If immutableChanged is not nil then spec differs from lb such that an update will fail.
-// Otherwise, fulfills will indicate whether an update is necessary.
-
-func compareLBwithSpec(lb *loadbalancer.LoadBalancer, spec *loadbalancer.CreateLoadBalancerPayload) (fulfills bool, immutableChanged *resultImmutableChanged) { //nolint:gocyclo,funlen,lll // It is long but not complex.
+// Otherwise, diffs contains all properties that differ, and an update is necessary if it is not empty.
+func compareLBwithSpec(lb *loadbalancer.LoadBalancer, spec *loadbalancer.CreateLoadBalancerPayload) (diffs []specDiff, immutableChanged *resultImmutableChanged) { //nolint:gocyclo,funlen,lll // It is long but not complex.
 	// If a mutable property has changed we must still check the rest of the object because if there is an immutable change it must always be returned.
-	fulfills = true
+	lbOptions := cmp.UnpackPtr(lb.Options)
+	specOptions := cmp.UnpackPtr(spec.Options)
 
-	if cmp.UnpackPtr(cmp.UnpackPtr(lb.Options).PrivateNetworkOnly) != cmp.UnpackPtr(cmp.UnpackPtr(spec.Options).PrivateNetworkOnly) {
-		return false, &resultImmutableChanged{field: ".options.privateNetworkOnly", annotation: internalLBAnnotation}
+	if cmp.UnpackPtr(lbOptions.PrivateNetworkOnly) != cmp.UnpackPtr(specOptions.PrivateNetworkOnly) {
+		return nil, &resultImmutableChanged{field: ".options.privateNetworkOnly", annotation: internalLBAnnotation}
 	}
 
 	if !cmp.PtrValEqualFn(
-		cmp.UnpackPtr(lb.Options).Observability,
-		cmp.UnpackPtr(spec.Options).Observability,
+		lbOptions.Observability,
+		specOptions.Observability,
 		func(a, b loadbalancer.LoadbalancerOptionObservability) bool {
 			sameMetrics := cmp.PtrValEqualFn(
 				a.Metrics,
@@ -639,82 +1275,102 @@ func compareLBwithSpec(lb *loadbalancer.LoadBalancer, spec *loadbalancer.CreateL
 			return sameMetrics && sameLogs
 		},
 	) {
-		fulfills = false
+		diffs = append(diffs, newSpecDiff(".options.observability", lbOptions.Observability, specOptions.Observability))
+	}
+
+	// Labels that are no longer desired are kept until the next update for other reasons.
+	lbLabels := cmp.UnpackPtr(lb.Labels)
+	for _, key := range slices.Sorted(maps.Keys(cmp.UnpackPtr(spec.Labels))) {
+		value := (*spec.Labels)[key]
+		if current, found := lbLabels[key]; !found || current != value {
+			var currentValue *string
+			if found {
+				currentValue = &current
+			}
+			diffs = append(diffs, newSpecDiff(fmt.Sprintf(".labels[%s]", key), currentValue, value))
+		}
 	}
 
 	if cmp.UnpackPtr(spec.ExternalAddress) != "" {
 		// lb.ExternalAddress is set to the ephemeral IP if the load balancer is ephemeral, while spec will never contain an ephemeral IP.
 		// So we only compare them if the spec has a static IP.
 		if !cmp.PtrValEqual(lb.ExternalAddress, spec.ExternalAddress) {
-			return false, &resultImmutableChanged{field: ".externalAddress", annotation: externalIPAnnotation}
+			return nil, &resultImmutableChanged{field: ".externalAddress", annotation: externalIPAnnotation}
 		}
-		if cmp.UnpackPtr(cmp.UnpackPtr(lb.Options).EphemeralAddress) {
+		if cmp.UnpackPtr(lbOptions.EphemeralAddress) {
 			// Promote an ephemeral IP to a static IP.
-			fulfills = false
+			diffs = append(diffs, newSpecDiff(".options.ephemeralAddress", lbOptions.EphemeralAddress, specOptions.EphemeralAddress))
 		}
-	} else if !cmp.UnpackPtr(cmp.UnpackPtr(lb.Options).PrivateNetworkOnly) &&
-		!cmp.UnpackPtr(cmp.UnpackPtr(lb.Options).EphemeralAddress) {
+	} else if !cmp.UnpackPtr(lbOptions.PrivateNetworkOnly) &&
+		!cmp.UnpackPtr(lbOptions.EphemeralAddress) {
 		// Demotion is not allowed by the load balancer API.
-		return false, &resultImmutableChanged{field: ".options.ephemeralAddress", annotation: externalIPAnnotation}
+		return nil, &resultImmutableChanged{field: ".options.ephemeralAddress", annotation: externalIPAnnotation}
 	}
 
 	if len(lb.Listeners) != len(spec.Listeners) {
-		fulfills = false
+		diffs = append(diffs, newSpecDiff(".listeners", lb.Listeners, spec.Listeners))
 	} else {
 		for i, x := range lb.Listeners {
 			y := spec.Listeners[i]
+			field := func(name string) string { return fmt.Sprintf(".listeners[%d].%s", i, name) }
 			if !cmp.PtrValEqual(x.DisplayName, y.DisplayName) {
-				fulfills = false
+				diffs = append(diffs, newSpecDiff(field("displayName"), x.DisplayName, y.DisplayName))
 			}
 			if !cmp.PtrValEqual(x.Port, y.Port) {
-				fulfills = false
+				diffs = append(diffs, newSpecDiff(field("port"), x.Port, y.Port))
 			}
-			if !cmp.PtrValEqual(x.Protocol, y.Protocol) {
-				fulfills = false
+			protocol := listenerProtocol(x)
+			if protocol != cmp.UnpackPtr(y.Protocol) {
+				diffs = append(diffs, newSpecDiff(field("protocol"), x.Protocol, y.Protocol))
 			}
 			if !cmp.PtrValEqual(x.TargetPool, y.TargetPool) {
-				fulfills = false
+				diffs = append(diffs, newSpecDiff(field("targetPool"), x.TargetPool, y.TargetPool))
 			}
-			if (cmp.UnpackPtr(x.Protocol) == loadbalancer.LISTENERPROTOCOL_PROTOCOL_TCP || cmp.UnpackPtr(x.Protocol) == loadbalancer.LISTENERPROTOCOL_PROTOCOL_TCP_PROXY) &&
+			if isTCPListenerProtocol(protocol) &&
 				!cmp.PtrValEqualFn(x.Tcp, y.Tcp, func(a, b loadbalancer.OptionsTCP) bool {
 					return cmp.PtrValEqual(a.IdleTimeout, b.IdleTimeout)
 				}) {
-				fulfills = false
+				diffs = append(diffs, newSpecDiff(field("tcp"), x.Tcp, y.Tcp))
 			}
-			if cmp.UnpackPtr(x.Protocol) == loadbalancer.LISTENERPROTOCOL_PROTOCOL_UDP && !cmp.PtrValEqualFn(x.Udp, y.Udp, func(a, b loadbalancer.OptionsUDP) bool {
+			if protocol == listenerProtocolTLSTermination &&
+				!cmp.SliceEqual(listenerCertificateIDs(x), listenerCertificateIDs(y)) {
+				diffs = append(diffs, newSpecDiff(field(listenerTLSProperty+"."+listenerCertificateIDsProperty), listenerCertificateIDs(x), listenerCertificateIDs(y)))
+			}
+			if protocol == loadbalancer.LISTENERPROTOCOL_PROTOCOL_UDP && !cmp.PtrValEqualFn(x.Udp, y.Udp, func(a, b loadbalancer.OptionsUDP) bool {
 				return cmp.PtrValEqual(a.IdleTimeout, b.IdleTimeout)
 			}) {
-				fulfills = false
+				diffs = append(diffs, newSpecDiff(field("udp"), x.Udp, y.Udp))
 			}
 		}
 	}
 
 	if len(lb.Networks) != len(spec.Networks) {
-		return false, &resultImmutableChanged{field: "len(.networks)", annotation: listenerNetworkAnnotation}
+		return nil, &resultImmutableChanged{field: "len(.networks)", annotation: listenerNetworkAnnotation}
 	}
 	for i, x := range lb.Networks {
 		y := spec.Networks[i]
 		if !cmp.PtrValEqual(x.NetworkId, y.NetworkId) {
-			return false, &resultImmutableChanged{field: fmt.Sprintf(".networks[%d].networkId", i), annotation: listenerNetworkAnnotation}
+			return nil, &resultImmutableChanged{field: fmt.Sprintf(".networks[%d].networkId", i), annotation: listenerNetworkAnnotation}
 		}
 		if !cmp.PtrValEqual(x.Role, y.Role) {
-			return false, &resultImmutableChanged{field: fmt.Sprintf(".networks[%d].role", i), annotation: listenerNetworkAnnotation}
+			return nil, &resultImmutableChanged{field: fmt.Sprintf(".networks[%d].role", i), annotation: listenerNetworkAnnotation}
 		}
 	}
 
 	if len(lb.TargetPools) != len(spec.TargetPools) {
-		fulfills = false
+		diffs = append(diffs, newSpecDiff(".targetPools", lb.TargetPools, spec.TargetPools))
 	} else {
 		for i, x := range lb.TargetPools {
 			y := spec.TargetPools[i]
+			field := func(name string) string { return fmt.Sprintf(".targetPools[%d].%s", i, name) }
 			if !cmp.PtrValEqual(x.Name, y.Name) {
-				fulfills = false
+				diffs = append(diffs, newSpecDiff(field("name"), x.Name, y.Name))
 			}
 			if !cmp.PtrValEqual(x.TargetPort, y.TargetPort) {
-				fulfills = false
+				diffs = append(diffs, newSpecDiff(field("targetPort"), x.TargetPort, y.TargetPort))
 			}
 			if cmp.UnpackPtr(cmp.UnpackPtr(x.SessionPersistence).UseSourceIpAddress) != cmp.UnpackPtr(cmp.UnpackPtr(y.SessionPersistence).UseSourceIpAddress) {
-				fulfills = false
+				diffs = append(diffs, newSpecDiff(field("sessionPersistence"), x.SessionPersistence, y.SessionPersistence))
 			}
 			if !cmp.PtrValEqualFn(x.ActiveHealthCheck, y.ActiveHealthCheck, func(a, b loadbalancer.ActiveHealthCheck) bool {
 				if !cmp.PtrValEqual(a.HealthyThreshold, b.HealthyThreshold) {
@@ -732,9 +1388,14 @@ func compareLBwithSpec(lb *loadbalancer.LoadBalancer, spec *loadbalancer.CreateL
 				if !cmp.PtrValEqual(a.UnhealthyThreshold, b.UnhealthyThreshold) {
 					return false
 				}
+				if !cmp.PtrValEqualFn(a.HttpHealthChecks, b.HttpHealthChecks, func(c, d loadbalancer.HttpHealthChecks) bool {
+					return cmp.SliceEqual(c.OkStatuses, d.OkStatuses) && cmp.PtrValEqual(c.Path, d.Path)
+				}) {
+					return false
+				}
 				return true
 			}) {
-				fulfills = false
+				diffs = append(diffs, newSpecDiff(field("activeHealthCheck"), x.ActiveHealthCheck, y.ActiveHealthCheck))
 			}
 			if !cmp.SliceEqualUnordered(x.Targets, y.Targets, func(a, b loadbalancer.Target) bool {
 				if !cmp.PtrValEqual(a.DisplayName, b.DisplayName) {
@@ -745,7 +1406,7 @@ func compareLBwithSpec(lb *loadbalancer.LoadBalancer, spec *loadbalancer.CreateL
 				}
 				return true
 			}) {
-				fulfills = false
+				diffs = append(diffs, newSpecDiff(field("targets"), x.Targets, y.Targets))
 			}
 		}
 	}
@@ -754,17 +1415,284 @@ func compareLBwithSpec(lb *loadbalancer.LoadBalancer, spec *loadbalancer.CreateL
 		// In this comparison, an empty service plan is not equal to a default service plan.
 		// The API might return a default value if no value is specified.
 		// To avoid problems in the change detection, the CCM should also explicitly set a value.
-		fulfills = false
+		diffs = append(diffs, newSpecDiff(".planId", lb.PlanId, spec.PlanId))
 	}
 
-	if !cmp.SliceEqual(
-		cmp.UnpackPtr(cmp.UnpackPtr(lb.Options).AccessControl).AllowedSourceRanges,
-		cmp.UnpackPtr(cmp.UnpackPtr(spec.Options).AccessControl).AllowedSourceRanges,
-	) {
-		fulfills = false
+	lbSourceRanges := cmp.UnpackPtr(lbOptions.AccessControl).AllowedSourceRanges
+	specSourceRanges := cmp.UnpackPtr(specOptions.AccessControl).AllowedSourceRanges
+	if !cmp.SliceEqual(lbSourceRanges, specSourceRanges) {
+		diffs = append(diffs, newSpecDiff(".options.accessControl.allowedSourceRanges", lbSourceRanges, specSourceRanges))
+	}
+
+	return diffs, nil
+}
+
+// tlsFromAnnotations returns the TLS mode of all TCP ports, the per-port overrides and the certificates for TLS termination.
+func tlsFromAnnotations(service *corev1.Service, enabled bool) (tlsMode, map[int32]tlsMode, []string, error) {
+	defaultMode := tlsModeNone
+	if value, found := service.Annotations[tlsModeAnnotation]; found {
+		mode, err := parseTLSMode(value)
+		if err != nil {
+			return tlsModeNone, nil, nil, fmt.Errorf("invalid %s: %w", tlsModeAnnotation, err)
+		}
+		defaultMode = mode
+	}
+	overrides, err := perPortAnnotations(service, tlsModeAnnotation, parseTLSMode)
+	if err != nil {
+		return tlsModeNone, nil, nil, err
 	}
+	if !enabled {
+		usesTLS := defaultMode != tlsModeNone
+		for _, mode := range overrides {
+			usesTLS = usesTLS || mode != tlsModeNone
+		}
+		if usesTLS {
+			return tlsModeNone, nil, nil, fmt.Errorf("TLS listeners are not enabled in the cloud controller manager, see feature gate %s", features.TLSListeners)
+		}
+	}
+
+	var certificateIDs []string
+	for id := range strings.SplitSeq(service.Annotations[tlsCertificateIDsAnnotation], ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			certificateIDs = append(certificateIDs, id)
+		}
+	}
+	return defaultMode, overrides, certificateIDs, nil
+}
+
+func parseTLSMode(value string) (tlsMode, error) {
+	switch mode := tlsMode(value); mode {
+	case tlsModeNone, tlsModePassthrough, tlsModeTermination:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown TLS mode %q, must be one of %q, %q or %q", value, tlsModeNone, tlsModePassthrough, tlsModeTermination)
+	}
+}
+
+// targetModeFromService returns the target mode of the service, which defaults to node.
+func targetModeFromService(service *corev1.Service) (targetMode, error) {
+	value, found := service.Annotations[targetModeAnnotation]
+	if !found {
+		return targetModeNode, nil
+	}
+	switch mode := targetMode(value); mode {
+	case targetModeNode, targetModePod:
+		return mode, nil
+	default:
+		return targetModeNode, fmt.Errorf("unknown target mode %q for annotation %s, must be %q or %q",
+			value, targetModeAnnotation, targetModeNode, targetModePod)
+	}
+}
+
+// filterTargetNodes returns the nodes that can be targets of load balancers. Nodes with the
+// node.kubernetes.io/exclude-from-external-load-balancers label are always skipped, like in the service controller.
+// Not ready and unschedulable nodes are skipped if configured.
+func filterTargetNodes(nodes []*corev1.Node, opts stackitconfig.LoadBalancerOpts) []*corev1.Node {
+	return slices.DeleteFunc(slices.Clone(nodes), func(node *corev1.Node) bool {
+		if excludedFromLoadBalancers(node) {
+			return true
+		}
+		if opts.ExcludeNotReadyNodes && !nodeReady(node) {
+			return true
+		}
+		return opts.ExcludeUnschedulableNodes && node.Spec.Unschedulable
+	})
+}
+
+// excludedFromLoadBalancers returns whether the node has the node.kubernetes.io/exclude-from-external-load-balancers
+// label. Like in the service controller, the value is ignored, so that "false" also excludes the node.
+func excludedFromLoadBalancers(node *corev1.Node) bool {
+	_, found := node.Labels[corev1.LabelNodeExcludeBalancers]
+	return found
+}
+
+// nodeReady returns whether the node has a ready condition.
+func nodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
 
-	return fulfills, immutableChanged
+// hasStaticTargets returns whether the targets of the service are set by staticTargetsAnnotation instead of the nodes.
+func hasStaticTargets(service *corev1.Service) bool {
+	_, found := service.Annotations[staticTargetsAnnotation]
+	return found
+}
+
+// staticTargetsFromService returns the targets of staticTargetsAnnotation, or nil if the annotation is not set.
+func staticTargetsFromService(service *corev1.Service) ([]loadbalancer.Target, error) {
+	value, found := service.Annotations[staticTargetsAnnotation]
+	if !found {
+		return nil, nil
+	}
+	if strings.TrimSpace(value) == "" {
+		return nil, fmt.Errorf("annotation %s must not be empty", staticTargetsAnnotation)
+	}
+	targets := []loadbalancer.Target{}
+	names := map[string]bool{}
+	for entry := range strings.SplitSeq(value, ",") {
+		name, ip, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found {
+			return nil, fmt.Errorf("invalid target %q in annotation %s, must be name=ip", entry, staticTargetsAnnotation)
+		}
+		if !targetDisplayNameRegexp.MatchString(name) {
+			return nil, fmt.Errorf("invalid target name %q in annotation %s, must consist of up to 63 alphanumeric characters or '-'",
+				name, staticTargetsAnnotation)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("duplicate target name %q in annotation %s", name, staticTargetsAnnotation)
+		}
+		names[name] = true
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP %q of target %q in annotation %s", ip, name, staticTargetsAnnotation)
+		}
+		targets = append(targets, loadbalancer.Target{DisplayName: new(name), Ip: new(addr.String())})
+	}
+	return targets, nil
+}
+
+// staticTargetPort returns the port on which static targets receive the traffic of a service port: the numeric
+// target port, or the port itself if the target port is named or not set.
+func staticTargetPort(port corev1.ServicePort) int32 {
+	if port.TargetPort.Type == intstr.Int && port.TargetPort.IntVal != 0 {
+		return port.TargetPort.IntVal
+	}
+	return port.Port
+}
+
+// portRangePorts returns a port for every port of the port range annotations. They are named range-<protocol>-<port>
+// and use the node port and the target port of the range.
+func portRangePorts(service *corev1.Service) ([]corev1.ServicePort, error) {
+	used := map[string]bool{}
+	for _, port := range service.Spec.Ports {
+		used[fmt.Sprintf("%s/%d", port.Protocol, port.Port)] = true
+	}
+
+	var ports []corev1.ServicePort
+	for annotation, protocol := range map[string]corev1.Protocol{
+		portRangeTCPAnnotation: corev1.ProtocolTCP,
+		portRangeUDPAnnotation: corev1.ProtocolUDP,
+	} {
+		value, found := service.Annotations[annotation]
+		if !found {
+			continue
+		}
+		start, end, base, err := parsePortRange(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port range %q in annotation %s: %w", value, annotation, err)
+		}
+		for port := start; port <= end; port++ {
+			if used[fmt.Sprintf("%s/%d", protocol, port)] {
+				return nil, fmt.Errorf("port %d of annotation %s is already used by the service", port, annotation)
+			}
+			targetPort := base + port - start
+			ports = append(ports, corev1.ServicePort{
+				Name:       fmt.Sprintf("range-%s-%d", strings.ToLower(string(protocol)), port),
+				Protocol:   protocol,
+				Port:       port,
+				NodePort:   targetPort,
+				TargetPort: intstr.FromInt32(targetPort),
+			})
+		}
+	}
+	// The order of the annotations must not change the spec.
+	slices.SortFunc(ports, func(a, b corev1.ServicePort) int { return strings.Compare(a.Name, b.Name) })
+	return ports, nil
+}
+
+// parsePortRange parses "start-end" or "start-end:base". The base defaults to start.
+func parsePortRange(value string) (start, end, base int32, err error) {
+	rangeStr, baseStr, hasBase := strings.Cut(strings.TrimSpace(value), ":")
+	startStr, endStr, found := strings.Cut(rangeStr, "-")
+	if !found {
+		return 0, 0, 0, errors.New("must be start-end or start-end:base")
+	}
+	parse := func(s string) (int32, error) {
+		port, err := strconv.ParseUint(strings.TrimSpace(s), 10, 16)
+		if err != nil || port == 0 {
+			return 0, fmt.Errorf("invalid port %q", s)
+		}
+		return int32(port), nil //nolint:gosec // Parsed as 16 bit integer.
+	}
+	if start, err = parse(startStr); err != nil {
+		return 0, 0, 0, err
+	}
+	if end, err = parse(endStr); err != nil {
+		return 0, 0, 0, err
+	}
+	if end < start {
+		return 0, 0, 0, errors.New("end of range is lower than start")
+	}
+	if end-start+1 > maxPortRangeSize {
+		return 0, 0, 0, fmt.Errorf("range contains more than %d ports", maxPortRangeSize)
+	}
+	base = start
+	if hasBase {
+		if base, err = parse(baseStr); err != nil {
+			return 0, 0, 0, err
+		}
+		if base+end-start > 65535 {
+			return 0, 0, 0, errors.New("target ports exceed 65535")
+		}
+	}
+	return start, end, base, nil
+}
+
+// targetPoolName returns the name of the listener and the target pool of a service port.
+func targetPoolName(port corev1.ServicePort) string {
+	if port.Name != "" {
+		return port.Name
+	}
+	// Use a descriptive name for a port without name. This only applies for
+	// services with a single port. A service with more than one port must
+	// have names set for all ports.
+	return fmt.Sprintf("port-%s-%d", strings.ToLower(string(port.Protocol)), port.Port)
+}
+
+// isTCPListenerProtocol returns whether the listener has TCP options.
+func isTCPListenerProtocol(protocol loadbalancer.ListenerProtocol) bool {
+	switch protocol {
+	case loadbalancer.LISTENERPROTOCOL_PROTOCOL_TCP, loadbalancer.LISTENERPROTOCOL_PROTOCOL_TCP_PROXY,
+		loadbalancer.LISTENERPROTOCOL_PROTOCOL_TLS_PASSTHROUGH, listenerProtocolTLSTermination:
+		return true
+	default:
+		return false
+	}
+}
+
+// listenerProtocol returns the protocol of a listener returned by the API. The API client decodes protocols it doesn't
+// know, like listenerProtocolTLSTermination, as LISTENERPROTOCOL_UNKNOWN_DEFAULT_OPEN_API, so a listener with an
+// unknown protocol and TLS properties uses TLS termination.
+func listenerProtocol(listener loadbalancer.Listener) loadbalancer.ListenerProtocol {
+	protocol := cmp.UnpackPtr(listener.Protocol)
+	if protocol == loadbalancer.LISTENERPROTOCOL_UNKNOWN_DEFAULT_OPEN_API && listener.AdditionalProperties[listenerTLSProperty] != nil {
+		return listenerProtocolTLSTermination
+	}
+	return protocol
+}
+
+// listenerCertificateIDs returns the certificates of a listener with TLS termination.
+// Listeners returned by the API contain the JSON decoded additional properties.
+func listenerCertificateIDs(listener loadbalancer.Listener) []string {
+	tls, _ := listener.AdditionalProperties[listenerTLSProperty].(map[string]any)
+	switch ids := tls[listenerCertificateIDsProperty].(type) {
+	case []string:
+		return ids
+	case []any:
+		certificateIDs := make([]string, 0, len(ids))
+		for _, id := range ids {
+			if s, ok := id.(string); ok {
+				certificateIDs = append(certificateIDs, s)
+			}
+		}
+		return certificateIDs
+	default:
+		return nil
+	}
 }
 
 // sanitizeNodeName returns a node name which fits in the DisplayName of a target.
@@ -1,17 +1,25 @@
 package ccm
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"slices"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gstruct"
 	"github.com/onsi/gomega/types"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/features"
 	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	featuregatetesting "k8s.io/component-base/featuregate/testing"
 
 	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/utils/ptr"
 )
 
@@ -160,6 +168,57 @@ var _ = Describe("lbSpecFromService", func() {
 			Expect(err).To(MatchError(ContainSubstring("incompatible values")))
 		})
 
+		It("should take external IP from spec.loadBalancerIP", func() {
+			spec, _, err := lbSpecFromService(&corev1.Service{
+				Spec: corev1.ServiceSpec{LoadBalancerIP: externalAddress},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.ExternalAddress).To(PointTo(Equal(externalAddress)))
+			Expect(spec.Options.EphemeralAddress).To(PointTo(BeFalse()))
+		})
+
+		It("should accept spec.loadBalancerIP matching the annotation", func() {
+			spec, _, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/external-address": externalAddress,
+					},
+				},
+				Spec: corev1.ServiceSpec{LoadBalancerIP: externalAddress},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.ExternalAddress).To(PointTo(Equal(externalAddress)))
+		})
+
+		DescribeTable("should error if spec.loadBalancerIP differs from the annotation",
+			func(annotation string) {
+				_, _, err := lbSpecFromService(&corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{
+						Annotations: map[string]string{annotation: "123.124.88.99"},
+					},
+					Spec: corev1.ServiceSpec{LoadBalancerIP: "55.66.77.88"},
+				}, []*corev1.Node{}, lbOpts, nil)
+				Expect(err).To(MatchError(ContainSubstring(
+					fmt.Sprintf("incompatible values for annotation %s and spec.loadBalancerIP", annotation),
+				)))
+			},
+			Entry("STACKIT annotation", "lb.stackit.cloud/external-address"),
+			Entry("yawol annotation", "yawol.stackit.cloud/existingFloatingIP"),
+		)
+
+		It("should ignore spec.loadBalancerIP for internal load balancers", func() {
+			spec, _, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/internal-lb": "true",
+					},
+				},
+				Spec: corev1.ServiceSpec{LoadBalancerIP: externalAddress},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.ExternalAddress).To(BeNil())
+		})
+
 		It("should error if external IP is not a valid IP", func() {
 			_, _, err := lbSpecFromService(&corev1.Service{
 				ObjectMeta: metav1.ObjectMeta{
@@ -274,6 +333,74 @@ var _ = Describe("lbSpecFromService", func() {
 			Expect(spec).To(haveConsistentTargetPool())
 		})
 
+		It("should set TCP proxy protocol for port ranges", func() {
+			spec, _, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/internal-lb":                    "true",
+						"yawol.stackit.cloud/tcpProxyProtocol":            "true",
+						"yawol.stackit.cloud/tcpProxyProtocolPortsFilter": "1-100, 8000-8100",
+					},
+				},
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{http, httpAlt, https},
+				},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.Listeners).To(ConsistOf(
+				MatchFields(IgnoreExtras, Fields{
+					"DisplayName": PointTo(Equal("http")),
+					"Protocol":    PointTo(Equal(loadbalancer.LISTENERPROTOCOL_PROTOCOL_TCP_PROXY)),
+				}),
+				MatchFields(IgnoreExtras, Fields{
+					"DisplayName": PointTo(Equal("http-alt")),
+					"Protocol":    PointTo(Equal(loadbalancer.LISTENERPROTOCOL_PROTOCOL_TCP_PROXY)),
+				}),
+				MatchFields(IgnoreExtras, Fields{
+					"DisplayName": PointTo(Equal("https")),
+					"Protocol":    PointTo(Equal(loadbalancer.LISTENERPROTOCOL_PROTOCOL_TCP)),
+				}),
+			))
+		})
+
+		It("should set TCP proxy protocol for all ports with wildcard filter", func() {
+			spec, _, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/internal-lb":                     "true",
+						"lb.stackit.cloud/tcp-proxy-protocol":              "true",
+						"lb.stackit.cloud/tcp-proxy-protocol-ports-filter": "80,*",
+					},
+				},
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{http, https},
+				},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.Listeners).To(HaveEach(
+				HaveField("Protocol", PointTo(Equal(loadbalancer.LISTENERPROTOCOL_PROTOCOL_TCP_PROXY))),
+			))
+		})
+
+		It("should error on invalid port ranges", func() {
+			_, _, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/internal-lb":                     "true",
+						"lb.stackit.cloud/tcp-proxy-protocol":              "true",
+						"lb.stackit.cloud/tcp-proxy-protocol-ports-filter": "100-80,8000-",
+					},
+				},
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{http},
+				},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).To(MatchError(And(
+				ContainSubstring(`invalid port "100-80" at position 0`),
+				ContainSubstring(`invalid port "8000-" at position 1`),
+			)))
+		})
+
 		It("should not set TCP proxy protocol on empty filter", func() {
 			spec, _, err := lbSpecFromService(&corev1.Service{
 				ObjectMeta: metav1.ObjectMeta{
@@ -475,6 +602,118 @@ var _ = Describe("lbSpecFromService", func() {
 				})),
 			})))
 		})
+		It("should reject invalid source IP ranges", func() {
+			_, _, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/external-address": externalAddress,
+					},
+				},
+				Spec: corev1.ServiceSpec{
+					LoadBalancerSourceRanges: []string{"15.0.0.0/33"},
+				},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).To(MatchError(ContainSubstring("invalid allowed source ranges")))
+		})
+
+		It("should warn if the yawol annotation differs from the spec", func() {
+			_, events, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/external-address":            externalAddress,
+						"yawol.stackit.cloud/loadBalancerSourceRanges": "2.0.0.0/8",
+					},
+				},
+				Spec: corev1.ServiceSpec{
+					LoadBalancerSourceRanges: []string{"15.0.0.0/8"},
+				},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(events).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+				"Type":    Equal(corev1.EventTypeWarning),
+				"Reason":  Equal(eventReasonConflictingSourceRanges),
+				"Message": ContainSubstring("yawol.stackit.cloud/loadBalancerSourceRanges is ignored"),
+			})))
+		})
+
+		It("should keep source IP ranges on internal load balancers and warn about public ranges", func() {
+			spec, events, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/internal-lb": "true",
+					},
+				},
+				Spec: corev1.ServiceSpec{
+					LoadBalancerSourceRanges: []string{"10.1.0.0/16", "15.0.0.0/8"},
+				},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.Options.AccessControl.AllowedSourceRanges).To(Equal([]string{"10.1.0.0/16", "15.0.0.0/8"}))
+			Expect(events).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+				"Reason":  Equal(eventReasonConflictingSourceRanges),
+				"Message": HaveSuffix("have no effect: 15.0.0.0/8"),
+			})))
+		})
+
+		It("should remove denied source IP ranges from the allowed ranges", func() {
+			spec, events, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/external-address":     externalAddress,
+						"lb.stackit.cloud/denied-source-ranges": "15.128.0.0/9, 16.0.0.0/8",
+					},
+				},
+				Spec: corev1.ServiceSpec{
+					LoadBalancerSourceRanges: []string{"15.0.0.0/8", "16.1.0.0/16", "17.0.0.0/8"},
+				},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.Options.AccessControl.AllowedSourceRanges).To(Equal([]string{"15.0.0.0/9", "17.0.0.0/8"}))
+			Expect(events).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+				"Reason":  Equal(eventReasonConflictingSourceRanges),
+				"Message": Equal("Allowed source range 16.1.0.0/16 is entirely denied by annotation lb.stackit.cloud/denied-source-ranges"),
+			})))
+		})
+
+		It("should deny source IP ranges if no ranges are allowed explicitly", func() {
+			spec, _, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/external-address":     externalAddress,
+						"lb.stackit.cloud/denied-source-ranges": "128.0.0.0/1,64.0.0.0/2",
+					},
+				},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.Options.AccessControl.AllowedSourceRanges).To(Equal([]string{"0.0.0.0/2"}))
+		})
+
+		It("should reject denied source IP ranges that deny everything", func() {
+			_, _, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/external-address":     externalAddress,
+						"lb.stackit.cloud/denied-source-ranges": "15.0.0.0/8",
+					},
+				},
+				Spec: corev1.ServiceSpec{
+					LoadBalancerSourceRanges: []string{"15.1.0.0/16"},
+				},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).To(MatchError(ContainSubstring("denies all allowed source ranges")))
+		})
+
+		It("should reject invalid denied source IP ranges", func() {
+			_, _, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/external-address":     externalAddress,
+						"lb.stackit.cloud/denied-source-ranges": "2001:db8::/32",
+					},
+				},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).To(MatchError(ContainSubstring("only supports IPv4 ranges")))
+		})
 	})
 
 	Context("target pools", func() {
@@ -540,6 +779,54 @@ var _ = Describe("lbSpecFromService", func() {
 			))
 			Expect(spec).To(haveConsistentTargetPool())
 		})
+
+		DescribeTable("node filtering",
+			func(excludeNotReady, excludeUnschedulable bool, expectedNodes ...string) {
+				node := func(name, ip string, mutate func(*corev1.Node)) *corev1.Node {
+					n := &corev1.Node{
+						ObjectMeta: metav1.ObjectMeta{Name: name},
+						Status: corev1.NodeStatus{
+							Addresses:  []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: ip}},
+							Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+						},
+					}
+					if mutate != nil {
+						mutate(n)
+					}
+					return n
+				}
+				opts := lbOpts
+				opts.ExcludeNotReadyNodes = excludeNotReady
+				opts.ExcludeUnschedulableNodes = excludeUnschedulable
+				spec, _, err := lbSpecFromService(&corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{
+						Annotations: map[string]string{"lb.stackit.cloud/external-address": externalAddress},
+					},
+					Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{http}},
+				}, []*corev1.Node{
+					node("ready", "10.0.0.1", nil),
+					node("excluded", "10.0.0.2", func(n *corev1.Node) {
+						n.Labels = map[string]string{corev1.LabelNodeExcludeBalancers: "true"}
+					}),
+					node("excluded-false", "10.0.0.3", func(n *corev1.Node) {
+						n.Labels = map[string]string{corev1.LabelNodeExcludeBalancers: "false"}
+					}),
+					node("excluded-empty", "10.0.0.4", func(n *corev1.Node) {
+						n.Labels = map[string]string{corev1.LabelNodeExcludeBalancers: ""}
+					}),
+					node("not-ready", "10.0.0.5", func(n *corev1.Node) {
+						n.Status.Conditions[0].Status = corev1.ConditionFalse
+					}),
+					node("unschedulable", "10.0.0.6", func(n *corev1.Node) { n.Spec.Unschedulable = true }),
+				}, opts, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(spec.TargetPools).To(ConsistOf(haveTargets(HaveEach(HaveField("DisplayName", PointTo(BeElementOf(expectedNodes)))))))
+				Expect(spec.TargetPools[0].Targets).To(HaveLen(len(expectedNodes)))
+			},
+			Entry("should only skip excluded nodes by default", false, false, "ready", "not-ready", "unschedulable"),
+			Entry("should skip not ready nodes", true, false, "ready", "unschedulable"),
+			Entry("should skip unschedulable nodes", false, true, "ready", "not-ready"),
+		)
 	})
 
 	DescribeTable("unsupported annotations",
@@ -789,6 +1076,81 @@ var _ = Describe("lbSpecFromService", func() {
 	})
 
 	Context("Custom service plan", func() {
+		It("should start automatic plans with the minimum plan", func() {
+			spec, _, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/external-address":  externalAddress,
+						"lb.stackit.cloud/service-plan-auto": "true",
+						"lb.stackit.cloud/service-plan-min":  p50,
+					},
+				},
+				Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{http}},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.PlanId).To(HaveValue(Equal(p50)))
+		})
+
+		It("should start automatic plans with the smallest plan that fits the listeners and targets", func() {
+			lbOpts.MaxListenersPerPlan = map[string]int{p10: 0}
+			lbOpts.MaxTargetsPerPlan = map[string]int{p50: 0}
+			spec, _, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/external-address":  externalAddress,
+						"lb.stackit.cloud/service-plan-auto": "true",
+					},
+				},
+				Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{http}},
+			}, []*corev1.Node{{
+				ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+				Status:     corev1.NodeStatus{Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.0.1"}}},
+			}}, lbOpts, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.PlanId).To(HaveValue(Equal(p250)))
+		})
+
+		It("should not start automatic plans above the maximum plan", func() {
+			lbOpts.MaxListenersPerPlan = map[string]int{p10: 0, p50: 0}
+			_, _, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/external-address":  externalAddress,
+						"lb.stackit.cloud/service-plan-auto": "true",
+						"lb.stackit.cloud/service-plan-max":  p50,
+					},
+				},
+				Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{http}},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).To(MatchError(ContainSubstring("plan p50 allows at most 0")))
+		})
+
+		DescribeTable("should reject invalid automatic plan annotations", func(annotations map[string]string, expectedErr string) {
+			annotations["lb.stackit.cloud/external-address"] = externalAddress
+			annotations["lb.stackit.cloud/service-plan-auto"] = "true"
+			_, _, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Annotations: annotations},
+				Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{http}},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+		},
+			Entry("fixed plan", map[string]string{"lb.stackit.cloud/service-plan-id": p50}, "can't be combined with lb.stackit.cloud/service-plan-id"),
+			Entry("flavor", map[string]string{"yawol.stackit.cloud/flavorId": "85f57dd5-712b-489d-a0e3-4898c3962930"}, "can't be combined"),
+			Entry("unknown minimum", map[string]string{"lb.stackit.cloud/service-plan-min": "p35"}, `unsupported plan ID value "p35"`),
+			Entry("minimum above maximum", map[string]string{
+				"lb.stackit.cloud/service-plan-min": p250,
+				"lb.stackit.cloud/service-plan-max": p50,
+			}, "is bigger than plan p50"),
+		)
+
+		It("should clamp plans to the bounds", func() {
+			bounds := planBounds{min: p50, max: p250}
+			Expect(bounds.clamp(p10)).To(Equal(p50))
+			Expect(bounds.clamp(p250)).To(Equal(p250))
+			Expect(bounds.clamp(p750)).To(Equal(p250))
+			Expect(bounds.clamp("")).To(Equal(p50))
+		})
+
 		It("should create an LB with a custom plan when service-plan-id annotation is set to a valid value", func() {
 			spec, _, err := lbSpecFromService(&corev1.Service{
 				ObjectMeta: metav1.ObjectMeta{
@@ -970,115 +1332,634 @@ var _ = Describe("lbSpecFromService", func() {
 			Expect(err).NotTo(HaveOccurred())
 			Expect(spec.Listeners).To(ConsistOf(
 				MatchFields(IgnoreExtras, Fields{
-					"DisplayName": PointTo(Equal("my-udp-port")),
+					"DisplayName": PointTo(Equal("my-udp-port")),
+					"Udp": PointTo(MatchFields(IgnoreExtras, Fields{
+						"IdleTimeout": PointTo(Equal("120s")),
+					})),
+				}),
+			))
+		})
+
+		It("should set timeout based on yawol annotation", func() {
+			spec, _, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/internal-lb":       "true",
+						"yawol.stackit.cloud/udpIdleTimeout": "3m",
+					},
+				},
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{
+						{
+							Name:     "my-udp-port",
+							Protocol: corev1.ProtocolUDP,
+							Port:     53,
+						},
+					},
+				},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.Listeners).To(ConsistOf(
+				MatchFields(IgnoreExtras, Fields{
+					"DisplayName": PointTo(Equal("my-udp-port")),
+					"Udp": PointTo(MatchFields(IgnoreExtras, Fields{
+						"IdleTimeout": PointTo(Equal("180s")),
+					})),
+				}),
+			))
+		})
+
+		It("should error on non-compatible timeouts", func() {
+			_, _, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/internal-lb":       "true",
+						"lb.stackit.cloud/udp-idle-timeout":  "15m",
+						"yawol.stackit.cloud/udpIdleTimeout": "3m",
+					},
+				},
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{
+						{
+							Name:     "my-udp-port",
+							Protocol: corev1.ProtocolUDP,
+							Port:     80,
+						},
+					},
+				},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should error on invalid timeout format", func() {
+			_, _, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/internal-lb":      "true",
+						"lb.stackit.cloud/udp-idle-timeout": "15x",
+					},
+				},
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{
+						{
+							Name:     "my-udp-port",
+							Protocol: corev1.ProtocolUDP,
+							Port:     80,
+						},
+					},
+				},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should use default timeout if yawol annotation is invalid", func() {
+			spec, _, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/internal-lb":       "true",
+						"yawol.stackit.cloud/udpIdleTimeout": "3x",
+					},
+				},
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{
+						{
+							Name:     "my-udp-port",
+							Protocol: corev1.ProtocolUDP,
+							Port:     80,
+						},
+					},
+				},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.Listeners).To(ConsistOf(
+				MatchFields(IgnoreExtras, Fields{
+					"DisplayName": PointTo(Equal("my-udp-port")),
+					"Udp": PointTo(MatchFields(IgnoreExtras, Fields{
+						"IdleTimeout": PointTo(Equal("120s")),
+					})),
+				}),
+			))
+		})
+	})
+	Context("validation", func() {
+		It("should return all validation errors at once", func() {
+			_, _, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/external-address":         "not-an-ip",
+						"lb.stackit.cloud/tcp-idle-timeout":         "15x",
+						"lb.stackit.cloud/udp-idle-timeout":         "1m",
+						"yawol.stackit.cloud/udpIdleTimeout":        "2m",
+						"lb.stackit.cloud/health-check-host-header": "example.com",
+					},
+				},
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{http, dns},
+				},
+			}, []*corev1.Node{}, lbOpts, nil)
+			var aggregate utilerrors.Aggregate
+			Expect(errors.As(err, &aggregate)).To(BeTrue())
+			Expect(aggregate.Errors()).To(ConsistOf(
+				MatchError(ContainSubstring("invalid format for external IP")),
+				MatchError(ContainSubstring("invalid format for annotation lb.stackit.cloud/tcp-idle-timeout")),
+				MatchError(ContainSubstring("incompatible values for annotations lb.stackit.cloud/udp-idle-timeout")),
+				MatchError(ContainSubstring("lb.stackit.cloud/health-check-host-header is not supported")),
+			))
+		})
+	})
+
+	Context("node ports", func() {
+		It("should reject ports without node port if node port allocation is disabled", func() {
+			http.NodePort = 30080
+			_, _, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{"lb.stackit.cloud/external-address": externalAddress},
+				},
+				Spec: corev1.ServiceSpec{
+					AllocateLoadBalancerNodePorts: new(false),
+					Ports:                         []corev1.ServicePort{http, https},
+				},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).To(MatchError(ContainSubstring("port 443 has no node port")))
+			Expect(err).NotTo(MatchError(ContainSubstring("port 80 ")))
+		})
+
+		It("should not require node ports in pod target mode", func() {
+			_, _, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/external-address": externalAddress,
+						"lb.stackit.cloud/target-mode":      "pod",
+					},
+				},
+				Spec: corev1.ServiceSpec{
+					AllocateLoadBalancerNodePorts: new(false),
+					Ports:                         []corev1.ServicePort{http},
+				},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should reject unknown target modes", func() {
+			_, _, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/external-address": externalAddress,
+						"lb.stackit.cloud/target-mode":      "ip",
+					},
+				},
+				Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{http}},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).To(MatchError(ContainSubstring(`unknown target mode "ip"`)))
+		})
+
+		It("should accept explicit node ports if node port allocation is disabled", func() {
+			http.NodePort = 30080
+			spec, _, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{"lb.stackit.cloud/external-address": externalAddress},
+				},
+				Spec: corev1.ServiceSpec{
+					AllocateLoadBalancerNodePorts: new(false),
+					Ports:                         []corev1.ServicePort{http},
+				},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.TargetPools).To(ConsistOf(HaveField("TargetPort", PointTo(BeEquivalentTo(30080)))))
+		})
+	})
+
+	Context("static targets", func() {
+		var (
+			service *corev1.Service
+			nodes   []*corev1.Node
+		)
+
+		BeforeEach(func() {
+			http.NodePort = 30080
+			http.TargetPort = intstr.FromInt32(8080)
+			https.NodePort = 30443
+			https.TargetPort = intstr.FromString("https")
+			service = &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/external-address": externalAddress,
+						"lb.stackit.cloud/static-targets":   "gateway-1=10.0.0.10, gateway-2=10.0.0.11",
+					},
+				},
+				Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{http, https}},
+			}
+			nodes = []*corev1.Node{{
+				ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+				Status:     corev1.NodeStatus{Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.1.1"}}},
+			}}
+		})
+
+		It("should replace the nodes with the static targets", func() {
+			spec, _, err := lbSpecFromService(service, nodes, lbOpts, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.TargetPools).To(HaveLen(2))
+			for _, pool := range spec.TargetPools {
+				Expect(pool.Targets).To(Equal([]loadbalancer.Target{
+					{DisplayName: new("gateway-1"), Ip: new("10.0.0.10")},
+					{DisplayName: new("gateway-2"), Ip: new("10.0.0.11")},
+				}))
+			}
+		})
+
+		It("should use the numeric target port or the port", func() {
+			spec, _, err := lbSpecFromService(service, nodes, lbOpts, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.TargetPools).To(ConsistOf(
+				And(HaveField("Name", PointTo(Equal("http"))), HaveField("TargetPort", PointTo(BeEquivalentTo(8080)))),
+				And(HaveField("Name", PointTo(Equal("https"))), HaveField("TargetPort", PointTo(BeEquivalentTo(443)))),
+			))
+		})
+
+		It("should not require node ports", func() {
+			service.Spec.AllocateLoadBalancerNodePorts = new(false)
+			http.NodePort = 0
+			service.Spec.Ports = []corev1.ServicePort{http}
+			_, _, err := lbSpecFromService(service, nodes, lbOpts, nil)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should reject static targets in pod target mode", func() {
+			service.Annotations["lb.stackit.cloud/target-mode"] = "pod"
+			_, _, err := lbSpecFromService(service, nodes, lbOpts, nil)
+			Expect(err).To(MatchError(ContainSubstring(`can't be combined with target mode "pod"`)))
+		})
+
+		DescribeTable("should reject invalid static targets", func(value, expectedErr string) {
+			service.Annotations["lb.stackit.cloud/static-targets"] = value
+			_, _, err := lbSpecFromService(service, nodes, lbOpts, nil)
+			Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+		},
+			Entry("empty", " ", "must not be empty"),
+			Entry("without IP", "gateway-1", "must be name=ip"),
+			Entry("invalid name", "gateway_1=10.0.0.10", `invalid target name "gateway_1"`),
+			Entry("duplicate name", "gateway-1=10.0.0.10,gateway-1=10.0.0.11", `duplicate target name "gateway-1"`),
+			Entry("invalid IP", "gateway-1=10.0.0", `invalid IP "10.0.0"`),
+		)
+	})
+
+	Context("port ranges", func() {
+		var service *corev1.Service
+
+		BeforeEach(func() {
+			http.NodePort = 30080
+			service = &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/external-address": externalAddress,
+						"lb.stackit.cloud/port-range-udp":   "10000-10002:31000",
+						"lb.stackit.cloud/port-range-tcp":   "20000-20001",
+					},
+				},
+				Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{http}},
+			}
+		})
+
+		It("should add a listener and target pool for every port of the ranges", func() {
+			spec, _, err := lbSpecFromService(service, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.Listeners).To(HaveLen(6))
+			Expect(spec.TargetPools).To(HaveLen(6))
+			Expect(spec.Listeners).To(ContainElements(
+				And(HaveField("Port", PointTo(BeEquivalentTo(10001))), HaveField("TargetPool", PointTo(Equal("range-udp-10001"))),
+					HaveField("Protocol", PointTo(Equal(loadbalancer.LISTENERPROTOCOL_PROTOCOL_UDP)))),
+				And(HaveField("Port", PointTo(BeEquivalentTo(20001))), HaveField("TargetPool", PointTo(Equal("range-tcp-20001"))),
+					HaveField("Protocol", PointTo(Equal(loadbalancer.LISTENERPROTOCOL_PROTOCOL_TCP)))),
+			))
+			Expect(spec.TargetPools).To(ContainElements(
+				And(HaveField("Name", PointTo(Equal("range-udp-10001"))), HaveField("TargetPort", PointTo(BeEquivalentTo(31001)))),
+				And(HaveField("Name", PointTo(Equal("range-tcp-20001"))), HaveField("TargetPort", PointTo(BeEquivalentTo(20001)))),
+			))
+		})
+
+		It("should reject ranges that exceed the listener limit of the plan", func() {
+			lbOpts.MaxListenersPerPlan = map[string]int{"p10": 5}
+			_, _, err := lbSpecFromService(service, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).To(MatchError(ContainSubstring("the load balancer has 6 listeners, but plan p10 allows at most 5")))
+		})
+
+		It("should reject ranges that exceed the listener limit even if the plan has no limit", func() {
+			service.Annotations["lb.stackit.cloud/port-range-udp"] = "10000-10999"
+			service.Annotations["lb.stackit.cloud/port-range-tcp"] = "20000-20999"
+			_, _, err := lbSpecFromService(service, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).To(MatchError(ContainSubstring("the load balancer has 2001 listeners, but at most 1000 are supported")))
+		})
+
+		It("should reject port ranges in pod target mode", func() {
+			service.Annotations["lb.stackit.cloud/target-mode"] = "pod"
+			_, _, err := lbSpecFromService(service, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).To(MatchError(ContainSubstring(`port ranges can't be combined with target mode "pod"`)))
+		})
+
+		DescribeTable("should reject invalid port ranges", func(value, expectedErr string) {
+			service.Annotations["lb.stackit.cloud/port-range-tcp"] = value
+			_, _, err := lbSpecFromService(service, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+		},
+			Entry("without end", "20000", "must be start-end"),
+			Entry("invalid port", "20000-70000", `invalid port "70000"`),
+			Entry("reversed", "20001-20000", "end of range is lower than start"),
+			Entry("too large", "20000-21000", "more than 1000 ports"),
+			Entry("target ports too high", "20000-20010:65530", "target ports exceed 65535"),
+			Entry("overlapping with the service", "79-81", "port 80 of annotation lb.stackit.cloud/port-range-tcp is already used"),
+		)
+	})
+
+	Context("per-port overrides", func() {
+		It("should override idle timeouts and proxy protocol of individual ports", func() {
+			spec, _, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/internal-lb":           "true",
+						"lb.stackit.cloud/tcp-proxy-protocol":    "true",
+						"lb.stackit.cloud/tcp-proxy-protocol-80": "false",
+						"lb.stackit.cloud/tcp-idle-timeout":      "5m",
+						"lb.stackit.cloud/tcp-idle-timeout-443":  "30m",
+						"lb.stackit.cloud/udp-idle-timeout-53":   "10s",
+					},
+				},
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{http, https, dns},
+				},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.Listeners).To(ConsistOf(
+				MatchFields(IgnoreExtras, Fields{
+					"DisplayName": PointTo(Equal("http")),
+					"Protocol":    PointTo(Equal(loadbalancer.LISTENERPROTOCOL_PROTOCOL_TCP)),
+					"Tcp": PointTo(MatchFields(IgnoreExtras, Fields{
+						"IdleTimeout": PointTo(Equal("300s")),
+					})),
+				}),
+				MatchFields(IgnoreExtras, Fields{
+					"DisplayName": PointTo(Equal("https")),
+					"Protocol":    PointTo(Equal(loadbalancer.LISTENERPROTOCOL_PROTOCOL_TCP_PROXY)),
+					"Tcp": PointTo(MatchFields(IgnoreExtras, Fields{
+						"IdleTimeout": PointTo(Equal("1800s")),
+					})),
+				}),
+				MatchFields(IgnoreExtras, Fields{
+					"DisplayName": PointTo(Equal("dns")),
 					"Udp": PointTo(MatchFields(IgnoreExtras, Fields{
-						"IdleTimeout": PointTo(Equal("120s")),
+						"IdleTimeout": PointTo(Equal("10s")),
 					})),
 				}),
 			))
 		})
 
-		It("should set timeout based on yawol annotation", func() {
+		It("should enable the proxy protocol for individual ports", func() {
 			spec, _, err := lbSpecFromService(&corev1.Service{
 				ObjectMeta: metav1.ObjectMeta{
 					Annotations: map[string]string{
-						"lb.stackit.cloud/internal-lb":       "true",
-						"yawol.stackit.cloud/udpIdleTimeout": "3m",
+						"lb.stackit.cloud/internal-lb":            "true",
+						"lb.stackit.cloud/tcp-proxy-protocol-443": "true",
 					},
 				},
 				Spec: corev1.ServiceSpec{
-					Ports: []corev1.ServicePort{
-						{
-							Name:     "my-udp-port",
-							Protocol: corev1.ProtocolUDP,
-							Port:     53,
-						},
-					},
+					Ports: []corev1.ServicePort{http, https},
 				},
 			}, []*corev1.Node{}, lbOpts, nil)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(spec.Listeners).To(ConsistOf(
 				MatchFields(IgnoreExtras, Fields{
-					"DisplayName": PointTo(Equal("my-udp-port")),
-					"Udp": PointTo(MatchFields(IgnoreExtras, Fields{
-						"IdleTimeout": PointTo(Equal("180s")),
-					})),
+					"DisplayName": PointTo(Equal("http")),
+					"Protocol":    PointTo(Equal(loadbalancer.LISTENERPROTOCOL_PROTOCOL_TCP)),
+				}),
+				MatchFields(IgnoreExtras, Fields{
+					"DisplayName": PointTo(Equal("https")),
+					"Protocol":    PointTo(Equal(loadbalancer.LISTENERPROTOCOL_PROTOCOL_TCP_PROXY)),
 				}),
 			))
 		})
 
-		It("should error on non-compatible timeouts", func() {
+		It("should error on invalid override values", func() {
 			_, _, err := lbSpecFromService(&corev1.Service{
 				ObjectMeta: metav1.ObjectMeta{
 					Annotations: map[string]string{
-						"lb.stackit.cloud/internal-lb":       "true",
-						"lb.stackit.cloud/udp-idle-timeout":  "15m",
-						"yawol.stackit.cloud/udpIdleTimeout": "3m",
+						"lb.stackit.cloud/internal-lb":         "true",
+						"lb.stackit.cloud/tcp-idle-timeout-80": "15x",
 					},
 				},
 				Spec: corev1.ServiceSpec{
-					Ports: []corev1.ServicePort{
-						{
-							Name:     "my-udp-port",
-							Protocol: corev1.ProtocolUDP,
-							Port:     80,
-						},
-					},
+					Ports: []corev1.ServicePort{http},
 				},
 			}, []*corev1.Node{}, lbOpts, nil)
-			Expect(err).To(HaveOccurred())
+			Expect(err).To(MatchError(ContainSubstring("invalid value for annotation lb.stackit.cloud/tcp-idle-timeout-80")))
 		})
 
-		It("should error on invalid timeout format", func() {
+		It("should error on overrides for unknown ports", func() {
 			_, _, err := lbSpecFromService(&corev1.Service{
 				ObjectMeta: metav1.ObjectMeta{
 					Annotations: map[string]string{
-						"lb.stackit.cloud/internal-lb":      "true",
-						"lb.stackit.cloud/udp-idle-timeout": "15x",
+						"lb.stackit.cloud/internal-lb":          "true",
+						"lb.stackit.cloud/tcp-idle-timeout-443": "30m",
 					},
 				},
 				Spec: corev1.ServiceSpec{
-					Ports: []corev1.ServicePort{
-						{
-							Name:     "my-udp-port",
-							Protocol: corev1.ProtocolUDP,
-							Port:     80,
-						},
-					},
+					Ports: []corev1.ServicePort{http},
 				},
 			}, []*corev1.Node{}, lbOpts, nil)
-			Expect(err).To(HaveOccurred())
+			Expect(err).To(MatchError(ContainSubstring("port 443 in annotation lb.stackit.cloud/tcp-idle-timeout-443 is not a port of the service")))
 		})
+	})
 
-		It("should use default timeout if yawol annotation is invalid", func() {
+	Context("TLS listeners", func() {
+		BeforeEach(func() {
+			featuregatetesting.SetFeatureGateDuringTest(GinkgoT(), utilfeature.DefaultFeatureGate, features.TLSListeners, true)
+		})
+
+		It("should configure TLS passthrough and termination for individual ports", func() {
 			spec, _, err := lbSpecFromService(&corev1.Service{
 				ObjectMeta: metav1.ObjectMeta{
 					Annotations: map[string]string{
-						"lb.stackit.cloud/internal-lb":       "true",
-						"yawol.stackit.cloud/udpIdleTimeout": "3x",
+						"lb.stackit.cloud/internal-lb":         "true",
+						"lb.stackit.cloud/tls-mode":            "passthrough",
+						"lb.stackit.cloud/tls-mode-443":        "termination",
+						"lb.stackit.cloud/tls-mode-80":         "none",
+						"lb.stackit.cloud/tls-certificate-ids": "cert-a, cert-b",
 					},
 				},
 				Spec: corev1.ServiceSpec{
-					Ports: []corev1.ServicePort{
-						{
-							Name:     "my-udp-port",
-							Protocol: corev1.ProtocolUDP,
-							Port:     80,
-						},
-					},
+					Ports: []corev1.ServicePort{http, https, {Name: "smtps", Protocol: corev1.ProtocolTCP, Port: 465, NodePort: 30465}, dns},
 				},
 			}, []*corev1.Node{}, lbOpts, nil)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(spec.Listeners).To(ConsistOf(
 				MatchFields(IgnoreExtras, Fields{
-					"DisplayName": PointTo(Equal("my-udp-port")),
-					"Udp": PointTo(MatchFields(IgnoreExtras, Fields{
-						"IdleTimeout": PointTo(Equal("120s")),
+					"DisplayName": PointTo(Equal("http")),
+					"Protocol":    PointTo(Equal(loadbalancer.LISTENERPROTOCOL_PROTOCOL_TCP)),
+				}),
+				MatchFields(IgnoreExtras, Fields{
+					"DisplayName": PointTo(Equal("https")),
+					"Protocol":    PointTo(Equal(listenerProtocolTLSTermination)),
+					"Tcp":         Not(BeNil()),
+				}),
+				MatchFields(IgnoreExtras, Fields{
+					"DisplayName": PointTo(Equal("smtps")),
+					"Protocol":    PointTo(Equal(loadbalancer.LISTENERPROTOCOL_PROTOCOL_TLS_PASSTHROUGH)),
+				}),
+				MatchFields(IgnoreExtras, Fields{
+					"DisplayName": PointTo(Equal("dns")),
+					"Protocol":    PointTo(Equal(loadbalancer.LISTENERPROTOCOL_PROTOCOL_UDP)),
+				}),
+			))
+			Expect(listenerCertificateIDs(spec.Listeners[1])).To(Equal([]string{"cert-a", "cert-b"}))
+		})
+
+		It("should reject TLS if TLS listeners are not enabled", func() {
+			featuregatetesting.SetFeatureGateDuringTest(GinkgoT(), utilfeature.DefaultFeatureGate, features.TLSListeners, false)
+			_, _, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/internal-lb":  "true",
+						"lb.stackit.cloud/tls-mode-443": "passthrough",
+					},
+				},
+				Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{https}},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).To(MatchError(ContainSubstring("TLS listeners are not enabled")))
+		})
+
+		DescribeTable("should reject invalid configurations", func(annotations map[string]string, expectedErr string) {
+			annotations["lb.stackit.cloud/internal-lb"] = "true"
+			_, _, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Annotations: annotations},
+				Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{https}},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+		},
+			Entry("unknown mode", map[string]string{"lb.stackit.cloud/tls-mode": "mtls"}, `unknown TLS mode "mtls"`),
+			Entry("termination without certificates", map[string]string{"lb.stackit.cloud/tls-mode": "termination"},
+				"port 443 uses TLS termination, but lb.stackit.cloud/tls-certificate-ids is not set"),
+			Entry("proxy protocol", map[string]string{"lb.stackit.cloud/tls-mode": "passthrough", "lb.stackit.cloud/tcp-proxy-protocol": "true"},
+				"port 443 can't use the TCP proxy protocol and TLS passthrough at the same time"),
+		)
+	})
+
+	Context("health checks", func() {
+		It("should not configure health checks without annotations", func() {
+			spec, _, err := lbSpecFromService(&corev1.Service{
+				Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{http}},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.TargetPools).To(HaveEach(HaveField("ActiveHealthCheck", BeNil())))
+		})
+
+		It("should configure HTTP health checks with the expected status for TCP ports", func() {
+			spec, _, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/health-check-expected-status": "200, 204",
+					},
+				},
+				Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{http, dns}},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.TargetPools).To(ConsistOf(
+				MatchFields(IgnoreExtras, Fields{
+					"Name": PointTo(Equal("http")),
+					"ActiveHealthCheck": PointTo(MatchFields(IgnoreExtras, Fields{
+						"HttpHealthChecks": PointTo(MatchFields(IgnoreExtras, Fields{
+							"OkStatuses": Equal([]string{"200", "204"}),
+						})),
 					})),
 				}),
+				MatchFields(IgnoreExtras, Fields{
+					"Name":              PointTo(Equal("dns")),
+					"ActiveHealthCheck": BeNil(),
+				}),
 			))
 		})
+
+		DescribeTable("should reject invalid status codes",
+			func(value string) {
+				_, _, err := lbSpecFromService(&corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{
+						Annotations: map[string]string{
+							"lb.stackit.cloud/health-check-expected-status": value,
+						},
+					},
+					Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{http}},
+				}, []*corev1.Node{}, lbOpts, nil)
+				Expect(err).To(MatchError(ContainSubstring("invalid HTTP status code")))
+			},
+			Entry("empty", ""),
+			Entry("not a number", "ok"),
+			Entry("out of range", "200,600"),
+		)
+
+		It("should configure HTTP health checks with the path", func() {
+			spec, _, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/health-check-protocol": "http",
+						"lb.stackit.cloud/health-check-path":     "/healthz",
+					},
+				},
+				Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{http}},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.TargetPools).To(ConsistOf(HaveField("ActiveHealthCheck", PointTo(MatchFields(IgnoreExtras, Fields{
+				"HttpHealthChecks": PointTo(MatchFields(IgnoreExtras, Fields{
+					"Path":       PointTo(Equal("/healthz")),
+					"OkStatuses": BeEmpty(),
+				})),
+			})))))
+		})
+
+		It("should use the default health checks for protocol tcp", func() {
+			spec, _, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{"lb.stackit.cloud/health-check-protocol": "tcp"},
+				},
+				Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{http}},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(spec.TargetPools).To(HaveEach(HaveField("ActiveHealthCheck", BeNil())))
+		})
+
+		DescribeTable("should reject invalid health check annotations",
+			func(annotations map[string]string, message string) {
+				_, _, err := lbSpecFromService(&corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{Annotations: annotations},
+					Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{http}},
+				}, []*corev1.Node{}, lbOpts, nil)
+				Expect(err).To(MatchError(ContainSubstring(message)))
+			},
+			Entry("unsupported protocol", map[string]string{
+				"lb.stackit.cloud/health-check-protocol": "grpc",
+			}, `unsupported health check protocol "grpc"`),
+			Entry("path with protocol tcp", map[string]string{
+				"lb.stackit.cloud/health-check-protocol": "tcp",
+				"lb.stackit.cloud/health-check-path":     "/healthz",
+			}, `require health check protocol "http"`),
+			Entry("expected status with protocol tcp", map[string]string{
+				"lb.stackit.cloud/health-check-protocol":        "tcp",
+				"lb.stackit.cloud/health-check-expected-status": "200",
+			}, `require health check protocol "http"`),
+			Entry("relative path", map[string]string{
+				"lb.stackit.cloud/health-check-path": "healthz",
+			}, "must start with /"),
+		)
+
+		It("should reject the host header annotation", func() {
+			_, _, err := lbSpecFromService(&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/health-check-host-header": "example.com",
+					},
+				},
+				Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{http}},
+			}, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).To(MatchError(ContainSubstring("not supported")))
+		})
 	})
+
 	Context("Session Persistence", func() {
 		It("should enable session persistence when annotation is true", func() {
 			spec, _, err := lbSpecFromService(&corev1.Service{
@@ -1217,16 +2098,36 @@ func haveConsistentTargetPool() types.GomegaMatcher {
 type compareLBwithSpecTest struct {
 	wantFulfilled         bool
 	wantImmutabledChanged *resultImmutableChanged
-	lb                    *loadbalancer.LoadBalancer
-	spec                  *loadbalancer.CreateLoadBalancerPayload
+	// wantFields are the fields of the returned diffs, only checked if set.
+	wantFields []string
+	lb         *loadbalancer.LoadBalancer
+	spec       *loadbalancer.CreateLoadBalancerPayload
 }
 
 var _ = DescribeTable("compareLBwithSpec",
 	func(t *compareLBwithSpecTest) {
-		fulfills, immutableChanged := compareLBwithSpec(t.lb, t.spec)
+		diffs, immutableChanged := compareLBwithSpec(t.lb, t.spec)
 		Expect(immutableChanged).To(Equal(t.wantImmutabledChanged))
-		Expect(fulfills).To(Equal(t.wantFulfilled))
+		Expect(len(diffs) == 0 && immutableChanged == nil).To(Equal(t.wantFulfilled))
+		if t.wantFields != nil {
+			Expect(diffFields(diffs)).To(Equal(t.wantFields))
+		}
 	},
+	Entry("When several fields differ", &compareLBwithSpecTest{
+		wantFulfilled: false,
+		wantFields:    []string{".labels[cluster-id]", ".listeners[0].port", ".planId"},
+		lb: &loadbalancer.LoadBalancer{
+			Options:   &loadbalancer.LoadBalancerOptions{PrivateNetworkOnly: new(true)},
+			Listeners: []loadbalancer.Listener{{Port: new(int32(80))}},
+			PlanId:    new("p10"),
+		},
+		spec: &loadbalancer.CreateLoadBalancerPayload{
+			Options:   &loadbalancer.LoadBalancerOptions{PrivateNetworkOnly: new(true)},
+			Labels:    &map[string]string{"cluster-id": "my-cluster"},
+			Listeners: []loadbalancer.Listener{{Port: new(int32(8080))}},
+			PlanId:    new("p50"),
+		},
+	}),
 	Entry("When LB has Observability set", &compareLBwithSpecTest{
 		// The load balancer API uses the same field to report an ephemeral IP and to reference a static IP.
 		wantFulfilled: true,
@@ -1466,6 +2367,83 @@ var _ = DescribeTable("compareLBwithSpec",
 			},
 		},
 	}),
+	Entry("When TLS certificates don't match", &compareLBwithSpecTest{
+		wantFulfilled: false,
+		wantFields:    []string{".listeners[0].tls.certificateIds"},
+		lb: &loadbalancer.LoadBalancer{
+			Options: &loadbalancer.LoadBalancerOptions{
+				PrivateNetworkOnly: new(true),
+			},
+			Listeners: []loadbalancer.Listener{
+				{
+					Protocol: new(listenerProtocolTLSTermination),
+					// Listeners returned by the API contain JSON decoded additional properties.
+					AdditionalProperties: map[string]any{"tls": map[string]any{"certificateIds": []any{"cert-a"}}},
+				},
+			},
+		},
+		spec: &loadbalancer.CreateLoadBalancerPayload{
+			Options: &loadbalancer.LoadBalancerOptions{
+				PrivateNetworkOnly: new(true),
+			},
+			Listeners: []loadbalancer.Listener{
+				{
+					Protocol:             new(listenerProtocolTLSTermination),
+					AdditionalProperties: map[string]any{"tls": map[string]any{"certificateIds": []string{"cert-a", "cert-b"}}},
+				},
+			},
+		},
+	}),
+	Entry("When TLS certificates match", &compareLBwithSpecTest{
+		wantFulfilled: true,
+		lb: &loadbalancer.LoadBalancer{
+			Options: &loadbalancer.LoadBalancerOptions{
+				PrivateNetworkOnly: new(true),
+			},
+			Listeners: []loadbalancer.Listener{
+				{
+					Protocol:             new(listenerProtocolTLSTermination),
+					AdditionalProperties: map[string]any{"tls": map[string]any{"certificateIds": []any{"cert-a"}}},
+				},
+			},
+		},
+		spec: &loadbalancer.CreateLoadBalancerPayload{
+			Options: &loadbalancer.LoadBalancerOptions{
+				PrivateNetworkOnly: new(true),
+			},
+			Listeners: []loadbalancer.Listener{
+				{
+					Protocol:             new(listenerProtocolTLSTermination),
+					AdditionalProperties: map[string]any{"tls": map[string]any{"certificateIds": []string{"cert-a"}}},
+				},
+			},
+		},
+	}),
+	Entry("When TLS termination is returned as unknown protocol by the API client", &compareLBwithSpecTest{
+		wantFulfilled: true,
+		lb: &loadbalancer.LoadBalancer{
+			Options: &loadbalancer.LoadBalancerOptions{
+				PrivateNetworkOnly: new(true),
+			},
+			Listeners: []loadbalancer.Listener{
+				{
+					Protocol:             new(loadbalancer.LISTENERPROTOCOL_UNKNOWN_DEFAULT_OPEN_API),
+					AdditionalProperties: map[string]any{"tls": map[string]any{"certificateIds": []any{"cert-a"}}},
+				},
+			},
+		},
+		spec: &loadbalancer.CreateLoadBalancerPayload{
+			Options: &loadbalancer.LoadBalancerOptions{
+				PrivateNetworkOnly: new(true),
+			},
+			Listeners: []loadbalancer.Listener{
+				{
+					Protocol:             new(listenerProtocolTLSTermination),
+					AdditionalProperties: map[string]any{"tls": map[string]any{"certificateIds": []string{"cert-a"}}},
+				},
+			},
+		},
+	}),
 	Entry("When UDP idle timeout doesn't match", &compareLBwithSpecTest{
 		wantFulfilled: false,
 		lb: &loadbalancer.LoadBalancer{
@@ -1832,6 +2810,33 @@ var _ = DescribeTable("compareLBwithSpec",
 			},
 		},
 	}),
+	Entry("When expected health check status codes don't match", &compareLBwithSpecTest{
+		wantFulfilled: false,
+		lb: &loadbalancer.LoadBalancer{
+			Options: &loadbalancer.LoadBalancerOptions{
+				PrivateNetworkOnly: new(true),
+			},
+			TargetPools: []loadbalancer.TargetPool{
+				{
+					ActiveHealthCheck: &loadbalancer.ActiveHealthCheck{
+						HttpHealthChecks: &loadbalancer.HttpHealthChecks{OkStatuses: []string{"200"}},
+					},
+				},
+			},
+		},
+		spec: &loadbalancer.CreateLoadBalancerPayload{
+			Options: &loadbalancer.LoadBalancerOptions{
+				PrivateNetworkOnly: new(true),
+			},
+			TargetPools: []loadbalancer.TargetPool{
+				{
+					ActiveHealthCheck: &loadbalancer.ActiveHealthCheck{
+						HttpHealthChecks: &loadbalancer.HttpHealthChecks{OkStatuses: []string{"200", "204"}},
+					},
+				},
+			},
+		},
+	}),
 	Entry("When unhealthy threshold is unset but specified", &compareLBwithSpecTest{
 		wantFulfilled: false,
 		lb: &loadbalancer.LoadBalancer{
@@ -1990,6 +2995,32 @@ var _ = DescribeTable("compareLBwithSpec",
 	}),
 )
 
+var _ = Describe("listenerProtocol", func() {
+	It("should recognize TLS termination listeners after a JSON round trip through the API client", func() {
+		spec := &loadbalancer.CreateLoadBalancerPayload{
+			Options: &loadbalancer.LoadBalancerOptions{PrivateNetworkOnly: new(true)},
+			Listeners: []loadbalancer.Listener{
+				{
+					DisplayName:          new("https"),
+					Port:                 new(int32(443)),
+					Protocol:             new(listenerProtocolTLSTermination),
+					Tcp:                  &loadbalancer.OptionsTCP{IdleTimeout: new("3600s")},
+					AdditionalProperties: map[string]any{"tls": map[string]any{"certificateIds": []string{"cert-a"}}},
+				},
+			},
+		}
+		data, err := json.Marshal(spec)
+		Expect(err).NotTo(HaveOccurred())
+		lb := &loadbalancer.LoadBalancer{}
+		Expect(json.Unmarshal(data, lb)).To(Succeed())
+
+		Expect(listenerProtocol(lb.Listeners[0])).To(Equal(listenerProtocolTLSTermination))
+		diffs, immutableChanged := compareLBwithSpec(lb, spec)
+		Expect(immutableChanged).To(BeNil())
+		Expect(diffs).To(BeEmpty())
+	})
+})
+
 var _ = DescribeTable("sanitizeNodeName",
 	func(name, safe string) {
 		Expect(sanitizeNodeName(name)).To(Equal(safe))
@@ -2023,3 +3054,32 @@ var _ = DescribeTable("sanitizeNodeName",
 		"a-very-long-node-0123456789012345678901234-example-com-e241059",
 	),
 )
+
+var _ = Describe("specDiff", func() {
+	It("should contain the JSON encoded values", func() {
+		diffs, immutableChanged := compareLBwithSpec(&loadbalancer.LoadBalancer{
+			Options:   &loadbalancer.LoadBalancerOptions{PrivateNetworkOnly: new(true)},
+			Listeners: []loadbalancer.Listener{{Port: new(int32(80))}},
+			PlanId:    new("p10"),
+		}, &loadbalancer.CreateLoadBalancerPayload{
+			Options:   &loadbalancer.LoadBalancerOptions{PrivateNetworkOnly: new(true)},
+			Labels:    &map[string]string{"cluster-id": "my-cluster"},
+			Listeners: []loadbalancer.Listener{{Port: new(int32(8080))}},
+			PlanId:    new("p10"),
+		})
+		Expect(immutableChanged).To(BeNil())
+		Expect(diffs).To(Equal([]specDiff{
+			{field: ".labels[cluster-id]", current: "null", desired: `"my-cluster"`},
+			{field: ".listeners[0].port", current: "80", desired: "8080"},
+		}))
+	})
+
+	DescribeTable("diffFieldLabel",
+		func(field, want string) {
+			Expect(diffFieldLabel(field)).To(Equal(want))
+		},
+		Entry("without indices", ".planId", ".planId"),
+		Entry("with an index", ".listeners[0].port", ".listeners.port"),
+		Entry("with a map key", ".labels[cluster-id]", ".labels"),
+	)
+})
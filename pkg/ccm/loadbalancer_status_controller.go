@@ -0,0 +1,128 @@
+package ccm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	cloudprovider "k8s.io/cloud-provider"
+	"k8s.io/klog/v2"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/cmp"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/metrics"
+)
+
+const (
+	// LoadBalancerStatusControllerName is the name of the controller that exports the status of load balancers as metrics.
+	LoadBalancerStatusControllerName = "load-balancer-status"
+
+	// DefaultLoadBalancerStatusInterval is the default interval in which the load balancers are listed.
+	DefaultLoadBalancerStatusInterval = time.Minute
+)
+
+// LoadBalancerStatusController periodically lists the load balancers of the project and exports the status, targets
+// and plan of the load balancers of the services of this cluster in metrics.LoadBalancerStatus.
+type LoadBalancerStatusController struct {
+	loadBalancer   *LoadBalancer
+	serviceLister  corelisters.ServiceLister
+	servicesSynced cache.InformerSynced
+	interval       time.Duration
+	collector      *metrics.LoadBalancerStatusCollector
+}
+
+// NewLoadBalancerStatusController creates the controller from the STACKIT cloud provider.
+func NewLoadBalancerStatusController(
+	serviceInformer coreinformers.ServiceInformer,
+	cloud cloudprovider.Interface,
+	interval time.Duration,
+) (*LoadBalancerStatusController, error) {
+	stackitCloud, ok := cloud.(*CloudControllerManager)
+	if !ok {
+		return nil, fmt.Errorf("cloud provider %T is not supported by the %s controller", cloud, LoadBalancerStatusControllerName)
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("the interval of the %s controller must be positive", LoadBalancerStatusControllerName)
+	}
+	return &LoadBalancerStatusController{
+		loadBalancer:   stackitCloud.loadBalancer,
+		serviceLister:  serviceInformer.Lister(),
+		servicesSynced: serviceInformer.Informer().HasSynced,
+		interval:       interval,
+		collector:      metrics.LoadBalancerStatus,
+	}, nil
+}
+
+// Run exports the status of the load balancers every interval and blocks until ctx is cancelled.
+func (c *LoadBalancerStatusController) Run(ctx context.Context) {
+	defer utilruntime.HandleCrash()
+
+	klog.InfoS("Starting controller", "controller", LoadBalancerStatusControllerName)
+	defer klog.InfoS("Shutting down controller", "controller", LoadBalancerStatusControllerName)
+
+	if !cache.WaitForCacheSync(ctx.Done(), c.servicesSynced) {
+		return
+	}
+
+	c.collector.Run(ctx, c.interval, c.loadBalancerStates)
+}
+
+// loadBalancerStates returns the states of all load balancers that belong to a service of this cluster.
+// Load balancers are matched by the UID of the service in their name, see LoadBalancer.GetLoadBalancerName.
+func (c *LoadBalancerStatusController) loadBalancerStates(ctx context.Context) ([]metrics.LoadBalancerState, error) {
+	services, err := c.serviceLister.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+	servicesByUID := map[types.UID]*corev1.Service{}
+	for _, service := range services {
+		if service.Spec.Type == corev1.ServiceTypeLoadBalancer {
+			servicesByUID[service.UID] = service
+		}
+	}
+
+	lbs, err := c.loadBalancer.client.ListLoadBalancers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list load balancers: %w", err)
+	}
+	states := []metrics.LoadBalancerState{}
+	for i := range lbs {
+		lb := &lbs[i]
+		service := servicesByUID[serviceUIDFromLoadBalancerName(cmp.UnpackPtr(lb.Name))]
+		if service == nil {
+			continue
+		}
+		targets := map[string]int{}
+		for _, pool := range lb.TargetPools {
+			targets[cmp.UnpackPtr(pool.Name)] = len(pool.Targets)
+		}
+		states = append(states, metrics.LoadBalancerState{
+			Namespace: service.Namespace,
+			Service:   service.Name,
+			Name:      cmp.UnpackPtr(lb.Name),
+			Status:    string(cmp.UnpackPtr(lb.Status)),
+			PlanID:    cmp.UnpackPtr(lb.PlanId),
+			Targets:   targets,
+		})
+	}
+	return states, nil
+}
+
+// serviceUIDFromLoadBalancerName returns the UID of the service of a load balancer created by the CCM,
+// or an empty UID if the name doesn't belong to a service.
+func serviceUIDFromLoadBalancerName(name string) types.UID {
+	// Service UIDs are UUIDs, which are followed by a dash and the name of the service.
+	const uidLength = 36
+	rest, found := strings.CutPrefix(name, loadBalancerNamePrefix)
+	if !found || len(rest) <= uidLength || rest[uidLength] != '-' {
+		return ""
+	}
+	return types.UID(rest[:uidLength])
+}
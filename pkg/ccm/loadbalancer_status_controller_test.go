@@ -0,0 +1,92 @@
+package ccm
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/metrics"
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+)
+
+var _ = Describe("LoadBalancerStatusController", func() {
+	const uid = "89ec9a0e-6b00-4e2f-b57b-02e89193093d"
+
+	var (
+		mockClient *stackitclientmock.MockLoadBalancingClient
+		lb         *LoadBalancer
+		controller *LoadBalancerStatusController
+		svc        *corev1.Service
+	)
+
+	BeforeEach(func() {
+		mockClient = stackitclientmock.NewMockLoadBalancingClient(gomock.NewController(GinkgoT()))
+		var err error
+		lb, err = NewLoadBalancer(mockClient, nil, config.LoadBalancerOpts{}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		informerFactory := informers.NewSharedInformerFactory(fake.NewClientset(), 0)
+		controller, err = NewLoadBalancerStatusController(
+			informerFactory.Core().V1().Services(), &CloudControllerManager{loadBalancer: lb}, DefaultLoadBalancerStatusInterval,
+		)
+		Expect(err).NotTo(HaveOccurred())
+
+		svc = &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "echo", Namespace: "default", UID: uid},
+			Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+		}
+		Expect(informerFactory.Core().V1().Services().Informer().GetIndexer().Add(svc)).To(Succeed())
+	})
+
+	It("should only report the load balancers of services of the cluster", func() {
+		mockClient.EXPECT().ListLoadBalancers(gomock.Any()).Return([]loadbalancer.LoadBalancer{
+			{
+				Name:   new(lb.GetLoadBalancerName(context.Background(), "", svc)),
+				Status: new(loadbalancer.LOADBALANCERSTATUS_STATUS_READY),
+				PlanId: new("p10"),
+				TargetPools: []loadbalancer.TargetPool{
+					{Name: new("http"), Targets: []loadbalancer.Target{{Ip: new("10.0.0.1")}, {Ip: new("10.0.0.2")}}},
+				},
+			},
+			{Name: new("k8s-svc-00000000-0000-0000-0000-000000000000-other-cluster")},
+			{Name: new("manually-created")},
+		}, nil)
+
+		states, err := controller.loadBalancerStates(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(states).To(Equal([]metrics.LoadBalancerState{{
+			Namespace: "default",
+			Service:   "echo",
+			Name:      "k8s-svc-" + uid + "-echo",
+			Status:    "STATUS_READY",
+			PlanID:    "p10",
+			Targets:   map[string]int{"http": 2},
+		}}))
+	})
+
+	It("should fail if the load balancers can't be listed", func() {
+		mockClient.EXPECT().ListLoadBalancers(gomock.Any()).Return(nil, errors.New("timeout"))
+
+		_, err := controller.loadBalancerStates(context.Background())
+		Expect(err).To(MatchError(ContainSubstring("timeout")))
+	})
+
+	DescribeTable("serviceUIDFromLoadBalancerName",
+		func(name string, expected types.UID) {
+			Expect(serviceUIDFromLoadBalancerName(name)).To(Equal(expected))
+		},
+		Entry("should return the UID", "k8s-svc-"+uid+"-echo", types.UID(uid)),
+		Entry("should ignore other load balancers", "my-lb", types.UID("")),
+		Entry("should ignore names without service", "k8s-svc-"+uid, types.UID("")),
+	)
+})
@@ -3,18 +3,26 @@ package ccm
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/metrics"
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
 	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
 	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
 	oapiError "github.com/stackitcloud/stackit-sdk-go/core/oapierror"
 	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
 	"go.uber.org/mock/gomock"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/cloud-provider/api"
 )
 
@@ -28,6 +36,7 @@ const (
 var _ = Describe("LoadBalancer", func() {
 	var (
 		mockClient           *stackitclientmock.MockLoadBalancingClient
+		mockIaaSClient       *stackitclientmock.MockIaaSClient
 		lbInModeIgnoreAndObs *LoadBalancer
 		loadBalancer         *LoadBalancer
 		clusterName          string
@@ -40,17 +49,28 @@ var _ = Describe("LoadBalancer", func() {
 
 		ctrl := gomock.NewController(GinkgoT())
 		mockClient = stackitclientmock.NewMockLoadBalancingClient(ctrl)
+		mockIaaSClient = stackitclientmock.NewMockIaaSClient(ctrl)
 		var err error
-		lbInModeIgnoreAndObs, err = NewLoadBalancer(mockClient, lbOpts, &MetricsRemoteWrite{
+		lbInModeIgnoreAndObs, err = NewLoadBalancer(mockClient, mockIaaSClient, lbOpts, &MetricsRemoteWrite{
 			endpoint: "test-endpoint",
 			username: "test-username",
 			password: "test-password",
 		})
 		Expect(err).NotTo(HaveOccurred())
-		loadBalancer, err = NewLoadBalancer(mockClient, lbOpts, nil)
+		loadBalancer, err = NewLoadBalancer(mockClient, mockIaaSClient, lbOpts, nil)
 		Expect(err).NotTo(HaveOccurred())
+		// Updates are always reported in an event.
+		lbInModeIgnoreAndObs.recorder = record.NewFakeRecorder(100)
+		loadBalancer.recorder = record.NewFakeRecorder(100)
 	})
 
+	expectQuota := func(used, maxLoadBalancers int32) {
+		mockClient.EXPECT().GetQuota(gomock.Any()).Return(&loadbalancer.GetQuotaResponse{
+			MaxLoadBalancers:  new(maxLoadBalancers),
+			UsedLoadBalancers: new(used),
+		}, nil)
+	}
+
 	Describe("GetLoadBalancerName", func() {
 		It("should generate the name based on the UID and name", func() {
 			name := loadBalancer.GetLoadBalancerName(context.Background(), clusterName, &corev1.Service{
@@ -189,6 +209,7 @@ var _ = Describe("LoadBalancer", func() {
 	Describe("EnsureLoadBalancer", func() {
 		It("ensure load balancer should trigger load balancer creation if LB doesn't exist", func() {
 			mockClient.EXPECT().GetLoadBalancer(gomock.Any(), gomock.Any()).Return(nil, &oapiError.GenericOpenAPIError{StatusCode: http.StatusNotFound})
+			expectQuota(0, 10)
 			mockClient.EXPECT().CreateLoadBalancer(gomock.Any(), gomock.Any()).MinTimes(1).Return(&loadbalancer.LoadBalancer{}, nil)
 
 			_, err := loadBalancer.EnsureLoadBalancer(context.Background(), clusterName, minimalLoadBalancerService(), []*corev1.Node{})
@@ -198,6 +219,7 @@ var _ = Describe("LoadBalancer", func() {
 
 		It("should create a load balancer with observability configured", func() {
 			mockClient.EXPECT().GetLoadBalancer(gomock.Any(), gomock.Any()).Return(nil, &oapiError.GenericOpenAPIError{StatusCode: http.StatusNotFound})
+			expectQuota(0, 10)
 			mockClient.EXPECT().ListCredentials(gomock.Any()).Return(&loadbalancer.ListCredentialsResponse{
 				Credentials: []loadbalancer.CredentialsResponse{},
 			}, nil)
@@ -221,6 +243,90 @@ var _ = Describe("LoadBalancer", func() {
 			// Expected CreateLoadBalancer to have been called.
 		})
 
+		DescribeTable("should keep the address of an existing load balancer that differs from spec.loadBalancerIP",
+			func(ephemeral bool) {
+				svc := minimalLoadBalancerService()
+				delete(svc.Annotations, externalIPAnnotation)
+				spec, _, err := lbSpecFromService(svc, []*corev1.Node{}, lbOpts, nil)
+				Expect(err).NotTo(HaveOccurred())
+				spec.Options.EphemeralAddress = new(ephemeral)
+				myLb := &loadbalancer.LoadBalancer{
+					ExternalAddress: new("1.2.3.4"),
+					Listeners:       spec.Listeners,
+					Name:            spec.Name,
+					Networks:        spec.Networks,
+					Options:         spec.Options,
+					Status:          new(loadbalancer.LOADBALANCERSTATUS_STATUS_READY),
+					TargetPools:     spec.TargetPools,
+					Version:         new("current-version"),
+					PlanId:          spec.PlanId,
+				}
+				svc.Spec.LoadBalancerIP = "5.6.7.8"
+				mockClient.EXPECT().GetLoadBalancer(gomock.Any(), gomock.Any()).Return(myLb, nil)
+
+				// The mock fails on any call that updates the load balancer.
+				status, err := loadBalancer.EnsureLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(status.Ingress).To(ConsistOf(HaveField("IP", "1.2.3.4")))
+			},
+			Entry("with an ephemeral address", true),
+			Entry("with a static address", false),
+		)
+
+		It("should wait for a terminating load balancer to be deleted before recreating it", func() {
+			mockClient.EXPECT().GetLoadBalancer(gomock.Any(), gomock.Any()).Return(&loadbalancer.LoadBalancer{
+				Status: new(loadbalancer.LOADBALANCERSTATUS_STATUS_TERMINATING),
+			}, nil)
+
+			// The mock fails on any call that updates the load balancer.
+			_, err := loadBalancer.EnsureLoadBalancer(context.Background(), clusterName, minimalLoadBalancerService(), []*corev1.Node{})
+			var retryErr *api.RetryError
+			Expect(errors.As(err, &retryErr)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring("deletion of the previous load balancer")))
+
+			mockClient.EXPECT().GetLoadBalancer(gomock.Any(), gomock.Any()).Return(nil, &oapiError.GenericOpenAPIError{StatusCode: http.StatusNotFound})
+			expectQuota(0, 10)
+			mockClient.EXPECT().CreateLoadBalancer(gomock.Any(), gomock.Any()).Return(&loadbalancer.LoadBalancer{}, nil)
+
+			_, err = loadBalancer.EnsureLoadBalancer(context.Background(), clusterName, minimalLoadBalancerService(), []*corev1.Node{})
+			Expect(err).To(MatchError(notYetReadyError))
+		})
+
+		It("should fail if the observability credentials cannot be listed", func() {
+			errTest := errors.New("list credentials test error")
+			mockClient.EXPECT().GetLoadBalancer(gomock.Any(), gomock.Any()).Return(&loadbalancer.LoadBalancer{}, nil)
+			mockClient.EXPECT().ListCredentials(gomock.Any()).Return(nil, errTest)
+
+			_, err := lbInModeIgnoreAndObs.EnsureLoadBalancer(context.Background(), clusterName, minimalLoadBalancerService(), []*corev1.Node{})
+			Expect(err).To(MatchError(errTest))
+		})
+
+		It("should report the changed fields of an update in an event", func() {
+			svc := minimalLoadBalancerService()
+			spec, _, err := lbSpecFromService(svc, []*corev1.Node{}, lbOpts, nil)
+			Expect(err).NotTo(HaveOccurred())
+			myLb := &loadbalancer.LoadBalancer{
+				ExternalAddress: spec.ExternalAddress,
+				Listeners:       spec.Listeners,
+				Name:            spec.Name,
+				Networks:        spec.Networks,
+				Options:         spec.Options,
+				Status:          new(loadbalancer.LOADBALANCERSTATUS_STATUS_READY),
+				TargetPools:     spec.TargetPools,
+				Version:         new("current-version"),
+				PlanId:          new("p50"),
+			}
+			recorder := record.NewFakeRecorder(10)
+			loadBalancer.recorder = recorder
+
+			mockClient.EXPECT().GetLoadBalancer(gomock.Any(), gomock.Any()).Return(myLb, nil)
+			mockClient.EXPECT().UpdateLoadBalancer(gomock.Any(), gomock.Any(), gomock.Any()).Return(myLb, nil)
+
+			_, err = loadBalancer.EnsureLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recorder.Events).To(Receive(And(ContainSubstring(EventReasonUpdating), ContainSubstring(".planId"))))
+		})
+
 		DescribeTable("LoadBalancer UPDATE behavior for DisableTargetSecurityGroupAssignment",
 			func(disableTargetSG bool, matcher gomock.Matcher) {
 				svc := minimalLoadBalancerService()
@@ -296,6 +402,7 @@ var _ = Describe("LoadBalancer", func() {
 			}
 
 			mockClient.EXPECT().GetLoadBalancer(gomock.Any(), gomock.Any()).Return(myLb, nil)
+			// The credentials are only listed when new ones are created.
 			mockClient.EXPECT().UpdateCredentials(gomock.Any(), sampleCredentialsRef, gomock.Any()).MinTimes(1).Return(nil)
 
 			_, err = lbInModeIgnoreAndObs.EnsureLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})
@@ -396,6 +503,60 @@ var _ = Describe("LoadBalancer", func() {
 			// Expect UpdateLoadBalancer to have been called.
 		})
 
+		Context("with resource labels", func() {
+			expectedLabels := map[string]string{
+				"environment":                 "production",
+				stackitclient.ClusterIDLabel:  "my-cluster",
+				stackitclient.ManagedByLabel:  "stackit-cloud-controller-manager",
+				stackitclient.ServiceUIDLabel: "00000000-0000-0000-0000-000000000000",
+			}
+
+			BeforeEach(func() {
+				loadBalancer.opts.ExtraLabels = map[string]string{"environment": "production"}
+				loadBalancer.resourceLabels = stackitclient.ResourceLabels(stackitconfig.GlobalOpts{ClusterID: "my-cluster", ResourceLabels: true},
+					"stackit-cloud-controller-manager")
+			})
+
+			It("should label new load balancers", func() {
+				mockClient.EXPECT().GetLoadBalancer(gomock.Any(), gomock.Any()).Return(nil, &oapiError.GenericOpenAPIError{StatusCode: http.StatusNotFound})
+				expectQuota(0, 10)
+				mockClient.EXPECT().CreateLoadBalancer(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, payload *loadbalancer.CreateLoadBalancerPayload) (*loadbalancer.LoadBalancer, error) {
+						Expect(payload.Labels).To(HaveValue(Equal(expectedLabels)))
+						return &loadbalancer.LoadBalancer{}, nil
+					})
+
+				_, err := loadBalancer.EnsureLoadBalancer(context.Background(), clusterName, minimalLoadBalancerService(), []*corev1.Node{})
+				Expect(err).To(MatchError(notYetReadyError))
+			})
+
+			It("should add missing labels to existing load balancers", func() {
+				svc := minimalLoadBalancerService()
+				spec, _, err := lbSpecFromService(svc, []*corev1.Node{}, lbOpts, nil)
+				Expect(err).NotTo(HaveOccurred())
+				myLb := &loadbalancer.LoadBalancer{
+					ExternalAddress: spec.ExternalAddress,
+					Listeners:       spec.Listeners,
+					Networks:        spec.Networks,
+					Options:         spec.Options,
+					Status:          new(loadbalancer.LOADBALANCERSTATUS_STATUS_READY),
+					TargetPools:     spec.TargetPools,
+					Labels:          new(map[string]string{"environment": "production"}),
+					Version:         new("current-version"),
+				}
+
+				mockClient.EXPECT().GetLoadBalancer(gomock.Any(), gomock.Any()).Return(myLb, nil)
+				mockClient.EXPECT().UpdateLoadBalancer(gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ string, payload *loadbalancer.UpdateLoadBalancerPayload) (*loadbalancer.LoadBalancer, error) {
+						Expect(payload.Labels).To(HaveValue(Equal(expectedLabels)))
+						return myLb, nil
+					})
+
+				_, err = loadBalancer.EnsureLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
 		It("should delete observability credentials and delete reference from load balancer if controller is not configured (monitoring extension disabled)", func() {
 			svc := minimalLoadBalancerService()
 			spec, _, err := lbSpecFromService(svc, []*corev1.Node{}, lbOpts, &loadbalancer.LoadbalancerOptionObservability{
@@ -439,6 +600,270 @@ var _ = Describe("LoadBalancer", func() {
 			// Expect UpdateLoadBalancer to have been called.
 			// Expect DeleteCredentials to have been called.
 		})
+
+		Context("automatic service plan", func() {
+			var (
+				svc      *corev1.Service
+				myLb     *loadbalancer.LoadBalancer
+				recorder *record.FakeRecorder
+			)
+
+			BeforeEach(func() {
+				svc = minimalLoadBalancerService()
+				svc.Annotations["lb.stackit.cloud/service-plan-auto"] = "true"
+				spec, _, err := lbSpecFromService(svc, []*corev1.Node{}, lbOpts, nil)
+				Expect(err).NotTo(HaveOccurred())
+				myLb = &loadbalancer.LoadBalancer{
+					ExternalAddress: spec.ExternalAddress,
+					Listeners:       spec.Listeners,
+					Name:            new(loadBalancer.GetLoadBalancerName(context.Background(), clusterName, svc)),
+					Networks:        spec.Networks,
+					Options:         spec.Options,
+					Status:          new(loadbalancer.LOADBALANCERSTATUS_STATUS_READY),
+					TargetPools:     spec.TargetPools,
+					Version:         new("current-version"),
+					PlanId:          new(p10),
+				}
+				recorder = record.NewFakeRecorder(10)
+				loadBalancer.recorder = recorder
+				loadBalancer.planRecommender = &planRecommender{
+					querier:     &fakeConnectionsQuerier{connections: 45_000},
+					interval:    time.Hour,
+					now:         time.Now,
+					lastChecked: map[string]time.Time{},
+					capacities:  testPlanCapacities,
+				}
+			})
+
+			It("should change the plan to the recommended plan", func() {
+				mockClient.EXPECT().GetLoadBalancer(gomock.Any(), gomock.Any()).Return(myLb, nil)
+				mockClient.EXPECT().UpdateLoadBalancer(gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ string, payload *loadbalancer.UpdateLoadBalancerPayload) (*loadbalancer.LoadBalancer, error) {
+						Expect(payload.PlanId).To(PointTo(Equal(p250)))
+						return myLb, nil
+					})
+
+				_, err := loadBalancer.EnsureLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(recorder.Events).To(Receive(And(
+					ContainSubstring(EventReasonServicePlanChanged),
+					ContainSubstring("from p10 to p250"),
+				)))
+			})
+
+			It("should not exceed the maximum plan", func() {
+				svc.Annotations["lb.stackit.cloud/service-plan-max"] = p50
+				mockClient.EXPECT().GetLoadBalancer(gomock.Any(), gomock.Any()).Return(myLb, nil)
+				mockClient.EXPECT().UpdateLoadBalancer(gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ string, payload *loadbalancer.UpdateLoadBalancerPayload) (*loadbalancer.LoadBalancer, error) {
+						Expect(payload.PlanId).To(PointTo(Equal(p50)))
+						return myLb, nil
+					})
+
+				_, err := loadBalancer.EnsureLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("should keep the plan if it fits", func() {
+				myLb.PlanId = new(p250)
+				mockClient.EXPECT().GetLoadBalancer(gomock.Any(), gomock.Any()).Return(myLb, nil)
+
+				_, err := loadBalancer.EnsureLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(recorder.Events).NotTo(Receive())
+			})
+
+			It("should raise the plan to fit the listeners even without plan recommendations", func() {
+				loadBalancer.planRecommender = nil
+				loadBalancer.opts.MaxListenersPerPlan = map[string]int{p10: 0}
+				svc.Spec.Ports = []corev1.ServicePort{{Name: "http", Protocol: corev1.ProtocolTCP, Port: 80, NodePort: 30080}}
+				mockClient.EXPECT().GetLoadBalancer(gomock.Any(), gomock.Any()).Return(myLb, nil)
+				mockClient.EXPECT().UpdateLoadBalancer(gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ string, payload *loadbalancer.UpdateLoadBalancerPayload) (*loadbalancer.LoadBalancer, error) {
+						Expect(payload.PlanId).To(PointTo(Equal(p50)))
+						return myLb, nil
+					})
+
+				_, err := loadBalancer.EnsureLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(recorder.Events).To(Receive(ContainSubstring("requires plan recommendations")))
+				Expect(recorder.Events).To(Receive(And(
+					ContainSubstring(EventReasonServicePlanChanged),
+					ContainSubstring("from p10 to p50 to fit its listeners and targets"),
+				)))
+			})
+
+			It("should only warn once if plan recommendations are disabled", func() {
+				loadBalancer.planRecommender = nil
+				mockClient.EXPECT().GetLoadBalancer(gomock.Any(), gomock.Any()).Return(myLb, nil).Times(2)
+
+				for range 2 {
+					_, err := loadBalancer.EnsureLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})
+					Expect(err).NotTo(HaveOccurred())
+				}
+				Expect(recorder.Events).To(Receive(And(
+					ContainSubstring(EventReasonServicePlanChanged),
+					ContainSubstring("requires plan recommendations"),
+				)))
+				Expect(recorder.Events).NotTo(Receive())
+			})
+		})
+
+		Context("load balancer in error state", func() {
+			var (
+				svc      *corev1.Service
+				myLb     *loadbalancer.LoadBalancer
+				recorder *record.FakeRecorder
+			)
+
+			BeforeEach(func() {
+				svc = minimalLoadBalancerService()
+				spec, _, err := lbSpecFromService(svc, []*corev1.Node{}, lbOpts, nil)
+				Expect(err).NotTo(HaveOccurred())
+				myLb = &loadbalancer.LoadBalancer{
+					Errors: []loadbalancer.LoadBalancerError{{
+						Type:        new(loadbalancer.LOADBALANCERERRORTYPE_TYPE_PORT_NOT_CONFIGURED),
+						Description: new("port 80 is not configured"),
+					}},
+					ExternalAddress: spec.ExternalAddress,
+					Listeners:       spec.Listeners,
+					Name:            spec.Name,
+					Networks:        spec.Networks,
+					Options:         spec.Options,
+					Status:          new(loadbalancer.LOADBALANCERSTATUS_STATUS_ERROR),
+					TargetPools:     spec.TargetPools,
+					Version:         new("current-version"),
+					PlanId:          new(p10),
+				}
+				recorder = record.NewFakeRecorder(10)
+				loadBalancer.recorder = recorder
+			})
+
+			It("should delete a load balancer that never became ready due to listener errors", func() {
+				name := loadBalancer.GetLoadBalancerName(context.Background(), clusterName, svc)
+				mockClient.EXPECT().GetLoadBalancer(gomock.Any(), name).Return(myLb, nil)
+				mockClient.EXPECT().DeleteLoadBalancer(gomock.Any(), name).Return(nil)
+
+				_, err := loadBalancer.EnsureLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})
+				Expect(err).To(MatchError(ContainSubstring("port 80 is not configured")))
+				Expect(recorder.Events).To(Receive(ContainSubstring(EventReasonRolledBack)))
+			})
+
+			It("should only recreate a rolled back load balancer once its listeners changed", func() {
+				name := loadBalancer.GetLoadBalancerName(context.Background(), clusterName, svc)
+				mockClient.EXPECT().GetLoadBalancer(gomock.Any(), name).Return(myLb, nil)
+				mockClient.EXPECT().DeleteLoadBalancer(gomock.Any(), name).Return(nil)
+				_, err := loadBalancer.EnsureLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})
+				Expect(err).To(HaveOccurred())
+
+				// The mock fails if the load balancer is created from the unchanged service.
+				mockClient.EXPECT().GetLoadBalancer(gomock.Any(), name).Return(nil, &oapiError.GenericOpenAPIError{StatusCode: http.StatusNotFound})
+				expectQuota(0, 10)
+				_, err = loadBalancer.EnsureLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})
+				Expect(err).To(MatchError(ContainSubstring("not recreating the load balancer until its listeners are changed")))
+				var retryErr *api.RetryError
+				Expect(errors.As(err, &retryErr)).To(BeFalse())
+
+				svc.Spec.Ports = []corev1.ServicePort{{Name: "http", Port: 8080, NodePort: 30080, Protocol: corev1.ProtocolTCP}}
+				mockClient.EXPECT().GetLoadBalancer(gomock.Any(), name).Return(nil, &oapiError.GenericOpenAPIError{StatusCode: http.StatusNotFound})
+				expectQuota(0, 10)
+				mockClient.EXPECT().CreateLoadBalancer(gomock.Any(), gomock.Any()).Return(&loadbalancer.LoadBalancer{}, nil)
+				_, err = loadBalancer.EnsureLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})
+				Expect(err).To(MatchError(notYetReadyError))
+			})
+
+			It("should not delete a load balancer that has been ready before", func() {
+				svc.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "1.2.3.4"}}
+				mockClient.EXPECT().GetLoadBalancer(gomock.Any(), gomock.Any()).Return(myLb, nil)
+
+				_, err := loadBalancer.EnsureLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})
+				Expect(err).To(MatchError("the load balancer is in an error state: TYPE_PORT_NOT_CONFIGURED: port 80 is not configured"))
+			})
+
+			It("should not delete a load balancer with errors unrelated to listeners", func() {
+				myLb.Errors = append(myLb.Errors, loadbalancer.LoadBalancerError{
+					Type:        new(loadbalancer.LOADBALANCERERRORTYPE_TYPE_FIP_NOT_FOUND),
+					Description: new("floating IP not found"),
+				})
+				mockClient.EXPECT().GetLoadBalancer(gomock.Any(), gomock.Any()).Return(myLb, nil)
+
+				_, err := loadBalancer.EnsureLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})
+				Expect(err).To(MatchError(ContainSubstring("floating IP not found")))
+			})
+		})
+	})
+
+	Context("API errors", func() {
+		var (
+			svc      *corev1.Service
+			recorder *record.FakeRecorder
+		)
+
+		BeforeEach(func() {
+			svc = minimalLoadBalancerService()
+			recorder = record.NewFakeRecorder(10)
+			loadBalancer.recorder = recorder
+			loadBalancer.projectID = "my-project"
+			mockClient.EXPECT().GetLoadBalancer(gomock.Any(), gomock.Any()).
+				Return(nil, &oapiError.GenericOpenAPIError{StatusCode: http.StatusNotFound})
+		})
+
+		It("should record an event with the usage if the quota is exhausted before creation", func() {
+			expectQuota(3, 3)
+
+			_, err := loadBalancer.EnsureLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})
+			Expect(err).To(MatchError(stackiterrors.ErrQuotaExceeded))
+			Expect(recorder.Events).To(Receive(And(
+				ContainSubstring(EventReasonQuotaExceeded),
+				ContainSubstring("Load balancer quota exceeded in project my-project: 3/3 load balancers used"),
+			)))
+			Expect(testutil.ToFloat64(metrics.LoadBalancerQuotaRemaining)).To(BeZero())
+		})
+
+		It("should report the remaining quota", func() {
+			expectQuota(1, 3)
+			mockClient.EXPECT().CreateLoadBalancer(gomock.Any(), gomock.Any()).Return(&loadbalancer.LoadBalancer{}, nil)
+
+			_, err := loadBalancer.EnsureLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})
+			Expect(err).To(MatchError(notYetReadyError))
+			Expect(testutil.ToFloat64(metrics.LoadBalancerQuotaRemaining)).To(Equal(2.0))
+		})
+
+		It("should record an event if the quota of the project is exceeded", func() {
+			expectQuota(0, 10)
+			mockClient.EXPECT().CreateLoadBalancer(gomock.Any(), gomock.Any()).Return(nil, stackiterrors.Classify(
+				&oapiError.GenericOpenAPIError{StatusCode: http.StatusForbidden, Body: []byte(`{"message":"Quota exceeded for load balancers"}`)},
+			))
+
+			_, err := loadBalancer.EnsureLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})
+			Expect(err).To(MatchError(stackiterrors.ErrQuotaExceeded))
+			Expect(recorder.Events).To(Receive(And(
+				ContainSubstring(EventReasonQuotaExceeded),
+				ContainSubstring("Load balancer quota exceeded in project my-project"),
+			)))
+		})
+
+		It("should record an event if the API rejects the load balancer", func() {
+			expectQuota(0, 10)
+			mockClient.EXPECT().CreateLoadBalancer(gomock.Any(), gomock.Any()).Return(nil, stackiterrors.Classify(
+				&oapiError.GenericOpenAPIError{StatusCode: http.StatusBadRequest, Body: []byte(`{"message":"invalid listener"}`)},
+			))
+
+			_, err := loadBalancer.EnsureLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})
+			Expect(err).To(MatchError(stackiterrors.ErrValidation))
+			Expect(recorder.Events).To(Receive(And(ContainSubstring(EventReasonRejected), ContainSubstring("invalid listener"))))
+		})
+
+		It("should not record an event for other errors", func() {
+			expectQuota(0, 10)
+			mockClient.EXPECT().CreateLoadBalancer(gomock.Any(), gomock.Any()).Return(nil, stackiterrors.Classify(
+				&oapiError.GenericOpenAPIError{StatusCode: http.StatusInternalServerError},
+			))
+
+			_, err := loadBalancer.EnsureLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})
+			Expect(err).To(HaveOccurred())
+			Expect(recorder.Events).NotTo(Receive())
+		})
 	})
 
 	Describe("EnsureLoadBalancerDeleted", func() {
@@ -588,6 +1013,97 @@ var _ = Describe("LoadBalancer", func() {
 			Expect(err).NotTo(HaveOccurred())
 			// Expect UpdateTargetPool to have been called.
 		})
+
+		It("should not target nodes that are excluded from load balancers", func() {
+			mockClient.EXPECT().UpdateTargetPool(gomock.Any(), gomock.Any(), "my-port", gomock.Any()).
+				DoAndReturn(func(_ context.Context, _, _ string, payload loadbalancer.UpdateTargetPoolPayload) error {
+					Expect(payload.Targets).To(ConsistOf(loadbalancer.Target{DisplayName: new("node-a"), Ip: new("10.0.0.1")}))
+					return nil
+				})
+
+			svc := minimalLoadBalancerService()
+			svc.Spec.Ports = []corev1.ServicePort{{Name: "my-port", Protocol: corev1.ProtocolTCP, Port: 80, NodePort: 8080}}
+			nodes := []*corev1.Node{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "node-a"},
+					Status:     corev1.NodeStatus{Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.0.1"}}},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "node-b", Labels: map[string]string{corev1.LabelNodeExcludeBalancers: "true"}},
+					Status:     corev1.NodeStatus{Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.0.2"}}},
+				},
+			}
+			Expect(loadBalancer.UpdateLoadBalancer(context.Background(), clusterName, svc, nodes)).To(Succeed())
+		})
+
+		It("should not update static targets", func() {
+			svc := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"lb.stackit.cloud/external-address": "123.124.88.99",
+						"lb.stackit.cloud/static-targets":   "gateway-1=10.0.0.10",
+					},
+				},
+				Spec: corev1.ServiceSpec{
+					Ports: []corev1.ServicePort{{Name: "my-port", Protocol: corev1.ProtocolTCP, Port: 80, NodePort: 8080}},
+				},
+			}
+			// The mock fails on any call to UpdateTargetPool.
+			Expect(loadBalancer.UpdateLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})).To(Succeed())
+		})
+
+		It("should update all target pools even if some updates fail", func() {
+			var ports []corev1.ServicePort
+			for i := range 10 {
+				ports = append(ports, corev1.ServicePort{
+					Name: fmt.Sprintf("port-%d", i), Protocol: corev1.ProtocolTCP, Port: int32(80 + i), NodePort: int32(30080 + i),
+				})
+			}
+			svc := minimalLoadBalancerService()
+			svc.Spec.Ports = ports
+			var updated atomic.Int32
+			mockClient.EXPECT().UpdateTargetPool(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(len(ports)).
+				DoAndReturn(func(_ context.Context, _, pool string, _ loadbalancer.UpdateTargetPoolPayload) error {
+					updated.Add(1)
+					if pool == "port-3" || pool == "port-7" {
+						return errors.New("timeout")
+					}
+					return nil
+				})
+
+			err := loadBalancer.UpdateLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})
+			Expect(err).To(MatchError(And(ContainSubstring(`"port-3"`), ContainSubstring(`"port-7"`))))
+			Expect(updated.Load()).To(BeEquivalentTo(len(ports)))
+		})
+
+		It("should retry target pool updates that conflict with parallel updates", func() {
+			svc := minimalLoadBalancerService()
+			svc.Spec.Ports = []corev1.ServicePort{{Name: "my-port", Protocol: corev1.ProtocolTCP, Port: 80, NodePort: 30080}}
+			gomock.InOrder(
+				mockClient.EXPECT().UpdateTargetPool(gomock.Any(), gomock.Any(), "my-port", gomock.Any()).
+					Return(&oapiError.GenericOpenAPIError{StatusCode: http.StatusConflict}),
+				mockClient.EXPECT().UpdateTargetPool(gomock.Any(), gomock.Any(), "my-port", gomock.Any()).Return(nil),
+			)
+
+			Expect(loadBalancer.UpdateLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})).To(Succeed())
+		})
+
+		It("should report all invalid options in a single event", func() {
+			recorder := record.NewFakeRecorder(10)
+			loadBalancer.recorder = recorder
+
+			svc := minimalLoadBalancerService()
+			svc.Annotations["lb.stackit.cloud/tcp-idle-timeout"] = "15x"
+			svc.Annotations["lb.stackit.cloud/session-persistence-with-source-ip"] = "maybe"
+			err := loadBalancer.UpdateLoadBalancer(context.Background(), clusterName, svc, []*corev1.Node{})
+
+			Expect(err).To(HaveOccurred())
+			var event string
+			Expect(recorder.Events).To(Receive(&event))
+			Expect(event).To(ContainSubstring(EventReasonInvalidSpec))
+			Expect(event).To(ContainSubstring("lb.stackit.cloud/tcp-idle-timeout"))
+			Expect(event).To(ContainSubstring("lb.stackit.cloud/session-persistence-with-source-ip"))
+		})
 	})
 
 	Describe("reconcileObservabilityCredentials", func() {
@@ -637,9 +1153,7 @@ var _ = Describe("LoadBalancer", func() {
 		})
 
 		It("should create credentials if they do not exist", func() {
-			mockClient.EXPECT().ListCredentials(gomock.Any()).Return(&loadbalancer.ListCredentialsResponse{
-				Credentials: []loadbalancer.CredentialsResponse{},
-			}, nil)
+			mockClient.EXPECT().ListCredentials(gomock.Any()).Return(&loadbalancer.ListCredentialsResponse{}, nil)
 			mockClient.EXPECT().CreateCredentials(gomock.Any(), gomock.Any()).MinTimes(1).Return(&loadbalancer.CreateCredentialsResponse{
 				Credential: &loadbalancer.CredentialsResponse{
 					CredentialsRef: new(sampleCredentialsRef),
@@ -659,11 +1173,66 @@ var _ = Describe("LoadBalancer", func() {
 			}))
 		})
 
+		It("should delete orphaned credentials before creating new ones", func() {
+			gomock.InOrder(
+				mockClient.EXPECT().ListCredentials(gomock.Any()).Return(&loadbalancer.ListCredentialsResponse{
+					Credentials: []loadbalancer.CredentialsResponse{
+						{CredentialsRef: new("orphaned"), DisplayName: new(sampleLBName)},
+						{CredentialsRef: new("other"), DisplayName: new("other-loadbalancer")},
+					},
+				}, nil),
+				mockClient.EXPECT().DeleteCredentials(gomock.Any(), "orphaned").Return(nil),
+				mockClient.EXPECT().CreateCredentials(gomock.Any(), gomock.Any()).Return(&loadbalancer.CreateCredentialsResponse{
+					Credential: &loadbalancer.CredentialsResponse{CredentialsRef: new(sampleCredentialsRef)},
+				}, nil),
+			)
+			credentialRef, err := lbInModeIgnoreAndObs.reconcileObservabilityCredentials(context.Background(), nil, sampleLBName)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(credentialRef.Metrics.CredentialsRef).To(Equal(new(sampleCredentialsRef)))
+		})
+
+		It("should create new credentials if the referenced ones don't exist anymore", func() {
+			gomock.InOrder(
+				mockClient.EXPECT().UpdateCredentials(gomock.Any(), "deleted", gomock.Any()).
+					Return(&oapiError.GenericOpenAPIError{StatusCode: http.StatusNotFound}),
+				mockClient.EXPECT().ListCredentials(gomock.Any()).Return(&loadbalancer.ListCredentialsResponse{}, nil),
+				mockClient.EXPECT().CreateCredentials(gomock.Any(), gomock.Any()).Return(&loadbalancer.CreateCredentialsResponse{
+					Credential: &loadbalancer.CredentialsResponse{CredentialsRef: new(sampleCredentialsRef)},
+				}, nil),
+			)
+			credentialRef, err := lbInModeIgnoreAndObs.reconcileObservabilityCredentials(context.Background(), &loadbalancer.LoadBalancer{
+				Name: new(sampleLBName),
+				Options: &loadbalancer.LoadBalancerOptions{
+					Observability: &loadbalancer.LoadbalancerOptionObservability{
+						Metrics: &loadbalancer.LoadbalancerOptionMetrics{CredentialsRef: new("deleted")},
+					},
+				},
+			}, sampleLBName)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(credentialRef.Metrics.CredentialsRef).To(Equal(new(sampleCredentialsRef)))
+		})
+
+		It("should migrate credentials to the display name scoped to the cluster", func() {
+			lbInModeIgnoreAndObs.clusterID = "my-cluster"
+			mockClient.EXPECT().UpdateCredentials(gomock.Any(), sampleCredentialsRef, gomock.Any()).DoAndReturn(
+				func(_ context.Context, _ string, payload loadbalancer.UpdateCredentialsPayload) error {
+					Expect(payload.DisplayName).To(Equal(new("my-cluster-" + sampleLBName)))
+					return nil
+				})
+			_, err := lbInModeIgnoreAndObs.reconcileObservabilityCredentials(context.Background(), &loadbalancer.LoadBalancer{
+				Name: new(sampleLBName),
+				Options: &loadbalancer.LoadBalancerOptions{
+					Observability: &loadbalancer.LoadbalancerOptionObservability{
+						Metrics: &loadbalancer.LoadbalancerOptionMetrics{CredentialsRef: new(sampleCredentialsRef)},
+					},
+				},
+			}, sampleLBName)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
 		It("should return error if creating new credentials fails", func() {
-			mockClient.EXPECT().ListCredentials(gomock.Any()).Return(&loadbalancer.ListCredentialsResponse{
-				Credentials: []loadbalancer.CredentialsResponse{},
-			}, nil)
 			errTest := errors.New("delete credentials test error")
+			mockClient.EXPECT().ListCredentials(gomock.Any()).Return(&loadbalancer.ListCredentialsResponse{}, nil)
 			mockClient.EXPECT().CreateCredentials(gomock.Any(), gomock.Any()).MinTimes(1).Return(nil, errTest)
 			credentialRef, err := lbInModeIgnoreAndObs.reconcileObservabilityCredentials(context.Background(), &loadbalancer.LoadBalancer{
 				Name: new(sampleLBName),
@@ -705,6 +1274,36 @@ var _ = Describe("LoadBalancer", func() {
 			)
 			Expect(lbInModeIgnoreAndObs.cleanUpCredentials(context.Background(), "my-loadbalancer")).To(Succeed())
 		})
+
+		It("should delete scoped and legacy credentials if the cluster ID is set", func() {
+			lbInModeIgnoreAndObs.clusterID = "my-cluster"
+			gomock.InOrder(
+				mockClient.EXPECT().ListCredentials(gomock.Any()).Return(&loadbalancer.ListCredentialsResponse{
+					Credentials: []loadbalancer.CredentialsResponse{
+						{CredentialsRef: new("scoped"), DisplayName: new("my-cluster-my-loadbalancer")},
+						{CredentialsRef: new("other-cluster"), DisplayName: new("other-cluster-my-loadbalancer")},
+						{CredentialsRef: new("legacy"), DisplayName: new("my-loadbalancer")},
+					},
+				}, nil),
+				mockClient.EXPECT().DeleteCredentials(gomock.Any(), "scoped"),
+				mockClient.EXPECT().DeleteCredentials(gomock.Any(), "legacy"),
+			)
+			Expect(lbInModeIgnoreAndObs.cleanUpCredentials(context.Background(), "my-loadbalancer")).To(Succeed())
+		})
+	})
+
+	Describe("credentialsName", func() {
+		It("should use the load balancer name without cluster ID", func() {
+			Expect(loadBalancer.credentialsName(sampleLBName)).To(Equal(sampleLBName))
+		})
+
+		It("should prefix the load balancer name with the cluster ID", func() {
+			loadBalancer.clusterID = "shoot--myproject"
+			name := loadBalancer.credentialsName(sampleLBName)
+			Expect(name).To(HaveLen(maxCredentialsNameLength))
+			Expect(name).To(Equal("shoot--myproject-k8s-svc-89ec9a0e-6b00-4e2f-b57b-02e89193093d-e"))
+			Expect(name).To(HavePrefix(loadBalancer.credentialsPrefix()))
+		})
 	})
 })
 
@@ -0,0 +1,218 @@
+package ccm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	cloudprovider "k8s.io/cloud-provider"
+	"k8s.io/klog/v2"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/labels"
+)
+
+const (
+	// NodeMetadataLabelsControllerName is the name of the controller that labels nodes with properties of their server.
+	NodeMetadataLabelsControllerName = "node-metadata-labels"
+
+	// LabelMachineType contains the machine type of the server of a node, e.g. g1a.8d.
+	LabelMachineType = "stackit.cloud/machine-type"
+	// LabelFlavor contains the family of the machine type, e.g. g1a for g1a.8d.
+	LabelFlavor = "stackit.cloud/flavor"
+	// LabelImage contains the ID of the image the server was created from.
+	LabelImage = "stackit.cloud/image"
+	// LabelNetworkID contains the ID of the network of the primary NIC of the server, whose IP is the first
+	// internal IP of the node. It is used to detect the target network of load balancers.
+	LabelNetworkID = "stackit.cloud/network-id"
+
+	// DefaultNodeMetadataLabelsInterval is the default interval in which all nodes are checked again.
+	DefaultNodeMetadataLabelsInterval = 10 * time.Minute
+)
+
+var nodeMetadataLabels = []string{LabelMachineType, LabelFlavor, LabelImage, LabelNetworkID}
+
+// NodeMetadataLabelsController labels nodes with properties of their servers from the IaaS API,
+// so that schedulers and autoscalers can target them.
+type NodeMetadataLabelsController struct {
+	kubeClient  kubernetes.Interface
+	nodeLister  corelisters.NodeLister
+	nodesSynced cache.InformerSynced
+	instances   *Instances
+	queue       workqueue.TypedRateLimitingInterface[string]
+}
+
+// NewNodeMetadataLabelsController creates the controller from the STACKIT cloud provider.
+// All nodes are checked again every interval, since the properties of a server can change without a node event,
+// e.g. when it is resized.
+func NewNodeMetadataLabelsController(
+	nodeInformer coreinformers.NodeInformer,
+	kubeClient kubernetes.Interface,
+	cloud cloudprovider.Interface,
+	interval time.Duration,
+) (*NodeMetadataLabelsController, error) {
+	stackitCloud, ok := cloud.(*CloudControllerManager)
+	if !ok {
+		return nil, fmt.Errorf("cloud provider %T is not supported by the %s controller", cloud, NodeMetadataLabelsControllerName)
+	}
+	if interval <= 0 {
+		interval = DefaultNodeMetadataLabelsInterval
+	}
+
+	c := &NodeMetadataLabelsController{
+		kubeClient:  kubeClient,
+		nodeLister:  nodeInformer.Lister(),
+		nodesSynced: nodeInformer.Informer().HasSynced,
+		instances:   stackitCloud.instances,
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.DefaultTypedControllerRateLimiter[string](),
+			workqueue.TypedRateLimitingQueueConfig[string]{Name: NodeMetadataLabelsControllerName},
+		),
+	}
+
+	_, err := nodeInformer.Informer().AddEventHandlerWithResyncPeriod(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueue,
+		UpdateFunc: func(oldObj, newObj any) {
+			oldNode, newNode := oldObj.(*corev1.Node), newObj.(*corev1.Node)
+			// Resyncs are delivered as updates without changes, labels could have been removed by someone else.
+			if oldNode.ResourceVersion == newNode.ResourceVersion || oldNode.Spec.ProviderID != newNode.Spec.ProviderID {
+				c.enqueue(newObj)
+				return
+			}
+			for _, key := range nodeMetadataLabels {
+				if oldNode.Labels[key] != newNode.Labels[key] {
+					c.enqueue(newObj)
+					return
+				}
+			}
+		},
+	}, interval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add node event handler: %w", err)
+	}
+
+	return c, nil
+}
+
+func (c *NodeMetadataLabelsController) enqueue(obj any) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.queue.Add(key)
+}
+
+// Run starts the workers and blocks until ctx is cancelled.
+func (c *NodeMetadataLabelsController) Run(ctx context.Context, workers int) {
+	defer utilruntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	klog.InfoS("Starting controller", "controller", NodeMetadataLabelsControllerName)
+	defer klog.InfoS("Shutting down controller", "controller", NodeMetadataLabelsControllerName)
+
+	if !cache.WaitForCacheSync(ctx.Done(), c.nodesSynced) {
+		return
+	}
+
+	for range workers {
+		go wait.UntilWithContext(ctx, c.runWorker, time.Second)
+	}
+
+	<-ctx.Done()
+}
+
+func (c *NodeMetadataLabelsController) runWorker(ctx context.Context) {
+	for c.processNextItem(ctx) {
+	}
+}
+
+func (c *NodeMetadataLabelsController) processNextItem(ctx context.Context) bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	if err := c.syncNode(ctx, key); err != nil {
+		utilruntime.HandleError(fmt.Errorf("failed to sync metadata labels of node %q: %w", key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *NodeMetadataLabelsController) syncNode(ctx context.Context, name string) error {
+	node, err := c.nodeLister.Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	server, err := c.instances.getInstance(ctx, node)
+	if errors.Is(err, cloudprovider.InstanceNotFound) {
+		klog.V(4).InfoS("Server of node not found, skipping metadata labels", "node", klog.KRef("", name))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	desired := serverMetadataLabels(server, c.instances.networkPriority)
+	patch := labelsPatch(nodeMetadataLabels, node.Labels, desired)
+	if patch == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	klog.V(2).InfoS("Updating metadata labels of node", "node", klog.KRef("", name), "labels", desired)
+	_, err = c.kubeClient.CoreV1().Nodes().Patch(ctx, name, types.StrategicMergePatchType, data, metav1.PatchOptions{})
+	return err
+}
+
+// serverMetadataLabels returns the metadata labels of the server. Unknown properties are omitted.
+// The primary NIC is determined by networkPriority like for the node addresses.
+func serverMetadataLabels(server *iaas.Server, networkPriority []string) map[string]string {
+	desired := map[string]string{}
+	if machineType := labels.Sanitize(server.MachineType); machineType != "" {
+		desired[LabelMachineType] = machineType
+		flavor, _, _ := strings.Cut(machineType, ".")
+		desired[LabelFlavor] = flavor
+	}
+
+	imageID := server.GetImageId()
+	if source := server.GetBootVolume().Source; imageID == "" && source != nil && source.Type == "image" {
+		imageID = source.Id
+	}
+	if imageID = labels.Sanitize(imageID); imageID != "" {
+		desired[LabelImage] = imageID
+	}
+
+	if nics := sortNics(slices.Clone(server.GetNics()), networkPriority); len(nics) > 0 {
+		if networkID := labels.Sanitize(nics[0].NetworkId); networkID != "" {
+			desired[LabelNetworkID] = networkID
+		}
+	}
+	return desired
+}
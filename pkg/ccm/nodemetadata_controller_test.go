@@ -0,0 +1,132 @@
+package ccm
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+)
+
+var _ = Describe("NodeMetadataLabelsController", func() {
+	const (
+		serverID = "server-id"
+		imageID  = "2f1e9b7c-0a3d-4c5e-8f6a-1b2c3d4e5f60"
+	)
+
+	var (
+		iaasMock   *stackitclientmock.MockIaaSClient
+		kubeClient *fake.Clientset
+		controller *NodeMetadataLabelsController
+		node       *corev1.Node
+	)
+
+	BeforeEach(func() {
+		iaasMock = stackitclientmock.NewMockIaaSClient(gomock.NewController(GinkgoT()))
+		instances, err := NewInstance(iaasMock, "eu01", config.InstanceOpts{})
+		Expect(err).NotTo(HaveOccurred())
+
+		node = &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-a", Labels: map[string]string{"foo": "bar"}},
+			Spec:       corev1.NodeSpec{ProviderID: "stackit://" + serverID},
+		}
+		kubeClient = fake.NewClientset(node)
+		informerFactory := informers.NewSharedInformerFactory(kubeClient, 0)
+		nodeInformer := informerFactory.Core().V1().Nodes()
+
+		controller, err = NewNodeMetadataLabelsController(nodeInformer, kubeClient, &CloudControllerManager{instances: instances}, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nodeInformer.Informer().GetIndexer().Add(node)).To(Succeed())
+	})
+
+	getLabels := func() map[string]string {
+		n, err := kubeClient.CoreV1().Nodes().Get(context.Background(), node.Name, metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		return n.Labels
+	}
+
+	It("should label nodes with the machine type, flavor and image", func() {
+		iaasMock.EXPECT().GetServerWithDetails(gomock.Any(), serverID).Return(&iaas.Server{
+			Id:          new(serverID),
+			MachineType: "g1a.8d",
+			ImageId:     new(imageID),
+		}, nil)
+
+		Expect(controller.syncNode(context.Background(), node.Name)).To(Succeed())
+		Expect(getLabels()).To(Equal(map[string]string{
+			"foo":            "bar",
+			LabelMachineType: "g1a.8d",
+			LabelFlavor:      "g1a",
+			LabelImage:       imageID,
+		}))
+	})
+
+	It("should update the labels after the server was resized", func() {
+		node.Labels[LabelMachineType] = "g1a.4d"
+		node.Labels[LabelFlavor] = "g1a"
+		Expect(kubeClient.Tracker().Update(corev1.SchemeGroupVersion.WithResource("nodes"), node, "")).To(Succeed())
+		iaasMock.EXPECT().GetServerWithDetails(gomock.Any(), serverID).Return(&iaas.Server{Id: new(serverID), MachineType: "c2i.8"}, nil)
+
+		Expect(controller.syncNode(context.Background(), node.Name)).To(Succeed())
+		Expect(getLabels()).To(Equal(map[string]string{
+			"foo":            "bar",
+			LabelMachineType: "c2i.8",
+			LabelFlavor:      "c2i",
+		}))
+	})
+
+	It("should not patch nodes that are up to date", func() {
+		node.Labels[LabelMachineType] = "g1a.8d"
+		node.Labels[LabelFlavor] = "g1a"
+		iaasMock.EXPECT().GetServerWithDetails(gomock.Any(), serverID).Return(&iaas.Server{Id: new(serverID), MachineType: "g1a.8d"}, nil)
+
+		Expect(controller.syncNode(context.Background(), node.Name)).To(Succeed())
+		Expect(kubeClient.Actions()).To(BeEmpty())
+	})
+
+	It("should ignore deleted nodes", func() {
+		Expect(controller.syncNode(context.Background(), "unknown")).To(Succeed())
+	})
+})
+
+var _ = Describe("serverMetadataLabels", func() {
+	It("should use the image of the boot volume", func() {
+		server := &iaas.Server{
+			MachineType: "t1.1",
+			BootVolume:  &iaas.BootVolume{Source: &iaas.BootVolumeSource{Type: "image", Id: "image-id"}},
+		}
+		Expect(serverMetadataLabels(server, nil)).To(Equal(map[string]string{
+			LabelMachineType: "t1.1",
+			LabelFlavor:      "t1",
+			LabelImage:       "image-id",
+		}))
+	})
+
+	It("should use the network of the primary NIC", func() {
+		server := &iaas.Server{
+			MachineType: "t1.1",
+			Nics: []iaas.ServerNetwork{
+				{NetworkId: "network-a", NetworkName: "a"},
+				{NetworkId: "network-b", NetworkName: "b"},
+			},
+		}
+		Expect(serverMetadataLabels(server, nil)).To(HaveKeyWithValue(LabelNetworkID, "network-a"))
+		Expect(serverMetadataLabels(server, []string{"b"})).To(HaveKeyWithValue(LabelNetworkID, "network-b"))
+	})
+
+	It("should omit the image of servers booted from a volume", func() {
+		server := &iaas.Server{
+			MachineType: "t1.1",
+			BootVolume:  &iaas.BootVolume{Source: &iaas.BootVolumeSource{Type: "volume", Id: "volume-id"}},
+		}
+		Expect(serverMetadataLabels(server, nil)).NotTo(HaveKey(LabelImage))
+	})
+})
@@ -0,0 +1,199 @@
+package ccm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	cloudprovider "k8s.io/cloud-provider"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// ServerGroupLabelsControllerName is the name of the controller that labels nodes with the server group of their server.
+	ServerGroupLabelsControllerName = "server-group-labels"
+
+	// LabelServerGroup contains the ID of the server (affinity) group the server of a node belongs to.
+	LabelServerGroup = "stackit.cloud/server-group"
+	// LabelServerGroupPolicy contains the placement policy of the server group, e.g. soft-anti-affinity.
+	LabelServerGroupPolicy = "stackit.cloud/server-group-policy"
+
+	// serverGroupLabelsResync is the interval in which all nodes are checked again.
+	serverGroupLabelsResync = 10 * time.Minute
+)
+
+// ServerGroupLabelsController labels nodes with the server group membership of their servers,
+// so that workloads can be spread across physical anti-affinity groups.
+type ServerGroupLabelsController struct {
+	kubeClient  kubernetes.Interface
+	nodeLister  corelisters.NodeLister
+	nodesSynced cache.InformerSynced
+	instances   *Instances
+	queue       workqueue.TypedRateLimitingInterface[string]
+}
+
+// NewServerGroupLabelsController creates the controller from the STACKIT cloud provider.
+func NewServerGroupLabelsController(
+	nodeInformer coreinformers.NodeInformer,
+	kubeClient kubernetes.Interface,
+	cloud cloudprovider.Interface,
+) (*ServerGroupLabelsController, error) {
+	stackitCloud, ok := cloud.(*CloudControllerManager)
+	if !ok {
+		return nil, fmt.Errorf("cloud provider %T is not supported by the %s controller", cloud, ServerGroupLabelsControllerName)
+	}
+
+	c := &ServerGroupLabelsController{
+		kubeClient:  kubeClient,
+		nodeLister:  nodeInformer.Lister(),
+		nodesSynced: nodeInformer.Informer().HasSynced,
+		instances:   stackitCloud.instances,
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.DefaultTypedControllerRateLimiter[string](),
+			workqueue.TypedRateLimitingQueueConfig[string]{Name: ServerGroupLabelsControllerName},
+		),
+	}
+
+	_, err := nodeInformer.Informer().AddEventHandlerWithResyncPeriod(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueue,
+		UpdateFunc: func(oldObj, newObj any) {
+			oldNode, newNode := oldObj.(*corev1.Node), newObj.(*corev1.Node)
+			// Resyncs are delivered as updates without changes, labels could have been removed by someone else.
+			if oldNode.ResourceVersion == newNode.ResourceVersion ||
+				oldNode.Spec.ProviderID != newNode.Spec.ProviderID ||
+				oldNode.Labels[LabelServerGroup] != newNode.Labels[LabelServerGroup] ||
+				oldNode.Labels[LabelServerGroupPolicy] != newNode.Labels[LabelServerGroupPolicy] {
+				c.enqueue(newObj)
+			}
+		},
+	}, serverGroupLabelsResync)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add node event handler: %w", err)
+	}
+
+	return c, nil
+}
+
+func (c *ServerGroupLabelsController) enqueue(obj any) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.queue.Add(key)
+}
+
+// Run starts the workers and blocks until ctx is cancelled.
+func (c *ServerGroupLabelsController) Run(ctx context.Context, workers int) {
+	defer utilruntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	klog.InfoS("Starting controller", "controller", ServerGroupLabelsControllerName)
+	defer klog.InfoS("Shutting down controller", "controller", ServerGroupLabelsControllerName)
+
+	if !cache.WaitForCacheSync(ctx.Done(), c.nodesSynced) {
+		return
+	}
+
+	for range workers {
+		go wait.UntilWithContext(ctx, c.runWorker, time.Second)
+	}
+
+	<-ctx.Done()
+}
+
+func (c *ServerGroupLabelsController) runWorker(ctx context.Context) {
+	for c.processNextItem(ctx) {
+	}
+}
+
+func (c *ServerGroupLabelsController) processNextItem(ctx context.Context) bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	if err := c.syncNode(ctx, key); err != nil {
+		utilruntime.HandleError(fmt.Errorf("failed to sync server group labels of node %q: %w", key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *ServerGroupLabelsController) syncNode(ctx context.Context, name string) error {
+	node, err := c.nodeLister.Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	server, err := c.instances.getInstance(ctx, node)
+	if errors.Is(err, cloudprovider.InstanceNotFound) {
+		klog.V(4).InfoS("Server of node not found, skipping server group labels", "node", klog.KRef("", name))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	desired := map[string]string{}
+	if server.AffinityGroup != nil && *server.AffinityGroup != "" {
+		group, err := c.instances.iaasClient.GetAffinityGroup(ctx, *server.AffinityGroup)
+		if err != nil {
+			return fmt.Errorf("failed to get server group %q: %w", *server.AffinityGroup, err)
+		}
+		desired[LabelServerGroup] = *server.AffinityGroup
+		desired[LabelServerGroupPolicy] = group.Policy
+	}
+
+	patch := labelsPatch([]string{LabelServerGroup, LabelServerGroupPolicy}, node.Labels, desired)
+	if patch == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	klog.V(2).InfoS("Updating server group labels of node", "node", klog.KRef("", name), "labels", desired)
+	_, err = c.kubeClient.CoreV1().Nodes().Patch(ctx, name, types.StrategicMergePatchType, data, metav1.PatchOptions{})
+	return err
+}
+
+// labelsPatch returns the patch that sets the managed labels keys of a node to desired,
+// or nil if the node is already labelled correctly. Keys that are not desired are removed.
+func labelsPatch(keys []string, current, desired map[string]string) map[string]any {
+	labels := map[string]any{}
+	for _, key := range keys {
+		value, want := desired[key]
+		currentValue, has := current[key]
+		switch {
+		case want && (!has || currentValue != value):
+			labels[key] = value
+		case !want && has:
+			labels[key] = nil
+		}
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+	return map[string]any{"metadata": map[string]any{"labels": labels}}
+}
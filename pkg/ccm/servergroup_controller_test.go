@@ -0,0 +1,114 @@
+package ccm
+
+import (
+	"context"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	oapiError "github.com/stackitcloud/stackit-sdk-go/core/oapierror"
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+)
+
+var _ = Describe("ServerGroupLabelsController", func() {
+	const (
+		serverID = "server-id"
+		groupID  = "8a6c5a8e-3f0f-4b7c-9c1e-3a4f1b2c3d4e"
+	)
+
+	var (
+		iaasMock   *stackitclientmock.MockIaaSClient
+		kubeClient *fake.Clientset
+		controller *ServerGroupLabelsController
+		node       *corev1.Node
+	)
+
+	BeforeEach(func() {
+		iaasMock = stackitclientmock.NewMockIaaSClient(gomock.NewController(GinkgoT()))
+		instances, err := NewInstance(iaasMock, "eu01", config.InstanceOpts{})
+		Expect(err).NotTo(HaveOccurred())
+
+		node = &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-a", Labels: map[string]string{"foo": "bar"}},
+			Spec:       corev1.NodeSpec{ProviderID: "stackit://" + serverID},
+		}
+		kubeClient = fake.NewClientset(node)
+		informerFactory := informers.NewSharedInformerFactory(kubeClient, 0)
+		nodeInformer := informerFactory.Core().V1().Nodes()
+
+		controller, err = NewServerGroupLabelsController(nodeInformer, kubeClient, &CloudControllerManager{instances: instances})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nodeInformer.Informer().GetIndexer().Add(node)).To(Succeed())
+	})
+
+	getLabels := func() map[string]string {
+		n, err := kubeClient.CoreV1().Nodes().Get(context.Background(), node.Name, metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		return n.Labels
+	}
+
+	It("should label nodes with the server group and its policy", func() {
+		iaasMock.EXPECT().GetServerWithDetails(gomock.Any(), serverID).Return(&iaas.Server{Id: new(serverID), AffinityGroup: new(groupID)}, nil)
+		iaasMock.EXPECT().GetAffinityGroup(gomock.Any(), groupID).Return(&iaas.AffinityGroup{Id: new(groupID), Policy: "hard-anti-affinity"}, nil)
+
+		Expect(controller.syncNode(context.Background(), node.Name)).To(Succeed())
+		Expect(getLabels()).To(Equal(map[string]string{
+			"foo":                  "bar",
+			LabelServerGroup:       groupID,
+			LabelServerGroupPolicy: "hard-anti-affinity",
+		}))
+	})
+
+	It("should not patch nodes for servers without a server group", func() {
+		iaasMock.EXPECT().GetServerWithDetails(gomock.Any(), serverID).Return(&iaas.Server{Id: new(serverID)}, nil)
+
+		Expect(controller.syncNode(context.Background(), node.Name)).To(Succeed())
+		Expect(kubeClient.Actions()).To(BeEmpty())
+	})
+
+	It("should ignore nodes whose server does not exist", func() {
+		iaasMock.EXPECT().GetServerWithDetails(gomock.Any(), serverID).Return(nil, &oapiError.GenericOpenAPIError{StatusCode: http.StatusNotFound})
+
+		Expect(controller.syncNode(context.Background(), node.Name)).To(Succeed())
+		Expect(kubeClient.Actions()).To(BeEmpty())
+	})
+
+	It("should ignore deleted nodes", func() {
+		Expect(controller.syncNode(context.Background(), "unknown")).To(Succeed())
+	})
+})
+
+var _ = DescribeTable("labelsPatch",
+	func(current, desired map[string]string, expected map[string]any) {
+		patch := labelsPatch([]string{LabelServerGroup, LabelServerGroupPolicy}, current, desired)
+		if expected == nil {
+			Expect(patch).To(BeNil())
+			return
+		}
+		Expect(patch).To(Equal(map[string]any{"metadata": map[string]any{"labels": expected}}))
+	},
+	Entry("up to date",
+		map[string]string{LabelServerGroup: "a", LabelServerGroupPolicy: "soft-affinity"},
+		map[string]string{LabelServerGroup: "a", LabelServerGroupPolicy: "soft-affinity"},
+		nil,
+	),
+	Entry("nothing to remove", map[string]string{"foo": "bar"}, map[string]string{}, nil),
+	Entry("changed policy",
+		map[string]string{LabelServerGroup: "a", LabelServerGroupPolicy: "soft-affinity"},
+		map[string]string{LabelServerGroup: "a", LabelServerGroupPolicy: "hard-affinity"},
+		map[string]any{LabelServerGroupPolicy: "hard-affinity"},
+	),
+	Entry("server left its group",
+		map[string]string{LabelServerGroup: "a", LabelServerGroupPolicy: "soft-affinity"},
+		map[string]string{},
+		map[string]any{LabelServerGroup: nil, LabelServerGroupPolicy: nil},
+	),
+)
@@ -1,16 +1,20 @@
 package ccm
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"regexp"
 
 	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
 	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/validation"
 	sdkconfig "github.com/stackitcloud/stackit-sdk-go/core/config"
 	"gopkg.in/yaml.v3"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes/scheme"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/record"
@@ -23,8 +27,6 @@ import (
 const (
 	// ProviderName is the name of the stackit provider
 	ProviderName = "stackit"
-	// TODO: remove old provider after migration
-	oldProviderName = "openstack"
 
 	// metricsRemoteWrite ENVs for metrics shipping to argus using basic auth
 	stackitRemoteWriteEndpointKey = "STACKIT_REMOTEWRITE_ENDPOINT"
@@ -35,9 +37,21 @@ const (
 	stackitLoadBalancerEmergencyAPIToken = "STACKIT_LB_API_EMERGENCY_TOKEN" //nolint:gosec // this is just the env var name
 )
 
+// maxClusterIDLength keeps the service UID in the display name of observability credentials, see LoadBalancer.credentialsName.
+const maxClusterIDLength = 16
+
+var clusterIDRegexp = regexp.MustCompile(fmt.Sprintf(`^[0-9a-z][0-9a-z-]{0,%d}$`, maxClusterIDLength-1))
+
 type CloudControllerManager struct {
 	loadBalancer *LoadBalancer
 	instances    *Instances
+	// dnsClient is nil if no DNS zone is configured
+	dnsClient stackitclient.DNSClient
+	dnsOpts   stackitconfig.DNSOpts
+	// auditor is nil if auditing is disabled
+	auditor *stackitclient.Auditor
+	// leading is closed once this instance is the leader, nil if the cloud-provider app runs the leader election
+	leading <-chan struct{}
 }
 
 func init() {
@@ -47,15 +61,8 @@ func init() {
 			return nil, err
 		}
 
-		if cfg.Global.ProjectID == "" {
-			return nil, errors.New("projectId must be set")
-		}
-		if cfg.Global.Region == "" {
-			return nil, errors.New("region must be set")
-		}
-
-		if cfg.LoadBalancer.NetworkID == "" {
-			return nil, errors.New("networkId must be set")
+		if err := validateConfig(&cfg); err != nil {
+			return nil, err
 		}
 
 		obs, err := BuildObservability()
@@ -64,12 +71,35 @@ func init() {
 		}
 		cloud, err := NewCloudControllerManager(&cfg, obs)
 		if err != nil {
-			klog.Warningf("Failed to create STACKIT cloud provider: %v", err)
+			klog.InfoS("Failed to create STACKIT cloud provider", "err", err)
 		}
 		return cloud, err
 	})
 }
 
+// validateConfig checks the options that are required to start the cloud controller manager.
+func validateConfig(cfg *stackitconfig.CCMConfig) error {
+	if err := validation.GlobalOpts(cfg.Global); err != nil {
+		return err
+	}
+
+	if cfg.Global.ClusterID != "" && !clusterIDRegexp.MatchString(cfg.Global.ClusterID) {
+		return fmt.Errorf("clusterId must consist of at most %d lower case alphanumeric characters or '-'", maxClusterIDLength)
+	}
+
+	if cfg.LoadBalancer.NetworkID == "" && !cfg.LoadBalancer.NetworkAutoDetection && len(cfg.LoadBalancer.NodePoolNetworks) == 0 {
+		return errors.New("networkId must be set unless networkAutoDetection or nodePoolNetworks are configured")
+	}
+
+	clients := cfg.LoadBalancer.APIClients
+	for name, limit := range map[string]stackitconfig.RateLimitOpts{"read": clients.Read.RateLimit, "write": clients.Write.RateLimit} {
+		if limit.QPS < 0 || limit.Burst < 0 {
+			return fmt.Errorf("apiClients.%s.rateLimit must not be negative", name)
+		}
+	}
+	return nil
+}
+
 func GetConfig(reader io.Reader) (stackitconfig.CCMConfig, error) {
 	var cfg stackitconfig.CCMConfig
 
@@ -117,66 +147,149 @@ func BuildObservability() (*MetricsRemoteWrite, error) {
 
 // NewCloudControllerManager creates a new instance of the stackit struct from a stackitconfig struct
 func NewCloudControllerManager(cfg *stackitconfig.CCMConfig, obs *MetricsRemoteWrite) (*CloudControllerManager, error) {
-	lbHTTPClient := metrics.NewInstrumentedHTTPClient(metrics.APINameLoadBalancer)
-	lbOpts := []sdkconfig.ConfigurationOption{
-		sdkconfig.WithHTTPClient(lbHTTPClient),
+	loadbalancingClient, iaasClient, err := NewClients(cfg)
+	if err != nil {
+		return nil, err
 	}
 
-	if cfg.Global.APIEndpoints.LoadBalancerAPI != "" {
-		lbOpts = append(lbOpts, sdkconfig.WithEndpoint(cfg.Global.APIEndpoints.LoadBalancerAPI))
+	auditor := stackitclient.NewAuditor(cfg.Global.Audit, "stackit-cloud-controller-manager", cfg.Global.Region, cfg.Global.ProjectID)
+	loadbalancingClient = stackitclient.NewAuditedLoadBalancingClient(loadbalancingClient, auditor)
+	iaasClient = stackitclient.NewAuditedIaaSClient(iaasClient, auditor)
+
+	if err := validateNetworks(context.Background(), iaasClient, cfg.LoadBalancer); err != nil {
+		return nil, err
 	}
 
-	// The token is only provided by the 'gardener-extension-provider-stackit' in case of emergency access.
-	// In those cases, the [cfg.LoadBalancerAPI.URL] will also be different (direct API URL instead of the API Gateway)
-	lbEmergencyAPIToken := os.Getenv(stackitLoadBalancerEmergencyAPIToken)
-	if lbEmergencyAPIToken != "" {
-		klog.Warningf("Using emergency token for loadbalancer api on host: %s", cfg.Global.APIEndpoints.LoadBalancerAPI)
-		lbOpts = append(lbOpts, sdkconfig.WithToken(lbEmergencyAPIToken))
+	instances, err := NewInstance(iaasClient, cfg.Global.Region, cfg.Instance)
+	if err != nil {
+		return nil, err
 	}
 
-	loadbalancingClient, err := stackitclient.New(cfg.Global.Region, cfg.Global.ProjectID).LoadBalancing(lbOpts)
+	lb, err := NewLoadBalancer(loadbalancingClient, iaasClient, cfg.LoadBalancer, obs)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create lb client: %v", err)
+		return nil, err
 	}
+	lb.clusterID = cfg.Global.ClusterID
+	lb.resourceLabels = stackitclient.ResourceLabels(cfg.Global, "stackit-cloud-controller-manager")
+	lb.projectID = cfg.Global.ProjectID
 
-	iaasHTTPClient := metrics.NewInstrumentedHTTPClient(metrics.APINameIaaS)
-	iaasOpts := []sdkconfig.ConfigurationOption{
-		sdkconfig.WithHTTPClient(iaasHTTPClient),
+	ccm := CloudControllerManager{
+		loadBalancer: lb,
+		instances:    instances,
+		dnsOpts:      cfg.DNS,
+		auditor:      auditor,
 	}
+	if cfg.DNS.ZoneID != "" {
+		dnsOpts, err := stackitclient.ConfigurationOptions(metrics.APINameDNS, cfg.Global.APIEndpoints.DNSAPI, cfg.Global.APIEndpoints)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure DNS client: %w", err)
+		}
+		dnsClient, err := stackitclient.New(cfg.Global.Region, cfg.Global.ProjectID, cfg.Global.APITimeouts).DNS(dnsOpts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create DNS client: %w", err)
+		}
+		ccm.dnsClient = stackitclient.NewAuditedDNSClient(dnsClient, auditor)
+	}
+	return &ccm, nil
+}
 
-	if cfg.Global.APIEndpoints.IaasAPI != "" {
-		iaasOpts = append(iaasOpts, sdkconfig.WithEndpoint(cfg.Global.APIEndpoints.IaasAPI))
+// NewClients creates the clients of the load balancer and IaaS APIs, also used by the orphan-gc command.
+func NewClients(cfg *stackitconfig.CCMConfig) (stackitclient.LoadBalancingClient, stackitclient.IaaSClient, error) {
+	loadbalancingClient, err := newLoadBalancingClient(cfg)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	iaasClient, err := stackitclient.New(cfg.Global.Region, cfg.Global.ProjectID).IaaS(iaasOpts)
+	iaasOpts, err := stackitclient.ConfigurationOptions(metrics.APINameIaaS, cfg.Global.APIEndpoints.IaasAPI, cfg.Global.APIEndpoints)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create IaaS client: %v", err)
+		return nil, nil, fmt.Errorf("failed to configure IaaS client: %w", err)
 	}
 
-	instances, err := NewInstance(iaasClient, cfg.Global.Region, cfg.Instance)
+	iaasClient, err := stackitclient.New(cfg.Global.Region, cfg.Global.ProjectID, cfg.Global.APITimeouts).IaaS(iaasOpts)
 	if err != nil {
-		return nil, err
+		return nil, nil, fmt.Errorf("failed to create IaaS client: %v", err)
+	}
+
+	return loadbalancingClient, iaasClient, nil
+}
+
+// newLoadBalancingClient creates the client of the load balancer API. If cfg.LoadBalancer.APIClients is configured,
+// reads and writes are made by separate clients with their own credentials and rate limits.
+func newLoadBalancingClient(cfg *stackitconfig.CCMConfig) (stackitclient.LoadBalancingClient, error) {
+	clients := cfg.LoadBalancer.APIClients
+	if clients == (stackitconfig.LoadBalancerAPIClients{}) {
+		client, err := newLoadBalancingClientWithOpts(cfg, stackitconfig.APIClientOpts{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create lb client: %w", err)
+		}
+		return client, nil
 	}
 
-	lb, err := NewLoadBalancer(loadbalancingClient, cfg.LoadBalancer, obs)
+	read, err := newLoadBalancingClientWithOpts(cfg, clients.Read)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to create lb read client: %w", err)
 	}
+	write, err := newLoadBalancingClientWithOpts(cfg, clients.Write)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lb write client: %w", err)
+	}
+	return stackitclient.NewReadWriteLoadBalancingClient(read, write), nil
+}
 
-	ccm := CloudControllerManager{
-		loadBalancer: lb,
-		instances:    instances,
+// newLoadBalancingClientWithOpts creates a client of the load balancer API with the credentials and the rate limit of
+// opts. Every client needs its own options, because the SDK sets the transport of the HTTP client in the options.
+func newLoadBalancingClientWithOpts(cfg *stackitconfig.CCMConfig, opts stackitconfig.APIClientOpts) (stackitclient.LoadBalancingClient, error) {
+	lbOpts, err := stackitclient.ConfigurationOptions(metrics.APINameLoadBalancer, cfg.Global.APIEndpoints.LoadBalancerAPI, cfg.Global.APIEndpoints)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure lb client: %w", err)
 	}
-	return &ccm, nil
+	lbOpts = append(lbOpts, stackitclient.WithRateLimit(opts.RateLimit)...)
+	if opts.ServiceAccountKeyPath != "" {
+		lbOpts = append(lbOpts, sdkconfig.WithServiceAccountKeyPath(opts.ServiceAccountKeyPath))
+	}
+
+	// The token is only provided by the 'gardener-extension-provider-stackit' in case of emergency access.
+	// In those cases, the [cfg.LoadBalancerAPI.URL] will also be different (direct API URL instead of the API Gateway)
+	lbEmergencyAPIToken := os.Getenv(stackitLoadBalancerEmergencyAPIToken)
+	if lbEmergencyAPIToken != "" {
+		klog.InfoS("Using emergency token for loadbalancer api", "host", cfg.Global.APIEndpoints.LoadBalancerAPI)
+		lbOpts = append(lbOpts, sdkconfig.WithToken(lbEmergencyAPIToken))
+	}
+
+	return stackitclient.New(cfg.Global.Region, cfg.Global.ProjectID, cfg.Global.APITimeouts).LoadBalancing(lbOpts)
 }
 
-func (ccm *CloudControllerManager) Initialize(clientBuilder cloudprovider.ControllerClientBuilder, _ <-chan struct{}) {
+func (ccm *CloudControllerManager) Initialize(clientBuilder cloudprovider.ControllerClientBuilder, stop <-chan struct{}) {
+	// The cloud-provider app initializes the cloud provider right before it starts the controllers.
+	if ccm.leading != nil {
+		select {
+		case <-stop:
+		case <-ccm.leading:
+		}
+	}
+
 	// create an EventRecorder
 	eventBroadcaster := record.NewBroadcaster()
-	eventBroadcaster.StartLogging(klog.Infof)
-	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientBuilder.ClientOrDie("cloud-controller-manager").CoreV1().Events("")})
+	eventBroadcaster.StartStructuredLogging(0)
+	kubeClient := clientBuilder.ClientOrDie("cloud-controller-manager")
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeClient.CoreV1().Events("")})
 	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "stackit-cloud-controller-manager"})
 	ccm.loadBalancer.recorder = recorder
+	ccm.loadBalancer.kubeClient = kubeClient
+
+	// Credentials are only created if metrics shipping is enabled.
+	if ccm.loadBalancer.metricsRemoteWrite != nil {
+		go ccm.loadBalancer.runCredentialsJanitor(wait.ContextForChannel(stop))
+	}
+
+	go func() {
+		<-stop
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), stackitclient.AuditShutdownTimeout)
+		defer cancel()
+		if err := ccm.auditor.Shutdown(shutdownCtx); err != nil {
+			klog.ErrorS(err, "Failed to flush audit events")
+		}
+	}()
 }
 
 func (ccm *CloudControllerManager) InstancesV2() (cloudprovider.InstancesV2, bool) {
@@ -210,3 +323,15 @@ func (ccm *CloudControllerManager) ProviderName() string {
 func (ccm *CloudControllerManager) HasClusterID() bool {
 	return true
 }
+
+// SetLoadBalancerOptIn restricts the reconciliation of load balancers to services that opted in with the annotation
+// lb.stackit.cloud/enabled=true. This allows migrating the load balancers of a cluster gradually.
+func (ccm *CloudControllerManager) SetLoadBalancerOptIn(optIn bool) {
+	ccm.loadBalancer.optIn = optIn
+}
+
+// SetLeading delays Initialize and thereby the start of all controllers until leading is closed, e.g. by
+// StartLeaderElection. The cloud-provider app serves /healthz in the meantime.
+func (ccm *CloudControllerManager) SetLeading(leading <-chan struct{}) {
+	ccm.leading = leading
+}
@@ -0,0 +1,74 @@
+package ccm
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/validation"
+)
+
+// ValidateCloudConfig checks the cloud-config at path like the cloud provider does on start.
+// Additionally, it checks the credentials and the referenced resources with read-only API calls.
+func ValidateCloudConfig(ctx context.Context, path string) *validation.Report {
+	r := &validation.Report{}
+
+	var cfg stackitconfig.CCMConfig
+	r.Check("cloud-config can be parsed", func() error {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		cfg, err = GetConfig(f)
+		return err
+	})
+	r.Check("required options are set", func() error {
+		return validateConfig(&cfg)
+	})
+	r.Check("observability environment variables are complete", func() error {
+		_, err := BuildObservability()
+		return err
+	})
+
+	var (
+		lbClient   stackitclient.LoadBalancingClient
+		iaasClient stackitclient.IaaSClient
+	)
+	r.Check("API clients can be created", func() (err error) {
+		lbClient, iaasClient, err = NewClients(&cfg)
+		return err
+	})
+	checkAPI(ctx, r, &cfg, lbClient, iaasClient)
+	return r
+}
+
+func checkAPI(
+	ctx context.Context,
+	r *validation.Report,
+	cfg *stackitconfig.CCMConfig,
+	lbClient stackitclient.LoadBalancingClient,
+	iaasClient stackitclient.IaaSClient,
+) {
+	validation.CheckProject(ctx, r, iaasClient, cfg.Global.ProjectID)
+	if cfg.LoadBalancer.NetworkID != "" {
+		validation.CheckNetwork(ctx, r, iaasClient, cfg.LoadBalancer.NetworkID)
+	}
+	for _, pool := range slices.Sorted(maps.Keys(cfg.LoadBalancer.NodePoolNetworks)) {
+		validation.CheckNetwork(ctx, r, iaasClient, cfg.LoadBalancer.NodePoolNetworks[pool])
+	}
+	if sgID := cfg.LoadBalancer.NodeSecurityGroupID; sgID != "" {
+		validation.CheckRead(r, fmt.Sprintf("node security group %q exists", sgID), "security group", func() error {
+			_, err := iaasClient.ListSecurityGroupRules(ctx, sgID)
+			return err
+		})
+	}
+	validation.CheckRead(r, "credentials can list load balancers", "load balancers", func() error {
+		_, err := lbClient.ListLoadBalancers(ctx)
+		return err
+	})
+}
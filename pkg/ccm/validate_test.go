@@ -0,0 +1,103 @@
+package ccm
+
+import (
+	"context"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/validation"
+	oapiError "github.com/stackitcloud/stackit-sdk-go/core/oapierror"
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("validateConfig", func() {
+	var cfg *stackitconfig.CCMConfig
+
+	BeforeEach(func() {
+		cfg = &stackitconfig.CCMConfig{
+			Global:       stackitconfig.GlobalOpts{ProjectID: "my-project", Region: "eu01", ClusterID: "my-cluster"},
+			LoadBalancer: stackitconfig.LoadBalancerOpts{NetworkID: "my-network"},
+		}
+	})
+
+	It("should accept a complete config", func() {
+		Expect(validateConfig(cfg)).To(Succeed())
+	})
+
+	It("should require the network ID", func() {
+		cfg.LoadBalancer.NetworkID = ""
+		Expect(validateConfig(cfg)).To(MatchError(ContainSubstring("networkId must be set")))
+	})
+
+	It("should not require the network ID if the networks of nodes are known", func() {
+		cfg.LoadBalancer.NetworkID = ""
+		cfg.LoadBalancer.NetworkAutoDetection = true
+		Expect(validateConfig(cfg)).To(Succeed())
+
+		cfg.LoadBalancer.NetworkAutoDetection = false
+		cfg.LoadBalancer.NodePoolNetworks = map[string]string{"pool-a": "network-a"}
+		Expect(validateConfig(cfg)).To(Succeed())
+	})
+
+	It("should reject an invalid cluster ID", func() {
+		cfg.Global.ClusterID = "My_Cluster"
+		Expect(validateConfig(cfg)).To(MatchError(ContainSubstring("clusterId must consist of")))
+	})
+
+	It("should reject negative rate limits of the API clients", func() {
+		cfg.LoadBalancer.APIClients.Write.RateLimit.QPS = -1
+		Expect(validateConfig(cfg)).To(MatchError(ContainSubstring("apiClients.write.rateLimit must not be negative")))
+	})
+})
+
+var _ = Describe("checkAPI", func() {
+	var (
+		ctx        context.Context
+		lbClient   *stackitclientmock.MockLoadBalancingClient
+		iaasClient *stackitclientmock.MockIaaSClient
+		cfg        *stackitconfig.CCMConfig
+		r          *validation.Report
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		ctrl := gomock.NewController(GinkgoT())
+		lbClient = stackitclientmock.NewMockLoadBalancingClient(ctrl)
+		iaasClient = stackitclientmock.NewMockIaaSClient(ctrl)
+		cfg = &stackitconfig.CCMConfig{
+			Global:       stackitconfig.GlobalOpts{ProjectID: "my-project", Region: "eu01"},
+			LoadBalancer: stackitconfig.LoadBalancerOpts{NetworkID: "my-network", NodeSecurityGroupID: "my-security-group"},
+		}
+		r = &validation.Report{}
+	})
+
+	It("should check the project, network, security group and load balancer access", func() {
+		iaasClient.EXPECT().GetProject(ctx).Return(&iaas.Project{}, nil)
+		iaasClient.EXPECT().GetNetwork(ctx, "my-network").Return(&iaas.Network{}, nil)
+		iaasClient.EXPECT().ListSecurityGroupRules(ctx, "my-security-group").Return(nil, nil)
+		lbClient.EXPECT().ListLoadBalancers(ctx).Return([]loadbalancer.LoadBalancer{}, nil)
+
+		checkAPI(ctx, r, cfg, lbClient, iaasClient)
+		Expect(r.Failed()).To(BeFalse())
+		Expect(r.Results).To(HaveLen(4))
+	})
+
+	It("should report a missing network and skip the remaining checks", func() {
+		iaasClient.EXPECT().GetProject(ctx).Return(&iaas.Project{}, nil)
+		iaasClient.EXPECT().GetNetwork(ctx, "my-network").Return(nil, &oapiError.GenericOpenAPIError{StatusCode: http.StatusNotFound})
+
+		checkAPI(ctx, r, cfg, lbClient, iaasClient)
+		Expect(r.Failed()).To(BeTrue())
+		Expect(r.Results).To(HaveExactElements(
+			HaveField("Err", BeNil()),
+			HaveField("Err", MatchError(ContainSubstring("network not found"))),
+			HaveField("Skipped", BeTrue()),
+			HaveField("Skipped", BeTrue()),
+		))
+	})
+})
@@ -0,0 +1,160 @@
+package blockstorage
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/klog/v2"
+
+	sharedcsi "github.com/stackitcloud/cloud-provider-stackit/pkg/csi"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/metrics"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/providerid"
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+)
+
+const (
+	// attachmentCapacityCheckInterval is the interval in which the attachments of nodes are checked.
+	attachmentCapacityCheckInterval = 5 * time.Minute
+
+	EventReasonNearAttachmentCapacity  = "NearVolumeAttachmentCapacity"
+	EventReasonAttachmentLimitExceeded = "VolumeAttachmentLimitExceeded"
+)
+
+// attachmentCapacity compares the volumes attached to the servers of nodes with the volume limit the nodes advertised
+// in NodeGetInfo. The limit is only computed when the node plugin starts, so volumes attached outside of Kubernetes
+// afterwards make the scheduler place pods on nodes that can't attach their volumes anymore.
+//
+// The attached volumes and limits are exported as metrics. An event is recorded on a node once it reaches the
+// threshold and once it has more volumes attached than its limit.
+type attachmentCapacity struct {
+	driver    *Driver
+	instance  stackitclient.IaaSClient
+	nodes     corelisters.NodeLister
+	csiNodes  storagelisters.CSINodeLister
+	threshold float64
+	// reported holds the reason of the last event recorded on each node, to record each event only once.
+	reported map[string]string
+	// exported holds the nodes whose metrics are exported, to delete them once a node is gone.
+	exported map[string]bool
+}
+
+func newAttachmentCapacity(d *Driver, instance stackitclient.IaaSClient, opts *sharedcsi.AttachmentCapacity) *attachmentCapacity {
+	return &attachmentCapacity{
+		driver:    d,
+		instance:  instance,
+		nodes:     opts.Informers.Core().V1().Nodes().Lister(),
+		csiNodes:  opts.Informers.Storage().V1().CSINodes().Lister(),
+		threshold: opts.Threshold,
+		reported:  map[string]string{},
+		exported:  map[string]bool{},
+	}
+}
+
+// reconcile checks the attachments of all nodes with a volume limit.
+func (c *attachmentCapacity) reconcile(ctx context.Context) {
+	nodes, err := c.nodes.List(labels.Everything())
+	if err != nil {
+		klog.ErrorS(err, "Failed to list nodes for attachment capacity check")
+		return
+	}
+	servers, err := c.instance.ListServers(ctx)
+	if err != nil {
+		klog.ErrorS(err, "Failed to list servers for attachment capacity check")
+		return
+	}
+	serversByID := make(map[string]*iaas.Server, len(*servers))
+	for i := range *servers {
+		serversByID[(*servers)[i].GetId()] = &(*servers)[i]
+	}
+
+	checked := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		serverID, err := providerid.ServerID(node.Spec.ProviderID)
+		if err != nil {
+			continue
+		}
+		server, ok := serversByID[serverID]
+		if !ok {
+			continue
+		}
+		limit, ok := c.volumeLimit(node.Name)
+		if !ok {
+			continue
+		}
+		checked[node.Name] = true
+		c.check(node, attachedVolumes(server), limit)
+	}
+
+	for name := range c.exported {
+		if !checked[name] {
+			metrics.CSINodeAttachedVolumes.DeleteLabelValues(name)
+			metrics.CSINodeVolumeLimit.DeleteLabelValues(name)
+			delete(c.exported, name)
+			delete(c.reported, name)
+		}
+	}
+}
+
+// check exports the attachments of the node and records an event if it is near or over its limit.
+func (c *attachmentCapacity) check(node *corev1.Node, attached, limit int) {
+	metrics.CSINodeAttachedVolumes.WithLabelValues(node.Name).Set(float64(attached))
+	metrics.CSINodeVolumeLimit.WithLabelValues(node.Name).Set(float64(limit))
+	c.exported[node.Name] = true
+
+	var reason, message string
+	switch {
+	case attached > limit:
+		reason = EventReasonAttachmentLimitExceeded
+		message = "The server of the node has %d volumes attached, more than the limit of %d the node advertises. " +
+			"Restart the CSI node plugin to update the limit."
+	case float64(attached) >= c.threshold*float64(limit):
+		reason = EventReasonNearAttachmentCapacity
+		message = "The server of the node has %d of %d volumes attached."
+	}
+	if reason == c.reported[node.Name] {
+		return
+	}
+	if reason == "" {
+		delete(c.reported, node.Name)
+		return
+	}
+	c.reported[node.Name] = reason
+
+	klog.InfoS("Node is near or over its volume limit", "node", klog.KObj(node), "attached", attached, "limit", limit, "reason", reason)
+	if c.driver.recorder == nil {
+		return
+	}
+	ref := &corev1.ObjectReference{Kind: "Node", Name: node.Name, UID: node.UID}
+	c.driver.recorder.Eventf(ref, corev1.EventTypeWarning, reason, message, attached, limit)
+}
+
+// volumeLimit returns the volume limit the node advertises for the driver in its CSINode.
+func (c *attachmentCapacity) volumeLimit(nodeName string) (int, bool) {
+	csiNode, err := c.csiNodes.Get(nodeName)
+	if err != nil {
+		klog.V(4).InfoS("Failed to get CSINode", "node", nodeName, "err", err)
+		return 0, false
+	}
+	for _, driver := range csiNode.Spec.Drivers {
+		if driver.Name == c.driver.name && driver.Allocatable != nil && driver.Allocatable.Count != nil {
+			return int(*driver.Allocatable.Count), true
+		}
+	}
+	return 0, false
+}
+
+// attachedVolumes returns the number of volumes attached to the server, without its boot volume, which is not
+// counted in the limit the node plugin advertises.
+func attachedVolumes(server *iaas.Server) int {
+	attached := len(server.Volumes)
+	if server.BootVolume != nil && slices.Contains(server.Volumes, server.BootVolume.GetId()) {
+		attached--
+	}
+	return attached
+}
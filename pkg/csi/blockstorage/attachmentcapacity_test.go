@@ -0,0 +1,115 @@
+package blockstorage
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/metrics"
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+)
+
+var _ = Describe("attachmentCapacity", func() {
+	var (
+		iaasClient *stackitclientmock.MockIaaSClient
+		recorder   *record.FakeRecorder
+		capacity   *attachmentCapacity
+		nodes      cache.Indexer
+		server     iaas.Server
+	)
+
+	BeforeEach(func() {
+		iaasClient = stackitclientmock.NewMockIaaSClient(gomock.NewController(GinkgoT()))
+		recorder = record.NewFakeRecorder(10)
+
+		nodes = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		Expect(nodes.Add(&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+			Spec:       corev1.NodeSpec{ProviderID: "stackit:///server-1"},
+		})).To(Succeed())
+		csiNodes := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		Expect(csiNodes.Add(&storagev1.CSINode{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+			Spec: storagev1.CSINodeSpec{Drivers: []storagev1.CSINodeDriver{
+				{Name: "other.csi.k8s.io", Allocatable: &storagev1.VolumeNodeResources{Count: new(int32(100))}},
+				{Name: driverName, Allocatable: &storagev1.VolumeNodeResources{Count: new(int32(4))}},
+			}},
+		})).To(Succeed())
+
+		server = iaas.Server{
+			Id:         new("server-1"),
+			BootVolume: &iaas.BootVolume{Id: new("boot")},
+			Volumes:    []string{"boot", "volume-1", "volume-2"},
+		}
+
+		d := NewDriver(&DriverOpts{EventRecorder: recorder})
+		capacity = &attachmentCapacity{
+			driver:    d,
+			instance:  iaasClient,
+			nodes:     corelisters.NewNodeLister(nodes),
+			csiNodes:  storagelisters.NewCSINodeLister(csiNodes),
+			threshold: 0.75,
+			reported:  map[string]string{},
+			exported:  map[string]bool{},
+		}
+	})
+
+	reconcile := func() {
+		iaasClient.EXPECT().ListServers(gomock.Any()).Return(&[]iaas.Server{server}, nil)
+		capacity.reconcile(context.Background())
+	}
+
+	It("should export the attached volumes without the boot volume and the limit", func() {
+		reconcile()
+
+		Expect(testutil.ToFloat64(metrics.CSINodeAttachedVolumes.WithLabelValues("node-1"))).To(Equal(2.0))
+		Expect(testutil.ToFloat64(metrics.CSINodeVolumeLimit.WithLabelValues("node-1"))).To(Equal(4.0))
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("should record an event once a node reaches the threshold", func() {
+		server.Volumes = append(server.Volumes, "volume-3")
+		reconcile()
+		reconcile()
+
+		Expect(recorder.Events).To(HaveLen(1))
+		Expect(<-recorder.Events).To(Equal("Warning NearVolumeAttachmentCapacity The server of the node has 3 of 4 volumes attached."))
+	})
+
+	It("should record an event if a node has more volumes attached than its limit", func() {
+		server.Volumes = append(server.Volumes, "volume-3", "volume-4", "volume-5")
+		reconcile()
+
+		Expect(<-recorder.Events).To(HavePrefix("Warning VolumeAttachmentLimitExceeded The server of the node has 5 volumes attached"))
+	})
+
+	It("should record the event again after the node went below the threshold", func() {
+		server.Volumes = append(server.Volumes, "volume-3")
+		reconcile()
+		server.Volumes = server.Volumes[:3]
+		reconcile()
+		server.Volumes = append(server.Volumes, "volume-3")
+		reconcile()
+
+		Expect(recorder.Events).To(HaveLen(2))
+	})
+
+	It("should delete the metrics of nodes that are gone", func() {
+		reconcile()
+		Expect(nodes.Delete(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}})).To(Succeed())
+		reconcile()
+
+		Expect(testutil.CollectAndCount(metrics.CSINodeAttachedVolumes)).To(Equal(0))
+	})
+})
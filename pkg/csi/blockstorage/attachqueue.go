@@ -0,0 +1,177 @@
+package blockstorage
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// maxConcurrentAttachesPerNode limits the attach calls that are in flight for the same server.
+	maxConcurrentAttachesPerNode = 4
+
+	attachPollInitDelay = 1 * time.Second
+	attachPollFactor    = 1.2
+	attachPollMaxDelay  = 10 * time.Second
+)
+
+// attachQueue limits the concurrent attach calls per server and batches waiting for the attachments.
+// When many pods are scheduled onto a new node, the volumes are attached concurrently. Instead of polling every
+// volume separately, a single poller per server gets the server and completes the waits of all volumes that are
+// attached to it.
+type attachQueue struct {
+	cloud stackitclient.IaaSClient
+
+	concurrency int
+	initDelay   time.Duration
+	factor      float64
+	maxDelay    time.Duration
+	waitTimeout time.Duration
+
+	mu    sync.Mutex
+	nodes map[string]*nodeAttachQueue
+}
+
+type nodeAttachQueue struct {
+	slots chan struct{}
+	// attaching is the number of callers that hold or wait for a slot.
+	attaching int
+	// waiters are notified once the volume is attached or polling the server fails.
+	waiters map[string][]chan error
+	polling bool
+}
+
+// newAttachQueue returns a queue that polls with the delay and factor of the backoff. Its steps don't apply, the
+// waits are bounded by the wait timeout, which defaults to stackitclient.DefaultWaitTimeout if it is 0.
+func newAttachQueue(cloud stackitclient.IaaSClient, backoff wait.Backoff, waitTimeout time.Duration) *attachQueue {
+	if waitTimeout == 0 {
+		waitTimeout = stackitclient.DefaultWaitTimeout
+	}
+	return &attachQueue{
+		cloud:       cloud,
+		concurrency: maxConcurrentAttachesPerNode,
+		initDelay:   backoff.Duration,
+		factor:      backoff.Factor,
+		maxDelay:    max(attachPollMaxDelay, backoff.Duration),
+		waitTimeout: waitTimeout,
+		nodes:       map[string]*nodeAttachQueue{},
+	}
+}
+
+// node returns the queue of the server. The caller must hold q.mu.
+func (q *attachQueue) node(instanceID string) *nodeAttachQueue {
+	n, ok := q.nodes[instanceID]
+	if !ok {
+		n = &nodeAttachQueue{
+			slots:   make(chan struct{}, q.concurrency),
+			waiters: map[string][]chan error{},
+		}
+		q.nodes[instanceID] = n
+	}
+	return n
+}
+
+// release forgets the queue of the server once it is idle. The caller must hold q.mu.
+func (q *attachQueue) release(instanceID string, n *nodeAttachQueue) {
+	if n.attaching == 0 && !n.polling && len(n.waiters) == 0 {
+		delete(q.nodes, instanceID)
+	}
+}
+
+// Attach attaches the volume to the server. At most q.concurrency attach calls are in flight per server.
+func (q *attachQueue) Attach(ctx context.Context, instanceID, volumeID string, payload iaas.AddVolumeToServerPayload) error {
+	q.mu.Lock()
+	n := q.node(instanceID)
+	n.attaching++
+	q.mu.Unlock()
+
+	defer func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		n.attaching--
+		q.release(instanceID, n)
+	}()
+
+	select {
+	case n.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-n.slots }()
+
+	_, err := q.cloud.AttachVolume(ctx, instanceID, volumeID, payload)
+	return err
+}
+
+// WaitAttached waits until the volume is attached to the server, ctx is done or the wait timeout expires.
+func (q *attachQueue) WaitAttached(ctx context.Context, instanceID, volumeID string) error {
+	ctx, cancel := context.WithTimeout(ctx, q.waitTimeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	q.mu.Lock()
+	n := q.node(instanceID)
+	n.waiters[volumeID] = append(n.waiters[volumeID], done)
+	if !n.polling {
+		n.polling = true
+		go q.poll(instanceID, n)
+	}
+	q.mu.Unlock()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		n.waiters[volumeID] = slices.DeleteFunc(n.waiters[volumeID], func(c chan error) bool { return c == done })
+		if len(n.waiters[volumeID]) == 0 {
+			delete(n.waiters, volumeID)
+		}
+		return fmt.Errorf("volume %q failed to be attached within the allowed time: %w", volumeID, ctx.Err())
+	}
+}
+
+// poll gets the server with increasing delays until no more volumes are waited for.
+func (q *attachQueue) poll(instanceID string, n *nodeAttachQueue) {
+	delay := q.initDelay
+	for {
+		time.Sleep(delay)
+		delay = min(time.Duration(float64(delay)*q.factor), q.maxDelay)
+
+		q.mu.Lock()
+		if len(n.waiters) == 0 {
+			n.polling = false
+			q.release(instanceID, n)
+			q.mu.Unlock()
+			return
+		}
+		q.mu.Unlock()
+
+		// The request timeout of the client bounds the call, the waiters are bounded by their own contexts.
+		server, err := q.cloud.GetServer(context.Background(), instanceID)
+
+		q.mu.Lock()
+		for volumeID, waiters := range n.waiters {
+			if err == nil && !slices.Contains(server.Volumes, volumeID) {
+				continue
+			}
+			for _, done := range waiters {
+				done <- err
+			}
+			delete(n.waiters, volumeID)
+		}
+		q.mu.Unlock()
+
+		if err != nil {
+			klog.ErrorS(err, "Failed to get server while waiting for volumes to be attached", "instanceID", instanceID)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package blockstorage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("attachQueue", func() {
+	const instanceID = "server-1"
+
+	var (
+		iaasClient *stackitclientmock.MockIaaSClient
+		q          *attachQueue
+	)
+
+	BeforeEach(func() {
+		iaasClient = stackitclientmock.NewMockIaaSClient(gomock.NewController(GinkgoT()))
+		q = newAttachQueue(iaasClient, defaultAttachBackoff, 0)
+		q.initDelay = 50 * time.Millisecond
+		q.maxDelay = 50 * time.Millisecond
+	})
+
+	waitAll := func(volumeIDs ...string) []error {
+		errs := make([]error, len(volumeIDs))
+		var wg sync.WaitGroup
+		for i, volumeID := range volumeIDs {
+			wg.Go(func() {
+				errs[i] = q.WaitAttached(context.Background(), instanceID, volumeID)
+			})
+		}
+		wg.Wait()
+		return errs
+	}
+
+	It("should wait for all volumes of a server with a single get", func() {
+		iaasClient.EXPECT().GetServer(gomock.Any(), instanceID).
+			Return(&iaas.Server{Volumes: []string{"vol-a", "vol-b", "vol-c"}}, nil)
+
+		Expect(waitAll("vol-a", "vol-b", "vol-c")).To(HaveEach(Succeed()))
+		Eventually(func() int {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			return len(q.nodes)
+		}).Should(BeZero())
+	})
+
+	It("should keep polling until all volumes are attached", func() {
+		gomock.InOrder(
+			iaasClient.EXPECT().GetServer(gomock.Any(), instanceID).
+				Return(&iaas.Server{Volumes: []string{"vol-a"}}, nil),
+			iaasClient.EXPECT().GetServer(gomock.Any(), instanceID).
+				Return(&iaas.Server{Volumes: []string{"vol-a", "vol-b"}}, nil),
+		)
+
+		Expect(waitAll("vol-a", "vol-b")).To(HaveEach(Succeed()))
+	})
+
+	It("should fail all waiters if the server can't be fetched", func() {
+		iaasClient.EXPECT().GetServer(gomock.Any(), instanceID).Return(nil, errors.New("injected error"))
+
+		Expect(waitAll("vol-a", "vol-b")).To(HaveEach(MatchError("injected error")))
+	})
+
+	It("should give up after the wait timeout", func() {
+		q.waitTimeout = 100 * time.Millisecond
+		iaasClient.EXPECT().GetServer(gomock.Any(), instanceID).Return(&iaas.Server{}, nil).AnyTimes()
+
+		err := q.WaitAttached(context.Background(), instanceID, "vol-a")
+		Expect(err).To(MatchError(ContainSubstring("failed to be attached within the allowed time")))
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+
+	It("should use the configured wait timeout", func() {
+		Expect(newAttachQueue(iaasClient, defaultAttachBackoff, time.Minute).waitTimeout).To(Equal(time.Minute))
+		Expect(q.waitTimeout).To(Equal(stackitclient.DefaultWaitTimeout))
+	})
+
+	It("should limit the concurrent attach calls per server", func() {
+		q.concurrency = 2
+		var inFlight, maxInFlight atomic.Int32
+		release := make(chan struct{})
+		iaasClient.EXPECT().AttachVolume(gomock.Any(), instanceID, gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, _, volumeID string, _ iaas.AddVolumeToServerPayload) (string, error) {
+				current := inFlight.Add(1)
+				defer inFlight.Add(-1)
+				for {
+					previous := maxInFlight.Load()
+					if current <= previous || maxInFlight.CompareAndSwap(previous, current) {
+						break
+					}
+				}
+				<-release
+				return volumeID, nil
+			}).Times(5)
+
+		var wg sync.WaitGroup
+		for _, volumeID := range []string{"vol-a", "vol-b", "vol-c", "vol-d", "vol-e"} {
+			wg.Go(func() {
+				defer GinkgoRecover()
+				Expect(q.Attach(context.Background(), instanceID, volumeID, iaas.AddVolumeToServerPayload{})).To(Succeed())
+			})
+		}
+
+		Eventually(inFlight.Load).Should(BeNumerically("==", 2))
+		Consistently(inFlight.Load, 100*time.Millisecond).Should(BeNumerically("<=", 2))
+		close(release)
+		wg.Wait()
+		Expect(maxInFlight.Load()).To(BeNumerically("==", 2))
+	})
+})
@@ -0,0 +1,325 @@
+package blockstorage
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/metrics"
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+)
+
+const (
+	// BackupScheduleAnnotation is the interval between scheduled backups of a PVC, e.g. "24h".
+	// It can be set on the PVC or its StorageClass, the annotation of the PVC takes precedence.
+	BackupScheduleAnnotation = "backup.csi.stackit.cloud/schedule"
+	// BackupRetentionAnnotation is the number of scheduled backups that are kept per PVC.
+	BackupRetentionAnnotation = "backup.csi.stackit.cloud/retention"
+
+	// scheduledBackupLabel marks the backups created by the backup scheduler, only these are pruned.
+	scheduledBackupLabel = "scheduled-backup"
+
+	defaultBackupRetention = 7
+	// minBackupInterval prevents schedules that would create a backup on every check.
+	minBackupInterval = time.Hour
+	// backupScheduleCheckInterval is the interval in which the PVCs are checked for due backups.
+	backupScheduleCheckInterval = 5 * time.Minute
+
+	EventReasonScheduledBackupCreated = "ScheduledBackupCreated"
+	EventReasonScheduledBackupPruned  = "ScheduledBackupPruned"
+	EventReasonScheduledBackupFailed  = "ScheduledBackupFailed"
+)
+
+// backupSchedule is the schedule of a PVC parsed from its annotations.
+type backupSchedule struct {
+	interval  time.Duration
+	retention int
+}
+
+// parseBackupSchedule returns the backup schedule of the PVC, or nil if no schedule is configured.
+// Annotations of the PVC take precedence over the ones of the StorageClass, which may be nil.
+func parseBackupSchedule(pvc *corev1.PersistentVolumeClaim, sc *storagev1.StorageClass) (*backupSchedule, error) {
+	annotation := func(key string) string {
+		if value, ok := pvc.Annotations[key]; ok {
+			return value
+		}
+		if sc != nil {
+			return sc.Annotations[key]
+		}
+		return ""
+	}
+
+	interval := annotation(BackupScheduleAnnotation)
+	if interval == "" {
+		return nil, nil
+	}
+	schedule := &backupSchedule{retention: defaultBackupRetention}
+
+	var err error
+	schedule.interval, err = time.ParseDuration(interval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s annotation %q: %w", BackupScheduleAnnotation, interval, err)
+	}
+	if schedule.interval < minBackupInterval {
+		return nil, fmt.Errorf("invalid %s annotation %q: must be at least %s", BackupScheduleAnnotation, interval, minBackupInterval)
+	}
+
+	if retention := annotation(BackupRetentionAnnotation); retention != "" {
+		schedule.retention, err = strconv.Atoi(retention)
+		if err != nil || schedule.retention < 1 {
+			return nil, fmt.Errorf("invalid %s annotation %q: must be a positive number", BackupRetentionAnnotation, retention)
+		}
+	}
+	return schedule, nil
+}
+
+// backupScheduler creates backups of PVCs in the interval configured by their annotations and prunes the
+// scheduled backups exceeding the retention. Backups are created asynchronously, the scheduler doesn't wait until
+// they are available.
+type backupScheduler struct {
+	driver         *Driver
+	instance       stackitclient.IaaSClient
+	pvcs           corelisters.PersistentVolumeClaimLister
+	pvs            corelisters.PersistentVolumeLister
+	storageClasses storagelisters.StorageClassLister
+	// snapshots limits the concurrent snapshot operations together with CreateSnapshot, nil if they are not limited.
+	snapshots *snapshotLimiter
+	// cs finds the restores of backups, which are not pruned until the restore has completed.
+	cs  *controllerServer
+	now func() time.Time
+	// exported holds the PVCs whose metrics are exported, to delete them once a PVC is gone or has no schedule.
+	exported map[types.NamespacedName]bool
+	// invalid holds the generation and error of the invalid schedule last reported for each PVC, to record the event
+	// only once.
+	invalid map[types.UID]string
+}
+
+func newBackupScheduler(d *Driver, instance stackitclient.IaaSClient, factory informers.SharedInformerFactory) *backupScheduler {
+	return &backupScheduler{
+		driver:         d,
+		instance:       instance,
+		pvcs:           factory.Core().V1().PersistentVolumeClaims().Lister(),
+		pvs:            factory.Core().V1().PersistentVolumes().Lister(),
+		storageClasses: factory.Storage().V1().StorageClasses().Lister(),
+		snapshots:      d.cs.snapshots,
+		cs:             d.cs,
+		now:            time.Now,
+		exported:       map[types.NamespacedName]bool{},
+		invalid:        map[types.UID]string{},
+	}
+}
+
+// reconcile creates the due backups and prunes old backups of all PVCs with a backup schedule.
+func (s *backupScheduler) reconcile(ctx context.Context) {
+	pvcs, err := s.pvcs.List(labels.Everything())
+	if err != nil {
+		klog.ErrorS(err, "Failed to list PVCs for scheduled backups")
+		return
+	}
+
+	var backupsByVolume map[string][]iaas.Backup
+	scheduled := make(map[types.NamespacedName]bool, len(pvcs))
+	existing := make(map[types.UID]bool, len(pvcs))
+	for _, pvc := range pvcs {
+		existing[pvc.UID] = true
+		volumeID, schedule := s.scheduleOf(pvc)
+		if schedule == nil {
+			continue
+		}
+
+		// The backups are listed once per run and only if any PVC has a schedule.
+		if backupsByVolume == nil {
+			backupsByVolume, err = s.listScheduledBackups(ctx)
+			if err != nil {
+				klog.ErrorS(err, "Failed to list scheduled backups")
+				return
+			}
+		}
+		s.reconcilePVC(ctx, pvc, volumeID, schedule, backupsByVolume[volumeID])
+		scheduled[types.NamespacedName{Namespace: pvc.Namespace, Name: pvc.Name}] = true
+	}
+
+	for key := range s.exported {
+		if !scheduled[key] {
+			metrics.CSIScheduledBackupLastSuccess.DeleteLabelValues(key.Namespace, key.Name)
+			metrics.CSIScheduledBackupFailures.DeleteLabelValues(key.Namespace, key.Name)
+			metrics.CSIScheduledBackups.DeleteLabelValues(key.Namespace, key.Name)
+			delete(s.exported, key)
+		}
+	}
+	for uid := range s.invalid {
+		if !existing[uid] {
+			delete(s.invalid, uid)
+		}
+	}
+}
+
+// scheduleOf returns the volume ID and backup schedule of a bound PVC provisioned by this driver.
+// The schedule is nil if the PVC has no schedule or it is invalid.
+func (s *backupScheduler) scheduleOf(pvc *corev1.PersistentVolumeClaim) (string, *backupSchedule) {
+	if pvc.DeletionTimestamp != nil || pvc.Status.Phase != corev1.ClaimBound || pvc.Spec.VolumeName == "" {
+		return "", nil
+	}
+
+	var sc *storagev1.StorageClass
+	if className := pvc.Spec.StorageClassName; className != nil && *className != "" {
+		var err error
+		sc, err = s.storageClasses.Get(*className)
+		if err != nil {
+			klog.V(4).InfoS("Failed to get StorageClass of PVC", "pvc", klog.KObj(pvc), "storageClass", *className, "err", err)
+		}
+	}
+	schedule, err := parseBackupSchedule(pvc, sc)
+	if err != nil {
+		// Changing the annotations doesn't increase the generation, so a different error is reported as well.
+		if reported := fmt.Sprintf("%d/%v", pvc.Generation, err); s.invalid[pvc.UID] != reported {
+			klog.ErrorS(err, "Ignoring backup schedule", "pvc", klog.KObj(pvc))
+			s.eventf(pvc, corev1.EventTypeWarning, EventReasonScheduledBackupFailed, "Ignoring backup schedule: %v", err)
+			s.invalid[pvc.UID] = reported
+		}
+		return "", nil
+	}
+	delete(s.invalid, pvc.UID)
+	if schedule == nil {
+		return "", nil
+	}
+
+	pv, err := s.pvs.Get(pvc.Spec.VolumeName)
+	if err != nil {
+		klog.V(4).InfoS("Failed to get PV of PVC", "pvc", klog.KObj(pvc), "pv", pvc.Spec.VolumeName, "err", err)
+		return "", nil
+	}
+	if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != s.driver.name {
+		return "", nil
+	}
+	return pv.Spec.CSI.VolumeHandle, schedule
+}
+
+// listScheduledBackups returns the backups created by the scheduler grouped by volume ID.
+func (s *backupScheduler) listScheduledBackups(ctx context.Context) (map[string][]iaas.Backup, error) {
+	backups, err := s.instance.ListBackups(ctx, map[string]string{stackitclient.LabelFilter(scheduledBackupLabel): "true"})
+	if err != nil {
+		return nil, err
+	}
+	backupsByVolume := make(map[string][]iaas.Backup)
+	for _, backup := range backups {
+		backupsByVolume[backup.GetVolumeId()] = append(backupsByVolume[backup.GetVolumeId()], backup)
+	}
+	return backupsByVolume, nil
+}
+
+// reconcilePVC creates a backup of the volume if the newest scheduled backup is older than the interval and
+// deletes failed backups and the backups exceeding the retention.
+func (s *backupScheduler) reconcilePVC(ctx context.Context, pvc *corev1.PersistentVolumeClaim, volumeID string, schedule *backupSchedule, backups []iaas.Backup) {
+	// Newest backups first.
+	slices.SortFunc(backups, func(a, b iaas.Backup) int {
+		return b.GetCreatedAt().Compare(a.GetCreatedAt())
+	})
+
+	var kept, prune []iaas.Backup
+	for _, backup := range backups {
+		if strings.EqualFold(backup.GetStatus(), "error") {
+			prune = append(prune, backup)
+			continue
+		}
+		kept = append(kept, backup)
+	}
+
+	now := s.now()
+	if len(kept) == 0 || now.Sub(kept[0].GetCreatedAt()) >= schedule.interval {
+		backup, err := s.createBackup(ctx, pvc, volumeID, now)
+		if err != nil {
+			klog.ErrorS(err, "Failed to create scheduled backup", "pvc", klog.KObj(pvc), "volumeID", volumeID)
+			metrics.CSIScheduledBackupFailures.WithLabelValues(pvc.Namespace, pvc.Name).Inc()
+			s.eventf(pvc, corev1.EventTypeWarning, EventReasonScheduledBackupFailed, "Failed to create scheduled backup of volume %s: %v", volumeID, err)
+		} else {
+			klog.InfoS("Created scheduled backup", "pvc", klog.KObj(pvc), "volumeID", volumeID, "backupID", backup.GetId())
+			metrics.CSIScheduledBackupLastSuccess.WithLabelValues(pvc.Namespace, pvc.Name).Set(float64(now.Unix()))
+			s.eventf(pvc, corev1.EventTypeNormal, EventReasonScheduledBackupCreated, "Created scheduled backup %s of volume %s", backup.GetId(), volumeID)
+			kept = slices.Insert(kept, 0, *backup)
+		}
+	}
+
+	if len(kept) > schedule.retention {
+		prune = append(prune, kept[schedule.retention:]...)
+		kept = kept[:schedule.retention]
+	}
+	prune, inUse := s.withoutRestores(ctx, pvc, prune)
+	for _, backup := range prune {
+		if err := s.instance.DeleteBackup(ctx, backup.GetId()); err != nil {
+			klog.ErrorS(err, "Failed to delete scheduled backup", "pvc", klog.KObj(pvc), "backupID", backup.GetId())
+			metrics.CSIScheduledBackupFailures.WithLabelValues(pvc.Namespace, pvc.Name).Inc()
+			s.eventf(pvc, corev1.EventTypeWarning, EventReasonScheduledBackupFailed, "Failed to delete scheduled backup %s: %v", backup.GetId(), err)
+			continue
+		}
+		klog.InfoS("Deleted scheduled backup", "pvc", klog.KObj(pvc), "backupID", backup.GetId(), "status", backup.GetStatus())
+		s.eventf(pvc, corev1.EventTypeNormal, EventReasonScheduledBackupPruned, "Deleted scheduled backup %s, %d backups are retained", backup.GetId(), schedule.retention)
+	}
+	metrics.CSIScheduledBackups.WithLabelValues(pvc.Namespace, pvc.Name).Set(float64(len(kept) + inUse))
+	s.exported[types.NamespacedName{Namespace: pvc.Namespace, Name: pvc.Name}] = true
+}
+
+// withoutRestores returns the backups that can be pruned and the number of backups that are skipped because a volume
+// is still restored from them, like in DeleteSnapshot. No backups are pruned if the volumes can't be listed.
+func (s *backupScheduler) withoutRestores(ctx context.Context, pvc *corev1.PersistentVolumeClaim, prune []iaas.Backup) ([]iaas.Backup, int) {
+	if len(prune) == 0 {
+		return nil, 0
+	}
+	vols, _, err := s.instance.ListVolumes(ctx, 0, "")
+	if err != nil {
+		klog.ErrorS(err, "Failed to list volumes, not pruning scheduled backups", "pvc", klog.KObj(pvc))
+		return nil, len(prune)
+	}
+	var inUse int
+	prune = slices.DeleteFunc(prune, func(backup iaas.Backup) bool {
+		volumes := s.cs.restoresOf(backup.GetId(), vols)
+		if len(volumes) == 0 {
+			return false
+		}
+		klog.InfoS("Not pruning scheduled backup in use by restore", "pvc", klog.KObj(pvc), "backupID", backup.GetId(), "volumes", volumes)
+		inUse++
+		return true
+	})
+	return prune, inUse
+}
+
+func (s *backupScheduler) createBackup(ctx context.Context, pvc *corev1.PersistentVolumeClaim, volumeID string, now time.Time) (*iaas.Backup, error) {
+	tags := map[string]string{
+		scheduledBackupLabel: "true",
+		pvcNamespaceLabel:    pvc.Namespace,
+	}
+	if len(pvc.Name) <= maxLabelValueLength {
+		tags[pvcNameLabel] = pvc.Name
+	}
+	if s.driver.resourceLabels != nil {
+		tags = stackitclient.WithResourceLabels(tags, s.driver.resourceLabels)
+		tags[stackitclient.PVCUIDLabel] = string(pvc.UID)
+	}
+	name := fmt.Sprintf("%s-%s", pvc.Spec.VolumeName, now.UTC().Format("20060102-150405"))
+	release, err := s.snapshots.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return s.instance.CreateBackup(ctx, name, volumeID, "", tags)
+}
+
+func (s *backupScheduler) eventf(pvc *corev1.PersistentVolumeClaim, eventType, reason, messageFmt string, args ...any) {
+	if s.driver.recorder == nil {
+		return
+	}
+	s.driver.recorder.Eventf(pvc, eventType, reason, messageFmt, args...)
+}
@@ -0,0 +1,255 @@
+package blockstorage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/metrics"
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+)
+
+var _ = DescribeTable("parseBackupSchedule",
+	func(pvcAnnotations, scAnnotations map[string]string, expected *backupSchedule, expectErr bool) {
+		pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Annotations: pvcAnnotations}}
+		var sc *storagev1.StorageClass
+		if scAnnotations != nil {
+			sc = &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Annotations: scAnnotations}}
+		}
+
+		schedule, err := parseBackupSchedule(pvc, sc)
+		if expectErr {
+			Expect(err).To(HaveOccurred())
+			return
+		}
+		Expect(err).NotTo(HaveOccurred())
+		Expect(schedule).To(Equal(expected))
+	},
+	Entry("no schedule", nil, map[string]string{BackupRetentionAnnotation: "3"}, nil, false),
+	Entry("schedule of the PVC with default retention",
+		map[string]string{BackupScheduleAnnotation: "24h"}, nil,
+		&backupSchedule{interval: 24 * time.Hour, retention: defaultBackupRetention}, false),
+	Entry("schedule of the StorageClass",
+		nil, map[string]string{BackupScheduleAnnotation: "12h", BackupRetentionAnnotation: "3"},
+		&backupSchedule{interval: 12 * time.Hour, retention: 3}, false),
+	Entry("PVC annotations take precedence",
+		map[string]string{BackupRetentionAnnotation: "14"}, map[string]string{BackupScheduleAnnotation: "24h", BackupRetentionAnnotation: "3"},
+		&backupSchedule{interval: 24 * time.Hour, retention: 14}, false),
+	Entry("PVC can disable the schedule of the StorageClass",
+		map[string]string{BackupScheduleAnnotation: ""}, map[string]string{BackupScheduleAnnotation: "24h"}, nil, false),
+	Entry("invalid interval", map[string]string{BackupScheduleAnnotation: "daily"}, nil, nil, true),
+	Entry("too short interval", map[string]string{BackupScheduleAnnotation: "5m"}, nil, nil, true),
+	Entry("invalid retention", map[string]string{BackupScheduleAnnotation: "24h", BackupRetentionAnnotation: "0"}, nil, nil, true),
+)
+
+var _ = Describe("backupScheduler", func() {
+	var (
+		iaasClient *stackitclientmock.MockIaaSClient
+		recorder   *record.FakeRecorder
+		scheduler  *backupScheduler
+		pvcs, pvs  cache.Indexer
+		now        time.Time
+		pvc        *corev1.PersistentVolumeClaim
+	)
+
+	backup := func(id string, age time.Duration, status string) iaas.Backup {
+		return iaas.Backup{
+			Id:        new(id),
+			VolumeId:  new("volume-id"),
+			Status:    new(status),
+			CreatedAt: new(now.Add(-age)),
+			Labels:    map[string]any{scheduledBackupLabel: "true"},
+		}
+	}
+
+	BeforeEach(func() {
+		now = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+		iaasClient = stackitclientmock.NewMockIaaSClient(gomock.NewController(GinkgoT()))
+		recorder = record.NewFakeRecorder(10)
+
+		pvcs = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		pvs = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		storageClasses := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		Expect(storageClasses.Add(&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{
+			Name:        "daily",
+			Annotations: map[string]string{BackupScheduleAnnotation: "24h", BackupRetentionAnnotation: "2"},
+		}})).To(Succeed())
+
+		driver := NewDriver(&DriverOpts{EventRecorder: recorder})
+		scheduler = &backupScheduler{
+			driver:         driver,
+			instance:       iaasClient,
+			pvcs:           corelisters.NewPersistentVolumeClaimLister(pvcs),
+			pvs:            corelisters.NewPersistentVolumeLister(pvs),
+			storageClasses: storagelisters.NewStorageClassLister(storageClasses),
+			cs:             NewControllerServer(driver, iaasClient, stackitconfig.BlockStorageOpts{}),
+			now:            func() time.Time { return now },
+			exported:       map[types.NamespacedName]bool{},
+			invalid:        map[types.UID]string{},
+		}
+
+		pvc = &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "data"},
+			Spec:       corev1.PersistentVolumeClaimSpec{StorageClassName: new("daily"), VolumeName: "pv-1"},
+			Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+		}
+		Expect(pvs.Add(&corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-1"},
+			Spec: corev1.PersistentVolumeSpec{PersistentVolumeSource: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{Driver: driverName, VolumeHandle: "volume-id"},
+			}},
+		})).To(Succeed())
+	})
+
+	It("should create the first backup of a PVC", func() {
+		Expect(pvcs.Add(pvc)).To(Succeed())
+		iaasClient.EXPECT().ListBackups(gomock.Any(), map[string]string{stackitclient.LabelFilter(scheduledBackupLabel): "true"}).Return(nil, nil)
+		iaasClient.EXPECT().CreateBackup(gomock.Any(), "pv-1-20260102-030405", "volume-id", "", map[string]string{
+			scheduledBackupLabel: "true",
+			pvcNamespaceLabel:    "default",
+			pvcNameLabel:         "data",
+		}).Return(new(backup("new", 0, "CREATING")), nil)
+
+		scheduler.reconcile(context.Background())
+		Expect(recorder.Events).To(Receive(Equal("Normal ScheduledBackupCreated Created scheduled backup new of volume volume-id")))
+		Expect(testutil.ToFloat64(metrics.CSIScheduledBackupLastSuccess.WithLabelValues("default", "data"))).To(Equal(float64(now.Unix())))
+		Expect(testutil.ToFloat64(metrics.CSIScheduledBackups.WithLabelValues("default", "data"))).To(Equal(float64(1)))
+	})
+
+	It("should not create a backup without a slot of the snapshot limit", func() {
+		Expect(pvcs.Add(pvc)).To(Succeed())
+		scheduler.snapshots = newSnapshotLimiter(1, 1)
+		release, err := scheduler.snapshots.acquire(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		defer release()
+		iaasClient.EXPECT().ListBackups(gomock.Any(), map[string]string{stackitclient.LabelFilter(scheduledBackupLabel): "true"}).Return(nil, nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		scheduler.reconcile(ctx)
+		Expect(recorder.Events).To(Receive(ContainSubstring("ScheduledBackupFailed")))
+	})
+
+	It("should not create a backup before the interval elapsed", func() {
+		Expect(pvcs.Add(pvc)).To(Succeed())
+		iaasClient.EXPECT().ListBackups(gomock.Any(), map[string]string{stackitclient.LabelFilter(scheduledBackupLabel): "true"}).Return([]iaas.Backup{backup("recent", 23*time.Hour, "AVAILABLE")}, nil)
+
+		scheduler.reconcile(context.Background())
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("should prune failed backups and backups exceeding the retention", func() {
+		Expect(pvcs.Add(pvc)).To(Succeed())
+		iaasClient.EXPECT().ListBackups(gomock.Any(), map[string]string{stackitclient.LabelFilter(scheduledBackupLabel): "true"}).Return([]iaas.Backup{
+			backup("oldest", 73*time.Hour, "AVAILABLE"),
+			backup("newest", 25*time.Hour, "AVAILABLE"),
+			backup("failed", time.Hour, "error"),
+			backup("older", 49*time.Hour, "AVAILABLE"),
+		}, nil)
+		iaasClient.EXPECT().CreateBackup(gomock.Any(), gomock.Any(), "volume-id", "", gomock.Any()).Return(new(backup("new", 0, "CREATING")), nil)
+		iaasClient.EXPECT().ListVolumes(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, "", nil)
+		iaasClient.EXPECT().DeleteBackup(gomock.Any(), "failed").Return(nil)
+		iaasClient.EXPECT().DeleteBackup(gomock.Any(), "older").Return(nil)
+		iaasClient.EXPECT().DeleteBackup(gomock.Any(), "oldest").Return(errors.New("injected error"))
+		failures := testutil.ToFloat64(metrics.CSIScheduledBackupFailures.WithLabelValues("default", "data"))
+
+		scheduler.reconcile(context.Background())
+		Expect(recorder.Events).To(HaveLen(4))
+		Expect(testutil.ToFloat64(metrics.CSIScheduledBackupFailures.WithLabelValues("default", "data"))).To(Equal(failures + 1))
+		Expect(testutil.ToFloat64(metrics.CSIScheduledBackups.WithLabelValues("default", "data"))).To(Equal(float64(2)))
+	})
+
+	It("should not prune backups that are restored", func() {
+		Expect(pvcs.Add(pvc)).To(Succeed())
+		iaasClient.EXPECT().ListBackups(gomock.Any(), map[string]string{stackitclient.LabelFilter(scheduledBackupLabel): "true"}).Return([]iaas.Backup{
+			backup("oldest", 49*time.Hour, "AVAILABLE"),
+			backup("newest", 23*time.Hour, "AVAILABLE"),
+			backup("older", 25*time.Hour, "AVAILABLE"),
+		}, nil)
+		iaasClient.EXPECT().ListVolumes(gomock.Any(), gomock.Any(), gomock.Any()).Return([]iaas.Volume{{
+			Name:   new("restored"),
+			Source: &iaas.VolumeSource{Id: "oldest", Type: string(stackitclient.BackupSource)},
+			Status: new(stackitclient.VolumeRestoringStatus),
+		}}, "", nil)
+
+		// The mock fails if the backup is deleted.
+		scheduler.reconcile(context.Background())
+		Expect(recorder.Events).NotTo(Receive())
+		Expect(testutil.ToFloat64(metrics.CSIScheduledBackups.WithLabelValues("default", "data"))).To(Equal(float64(3)))
+	})
+
+	It("should delete the metrics of PVCs that are gone", func() {
+		Expect(pvcs.Add(pvc)).To(Succeed())
+		iaasClient.EXPECT().ListBackups(gomock.Any(), gomock.Any()).Return([]iaas.Backup{backup("recent", time.Hour, "AVAILABLE")}, nil)
+		scheduler.reconcile(context.Background())
+		Expect(testutil.CollectAndCount(metrics.CSIScheduledBackups)).To(Equal(1))
+
+		Expect(pvcs.Delete(pvc)).To(Succeed())
+		scheduler.reconcile(context.Background())
+		Expect(testutil.CollectAndCount(metrics.CSIScheduledBackups)).To(Equal(0))
+	})
+
+	It("should only report an invalid schedule once per generation", func() {
+		pvc.Annotations = map[string]string{BackupScheduleAnnotation: "daily"}
+		Expect(pvcs.Add(pvc)).To(Succeed())
+
+		scheduler.reconcile(context.Background())
+		scheduler.reconcile(context.Background())
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning ScheduledBackupFailed Ignoring backup schedule")))
+		Expect(recorder.Events).NotTo(Receive())
+
+		pvc.Generation++
+		Expect(pvcs.Update(pvc)).To(Succeed())
+		scheduler.reconcile(context.Background())
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning ScheduledBackupFailed Ignoring backup schedule")))
+	})
+
+	It("should record a failed backup", func() {
+		Expect(pvcs.Add(pvc)).To(Succeed())
+		iaasClient.EXPECT().ListBackups(gomock.Any(), map[string]string{stackitclient.LabelFilter(scheduledBackupLabel): "true"}).Return(nil, nil)
+		iaasClient.EXPECT().CreateBackup(gomock.Any(), gomock.Any(), "volume-id", "", gomock.Any()).Return(nil, errors.New("injected error"))
+
+		scheduler.reconcile(context.Background())
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning ScheduledBackupFailed Failed to create scheduled backup of volume volume-id")))
+	})
+
+	It("should ignore PVCs without schedule, unbound PVCs and volumes of other drivers", func() {
+		withoutSchedule := pvc.DeepCopy()
+		withoutSchedule.Name = "no-schedule"
+		withoutSchedule.Spec.StorageClassName = new("standard")
+		unbound := pvc.DeepCopy()
+		unbound.Name = "unbound"
+		unbound.Status.Phase = corev1.ClaimPending
+		otherDriver := pvc.DeepCopy()
+		otherDriver.Name = "other-driver"
+		otherDriver.Spec.VolumeName = "pv-2"
+		Expect(pvs.Add(&corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-2"},
+			Spec: corev1.PersistentVolumeSpec{PersistentVolumeSource: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{Driver: "other.csi.k8s.io", VolumeHandle: "volume-id"},
+			}},
+		})).To(Succeed())
+		for _, obj := range []*corev1.PersistentVolumeClaim{withoutSchedule, unbound, otherDriver} {
+			Expect(pvcs.Add(obj)).To(Succeed())
+		}
+
+		scheduler.reconcile(context.Background())
+		Expect(recorder.Events).NotTo(Receive())
+	})
+})
@@ -0,0 +1,55 @@
+package blockstorage
+
+import (
+	"slices"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+)
+
+// capabilityRPCs are the controller service capabilities that are removed when a capability is disabled.
+var capabilityRPCs = map[stackitconfig.ControllerCapability][]csi.ControllerServiceCapability_RPC_Type{
+	stackitconfig.ControllerCapabilitySnapshots: {
+		csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT,
+		csi.ControllerServiceCapability_RPC_LIST_SNAPSHOTS,
+	},
+	stackitconfig.ControllerCapabilityExpansion:    {csi.ControllerServiceCapability_RPC_EXPAND_VOLUME},
+	stackitconfig.ControllerCapabilityCloning:      {csi.ControllerServiceCapability_RPC_CLONE_VOLUME},
+	stackitconfig.ControllerCapabilityModification: {csi.ControllerServiceCapability_RPC_MODIFY_VOLUME},
+}
+
+// disableControllerCapabilities stops advertising the capabilities, so that the sidecars don't call them.
+// Disabling snapshots also disables volume group snapshots.
+func (d *Driver) disableControllerCapabilities(disabled []stackitconfig.ControllerCapability) {
+	for _, c := range disabled {
+		klog.InfoS("Disabling controller capability", "capability", c)
+		for _, rpc := range capabilityRPCs[c] {
+			d.cscap = slices.DeleteFunc(d.cscap, func(capability *csi.ControllerServiceCapability) bool {
+				return capability.GetRpc().GetType() == rpc
+			})
+		}
+		if c == stackitconfig.ControllerCapabilitySnapshots {
+			d.gcscap = nil
+		}
+	}
+}
+
+// hasControllerCapability reports whether the controller service advertises the capability.
+func (d *Driver) hasControllerCapability(rpc csi.ControllerServiceCapability_RPC_Type) bool {
+	return slices.ContainsFunc(d.cscap, func(capability *csi.ControllerServiceCapability) bool {
+		return capability.GetRpc().GetType() == rpc
+	})
+}
+
+// requireControllerCapability returns an Unimplemented error if the capability was disabled, e.g. for calls of
+// sidecars that don't check the advertised capabilities.
+func (d *Driver) requireControllerCapability(rpc csi.ControllerServiceCapability_RPC_Type) error {
+	if !d.hasControllerCapability(rpc) {
+		return status.Errorf(codes.Unimplemented, "capability %s is disabled", rpc)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package blockstorage
+
+import (
+	"context"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+)
+
+var _ = Describe("Disabled controller capabilities", func() {
+	var d *Driver
+
+	BeforeEach(func() {
+		d = NewDriver(&DriverOpts{Endpoint: "tcp://127.0.0.1:10000", ClusterID: "cluster"})
+		d.SetupControllerService(stackitclientmock.NewMockIaaSClient(gomock.NewController(GinkgoT())), stackitconfig.BlockStorageOpts{
+			DisabledCapabilities: []stackitconfig.ControllerCapability{
+				stackitconfig.ControllerCapabilitySnapshots,
+				stackitconfig.ControllerCapabilityExpansion,
+			},
+		})
+	})
+
+	It("should not advertise disabled capabilities", func() {
+		res, err := d.cs.ControllerGetCapabilities(context.Background(), &csi.ControllerGetCapabilitiesRequest{})
+		Expect(err).NotTo(HaveOccurred())
+		var rpcs []csi.ControllerServiceCapability_RPC_Type
+		for _, c := range res.GetCapabilities() {
+			rpcs = append(rpcs, c.GetRpc().GetType())
+		}
+		Expect(rpcs).To(ContainElements(
+			csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME,
+			csi.ControllerServiceCapability_RPC_CLONE_VOLUME,
+		))
+		Expect(rpcs).NotTo(ContainElements(
+			csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT,
+			csi.ControllerServiceCapability_RPC_LIST_SNAPSHOTS,
+			csi.ControllerServiceCapability_RPC_EXPAND_VOLUME,
+		))
+
+		groupRes, err := d.gcs.GroupControllerGetCapabilities(context.Background(), &csi.GroupControllerGetCapabilitiesRequest{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(groupRes.GetCapabilities()).To(BeEmpty())
+	})
+
+	It("should reject calls that need a disabled capability", func() {
+		_, err := d.cs.CreateSnapshot(context.Background(), &csi.CreateSnapshotRequest{Name: "snap", SourceVolumeId: "volume-id"})
+		Expect(status.Code(err)).To(Equal(codes.Unimplemented))
+
+		_, err = d.cs.ControllerExpandVolume(context.Background(), &csi.ControllerExpandVolumeRequest{
+			VolumeId:      "volume-id",
+			CapacityRange: &csi.CapacityRange{RequiredBytes: 1},
+		})
+		Expect(status.Code(err)).To(Equal(codes.Unimplemented))
+
+		_, err = d.gcs.CreateVolumeGroupSnapshot(context.Background(), &csi.CreateVolumeGroupSnapshotRequest{
+			Name:            "group",
+			SourceVolumeIds: []string{"volume-id"},
+		})
+		Expect(status.Code(err)).To(Equal(codes.Unimplemented))
+	})
+})
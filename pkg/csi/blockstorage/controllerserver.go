@@ -20,21 +20,28 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"strconv"
-	"time"
+	"strings"
+	"sync"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/go-viper/mapstructure/v2"
 	"github.com/kubernetes-csi/csi-lib-utils/protosanitizer"
 	sharedcsi "github.com/stackitcloud/cloud-provider-stackit/pkg/csi"
 	"github.com/stackitcloud/cloud-provider-stackit/pkg/csi/util"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/features"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/metrics"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/providerid"
 	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
 	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
 	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
-	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/labels"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/ptr"
 )
@@ -42,6 +49,23 @@ import (
 type controllerServer struct {
 	Driver   *Driver
 	Instance stackitclient.IaaSClient
+	// operationLocks prevents concurrent create requests for the same name from racing each other,
+	// since the IaaS API doesn't support idempotency keys.
+	operationLocks *util.OperationLocks
+	// restores tracks the snapshots and backups that volumes are currently restored from,
+	// DeleteSnapshot must not remove them until the restore has completed.
+	restores *util.InFlightRestores
+	// backoffs are the backoffs of waiting for volumes and snapshots after an operation.
+	backoffs waitBackoffs
+	// attachQueue batches attaching volumes to the same server.
+	attachQueue *attachQueue
+	// snapshots limits the concurrent CreateSnapshot calls, nil if they are not limited.
+	snapshots *snapshotLimiter
+	// reclaimer defers the deletion of volumes, nil if no reclaim grace period is configured.
+	reclaimer *volumeReclaimer
+	// restoreProgress reports the progress of restores from backups until the volumes are available or failed.
+	restoreProgress *restoreProgresses
+	Opts            stackitconfig.BlockStorageOpts
 	csi.UnimplementedControllerServer
 }
 
@@ -55,26 +79,52 @@ type stackitParameterConfig struct {
 	KMSServiceAccount *string `mapstructure:"kmsServiceAccount,omitempty"`
 	// optional - IaaS will set this value to the projectID of the volume, this is only relevant in case the KMS is in a different project
 	KMSProjectID *string `mapstructure:"kmsProjectID,omitempty"`
+	// optional - IaaS does not accept explicit QoS values, these are validated against the limits of the performance class
+	MinIOPS       *string `mapstructure:"minIops,omitempty"`
+	MinThroughput *string `mapstructure:"minThroughput,omitempty"`
+	// optional - adopt an unattached volume that was provisioned for a PVC with the same namespace and name
+	AdoptExisting *string `mapstructure:"adoptExisting,omitempty"`
+	// optional - the size of volumes without a requested capacity and the maximum size of volumes, e.g. "100Gi"
+	DefaultSize *string `mapstructure:"defaultSize,omitempty"`
+	MaxSize     *string `mapstructure:"maxSize,omitempty"`
 }
 
 const (
 	blockStorageCSIClusterIDKey = "block-storage.csi.stackit.cloud/cluster"
 	snapshotTypeSnapshot        = "snapshot"
 	snapshotTypeBackup          = "backup"
+
+	qosIOPSLabel       = "qos-iops"
+	qosThroughputLabel = "qos-throughput"
+
+	// pvcNamespaceLabel holds the namespace of the PVC a volume was provisioned for.
+	// It is used to account the capacity of a namespace against its quota.
+	pvcNamespaceLabel = stackitclient.PVCNamespaceLabel
+	// pvcNameLabel holds the name of the PVC a volume was provisioned for.
+	// Together with pvcNamespaceLabel it is used to adopt retained volumes when a PVC is recreated.
+	pvcNameLabel = stackitclient.PVCNameLabel
+	// maxLabelValueLength is the maximum length of IaaS label values, longer PVC names are not recorded.
+	maxLabelValueLength = 63
+	// publishedReadOnlyLabel records whether a volume is published read-only, since the attach API has no read-only
+	// attachments. It is used to reject publishing an attached volume again with a different readonly flag.
+	publishedReadOnlyLabel = "published-read-only"
 )
 
 func (cs *controllerServer) validateVolumeCapabilities(req []*csi.VolumeCapability) error {
 	for _, volCap := range req {
-		if volCap.GetAccessMode().GetMode() != cs.Driver.vcap[0].GetMode() {
+		if !cs.Driver.supportsAccessMode(volCap.GetAccessMode().GetMode()) {
 			return fmt.Errorf("volume access mode %s not supported", volCap.GetAccessMode().GetMode().String())
 		}
+		if err := validateMountOptions(volCap.GetMount().GetMountFlags()); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
 //nolint:gocyclo,funlen // This function is complex and should be broken down further, but it's ok for now.
 func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest) (*csi.CreateVolumeResponse, error) {
-	klog.V(4).Infof("CreateVolume: called with args %+v", protosanitizer.StripSecrets(req))
+	klog.V(4).InfoS("CreateVolume called", "args", protosanitizer.StripSecrets(req))
 
 	cloud := cs.Instance
 
@@ -89,11 +139,25 @@ func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVol
 	if err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
+	encrypted, err := parseEncrypted(volParams)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	// The mutable parameters of the VolumeAttributesClass the PVC is created with.
+	mutableLabels, err := parseMutableParameters(req.GetMutableParameters())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
 
 	if volName == "" {
 		return nil, status.Error(codes.InvalidArgument, "[CreateVolume] missing Volume Name")
 	}
 
+	if !cs.operationLocks.TryAcquire(volName) {
+		return nil, status.Errorf(codes.Aborted, "[CreateVolume] an operation for volume %s is already in progress", volName)
+	}
+	defer cs.operationLocks.Release(volName)
+
 	if volCapabilities == nil {
 		return nil, status.Error(codes.InvalidArgument, "[CreateVolume] missing Volume capability")
 	}
@@ -102,12 +166,10 @@ func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVol
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
-	// Volume Size - Default is 1 GiB
-	volSizeBytes := 1 * util.GIBIBYTE
-	if req.GetCapacityRange() != nil {
-		volSizeBytes = req.GetCapacityRange().GetRequiredBytes()
+	volSizeGB, err := cs.volumeSize(req.GetCapacityRange(), volParams)
+	if err != nil {
+		return nil, err
 	}
-	volSizeGB := util.RoundUpSize(volSizeBytes, util.GIBIBYTE)
 
 	var volAvailability string
 	// First check if volAvailability is already specified, if not, get preferred from topology
@@ -117,18 +179,14 @@ func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVol
 		accessibleTopologyReq := req.GetAccessibilityRequirements()
 		// Check from topology
 		if accessibleTopologyReq != nil {
-			if cs.Driver.legacyDriver {
-				volAvailability = sharedcsi.GetAZFromTopology(legacyTopologyKey, accessibleTopologyReq)
-			} else {
-				volAvailability = sharedcsi.GetAZFromTopology(topologyKey, accessibleTopologyReq)
-			}
+			volAvailability = sharedcsi.GetAZFromTopology(accessibleTopologyReq, cs.Driver.topologyKeys()...)
 		}
 	}
 
 	// Verify a volume with the provided name doesn't already exist for this tenant
 	vols, err := cloud.GetVolumesByName(ctx, volName)
 	if err != nil {
-		klog.Errorf("Failed to query for existing Volume during CreateVolume: %v", err)
+		klog.ErrorS(err, "Failed to query for existing volume during CreateVolume", "name", volName)
 		return nil, status.Errorf(codes.Internal, "Failed to get volumes: %v", err)
 	}
 
@@ -137,15 +195,39 @@ func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVol
 			return nil, status.Error(codes.AlreadyExists, "Volume Already exists with same name and different capacity")
 		}
 		if *vols[0].Status != stackitclient.VolumeAvailableStatus {
+			// The restore of a large backup outlives the CreateVolume call, keep reporting its progress until it is done.
+			if source := vols[0].Source; source != nil && source.Type == string(stackitclient.BackupSource) && volumeRestoring(&vols[0]) {
+				cs.restoreProgress.track(&vols[0], source.Id, true)
+			}
 			return nil, status.Error(codes.Internal, fmt.Sprintf("Volume %s is not in available state", *vols[0].Id))
 		}
-		klog.V(4).Infof("Volume %s already exists in Availability Zone: %s of size %d GiB", *vols[0].Id, vols[0].AvailabilityZone, *vols[0].Size)
+		cs.restoreProgress.completed(*vols[0].Id)
+		klog.V(4).InfoS("Volume already exists", "volumeID", *vols[0].Id, "availabilityZone", vols[0].AvailabilityZone, "sizeGiB", *vols[0].Size)
 		return cs.getCreateVolumeResponse(&vols[0]), nil
 	} else if len(vols) > 1 {
-		klog.V(3).Infof("found multiple existing volumes with selected name (%s) during create", volName)
+		klog.V(3).InfoS("Found multiple existing volumes with selected name during create", "name", volName)
 		return nil, status.Error(codes.Internal, "Multiple volumes reported by Cinder with same name")
 	}
 
+	pvcNamespace := req.GetParameters()[sharedcsi.PvcNamespaceKey]
+	pvcName := req.GetParameters()[sharedcsi.PvcNameKey]
+
+	if req.GetVolumeContentSource() == nil && volParams.AdoptExisting != nil {
+		adopt, err := strconv.ParseBool(*volParams.AdoptExisting)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "parameter adoptExisting must be of type boolean")
+		}
+		if adopt {
+			vol, err := cs.adoptVolume(ctx, volName, pvcNamespace, pvcName, volSizeGB, volAvailability)
+			if err != nil {
+				return nil, err
+			}
+			if vol != nil {
+				return cs.getCreateVolumeResponse(vol), nil
+			}
+		}
+	}
+
 	// Volume Create
 	// TODO: Use once IaaS has extended the label regex to allow for forward slashes and dots
 	// properties := map[string]string{blockStorageCSIClusterIDKey: cs.Driver.clusterID}
@@ -156,16 +238,23 @@ func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVol
 			properties[mKey] = v
 		}
 	}
+	// Volume populators never pass a content source: they provision a PVC without data source, fill the volume and
+	// rebind it to the PVC with the dataSourceRef. Content sources without a snapshot or volume create an empty volume.
 	content := req.GetVolumeContentSource()
 	var sourceVolID string
 	var sourceBackupID string
 	var sourceSnapshotID string
 	var volumeSourceType stackitclient.VolumeSourceTypes
+	// inheritedFrom is the volume whose encryption the new volume inherits, if it is known.
+	var inheritedFrom *iaas.Volume
 
 	if content != nil && content.GetSnapshot() != nil {
 		// Backups and Snapshots are the same for Kubernetes
 		sourceSnapshotID = content.GetSnapshot().GetSnapshotId()
 		sourceBackupID = content.GetSnapshot().GetSnapshotId()
+		// The restore is tracked until the volume is created, afterwards it is found by the status of the volume.
+		cs.restores.Add(sourceSnapshotID, volName)
+		defer cs.restores.Remove(sourceSnapshotID, volName)
 		// By default, we try to clone volumes from snapshots
 		volumeSourceType = stackitclient.SnapshotSource
 
@@ -179,9 +268,17 @@ func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVol
 		}
 		// Only continue checking if the Snapshot is found
 		if !stackiterrors.IsNotFound(err) {
-			if snap.GetAvailabilityZone() != volAvailability {
+			if snap.GetAvailabilityZone() != volAvailability && !utilfeature.DefaultFeatureGate.Enabled(features.CrossZoneClone) {
 				return nil, status.Errorf(codes.ResourceExhausted, "Volume must be in the same availability zone as source Snapshot. Got %s Required: %s", volAvailability, snap.GetAvailabilityZone())
 			}
+			// The encryption of the snapshot is only known from its volume, which may have been deleted already.
+			if volParams.Encrypted != nil {
+				snapVolume, err := cloud.GetVolume(ctx, snap.VolumeId)
+				if stackiterrors.IgnoreNotFound(err) != nil {
+					return nil, status.Errorf(codes.Internal, "Failed to retrieve the volume %s of the source snapshot %s: %v", snap.VolumeId, sourceSnapshotID, err)
+				}
+				inheritedFrom = snapVolume
+			}
 		}
 
 		// In case a snapshot is not found
@@ -204,6 +301,9 @@ func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVol
 
 	// Clone from a Volume Source
 	if content != nil && content.GetVolume() != nil {
+		if err := cs.Driver.requireControllerCapability(csi.ControllerServiceCapability_RPC_CLONE_VOLUME); err != nil {
+			return nil, err
+		}
 		sourceVolID = content.GetVolume().GetVolumeId()
 		sourceVolume, err := cloud.GetVolume(ctx, sourceVolID)
 		if err != nil {
@@ -212,12 +312,43 @@ func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVol
 			}
 			return nil, status.Errorf(codes.Internal, "Failed to retrieve the source volume %s: %v", sourceVolID, err)
 		}
-		if volAvailability != sourceVolume.AvailabilityZone {
+		if volAvailability != sourceVolume.AvailabilityZone && !utilfeature.DefaultFeatureGate.Enabled(features.CrossZoneClone) {
 			return nil, status.Errorf(codes.ResourceExhausted, "Volume must be in the same availability zone as source Volume. Got %s Required: %s", volAvailability, sourceVolume.AvailabilityZone)
 		}
 		volumeSourceType = stackitclient.VolumeSource
+		inheritedFrom = sourceVolume
+	}
+
+	// The encryption of snapshots and volumes is inherited, so different encryption parameters can't be honored for them.
+	if volParams.Encrypted != nil && inheritedFrom != nil {
+		if err := validateInheritedEncryption(inheritedFrom, encrypted, volParams); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Cannot create a volume from a %s with these encryption parameters: %v", volumeSourceType, err)
+		}
+	}
+
+	// The performance class of snapshots and volumes is inherited and not restricted.
+	if volumeSourceType != stackitclient.SnapshotSource && volumeSourceType != stackitclient.VolumeSource {
+		if err := cs.checkPerformanceClass(volParams.PerformanceClass, volAvailability); err != nil {
+			return nil, err
+		}
+	}
+
+	// The performance class of snapshots and volumes is inherited, so QoS requirements can't be honored for them.
+	if (volParams.MinIOPS != nil || volParams.MinThroughput != nil) &&
+		(volumeSourceType == stackitclient.SnapshotSource || volumeSourceType == stackitclient.VolumeSource) {
+		return nil, status.Errorf(codes.InvalidArgument, "parameters minIops and minThroughput are not supported when creating a volume from a %s", volumeSourceType)
+	}
+	qosLabels, err := cs.validateQoSParameters(ctx, volParams)
+	if err != nil {
+		return nil, err
 	}
 
+	namespaceUsage, releaseNamespace, err := cs.checkNamespaceQuota(ctx, pvcNamespace, volSizeGB)
+	if err != nil {
+		return nil, err
+	}
+	defer releaseNamespace()
+
 	opts := &iaas.CreateVolumePayload{
 		Name:             new(volName),
 		PerformanceClass: volParams.PerformanceClass,
@@ -226,6 +357,29 @@ func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVol
 		//TODO: IaaS API does not allow dots or slashes. Additionally we would like to actually use metadata/annotations
 		//Labels:           new(util.ConvertMapStringToInterface(properties)),
 	}
+	volLabels := maps.Clone(qosLabels)
+	if pvcNamespace != "" {
+		if volLabels == nil {
+			volLabels = map[string]string{}
+		}
+		volLabels[pvcNamespaceLabel] = pvcNamespace
+		if pvcName != "" && len(pvcName) <= maxLabelValueLength {
+			volLabels[pvcNameLabel] = pvcName
+		}
+	}
+	for key, value := range mutableLabels {
+		if value == nil {
+			continue
+		}
+		if volLabels == nil {
+			volLabels = map[string]string{}
+		}
+		volLabels[key] = *value
+	}
+	volLabels = stackitclient.WithResourceLabels(volLabels, cs.Driver.volumeResourceLabels(volName))
+	if len(volLabels) > 0 {
+		opts.Labels = stackitclient.LabelsFromTags(volLabels)
+	}
 
 	// Only set CreateVolumePayload.Source when actually creating volume from source/snapshot/backup
 	if volumeSourceType != "" {
@@ -235,7 +389,7 @@ func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVol
 		}
 		// Again sourceSnapshotID == sourceBackupID
 		volumeSourceID := determineSourceIDForSourceType(volumeSourceType, sourceSnapshotID, sourceVolID)
-		klog.V(4).Infof("Creating volume from %s source", volumeSourceType)
+		klog.V(4).InfoS("Creating volume from source", "sourceType", volumeSourceType)
 		opts.Source = &iaas.VolumeSource{
 			Id:   volumeSourceID,
 			Type: string(volumeSourceType),
@@ -245,42 +399,138 @@ func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVol
 	// The encryption config is already set for volumes created from snapshot or volume. We MUST never set it when
 	// restoring from snapshot or volume.
 	// This is not true for volumeSourceType == Backup. The encryptionConfig must be set BUT the parameters can be different.
-	if volParams.Encrypted != nil && (volumeSourceType == "" || volumeSourceType == stackitclient.BackupSource) {
-		encrypted, err := strconv.ParseBool(*volParams.Encrypted)
-		if err != nil {
-			return nil, status.Error(codes.InvalidArgument, "parameter encrypted must be of type boolean")
-		}
-		if encrypted {
-			if err := setVolumeEncryptionParameters(opts, volParams); err != nil {
-				return nil, status.Errorf(codes.InvalidArgument, "Failed to set volume encryption parameters: %v", err)
-			}
+	if encrypted && (volumeSourceType == "" || volumeSourceType == stackitclient.BackupSource) {
+		if err := setVolumeEncryptionParameters(opts, volParams); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Failed to set volume encryption parameters: %v", err)
 		}
 	}
 
 	vol, err := cloud.CreateVolume(ctx, *opts)
 	if err != nil {
-		klog.Errorf("Failed to CreateVolume: %v", err)
-		return nil, status.Errorf(codes.Internal, "CreateVolume failed with error %v", err)
+		vol = cs.findVolumeAfterFailedCreate(ctx, volName, err)
+		if vol == nil {
+			klog.ErrorS(err, "Failed to CreateVolume", "name", volName)
+			return nil, status.Errorf(codes.Internal, "CreateVolume failed with error %v", err)
+		}
+	}
+	// The volume is counted against the quota of its namespace from now on.
+	releaseNamespace()
+
+	// The progress is reported until the volume is available or failed, even if waiting for it below gives up.
+	if volumeSourceType == stackitclient.BackupSource {
+		cs.restoreProgress.track(vol, sourceBackupID, false)
 	}
 
 	targetStatus := []string{stackitclient.VolumeAvailableStatus}
-	// Recheck after: 0s (immediate), 20s, 45.6s, 78.36s, 120.31s
-	err = cloud.WaitVolumeTargetStatusWithCustomBackoff(ctx, *vol.Id, targetStatus,
-		&wait.Backoff{
-			Duration: 20 * time.Second,
-			Steps:    5,
-			Factor:   1.28,
-		})
+	err = cloud.WaitVolumeTargetStatusWithCustomBackoff(ctx, *vol.Id, targetStatus, new(cs.backoffs.create))
 	if err != nil {
-		klog.Errorf("Failed to WaitVolumeTargetStatus of volume %s: %v", *vol.Id, err)
+		klog.ErrorS(err, "Failed to WaitVolumeTargetStatus", "volumeID", *vol.Id)
 		return nil, status.Error(codes.Internal, fmt.Sprintf("CreateVolume Volume %s failed getting available in time: %v", *vol.Id, err))
 	}
 
-	klog.V(4).Infof("CreateVolume: Successfully created volume %s in Availability Zone: %s of size %d GiB", *vol.Id, vol.AvailabilityZone, *vol.Size)
+	cs.restoreProgress.completed(*vol.Id)
+
+	klog.V(4).InfoS("CreateVolume successfully created volume", "volumeID", *vol.Id, "availabilityZone", vol.AvailabilityZone, "sizeGiB", *vol.Size)
+
+	if _, ok := cs.Opts.NamespaceQuotas[pvcNamespace]; ok {
+		metrics.CSINamespaceCapacityUsed.WithLabelValues(pvcNamespace).Set(float64(namespaceUsage + volSizeGB))
+	}
 
 	return cs.getCreateVolumeResponse(vol), nil
 }
 
+// adoptVolume returns an unattached volume that was provisioned for the PVC with the given namespace and name,
+// e.g. a volume retained after the PVC was deleted, and renames it to volName. It returns nil if there is none.
+func (cs *controllerServer) adoptVolume(
+	ctx context.Context, volName, pvcNamespace, pvcName string, volSizeGB int64, volAvailability string,
+) (*iaas.Volume, error) {
+	if pvcNamespace == "" || pvcName == "" {
+		return nil, status.Error(codes.InvalidArgument, "parameter adoptExisting requires the csi-provisioner to run with --extra-create-metadata")
+	}
+	if cs.Driver.pvLister == nil {
+		return nil, status.Error(codes.FailedPrecondition, "parameter adoptExisting requires the controller to run with --volume-adoption")
+	}
+
+	vols, _, err := cs.Instance.ListVolumes(ctx, 0, "")
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to list volumes for adoption: %v", err)
+	}
+	var candidates []iaas.Volume
+	for i := range vols {
+		if vols[i].Labels[pvcNamespaceLabel] == pvcNamespace && vols[i].Labels[pvcNameLabel] == pvcName {
+			candidates = append(candidates, vols[i])
+		}
+	}
+
+	switch {
+	case len(candidates) == 0:
+		return nil, nil
+	case len(candidates) > 1:
+		return nil, status.Errorf(codes.FailedPrecondition, "Multiple volumes labelled with PVC %s/%s exist, can't decide which one to adopt", pvcNamespace, pvcName)
+	}
+
+	vol := &candidates[0]
+	if vol.ServerId != nil || ptr.Deref(vol.Status, "") != stackitclient.VolumeAvailableStatus {
+		return nil, status.Errorf(codes.FailedPrecondition, "Volume %s of PVC %s/%s can't be adopted, it is attached or not available", *vol.Id, pvcNamespace, pvcName)
+	}
+	if *vol.Size < volSizeGB {
+		return nil, status.Errorf(codes.FailedPrecondition, "Volume %s of PVC %s/%s has %d GiB, less than the requested %d GiB", *vol.Id, pvcNamespace, pvcName, *vol.Size, volSizeGB)
+	}
+	if volAvailability != "" && vol.AvailabilityZone != volAvailability {
+		return nil, status.Errorf(codes.FailedPrecondition, "Volume %s of PVC %s/%s is in availability zone %s, not %s", *vol.Id, pvcNamespace, pvcName, vol.AvailabilityZone, volAvailability)
+	}
+	// The released PV of a retained volume still references it, adopting it would make two PVs use the same volume.
+	pvName, err := cs.persistentVolumeOf(*vol.Id)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to list PVs for adoption: %v", err)
+	}
+	if pvName != "" {
+		return nil, status.Errorf(codes.FailedPrecondition, "Volume %s of PVC %s/%s can't be adopted, it is still referenced by PV %s, delete the PV to adopt it", *vol.Id, pvcNamespace, pvcName, pvName)
+	}
+
+	// Renaming the volume makes retries of CreateVolume find it by name.
+	payload := iaas.UpdateVolumePayload{Name: new(volName)}
+	// Adopting a volume whose PVC was deleted recovers it before its reclaim grace period is over. The API merges the
+	// labels of the update into the labels of the volume, a null value removes a label.
+	if _, pending := vol.Labels[stackitclient.PendingDeleteLabel]; pending {
+		payload.Labels = map[string]any{stackitclient.PendingDeleteLabel: nil}
+	}
+	if _, err := cs.Instance.UpdateVolume(ctx, *vol.Id, payload); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to rename adopted volume %s: %v", *vol.Id, err)
+	}
+	vol.Name = new(volName)
+	klog.V(2).InfoS("Adopted existing volume", "volumeID", *vol.Id, "name", volName, "pvc", klog.KRef(pvcNamespace, pvcName))
+	return vol, nil
+}
+
+// persistentVolumeOf returns the name of a PV whose volume handle is volumeID, or an empty string if there is none.
+func (cs *controllerServer) persistentVolumeOf(volumeID string) (string, error) {
+	pvs, err := cs.Driver.pvLister.List(labels.Everything())
+	if err != nil {
+		return "", err
+	}
+	for _, pv := range pvs {
+		if pv.Spec.CSI != nil && pv.Spec.CSI.VolumeHandle == volumeID {
+			return pv.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// findVolumeAfterFailedCreate looks up the volume by name if the create request failed in a way that leaves it open
+// whether the volume was created, e.g. due to a network timeout. This prevents retries from creating duplicates.
+func (cs *controllerServer) findVolumeAfterFailedCreate(ctx context.Context, volName string, createErr error) *iaas.Volume {
+	if !stackiterrors.IsAmbiguousError(createErr) {
+		return nil
+	}
+	vols, err := cs.Instance.GetVolumesByName(ctx, volName)
+	if err != nil || len(vols) != 1 {
+		return nil
+	}
+	klog.V(3).InfoS("CreateVolume failed, but volume was created anyway", "name", volName, "err", createErr, "volumeID", *vols[0].Id)
+	return &vols[0]
+}
+
 func setVolumeEncryptionParameters(opts *iaas.CreateVolumePayload, volParams *stackitParameterConfig) error {
 	err := validateEncryptionConfig(volParams)
 	if err != nil {
@@ -307,12 +557,110 @@ func setVolumeEncryptionParameters(opts *iaas.CreateVolumePayload, volParams *st
 	return nil
 }
 
-func (cs *controllerServer) ControllerModifyVolume(_ context.Context, _ *csi.ControllerModifyVolumeRequest) (*csi.ControllerModifyVolumeResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "")
+// validateQoSParameters ensures the performance class of the volume satisfies the requested minIops and minThroughput.
+// The returned labels describe the QoS limits the volume is created with.
+func (cs *controllerServer) validateQoSParameters(ctx context.Context, volParams *stackitParameterConfig) (map[string]string, error) {
+	if volParams.MinIOPS == nil && volParams.MinThroughput == nil {
+		return nil, nil
+	}
+	if volParams.PerformanceClass == nil {
+		return nil, status.Error(codes.InvalidArgument, "parameter type must be set when minIops or minThroughput are requested")
+	}
+
+	minIOPS, err := parseQoSParameter("minIops", volParams.MinIOPS)
+	if err != nil {
+		return nil, err
+	}
+	minThroughput, err := parseQoSParameter("minThroughput", volParams.MinThroughput)
+	if err != nil {
+		return nil, err
+	}
+
+	class, err := cs.Instance.GetVolumePerformanceClass(ctx, *volParams.PerformanceClass)
+	if err != nil {
+		if stackiterrors.IsNotFound(err) {
+			return nil, status.Errorf(codes.InvalidArgument, "performance class %s not found", *volParams.PerformanceClass)
+		}
+		return nil, status.Errorf(codes.Internal, "Failed to get performance class %s: %v", *volParams.PerformanceClass, err)
+	}
+
+	if class.GetIops() < minIOPS {
+		return nil, status.Errorf(codes.InvalidArgument, "performance class %s provides %d IOPS, but minIops is %d", class.Name, class.GetIops(), minIOPS)
+	}
+	if class.GetThroughput() < minThroughput {
+		return nil, status.Errorf(codes.InvalidArgument, "performance class %s provides a throughput of %d MB/s, but minThroughput is %d",
+			class.Name, class.GetThroughput(), minThroughput)
+	}
+
+	labels := map[string]string{}
+	if class.Iops != nil {
+		labels[qosIOPSLabel] = strconv.FormatInt(*class.Iops, 10)
+	}
+	if class.Throughput != nil {
+		labels[qosThroughputLabel] = strconv.FormatInt(*class.Throughput, 10)
+	}
+	return labels, nil
+}
+
+// checkNamespaceQuota ensures that provisioning sizeGB more capacity for a PVC in the given namespace
+// doesn't exceed the quota configured for it. It returns the capacity in GiB currently used by the namespace.
+//
+// The namespace is locked until the returned release function is called, which must happen once the volume was
+// created. Otherwise concurrent requests could all pass the check before any of their volumes is counted.
+func (cs *controllerServer) checkNamespaceQuota(ctx context.Context, namespace string, sizeGB int64) (int64, func(), error) {
+	quota, ok := cs.Opts.NamespaceQuotas[namespace]
+	if namespace == "" || !ok {
+		return 0, func() {}, nil
+	}
+
+	lockKey := "ns/" + namespace
+	if !cs.operationLocks.TryAcquire(lockKey) {
+		return 0, nil, status.Errorf(codes.Aborted, "[CreateVolume] a volume is already being created in namespace %s with a quota", namespace)
+	}
+	release := sync.OnceFunc(func() { cs.operationLocks.Release(lockKey) })
+
+	vols, _, err := cs.Instance.ListVolumes(ctx, 0, "")
+	if err != nil {
+		release()
+		klog.ErrorS(err, "Failed to list volumes for the quota of namespace", "namespace", namespace)
+		return 0, nil, status.Errorf(codes.Internal, "Failed to get volumes: %v", err)
+	}
+
+	var used int64
+	for i := range vols {
+		// The PVCs of volumes pending deletion were already deleted.
+		if _, pending := vols[i].Labels[stackitclient.PendingDeleteLabel]; pending {
+			continue
+		}
+		if vols[i].Labels[pvcNamespaceLabel] == namespace {
+			used += ptr.Deref(vols[i].Size, 0)
+		}
+	}
+
+	metrics.CSINamespaceCapacityQuota.WithLabelValues(namespace).Set(float64(quota))
+	metrics.CSINamespaceCapacityUsed.WithLabelValues(namespace).Set(float64(used))
+
+	if used+sizeGB > quota {
+		release()
+		return 0, nil, status.Errorf(codes.ResourceExhausted, "namespace %s uses %d GiB of its %d GiB quota, a volume of %d GiB exceeds it",
+			namespace, used, quota, sizeGB)
+	}
+	return used, release, nil
+}
+
+func parseQoSParameter(name string, value *string) (int64, error) {
+	if value == nil {
+		return 0, nil
+	}
+	parsed, err := strconv.ParseInt(*value, 10, 64)
+	if err != nil || parsed <= 0 {
+		return 0, status.Errorf(codes.InvalidArgument, "parameter %s must be a positive integer", name)
+	}
+	return parsed, nil
 }
 
 func (cs *controllerServer) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequest) (*csi.DeleteVolumeResponse, error) {
-	klog.V(4).Infof("DeleteVolume: called with args %+v", protosanitizer.StripSecrets(req))
+	klog.V(4).InfoS("DeleteVolume called", "args", protosanitizer.StripSecrets(req))
 
 	cloud := cs.Instance
 
@@ -321,23 +669,29 @@ func (cs *controllerServer) DeleteVolume(ctx context.Context, req *csi.DeleteVol
 	if volID == "" {
 		return nil, status.Error(codes.InvalidArgument, "DeleteVolume Volume ID must be provided")
 	}
+	if cs.reclaimer != nil {
+		if err := cs.reclaimer.markPendingDelete(ctx, volID); err != nil {
+			return nil, err
+		}
+		return &csi.DeleteVolumeResponse{}, nil
+	}
 	err := cloud.DeleteVolume(ctx, volID)
 	if err != nil {
 		if stackiterrors.IsNotFound(err) {
-			klog.V(3).Infof("Volume %s is already deleted.", volID)
+			klog.V(3).InfoS("Volume is already deleted", "volumeID", volID)
 			return &csi.DeleteVolumeResponse{}, nil
 		}
-		klog.Errorf("Failed to DeleteVolume: %v", err)
+		klog.ErrorS(err, "Failed to DeleteVolume", "volumeID", volID)
 		return nil, status.Errorf(codes.Internal, "DeleteVolume failed with error %v", err)
 	}
 
-	klog.V(4).Infof("DeleteVolume: Successfully deleted volume %s", volID)
+	klog.V(4).InfoS("DeleteVolume successfully deleted volume", "volumeID", volID)
 
 	return &csi.DeleteVolumeResponse{}, nil
 }
 
 func (cs *controllerServer) ControllerPublishVolume(ctx context.Context, req *csi.ControllerPublishVolumeRequest) (*csi.ControllerPublishVolumeResponse, error) {
-	klog.V(4).Infof("ControllerPublishVolume: called with args %+v", protosanitizer.StripSecrets(req))
+	klog.V(4).InfoS("ControllerPublishVolume called", "args", protosanitizer.StripSecrets(req))
 
 	cloud := cs.Instance
 
@@ -355,6 +709,11 @@ func (cs *controllerServer) ControllerPublishVolume(ctx context.Context, req *cs
 	if volumeCapability == nil {
 		return nil, status.Error(codes.InvalidArgument, "[ControllerPublishVolume] Volume capability must be provided")
 	}
+	// The node ID is usually the bare server ID reported by NodeGetInfo, but providerIDs are accepted as well.
+	instanceID, err := providerid.ServerID(instanceID)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "[ControllerPublishVolume] Invalid node ID: %v", err)
+	}
 
 	vol, err := cloud.GetVolume(ctx, volumeID)
 	if err != nil {
@@ -372,41 +731,59 @@ func (cs *controllerServer) ControllerPublishVolume(ctx context.Context, req *cs
 		return nil, status.Errorf(codes.Internal, "[ControllerPublishVolume] GetInstanceByID failed with error %v", err)
 	}
 
+	// The attach API has no read-only attachments, so the node plugin enforces read-only mounts.
+	var publishContext map[string]string
+	if req.GetReadonly() {
+		publishContext = map[string]string{publishReadOnlyKey: "true"}
+	}
+
 	// If Volume is already mounted to target instanceID, return OK
 	if vol.ServerId != nil && *vol.ServerId == instanceID {
-		return &csi.ControllerPublishVolumeResponse{}, nil
+		if publishedReadOnly(vol) != req.GetReadonly() {
+			return nil, status.Errorf(codes.AlreadyExists,
+				"[ControllerPublishVolume] Volume %s is already published to %s with readonly=%t", volumeID, instanceID, publishedReadOnly(vol))
+		}
+		return &csi.ControllerPublishVolumeResponse{PublishContext: publishContext}, nil
 	}
 
 	if vol.GetStatus() != stackitclient.VolumeAvailableStatus {
 		return nil, status.Errorf(codes.Internal, "[ControllerPublishVolume] Volume %s is not in an READY state. Got:%s Want:%s", volumeID, vol.GetStatus(), stackitclient.VolumeAvailableStatus)
 	}
 
+	if publishedReadOnly(vol) != req.GetReadonly() {
+		// The API merges the labels of the update into the labels of the volume.
+		labels := map[string]any{publishedReadOnlyLabel: strconv.FormatBool(req.GetReadonly())}
+		if _, err := cloud.UpdateVolume(ctx, volumeID, iaas.UpdateVolumePayload{Labels: labels}); err != nil {
+			return nil, status.Errorf(codes.Internal, "[ControllerPublishVolume] failed to record the readonly flag of volume %s: %v", volumeID, err)
+		}
+	}
+
 	payload := iaas.AddVolumeToServerPayload{
 		DeleteOnTermination: new(false),
 	}
-	_, err = cloud.AttachVolume(ctx, instanceID, volumeID, payload)
+	err = cs.attachQueue.Attach(ctx, instanceID, volumeID, payload)
 	if err != nil {
 		// Trigger's an immediate `NodeGetInfo` RPC call when MutableCSINodeAllocatableCount is enabled
 		if stackiterrors.IsTooManyDevicesError(err) {
 			return nil, status.Errorf(codes.ResourceExhausted, "[ControllerPublishVolume] Node can't accept any more volumes %v. All PCIe lanes are exhausted!", err)
 		}
-		klog.Errorf("Failed to AttachVolume: %v", err)
+		klog.ErrorS(err, "Failed to AttachVolume", "volumeID", volumeID, "instanceID", instanceID)
 		return nil, status.Errorf(codes.Internal, "[ControllerPublishVolume] Attach Volume failed with error %v", err)
 	}
 
-	err = cloud.WaitDiskAttached(ctx, instanceID, volumeID)
+	err = cs.attachQueue.WaitAttached(ctx, instanceID, volumeID)
 	if err != nil {
-		klog.Errorf("Failed to WaitDiskAttached: %v", err)
+		klog.ErrorS(err, "Failed to wait for volume to be attached", "volumeID", volumeID, "instanceID", instanceID)
 		return nil, status.Errorf(codes.Internal, "[ControllerPublishVolume] failed to attach volume: %v", err)
 	}
 
-	klog.V(4).Infof("ControllerPublishVolume %s on %s is successful", volumeID, instanceID)
+	klog.V(4).InfoS("ControllerPublishVolume is successful", "volumeID", volumeID, "instanceID", instanceID)
 
-	return &csi.ControllerPublishVolumeResponse{}, nil
+	return &csi.ControllerPublishVolumeResponse{PublishContext: publishContext}, nil
 }
 
 func (cs *controllerServer) ControllerUnpublishVolume(ctx context.Context, req *csi.ControllerUnpublishVolumeRequest) (*csi.ControllerUnpublishVolumeResponse, error) { //nolint:lll // looks weird when shortened
-	klog.V(4).Infof("ControllerUnpublishVolume: called with args %+v", protosanitizer.StripSecrets(req))
+	klog.V(4).InfoS("ControllerUnpublishVolume called", "args", protosanitizer.StripSecrets(req))
 
 	cloud := cs.Instance
 
@@ -417,10 +794,17 @@ func (cs *controllerServer) ControllerUnpublishVolume(ctx context.Context, req *
 	if volumeID == "" {
 		return nil, status.Error(codes.InvalidArgument, "[ControllerUnpublishVolume] Volume ID must be provided")
 	}
+	if instanceID != "" {
+		var err error
+		instanceID, err = providerid.ServerID(instanceID)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "[ControllerUnpublishVolume] Invalid node ID: %v", err)
+		}
+	}
 	_, err := cloud.GetServer(ctx, instanceID)
 	if err != nil {
 		if stackiterrors.IsNotFound(err) {
-			klog.V(3).Infof("ControllerUnpublishVolume assuming volume %s is detached, because node %s does not exist", volumeID, instanceID)
+			klog.V(3).InfoS("ControllerUnpublishVolume assuming volume is detached, because the node does not exist", "volumeID", volumeID, "instanceID", instanceID)
 			return &csi.ControllerUnpublishVolumeResponse{}, nil
 		}
 		return nil, status.Errorf(codes.Internal, "[ControllerUnpublishVolume] GetInstanceByID failed with error %v", err)
@@ -429,30 +813,30 @@ func (cs *controllerServer) ControllerUnpublishVolume(ctx context.Context, req *
 	err = cloud.DetachVolume(ctx, instanceID, volumeID)
 	if err != nil {
 		if stackiterrors.IsNotFound(err) {
-			klog.V(3).Infof("ControllerUnpublishVolume assuming volume %s is detached, because it does not exist", volumeID)
+			klog.V(3).InfoS("ControllerUnpublishVolume assuming volume is detached, because it does not exist", "volumeID", volumeID)
 			return &csi.ControllerUnpublishVolumeResponse{}, nil
 		}
-		klog.Errorf("Failed to DetachVolume: %v", err)
+		klog.ErrorS(err, "Failed to DetachVolume", "volumeID", volumeID, "instanceID", instanceID)
 		return nil, status.Errorf(codes.Internal, "ControllerUnpublishVolume Detach Volume failed with error %v", err)
 	}
 
-	err = cloud.WaitDiskDetached(ctx, instanceID, volumeID)
+	err = cloud.WaitDiskDetached(ctx, instanceID, volumeID, new(cs.backoffs.detach))
 	if err != nil {
-		klog.Errorf("Failed to WaitDiskDetached: %v", err)
+		klog.ErrorS(err, "Failed to WaitDiskDetached", "volumeID", volumeID, "instanceID", instanceID)
 		if stackiterrors.IsNotFound(err) {
-			klog.V(3).Infof("ControllerUnpublishVolume assuming volume %s is detached, because it was deleted in the meanwhile", volumeID)
+			klog.V(3).InfoS("ControllerUnpublishVolume assuming volume is detached, because it was deleted in the meanwhile", "volumeID", volumeID)
 			return &csi.ControllerUnpublishVolumeResponse{}, nil
 		}
 		return nil, status.Errorf(codes.Internal, "ControllerUnpublishVolume failed with error %v", err)
 	}
 
-	klog.V(4).Infof("ControllerUnpublishVolume %s on %s", volumeID, instanceID)
+	klog.V(4).InfoS("ControllerUnpublishVolume is successful", "volumeID", volumeID, "instanceID", instanceID)
 
 	return &csi.ControllerUnpublishVolumeResponse{}, nil
 }
 
 func (cs *controllerServer) ListVolumes(ctx context.Context, req *csi.ListVolumesRequest) (*csi.ListVolumesResponse, error) {
-	klog.V(4).Infof("ListVolumes: called with %+#v request", req)
+	klog.V(4).InfoS("ListVolumes called", "request", req)
 
 	if req.GetStartingToken() != "" {
 		return nil, status.Error(codes.Aborted, "starting_token is not supported")
@@ -471,7 +855,7 @@ func (cs *controllerServer) ListVolumes(ctx context.Context, req *csi.ListVolume
 	// It's not used anyway.
 	volumeList, _, err = cloud.ListVolumes(ctx, maxEntries, "")
 	if err != nil {
-		klog.Errorf("Failed to ListVolumes: %v", err)
+		klog.ErrorS(err, "Failed to ListVolumes")
 		if stackiterrors.IsInvalidError(err) {
 			return nil, status.Errorf(codes.Aborted, "[ListVolumes] Invalid request: %v", err)
 		}
@@ -479,7 +863,7 @@ func (cs *controllerServer) ListVolumes(ctx context.Context, req *csi.ListVolume
 	}
 	volumeEntries := createVolumeEntries(volumeList)
 
-	klog.V(4).Infof("ListVolumes: completed with %d entries", len(volumeEntries))
+	klog.V(4).InfoS("ListVolumes completed", "entries", len(volumeEntries))
 	return &csi.ListVolumesResponse{
 		Entries:   volumeEntries,
 		NextToken: "",
@@ -488,13 +872,16 @@ func (cs *controllerServer) ListVolumes(ctx context.Context, req *csi.ListVolume
 
 //nolint:gocyclo,funlen // This function is complex and should be broken down further, but it's ok for now.
 func (cs *controllerServer) CreateSnapshot(ctx context.Context, req *csi.CreateSnapshotRequest) (*csi.CreateSnapshotResponse, error) {
-	klog.V(4).Infof("CreateSnapshot: called with args %+v", protosanitizer.StripSecrets(req))
+	klog.V(4).InfoS("CreateSnapshot called", "args", protosanitizer.StripSecrets(req))
 
 	cloud := cs.Instance
 
 	if cs.Driver.blockVolumeCreation {
 		return nil, status.Errorf(codes.Unimplemented, "The %s driver is update/read-only mode please migrate to the new driver", legacyDriverName)
 	}
+	if err := cs.Driver.requireControllerCapability(csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT); err != nil {
+		return nil, err
+	}
 
 	name := req.Name
 	volumeID := req.GetSourceVolumeId()
@@ -526,6 +913,11 @@ func (cs *controllerServer) CreateSnapshot(ctx context.Context, req *csi.CreateS
 		return nil, status.Error(codes.InvalidArgument, "VolumeID must be provided in CreateSnapshot request")
 	}
 
+	if !cs.operationLocks.TryAcquire(name) {
+		return nil, status.Errorf(codes.Aborted, "an operation for snapshot %s is already in progress", name)
+	}
+	defer cs.operationLocks.Release(name)
+
 	// Verify snapshot type has a valid value
 	if snapshotType != snapshotTypeSnapshot && snapshotType != snapshotTypeBackup {
 		return nil, status.Error(codes.InvalidArgument, "Snapshot type must be 'backup', 'snapshot' or not defined")
@@ -536,12 +928,12 @@ func (cs *controllerServer) CreateSnapshot(ctx context.Context, req *csi.CreateS
 		// Get a list of backups with the provided name
 		backups, err = cloud.ListBackups(ctx, filters)
 		if err != nil {
-			klog.Errorf("Failed to query for existing Backup during CreateSnapshot: %v", err)
+			klog.ErrorS(err, "Failed to query for existing backup during CreateSnapshot", "name", name)
 			return nil, status.Error(codes.Internal, "Failed to get backups")
 		}
 		// If more than one backup with the provided name exists, fail
 		if len(backups) > 1 {
-			klog.Errorf("found multiple existing backups with selected name (%s) during create", name)
+			klog.ErrorS(nil, "Found multiple existing backups with selected name during create", "name", name)
 			return nil, status.Error(codes.Internal, "Multiple backups reported by Cinder with same name")
 		}
 
@@ -549,20 +941,20 @@ func (cs *controllerServer) CreateSnapshot(ctx context.Context, req *csi.CreateS
 			backup = &backups[0]
 			// Verify the existing backup has the same VolumeID, otherwise it belongs to another volume
 			if *backup.VolumeId != volumeID {
-				klog.Errorf("found existing backup for volumeID (%s) but different source volume ID (%s)", volumeID, *backup.VolumeId)
+				klog.ErrorS(nil, "Found existing backup with different source volume ID", "volumeID", volumeID, "backupVolumeID", *backup.VolumeId)
 				return nil, status.Error(codes.AlreadyExists, "Backup with given name already exists, with different source volume ID")
 			}
 
 			// If a backup of the volume already exists, skip creating the snapshot
 			backupAlreadyExists = true
-			klog.V(3).Infof("Found existing backup %s from volume with ID: %s", name, volumeID)
+			klog.V(3).InfoS("Found existing backup", "name", name, "volumeID", volumeID)
 		}
 
 		// Get the max duration to wait in seconds per GB of snapshot and fail if parsing fails
 		if item, ok := (req.Parameters)[stackitclient.BackupMaxDurationPerGB]; ok {
 			backupMaxDurationSecondsPerGB, err = strconv.Atoi(item)
 			if err != nil {
-				klog.Errorf("Setting backup-max-duration-seconds-per-gb failed due to a parsing error: %v", err)
+				klog.ErrorS(err, "Setting backup-max-duration-seconds-per-gb failed due to a parsing error")
 				return nil, status.Error(codes.Internal, "Failed to parse backup-max-duration-seconds-per-gb")
 			}
 		}
@@ -570,19 +962,35 @@ func (cs *controllerServer) CreateSnapshot(ctx context.Context, req *csi.CreateS
 
 	// Create the snapshot if the backup does not already exist and wait for it to be ready
 	if !backupAlreadyExists {
-		snap, err = cs.createSnapshot(ctx, name, volumeID, req.Parameters)
+		snap, err = cs.findSnapshot(ctx, name, volumeID)
 		if err != nil {
 			return nil, err
 		}
+		// Only calls that create a snapshot or backup take a slot, retries of calls whose snapshot already exists
+		// don't wait in the queue.
+		if snap == nil || snapshotType == snapshotTypeBackup {
+			var release func()
+			release, err = cs.snapshots.acquire(ctx)
+			if err != nil {
+				return nil, err
+			}
+			defer release()
+		}
+		if snap == nil {
+			snap, err = cs.createSnapshot(ctx, name, volumeID, req.Parameters)
+			if err != nil {
+				return nil, err
+			}
+		}
 
 		ctime = timestamppb.New(*snap.CreatedAt)
 		if err = ctime.CheckValid(); err != nil {
-			klog.Errorf("Error to convert time to timestamp: %v", err)
+			klog.ErrorS(err, "Error to convert time to timestamp")
 		}
 
-		snap.Status, err = cloud.WaitSnapshotReady(ctx, *snap.Id)
+		snap.Status, err = cloud.WaitSnapshotReady(ctx, *snap.Id, new(cs.backoffs.snapshot))
 		if err != nil {
-			klog.Errorf("Failed to WaitSnapshotReady: %v", err)
+			klog.ErrorS(err, "Failed to WaitSnapshotReady")
 			return nil, status.Errorf(codes.Internal, "CreateSnapshot failed with error: %v. Current snapshot status: %v", err, snap.Status)
 		}
 
@@ -613,25 +1021,25 @@ func (cs *controllerServer) CreateSnapshot(ctx context.Context, req *csi.CreateS
 
 	ctime = timestamppb.New(*backup.CreatedAt)
 	if err := ctime.CheckValid(); err != nil {
-		klog.Errorf("Error to convert time to timestamp: %v", err)
+		klog.ErrorS(err, "Error to convert time to timestamp")
 	}
 
 	backup.Status, err = cloud.WaitBackupReady(ctx, *backup.Id, snapSize, backupMaxDurationSecondsPerGB)
 	if err != nil {
-		klog.Errorf("Failed to WaitBackupReady: %v", err)
+		klog.ErrorS(err, "Failed to WaitBackupReady")
 		return nil, status.Error(codes.Internal, fmt.Sprintf("CreateBackup failed with error %v. Current backups status: %s", err, *backup.Status))
 	}
 
 	// Necessary to get all the backup information, including size.
 	backup, err = cloud.GetBackup(ctx, *backup.Id)
 	if err != nil {
-		klog.Errorf("Failed to GetBackupByID after backup creation: %v", err)
+		klog.ErrorS(err, "Failed to GetBackupByID after backup creation")
 		return nil, status.Error(codes.Internal, fmt.Sprintf("GetBackupByID failed with error %v", err))
 	}
 
 	err = cloud.DeleteSnapshot(ctx, *backup.SnapshotId)
 	if err != nil && !stackiterrors.IsNotFound(err) {
-		klog.Errorf("Failed to DeleteSnapshot: %v", err)
+		klog.ErrorS(err, "Failed to DeleteSnapshot")
 		return nil, status.Error(codes.Internal, fmt.Sprintf("DeleteSnapshot failed with error %v", err))
 	}
 
@@ -646,20 +1054,21 @@ func (cs *controllerServer) CreateSnapshot(ctx context.Context, req *csi.CreateS
 	}, nil
 }
 
-func (cs *controllerServer) createSnapshot(ctx context.Context, name, volumeID string, parameters map[string]string) (*iaas.Snapshot, error) {
+// findSnapshot returns the snapshot with the given name of the volume, or nil if there is none.
+func (cs *controllerServer) findSnapshot(ctx context.Context, name, volumeID string) (*iaas.Snapshot, error) {
 	filters := map[string]string{}
 	filters["Name"] = name
 
 	// List existing snapshots with the same name
 	snapshots, _, err := cs.Instance.ListSnapshots(ctx, filters)
 	if err != nil {
-		klog.Errorf("Failed to query for existing Snapshot during CreateSnapshot: %v", err)
+		klog.ErrorS(err, "Failed to query for existing snapshot during CreateSnapshot", "name", name)
 		return nil, status.Error(codes.Internal, "Failed to get snapshots")
 	}
 
 	// If more than one snapshot with the provided name exists, fail
 	if len(snapshots) > 1 {
-		klog.Errorf("found multiple existing snapshots with selected name (%s) during create", name)
+		klog.ErrorS(nil, "Found multiple existing snapshots with selected name during create", "name", name)
 
 		return nil, status.Error(codes.Internal, "Multiple snapshots reported by Cinder with same name")
 	}
@@ -672,9 +1081,17 @@ func (cs *controllerServer) createSnapshot(ctx context.Context, name, volumeID s
 		}
 
 		// If the snapshot for the correct volume already exists, return it
-		klog.V(3).Infof("Found existing snapshot %s from volume with ID: %s", name, volumeID)
+		klog.V(3).InfoS("Found existing snapshot", "name", name, "volumeID", volumeID)
 		return snap, nil
 	}
+	return nil, nil
+}
+
+// createSnapshot creates a snapshot of the volume, which must not exist yet, see findSnapshot.
+func (cs *controllerServer) createSnapshot(ctx context.Context, name, volumeID string, parameters map[string]string) (*iaas.Snapshot, error) {
+	if err := cs.checkSnapshotSource(ctx, volumeID, parameters); err != nil {
+		return nil, err
+	}
 
 	// Add cluster ID to the snapshot metadata
 	// TODO: Use once IaaS has extended the label regex to allow for forward slashes and dots
@@ -689,6 +1106,8 @@ func (cs *controllerServer) createSnapshot(ctx context.Context, name, volumeID s
 		}
 	}
 
+	properties = stackitclient.WithResourceLabels(properties, cs.Driver.resourceLabels)
+
 	payload := &iaas.CreateSnapshotPayload{
 		Name:     new(name),
 		VolumeId: volumeID,
@@ -699,11 +1118,14 @@ func (cs *controllerServer) createSnapshot(ctx context.Context, name, volumeID s
 
 	snap, err := cs.Instance.CreateSnapshot(ctx, *payload)
 	if err != nil {
-		klog.Errorf("Failed to Create snapshot: %v", err)
-		return nil, status.Errorf(codes.Internal, "CreateSnapshot failed with error %v", err)
+		snap = cs.findSnapshotAfterFailedCreate(ctx, name, volumeID, err)
+		if snap == nil {
+			klog.ErrorS(err, "Failed to create snapshot", "name", name)
+			return nil, status.Errorf(codes.Internal, "CreateSnapshot failed with error %v", err)
+		}
 	}
 
-	klog.V(3).Infof("CreateSnapshot %s from volume with ID: %s", name, volumeID)
+	klog.V(3).InfoS("CreateSnapshot created snapshot", "name", name, "volumeID", volumeID)
 
 	return snap, nil
 }
@@ -722,18 +1144,49 @@ func (cs *controllerServer) createBackup(ctx context.Context, cloud stackitclien
 		}
 	}
 
+	properties = stackitclient.WithResourceLabels(properties, cs.Driver.resourceLabels)
+
 	backup, err := cloud.CreateBackup(ctx, name, volumeID, *snap.Id, properties)
 	if err != nil {
-		klog.Errorf("Failed to Create backup: %v", err)
-		return nil, status.Error(codes.Internal, fmt.Sprintf("CreateBackup failed with error %v", err))
+		backup = cs.findBackupAfterFailedCreate(ctx, name, volumeID, err)
+		if backup == nil {
+			klog.ErrorS(err, "Failed to create backup", "name", name)
+			return nil, status.Error(codes.Internal, fmt.Sprintf("CreateBackup failed with error %v", err))
+		}
 	}
-	klog.V(4).Infof("Backup created: %+v", backup)
+	klog.V(4).InfoS("Backup created", "backup", backup)
 
 	return backup, nil
 }
 
+// findSnapshotAfterFailedCreate is the snapshot counterpart of findVolumeAfterFailedCreate.
+func (cs *controllerServer) findSnapshotAfterFailedCreate(ctx context.Context, name, volumeID string, createErr error) *iaas.Snapshot {
+	if !stackiterrors.IsAmbiguousError(createErr) {
+		return nil
+	}
+	snapshots, _, err := cs.Instance.ListSnapshots(ctx, map[string]string{"Name": name, "VolumeID": volumeID})
+	if err != nil || len(snapshots) != 1 {
+		return nil
+	}
+	klog.V(3).InfoS("CreateSnapshot failed, but snapshot was created anyway", "name", name, "err", createErr, "snapshotID", *snapshots[0].Id)
+	return &snapshots[0]
+}
+
+// findBackupAfterFailedCreate is the backup counterpart of findVolumeAfterFailedCreate.
+func (cs *controllerServer) findBackupAfterFailedCreate(ctx context.Context, name, volumeID string, createErr error) *iaas.Backup {
+	if !stackiterrors.IsAmbiguousError(createErr) {
+		return nil
+	}
+	backups, err := cs.Instance.ListBackups(ctx, map[string]string{"Name": name, "VolumeID": volumeID})
+	if err != nil || len(backups) != 1 {
+		return nil
+	}
+	klog.V(3).InfoS("CreateBackup failed, but backup was created anyway", "name", name, "err", createErr, "backupID", *backups[0].Id)
+	return &backups[0]
+}
+
 func (cs *controllerServer) DeleteSnapshot(ctx context.Context, req *csi.DeleteSnapshotRequest) (*csi.DeleteSnapshotResponse, error) {
-	klog.V(4).Infof("DeleteSnapshot: called with args %+v", protosanitizer.StripSecrets(req))
+	klog.V(4).InfoS("DeleteSnapshot called", "args", protosanitizer.StripSecrets(req))
 
 	cloud := cs.Instance
 
@@ -743,12 +1196,21 @@ func (cs *controllerServer) DeleteSnapshot(ctx context.Context, req *csi.DeleteS
 		return nil, status.Error(codes.InvalidArgument, "Snapshot ID must be provided in DeleteSnapshot request")
 	}
 
+	// Deleting the source of a restore that is still in progress would make the volume creation fail midway.
+	vols, _, err := cloud.ListVolumes(ctx, 0, "")
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to list volumes restored from snapshot %s: %v", id, err)
+	}
+	if volumes := cs.restoresOf(id, vols); len(volumes) > 0 {
+		return nil, status.Errorf(codes.FailedPrecondition, "snapshot %s in use by restore of volume %s", id, strings.Join(volumes, ", "))
+	}
+
 	// If volumeSnapshot object was linked to a cinder backup, delete the backup.
 	back, err := cloud.GetBackup(ctx, id)
 	if err == nil && back != nil {
 		err = cloud.DeleteBackup(ctx, id)
 		if err != nil {
-			klog.Errorf("Failed to Delete backup: %v", err)
+			klog.ErrorS(err, "Failed to delete backup", "backupID", id)
 			return nil, status.Error(codes.Internal, fmt.Sprintf("DeleteBackup failed with error %v", err))
 		}
 	}
@@ -757,10 +1219,10 @@ func (cs *controllerServer) DeleteSnapshot(ctx context.Context, req *csi.DeleteS
 	err = cloud.DeleteSnapshot(ctx, id)
 	if err != nil {
 		if stackiterrors.IsNotFound(err) {
-			klog.V(3).Infof("Snapshot %s is already deleted.", id)
+			klog.V(3).InfoS("Snapshot is already deleted", "snapshotID", id)
 			return &csi.DeleteSnapshotResponse{}, nil
 		}
-		klog.Errorf("Failed to Delete snapshot: %v", err)
+		klog.ErrorS(err, "Failed to delete snapshot", "snapshotID", id)
 		return nil, status.Errorf(codes.Internal, "DeleteSnapshot failed with error %v", err)
 	}
 	return &csi.DeleteSnapshotResponse{}, nil
@@ -780,7 +1242,7 @@ func (cs *controllerServer) ListSnapshots(ctx context.Context, req *csi.ListSnap
 			backup, backupErr := cloud.GetBackup(ctx, snapshotID)
 			if backupErr != nil {
 				if stackiterrors.IsNotFound(backupErr) {
-					klog.V(3).Infof("Snapshot or backup %s not found", snapshotID)
+					klog.V(3).InfoS("Snapshot or backup not found", "snapshotID", snapshotID)
 					return &csi.ListSnapshotsResponse{}, nil
 				}
 				return nil, status.Errorf(codes.Internal, "Failed to GetBackup %s: %v", snapshotID, backupErr)
@@ -806,13 +1268,13 @@ func (cs *controllerServer) ListSnapshots(ctx context.Context, req *csi.ListSnap
 
 	snapshotList, _, err := cloud.ListSnapshots(ctx, filters)
 	if err != nil {
-		klog.Errorf("Failed to ListSnapshots: %v", err)
+		klog.ErrorS(err, "Failed to ListSnapshots")
 		return nil, status.Errorf(codes.Internal, "ListSnapshots failed with error %v", err)
 	}
 
 	backupList, err := cloud.ListBackups(ctx, filters)
 	if err != nil {
-		klog.Errorf("Failed to ListBackups: %v", err)
+		klog.ErrorS(err, "Failed to ListBackups")
 		return nil, status.Errorf(codes.Internal, "ListBackups failed with error %v", err)
 	}
 
@@ -833,16 +1295,19 @@ func (cs *controllerServer) ListSnapshots(ctx context.Context, req *csi.ListSnap
 func snapshotEntry(snapshot *iaas.Snapshot) *csi.ListSnapshotsResponse_Entry {
 	ctime := timestamppb.New(*snapshot.CreatedAt)
 	if err := ctime.CheckValid(); err != nil {
-		klog.Errorf("Error to convert time to timestamp: %v", err)
+		klog.ErrorS(err, "Error to convert time to timestamp")
 	}
 
+	groupSnapshotID, _ := snapshot.GetLabels()[stackitclient.SnapshotGroupLabel].(string)
+
 	return &csi.ListSnapshotsResponse_Entry{
 		Snapshot: &csi.Snapshot{
-			SizeBytes:      *snapshot.Size * util.GIBIBYTE,
-			SnapshotId:     *snapshot.Id,
-			SourceVolumeId: snapshot.VolumeId,
-			CreationTime:   ctime,
-			ReadyToUse:     true,
+			SizeBytes:       *snapshot.Size * util.GIBIBYTE,
+			SnapshotId:      *snapshot.Id,
+			SourceVolumeId:  snapshot.VolumeId,
+			CreationTime:    ctime,
+			ReadyToUse:      true,
+			GroupSnapshotId: groupSnapshotID,
 		},
 	}
 }
@@ -850,7 +1315,7 @@ func snapshotEntry(snapshot *iaas.Snapshot) *csi.ListSnapshotsResponse_Entry {
 func backupSnapshotEntry(backup *iaas.Backup) *csi.ListSnapshotsResponse_Entry {
 	ctime := timestamppb.New(*backup.CreatedAt)
 	if err := ctime.CheckValid(); err != nil {
-		klog.Errorf("Error to convert time to timestamp: %v", err)
+		klog.ErrorS(err, "Error to convert time to timestamp")
 	}
 
 	return &csi.ListSnapshotsResponse_Entry{
@@ -867,7 +1332,7 @@ func backupSnapshotEntry(backup *iaas.Backup) *csi.ListSnapshotsResponse_Entry {
 // ControllerGetCapabilities implements the default GRPC callout.
 // Default supports all capabilities
 func (cs *controllerServer) ControllerGetCapabilities(_ context.Context, _ *csi.ControllerGetCapabilitiesRequest) (*csi.ControllerGetCapabilitiesResponse, error) {
-	klog.V(5).Infof("Using default ControllerGetCapabilities")
+	klog.V(5).InfoS("Using default ControllerGetCapabilities")
 
 	return &csi.ControllerGetCapabilitiesResponse{
 		Capabilities: cs.Driver.cscap,
@@ -897,19 +1362,14 @@ func (cs *controllerServer) ValidateVolumeCapabilities(ctx context.Context, req
 	}
 
 	for _, volCap := range reqVolCap {
-		if volCap.GetAccessMode().GetMode() != cs.Driver.vcap[0].Mode {
+		if !cs.Driver.supportsAccessMode(volCap.GetAccessMode().GetMode()) {
 			return &csi.ValidateVolumeCapabilitiesResponse{Message: "Requested Volume Capability not supported"}, nil
 		}
 	}
 
-	// Block Storage CSI driver currently supports one mode only
 	resp := &csi.ValidateVolumeCapabilitiesResponse{
 		Confirmed: &csi.ValidateVolumeCapabilitiesResponse_Confirmed{
-			VolumeCapabilities: []*csi.VolumeCapability{
-				{
-					AccessMode: cs.Driver.vcap[0],
-				},
-			},
+			VolumeCapabilities: reqVolCap,
 		},
 	}
 
@@ -921,7 +1381,7 @@ func (cs *controllerServer) GetCapacity(_ context.Context, _ *csi.GetCapacityReq
 }
 
 func (cs *controllerServer) ControllerGetVolume(ctx context.Context, req *csi.ControllerGetVolumeRequest) (*csi.ControllerGetVolumeResponse, error) {
-	klog.V(4).Infof("ControllerGetVolume: called with args %+v", protosanitizer.StripSecrets(req))
+	klog.V(4).InfoS("ControllerGetVolume called", "args", protosanitizer.StripSecrets(req))
 
 	cloud := cs.Instance
 	volumeID := req.GetVolumeId()
@@ -944,21 +1404,28 @@ func (cs *controllerServer) ControllerGetVolume(ctx context.Context, req *csi.Co
 		Volume: &csi.Volume{
 			VolumeId:      volumeID,
 			CapacityBytes: *volume.Size * util.GIBIBYTE,
+			VolumeContext: volumeContext(volume),
 		},
 	}
 
 	volumeStatus := &csi.ControllerGetVolumeResponse_VolumeStatus{}
-	volumeStatus.PublishedNodeIds = []string{*volume.ServerId}
+	if volume.ServerId != nil {
+		volumeStatus.PublishedNodeIds = []string{*volume.ServerId}
+	}
 	ventry.Status = volumeStatus
 
 	return &ventry, nil
 }
 
 func (cs *controllerServer) ControllerExpandVolume(ctx context.Context, req *csi.ControllerExpandVolumeRequest) (*csi.ControllerExpandVolumeResponse, error) {
-	klog.V(4).Infof("ControllerExpandVolume: called with args %+v", protosanitizer.StripSecrets(req))
+	klog.V(4).InfoS("ControllerExpandVolume called", "args", protosanitizer.StripSecrets(req))
 
 	cloud := cs.Instance
 
+	if err := cs.Driver.requireControllerCapability(csi.ControllerServiceCapability_RPC_EXPAND_VOLUME); err != nil {
+		return nil, err
+	}
+
 	volumeID := req.GetVolumeId()
 	if volumeID == "" {
 		return nil, status.Error(codes.InvalidArgument, "Volume ID not provided")
@@ -975,6 +1442,10 @@ func (cs *controllerServer) ControllerExpandVolume(ctx context.Context, req *csi
 	if maxVolSize > 0 && maxVolSize < volSizeBytes {
 		return nil, status.Error(codes.OutOfRange, "After round-up, volume size exceeds the limit specified")
 	}
+	// The parameters of the StorageClass aren't passed on expansion, so only the maximum size of the driver applies.
+	if err := checkMaxVolumeSize(volSizeGB, cs.Opts.MaxVolumeSize); err != nil {
+		return nil, err
+	}
 
 	volume, err := cloud.GetVolume(ctx, volumeID)
 	if err != nil {
@@ -983,10 +1454,12 @@ func (cs *controllerServer) ControllerExpandVolume(ctx context.Context, req *csi
 		}
 		return nil, status.Errorf(codes.Internal, "GetVolume failed with error %v", err)
 	}
+	// Volumes created before resource labels were enabled get them on their next expansion.
+	cs.addMissingResourceLabels(ctx, volume)
 
 	if *volume.Size >= volSizeGB {
 		// a volume was already resized
-		klog.V(2).Infof("Volume %q has been already expanded to %d, requested %d", volumeID, volume.Size, volSizeGB)
+		klog.V(2).InfoS("Volume has been already expanded", "volumeID", volumeID, "size", volume.Size, "requestedSize", volSizeGB)
 		return &csi.ControllerExpandVolumeResponse{
 			CapacityBytes:         *volume.Size * util.GIBIBYTE,
 			NodeExpansionRequired: true,
@@ -1000,13 +1473,13 @@ func (cs *controllerServer) ControllerExpandVolume(ctx context.Context, req *csi
 
 	// we need wait for the volume to be available or InUse, it might be error_extending in some scenario
 	targetStatus := []string{stackitclient.VolumeAvailableStatus, stackitclient.VolumeAttachedStatus}
-	err = cloud.WaitVolumeTargetStatus(ctx, volumeID, targetStatus)
+	err = cloud.WaitVolumeTargetStatusWithCustomBackoff(ctx, volumeID, targetStatus, new(cs.backoffs.expand))
 	if err != nil {
-		klog.Errorf("Failed to WaitVolumeTargetStatus of volume %s: %v", volumeID, err)
+		klog.ErrorS(err, "Failed to WaitVolumeTargetStatus", "volumeID", volumeID)
 		return nil, status.Errorf(codes.Internal, "[ControllerExpandVolume] Volume %s not in target state after resize operation: %v", volumeID, err)
 	}
 
-	klog.V(4).Infof("ControllerExpandVolume resized volume %v to size %v", volumeID, volSizeGB)
+	klog.V(4).InfoS("ControllerExpandVolume resized volume", "volumeID", volumeID, "size", volSizeGB)
 
 	return &csi.ControllerExpandVolumeResponse{
 		CapacityBytes:         volSizeBytes,
@@ -1017,7 +1490,7 @@ func (cs *controllerServer) ControllerExpandVolume(ctx context.Context, req *csi
 func (cs *controllerServer) getCreateVolumeResponse(vol *iaas.Volume) *csi.CreateVolumeResponse {
 	var volsrc *csi.VolumeContentSource
 	var volumeSourceType stackitclient.VolumeSourceTypes
-	volCnx := map[string]string{}
+	volCnx := volumeContext(vol)
 
 	if vol.Source != nil {
 		volumeSourceType = stackitclient.VolumeSourceTypes(vol.Source.Type)
@@ -1055,14 +1528,9 @@ func (cs *controllerServer) getCreateVolumeResponse(vol *iaas.Volume) *csi.Creat
 		}
 	}
 
-	topoKey := topologyKey
-	if cs.Driver.legacyDriver {
-		topoKey = legacyTopologyKey
-	}
-
 	accessibleTopology := []*csi.Topology{
 		{
-			Segments: map[string]string{topoKey: vol.AvailabilityZone},
+			Segments: map[string]string{cs.Driver.topologyKey: vol.AvailabilityZone},
 		},
 	}
 
@@ -1079,6 +1547,24 @@ func (cs *controllerServer) getCreateVolumeResponse(vol *iaas.Volume) *csi.Creat
 	return resp
 }
 
+// volumeContext returns the metadata of the volume that is exposed in the volume context.
+func volumeContext(vol *iaas.Volume) map[string]string {
+	volCnx := map[string]string{}
+	if vol.PerformanceClass != nil {
+		volCnx[PerformanceClassKey] = *vol.PerformanceClass
+	}
+	if vol.AvailabilityZone != "" {
+		volCnx[AvailabilityZoneKey] = vol.AvailabilityZone
+	}
+	if vol.Encrypted != nil {
+		volCnx[EncryptedKey] = strconv.FormatBool(*vol.Encrypted)
+	}
+	if vol.Source != nil {
+		volCnx[SourceTypeKey] = vol.Source.Type
+	}
+	return volCnx
+}
+
 // determineSourceIDForSourceType returns the correct sourceID for the given stackitclient.VolumeSourceTypes
 func determineSourceIDForSourceType(srcType stackitclient.VolumeSourceTypes, sourceSnapshotID, sourceVolID string) string {
 	switch srcType {
@@ -1121,6 +1607,40 @@ func createVolumeEntries(vlist []iaas.Volume) []*csi.ListVolumesResponse_Entry {
 	return entries
 }
 
+// parseEncrypted returns whether the parameters request an encrypted volume. KMS parameters are rejected unless
+// encrypted is true, because the volume would be created without encryption otherwise.
+func parseEncrypted(volParams *stackitParameterConfig) (bool, error) {
+	encrypted := false
+	if volParams.Encrypted != nil {
+		var err error
+		encrypted, err = strconv.ParseBool(*volParams.Encrypted)
+		if err != nil {
+			return false, errors.New("parameter encrypted must be of type boolean")
+		}
+	}
+	if !encrypted && (volParams.KMSKeyID != nil || volParams.KMSKeyringID != nil || volParams.KMSKeyVersion != nil ||
+		volParams.KMSServiceAccount != nil || volParams.KMSProjectID != nil) {
+		return false, errors.New(`parameters kmsKeyID, kmsKeyringID, kmsKeyVersion, kmsServiceAccount and kmsProjectID require encrypted: "true"`)
+	}
+	return encrypted, nil
+}
+
+// validateInheritedEncryption rejects encryption parameters that differ from the encryption of source, which volumes
+// created from a snapshot or volume inherit.
+func validateInheritedEncryption(source *iaas.Volume, encrypted bool, volParams *stackitParameterConfig) error {
+	sourceEncrypted := ptr.Deref(source.Encrypted, false)
+	switch {
+	case encrypted && !sourceEncrypted:
+		return errors.New("the source isn't encrypted")
+	case !encrypted && sourceEncrypted:
+		return errors.New("the source is encrypted")
+	case encrypted && source.EncryptionParameters != nil && volParams.KMSKeyID != nil &&
+		*volParams.KMSKeyID != source.EncryptionParameters.KekKeyId:
+		return fmt.Errorf("the source is encrypted with the KMS key %s instead of %s", source.EncryptionParameters.KekKeyId, *volParams.KMSKeyID)
+	}
+	return nil
+}
+
 // validateEncryptionConfig validates that the required parameters are set
 func validateEncryptionConfig(volParams *stackitParameterConfig) error {
 	if volParams.PerformanceClass == nil {
@@ -1140,3 +1660,8 @@ func validateEncryptionConfig(volParams *stackitParameterConfig) error {
 	}
 	return nil
 }
+
+// publishedReadOnly reports whether the volume was last published read-only.
+func publishedReadOnly(vol *iaas.Volume) bool {
+	return vol.Labels[publishedReadOnlyLabel] == "true"
+}
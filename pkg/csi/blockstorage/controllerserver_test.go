@@ -10,16 +10,27 @@ import (
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	sharedcsi "github.com/stackitcloud/cloud-provider-stackit/pkg/csi"
 	"github.com/stackitcloud/cloud-provider-stackit/pkg/csi/util"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/features"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/metrics"
 	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
 	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
 	"github.com/stackitcloud/stackit-sdk-go/core/oapierror"
 	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
 	"go.uber.org/mock/gomock"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	featuregatetesting "k8s.io/component-base/featuregate/testing"
 )
 
 var _ = Describe("ControllerServer test", Ordered, func() {
@@ -54,7 +65,7 @@ var _ = Describe("ControllerServer test", Ordered, func() {
 		mockCtrl := gomock.NewController(GinkgoT())
 		iaasClient = stackitclientmock.NewMockIaaSClient(mockCtrl)
 
-		fakeCs = NewControllerServer(d, iaasClient)
+		fakeCs = NewControllerServer(d, iaasClient, stackitconfig.BlockStorageOpts{})
 	})
 
 	Describe("CreateVolume", func() {
@@ -80,6 +91,7 @@ var _ = Describe("ControllerServer test", Ordered, func() {
 			Expect(resp).NotTo(BeNil())
 			Expect(resp.Volume.VolumeId).To(Equal("volume-id"))
 			Expect(resp.Volume.CapacityBytes).To(Equal(util.GIBIBYTE * 20))
+			Expect(resp.Volume.VolumeContext).To(Equal(map[string]string{AvailabilityZoneKey: "eu01"}))
 		})
 
 		It("should not accept an empty volume name", func() {
@@ -105,6 +117,22 @@ var _ = Describe("ControllerServer test", Ordered, func() {
 			Expect(err.Error()).To(ContainSubstring("missing Volume capability"))
 		})
 
+		It("should not accept dangerous mount options", func() {
+			req := &csi.CreateVolumeRequest{
+				Name: "volume name",
+				VolumeCapabilities: []*csi.VolumeCapability{{
+					AccessType: &csi.VolumeCapability_Mount{
+						Mount: &csi.VolumeCapability_MountVolume{MountFlags: []string{"dev"}},
+					},
+					AccessMode: stdVolCap.AccessMode,
+				}},
+			}
+
+			_, err := fakeCs.CreateVolume(context.Background(), req)
+			Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+			Expect(err.Error()).To(ContainSubstring(`mount option "dev" is not allowed`))
+		})
+
 		It("should prefer the availability zone defined in VolumeParameters", func() {
 			req := &csi.CreateVolumeRequest{
 				Name:               "volume name",
@@ -162,6 +190,38 @@ var _ = Describe("ControllerServer test", Ordered, func() {
 			Expect(err).ToNot(HaveOccurred())
 		})
 
+		It("should understand both topology keys with a configured topology key", func() {
+			fakeCs.Driver.topologyKey = corev1.LabelTopologyZone
+			req := &csi.CreateVolumeRequest{
+				Name:               "volume name",
+				VolumeCapabilities: stdVolCaps,
+				Parameters:         map[string]string{"type": "perf1"},
+				AccessibilityRequirements: &csi.TopologyRequirement{
+					Requisite: []*csi.Topology{
+						{Segments: map[string]string{topologyKey: "zone-from-accessibility-reqs"}},
+					},
+				},
+			}
+
+			iaasClient.EXPECT().GetVolumesByName(gomock.Any(), "volume name").Return([]iaas.Volume{}, nil)
+			iaasClient.EXPECT().CreateVolume(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, payload iaas.CreateVolumePayload) (*iaas.Volume, error) {
+					Expect(payload.AvailabilityZone).To(Equal("zone-from-accessibility-reqs"))
+					return &iaas.Volume{
+						Id:               new("volume-id"),
+						Name:             new("volume name"),
+						AvailabilityZone: "zone-from-accessibility-reqs",
+						Size:             new(int64(20)),
+					}, nil
+				})
+			iaasClient.EXPECT().WaitVolumeTargetStatusWithCustomBackoff(gomock.Any(), "volume-id", gomock.Any(), gomock.Any()).Return(nil)
+
+			resp, err := fakeCs.CreateVolume(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.GetVolume().GetAccessibleTopology()).To(ConsistOf(
+				HaveField("Segments", Equal(map[string]string{corev1.LabelTopologyZone: "zone-from-accessibility-reqs"}))))
+		})
+
 		It("should fail when looking for existing volumes fails", func() {
 			req := &csi.CreateVolumeRequest{
 				Name:               "new volume",
@@ -246,6 +306,33 @@ var _ = Describe("ControllerServer test", Ordered, func() {
 			Expect(err.Error()).To(ContainSubstring("is not in available state"))
 		})
 
+		It("should resume tracking the restore if a volume exists that is still restored from a backup", func() {
+			req := &csi.CreateVolumeRequest{
+				Name:               "new volume",
+				VolumeCapabilities: stdVolCaps,
+				CapacityRange:      stdCapRange,
+			}
+			inFlight := testutil.ToFloat64(metrics.CSIBackupRestoresInFlight)
+
+			iaasClient.EXPECT().GetVolumesByName(gomock.Any(), "new volume").Return([]iaas.Volume{
+				{
+					Id:               new("restoring-volume-id"),
+					Name:             new("new volume"),
+					Size:             new(int64(20)),
+					Status:           new(stackitclient.VolumeRestoringStatus),
+					Source:           &iaas.VolumeSource{Id: "backup-id", Type: string(stackitclient.BackupSource)},
+					AvailabilityZone: "eu01",
+				},
+			}, nil)
+
+			_, err := fakeCs.CreateVolume(context.Background(), req)
+			Expect(status.Code(err)).To(Equal(codes.Internal))
+			Expect(testutil.ToFloat64(metrics.CSIBackupRestoresInFlight)).To(Equal(inFlight + 1))
+
+			fakeCs.restoreProgress.completed("restoring-volume-id")
+			Expect(testutil.ToFloat64(metrics.CSIBackupRestoresInFlight)).To(Equal(inFlight))
+		})
+
 		It("should fail if more than one volume with the same name are available", func() {
 			req := &csi.CreateVolumeRequest{
 				Name:               "new volume",
@@ -288,6 +375,23 @@ var _ = Describe("ControllerServer test", Ordered, func() {
 				iaasClient.EXPECT().GetVolumesByName(gomock.Any(), "new volume").Return([]iaas.Volume{}, nil)
 			})
 
+			It("should create an empty volume if the content source has neither a snapshot nor a volume", func() {
+				req.VolumeContentSource = &csi.VolumeContentSource{}
+				iaasClient.EXPECT().CreateVolume(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, payload iaas.CreateVolumePayload) (*iaas.Volume, error) {
+					Expect(payload.Source).To(BeNil())
+					return &iaas.Volume{
+						Id:               new("volume-id"),
+						AvailabilityZone: "eu01",
+						Size:             new(int64(20)),
+					}, nil
+				})
+				iaasClient.EXPECT().WaitVolumeTargetStatusWithCustomBackoff(gomock.Any(), "volume-id", gomock.Any(), gomock.Any()).Return(nil)
+
+				resp, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp.Volume.ContentSource).To(BeNil())
+			})
+
 			It("should use a snapshot if a snapshot ID is provided as content source and the snapshot is available", func() {
 				req.VolumeContentSource = &csi.VolumeContentSource{
 					Type: &csi.VolumeContentSource_Snapshot{
@@ -326,6 +430,58 @@ var _ = Describe("ControllerServer test", Ordered, func() {
 				Expect(err).ToNot(HaveOccurred())
 			})
 
+			It("should block deleting the source snapshot until the restore has completed", func() {
+				req.VolumeContentSource = &csi.VolumeContentSource{
+					Type: &csi.VolumeContentSource_Snapshot{
+						Snapshot: &csi.VolumeContentSource_SnapshotSource{
+							SnapshotId: "snapshot-id",
+						},
+					},
+				}
+
+				iaasClient.EXPECT().GetSnapshot(gomock.Any(), "snapshot-id").Return(&iaas.Snapshot{
+					Id:               new("snapshot-id"),
+					Status:           new("AVAILABLE"),
+					VolumeId:         "snapshot-volume-id",
+					AvailabilityZone: new("eu01"),
+				}, nil)
+				iaasClient.EXPECT().CreateVolume(gomock.Any(), gomock.Any()).Return(&iaas.Volume{
+					Id:               new("volume-id"),
+					Name:             new("new volume"),
+					AvailabilityZone: "eu01",
+					Size:             new(int64(20)),
+				}, nil)
+				iaasClient.EXPECT().WaitVolumeTargetStatusWithCustomBackoff(gomock.Any(), "volume-id", gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, _ string, _ []string, _ *wait.Backoff) error {
+						iaasClient.EXPECT().ListVolumes(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, "", nil)
+						_, err := fakeCs.DeleteSnapshot(ctx, &csi.DeleteSnapshotRequest{SnapshotId: "snapshot-id"})
+						Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+						Expect(status.Convert(err).Message()).To(Equal("snapshot snapshot-id in use by restore of volume new volume"))
+						return nil
+					})
+
+				_, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(err).ToNot(HaveOccurred())
+
+				// The restore outlives the CreateVolume call until the volume leaves its restoring status.
+				restored := iaas.Volume{
+					Id:     new("volume-id"),
+					Name:   new("new volume"),
+					Source: &iaas.VolumeSource{Id: "snapshot-id", Type: string(stackitclient.SnapshotSource)},
+					Status: new(stackitclient.VolumeRestoringStatus),
+				}
+				iaasClient.EXPECT().ListVolumes(gomock.Any(), gomock.Any(), gomock.Any()).Return([]iaas.Volume{restored}, "", nil)
+				_, err = fakeCs.DeleteSnapshot(context.Background(), &csi.DeleteSnapshotRequest{SnapshotId: "snapshot-id"})
+				Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+
+				restored.Status = new(stackitclient.VolumeAvailableStatus)
+				iaasClient.EXPECT().ListVolumes(gomock.Any(), gomock.Any(), gomock.Any()).Return([]iaas.Volume{restored}, "", nil)
+				iaasClient.EXPECT().GetBackup(gomock.Any(), "snapshot-id").Return(nil, &oapierror.GenericOpenAPIError{StatusCode: http.StatusNotFound})
+				iaasClient.EXPECT().DeleteSnapshot(gomock.Any(), "snapshot-id").Return(nil)
+				_, err = fakeCs.DeleteSnapshot(context.Background(), &csi.DeleteSnapshotRequest{SnapshotId: "snapshot-id"})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
 			It("should fail if a snapshot ID is provided as content source and the snapshot cannot be retrieved", func() {
 				req.VolumeContentSource = &csi.VolumeContentSource{
 					Type: &csi.VolumeContentSource_Snapshot{
@@ -429,6 +585,39 @@ var _ = Describe("ControllerServer test", Ordered, func() {
 				Expect(err.Error()).To(ContainSubstring("must be in the same availability zone as source"))
 			})
 
+			It("should create a volume from a snapshot in a different AZ with the CrossZoneClone feature gate", func() {
+				featuregatetesting.SetFeatureGateDuringTest(GinkgoT(), utilfeature.DefaultFeatureGate, features.CrossZoneClone, true)
+				req.VolumeContentSource = &csi.VolumeContentSource{
+					Type: &csi.VolumeContentSource_Snapshot{
+						Snapshot: &csi.VolumeContentSource_SnapshotSource{
+							SnapshotId: "snapshot-id",
+						},
+					},
+				}
+				req.AccessibilityRequirements = &csi.TopologyRequirement{
+					Requisite: []*csi.Topology{
+						{Segments: map[string]string{topologyKey: "some-other-zone"}},
+					},
+				}
+
+				iaasClient.EXPECT().GetSnapshot(gomock.Any(), "snapshot-id").Return(&iaas.Snapshot{
+					Id:               new("snapshot-id"),
+					VolumeId:         "volume-id",
+					Status:           new("AVAILABLE"),
+					AvailabilityZone: new("eu01"),
+				}, nil)
+				iaasClient.EXPECT().
+					CreateVolume(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, opts iaas.CreateVolumePayload) (*iaas.Volume, error) {
+						Expect(opts.AvailabilityZone).To(Equal("some-other-zone"))
+						return &iaas.Volume{Id: new("volume-id"), AvailabilityZone: "some-other-zone", Size: new(int64(20))}, nil
+					})
+				iaasClient.EXPECT().WaitVolumeTargetStatusWithCustomBackoff(gomock.Any(), "volume-id", gomock.Any(), gomock.Any()).Return(nil)
+
+				_, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
 			It("should fail if the snapshot and the backup can both not be found", func() {
 				req.VolumeContentSource = &csi.VolumeContentSource{
 					Type: &csi.VolumeContentSource_Snapshot{
@@ -552,142 +741,774 @@ var _ = Describe("ControllerServer test", Ordered, func() {
 			})
 		})
 
-		It("should fail if the final call to CreateVolume fails", func() {
-			req := &csi.CreateVolumeRequest{
-				Name:               "new volume",
-				VolumeCapabilities: stdVolCaps,
-				CapacityRange:      stdCapRange,
-			}
+		Context("QoS parameters", func() {
+			var perfClass *iaas.VolumePerformanceClass
 
-			iaasClient.EXPECT().GetVolumesByName(gomock.Any(), "new volume").Return([]iaas.Volume{}, nil)
+			BeforeEach(func() {
+				perfClass = &iaas.VolumePerformanceClass{
+					Name:       "storage_premium_perf4",
+					Iops:       new(int64(4000)),
+					Throughput: new(int64(200)),
+				}
+			})
 
-			iaasClient.EXPECT().CreateVolume(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("injected error"))
+			It("should label the volume with the QoS limits of the performance class", func() {
+				req := &csi.CreateVolumeRequest{
+					Name:               "new volume",
+					VolumeCapabilities: stdVolCaps,
+					CapacityRange:      stdCapRange,
+					Parameters: map[string]string{
+						"type":          "storage_premium_perf4",
+						"minIops":       "3000",
+						"minThroughput": "200",
+					},
+				}
 
-			_, err := fakeCs.CreateVolume(context.Background(), req)
-			Expect(err).To(HaveOccurred())
-			Expect(status.Code(err)).To(Equal(codes.Internal))
-			Expect(err.Error()).To(ContainSubstring("CreateVolume failed with error injected error"))
-		})
+				iaasClient.EXPECT().GetVolumesByName(gomock.Any(), "new volume").Return([]iaas.Volume{}, nil)
+				iaasClient.EXPECT().GetVolumePerformanceClass(gomock.Any(), "storage_premium_perf4").Return(perfClass, nil)
+				iaasClient.EXPECT().CreateVolume(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, payload iaas.CreateVolumePayload) (*iaas.Volume, error) {
+					Expect(payload.Labels).To(Equal(map[string]any{
+						"qos-iops":       "4000",
+						"qos-throughput": "200",
+					}))
+					return &iaas.Volume{
+						Id:               new("volume-id"),
+						AvailabilityZone: "eu01",
+						Size:             new(int64(20)),
+					}, nil
+				})
+				iaasClient.EXPECT().WaitVolumeTargetStatusWithCustomBackoff(gomock.Any(), "volume-id", gomock.Any(), gomock.Any()).Return(nil)
 
-		It("should fail if the created volume is not available within time", func() {
-			req := &csi.CreateVolumeRequest{
-				Name:               "new volume",
-				VolumeCapabilities: stdVolCaps,
-				CapacityRange:      stdCapRange,
-			}
+				_, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(err).ToNot(HaveOccurred())
+			})
 
-			iaasClient.EXPECT().GetVolumesByName(gomock.Any(), "new volume").Return([]iaas.Volume{}, nil)
+			It("should fail if the performance class does not satisfy the requested IOPS", func() {
+				req := &csi.CreateVolumeRequest{
+					Name:               "new volume",
+					VolumeCapabilities: stdVolCaps,
+					CapacityRange:      stdCapRange,
+					Parameters: map[string]string{
+						"type":    "storage_premium_perf4",
+						"minIops": "5000",
+					},
+				}
 
-			iaasClient.EXPECT().CreateVolume(gomock.Any(), gomock.Any()).Return(&iaas.Volume{
-				Id:               new("volume-id"),
-				Name:             new("new volume"),
-				AvailabilityZone: "eu01",
-				Size:             new(int64(20)),
-			}, nil)
-			iaasClient.EXPECT().WaitVolumeTargetStatusWithCustomBackoff(gomock.Any(), "volume-id", gomock.Any(), gomock.Any()).
-				Return(fmt.Errorf("injected error"))
+				iaasClient.EXPECT().GetVolumesByName(gomock.Any(), "new volume").Return([]iaas.Volume{}, nil)
+				iaasClient.EXPECT().GetVolumePerformanceClass(gomock.Any(), "storage_premium_perf4").Return(perfClass, nil)
 
-			_, err := fakeCs.CreateVolume(context.Background(), req)
-			Expect(err).To(HaveOccurred())
-			Expect(status.Code(err)).To(Equal(codes.Internal))
-			Expect(err.Error()).To(ContainSubstring("failed getting available in time"))
-		})
-	})
+				_, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+				Expect(err.Error()).To(ContainSubstring("provides 4000 IOPS"))
+			})
 
-	Describe("DeleteVolume", func() {
-		It("should return error when Volume ID is not provided", func() {
-			req := &csi.DeleteVolumeRequest{}
-			_, err := fakeCs.DeleteVolume(context.Background(), req)
-			Expect(err).Should(Equal(status.Error(codes.InvalidArgument, "DeleteVolume Volume ID must be provided")))
-		})
-		It("should succeed when Volume ID is provided", func() {
-			req := &csi.DeleteVolumeRequest{
-				VolumeId: "fake",
-			}
-			iaasClient.EXPECT().DeleteVolume(gomock.Any(), req.VolumeId).Return(nil)
-			_, err := fakeCs.DeleteVolume(context.Background(), req)
-			Expect(err).ShouldNot(HaveOccurred())
-		})
-	})
-	Describe("ListVolumes", func() {
-		It("should correctly produce ListVolumesResponse_Entry response", func() {
-			req := &csi.ListVolumesRequest{
-				MaxEntries:    10,
-				StartingToken: "",
-			}
-			expectedVolumeResponseList := []*csi.ListVolumesResponse_Entry{
-				{
-					Volume: &csi.Volume{
-						VolumeId:      "fake",
-						CapacityBytes: 10 * util.GIBIBYTE,
-					},
-				},
-				{
-					Volume: &csi.Volume{
-						VolumeId:      "fake1",
-						CapacityBytes: 10 * util.GIBIBYTE,
-					},
-					Status: &csi.ListVolumesResponse_VolumeStatus{
-						PublishedNodeIds: []string{"serverID"},
+			It("should fail if QoS parameters are requested without a performance class", func() {
+				req := &csi.CreateVolumeRequest{
+					Name:               "new volume",
+					VolumeCapabilities: stdVolCaps,
+					CapacityRange:      stdCapRange,
+					Parameters: map[string]string{
+						"minThroughput": "100",
 					},
-				},
-			}
-			// Pagination is not supported by the API yet, so the arguments are ignored
-			iaasClient.EXPECT().ListVolumes(gomock.Any(), gomock.Any(), gomock.Any()).Return([]iaas.Volume{
-				{
-					Id:     new("fake"),
-					Status: new("AVAILABLE"),
-					Name:   new("fake"),
-					Size:   new(int64(10)),
-				},
-				{
-					Id:       new("fake1"),
-					Status:   new("AVAILABLE"),
-					Name:     new("fake1"),
-					Size:     new(int64(10)),
-					ServerId: new("serverID"),
-				},
-			}, "", nil)
-			resp, err := fakeCs.ListVolumes(context.Background(), req)
-			Expect(err).Should(Not(HaveOccurred()))
-			Expect(resp.GetEntries()).Should(Equal(expectedVolumeResponseList))
-		})
-	})
-	Describe("ControllerPublishVolume", func() {
-		It("should successfully attach volume to node", func() {
-			req := &csi.ControllerPublishVolumeRequest{
-				VolumeId:         "fake",
-				NodeId:           "fake",
-				VolumeCapability: stdVolCap,
-			}
-			iaasClient.EXPECT().GetVolume(gomock.Any(), req.VolumeId).Return(&iaas.Volume{Status: new("AVAILABLE")}, nil)
-			iaasClient.EXPECT().GetServer(gomock.Any(), "fake").Return(&iaas.Server{}, nil)
-			iaasClient.EXPECT().AttachVolume(gomock.Any(), req.NodeId, req.VolumeId, gomock.Any()).Return(req.VolumeId, nil)
-			iaasClient.EXPECT().WaitDiskAttached(gomock.Any(), req.NodeId, req.VolumeId).Return(nil)
-			_, err := fakeCs.ControllerPublishVolume(context.Background(), req)
-			Expect(err).To(Not(HaveOccurred()))
-		})
+				}
 
-		It("should return resource exhausted when node cannot attach more disks", func() {
-			req := &csi.ControllerPublishVolumeRequest{
-				VolumeId:         "fake",
-				NodeId:           "fake",
-				VolumeCapability: stdVolCap,
-			}
-			iaasClient.EXPECT().GetVolume(gomock.Any(), req.VolumeId).Return(&iaas.Volume{Status: new("AVAILABLE")}, nil)
-			iaasClient.EXPECT().GetServer(gomock.Any(), req.NodeId).Return(&iaas.Server{}, nil)
-			iaasClient.EXPECT().AttachVolume(gomock.Any(), req.NodeId, req.VolumeId, gomock.Any()).Return("", &oapierror.GenericOpenAPIError{
-				StatusCode: http.StatusForbidden,
-				Body:       []byte("maximum allowed number of disk devices"),
+				iaasClient.EXPECT().GetVolumesByName(gomock.Any(), "new volume").Return([]iaas.Volume{}, nil)
+
+				_, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
 			})
 
-			_, err := fakeCs.ControllerPublishVolume(context.Background(), req)
-			Expect(err).To(HaveOccurred())
-			Expect(status.Code(err)).To(Equal(codes.ResourceExhausted))
-			Expect(status.Convert(err).Message()).To(ContainSubstring("Node can't accept any more volumes"))
-		})
-	})
-	Describe("ControllerUnpublishVolume", func() {
+			It("should fail if a QoS parameter is not a positive integer", func() {
+				req := &csi.CreateVolumeRequest{
+					Name:               "new volume",
+					VolumeCapabilities: stdVolCaps,
+					CapacityRange:      stdCapRange,
+					Parameters: map[string]string{
+						"type":    "storage_premium_perf4",
+						"minIops": "-1",
+					},
+				}
+
+				iaasClient.EXPECT().GetVolumesByName(gomock.Any(), "new volume").Return([]iaas.Volume{}, nil)
+
+				_, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+			})
+
+			It("should fail if the performance class does not exist", func() {
+				req := &csi.CreateVolumeRequest{
+					Name:               "new volume",
+					VolumeCapabilities: stdVolCaps,
+					CapacityRange:      stdCapRange,
+					Parameters: map[string]string{
+						"type":    "unknown",
+						"minIops": "1000",
+					},
+				}
+
+				iaasClient.EXPECT().GetVolumesByName(gomock.Any(), "new volume").Return([]iaas.Volume{}, nil)
+				iaasClient.EXPECT().GetVolumePerformanceClass(gomock.Any(), "unknown").Return(nil, &oapierror.GenericOpenAPIError{StatusCode: http.StatusNotFound})
+
+				_, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+			})
+		})
+
+		Context("encryption parameters", func() {
+			const keyID = "0b9c2e9e-6f3a-4c1e-9a55-2d1f8f6c7a10"
+
+			var (
+				req                  *csi.CreateVolumeRequest
+				encryptionParameters *iaas.VolumeEncryptionParameter
+			)
+
+			BeforeEach(func() {
+				req = &csi.CreateVolumeRequest{
+					Name:               "new volume",
+					VolumeCapabilities: stdVolCaps,
+					CapacityRange:      stdCapRange,
+					Parameters: map[string]string{
+						"type":              "storage_premium_perf4",
+						"availability":      "eu01",
+						"encrypted":         "true",
+						"kmsKeyID":          keyID,
+						"kmsKeyringID":      "keyring-id",
+						"kmsKeyVersion":     "1",
+						"kmsServiceAccount": "sa@example.com",
+					},
+				}
+				encryptionParameters = &iaas.VolumeEncryptionParameter{
+					KekKeyId:       keyID,
+					KekKeyVersion:  1,
+					KekKeyringId:   "keyring-id",
+					ServiceAccount: "sa@example.com",
+				}
+			})
+
+			expectCreate := func(expected *iaas.VolumeEncryptionParameter) {
+				iaasClient.EXPECT().CreateVolume(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, opts iaas.CreateVolumePayload) (*iaas.Volume, error) {
+						Expect(opts.EncryptionParameters).To(Equal(expected))
+						return &iaas.Volume{Id: new("volume-id"), AvailabilityZone: "eu01", Size: new(int64(20))}, nil
+					})
+				iaasClient.EXPECT().WaitVolumeTargetStatusWithCustomBackoff(gomock.Any(), "volume-id", gomock.Any(), gomock.Any()).Return(nil)
+			}
+			cloneFrom := func(volumeID string) {
+				req.VolumeContentSource = &csi.VolumeContentSource{
+					Type: &csi.VolumeContentSource_Volume{
+						Volume: &csi.VolumeContentSource_VolumeSource{VolumeId: volumeID},
+					},
+				}
+			}
+
+			It("should create an encrypted volume with the KMS key", func() {
+				iaasClient.EXPECT().GetVolumesByName(gomock.Any(), "new volume").Return([]iaas.Volume{}, nil)
+				expectCreate(encryptionParameters)
+
+				_, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should reject KMS parameters without encryption", func() {
+				delete(req.Parameters, "encrypted")
+
+				_, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+				Expect(err.Error()).To(ContainSubstring(`require encrypted: "true"`))
+			})
+
+			It("should reject a clone with a different KMS key than the source volume", func() {
+				cloneFrom("volume-source-id")
+				iaasClient.EXPECT().GetVolumesByName(gomock.Any(), "new volume").Return([]iaas.Volume{}, nil)
+				iaasClient.EXPECT().GetVolume(gomock.Any(), "volume-source-id").Return(&iaas.Volume{
+					Id:                   new("volume-source-id"),
+					AvailabilityZone:     "eu01",
+					Encrypted:            new(true),
+					EncryptionParameters: &iaas.VolumeEncryptionParameter{KekKeyId: "other-key-id"},
+				}, nil)
+
+				_, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+				Expect(err.Error()).To(ContainSubstring("encrypted with the KMS key other-key-id"))
+			})
+
+			It("should reject an encrypted clone of an unencrypted volume", func() {
+				cloneFrom("volume-source-id")
+				iaasClient.EXPECT().GetVolumesByName(gomock.Any(), "new volume").Return([]iaas.Volume{}, nil)
+				iaasClient.EXPECT().GetVolume(gomock.Any(), "volume-source-id").Return(&iaas.Volume{
+					Id:               new("volume-source-id"),
+					AvailabilityZone: "eu01",
+				}, nil)
+
+				_, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+				Expect(err.Error()).To(ContainSubstring("the source isn't encrypted"))
+			})
+
+			It("should inherit the encryption of the volume of a snapshot with the same KMS key", func() {
+				req.VolumeContentSource = &csi.VolumeContentSource{
+					Type: &csi.VolumeContentSource_Snapshot{
+						Snapshot: &csi.VolumeContentSource_SnapshotSource{SnapshotId: "snapshot-id"},
+					},
+				}
+				iaasClient.EXPECT().GetVolumesByName(gomock.Any(), "new volume").Return([]iaas.Volume{}, nil)
+				iaasClient.EXPECT().GetSnapshot(gomock.Any(), "snapshot-id").Return(&iaas.Snapshot{
+					Id:               new("snapshot-id"),
+					Status:           new("AVAILABLE"),
+					VolumeId:         "snapshot-volume-id",
+					AvailabilityZone: new("eu01"),
+				}, nil)
+				iaasClient.EXPECT().GetVolume(gomock.Any(), "snapshot-volume-id").Return(&iaas.Volume{
+					Id:                   new("snapshot-volume-id"),
+					AvailabilityZone:     "eu01",
+					Encrypted:            new(true),
+					EncryptionParameters: encryptionParameters,
+				}, nil)
+				// The encryption parameters must never be set for volumes created from a snapshot.
+				expectCreate(nil)
+
+				_, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should not validate the encryption of a snapshot whose volume was deleted", func() {
+				req.VolumeContentSource = &csi.VolumeContentSource{
+					Type: &csi.VolumeContentSource_Snapshot{
+						Snapshot: &csi.VolumeContentSource_SnapshotSource{SnapshotId: "snapshot-id"},
+					},
+				}
+				iaasClient.EXPECT().GetVolumesByName(gomock.Any(), "new volume").Return([]iaas.Volume{}, nil)
+				iaasClient.EXPECT().GetSnapshot(gomock.Any(), "snapshot-id").Return(&iaas.Snapshot{
+					Id:               new("snapshot-id"),
+					Status:           new("AVAILABLE"),
+					VolumeId:         "snapshot-volume-id",
+					AvailabilityZone: new("eu01"),
+				}, nil)
+				iaasClient.EXPECT().GetVolume(gomock.Any(), "snapshot-volume-id").Return(nil,
+					&oapierror.GenericOpenAPIError{StatusCode: http.StatusNotFound})
+				expectCreate(nil)
+
+				_, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		Context("namespace quotas", func() {
+			var req *csi.CreateVolumeRequest
+
+			BeforeEach(func() {
+				fakeCs.Opts.NamespaceQuotas = map[string]int64{"team-a": 50}
+				req = &csi.CreateVolumeRequest{
+					Name:               "new volume",
+					VolumeCapabilities: stdVolCaps,
+					CapacityRange:      stdCapRange,
+					Parameters: map[string]string{
+						sharedcsi.PvcNamespaceKey: "team-a",
+					},
+				}
+				iaasClient.EXPECT().GetVolumesByName(gomock.Any(), "new volume").Return([]iaas.Volume{}, nil)
+			})
+
+			It("should create the volume and label it with the namespace if the quota is not exceeded", func() {
+				iaasClient.EXPECT().ListVolumes(gomock.Any(), gomock.Any(), gomock.Any()).Return([]iaas.Volume{
+					{Size: new(int64(20)), Labels: map[string]any{"pvc-namespace": "team-a"}},
+					{Size: new(int64(100)), Labels: map[string]any{"pvc-namespace": "team-b"}},
+					{Size: new(int64(100))},
+				}, "", nil)
+				iaasClient.EXPECT().CreateVolume(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, payload iaas.CreateVolumePayload) (*iaas.Volume, error) {
+					Expect(payload.Labels).To(HaveKeyWithValue("pvc-namespace", "team-a"))
+					return &iaas.Volume{
+						Id:               new("volume-id"),
+						AvailabilityZone: "eu01",
+						Size:             new(int64(20)),
+					}, nil
+				})
+				iaasClient.EXPECT().WaitVolumeTargetStatusWithCustomBackoff(gomock.Any(), "volume-id", gomock.Any(), gomock.Any()).Return(nil)
+
+				_, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(testutil.ToFloat64(metrics.CSINamespaceCapacityUsed.WithLabelValues("team-a"))).To(Equal(float64(40)))
+				Expect(testutil.ToFloat64(metrics.CSINamespaceCapacityQuota.WithLabelValues("team-a"))).To(Equal(float64(50)))
+				Expect(fakeCs.operationLocks.TryAcquire("ns/team-a")).To(BeTrue(), "the namespace should be released")
+			})
+
+			It("should reject the volume if the quota would be exceeded", func() {
+				iaasClient.EXPECT().ListVolumes(gomock.Any(), gomock.Any(), gomock.Any()).Return([]iaas.Volume{
+					{Size: new(int64(40)), Labels: map[string]any{"pvc-namespace": "team-a"}},
+				}, "", nil)
+
+				_, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(status.Code(err)).To(Equal(codes.ResourceExhausted))
+				Expect(fakeCs.operationLocks.TryAcquire("ns/team-a")).To(BeTrue(), "the namespace should be released")
+			})
+
+			It("should abort while another volume is created in the namespace", func() {
+				Expect(fakeCs.operationLocks.TryAcquire("ns/team-a")).To(BeTrue())
+
+				_, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(status.Code(err)).To(Equal(codes.Aborted))
+			})
+
+			It("should not check the quota of namespaces without one", func() {
+				req.Parameters[sharedcsi.PvcNamespaceKey] = "team-b"
+				iaasClient.EXPECT().CreateVolume(gomock.Any(), gomock.Any()).Return(&iaas.Volume{
+					Id:               new("volume-id"),
+					AvailabilityZone: "eu01",
+					Size:             new(int64(20)),
+				}, nil)
+				iaasClient.EXPECT().WaitVolumeTargetStatusWithCustomBackoff(gomock.Any(), "volume-id", gomock.Any(), gomock.Any()).Return(nil)
+
+				_, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		Context("volume size", func() {
+			var req *csi.CreateVolumeRequest
+
+			BeforeEach(func() {
+				fakeCs.Opts.DefaultVolumeSize = 5
+				fakeCs.Opts.MaxVolumeSize = 100
+				req = &csi.CreateVolumeRequest{
+					Name:               "new volume",
+					VolumeCapabilities: stdVolCaps,
+					Parameters:         map[string]string{},
+				}
+			})
+
+			expectCreateVolume := func(size int64) {
+				iaasClient.EXPECT().GetVolumesByName(gomock.Any(), "new volume").Return([]iaas.Volume{}, nil)
+				iaasClient.EXPECT().CreateVolume(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, payload iaas.CreateVolumePayload) (*iaas.Volume, error) {
+					Expect(payload.Size).To(Equal(new(size)))
+					return &iaas.Volume{
+						Id:               new("volume-id"),
+						AvailabilityZone: "eu01",
+						Size:             new(size),
+					}, nil
+				})
+				iaasClient.EXPECT().WaitVolumeTargetStatusWithCustomBackoff(gomock.Any(), "volume-id", gomock.Any(), gomock.Any()).Return(nil)
+			}
+
+			It("should use the default size of the driver if no capacity is requested", func() {
+				expectCreateVolume(5)
+
+				_, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should use the default size of the StorageClass if no capacity is requested", func() {
+				req.Parameters["defaultSize"] = "10Gi"
+				expectCreateVolume(10)
+
+				_, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should use the largest size within the limit if the default size exceeds it", func() {
+				req.CapacityRange = &csi.CapacityRange{LimitBytes: 3 * util.GIBIBYTE}
+				expectCreateVolume(3)
+
+				_, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should reject volumes larger than the maximum size of the driver", func() {
+				req.CapacityRange = &csi.CapacityRange{RequiredBytes: 101 * util.GIBIBYTE}
+
+				_, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(status.Code(err)).To(Equal(codes.OutOfRange))
+			})
+
+			It("should reject volumes larger than the maximum size of the StorageClass", func() {
+				req.Parameters["maxSize"] = "50Gi"
+				req.CapacityRange = &csi.CapacityRange{RequiredBytes: 51 * util.GIBIBYTE}
+
+				_, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(status.Code(err)).To(Equal(codes.OutOfRange))
+			})
+
+			It("should not raise the maximum size of the driver with the StorageClass", func() {
+				req.Parameters["maxSize"] = "1Ti"
+				req.CapacityRange = &csi.CapacityRange{RequiredBytes: 101 * util.GIBIBYTE}
+
+				_, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(status.Code(err)).To(Equal(codes.OutOfRange))
+			})
+
+			It("should reject invalid size parameters", func() {
+				for _, params := range []map[string]string{
+					{"defaultSize": "ten"},
+					{"defaultSize": "-1Gi"},
+					{"maxSize": "512Mi"},
+				} {
+					req.Parameters = params
+					_, err := fakeCs.CreateVolume(context.Background(), req)
+					Expect(status.Code(err)).To(Equal(codes.InvalidArgument), "parameters %v", params)
+				}
+			})
+
+			It("should reject expanding volumes beyond the maximum size of the driver", func() {
+				_, err := fakeCs.ControllerExpandVolume(context.Background(), &csi.ControllerExpandVolumeRequest{
+					VolumeId:      "fake",
+					CapacityRange: &csi.CapacityRange{RequiredBytes: 101 * util.GIBIBYTE},
+				})
+				Expect(status.Code(err)).To(Equal(codes.OutOfRange))
+			})
+		})
+
+		Context("adopt existing volumes", func() {
+			var (
+				req *csi.CreateVolumeRequest
+				pvs cache.Indexer
+			)
+
+			BeforeEach(func() {
+				pvs = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+				fakeCs.Driver.pvLister = corelisters.NewPersistentVolumeLister(pvs)
+				req = &csi.CreateVolumeRequest{
+					Name:               "new volume",
+					VolumeCapabilities: stdVolCaps,
+					CapacityRange:      stdCapRange,
+					Parameters: map[string]string{
+						sharedcsi.PvcNamespaceKey: "team-a",
+						sharedcsi.PvcNameKey:      "data",
+						"adoptExisting":           "true",
+					},
+				}
+				iaasClient.EXPECT().GetVolumesByName(gomock.Any(), "new volume").Return([]iaas.Volume{}, nil)
+			})
+
+			It("should adopt and rename an unattached volume of the same PVC", func() {
+				iaasClient.EXPECT().ListVolumes(gomock.Any(), gomock.Any(), gomock.Any()).Return([]iaas.Volume{
+					{Id: new("other-volume"), Size: new(int64(20)), Status: new("AVAILABLE"), Labels: map[string]any{"pvc-namespace": "team-b", "pvc-name": "data"}},
+					{Id: new("old-volume"), Name: new("old volume"), Size: new(int64(30)), Status: new("AVAILABLE"), AvailabilityZone: "eu01",
+						Labels: map[string]any{"pvc-namespace": "team-a", "pvc-name": "data"}},
+				}, "", nil)
+				iaasClient.EXPECT().UpdateVolume(gomock.Any(), "old-volume", iaas.UpdateVolumePayload{Name: new("new volume")}).Return(&iaas.Volume{}, nil)
+
+				resp, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp.Volume.VolumeId).To(Equal("old-volume"))
+				Expect(resp.Volume.CapacityBytes).To(Equal(30 * util.GIBIBYTE))
+			})
+
+			It("should not adopt attached volumes", func() {
+				iaasClient.EXPECT().ListVolumes(gomock.Any(), gomock.Any(), gomock.Any()).Return([]iaas.Volume{
+					{Id: new("old-volume"), Size: new(int64(20)), Status: new("ATTACHED"), ServerId: new("server-id"),
+						Labels: map[string]any{"pvc-namespace": "team-a", "pvc-name": "data"}},
+				}, "", nil)
+
+				_, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+			})
+
+			It("should not adopt volumes that are still referenced by a PV", func() {
+				Expect(pvs.Add(&corev1.PersistentVolume{
+					ObjectMeta: metav1.ObjectMeta{Name: "pv-old"},
+					Spec: corev1.PersistentVolumeSpec{PersistentVolumeSource: corev1.PersistentVolumeSource{
+						CSI: &corev1.CSIPersistentVolumeSource{Driver: driverName, VolumeHandle: "old-volume"},
+					}},
+				})).To(Succeed())
+				iaasClient.EXPECT().ListVolumes(gomock.Any(), gomock.Any(), gomock.Any()).Return([]iaas.Volume{
+					{Id: new("old-volume"), Size: new(int64(20)), Status: new("AVAILABLE"), Labels: map[string]any{"pvc-namespace": "team-a", "pvc-name": "data"}},
+				}, "", nil)
+
+				_, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+				Expect(err).To(MatchError(ContainSubstring("still referenced by PV pv-old")))
+			})
+
+			It("should require volume adoption to be enabled", func() {
+				fakeCs.Driver.pvLister = nil
+
+				_, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+			})
+
+			It("should not adopt volumes that are too small", func() {
+				iaasClient.EXPECT().ListVolumes(gomock.Any(), gomock.Any(), gomock.Any()).Return([]iaas.Volume{
+					{Id: new("old-volume"), Size: new(int64(1)), Status: new("AVAILABLE"), Labels: map[string]any{"pvc-namespace": "team-a", "pvc-name": "data"}},
+				}, "", nil)
+
+				_, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+			})
+
+			It("should provision a new volume labelled with the PVC if there is none to adopt", func() {
+				iaasClient.EXPECT().ListVolumes(gomock.Any(), gomock.Any(), gomock.Any()).Return([]iaas.Volume{}, "", nil)
+				iaasClient.EXPECT().CreateVolume(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, payload iaas.CreateVolumePayload) (*iaas.Volume, error) {
+					Expect(payload.Labels).To(HaveKeyWithValue("pvc-namespace", "team-a"))
+					Expect(payload.Labels).To(HaveKeyWithValue("pvc-name", "data"))
+					return &iaas.Volume{
+						Id:               new("volume-id"),
+						AvailabilityZone: "eu01",
+						Size:             new(int64(20)),
+					}, nil
+				})
+				iaasClient.EXPECT().WaitVolumeTargetStatusWithCustomBackoff(gomock.Any(), "volume-id", gomock.Any(), gomock.Any()).Return(nil)
+
+				_, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should require the PVC metadata", func() {
+				delete(req.Parameters, sharedcsi.PvcNameKey)
+
+				_, err := fakeCs.CreateVolume(context.Background(), req)
+				Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+			})
+		})
+
+		It("should fail if the final call to CreateVolume fails", func() {
+			req := &csi.CreateVolumeRequest{
+				Name:               "new volume",
+				VolumeCapabilities: stdVolCaps,
+				CapacityRange:      stdCapRange,
+			}
+
+			// The volume is looked up again after the failed create call, since it might have been created anyway
+			iaasClient.EXPECT().GetVolumesByName(gomock.Any(), "new volume").Return([]iaas.Volume{}, nil).Times(2)
+
+			iaasClient.EXPECT().CreateVolume(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("injected error"))
+
+			_, err := fakeCs.CreateVolume(context.Background(), req)
+			Expect(err).To(HaveOccurred())
+			Expect(status.Code(err)).To(Equal(codes.Internal))
+			Expect(err.Error()).To(ContainSubstring("CreateVolume failed with error injected error"))
+		})
+
+		It("should not look up the volume again if the create call was rejected", func() {
+			req := &csi.CreateVolumeRequest{
+				Name:               "new volume",
+				VolumeCapabilities: stdVolCaps,
+				CapacityRange:      stdCapRange,
+			}
+
+			iaasClient.EXPECT().GetVolumesByName(gomock.Any(), "new volume").Return([]iaas.Volume{}, nil)
+			iaasClient.EXPECT().CreateVolume(gomock.Any(), gomock.Any()).Return(nil, &oapierror.GenericOpenAPIError{StatusCode: http.StatusBadRequest})
+
+			_, err := fakeCs.CreateVolume(context.Background(), req)
+			Expect(status.Code(err)).To(Equal(codes.Internal))
+		})
+
+		It("should use the volume created by a create call that timed out", func() {
+			req := &csi.CreateVolumeRequest{
+				Name:               "new volume",
+				VolumeCapabilities: stdVolCaps,
+				CapacityRange:      stdCapRange,
+			}
+
+			iaasClient.EXPECT().GetVolumesByName(gomock.Any(), "new volume").Return([]iaas.Volume{}, nil)
+			iaasClient.EXPECT().CreateVolume(gomock.Any(), gomock.Any()).Return(nil, context.DeadlineExceeded)
+			iaasClient.EXPECT().GetVolumesByName(gomock.Any(), "new volume").Return([]iaas.Volume{{
+				Id:               new("volume-id"),
+				Name:             new("new volume"),
+				AvailabilityZone: "eu01",
+				Size:             new(int64(20)),
+			}}, nil)
+			iaasClient.EXPECT().WaitVolumeTargetStatusWithCustomBackoff(gomock.Any(), "volume-id", gomock.Any(), gomock.Any()).Return(nil)
+
+			resp, err := fakeCs.CreateVolume(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.Volume.VolumeId).To(Equal("volume-id"))
+		})
+
+		It("should abort if an operation for the same volume is in progress", func() {
+			req := &csi.CreateVolumeRequest{
+				Name:               "new volume",
+				VolumeCapabilities: stdVolCaps,
+				CapacityRange:      stdCapRange,
+			}
+
+			Expect(fakeCs.operationLocks.TryAcquire("new volume")).To(BeTrue())
+			defer fakeCs.operationLocks.Release("new volume")
+
+			_, err := fakeCs.CreateVolume(context.Background(), req)
+			Expect(status.Code(err)).To(Equal(codes.Aborted))
+		})
+
+		It("should fail if the created volume is not available within time", func() {
+			req := &csi.CreateVolumeRequest{
+				Name:               "new volume",
+				VolumeCapabilities: stdVolCaps,
+				CapacityRange:      stdCapRange,
+			}
+
+			iaasClient.EXPECT().GetVolumesByName(gomock.Any(), "new volume").Return([]iaas.Volume{}, nil)
+
+			iaasClient.EXPECT().CreateVolume(gomock.Any(), gomock.Any()).Return(&iaas.Volume{
+				Id:               new("volume-id"),
+				Name:             new("new volume"),
+				AvailabilityZone: "eu01",
+				Size:             new(int64(20)),
+			}, nil)
+			iaasClient.EXPECT().WaitVolumeTargetStatusWithCustomBackoff(gomock.Any(), "volume-id", gomock.Any(), gomock.Any()).
+				Return(fmt.Errorf("injected error"))
+
+			_, err := fakeCs.CreateVolume(context.Background(), req)
+			Expect(err).To(HaveOccurred())
+			Expect(status.Code(err)).To(Equal(codes.Internal))
+			Expect(err.Error()).To(ContainSubstring("failed getting available in time"))
+		})
+	})
+
+	Describe("DeleteVolume", func() {
+		It("should return error when Volume ID is not provided", func() {
+			req := &csi.DeleteVolumeRequest{}
+			_, err := fakeCs.DeleteVolume(context.Background(), req)
+			Expect(err).Should(Equal(status.Error(codes.InvalidArgument, "DeleteVolume Volume ID must be provided")))
+		})
+		It("should succeed when Volume ID is provided", func() {
+			req := &csi.DeleteVolumeRequest{
+				VolumeId: "fake",
+			}
+			iaasClient.EXPECT().DeleteVolume(gomock.Any(), req.VolumeId).Return(nil)
+			_, err := fakeCs.DeleteVolume(context.Background(), req)
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+	})
+	Describe("ListVolumes", func() {
+		It("should correctly produce ListVolumesResponse_Entry response", func() {
+			req := &csi.ListVolumesRequest{
+				MaxEntries:    10,
+				StartingToken: "",
+			}
+			expectedVolumeResponseList := []*csi.ListVolumesResponse_Entry{
+				{
+					Volume: &csi.Volume{
+						VolumeId:      "fake",
+						CapacityBytes: 10 * util.GIBIBYTE,
+					},
+				},
+				{
+					Volume: &csi.Volume{
+						VolumeId:      "fake1",
+						CapacityBytes: 10 * util.GIBIBYTE,
+					},
+					Status: &csi.ListVolumesResponse_VolumeStatus{
+						PublishedNodeIds: []string{"serverID"},
+					},
+				},
+			}
+			// Pagination is not supported by the API yet, so the arguments are ignored
+			iaasClient.EXPECT().ListVolumes(gomock.Any(), gomock.Any(), gomock.Any()).Return([]iaas.Volume{
+				{
+					Id:     new("fake"),
+					Status: new("AVAILABLE"),
+					Name:   new("fake"),
+					Size:   new(int64(10)),
+				},
+				{
+					Id:       new("fake1"),
+					Status:   new("AVAILABLE"),
+					Name:     new("fake1"),
+					Size:     new(int64(10)),
+					ServerId: new("serverID"),
+				},
+			}, "", nil)
+			resp, err := fakeCs.ListVolumes(context.Background(), req)
+			Expect(err).Should(Not(HaveOccurred()))
+			Expect(resp.GetEntries()).Should(Equal(expectedVolumeResponseList))
+		})
+	})
+	Describe("ControllerPublishVolume", func() {
+		It("should successfully attach volume to node", func() {
+			req := &csi.ControllerPublishVolumeRequest{
+				VolumeId:         "fake",
+				NodeId:           "fake",
+				VolumeCapability: stdVolCap,
+			}
+			iaasClient.EXPECT().GetVolume(gomock.Any(), req.VolumeId).Return(&iaas.Volume{Status: new("AVAILABLE")}, nil)
+			fakeCs.attachQueue.initDelay = time.Millisecond
+			iaasClient.EXPECT().GetServer(gomock.Any(), "fake").Return(&iaas.Server{}, nil)
+			iaasClient.EXPECT().AttachVolume(gomock.Any(), req.NodeId, req.VolumeId, gomock.Any()).Return(req.VolumeId, nil)
+			iaasClient.EXPECT().GetServer(gomock.Any(), "fake").Return(&iaas.Server{Volumes: []string{req.VolumeId}}, nil)
+			_, err := fakeCs.ControllerPublishVolume(context.Background(), req)
+			Expect(err).To(Not(HaveOccurred()))
+		})
+
+		It("should return resource exhausted when node cannot attach more disks", func() {
+			req := &csi.ControllerPublishVolumeRequest{
+				VolumeId:         "fake",
+				NodeId:           "fake",
+				VolumeCapability: stdVolCap,
+			}
+			iaasClient.EXPECT().GetVolume(gomock.Any(), req.VolumeId).Return(&iaas.Volume{Status: new("AVAILABLE")}, nil)
+			iaasClient.EXPECT().GetServer(gomock.Any(), req.NodeId).Return(&iaas.Server{}, nil)
+			iaasClient.EXPECT().AttachVolume(gomock.Any(), req.NodeId, req.VolumeId, gomock.Any()).Return("", &oapierror.GenericOpenAPIError{
+				StatusCode: http.StatusForbidden,
+				Body:       []byte("maximum allowed number of disk devices"),
+			})
+
+			_, err := fakeCs.ControllerPublishVolume(context.Background(), req)
+			Expect(err).To(HaveOccurred())
+			Expect(status.Code(err)).To(Equal(codes.ResourceExhausted))
+			Expect(status.Convert(err).Message()).To(ContainSubstring("Node can't accept any more volumes"))
+		})
+
+		It("should accept a providerID as node ID", func() {
+			req := &csi.ControllerPublishVolumeRequest{
+				VolumeId:         "fake",
+				NodeId:           "stackit:///eu01-1/fake",
+				VolumeCapability: stdVolCap,
+			}
+			iaasClient.EXPECT().GetVolume(gomock.Any(), req.VolumeId).Return(&iaas.Volume{Status: new("AVAILABLE"), ServerId: new("fake")}, nil)
+			iaasClient.EXPECT().GetServer(gomock.Any(), "fake").Return(&iaas.Server{}, nil)
+
+			_, err := fakeCs.ControllerPublishVolume(context.Background(), req)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should record a read-only publish on the volume and in the publish context", func() {
+			req := &csi.ControllerPublishVolumeRequest{
+				VolumeId:         "fake",
+				NodeId:           "fake",
+				VolumeCapability: stdVolCap,
+				Readonly:         true,
+			}
+			iaasClient.EXPECT().GetVolume(gomock.Any(), req.VolumeId).Return(&iaas.Volume{
+				Status: new("AVAILABLE"),
+				Labels: map[string]any{"pvc-name": "data"},
+			}, nil)
+			fakeCs.attachQueue.initDelay = time.Millisecond
+			iaasClient.EXPECT().GetServer(gomock.Any(), "fake").Return(&iaas.Server{}, nil)
+			iaasClient.EXPECT().UpdateVolume(gomock.Any(), req.VolumeId, iaas.UpdateVolumePayload{Labels: map[string]any{
+				publishedReadOnlyLabel: "true",
+			}}).Return(&iaas.Volume{}, nil)
+			iaasClient.EXPECT().AttachVolume(gomock.Any(), req.NodeId, req.VolumeId, gomock.Any()).Return(req.VolumeId, nil)
+			iaasClient.EXPECT().GetServer(gomock.Any(), "fake").Return(&iaas.Server{Volumes: []string{req.VolumeId}}, nil)
+
+			resp, err := fakeCs.ControllerPublishVolume(context.Background(), req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.GetPublishContext()).To(Equal(map[string]string{publishReadOnlyKey: "true"}))
+		})
+
+		It("should reject publishing an attached volume with a different readonly flag", func() {
+			req := &csi.ControllerPublishVolumeRequest{
+				VolumeId:         "fake",
+				NodeId:           "fake",
+				VolumeCapability: stdVolCap,
+				Readonly:         true,
+			}
+			iaasClient.EXPECT().GetVolume(gomock.Any(), req.VolumeId).Return(&iaas.Volume{Status: new("ATTACHED"), ServerId: new("fake")}, nil)
+			iaasClient.EXPECT().GetServer(gomock.Any(), "fake").Return(&iaas.Server{}, nil)
+
+			_, err := fakeCs.ControllerPublishVolume(context.Background(), req)
+			Expect(status.Code(err)).To(Equal(codes.AlreadyExists))
+		})
+
+		It("should reject an invalid node ID", func() {
+			req := &csi.ControllerPublishVolumeRequest{
+				VolumeId:         "fake",
+				NodeId:           "aws:///eu-central-1a/i-123",
+				VolumeCapability: stdVolCap,
+			}
+
+			_, err := fakeCs.ControllerPublishVolume(context.Background(), req)
+			Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		})
+	})
+	Describe("ControllerUnpublishVolume", func() {
 		It("should successfully detach volume from node", func() {
 			req := &csi.ControllerUnpublishVolumeRequest{
 				VolumeId: "fake",
@@ -695,10 +1516,22 @@ var _ = Describe("ControllerServer test", Ordered, func() {
 			}
 			iaasClient.EXPECT().GetServer(gomock.Any(), "fake").Return(&iaas.Server{}, nil)
 			iaasClient.EXPECT().DetachVolume(gomock.Any(), req.NodeId, req.VolumeId).Return(nil)
-			iaasClient.EXPECT().WaitDiskDetached(gomock.Any(), req.NodeId, req.VolumeId).Return(nil)
+			iaasClient.EXPECT().WaitDiskDetached(gomock.Any(), req.NodeId, req.VolumeId, gomock.Any()).Return(nil)
 			_, err := fakeCs.ControllerUnpublishVolume(context.Background(), req)
 			Expect(err).To(Not(HaveOccurred()))
 		})
+
+		It("should detach the volume from the server of a providerID", func() {
+			req := &csi.ControllerUnpublishVolumeRequest{
+				VolumeId: "fake",
+				NodeId:   "stackit://fake",
+			}
+			iaasClient.EXPECT().GetServer(gomock.Any(), "fake").Return(&iaas.Server{}, nil)
+			iaasClient.EXPECT().DetachVolume(gomock.Any(), "fake", req.VolumeId).Return(nil)
+			iaasClient.EXPECT().WaitDiskDetached(gomock.Any(), "fake", req.VolumeId, gomock.Any()).Return(nil)
+			_, err := fakeCs.ControllerUnpublishVolume(context.Background(), req)
+			Expect(err).NotTo(HaveOccurred())
+		})
 	})
 	Describe("ControllerGetVolume", func() {
 		It("should get volume successfully", func() {
@@ -715,7 +1548,65 @@ var _ = Describe("ControllerServer test", Ordered, func() {
 			Expect(resp.GetStatus().GetPublishedNodeIds()[0]).To(Equal(expectedVol.GetServerId()))
 			Expect(resp.GetStatus().GetPublishedNodeIds()).To(HaveLen(1))
 		})
+
+		It("should return the metadata of the volume in the volume context", func() {
+			req := &csi.ControllerGetVolumeRequest{
+				VolumeId: "fake",
+			}
+			iaasClient.EXPECT().GetVolume(gomock.Any(), req.VolumeId).Return(&iaas.Volume{
+				Size:             new(int64(10)),
+				AvailabilityZone: "eu01-1",
+				PerformanceClass: new("storage_premium_perf4"),
+				Encrypted:        new(true),
+				Source:           &iaas.VolumeSource{Id: "snapshot-id", Type: "snapshot"},
+			}, nil)
+			resp, err := fakeCs.ControllerGetVolume(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.GetVolume().GetVolumeContext()).To(Equal(map[string]string{
+				PerformanceClassKey: "storage_premium_perf4",
+				AvailabilityZoneKey: "eu01-1",
+				EncryptedKey:        "true",
+				SourceTypeKey:       "snapshot",
+			}))
+			Expect(resp.GetStatus().GetPublishedNodeIds()).To(BeEmpty())
+		})
 	})
+	Describe("ValidateVolumeCapabilities", func() {
+		volCap := func(mode csi.VolumeCapability_AccessMode_Mode) *csi.VolumeCapability {
+			return &csi.VolumeCapability{
+				AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+				AccessMode: &csi.VolumeCapability_AccessMode{Mode: mode},
+			}
+		}
+
+		It("should confirm the single node access modes", func() {
+			req := &csi.ValidateVolumeCapabilitiesRequest{
+				VolumeId: "fake",
+				VolumeCapabilities: []*csi.VolumeCapability{
+					volCap(csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER),
+					volCap(csi.VolumeCapability_AccessMode_SINGLE_NODE_SINGLE_WRITER),
+					volCap(csi.VolumeCapability_AccessMode_SINGLE_NODE_MULTI_WRITER),
+				},
+			}
+			iaasClient.EXPECT().GetVolume(gomock.Any(), req.VolumeId).Return(&iaas.Volume{}, nil)
+			resp, err := fakeCs.ValidateVolumeCapabilities(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.GetConfirmed().GetVolumeCapabilities()).To(Equal(req.VolumeCapabilities))
+		})
+
+		It("should not confirm multi node access modes", func() {
+			req := &csi.ValidateVolumeCapabilitiesRequest{
+				VolumeId:           "fake",
+				VolumeCapabilities: []*csi.VolumeCapability{volCap(csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER)},
+			}
+			iaasClient.EXPECT().GetVolume(gomock.Any(), req.VolumeId).Return(&iaas.Volume{}, nil)
+			resp, err := fakeCs.ValidateVolumeCapabilities(context.Background(), req)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.GetConfirmed()).To(BeNil())
+			Expect(resp.GetMessage()).To(ContainSubstring("not supported"))
+		})
+	})
+
 	Describe("ControllerExpandVolume", func() {
 		It("should expand volume successfully", func() {
 			req := &csi.ControllerExpandVolumeRequest{
@@ -728,7 +1619,7 @@ var _ = Describe("ControllerServer test", Ordered, func() {
 				Status: new(stackitclient.VolumeAvailableStatus),
 			}, nil)
 			iaasClient.EXPECT().ExpandVolume(gomock.Any(), req.VolumeId, stackitclient.VolumeAvailableStatus, iaas.ResizeVolumePayload{Size: volSizeGB}).Return(nil)
-			iaasClient.EXPECT().WaitVolumeTargetStatus(gomock.Any(), req.VolumeId, expandTargetStatus).Return(nil)
+			iaasClient.EXPECT().WaitVolumeTargetStatusWithCustomBackoff(gomock.Any(), req.VolumeId, expandTargetStatus, new(stackitclient.DefaultVolumeStatusBackoff)).Return(nil)
 			_, err := fakeCs.ControllerExpandVolume(context.Background(), req)
 			Expect(err).To(Not(HaveOccurred()))
 		})
@@ -786,8 +1677,9 @@ var _ = Describe("ControllerServer test", Ordered, func() {
 
 				// Backups are created from snapshots
 				iaasClient.EXPECT().ListSnapshots(gomock.Any(), gomock.Any()).Return([]iaas.Snapshot{}, "", nil)
+				iaasClient.EXPECT().GetVolume(gomock.Any(), "fake").Return(&iaas.Volume{Id: new("fake"), Status: new(stackitclient.VolumeAvailableStatus)}, nil)
 				iaasClient.EXPECT().CreateSnapshot(gomock.Any(), gomock.Any()).Return(expectedSnap, nil)
-				iaasClient.EXPECT().WaitSnapshotReady(gomock.Any(), "fake-snapshot").Return(expectedSnap.Status, nil)
+				iaasClient.EXPECT().WaitSnapshotReady(gomock.Any(), "fake-snapshot", gomock.Any()).Return(expectedSnap.Status, nil)
 
 				// Actually create the backup from the snapshot
 				iaasClient.EXPECT().CreateBackup(gomock.Any(), "fake-snapshot", req.GetSourceVolumeId(), "fake-snapshot", gomock.Any()).Return(expectedBackup, nil)
@@ -879,8 +1771,9 @@ var _ = Describe("ControllerServer test", Ordered, func() {
 
 				// Backups are created from snapshots
 				iaasClient.EXPECT().ListSnapshots(gomock.Any(), gomock.Any()).Return([]iaas.Snapshot{}, "", nil)
+				iaasClient.EXPECT().GetVolume(gomock.Any(), "fake").Return(&iaas.Volume{Id: new("fake"), Status: new(stackitclient.VolumeAvailableStatus)}, nil)
 				iaasClient.EXPECT().CreateSnapshot(gomock.Any(), gomock.Any()).Return(expectedSnap, nil)
-				iaasClient.EXPECT().WaitSnapshotReady(gomock.Any(), "fake-snapshot").Return(expectedSnap.Status, nil)
+				iaasClient.EXPECT().WaitSnapshotReady(gomock.Any(), "fake-snapshot", gomock.Any()).Return(expectedSnap.Status, nil)
 
 				// Actually create the backup from the snapshot
 				iaasClient.EXPECT().CreateBackup(gomock.Any(), "fake-snapshot", req.GetSourceVolumeId(), "fake-snapshot", gomock.Any()).Return(expectedBackup, nil)
@@ -913,11 +1806,38 @@ var _ = Describe("ControllerServer test", Ordered, func() {
 				}
 				// TODO: Again filters are not implemented yet by the API
 				iaasClient.EXPECT().ListSnapshots(gomock.Any(), gomock.Any()).Return([]iaas.Snapshot{}, "", nil)
+				iaasClient.EXPECT().GetVolume(gomock.Any(), "fake").Return(&iaas.Volume{Id: new("fake"), Status: new(stackitclient.VolumeAvailableStatus)}, nil)
 				iaasClient.EXPECT().CreateSnapshot(gomock.Any(), gomock.Any()).Return(expectedSnap, nil)
-				iaasClient.EXPECT().WaitSnapshotReady(gomock.Any(), "fake-snapshot").Return(expectedSnap.Status, nil)
+				iaasClient.EXPECT().WaitSnapshotReady(gomock.Any(), "fake-snapshot", gomock.Any()).Return(expectedSnap.Status, nil)
 				_, err := fakeCs.CreateSnapshot(context.Background(), req)
 				Expect(err).ToNot(HaveOccurred())
 			})
+			It("should use the snapshot created by a create call that failed with a server error", func() {
+				expectedSnap := &iaas.Snapshot{
+					Id:        new("fake-snapshot"),
+					Name:      new("fake-snapshot"),
+					VolumeId:  "fake",
+					Status:    new("AVAILABLE"),
+					Size:      new(int64(10)),
+					CreatedAt: new(time.Now()),
+				}
+				iaasClient.EXPECT().ListSnapshots(gomock.Any(), map[string]string{"Name": "fake-snapshot"}).Return([]iaas.Snapshot{}, "", nil)
+				iaasClient.EXPECT().GetVolume(gomock.Any(), "fake").Return(&iaas.Volume{Id: new("fake"), Status: new(stackitclient.VolumeAvailableStatus)}, nil)
+				iaasClient.EXPECT().CreateSnapshot(gomock.Any(), gomock.Any()).Return(nil, &oapierror.GenericOpenAPIError{StatusCode: http.StatusBadGateway})
+				iaasClient.EXPECT().ListSnapshots(gomock.Any(), map[string]string{"Name": "fake-snapshot", "VolumeID": "fake"}).Return([]iaas.Snapshot{*expectedSnap}, "", nil)
+				iaasClient.EXPECT().WaitSnapshotReady(gomock.Any(), "fake-snapshot", gomock.Any()).Return(expectedSnap.Status, nil)
+
+				resp, err := fakeCs.CreateSnapshot(context.Background(), req)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp.Snapshot.SnapshotId).To(Equal("fake-snapshot"))
+			})
+			It("should abort if an operation for the same snapshot is in progress", func() {
+				Expect(fakeCs.operationLocks.TryAcquire("fake-snapshot")).To(BeTrue())
+				defer fakeCs.operationLocks.Release("fake-snapshot")
+
+				_, err := fakeCs.CreateSnapshot(context.Background(), req)
+				Expect(status.Code(err)).To(Equal(codes.Aborted))
+			})
 			It("should pass recognized snapshotter metadata when creating snapshots", func() {
 				req.Parameters = map[string]string{
 					stackitclient.SnapshotType:          "snapshot",
@@ -937,6 +1857,7 @@ var _ = Describe("ControllerServer test", Ordered, func() {
 				}
 
 				iaasClient.EXPECT().ListSnapshots(gomock.Any(), gomock.Any()).Return([]iaas.Snapshot{}, "", nil)
+				iaasClient.EXPECT().GetVolume(gomock.Any(), "fake").Return(&iaas.Volume{Id: new("fake"), Status: new(stackitclient.VolumeAvailableStatus)}, nil)
 				iaasClient.EXPECT().CreateSnapshot(gomock.Any(), gomock.Any()).
 					DoAndReturn(func(_ context.Context, payload iaas.CreateSnapshotPayload) (*iaas.Snapshot, error) {
 						Expect(payload.Labels).To(Equal(map[string]any{
@@ -946,7 +1867,7 @@ var _ = Describe("ControllerServer test", Ordered, func() {
 						}))
 						return expectedSnap, nil
 					})
-				iaasClient.EXPECT().WaitSnapshotReady(gomock.Any(), "fake-snapshot").Return(expectedSnap.Status, nil)
+				iaasClient.EXPECT().WaitSnapshotReady(gomock.Any(), "fake-snapshot", gomock.Any()).Return(expectedSnap.Status, nil)
 
 				_, err := fakeCs.CreateSnapshot(context.Background(), req)
 				Expect(err).ToNot(HaveOccurred())
@@ -963,10 +1884,41 @@ var _ = Describe("ControllerServer test", Ordered, func() {
 
 				// TODO: Again filters are not implemented yet by the API
 				iaasClient.EXPECT().ListSnapshots(gomock.Any(), gomock.Any()).Return([]iaas.Snapshot{*expectedSnap}, "", nil)
-				iaasClient.EXPECT().WaitSnapshotReady(gomock.Any(), "fake-snapshot").Return(new("AVAILABLE"), nil)
+				iaasClient.EXPECT().WaitSnapshotReady(gomock.Any(), "fake-snapshot", gomock.Any()).Return(new("AVAILABLE"), nil)
 				_, err := fakeCs.CreateSnapshot(context.Background(), req)
 				Expect(err).ToNot(HaveOccurred())
 			})
+			It("should return an existing snapshot without waiting for a slot of the snapshot limit", func() {
+				fakeCs.snapshots = newSnapshotLimiter(1, 1)
+				release, err := fakeCs.snapshots.acquire(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				defer release()
+
+				iaasClient.EXPECT().ListSnapshots(gomock.Any(), gomock.Any()).Return([]iaas.Snapshot{{
+					Id:        new("fake-snapshot"),
+					VolumeId:  "fake",
+					Status:    new("AVAILABLE"),
+					Size:      new(int64(10)),
+					CreatedAt: new(time.Now()),
+				}}, "", nil)
+				iaasClient.EXPECT().WaitSnapshotReady(gomock.Any(), "fake-snapshot", gomock.Any()).Return(new("AVAILABLE"), nil)
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				_, err = fakeCs.CreateSnapshot(ctx, req)
+				Expect(err).ToNot(HaveOccurred())
+			})
+			It("should wait for a slot of the snapshot limit before creating a snapshot", func() {
+				fakeCs.snapshots = newSnapshotLimiter(1, 1)
+				release, err := fakeCs.snapshots.acquire(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				defer release()
+
+				iaasClient.EXPECT().ListSnapshots(gomock.Any(), gomock.Any()).Return([]iaas.Snapshot{}, "", nil)
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				_, err = fakeCs.CreateSnapshot(ctx, req)
+				Expect(status.Code(err)).To(Equal(codes.Aborted))
+			})
 			It("should fail when we find more than one snapshot with the same name", func() {
 				// TODO: Again filters are not implemented yet by the API
 				iaasClient.EXPECT().ListSnapshots(gomock.Any(), gomock.Any()).Return([]iaas.Snapshot{
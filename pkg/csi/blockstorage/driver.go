@@ -1,14 +1,22 @@
 package blockstorage
 
 import (
+	"context"
 	"fmt"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
 	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
 	corev1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 
+	sharedcsi "github.com/stackitcloud/cloud-provider-stackit/pkg/csi"
 	"github.com/stackitcloud/cloud-provider-stackit/pkg/csi/util/mount"
 	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/metadata"
 	"github.com/stackitcloud/cloud-provider-stackit/pkg/version"
@@ -22,6 +30,22 @@ const (
 
 	// ResizeRequired parameter, if set to true, will trigger a resize on mount operation
 	ResizeRequired = driverName + "/resizeRequired"
+
+	// The following keys are added to the volume context, which ends up in the volumeAttributes of the PV.
+	// They allow external tooling to select volumes without querying the STACKIT API.
+
+	// PerformanceClassKey is the performance class of the volume.
+	PerformanceClassKey = driverName + "/performanceClass"
+	// AvailabilityZoneKey is the availability zone of the volume.
+	AvailabilityZoneKey = driverName + "/availabilityZone"
+	// EncryptedKey is "true" if the volume is encrypted.
+	EncryptedKey = driverName + "/encrypted"
+	// SourceTypeKey is the type of the volume source (volume, snapshot or backup), if any.
+	SourceTypeKey = driverName + "/sourceType"
+
+	// publishReadOnlyKey is "true" in the publish context of volumes published read-only by the controller, e.g.
+	// because the PV is marked readOnly. The node plugin mounts them read-only even if the kubelet doesn't ask for it.
+	publishReadOnlyKey = "readOnly"
 )
 
 var (
@@ -30,6 +54,23 @@ var (
 	Version     = "1.0.0"
 )
 
+// FSGroupPolicy defines who applies the fsGroup of a pod to the filesystem of a volume.
+type FSGroupPolicy string
+
+const (
+	// FSGroupPolicyKubelet lets the kubelet apply the fsGroup according to the fsGroupPolicy of the CSIDriver,
+	// which changes the ownership of all files on every mount.
+	FSGroupPolicyKubelet FSGroupPolicy = "Kubelet"
+	// FSGroupPolicyFile delegates applying the fsGroup to the node plugin when staging the volume.
+	// It only changes the ownership if the root directory of the volume doesn't belong to the fsGroup yet.
+	FSGroupPolicyFile FSGroupPolicy = "File"
+	// FSGroupPolicyNone delegates applying the fsGroup to the node plugin, which ignores it.
+	FSGroupPolicyNone FSGroupPolicy = "None"
+)
+
+// FSGroupPolicies are the supported values of FSGroupPolicy.
+var FSGroupPolicies = []FSGroupPolicy{FSGroupPolicyKubelet, FSGroupPolicyFile, FSGroupPolicyNone}
+
 type Driver struct {
 	name                string
 	fqVersion           string // Fully qualified version in format {Version}@{CPO version}
@@ -37,17 +78,39 @@ type Driver struct {
 	clusterID           string
 	legacyDriver        bool
 	blockVolumeCreation bool
+	fsGroupPolicy       FSGroupPolicy
+	// topologyKey is the topology key of the zone of provisioned volumes
+	topologyKey string
 
 	ids *identityServer
 	cs  *controllerServer
+	gcs *groupControllerServer
 	ns  *nodeServer
 
-	vcap  []*csi.VolumeCapability_AccessMode
-	cscap []*csi.ControllerServiceCapability
-	nscap []*csi.NodeServiceCapability
+	vcap   []*csi.VolumeCapability_AccessMode
+	cscap  []*csi.ControllerServiceCapability
+	gcscap []*csi.GroupControllerServiceCapability
+	nscap  []*csi.NodeServiceCapability
 	csi.UnimplementedNodeServer
 
-	pvcLister corev1.PersistentVolumeClaimLister
+	pvcLister       corev1.PersistentVolumeClaimLister
+	pvLister        corev1.PersistentVolumeLister
+	recorder        record.EventRecorder
+	backupInformers informers.SharedInformerFactory
+
+	snapshotAnnotations sharedcsi.VolumeSnapshotAnnotationsFunc
+	// nodeFailover detaches the volumes of failed nodes, nil if disabled
+	nodeFailover *sharedcsi.NodeFailover
+	// attachmentCapacity checks the attachments of nodes against their volume limit, nil if disabled
+	attachmentCapacity *sharedcsi.AttachmentCapacity
+	// leaderElection runs the control loops of the controller service only in the leader, nil if disabled
+	leaderElection sharedcsi.LeaderElectionFunc
+	// manifest is returned by GetPluginInfo, e.g. with the allowed performance classes
+	manifest map[string]string
+	// resourceLabels are added to all volumes, snapshots and backups, nil if disabled
+	resourceLabels map[string]string
+	// waitTimeout bounds waiting for volumes to be attached, the default wait timeout if 0
+	waitTimeout time.Duration
 }
 
 type DriverOpts struct {
@@ -55,17 +118,56 @@ type DriverOpts struct {
 	Endpoint            string
 	LegacyDriverName    bool
 	BlockVolumeCreation bool
+	// FSGroupPolicy defaults to FSGroupPolicyKubelet.
+	FSGroupPolicy FSGroupPolicy
+	// TopologyKey is the topology key of the zone of provisioned volumes, e.g. topology.kubernetes.io/zone.
+	// Defaults to the topology key of the driver name. Nodes publish both keys.
+	TopologyKey string
 
 	PVCLister corev1.PersistentVolumeClaimLister
+	// PVLister provides the PVs that reference volumes, volumes are not adopted if it is nil.
+	PVLister corev1.PersistentVolumeLister
+	// EventRecorder records events on the PVCs of volumes, events are only logged if it is nil.
+	EventRecorder record.EventRecorder
+	// BackupInformers provide the PVCs, PVs and StorageClasses for scheduled backups, which are disabled if it is nil.
+	BackupInformers informers.SharedInformerFactory
+	// VolumeSnapshotAnnotations reads the annotations of VolumeSnapshots, e.g. whether the source volume was quiesced.
+	VolumeSnapshotAnnotations sharedcsi.VolumeSnapshotAnnotationsFunc
+	// ResourceLabels are added to all volumes, snapshots and backups, see stackitclient.ResourceLabels.
+	ResourceLabels map[string]string
+	// NodeFailover detaches the volumes of failed nodes, which is disabled if it is nil.
+	NodeFailover *sharedcsi.NodeFailover
+	// AttachmentCapacity records events on nodes near their volume limit, which is disabled if it is nil.
+	AttachmentCapacity *sharedcsi.AttachmentCapacity
+	// LeaderElection runs the control loops of the controller service, e.g. the backup scheduler, only in the
+	// replica holding the lease. All replicas run them if it is nil.
+	LeaderElection sharedcsi.LeaderElectionFunc
+	// WaitTimeout bounds waiting for volumes to be attached, see stackitconfig.APITimeouts.
+	// Defaults to stackitclient.DefaultWaitTimeout.
+	WaitTimeout time.Duration
 }
 
 func NewDriver(o *DriverOpts) *Driver {
 	d := &Driver{
-		name:      driverName,
-		fqVersion: fmt.Sprintf("%s@%s", Version, version.Version),
-		endpoint:  o.Endpoint,
-		clusterID: o.ClusterID,
-		pvcLister: o.PVCLister,
+		name:            driverName,
+		fqVersion:       fmt.Sprintf("%s@%s", Version, version.Version),
+		endpoint:        o.Endpoint,
+		clusterID:       o.ClusterID,
+		pvcLister:       o.PVCLister,
+		pvLister:        o.PVLister,
+		fsGroupPolicy:   o.FSGroupPolicy,
+		recorder:        o.EventRecorder,
+		backupInformers: o.BackupInformers,
+
+		snapshotAnnotations: o.VolumeSnapshotAnnotations,
+		resourceLabels:      o.ResourceLabels,
+		nodeFailover:        o.NodeFailover,
+		attachmentCapacity:  o.AttachmentCapacity,
+		leaderElection:      o.LeaderElection,
+		waitTimeout:         o.WaitTimeout,
+	}
+	if d.fsGroupPolicy == "" {
+		d.fsGroupPolicy = FSGroupPolicyKubelet
 	}
 
 	if o.LegacyDriverName {
@@ -77,35 +179,55 @@ func NewDriver(o *DriverOpts) *Driver {
 		d.blockVolumeCreation = true
 	}
 
-	klog.Info("Driver: ", d.name)
-	klog.Info("Driver version: ", d.fqVersion)
-	klog.Info("CSI Spec version: ", specVersion)
+	d.topologyKey = o.TopologyKey
+	if d.topologyKey == "" {
+		d.topologyKey = d.driverTopologyKey()
+	}
+
+	klog.InfoS("Driver", "name", d.name, "version", d.fqVersion, "specVersion", specVersion)
 
 	d.AddControllerServiceCapabilities(
 		[]csi.ControllerServiceCapability_RPC_Type{
 			csi.ControllerServiceCapability_RPC_LIST_VOLUMES,
 			csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME,
 			csi.ControllerServiceCapability_RPC_PUBLISH_UNPUBLISH_VOLUME,
+			csi.ControllerServiceCapability_RPC_PUBLISH_READONLY,
 			csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT,
 			csi.ControllerServiceCapability_RPC_LIST_SNAPSHOTS,
 			csi.ControllerServiceCapability_RPC_EXPAND_VOLUME,
 			csi.ControllerServiceCapability_RPC_CLONE_VOLUME,
 			csi.ControllerServiceCapability_RPC_LIST_VOLUMES_PUBLISHED_NODES,
 			csi.ControllerServiceCapability_RPC_GET_VOLUME,
+			csi.ControllerServiceCapability_RPC_SINGLE_NODE_MULTI_WRITER,
+			csi.ControllerServiceCapability_RPC_MODIFY_VOLUME,
+		})
+	d.AddGroupControllerServiceCapabilities(
+		[]csi.GroupControllerServiceCapability_RPC_Type{
+			csi.GroupControllerServiceCapability_RPC_CREATE_DELETE_GET_VOLUME_GROUP_SNAPSHOT,
 		})
 	d.AddVolumeCapabilityAccessModes(
 		[]csi.VolumeCapability_AccessMode_Mode{
 			csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+			// ReadWriteOncePod, Kubernetes ensures that only a single pod uses the volume.
+			csi.VolumeCapability_AccessMode_SINGLE_NODE_SINGLE_WRITER,
+			// ReadWriteOnce if the CO supports SINGLE_NODE_MULTI_WRITER, all pods on the node can use the volume.
+			csi.VolumeCapability_AccessMode_SINGLE_NODE_MULTI_WRITER,
 		})
 
+	nodeCapabilities := []csi.NodeServiceCapability_RPC_Type{
+		csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME,
+		csi.NodeServiceCapability_RPC_EXPAND_VOLUME,
+		csi.NodeServiceCapability_RPC_GET_VOLUME_STATS,
+		csi.NodeServiceCapability_RPC_VOLUME_CONDITION,
+		csi.NodeServiceCapability_RPC_SINGLE_NODE_MULTI_WRITER,
+	}
+	if d.fsGroupPolicy != FSGroupPolicyKubelet {
+		// The kubelet passes the fsGroup as volume mount group and doesn't change the ownership itself.
+		nodeCapabilities = append(nodeCapabilities, csi.NodeServiceCapability_RPC_VOLUME_MOUNT_GROUP)
+	}
 	// ignoring error, because AddNodeServiceCapabilities is public
 	// and so potentially used somewhere else.
-	_ = d.AddNodeServiceCapabilities(
-		[]csi.NodeServiceCapability_RPC_Type{
-			csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME,
-			csi.NodeServiceCapability_RPC_EXPAND_VOLUME,
-			csi.NodeServiceCapability_RPC_GET_VOLUME_STATS,
-		})
+	_ = d.AddNodeServiceCapabilities(nodeCapabilities)
 
 	d.ids = NewIdentityServer(d)
 
@@ -116,18 +238,29 @@ func (d *Driver) AddControllerServiceCapabilities(cl []csi.ControllerServiceCapa
 	csc := make([]*csi.ControllerServiceCapability, 0, len(cl))
 
 	for _, c := range cl {
-		klog.Infof("Enabling controller service capability: %v", c.String())
+		klog.InfoS("Enabling controller service capability", "capability", c.String())
 		csc = append(csc, NewControllerServiceCapability(c))
 	}
 
 	d.cscap = csc
 }
 
+func (d *Driver) AddGroupControllerServiceCapabilities(cl []csi.GroupControllerServiceCapability_RPC_Type) {
+	gcsc := make([]*csi.GroupControllerServiceCapability, 0, len(cl))
+
+	for _, c := range cl {
+		klog.InfoS("Enabling group controller service capability", "capability", c.String())
+		gcsc = append(gcsc, NewGroupControllerServiceCapability(c))
+	}
+
+	d.gcscap = gcsc
+}
+
 func (d *Driver) AddVolumeCapabilityAccessModes(vc []csi.VolumeCapability_AccessMode_Mode) []*csi.VolumeCapability_AccessMode {
 	vca := make([]*csi.VolumeCapability_AccessMode, 0, len(vc))
 
 	for _, c := range vc {
-		klog.Infof("Enabling volume access mode: %v", c.String())
+		klog.InfoS("Enabling volume access mode", "mode", c.String())
 		vca = append(vca, NewVolumeCapabilityAccessMode(c))
 	}
 
@@ -136,11 +269,18 @@ func (d *Driver) AddVolumeCapabilityAccessModes(vc []csi.VolumeCapability_Access
 	return vca
 }
 
+// supportsAccessMode reports whether volumes can be used with the access mode.
+func (d *Driver) supportsAccessMode(mode csi.VolumeCapability_AccessMode_Mode) bool {
+	return slices.ContainsFunc(d.vcap, func(c *csi.VolumeCapability_AccessMode) bool {
+		return c.GetMode() == mode
+	})
+}
+
 func (d *Driver) AddNodeServiceCapabilities(nl []csi.NodeServiceCapability_RPC_Type) error {
 	nsc := make([]*csi.NodeServiceCapability, 0, len(nl))
 
 	for _, n := range nl {
-		klog.Infof("Enabling node service capability: %v", n.String())
+		klog.InfoS("Enabling node service capability", "capability", n.String())
 		nsc = append(nsc, NewNodeServiceCapability(n))
 	}
 
@@ -149,14 +289,72 @@ func (d *Driver) AddNodeServiceCapabilities(nl []csi.NodeServiceCapability_RPC_T
 	return nil
 }
 
-func (d *Driver) SetupControllerService(instance stackitclient.IaaSClient) {
-	klog.Info("Providing controller service")
-	d.cs = NewControllerServer(d, instance)
+func (d *Driver) SetupControllerService(instance stackitclient.IaaSClient, opts stackitconfig.BlockStorageOpts) {
+	klog.InfoS("Providing controller service")
+	d.disableControllerCapabilities(opts.DisabledCapabilities)
+	d.manifest = performanceClassManifest(opts)
+	d.cs = NewControllerServer(d, instance, opts)
+	d.gcs = NewGroupControllerServer(d, instance, opts)
+
+	var loops []func(ctx context.Context)
+	if d.backupInformers != nil {
+		klog.InfoS("Creating scheduled backups of PVCs", "checkInterval", backupScheduleCheckInterval)
+		scheduler := newBackupScheduler(d, instance, d.backupInformers)
+		loops = append(loops, func(ctx context.Context) {
+			wait.UntilWithContext(ctx, scheduler.reconcile, backupScheduleCheckInterval)
+		})
+	}
+	if d.cs.reclaimer != nil {
+		klog.InfoS("Deferring the deletion of volumes", "gracePeriod", opts.ReclaimGracePeriod.Duration, "checkInterval", reclaimCheckInterval)
+		loops = append(loops, func(ctx context.Context) {
+			wait.UntilWithContext(ctx, d.cs.reclaimer.reconcile, reclaimCheckInterval)
+		})
+	}
+	if d.nodeFailover != nil {
+		klog.InfoS("Detaching the volumes of failed nodes", "timeout", d.nodeFailover.Timeout, "checkInterval", nodeFailoverCheckInterval)
+		failover := newNodeFailover(d, instance, d.nodeFailover)
+		loops = append(loops, func(ctx context.Context) {
+			wait.UntilWithContext(ctx, failover.reconcile, nodeFailoverCheckInterval)
+		})
+	}
+	if d.attachmentCapacity != nil {
+		klog.InfoS("Checking the attachments of nodes against their volume limit",
+			"threshold", d.attachmentCapacity.Threshold, "checkInterval", attachmentCapacityCheckInterval)
+		capacity := newAttachmentCapacity(d, instance, d.attachmentCapacity)
+		loops = append(loops, func(ctx context.Context) {
+			wait.UntilWithContext(ctx, capacity.reconcile, attachmentCapacityCheckInterval)
+		})
+	}
+	if len(loops) > 0 {
+		go d.runControlLoops(context.Background(), loops)
+	}
+}
+
+// runControlLoops runs the loops until ctx is canceled. With leader election, they only run while this replica is
+// the leader, because they mutate volumes and backups outside of CSI calls.
+func (d *Driver) runControlLoops(ctx context.Context, loops []func(ctx context.Context)) {
+	run := func(ctx context.Context) {
+		var wg sync.WaitGroup
+		for _, loop := range loops {
+			wg.Go(func() { loop(ctx) })
+		}
+		wg.Wait()
+	}
+	if d.leaderElection == nil {
+		run(ctx)
+		return
+	}
+	d.leaderElection(ctx, run)
 }
 
 func (d *Driver) SetupNodeService(mountProvider mount.IMount, metadataProvider metadata.IMetadata, opts stackitconfig.BlockStorageOpts) {
-	klog.Info("Providing node service")
+	klog.InfoS("Providing node service")
 	d.ns = NewNodeServer(d, mountProvider, metadataProvider, opts)
+
+	if opts.FstrimInterval.Duration > 0 {
+		klog.InfoS("Trimming staged filesystems periodically", "interval", opts.FstrimInterval.Duration)
+		go wait.Until(d.ns.trimStagedFilesystems, opts.FstrimInterval.Duration, wait.NeverStop)
+	}
 }
 
 func (d *Driver) Run() {
@@ -164,5 +362,21 @@ func (d *Driver) Run() {
 		klog.Fatal("No CSI services initialized")
 	}
 
-	RunServicesInitialized(d.endpoint, d.ids, d.cs, d.ns)
+	RunServicesInitialized(d.endpoint, d.ids, d.cs, d.gcs, d.ns)
+}
+
+// driverTopologyKey returns the topology key of the driver name.
+func (d *Driver) driverTopologyKey() string {
+	if d.legacyDriver {
+		return legacyTopologyKey
+	}
+	return topologyKey
+}
+
+// topologyKeys returns the configured topology key followed by the topology key of the driver name, if they differ.
+func (d *Driver) topologyKeys() []string {
+	if d.topologyKey == d.driverTopologyKey() {
+		return []string{d.topologyKey}
+	}
+	return []string{d.topologyKey, d.driverTopologyKey()}
 }
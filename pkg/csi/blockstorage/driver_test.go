@@ -0,0 +1,55 @@
+package blockstorage
+
+import (
+	"context"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	sharedcsi "github.com/stackitcloud/cloud-provider-stackit/pkg/csi"
+)
+
+var _ = Describe("Control loops", func() {
+	It("should run the loops only while leading", func() {
+		leading := make(chan context.CancelFunc)
+		d := NewDriver(&DriverOpts{
+			LeaderElection: func(ctx context.Context, run sharedcsi.RunFunc) {
+				leaderCtx, cancel := context.WithCancel(ctx)
+				leading <- cancel
+				run(leaderCtx)
+			},
+		})
+
+		var running atomic.Int32
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			d.runControlLoops(context.Background(), []func(ctx context.Context){
+				func(ctx context.Context) {
+					running.Add(1)
+					<-ctx.Done()
+					running.Add(-1)
+				},
+			})
+		}()
+
+		Consistently(running.Load).Should(BeZero())
+		stopLeading := <-leading
+		Eventually(running.Load).Should(BeEquivalentTo(1))
+		stopLeading()
+		Eventually(done).Should(BeClosed())
+		Expect(running.Load()).To(BeZero())
+	})
+
+	It("should run the loops without leader election", func() {
+		d := NewDriver(&DriverOpts{})
+		ctx, cancel := context.WithCancel(context.Background())
+		started := make(chan struct{})
+		go d.runControlLoops(ctx, []func(ctx context.Context){
+			func(context.Context) { close(started) },
+		})
+		Eventually(started).Should(BeClosed())
+		cancel()
+	})
+})
@@ -0,0 +1,26 @@
+package blockstorage
+
+import (
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// pvcEventf records an event on the PVC the volume was provisioned for. The PVC is identified by the labels
+// CreateVolume sets if the csi-provisioner runs with --extra-create-metadata, otherwise the event is only logged.
+func (d *Driver) pvcEventf(volume *iaas.Volume, eventType, reason, messageFmt string, args ...any) {
+	namespace, _ := volume.Labels[pvcNamespaceLabel].(string)
+	name, _ := volume.Labels[pvcNameLabel].(string)
+	if d.recorder == nil || namespace == "" || name == "" {
+		klog.V(4).InfoS("Not recording event on PVC", "volumeID", volume.GetId(), "reason", reason, "pvc", klog.KRef(namespace, name))
+		return
+	}
+
+	pvc := &corev1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "PersistentVolumeClaim",
+		Namespace:  namespace,
+		Name:       name,
+	}
+	d.recorder.Eventf(pvc, eventType, reason, messageFmt, args...)
+}
@@ -0,0 +1,69 @@
+package blockstorage
+
+import (
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/exec"
+)
+
+const (
+	// fsckErrorsUncorrected is the bit of the fsck exit code for errors that were left uncorrected.
+	fsckErrorsUncorrected = 4
+	// xfsRepairErrorsFound is the exit code of xfs_repair -n if the filesystem is corrupted.
+	xfsRepairErrorsFound = 1
+)
+
+// checkFilesystem checks the existing filesystem of the device before it is staged, so that silent corruption is
+// surfaced when staging instead of by the applications using the volume. Devices without a filesystem are skipped.
+//
+// ext filesystems are repaired automatically with fsck -a where that is safe. Read-only volumes and xfs filesystems
+// are only checked.
+func (ns *nodeServer) checkFilesystem(volumeID, devicePath string, readOnly bool) error {
+	mounter := ns.Mount.Mounter()
+	format, err := mounter.GetDiskFormat(devicePath)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to get the filesystem of volume %q: %v", volumeID, err)
+	}
+
+	var cmd string
+	var args []string
+	switch format {
+	case "ext2", "ext3", "ext4":
+		cmd, args = "fsck", []string{"-a", devicePath}
+		if readOnly {
+			args[0] = "-n"
+		}
+	case "xfs":
+		cmd, args = "xfs_repair", []string{"-n", devicePath}
+	default:
+		klog.V(4).InfoS("Skipping filesystem check", "volumeID", volumeID, "devicePath", devicePath, "format", format)
+		return nil
+	}
+
+	klog.V(4).InfoS("Checking filesystem", "volumeID", volumeID, "devicePath", devicePath, "format", format)
+	out, err := mounter.Exec.Command(cmd, args...).CombinedOutput()
+	if err == nil {
+		return nil
+	}
+
+	var exitErr exec.ExitError
+	switch {
+	case errors.Is(err, exec.ErrExecutableNotFound):
+		klog.InfoS("Skipping filesystem check, the check is not installed", "volumeID", volumeID, "command", cmd)
+		return nil
+	case !errors.As(err, &exitErr):
+		return status.Errorf(codes.Internal, "failed to check the %s filesystem of volume %q: %v", format, volumeID, err)
+	case cmd == "fsck" && exitErr.ExitStatus()&fsckErrorsUncorrected != 0,
+		cmd == "xfs_repair" && exitErr.ExitStatus() == xfsRepairErrorsFound:
+		return status.Errorf(codes.FailedPrecondition,
+			"the %s filesystem of volume %q on %s is corrupted and must be repaired manually: %s", format, volumeID, devicePath, out)
+	case cmd == "fsck" && exitErr.ExitStatus() < fsckErrorsUncorrected:
+		klog.InfoS("Corrected filesystem errors", "volumeID", volumeID, "devicePath", devicePath, "output", string(out))
+		return nil
+	default:
+		return status.Errorf(codes.Internal, "failed to check the %s filesystem of volume %q: %v: %s", format, volumeID, err, out)
+	}
+}
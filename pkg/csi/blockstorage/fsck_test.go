@@ -0,0 +1,114 @@
+package blockstorage
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	mountutils "k8s.io/mount-utils"
+	"k8s.io/utils/exec"
+	testingexec "k8s.io/utils/exec/testing"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/csi/util/mount"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+)
+
+var _ = Describe("checkFilesystem", func() {
+	var (
+		ns       *nodeServer
+		fakeExec *testingexec.FakeExec
+		commands [][]string
+	)
+
+	BeforeEach(func() {
+		commands = nil
+		fakeExec = &testingexec.FakeExec{}
+		mountMock := mount.NewMockIMount(gomock.NewController(GinkgoT()))
+		mountMock.EXPECT().Mounter().Return(&mountutils.SafeFormatAndMount{
+			Interface: mountutils.NewFakeMounter(nil),
+			Exec:      fakeExec,
+		})
+		ns = NewNodeServer(NewDriver(&DriverOpts{}), mountMock, nil, stackitconfig.BlockStorageOpts{FsckOnStage: true})
+	})
+
+	// run adds a command to the script, which returns the output and the exit status, 0 if it succeeds.
+	run := func(output string, exitStatus int) {
+		fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+			commands = append(commands, append([]string{cmd}, args...))
+			return &testingexec.FakeCmd{
+				CombinedOutputScript: []testingexec.FakeAction{func() ([]byte, []byte, error) {
+					if exitStatus != 0 {
+						return []byte(output), nil, &testingexec.FakeExitError{Status: exitStatus}
+					}
+					return []byte(output), nil, nil
+				}},
+			}
+		})
+	}
+
+	blkid := func(format string) {
+		run("DEVNAME=/dev/vdb\nTYPE="+format+"\n", 0)
+	}
+
+	It("should repair ext4 filesystems", func() {
+		blkid("ext4")
+		run("/dev/vdb: clean", 0)
+
+		Expect(ns.checkFilesystem("volume-id", "/dev/vdb", false)).To(Succeed())
+		Expect(commands[1]).To(Equal([]string{"fsck", "-a", "/dev/vdb"}))
+	})
+
+	It("should only check read-only ext4 filesystems", func() {
+		blkid("ext4")
+		run("/dev/vdb: clean", 0)
+
+		Expect(ns.checkFilesystem("volume-id", "/dev/vdb", true)).To(Succeed())
+		Expect(commands[1]).To(Equal([]string{"fsck", "-n", "/dev/vdb"}))
+	})
+
+	It("should accept corrected errors", func() {
+		blkid("ext4")
+		run("/dev/vdb: ***** FILE SYSTEM WAS MODIFIED *****", 1)
+
+		Expect(ns.checkFilesystem("volume-id", "/dev/vdb", false)).To(Succeed())
+	})
+
+	It("should fail on errors fsck could not correct", func() {
+		blkid("ext4")
+		run("/dev/vdb: UNEXPECTED INCONSISTENCY; RUN fsck MANUALLY.", 4)
+
+		err := ns.checkFilesystem("volume-id", "/dev/vdb", false)
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+		Expect(err).To(MatchError(And(ContainSubstring("corrupted"), ContainSubstring("UNEXPECTED INCONSISTENCY"))))
+	})
+
+	It("should fail on corrupted xfs filesystems", func() {
+		blkid("xfs")
+		run("Phase 1 - find and verify superblock...\nbad magic number", 1)
+
+		err := ns.checkFilesystem("volume-id", "/dev/vdb", false)
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+		Expect(commands[1]).To(Equal([]string{"xfs_repair", "-n", "/dev/vdb"}))
+	})
+
+	It("should skip devices without a filesystem", func() {
+		run("", 2)
+
+		Expect(ns.checkFilesystem("volume-id", "/dev/vdb", false)).To(Succeed())
+		Expect(commands).To(HaveLen(1))
+	})
+
+	It("should skip the check if fsck is not installed", func() {
+		blkid("ext4")
+		fakeExec.CommandScript = append(fakeExec.CommandScript, func(_ string, _ ...string) exec.Cmd {
+			return &testingexec.FakeCmd{
+				CombinedOutputScript: []testingexec.FakeAction{func() ([]byte, []byte, error) {
+					return nil, nil, exec.ErrExecutableNotFound
+				}},
+			}
+		})
+
+		Expect(ns.checkFilesystem("volume-id", "/dev/vdb", false)).To(Succeed())
+	})
+})
@@ -0,0 +1,37 @@
+package blockstorage
+
+import (
+	"k8s.io/klog/v2"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/csi/util/mount"
+)
+
+// trimStagedFilesystems runs fstrim on the staging paths of all filesystem volumes of the driver on this node.
+// Trimming returns blocks that are no longer used by the filesystem to the thin-provisioned block storage.
+func (ns *nodeServer) trimStagedFilesystems() {
+	stagingPaths, err := mount.ListLocalCSIFilesystemMounts(ns.Driver.name)
+	if err != nil {
+		klog.ErrorS(err, "Failed to list staged filesystems for fstrim")
+		return
+	}
+	ns.trimFilesystems(stagingPaths)
+}
+
+func (ns *nodeServer) trimFilesystems(stagingPaths []string) {
+	mounter := ns.Mount.Mounter()
+	for _, stagingPath := range stagingPaths {
+		// Never trim the filesystem the staging directory lives on if the volume is not mounted.
+		notMnt, err := mounter.IsLikelyNotMountPoint(stagingPath)
+		if err != nil || notMnt {
+			klog.V(4).InfoS("Skipping fstrim, staging path is not mounted", "stagingPath", stagingPath)
+			continue
+		}
+
+		out, err := mounter.Exec.Command("fstrim", stagingPath).CombinedOutput()
+		if err != nil {
+			klog.ErrorS(err, "Failed to run fstrim", "stagingPath", stagingPath, "output", string(out))
+			continue
+		}
+		klog.V(4).InfoS("Trimmed staged filesystem", "stagingPath", stagingPath)
+	}
+}
@@ -0,0 +1,47 @@
+package blockstorage
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	mountutils "k8s.io/mount-utils"
+	"k8s.io/utils/exec"
+	testingexec "k8s.io/utils/exec/testing"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/csi/util/mount"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+)
+
+var _ = Describe("fstrim", func() {
+	It("should only trim mounted staging paths", func() {
+		tmp := GinkgoT().TempDir()
+		mounted := filepath.Join(tmp, "mounted")
+		unmounted := filepath.Join(tmp, "unmounted")
+		Expect(os.Mkdir(mounted, 0o750)).To(Succeed())
+		Expect(os.Mkdir(unmounted, 0o750)).To(Succeed())
+
+		var trimmed []string
+		fakeExec := &testingexec.FakeExec{}
+		fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+			Expect(cmd).To(Equal("fstrim"))
+			trimmed = append(trimmed, args...)
+			return &testingexec.FakeCmd{
+				CombinedOutputScript: []testingexec.FakeAction{func() ([]byte, []byte, error) { return nil, nil, nil }},
+			}
+		})
+
+		mountMock := mount.NewMockIMount(gomock.NewController(GinkgoT()))
+		mountMock.EXPECT().Mounter().Return(&mountutils.SafeFormatAndMount{
+			Interface: mountutils.NewFakeMounter([]mountutils.MountPoint{{Path: mounted}}),
+			Exec:      fakeExec,
+		})
+
+		ns := NewNodeServer(NewDriver(&DriverOpts{}), mountMock, nil, stackitconfig.BlockStorageOpts{})
+		ns.trimFilesystems([]string{mounted, unmounted, filepath.Join(tmp, "missing")})
+
+		Expect(trimmed).To(Equal([]string{mounted}))
+	})
+})
@@ -0,0 +1,244 @@
+package blockstorage
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubernetes-csi/csi-lib-utils/protosanitizer"
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+// groupControllerServer implements volume group snapshots on top of regular IaaS snapshots.
+// The IaaS API has no native notion of consistency groups, so every member snapshot is labelled
+// with the group snapshot ID (stackitclient.SnapshotGroupLabel) and the group is reconstructed from these labels.
+type groupControllerServer struct {
+	Driver   *Driver
+	Instance stackitclient.IaaSClient
+	// snapshotBackoff is the backoff of waiting for the snapshots of a group to become ready.
+	snapshotBackoff wait.Backoff
+	// snapshots limits the concurrent snapshot operations, nil if they are not limited.
+	snapshots *snapshotLimiter
+	csi.UnimplementedGroupControllerServer
+}
+
+func (gs *groupControllerServer) GroupControllerGetCapabilities(_ context.Context, _ *csi.GroupControllerGetCapabilitiesRequest) (*csi.GroupControllerGetCapabilitiesResponse, error) { //nolint:lll // looks weird when shortened
+	klog.V(5).InfoS("Using default GroupControllerGetCapabilities")
+
+	return &csi.GroupControllerGetCapabilitiesResponse{
+		Capabilities: gs.Driver.gcscap,
+	}, nil
+}
+
+func (gs *groupControllerServer) CreateVolumeGroupSnapshot(ctx context.Context, req *csi.CreateVolumeGroupSnapshotRequest) (*csi.CreateVolumeGroupSnapshotResponse, error) { //nolint:lll // looks weird when shortened
+	klog.V(4).InfoS("CreateVolumeGroupSnapshot called", "args", protosanitizer.StripSecrets(req))
+
+	cloud := gs.Instance
+
+	if gs.Driver.blockVolumeCreation {
+		return nil, status.Errorf(codes.Unimplemented, "The %s driver is update/read-only mode please migrate to the new driver", legacyDriverName)
+	}
+	if len(gs.Driver.gcscap) == 0 {
+		return nil, status.Error(codes.Unimplemented, "[CreateVolumeGroupSnapshot] volume group snapshots are disabled")
+	}
+
+	groupName := req.GetName()
+	volumeIDs := req.GetSourceVolumeIds()
+
+	if groupName == "" {
+		return nil, status.Error(codes.InvalidArgument, "[CreateVolumeGroupSnapshot] missing group snapshot name")
+	}
+	if len(volumeIDs) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "[CreateVolumeGroupSnapshot] source volume IDs must be provided")
+	}
+
+	existing, err := gs.listGroupSnapshots(ctx, groupName)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshotsByVolume := make(map[string]*iaas.Snapshot, len(existing))
+	for i := range existing {
+		snap := &existing[i]
+		if !slices.Contains(volumeIDs, snap.VolumeId) {
+			return nil, status.Errorf(codes.AlreadyExists, "[CreateVolumeGroupSnapshot] group snapshot %s already exists with different source volumes", groupName)
+		}
+		snapshotsByVolume[snap.VolumeId] = snap
+	}
+
+	var missing []string
+	for _, volumeID := range volumeIDs {
+		if _, ok := snapshotsByVolume[volumeID]; !ok {
+			missing = append(missing, volumeID)
+		}
+	}
+	if err := gs.checkGroupSnapshotSources(ctx, missing, req.GetParameters()); err != nil {
+		return nil, err
+	}
+
+	// A group snapshot takes a single slot, so that its snapshots are still triggered back to back. Retries whose
+	// snapshots all exist don't take a slot.
+	if len(missing) > 0 {
+		release, err := gs.snapshots.acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
+	// Trigger all snapshots back to back before waiting on any of them to keep the window between
+	// the individual snapshots as small as possible.
+	for _, volumeID := range volumeIDs {
+		if _, ok := snapshotsByVolume[volumeID]; ok {
+			klog.V(3).InfoS("Found existing snapshot of volume in group snapshot", "volumeID", volumeID, "groupSnapshot", groupName)
+			continue
+		}
+
+		payload := iaas.CreateSnapshotPayload{
+			Name:     new(groupSnapshotMemberName(groupName, volumeID)),
+			VolumeId: volumeID,
+			Labels: stackitclient.LabelsFromTags(stackitclient.WithResourceLabels(
+				map[string]string{stackitclient.SnapshotGroupLabel: groupName}, gs.Driver.resourceLabels)),
+		}
+		snap, err := cloud.CreateSnapshot(ctx, payload)
+		if err != nil {
+			klog.ErrorS(err, "Failed to create snapshot of volume for group snapshot", "volumeID", volumeID, "groupSnapshot", groupName)
+			if stackiterrors.IsNotFound(err) {
+				return nil, status.Errorf(codes.NotFound, "[CreateVolumeGroupSnapshot] source volume %s not found", volumeID)
+			}
+			return nil, status.Errorf(codes.Internal, "[CreateVolumeGroupSnapshot] CreateSnapshot failed with error %v", err)
+		}
+		snapshotsByVolume[volumeID] = snap
+	}
+
+	snapshots := make([]*iaas.Snapshot, 0, len(volumeIDs))
+	for _, volumeID := range volumeIDs {
+		snap := snapshotsByVolume[volumeID]
+		snap.Status, err = cloud.WaitSnapshotReady(ctx, *snap.Id, new(gs.snapshotBackoff))
+		if err != nil {
+			klog.ErrorS(err, "Failed to WaitSnapshotReady")
+			return nil, status.Errorf(codes.Internal, "[CreateVolumeGroupSnapshot] snapshot %s failed getting ready in time: %v", *snap.Id, err)
+		}
+		snapshots = append(snapshots, snap)
+	}
+
+	klog.V(4).InfoS("CreateVolumeGroupSnapshot successfully created group snapshot", "groupSnapshot", groupName, "volumes", len(snapshots))
+
+	return &csi.CreateVolumeGroupSnapshotResponse{
+		GroupSnapshot: volumeGroupSnapshot(groupName, snapshots),
+	}, nil
+}
+
+func (gs *groupControllerServer) DeleteVolumeGroupSnapshot(ctx context.Context, req *csi.DeleteVolumeGroupSnapshotRequest) (*csi.DeleteVolumeGroupSnapshotResponse, error) { //nolint:lll // looks weird when shortened
+	klog.V(4).InfoS("DeleteVolumeGroupSnapshot called", "args", protosanitizer.StripSecrets(req))
+
+	groupID := req.GetGroupSnapshotId()
+	if groupID == "" {
+		return nil, status.Error(codes.InvalidArgument, "[DeleteVolumeGroupSnapshot] group snapshot ID must be provided")
+	}
+
+	snapshotIDs := slices.Clone(req.GetSnapshotIds())
+	existing, err := gs.listGroupSnapshots(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range existing {
+		if !slices.Contains(snapshotIDs, *existing[i].Id) {
+			snapshotIDs = append(snapshotIDs, *existing[i].Id)
+		}
+	}
+
+	for _, id := range snapshotIDs {
+		err := gs.Instance.DeleteSnapshot(ctx, id)
+		if err != nil {
+			if stackiterrors.IsNotFound(err) {
+				klog.V(3).InfoS("Snapshot of group snapshot is already deleted", "snapshotID", id, "groupSnapshotID", groupID)
+				continue
+			}
+			klog.ErrorS(err, "Failed to delete snapshot", "snapshotID", id)
+			return nil, status.Errorf(codes.Internal, "[DeleteVolumeGroupSnapshot] DeleteSnapshot failed with error %v", err)
+		}
+	}
+
+	klog.V(4).InfoS("DeleteVolumeGroupSnapshot successfully deleted group snapshot", "groupSnapshotID", groupID)
+
+	return &csi.DeleteVolumeGroupSnapshotResponse{}, nil
+}
+
+func (gs *groupControllerServer) GetVolumeGroupSnapshot(ctx context.Context, req *csi.GetVolumeGroupSnapshotRequest) (*csi.GetVolumeGroupSnapshotResponse, error) { //nolint:lll // looks weird when shortened
+	klog.V(4).InfoS("GetVolumeGroupSnapshot called", "args", protosanitizer.StripSecrets(req))
+
+	groupID := req.GetGroupSnapshotId()
+	if groupID == "" {
+		return nil, status.Error(codes.InvalidArgument, "[GetVolumeGroupSnapshot] group snapshot ID must be provided")
+	}
+
+	existing, err := gs.listGroupSnapshots(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) == 0 {
+		return nil, status.Errorf(codes.NotFound, "[GetVolumeGroupSnapshot] group snapshot %s not found", groupID)
+	}
+
+	snapshots := make([]*iaas.Snapshot, 0, len(existing))
+	for i := range existing {
+		snapshots = append(snapshots, &existing[i])
+	}
+	for _, id := range req.GetSnapshotIds() {
+		if !slices.ContainsFunc(snapshots, func(snap *iaas.Snapshot) bool { return *snap.Id == id }) {
+			return nil, status.Errorf(codes.NotFound, "[GetVolumeGroupSnapshot] snapshot %s of group snapshot %s not found", id, groupID)
+		}
+	}
+
+	return &csi.GetVolumeGroupSnapshotResponse{
+		GroupSnapshot: volumeGroupSnapshot(groupID, snapshots),
+	}, nil
+}
+
+func (gs *groupControllerServer) listGroupSnapshots(ctx context.Context, groupID string) ([]iaas.Snapshot, error) {
+	snapshots, _, err := gs.Instance.ListSnapshots(ctx, map[string]string{"GroupSnapshotID": groupID})
+	if err != nil {
+		klog.ErrorS(err, "Failed to list snapshots of group snapshot", "groupSnapshotID", groupID)
+		return nil, status.Errorf(codes.Internal, "Failed to get snapshots of group snapshot %s: %v", groupID, err)
+	}
+	return snapshots, nil
+}
+
+// groupSnapshotMemberName returns the name of the snapshot of volumeID within the given group snapshot.
+func groupSnapshotMemberName(groupName, volumeID string) string {
+	return fmt.Sprintf("%s-%s", groupName, volumeID)
+}
+
+func volumeGroupSnapshot(groupID string, snapshots []*iaas.Snapshot) *csi.VolumeGroupSnapshot {
+	group := &csi.VolumeGroupSnapshot{
+		GroupSnapshotId: groupID,
+		Snapshots:       make([]*csi.Snapshot, 0, len(snapshots)),
+		ReadyToUse:      true,
+	}
+
+	for _, snap := range snapshots {
+		entry := snapshotEntry(snap).Snapshot
+		entry.ReadyToUse = snap.GetStatus() == stackitclient.SnapshotReadyStatus
+		group.ReadyToUse = group.ReadyToUse && entry.ReadyToUse
+		// The group is considered to be created at the time of its oldest member
+		if group.CreationTime == nil || entry.CreationTime.AsTime().Before(group.CreationTime.AsTime()) {
+			group.CreationTime = entry.CreationTime
+		}
+		group.Snapshots = append(group.Snapshots, entry)
+	}
+
+	if group.CreationTime == nil {
+		group.CreationTime = timestamppb.Now()
+	}
+
+	return group
+}
@@ -0,0 +1,277 @@
+package blockstorage
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+	"github.com/stackitcloud/stackit-sdk-go/core/oapierror"
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ = Describe("GroupControllerServer test", func() {
+	var (
+		fakeGcs      *groupControllerServer
+		iaasClient   *stackitclientmock.MockIaaSClient
+		groupFilters = map[string]string{"GroupSnapshotID": "group-1"}
+	)
+
+	groupSnapshot := func(id, volumeID string, createdAt time.Time) iaas.Snapshot {
+		return iaas.Snapshot{
+			Id:        new(id),
+			Name:      new(groupSnapshotMemberName("group-1", volumeID)),
+			VolumeId:  volumeID,
+			Size:      new(int64(10)),
+			Status:    new(stackitclient.SnapshotReadyStatus),
+			CreatedAt: new(createdAt),
+			Labels:    map[string]any{stackitclient.SnapshotGroupLabel: "group-1"},
+		}
+	}
+
+	expectDetached := func(volumeIDs ...string) {
+		for _, volumeID := range volumeIDs {
+			iaasClient.EXPECT().GetVolume(gomock.Any(), volumeID).Return(&iaas.Volume{
+				Id: new(volumeID), Status: new(stackitclient.VolumeAvailableStatus),
+			}, nil)
+		}
+	}
+
+	BeforeEach(func() {
+		d := NewDriver(&DriverOpts{Endpoint: "tcp://127.0.0.1:10000", ClusterID: "cluster"})
+
+		mockCtrl := gomock.NewController(GinkgoT())
+		iaasClient = stackitclientmock.NewMockIaaSClient(mockCtrl)
+
+		fakeGcs = NewGroupControllerServer(d, iaasClient, stackitconfig.BlockStorageOpts{})
+	})
+
+	Describe("GroupControllerGetCapabilities", func() {
+		It("should advertise volume group snapshots", func() {
+			resp, err := fakeGcs.GroupControllerGetCapabilities(context.Background(), &csi.GroupControllerGetCapabilitiesRequest{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.GetCapabilities()).To(HaveLen(1))
+			Expect(resp.GetCapabilities()[0].GetRpc().GetType()).To(Equal(csi.GroupControllerServiceCapability_RPC_CREATE_DELETE_GET_VOLUME_GROUP_SNAPSHOT))
+		})
+	})
+
+	Describe("CreateVolumeGroupSnapshot", func() {
+		It("should fail without a name", func() {
+			_, err := fakeGcs.CreateVolumeGroupSnapshot(context.Background(), &csi.CreateVolumeGroupSnapshotRequest{
+				SourceVolumeIds: []string{"vol-1"},
+			})
+			Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		})
+
+		It("should fail without source volumes", func() {
+			_, err := fakeGcs.CreateVolumeGroupSnapshot(context.Background(), &csi.CreateVolumeGroupSnapshotRequest{
+				Name: "group-1",
+			})
+			Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		})
+
+		It("should snapshot every volume with the group label", func() {
+			now := time.Now()
+			iaasClient.EXPECT().ListSnapshots(gomock.Any(), groupFilters).Return([]iaas.Snapshot{}, "", nil)
+			expectDetached("vol-1", "vol-2")
+			for _, volumeID := range []string{"vol-1", "vol-2"} {
+				snap := groupSnapshot("snap-"+volumeID, volumeID, now)
+				iaasClient.EXPECT().CreateSnapshot(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, payload iaas.CreateSnapshotPayload) (*iaas.Snapshot, error) {
+						Expect(payload.VolumeId).To(Equal(volumeID))
+						Expect(*payload.Name).To(Equal("group-1-" + volumeID))
+						Expect(payload.Labels).To(HaveKeyWithValue(stackitclient.SnapshotGroupLabel, "group-1"))
+						return &snap, nil
+					})
+			}
+			iaasClient.EXPECT().WaitSnapshotReady(gomock.Any(), "snap-vol-1", gomock.Any()).Return(new(stackitclient.SnapshotReadyStatus), nil)
+			iaasClient.EXPECT().WaitSnapshotReady(gomock.Any(), "snap-vol-2", gomock.Any()).Return(new(stackitclient.SnapshotReadyStatus), nil)
+
+			resp, err := fakeGcs.CreateVolumeGroupSnapshot(context.Background(), &csi.CreateVolumeGroupSnapshotRequest{
+				Name:            "group-1",
+				SourceVolumeIds: []string{"vol-1", "vol-2"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.GetGroupSnapshot().GetGroupSnapshotId()).To(Equal("group-1"))
+			Expect(resp.GetGroupSnapshot().GetReadyToUse()).To(BeTrue())
+			Expect(resp.GetGroupSnapshot().GetSnapshots()).To(HaveLen(2))
+			for _, snap := range resp.GetGroupSnapshot().GetSnapshots() {
+				Expect(snap.GetGroupSnapshotId()).To(Equal("group-1"))
+			}
+		})
+
+		It("should only create missing snapshots of an existing group", func() {
+			now := time.Now()
+			existing := groupSnapshot("snap-vol-1", "vol-1", now.Add(-time.Minute))
+			created := groupSnapshot("snap-vol-2", "vol-2", now)
+			iaasClient.EXPECT().ListSnapshots(gomock.Any(), groupFilters).Return([]iaas.Snapshot{existing}, "", nil)
+			expectDetached("vol-2")
+			iaasClient.EXPECT().CreateSnapshot(gomock.Any(), gomock.Any()).Return(&created, nil)
+			iaasClient.EXPECT().WaitSnapshotReady(gomock.Any(), "snap-vol-1", gomock.Any()).Return(new(stackitclient.SnapshotReadyStatus), nil)
+			iaasClient.EXPECT().WaitSnapshotReady(gomock.Any(), "snap-vol-2", gomock.Any()).Return(new(stackitclient.SnapshotReadyStatus), nil)
+
+			resp, err := fakeGcs.CreateVolumeGroupSnapshot(context.Background(), &csi.CreateVolumeGroupSnapshotRequest{
+				Name:            "group-1",
+				SourceVolumeIds: []string{"vol-1", "vol-2"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.GetGroupSnapshot().GetSnapshots()).To(HaveLen(2))
+			Expect(resp.GetGroupSnapshot().GetCreationTime().AsTime()).To(BeTemporally("==", *existing.CreatedAt))
+		})
+
+		It("should take a single slot of the snapshot limit for the group", func() {
+			fakeGcs.snapshots = newSnapshotLimiter(1, 1)
+			now := time.Now()
+			iaasClient.EXPECT().ListSnapshots(gomock.Any(), groupFilters).Return([]iaas.Snapshot{}, "", nil)
+			expectDetached("vol-1", "vol-2")
+			for _, volumeID := range []string{"vol-1", "vol-2"} {
+				snap := groupSnapshot("snap-"+volumeID, volumeID, now)
+				iaasClient.EXPECT().CreateSnapshot(gomock.Any(), gomock.Any()).Return(&snap, nil)
+				iaasClient.EXPECT().WaitSnapshotReady(gomock.Any(), snap.GetId(), gomock.Any()).Return(new(stackitclient.SnapshotReadyStatus), nil)
+			}
+
+			_, err := fakeGcs.CreateVolumeGroupSnapshot(context.Background(), &csi.CreateVolumeGroupSnapshotRequest{
+				Name:            "group-1",
+				SourceVolumeIds: []string{"vol-1", "vol-2"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeGcs.snapshots.slots).To(BeEmpty())
+		})
+
+		It("should wait for a slot of the snapshot limit before creating missing snapshots", func() {
+			fakeGcs.snapshots = newSnapshotLimiter(1, 1)
+			release, err := fakeGcs.snapshots.acquire(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			defer release()
+			iaasClient.EXPECT().ListSnapshots(gomock.Any(), groupFilters).Return([]iaas.Snapshot{}, "", nil)
+			expectDetached("vol-1", "vol-2")
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err = fakeGcs.CreateVolumeGroupSnapshot(ctx, &csi.CreateVolumeGroupSnapshotRequest{
+				Name:            "group-1",
+				SourceVolumeIds: []string{"vol-1", "vol-2"},
+			})
+			Expect(status.Code(err)).To(Equal(codes.Aborted))
+		})
+
+		It("should fail if the group already exists with different volumes", func() {
+			existing := groupSnapshot("snap-vol-3", "vol-3", time.Now())
+			iaasClient.EXPECT().ListSnapshots(gomock.Any(), groupFilters).Return([]iaas.Snapshot{existing}, "", nil)
+
+			_, err := fakeGcs.CreateVolumeGroupSnapshot(context.Background(), &csi.CreateVolumeGroupSnapshotRequest{
+				Name:            "group-1",
+				SourceVolumeIds: []string{"vol-1"},
+			})
+			Expect(status.Code(err)).To(Equal(codes.AlreadyExists))
+		})
+
+		It("should return not found if a source volume does not exist", func() {
+			iaasClient.EXPECT().ListSnapshots(gomock.Any(), groupFilters).Return([]iaas.Snapshot{}, "", nil)
+			iaasClient.EXPECT().GetVolume(gomock.Any(), "vol-1").Return(nil, &oapierror.GenericOpenAPIError{StatusCode: http.StatusNotFound})
+
+			_, err := fakeGcs.CreateVolumeGroupSnapshot(context.Background(), &csi.CreateVolumeGroupSnapshotRequest{
+				Name:            "group-1",
+				SourceVolumeIds: []string{"vol-1"},
+			})
+			Expect(status.Code(err)).To(Equal(codes.NotFound))
+		})
+
+		It("should not snapshot any volume of a group with an attached volume", func() {
+			iaasClient.EXPECT().ListSnapshots(gomock.Any(), groupFilters).Return([]iaas.Snapshot{}, "", nil)
+			expectDetached("vol-1")
+			iaasClient.EXPECT().GetVolume(gomock.Any(), "vol-2").Return(&iaas.Volume{
+				Id: new("vol-2"), Status: new(stackitclient.VolumeAttachedStatus), ServerId: new("server-id"),
+			}, nil)
+
+			// The mock fails if any snapshot is created.
+			_, err := fakeGcs.CreateVolumeGroupSnapshot(context.Background(), &csi.CreateVolumeGroupSnapshotRequest{
+				Name:            "group-1",
+				SourceVolumeIds: []string{"vol-1", "vol-2"},
+			})
+			Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+			Expect(err).To(MatchError(ContainSubstring("VolumeGroupSnapshotClass")))
+		})
+
+		It("should snapshot attached volumes with force-create", func() {
+			now := time.Now()
+			iaasClient.EXPECT().ListSnapshots(gomock.Any(), groupFilters).Return([]iaas.Snapshot{}, "", nil)
+			iaasClient.EXPECT().GetVolume(gomock.Any(), "vol-1").Return(&iaas.Volume{
+				Id: new("vol-1"), Status: new(stackitclient.VolumeAttachedStatus), ServerId: new("server-id"),
+			}, nil)
+			snap := groupSnapshot("snap-vol-1", "vol-1", now)
+			iaasClient.EXPECT().CreateSnapshot(gomock.Any(), gomock.Any()).Return(&snap, nil)
+			iaasClient.EXPECT().WaitSnapshotReady(gomock.Any(), "snap-vol-1", gomock.Any()).Return(new(stackitclient.SnapshotReadyStatus), nil)
+
+			_, err := fakeGcs.CreateVolumeGroupSnapshot(context.Background(), &csi.CreateVolumeGroupSnapshotRequest{
+				Name:            "group-1",
+				SourceVolumeIds: []string{"vol-1"},
+				Parameters:      map[string]string{snapshotForceCreateParameter: "true"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Describe("DeleteVolumeGroupSnapshot", func() {
+		It("should fail without a group snapshot ID", func() {
+			_, err := fakeGcs.DeleteVolumeGroupSnapshot(context.Background(), &csi.DeleteVolumeGroupSnapshotRequest{})
+			Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		})
+
+		It("should delete the requested and all labelled snapshots", func() {
+			existing := groupSnapshot("snap-vol-2", "vol-2", time.Now())
+			iaasClient.EXPECT().ListSnapshots(gomock.Any(), groupFilters).Return([]iaas.Snapshot{existing}, "", nil)
+			iaasClient.EXPECT().DeleteSnapshot(gomock.Any(), "snap-vol-1").Return(&oapierror.GenericOpenAPIError{StatusCode: http.StatusNotFound})
+			iaasClient.EXPECT().DeleteSnapshot(gomock.Any(), "snap-vol-2").Return(nil)
+
+			_, err := fakeGcs.DeleteVolumeGroupSnapshot(context.Background(), &csi.DeleteVolumeGroupSnapshotRequest{
+				GroupSnapshotId: "group-1",
+				SnapshotIds:     []string{"snap-vol-1"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Describe("GetVolumeGroupSnapshot", func() {
+		It("should return not found for an unknown group", func() {
+			iaasClient.EXPECT().ListSnapshots(gomock.Any(), groupFilters).Return([]iaas.Snapshot{}, "", nil)
+
+			_, err := fakeGcs.GetVolumeGroupSnapshot(context.Background(), &csi.GetVolumeGroupSnapshotRequest{
+				GroupSnapshotId: "group-1",
+			})
+			Expect(status.Code(err)).To(Equal(codes.NotFound))
+		})
+
+		It("should return not found if a requested snapshot is missing", func() {
+			existing := groupSnapshot("snap-vol-1", "vol-1", time.Now())
+			iaasClient.EXPECT().ListSnapshots(gomock.Any(), groupFilters).Return([]iaas.Snapshot{existing}, "", nil)
+
+			_, err := fakeGcs.GetVolumeGroupSnapshot(context.Background(), &csi.GetVolumeGroupSnapshotRequest{
+				GroupSnapshotId: "group-1",
+				SnapshotIds:     []string{"snap-vol-1", "snap-vol-2"},
+			})
+			Expect(status.Code(err)).To(Equal(codes.NotFound))
+		})
+
+		It("should return the group snapshot", func() {
+			existing := groupSnapshot("snap-vol-1", "vol-1", time.Now())
+			iaasClient.EXPECT().ListSnapshots(gomock.Any(), groupFilters).Return([]iaas.Snapshot{existing}, "", nil)
+
+			resp, err := fakeGcs.GetVolumeGroupSnapshot(context.Background(), &csi.GetVolumeGroupSnapshotRequest{
+				GroupSnapshotId: "group-1",
+				SnapshotIds:     []string{"snap-vol-1"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resp.GetGroupSnapshot().GetSnapshots()).To(HaveLen(1))
+			Expect(resp.GetGroupSnapshot().GetSnapshots()[0].GetSourceVolumeId()).To(Equal("vol-1"))
+		})
+	})
+})
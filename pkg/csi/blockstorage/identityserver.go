@@ -15,7 +15,7 @@ type identityServer struct {
 }
 
 func (ids *identityServer) GetPluginInfo(_ context.Context, _ *csi.GetPluginInfoRequest) (*csi.GetPluginInfoResponse, error) {
-	klog.V(5).Infof("Using default GetPluginInfo")
+	klog.V(5).InfoS("Using default GetPluginInfo")
 
 	if ids.Driver.name == "" {
 		return nil, status.Error(codes.Unavailable, "Driver name not configured")
@@ -28,6 +28,7 @@ func (ids *identityServer) GetPluginInfo(_ context.Context, _ *csi.GetPluginInfo
 	return &csi.GetPluginInfoResponse{
 		Name:          ids.Driver.name,
 		VendorVersion: ids.Driver.fqVersion,
+		Manifest:      ids.Driver.manifest,
 	}, nil
 }
 
@@ -36,7 +37,7 @@ func (ids *identityServer) Probe(_ context.Context, _ *csi.ProbeRequest) (*csi.P
 }
 
 func (ids *identityServer) GetPluginCapabilities(_ context.Context, req *csi.GetPluginCapabilitiesRequest) (*csi.GetPluginCapabilitiesResponse, error) {
-	klog.V(5).Infof("GetPluginCapabilities called with req %+v", req)
+	klog.V(5).InfoS("GetPluginCapabilities called", "request", req)
 	return &csi.GetPluginCapabilitiesResponse{
 		Capabilities: []*csi.PluginCapability{
 			{
@@ -46,6 +47,13 @@ func (ids *identityServer) GetPluginCapabilities(_ context.Context, req *csi.Get
 					},
 				},
 			},
+			{
+				Type: &csi.PluginCapability_Service_{
+					Service: &csi.PluginCapability_Service{
+						Type: csi.PluginCapability_Service_GROUP_CONTROLLER_SERVICE,
+					},
+				},
+			},
 			{
 				Type: &csi.PluginCapability_Service_{
 					Service: &csi.PluginCapability_Service{
@@ -0,0 +1,170 @@
+package blockstorage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"k8s.io/klog/v2"
+)
+
+const (
+	inventoryOpStage     = "NodeStageVolume"
+	inventoryOpPublish   = "NodePublishVolume"
+	inventoryOpUnpublish = "NodeUnpublishVolume"
+)
+
+// VolumeInventoryEntry describes a volume that is currently staged or published on this node.
+type VolumeInventoryEntry struct {
+	VolumeID          string    `json:"volumeID"`
+	StagingTargetPath string    `json:"stagingTargetPath,omitempty"`
+	DevicePath        string    `json:"devicePath,omitempty"`
+	FsType            string    `json:"fsType,omitempty"`
+	MountOptions      []string  `json:"mountOptions,omitempty"`
+	TargetPaths       []string  `json:"targetPaths,omitempty"`
+	LastOperation     string    `json:"lastOperation"`
+	LastOperationTime time.Time `json:"lastOperationTime"`
+}
+
+// volumeInventory keeps track of the volumes the node service handled since it started.
+// It is only used for debugging and therefore kept in memory.
+type volumeInventory struct {
+	mu      sync.RWMutex
+	volumes map[string]*VolumeInventoryEntry
+	now     func() time.Time
+}
+
+func newVolumeInventory() *volumeInventory {
+	return &volumeInventory{
+		volumes: map[string]*VolumeInventoryEntry{},
+		now:     time.Now,
+	}
+}
+
+// entry returns the inventory entry for volumeID, creating it if needed. The caller must hold the lock.
+func (vi *volumeInventory) entry(volumeID, operation string) *VolumeInventoryEntry {
+	e, ok := vi.volumes[volumeID]
+	if !ok {
+		e = &VolumeInventoryEntry{VolumeID: volumeID}
+		vi.volumes[volumeID] = e
+	}
+	e.LastOperation = operation
+	e.LastOperationTime = vi.now()
+	return e
+}
+
+func (vi *volumeInventory) staged(volumeID, stagingTargetPath, devicePath, fsType string, mountOptions []string) {
+	vi.mu.Lock()
+	defer vi.mu.Unlock()
+
+	e := vi.entry(volumeID, inventoryOpStage)
+	e.StagingTargetPath = stagingTargetPath
+	e.DevicePath = devicePath
+	e.FsType = fsType
+	e.MountOptions = slices.Clone(mountOptions)
+}
+
+func (vi *volumeInventory) unstaged(volumeID string) {
+	vi.mu.Lock()
+	defer vi.mu.Unlock()
+
+	delete(vi.volumes, volumeID)
+}
+
+func (vi *volumeInventory) published(volumeID, targetPath string) {
+	vi.mu.Lock()
+	defer vi.mu.Unlock()
+
+	e := vi.entry(volumeID, inventoryOpPublish)
+	if !slices.Contains(e.TargetPaths, targetPath) {
+		e.TargetPaths = append(e.TargetPaths, targetPath)
+	}
+}
+
+func (vi *volumeInventory) unpublished(volumeID, targetPath string) {
+	vi.mu.Lock()
+	defer vi.mu.Unlock()
+
+	e, ok := vi.volumes[volumeID]
+	if !ok {
+		return
+	}
+	e.TargetPaths = slices.DeleteFunc(e.TargetPaths, func(p string) bool { return p == targetPath })
+	e.LastOperation = inventoryOpUnpublish
+	e.LastOperationTime = vi.now()
+}
+
+// list returns a copy of all entries sorted by volume ID.
+func (vi *volumeInventory) list() []VolumeInventoryEntry {
+	vi.mu.RLock()
+	defer vi.mu.RUnlock()
+
+	entries := make([]VolumeInventoryEntry, 0, len(vi.volumes))
+	for _, e := range vi.volumes {
+		c := *e
+		c.MountOptions = slices.Clone(e.MountOptions)
+		c.TargetPaths = slices.Clone(e.TargetPaths)
+		entries = append(entries, c)
+	}
+	slices.SortFunc(entries, func(a, b VolumeInventoryEntry) int {
+		return strings.Compare(a.VolumeID, b.VolumeID)
+	})
+	return entries
+}
+
+func (vi *volumeInventory) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(vi.list()); err != nil {
+		klog.ErrorS(err, "Failed to encode volume inventory")
+	}
+}
+
+// RunDebugServer serves the staged volume inventory of the node service on debugAddr until ctx is cancelled.
+func (d *Driver) RunDebugServer(ctx context.Context, debugAddr string) error {
+	if debugAddr == "" {
+		return errors.New("debug address is empty")
+	}
+	if d.ns == nil {
+		return errors.New("node service is not initialized")
+	}
+
+	klog.InfoS("Starting debug listener", "address", debugAddr)
+
+	mux := http.NewServeMux()
+	mux.Handle("/debug/volumes", d.ns.inventory)
+
+	serv := &http.Server{
+		Addr:              debugAddr,
+		Handler:           mux,
+		ReadTimeout:       5 * time.Second,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      5 * time.Second,
+	}
+	g, gCtx := errgroup.WithContext(ctx)
+
+	g.Go(func() error {
+		if err := serv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	})
+
+	g.Go(func() error {
+		<-gCtx.Done()
+		klog.InfoS("Shutdown debug listener")
+		return serv.Shutdown(gCtx)
+	})
+
+	return g.Wait()
+}
@@ -0,0 +1,85 @@
+package blockstorage
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("volumeInventory", func() {
+	var (
+		vi  *volumeInventory
+		now time.Time
+	)
+
+	BeforeEach(func() {
+		now = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		vi = newVolumeInventory()
+		vi.now = func() time.Time { return now }
+	})
+
+	It("should track staged and published volumes", func() {
+		vi.staged("vol-b", "/staging/b", "/dev/vdc", "xfs", []string{"nouuid"})
+		vi.staged("vol-a", "/staging/a", "/dev/vdb", "ext4", []string{"discard"})
+		now = now.Add(time.Minute)
+		vi.published("vol-a", "/target/a1")
+		vi.published("vol-a", "/target/a2")
+		vi.published("vol-a", "/target/a1")
+
+		entries := vi.list()
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0]).To(Equal(VolumeInventoryEntry{
+			VolumeID:          "vol-a",
+			StagingTargetPath: "/staging/a",
+			DevicePath:        "/dev/vdb",
+			FsType:            "ext4",
+			MountOptions:      []string{"discard"},
+			TargetPaths:       []string{"/target/a1", "/target/a2"},
+			LastOperation:     inventoryOpPublish,
+			LastOperationTime: now,
+		}))
+		Expect(entries[1].VolumeID).To(Equal("vol-b"))
+		Expect(entries[1].LastOperation).To(Equal(inventoryOpStage))
+	})
+
+	It("should forget volumes once they are unpublished and unstaged", func() {
+		vi.staged("vol-a", "/staging/a", "/dev/vdb", "ext4", nil)
+		vi.published("vol-a", "/target/a")
+
+		vi.unpublished("vol-a", "/target/a")
+		Expect(vi.list()).To(ConsistOf(HaveField("TargetPaths", BeEmpty())))
+		Expect(vi.list()[0].LastOperation).To(Equal(inventoryOpUnpublish))
+
+		vi.unstaged("vol-a")
+		Expect(vi.list()).To(BeEmpty())
+	})
+
+	It("should ignore unpublishing unknown volumes", func() {
+		vi.unpublished("vol-a", "/target/a")
+		Expect(vi.list()).To(BeEmpty())
+	})
+
+	It("should serve the inventory as JSON", func() {
+		vi.staged("vol-a", "/staging/a", "/dev/vdb", "ext4", nil)
+
+		rec := httptest.NewRecorder()
+		vi.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/volumes", http.NoBody))
+
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal("application/json"))
+		var entries []VolumeInventoryEntry
+		Expect(json.Unmarshal(rec.Body.Bytes(), &entries)).To(Succeed())
+		Expect(entries).To(ConsistOf(HaveField("DevicePath", "/dev/vdb")))
+	})
+
+	It("should reject other methods", func() {
+		rec := httptest.NewRecorder()
+		vi.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/debug/volumes", http.NoBody))
+
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
@@ -0,0 +1,184 @@
+package blockstorage
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubernetes-csi/csi-lib-utils/protosanitizer"
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
+)
+
+const (
+	// mutableParameterLabels sets labels on the volume, e.g. "team=a,cost-center=42".
+	// A key with a trailing dash, e.g. "team-", removes the label.
+	mutableParameterLabels = "labels"
+	// mutableParameterBackupPolicy records the backup policy that applies to the volume, e.g. for the backup
+	// tooling of the project. An empty value removes it.
+	mutableParameterBackupPolicy = "backupPolicy"
+	// mutableParameterBackupRetentionDays records how long backups of the volume are retained. An empty value
+	// removes it.
+	mutableParameterBackupRetentionDays = "backupRetentionDays"
+
+	backupPolicyLabel        = "backup-policy"
+	backupRetentionDaysLabel = "backup-retention-days"
+
+	EventReasonVolumeModified = "VolumeModified"
+)
+
+var (
+	// labelKeyRegex and labelValueRegex are the formats of IaaS label keys and values.
+	labelKeyRegex   = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?$`)
+	labelValueRegex = regexp.MustCompile(`^([A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?)?$`)
+
+	// reservedLabels are managed by the driver and can't be changed with mutable parameters.
+	reservedLabels = []string{
+		pvcNamespaceLabel, pvcNameLabel, qosIOPSLabel, qosThroughputLabel,
+		stackitclient.ClusterIDLabel, stackitclient.ManagedByLabel, stackitclient.PVCUIDLabel,
+	}
+)
+
+// parseMutableParameters returns the labels set by the mutable parameters of a VolumeAttributesClass.
+// A nil value removes the label from the volume.
+func parseMutableParameters(params map[string]string) (map[string]*string, error) {
+	labels := map[string]*string{}
+	for key, value := range params {
+		switch key {
+		case mutableParameterLabels:
+			for entry := range strings.SplitSeq(value, ",") {
+				entry = strings.TrimSpace(entry)
+				if entry == "" {
+					continue
+				}
+				if labelKey, labelValue, ok := strings.Cut(entry, "="); ok {
+					labels[labelKey] = new(labelValue)
+				} else if labelKey, ok := strings.CutSuffix(entry, "-"); ok {
+					labels[labelKey] = nil
+				} else {
+					return nil, fmt.Errorf("mutable parameter %s: %q must be key=value or key- to remove the label", key, entry)
+				}
+			}
+		case mutableParameterBackupPolicy:
+			labels[backupPolicyLabel] = optionalLabel(value)
+		case mutableParameterBackupRetentionDays:
+			if value != "" {
+				if days, err := strconv.Atoi(value); err != nil || days <= 0 {
+					return nil, fmt.Errorf("mutable parameter %s must be a positive integer", key)
+				}
+			}
+			labels[backupRetentionDaysLabel] = optionalLabel(value)
+		default:
+			return nil, fmt.Errorf("mutable parameter %s is not supported", key)
+		}
+	}
+
+	for key, value := range labels {
+		if slices.Contains(reservedLabels, key) {
+			return nil, fmt.Errorf("label %s is managed by the driver and can't be modified", key)
+		}
+		if !labelKeyRegex.MatchString(key) || strings.HasPrefix(key, "stackit-") {
+			return nil, fmt.Errorf("label key %q is invalid", key)
+		}
+		if value != nil && !labelValueRegex.MatchString(*value) {
+			return nil, fmt.Errorf("value %q of label %s is invalid", *value, key)
+		}
+	}
+	return labels, nil
+}
+
+func optionalLabel(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return new(value)
+}
+
+// labelChanges returns the labels that need to be updated to get from current to desired.
+// Removed labels have a nil value, which deletes them in UpdateVolume.
+func labelChanges(current map[string]any, desired map[string]*string) map[string]any {
+	changes := map[string]any{}
+	for key, value := range desired {
+		currentValue, exists := current[key]
+		switch {
+		case value == nil && exists:
+			changes[key] = nil
+		case value != nil && (!exists || currentValue != *value):
+			changes[key] = *value
+		}
+	}
+	return changes
+}
+
+// describeLabelChanges formats the changes for events, e.g. "backup-policy=daily, team removed".
+func describeLabelChanges(changes map[string]any) string {
+	var descriptions []string
+	for _, key := range slices.Sorted(maps.Keys(changes)) {
+		if changes[key] == nil {
+			descriptions = append(descriptions, key+" removed")
+		} else {
+			descriptions = append(descriptions, fmt.Sprintf("%s=%s", key, changes[key]))
+		}
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+func (cs *controllerServer) ControllerModifyVolume(ctx context.Context, req *csi.ControllerModifyVolumeRequest) (*csi.ControllerModifyVolumeResponse, error) { //nolint:lll // looks weird when shortened
+	klog.V(4).InfoS("ControllerModifyVolume called", "args", protosanitizer.StripSecrets(req))
+
+	if err := cs.Driver.requireControllerCapability(csi.ControllerServiceCapability_RPC_MODIFY_VOLUME); err != nil {
+		return nil, err
+	}
+
+	volumeID := req.GetVolumeId()
+	if volumeID == "" {
+		return nil, status.Error(codes.InvalidArgument, "ControllerModifyVolume Volume ID must be provided")
+	}
+	desired, err := parseMutableParameters(req.GetMutableParameters())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if !cs.operationLocks.TryAcquire(volumeID) {
+		return nil, status.Errorf(codes.Aborted, "ControllerModifyVolume an operation for volume %s is already in progress", volumeID)
+	}
+	defer cs.operationLocks.Release(volumeID)
+
+	volume, err := cs.Instance.GetVolume(ctx, volumeID)
+	if err != nil {
+		if stackiterrors.IsNotFound(err) {
+			return nil, status.Errorf(codes.NotFound, "ControllerModifyVolume Volume %s not found", volumeID)
+		}
+		return nil, status.Errorf(codes.Internal, "ControllerModifyVolume failed to get volume %s: %v", volumeID, err)
+	}
+
+	// Volumes created before resource labels were enabled get them on their next modification.
+	for key, value := range cs.Driver.volumeResourceLabels(volume.GetName()) {
+		desired[key] = new(value)
+	}
+	changes := labelChanges(volume.Labels, desired)
+	if len(changes) == 0 {
+		klog.V(4).InfoS("ControllerModifyVolume: volume is up to date", "volumeID", volumeID)
+		return &csi.ControllerModifyVolumeResponse{}, nil
+	}
+
+	if _, err := cs.Instance.UpdateVolume(ctx, volumeID, iaas.UpdateVolumePayload{Labels: changes}); err != nil {
+		return nil, status.Errorf(codes.Internal, "ControllerModifyVolume failed to update volume %s: %v", volumeID, err)
+	}
+
+	description := describeLabelChanges(changes)
+	klog.InfoS("ControllerModifyVolume: modified volume", "volumeID", volumeID, "changes", description)
+	cs.Driver.pvcEventf(volume, corev1.EventTypeNormal, EventReasonVolumeModified, "Modified labels of volume %s: %s", volumeID, description)
+	return &csi.ControllerModifyVolumeResponse{}, nil
+}
@@ -0,0 +1,125 @@
+package blockstorage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stackitcloud/stackit-sdk-go/core/oapierror"
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/client-go/tools/record"
+
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+)
+
+var _ = Describe("ControllerModifyVolume", func() {
+	var (
+		iaasClient *stackitclientmock.MockIaaSClient
+		recorder   *record.FakeRecorder
+		cs         *controllerServer
+		volume     *iaas.Volume
+	)
+
+	BeforeEach(func() {
+		recorder = record.NewFakeRecorder(10)
+		iaasClient = stackitclientmock.NewMockIaaSClient(gomock.NewController(GinkgoT()))
+		cs = NewControllerServer(NewDriver(&DriverOpts{EventRecorder: recorder}), iaasClient, stackitconfig.BlockStorageOpts{})
+
+		volume = &iaas.Volume{
+			Id: new("volume-id"),
+			Labels: map[string]any{
+				pvcNamespaceLabel: "default",
+				pvcNameLabel:      "data",
+				"team":            "a",
+				"cost-center":     "42",
+			},
+		}
+	})
+
+	modify := func(params map[string]string) error {
+		_, err := cs.ControllerModifyVolume(context.Background(), &csi.ControllerModifyVolumeRequest{
+			VolumeId:          "volume-id",
+			MutableParameters: params,
+		})
+		return err
+	}
+
+	It("should only update the changed labels and record an event", func() {
+		iaasClient.EXPECT().GetVolume(gomock.Any(), "volume-id").Return(volume, nil)
+		iaasClient.EXPECT().UpdateVolume(gomock.Any(), "volume-id", iaas.UpdateVolumePayload{Labels: map[string]any{
+			"team":                   "b",
+			"cost-center":            nil,
+			backupPolicyLabel:        "daily",
+			backupRetentionDaysLabel: "7",
+		}}).Return(volume, nil)
+
+		Expect(modify(map[string]string{
+			mutableParameterLabels:              "team=b, cost-center-, unknown-",
+			mutableParameterBackupPolicy:        "daily",
+			mutableParameterBackupRetentionDays: "7",
+		})).To(Succeed())
+		Expect(recorder.Events).To(Receive(Equal(
+			"Normal VolumeModified Modified labels of volume volume-id: backup-policy=daily, backup-retention-days=7, cost-center removed, team=b")))
+	})
+
+	It("should not update a volume that is up to date", func() {
+		iaasClient.EXPECT().GetVolume(gomock.Any(), "volume-id").Return(volume, nil)
+
+		Expect(modify(map[string]string{mutableParameterLabels: "team=a", mutableParameterBackupPolicy: ""})).To(Succeed())
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	It("should return NotFound for unknown volumes", func() {
+		iaasClient.EXPECT().GetVolume(gomock.Any(), "volume-id").Return(nil, &oapierror.GenericOpenAPIError{StatusCode: http.StatusNotFound})
+
+		Expect(status.Code(modify(map[string]string{mutableParameterLabels: "team=b"}))).To(Equal(codes.NotFound))
+	})
+
+	It("should fail if the update fails", func() {
+		iaasClient.EXPECT().GetVolume(gomock.Any(), "volume-id").Return(volume, nil)
+		iaasClient.EXPECT().UpdateVolume(gomock.Any(), "volume-id", gomock.Any()).Return(nil, errors.New("injected error"))
+
+		err := modify(map[string]string{mutableParameterLabels: "team=b"})
+		Expect(status.Code(err)).To(Equal(codes.Internal))
+		Expect(recorder.Events).NotTo(Receive())
+	})
+
+	DescribeTable("should reject invalid mutable parameters",
+		func(params map[string]string) {
+			Expect(status.Code(modify(params))).To(Equal(codes.InvalidArgument))
+		},
+		Entry("unknown parameter", map[string]string{"type": "storage_premium_perf4"}),
+		Entry("malformed label", map[string]string{mutableParameterLabels: "team"}),
+		Entry("invalid label key", map[string]string{mutableParameterLabels: "team/a=b"}),
+		Entry("invalid label value", map[string]string{mutableParameterLabels: "team=a b"}),
+		Entry("reserved label", map[string]string{mutableParameterLabels: pvcNameLabel + "=other"}),
+		Entry("STACKIT label", map[string]string{mutableParameterLabels: "stackit-owner=me"}),
+		Entry("invalid retention", map[string]string{mutableParameterBackupRetentionDays: "-1"}),
+	)
+
+	It("should apply the mutable parameters when creating a volume", func() {
+		iaasClient.EXPECT().GetVolumesByName(gomock.Any(), "new volume").Return([]iaas.Volume{}, nil)
+		iaasClient.EXPECT().CreateVolume(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, payload iaas.CreateVolumePayload) (*iaas.Volume, error) {
+			Expect(payload.Labels).To(Equal(map[string]any{"team": "a", backupPolicyLabel: "daily"}))
+			return &iaas.Volume{Id: new("volume-id"), AvailabilityZone: "eu01", Size: new(int64(20))}, nil
+		})
+		iaasClient.EXPECT().WaitVolumeTargetStatusWithCustomBackoff(gomock.Any(), "volume-id", gomock.Any(), gomock.Any()).Return(nil)
+
+		_, err := cs.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+			Name: "new volume",
+			VolumeCapabilities: []*csi.VolumeCapability{{
+				AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+				AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER},
+			}},
+			MutableParameters: map[string]string{mutableParameterLabels: "team=a,old-", mutableParameterBackupPolicy: "daily"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
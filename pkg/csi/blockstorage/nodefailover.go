@@ -0,0 +1,182 @@
+package blockstorage
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/klog/v2"
+
+	sharedcsi "github.com/stackitcloud/cloud-provider-stackit/pkg/csi"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/providerid"
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
+)
+
+const (
+	// nodeFailoverCheckInterval is the interval in which the nodes are checked for failures.
+	nodeFailoverCheckInterval = 10 * time.Second
+
+	// outOfServiceTaintValue marks the out-of-service taints added by the node failover, only these are removed
+	// once the node is ready again.
+	outOfServiceTaintValue = "csi-node-failover"
+)
+
+// serverStoppedPowerStatuses are the power statuses of servers that can't write to their volumes anymore.
+var serverStoppedPowerStatuses = []string{"CRASHED", "ERROR", "STOPPED"}
+
+// nodeFailover detaches the volumes of nodes that are not ready for longer than the timeout, so that their pods can
+// be started on other nodes without waiting for the attach-detach controller, which force-detaches volumes only after
+// 6 minutes. Volumes are only detached if the server of the node is not running, so that a node that just lost its
+// connection to the API server never loses its volumes while it still writes to them.
+//
+// The node is tainted with node.kubernetes.io/out-of-service, which makes Kubernetes delete its pods and
+// VolumeAttachments immediately. The taint is removed once the node is ready again.
+type nodeFailover struct {
+	driver            *Driver
+	instance          stackitclient.IaaSClient
+	client            kubernetes.Interface
+	nodes             corelisters.NodeLister
+	pvs               corelisters.PersistentVolumeLister
+	volumeAttachments storagelisters.VolumeAttachmentLister
+	timeout           time.Duration
+	now               func() time.Time
+}
+
+func newNodeFailover(d *Driver, instance stackitclient.IaaSClient, opts *sharedcsi.NodeFailover) *nodeFailover {
+	return &nodeFailover{
+		driver:            d,
+		instance:          instance,
+		client:            opts.Client,
+		nodes:             opts.Informers.Core().V1().Nodes().Lister(),
+		pvs:               opts.Informers.Core().V1().PersistentVolumes().Lister(),
+		volumeAttachments: opts.Informers.Storage().V1().VolumeAttachments().Lister(),
+		timeout:           opts.Timeout,
+		now:               time.Now,
+	}
+}
+
+// reconcile fails over the volumes of all failed nodes and removes the taint of recovered nodes.
+func (f *nodeFailover) reconcile(ctx context.Context) {
+	nodes, err := f.nodes.List(labels.Everything())
+	if err != nil {
+		klog.ErrorS(err, "Failed to list nodes for node failover")
+		return
+	}
+
+	for _, node := range nodes {
+		tainted := slices.ContainsFunc(node.Spec.Taints, isNodeFailoverTaint)
+		switch {
+		case f.failed(node) && !tainted:
+			f.failover(ctx, node)
+		case nodeReady(node) && tainted:
+			f.untaint(ctx, node)
+		}
+	}
+}
+
+// failed returns whether the node is not ready for longer than the timeout.
+func (f *nodeFailover) failed(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status != corev1.ConditionTrue && f.now().Sub(condition.LastTransitionTime.Time) >= f.timeout
+		}
+	}
+	return false
+}
+
+// failover detaches the volumes of the node and taints it if its server is not running.
+func (f *nodeFailover) failover(ctx context.Context, node *corev1.Node) {
+	serverID, err := providerid.ServerID(node.Spec.ProviderID)
+	if err != nil {
+		klog.V(4).InfoS("Skipping node failover of node without STACKIT provider ID", "node", klog.KObj(node), "err", err)
+		return
+	}
+	server, err := f.instance.GetServer(ctx, serverID)
+	if err != nil && !stackiterrors.IsNotFound(err) {
+		klog.ErrorS(err, "Failed to get server of failed node", "node", klog.KObj(node), "serverID", serverID)
+		return
+	}
+	if server != nil && !slices.Contains(serverStoppedPowerStatuses, server.GetPowerStatus()) {
+		klog.V(3).InfoS("Skipping node failover, because the server of the node is still running",
+			"node", klog.KObj(node), "serverID", serverID, "powerStatus", server.GetPowerStatus())
+		return
+	}
+
+	klog.InfoS("Failing over the volumes of node", "node", klog.KObj(node), "serverID", serverID, "timeout", f.timeout)
+	if server != nil {
+		for _, volumeID := range f.attachedVolumes(node.Name) {
+			err := f.instance.DetachVolume(ctx, serverID, volumeID)
+			if err != nil && !stackiterrors.IsNotFound(err) {
+				// Kubernetes still detaches the volume once the node is tainted.
+				klog.ErrorS(err, "Failed to detach volume of failed node", "node", klog.KObj(node), "volumeID", volumeID)
+				continue
+			}
+			klog.InfoS("Detached volume of failed node", "node", klog.KObj(node), "volumeID", volumeID)
+		}
+	}
+
+	node = node.DeepCopy()
+	node.Spec.Taints = append(node.Spec.Taints, corev1.Taint{
+		Key:       corev1.TaintNodeOutOfService,
+		Value:     outOfServiceTaintValue,
+		Effect:    corev1.TaintEffectNoExecute,
+		TimeAdded: &metav1.Time{Time: f.now()},
+	})
+	if _, err := f.client.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{}); err != nil {
+		klog.ErrorS(err, "Failed to taint failed node", "node", klog.KObj(node))
+	}
+}
+
+// untaint removes the out-of-service taint of the node failover from the node.
+func (f *nodeFailover) untaint(ctx context.Context, node *corev1.Node) {
+	node = node.DeepCopy()
+	node.Spec.Taints = slices.DeleteFunc(node.Spec.Taints, isNodeFailoverTaint)
+	if _, err := f.client.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{}); err != nil {
+		klog.ErrorS(err, "Failed to remove out-of-service taint from recovered node", "node", klog.KObj(node))
+		return
+	}
+	klog.InfoS("Removed out-of-service taint from recovered node", "node", klog.KObj(node))
+}
+
+// attachedVolumes returns the IDs of the volumes of this driver that are attached to the node.
+func (f *nodeFailover) attachedVolumes(nodeName string) []string {
+	attachments, err := f.volumeAttachments.List(labels.Everything())
+	if err != nil {
+		klog.ErrorS(err, "Failed to list VolumeAttachments", "node", nodeName)
+		return nil
+	}
+	var volumeIDs []string
+	for _, attachment := range attachments {
+		pvName := attachment.Spec.Source.PersistentVolumeName
+		if attachment.Spec.Attacher != f.driver.name || attachment.Spec.NodeName != nodeName || pvName == nil {
+			continue
+		}
+		pv, err := f.pvs.Get(*pvName)
+		if err != nil || pv.Spec.CSI == nil {
+			klog.V(4).InfoS("Failed to get PV of VolumeAttachment", "volumeAttachment", klog.KObj(attachment), "pv", *pvName, "err", err)
+			continue
+		}
+		volumeIDs = append(volumeIDs, pv.Spec.CSI.VolumeHandle)
+	}
+	return volumeIDs
+}
+
+func nodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func isNodeFailoverTaint(taint corev1.Taint) bool {
+	return taint.Key == corev1.TaintNodeOutOfService && taint.Value == outOfServiceTaintValue
+}
@@ -0,0 +1,129 @@
+package blockstorage
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/client-go/tools/cache"
+
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+)
+
+var _ = Describe("nodeFailover", func() {
+	var (
+		iaasClient *stackitclientmock.MockIaaSClient
+		client     *fake.Clientset
+		failover   *nodeFailover
+		nodes      cache.Indexer
+		now        time.Time
+		node       *corev1.Node
+	)
+
+	BeforeEach(func() {
+		now = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+		iaasClient = stackitclientmock.NewMockIaaSClient(gomock.NewController(GinkgoT()))
+
+		node = &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+			Spec:       corev1.NodeSpec{ProviderID: "stackit:///server-1"},
+			Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{{
+				Type:               corev1.NodeReady,
+				Status:             corev1.ConditionUnknown,
+				LastTransitionTime: metav1.NewTime(now.Add(-time.Minute)),
+			}}},
+		}
+		client = fake.NewClientset(node)
+		nodes = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		Expect(nodes.Add(node)).To(Succeed())
+
+		pvs := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		Expect(pvs.Add(&corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-1"},
+			Spec: corev1.PersistentVolumeSpec{PersistentVolumeSource: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{Driver: driverName, VolumeHandle: "volume-id"},
+			}},
+		})).To(Succeed())
+		attachments := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		for _, attachment := range []*storagev1.VolumeAttachment{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "va-1"},
+				Spec: storagev1.VolumeAttachmentSpec{
+					Attacher: driverName, NodeName: "node-1",
+					Source: storagev1.VolumeAttachmentSource{PersistentVolumeName: new("pv-1")},
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "va-other-node"},
+				Spec: storagev1.VolumeAttachmentSpec{
+					Attacher: driverName, NodeName: "node-2",
+					Source: storagev1.VolumeAttachmentSource{PersistentVolumeName: new("pv-1")},
+				},
+			},
+		} {
+			Expect(attachments.Add(attachment)).To(Succeed())
+		}
+
+		failover = &nodeFailover{
+			driver:            NewDriver(&DriverOpts{}),
+			instance:          iaasClient,
+			client:            client,
+			nodes:             corelisters.NewNodeLister(nodes),
+			pvs:               corelisters.NewPersistentVolumeLister(pvs),
+			volumeAttachments: storagelisters.NewVolumeAttachmentLister(attachments),
+			timeout:           30 * time.Second,
+			now:               func() time.Time { return now },
+		}
+	})
+
+	taints := func() []corev1.Taint {
+		n, err := client.CoreV1().Nodes().Get(context.Background(), "node-1", metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		return n.Spec.Taints
+	}
+
+	It("should detach the volumes of a failed node with a stopped server and taint it", func() {
+		iaasClient.EXPECT().GetServer(gomock.Any(), "server-1").Return(&iaas.Server{PowerStatus: new("CRASHED")}, nil)
+		iaasClient.EXPECT().DetachVolume(gomock.Any(), "server-1", "volume-id").Return(nil)
+
+		failover.reconcile(context.Background())
+		Expect(taints()).To(ConsistOf(HaveField("Key", corev1.TaintNodeOutOfService)))
+	})
+
+	It("should not touch a failed node whose server is still running", func() {
+		iaasClient.EXPECT().GetServer(gomock.Any(), "server-1").Return(&iaas.Server{PowerStatus: new("RUNNING")}, nil)
+
+		failover.reconcile(context.Background())
+		Expect(taints()).To(BeEmpty())
+	})
+
+	It("should wait for the timeout", func() {
+		failover.timeout = 2 * time.Minute
+
+		failover.reconcile(context.Background())
+		Expect(taints()).To(BeEmpty())
+	})
+
+	It("should remove the taint once the node is ready again", func() {
+		node.Spec.Taints = []corev1.Taint{
+			{Key: corev1.TaintNodeOutOfService, Value: outOfServiceTaintValue, Effect: corev1.TaintEffectNoExecute},
+			{Key: "other", Effect: corev1.TaintEffectNoSchedule},
+		}
+		node.Status.Conditions[0].Status = corev1.ConditionTrue
+		Expect(nodes.Update(node)).To(Succeed())
+		_, err := client.CoreV1().Nodes().Update(context.Background(), node, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		failover.reconcile(context.Background())
+		Expect(taints()).To(ConsistOf(HaveField("Key", "other")))
+	})
+})
@@ -21,7 +21,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/kubernetes-csi/csi-lib-utils/protosanitizer"
@@ -44,11 +47,15 @@ type nodeServer struct {
 	Mount    mount.IMount
 	Metadata metadata.IMetadata
 	Opts     stackitconfig.BlockStorageOpts
+
+	inventory *volumeInventory
+	// remountAttempts contains the IDs of volumes whose filesystem was remounted read-write while they are staged
+	remountAttempts sync.Map
 	csi.UnimplementedNodeServer
 }
 
 func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublishVolumeRequest) (*csi.NodePublishVolumeResponse, error) {
-	klog.V(4).Infof("NodePublishVolume: called with args %+v", protosanitizer.StripSecrets(req))
+	klog.V(4).InfoS("NodePublishVolume called", "args", protosanitizer.StripSecrets(req))
 
 	volumeID := req.GetVolumeId()
 	source := req.GetStagingTargetPath()
@@ -76,8 +83,9 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 		return nil, status.Error(codes.InvalidArgument, "NodePublishVolume Staging Target Path must be provided")
 	}
 
+	readOnly := req.GetReadonly() || req.GetPublishContext()[publishReadOnlyKey] == "true"
 	mountOptions := []string{"bind"}
-	if req.GetReadonly() {
+	if readOnly {
 		mountOptions = append(mountOptions, "ro")
 	} else {
 		mountOptions = append(mountOptions, "rw")
@@ -96,6 +104,13 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 
 	// Volume Mount
 	if notMnt {
+		// Don't hand a filesystem that silently turned read-only to a workload expecting to write to it.
+		if !readOnly {
+			if condition := ns.checkReadOnlyRemount(volumeID, source); condition.Abnormal {
+				return nil, status.Errorf(codes.Internal, "staged filesystem of volume %s: %s", volumeID, condition.Message)
+			}
+		}
+
 		fsType := "ext4"
 		if mnt := volumeCapability.GetMount(); mnt != nil {
 			if mnt.FsType != "" {
@@ -109,11 +124,12 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 		}
 	}
 
+	ns.inventory.published(volumeID, targetPath)
 	return &csi.NodePublishVolumeResponse{}, nil
 }
 
 func nodePublishVolumeForBlock(ctx context.Context, req *csi.NodePublishVolumeRequest, ns *nodeServer, mountOptions []string) (*csi.NodePublishVolumeResponse, error) { //nolint:lll // looks weird when shortened
-	klog.V(4).Infof("NodePublishVolumeBlock: called with args %+v", protosanitizer.StripSecrets(req))
+	klog.V(4).InfoS("NodePublishVolumeBlock called", "args", protosanitizer.StripSecrets(req))
 
 	volumeID := req.GetVolumeId()
 	targetPath := req.GetTargetPath()
@@ -148,11 +164,12 @@ func nodePublishVolumeForBlock(ctx context.Context, req *csi.NodePublishVolumeRe
 		return nil, status.Errorf(codes.Internal, "Could not mount %q at %q: %v", source, targetPath, err)
 	}
 
+	ns.inventory.published(volumeID, targetPath)
 	return &csi.NodePublishVolumeResponse{}, nil
 }
 
 func (ns *nodeServer) NodeUnpublishVolume(_ context.Context, req *csi.NodeUnpublishVolumeRequest) (*csi.NodeUnpublishVolumeResponse, error) {
-	klog.V(4).Infof("NodeUnPublishVolume: called with args %+v", protosanitizer.StripSecrets(req))
+	klog.V(4).InfoS("NodeUnpublishVolume called", "args", protosanitizer.StripSecrets(req))
 
 	volumeID := req.GetVolumeId()
 	targetPath := req.GetTargetPath()
@@ -166,12 +183,13 @@ func (ns *nodeServer) NodeUnpublishVolume(_ context.Context, req *csi.NodeUnpubl
 	if err := ns.Mount.UnmountPath(targetPath); err != nil {
 		return nil, status.Errorf(codes.Internal, "Unmount of targetpath %s failed with error %v", targetPath, err)
 	}
+	ns.inventory.unpublished(volumeID, targetPath)
 
 	return &csi.NodeUnpublishVolumeResponse{}, nil
 }
 
 func (ns *nodeServer) NodeStageVolume(ctx context.Context, req *csi.NodeStageVolumeRequest) (*csi.NodeStageVolumeResponse, error) {
-	klog.V(4).Infof("NodeStageVolume: called with args %+v", protosanitizer.StripSecrets(req))
+	klog.V(4).InfoS("NodeStageVolume called", "args", protosanitizer.StripSecrets(req))
 
 	stagingTarget, volumeCapability, volumeContext, volumeID, err := validateNodeStageVolumeRequest(req)
 	if err != nil {
@@ -185,8 +203,14 @@ func (ns *nodeServer) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 		return nil, status.Errorf(codes.Internal, "Unable to find Device path for volume: %v", err)
 	}
 
+	// Make sure we never hand out or format a disk that belongs to a different volume
+	if err := verifyDeviceIdentity(devicePath, volumeID, m); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
 	if blk := volumeCapability.GetBlock(); blk != nil {
 		// If block volume, do nothing
+		ns.inventory.staged(volumeID, stagingTarget, devicePath, "", nil)
 		return &csi.NodeStageVolumeResponse{}, nil
 	}
 
@@ -196,17 +220,27 @@ func (ns *nodeServer) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
+	// set default fstype is ext4
+	fsType := "ext4"
+	var options []string
+	if mnt := volumeCapability.GetMount(); mnt != nil {
+		if mnt.FsType != "" {
+			fsType = mnt.FsType
+		}
+		mountFlags := mnt.GetMountFlags()
+		options = append(options, collectMountOptions(fsType, mountFlags, ns.Opts.Discard)...)
+	}
+	if req.GetPublishContext()[publishReadOnlyKey] == "true" && !slices.Contains(options, "ro") {
+		options = append(options, "ro")
+	}
+	readOnly := slices.Contains(options, "ro")
+
 	// Volume Mount
 	if notMnt {
-		// set default fstype is ext4
-		fsType := "ext4"
-		var options []string
-		if mnt := volumeCapability.GetMount(); mnt != nil {
-			if mnt.FsType != "" {
-				fsType = mnt.FsType
+		if ns.Opts.FsckOnStage {
+			if err := ns.checkFilesystem(volumeID, devicePath, readOnly); err != nil {
+				return nil, err
 			}
-			mountFlags := mnt.GetMountFlags()
-			options = append(options, collectMountOptions(fsType, mountFlags)...)
 		}
 		// Mount
 		err = ns.formatAndMountRetry(devicePath, stagingTarget, fsType, options)
@@ -215,7 +249,8 @@ func (ns *nodeServer) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 		}
 	}
 
-	if required, ok := volumeContext[ResizeRequired]; ok && strings.EqualFold(required, "true") {
+	// Read-only filesystems can neither be resized nor chowned, they are resized once staged read-write.
+	if required, ok := volumeContext[ResizeRequired]; ok && strings.EqualFold(required, "true") && !readOnly {
 		r := mountutil.NewResizeFs(ns.Mount.Mounter().Exec)
 
 		needResize, err := r.NeedResize(devicePath, stagingTarget)
@@ -224,16 +259,43 @@ func (ns *nodeServer) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 		}
 
 		if needResize {
-			klog.V(4).Infof("NodeStageVolume: Resizing volume %q created from a snapshot/volume", volumeID)
+			klog.V(4).InfoS("NodeStageVolume resizing volume created from a snapshot or volume", "volumeID", volumeID)
 			if _, err := r.Resize(devicePath, stagingTarget); err != nil {
 				return nil, status.Errorf(codes.Internal, "Could not resize volume %q: %v", volumeID, err)
 			}
 		}
 	}
 
+	if !readOnly {
+		if err := ns.applyVolumeMountGroup(volumeID, stagingTarget, volumeCapability.GetMount().GetVolumeMountGroup()); err != nil {
+			return nil, err
+		}
+	}
+
+	ns.inventory.staged(volumeID, stagingTarget, devicePath, fsType, options)
 	return &csi.NodeStageVolumeResponse{}, nil
 }
 
+// applyVolumeMountGroup applies the fsGroup the kubelet delegated to the driver to the staged filesystem.
+func (ns *nodeServer) applyVolumeMountGroup(volumeID, stagingTarget, volumeMountGroup string) error {
+	if volumeMountGroup == "" {
+		return nil
+	}
+	if ns.Driver.fsGroupPolicy != FSGroupPolicyFile {
+		klog.V(4).InfoS("Ignoring volume mount group", "volumeID", volumeID, "fsGroupPolicy", ns.Driver.fsGroupPolicy)
+		return nil
+	}
+
+	gid, err := strconv.ParseInt(volumeMountGroup, 10, 32)
+	if err != nil || gid < 0 {
+		return status.Errorf(codes.InvalidArgument, "volume mount group %q is not a valid group ID", volumeMountGroup)
+	}
+	if err := ns.Mount.SetVolumeGroup(stagingTarget, gid); err != nil {
+		return status.Errorf(codes.Internal, "Could not apply volume mount group %d to volume %q: %v", gid, volumeID, err)
+	}
+	return nil
+}
+
 func validateNodeStageVolumeRequest(req *csi.NodeStageVolumeRequest) (stagingTarget string, volumeCapability *csi.VolumeCapability, volumeContext map[string]string, volumeID string, err error) { //nolint:lll // looks weird when shortened
 	stagingTarget = req.GetStagingTargetPath()
 	volumeCapability = req.GetVolumeCapability()
@@ -253,6 +315,10 @@ func validateNodeStageVolumeRequest(req *csi.NodeStageVolumeRequest) (stagingTar
 		err = status.Error(codes.InvalidArgument, "NodeStageVolume Volume Capability must be provided")
 		return
 	}
+	if mountErr := validateMountOptions(volumeCapability.GetMount().GetMountFlags()); mountErr != nil {
+		err = status.Error(codes.InvalidArgument, mountErr.Error())
+		return
+	}
 	return
 }
 
@@ -262,21 +328,21 @@ func (ns *nodeServer) formatAndMountRetry(devicePath, stagingTarget, fsType stri
 	m := ns.Mount
 	err := m.Mounter().FormatAndMount(devicePath, stagingTarget, fsType, options)
 	if err != nil {
-		klog.Infof("Initial format and mount failed: %v. Attempting rescan.", err)
+		klog.InfoS("Initial format and mount failed, attempting rescan", "err", err)
 		// Attempting rescan if the initial mount fails
 		rescanErr := blockdevice.RescanDevice(devicePath)
 		if rescanErr != nil {
-			klog.Infof("Rescan failed: %v. Returning original mount error.", rescanErr)
+			klog.InfoS("Rescan failed, returning original mount error", "err", rescanErr)
 			return err
 		}
-		klog.Infof("Rescan succeeded, retrying format and mount")
+		klog.InfoS("Rescan succeeded, retrying format and mount")
 		err = m.Mounter().FormatAndMount(devicePath, stagingTarget, fsType, options)
 	}
 	return err
 }
 
 func (ns *nodeServer) NodeUnstageVolume(_ context.Context, req *csi.NodeUnstageVolumeRequest) (*csi.NodeUnstageVolumeResponse, error) {
-	klog.V(4).Infof("NodeUnstageVolume: called with args %+v", protosanitizer.StripSecrets(req))
+	klog.V(4).InfoS("NodeUnstageVolume called", "args", protosanitizer.StripSecrets(req))
 
 	volumeID := req.GetVolumeId()
 	if volumeID == "" {
@@ -292,6 +358,8 @@ func (ns *nodeServer) NodeUnstageVolume(_ context.Context, req *csi.NodeUnstageV
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Unmount of targetPath %s failed with error %v", stagingTargetPath, err)
 	}
+	ns.inventory.unstaged(volumeID)
+	ns.remountAttempts.Delete(volumeID)
 
 	return &csi.NodeUnstageVolumeResponse{}, nil
 }
@@ -312,14 +380,11 @@ func (ns *nodeServer) NodeGetInfo(ctx context.Context, _ *csi.NodeGetInfoRequest
 		return nil, status.Errorf(codes.Internal, "[NodeGetInfo] Unable to retrieve availability zone of node %v", err)
 	}
 
-	topoKey := topologyKey
-	if ns.Driver.legacyDriver {
-		topoKey = legacyTopologyKey
-	}
-
-	//TODO: support well-known topology key "topology.kubernetes.io/zone"
-	segments := map[string]string{
-		topoKey: zone,
+	// Publish all topology keys, so that volumes provisioned with the driver key before --topology-key was set can
+	// still be scheduled to the node.
+	segments := map[string]string{}
+	for _, topoKey := range ns.Driver.topologyKeys() {
+		segments[topoKey] = zone
 	}
 
 	nodeInfo.AccessibleTopology = &csi.Topology{Segments: segments}
@@ -330,7 +395,7 @@ func (ns *nodeServer) NodeGetInfo(ctx context.Context, _ *csi.NodeGetInfoRequest
 func (ns *nodeServer) calculateMaxVolumesPerNode() int64 {
 	freePCIeRootPorts, err := mount.CountFreePCIeSlots()
 	if err != nil {
-		klog.Errorf("[NodeGetInfo] unable to retrieve PCIe root ports: %v", err)
+		klog.ErrorS(err, "NodeGetInfo unable to retrieve PCIe root ports")
 		freePCIeRootPorts = 0
 	}
 
@@ -342,7 +407,7 @@ func (ns *nodeServer) calculateMaxVolumesPerNode() int64 {
 
 	mountedCSIVolumes, err := mount.CountLocalCSIVolumes(csiDriverName)
 	if err != nil {
-		klog.Errorf("[NodeGetInfo] unable to retrieve volume count: %v", err)
+		klog.ErrorS(err, "NodeGetInfo unable to retrieve volume count")
 		mountedCSIVolumes = 0
 	}
 
@@ -350,7 +415,7 @@ func (ns *nodeServer) calculateMaxVolumesPerNode() int64 {
 }
 
 func (ns *nodeServer) NodeGetCapabilities(_ context.Context, req *csi.NodeGetCapabilitiesRequest) (*csi.NodeGetCapabilitiesResponse, error) {
-	klog.V(5).Infof("NodeGetCapabilities called with req: %#v", req)
+	klog.V(5).InfoS("NodeGetCapabilities called", "request", req)
 
 	return &csi.NodeGetCapabilitiesResponse{
 		Capabilities: ns.Driver.nscap,
@@ -358,7 +423,7 @@ func (ns *nodeServer) NodeGetCapabilities(_ context.Context, req *csi.NodeGetCap
 }
 
 func (ns *nodeServer) NodeGetVolumeStats(_ context.Context, req *csi.NodeGetVolumeStatsRequest) (*csi.NodeGetVolumeStatsResponse, error) {
-	klog.V(4).Infof("NodeGetVolumeStats: called with args %+v", protosanitizer.StripSecrets(req))
+	klog.V(4).InfoS("NodeGetVolumeStats called", "args", protosanitizer.StripSecrets(req))
 
 	volumeID := req.GetVolumeId()
 	if volumeID == "" {
@@ -398,11 +463,12 @@ func (ns *nodeServer) NodeGetVolumeStats(_ context.Context, req *csi.NodeGetVolu
 			{Total: stats.TotalBytes, Available: stats.AvailableBytes, Used: stats.UsedBytes, Unit: csi.VolumeUsage_BYTES},
 			{Total: stats.TotalInodes, Available: stats.AvailableInodes, Used: stats.UsedInodes, Unit: csi.VolumeUsage_INODES},
 		},
+		VolumeCondition: ns.checkReadOnlyRemount(volumeID, volumePath),
 	}, nil
 }
 
 func (ns *nodeServer) NodeExpandVolume(_ context.Context, req *csi.NodeExpandVolumeRequest) (*csi.NodeExpandVolumeResponse, error) {
-	klog.V(4).Infof("NodeExpandVolume: called with args %+v", protosanitizer.StripSecrets(req))
+	klog.V(4).InfoS("NodeExpandVolume called", "args", protosanitizer.StripSecrets(req))
 
 	volumeID := req.GetVolumeId()
 	if volumeID == "" {
@@ -473,15 +539,15 @@ func getDevicePath(ctx context.Context, volumeID string, m mount.IMount) (string
 	var devicePath string
 	devicePath, err := m.GetDevicePath(volumeID)
 	if err != nil {
-		klog.Warningf("Couldn't get device path from mount: %v", err)
+		klog.InfoS("Couldn't get device path from mount", "err", err)
 	}
 
 	if devicePath == "" {
 		// try to get from metadata service
-		klog.Info("Trying to get device path from metadata service")
+		klog.InfoS("Trying to get device path from metadata service")
 		devicePath, err = metadata.GetDevicePath(ctx, volumeID)
 		if err != nil {
-			klog.Errorf("Couldn't get device path from metadata service: %v", err)
+			klog.ErrorS(err, "Couldn't get device path from metadata service")
 			return "", fmt.Errorf("couldn't get device path from metadata service: %v", err)
 		}
 	}
@@ -489,13 +555,74 @@ func getDevicePath(ctx context.Context, volumeID string, m mount.IMount) (string
 	return devicePath, nil
 }
 
-func collectMountOptions(fsType string, mntFlags []string) []string {
+// verifyDeviceIdentity compares the serial the kernel reports for devicePath with the volume ID.
+// Devices that do not expose a serial cannot be verified and are accepted.
+func verifyDeviceIdentity(devicePath, volumeID string, m mount.IMount) error {
+	serial, err := m.GetDeviceSerial(devicePath)
+	if err != nil {
+		klog.InfoS("Unable to read serial of device, skipping identity verification", "devicePath", devicePath, "volumeID", volumeID, "err", err)
+		return nil
+	}
+	if serial == "" {
+		klog.V(4).InfoS("Device does not report a serial, skipping identity verification", "devicePath", devicePath, "volumeID", volumeID)
+		return nil
+	}
+	if !mount.SerialMatchesVolume(serial, volumeID) {
+		return fmt.Errorf("device %s has serial %q which does not belong to volume %s, refusing to use it", devicePath, serial, volumeID)
+	}
+	return nil
+}
+
+// deniedMountOptions can't be requested through the volume capability, since they either weaken the isolation
+// of the node (suid, dev) or change the semantics of the mount call itself.
+var deniedMountOptions = []string{"suid", "dev", "bind", "rbind", "remount", "move"}
+
+// selinuxMountOptions are the mount options the kubelet passes with a quoted SELinux context when the CSIDriver
+// enables seLinuxMount. The context may contain commas, e.g. context="system_u:object_r:container_file_t:s0:c0,c1".
+var selinuxMountOptions = []string{"context", "fscontext", "defcontext", "rootcontext"}
+
+// isSELinuxMountOption reports whether flag is an SELinux context mount option with a quoted value.
+func isSELinuxMountOption(flag string) bool {
+	key, value, ok := strings.Cut(flag, "=")
+	if !ok || !slices.Contains(selinuxMountOptions, key) || !strings.HasPrefix(value, `"`) {
+		return false
+	}
+	unquoted, err := strconv.Unquote(value)
+	return err == nil && unquoted != "" && !strings.ContainsAny(unquoted, "\" \t\n")
+}
+
+// validateMountOptions rejects mount options that are not safe to pass through from a StorageClass or PV.
+func validateMountOptions(mntFlags []string) error {
+	for _, flag := range mntFlags {
+		if isSELinuxMountOption(flag) {
+			continue
+		}
+		if flag == "" || strings.ContainsAny(flag, ", \t\n") {
+			return fmt.Errorf("mount option %q is malformed", flag)
+		}
+		if slices.Contains(deniedMountOptions, strings.ToLower(flag)) {
+			return fmt.Errorf("mount option %q is not allowed", flag)
+		}
+	}
+	return nil
+}
+
+func collectMountOptions(fsType string, mntFlags []string, discard bool) []string {
 	var options []string
-	options = append(options, mntFlags...)
+	for _, flag := range mntFlags {
+		if !slices.Contains(options, flag) {
+			options = append(options, flag)
+		}
+	}
+
+	// Enable online discard by default if configured, unless the volume explicitly opts out.
+	if discard && !slices.Contains(options, "discard") && !slices.Contains(options, "nodiscard") {
+		options = append(options, "discard")
+	}
 
 	// By default, xfs does not allow mounting of two volumes with the same filesystem uuid.
 	// Force ignore this uuid to be able to mount volume + its clone / restored snapshot on the same node.
-	if fsType == "xfs" {
+	if fsType == "xfs" && !slices.Contains(options, "nouuid") {
 		options = append(options, "nouuid")
 	}
 	return options
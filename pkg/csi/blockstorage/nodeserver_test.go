@@ -2,6 +2,8 @@ package blockstorage
 
 import (
 	"context"
+	"errors"
+	"slices"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	. "github.com/onsi/ginkgo/v2"
@@ -10,6 +12,7 @@ import (
 	"go.uber.org/mock/gomock"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
 	mountutils "k8s.io/mount-utils"
 
 	sharedcsi "github.com/stackitcloud/cloud-provider-stackit/pkg/csi"
@@ -114,6 +117,7 @@ var _ = Describe("NodeServer", func() {
 			mounter := mountutils.NewFakeMounter(mountPoints)
 
 			mountMock.EXPECT().IsLikelyNotMountPointAttach("/target/path").Return(true, nil)
+			mountMock.EXPECT().IsReadOnlyRemounted("/staging/target/path").Return(false, nil)
 			mountMock.EXPECT().Mounter().Return(mountutils.NewSafeFormatAndMount(mounter, nil))
 
 			_, err := ns.NodePublishVolume(context.Background(), req)
@@ -123,6 +127,41 @@ var _ = Describe("NodeServer", func() {
 			Expect(mounter.MountPoints[0].Type).To(Equal("ext4"))
 		})
 
+		It("should bind mount read-only if the controller published the volume read-only", func() {
+			req.PublishContext = map[string]string{publishReadOnlyKey: "true"}
+			mounter := mountutils.NewFakeMounter(nil)
+
+			mountMock.EXPECT().IsLikelyNotMountPointAttach("/target/path").Return(true, nil)
+			mountMock.EXPECT().Mounter().Return(mountutils.NewSafeFormatAndMount(mounter, nil))
+
+			_, err := ns.NodePublishVolume(context.Background(), req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mounter.MountPoints).To(ConsistOf(HaveField("Opts", ContainElement("ro"))))
+		})
+
+		It("should fail if the staged filesystem was remounted read-only", func() {
+			mountMock.EXPECT().IsLikelyNotMountPointAttach("/target/path").Return(true, nil)
+			mountMock.EXPECT().IsReadOnlyRemounted("/staging/target/path").Return(true, nil)
+
+			_, err := ns.NodePublishVolume(context.Background(), req)
+			Expect(status.Code(err)).To(Equal(codes.Internal))
+			Expect(err).To(MatchError(ContainSubstring("remounted read-only")))
+		})
+
+		It("should remount the staged filesystem read-write if enabled", func() {
+			ns.Opts.RemountReadOnly = true
+			mounter := mountutils.NewFakeMounter(nil)
+
+			mountMock.EXPECT().IsLikelyNotMountPointAttach("/target/path").Return(true, nil)
+			mountMock.EXPECT().IsReadOnlyRemounted("/staging/target/path").Return(true, nil)
+			mountMock.EXPECT().RemountReadWrite("/staging/target/path").Return(nil)
+			mountMock.EXPECT().Mounter().Return(mountutils.NewSafeFormatAndMount(mounter, nil))
+
+			_, err := ns.NodePublishVolume(context.Background(), req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mounter.MountPoints).To(HaveLen(1))
+		})
+
 		It("should mount successfully, if a block volume is requests in the volume capabilities", func() {
 			req.VolumeCapability.AccessType = &csi.VolumeCapability_Block{
 				Block: &csi.VolumeCapability_BlockVolume{},
@@ -147,6 +186,7 @@ var _ = Describe("NodeServer", func() {
 			mounter := mountutils.NewFakeMounter(mountPoints)
 
 			mountMock.EXPECT().IsLikelyNotMountPointAttach("/target/path").Return(true, nil)
+			mountMock.EXPECT().IsReadOnlyRemounted("/staging/target/path").Return(false, nil)
 			mountMock.EXPECT().Mounter().Return(mountutils.NewSafeFormatAndMount(mounter, nil))
 
 			_, err := ns.NodePublishVolume(context.Background(), req)
@@ -170,10 +210,244 @@ var _ = Describe("NodeServer", func() {
 	})
 
 	Describe("NodeUnpublishVolume", func() {})
-	Describe("NodeStageVolume", func() {})
+	Describe("NodeStageVolume", func() {
+		const volumeID = "4a1e4c3e-5d2f-4a8b-9c7d-0e1f2a3b4c5d"
+
+		var stageReq *csi.NodeStageVolumeRequest
+
+		BeforeEach(func() {
+			stageReq = &csi.NodeStageVolumeRequest{
+				VolumeId:          volumeID,
+				StagingTargetPath: "/staging/path",
+				VolumeCapability: &csi.VolumeCapability{
+					AccessType: &csi.VolumeCapability_Block{
+						Block: &csi.VolumeCapability_BlockVolume{},
+					},
+				},
+			}
+		})
+
+		It("should reject dangerous mount options", func() {
+			stageReq.VolumeCapability.AccessType = &csi.VolumeCapability_Mount{
+				Mount: &csi.VolumeCapability_MountVolume{
+					MountFlags: []string{"noatime", "suid"},
+				},
+			}
+
+			_, err := ns.NodeStageVolume(context.Background(), stageReq)
+			Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+			Expect(err).To(MatchError(ContainSubstring(`mount option "suid" is not allowed`)))
+		})
+
+		It("should accept a device whose serial matches the volume ID", func() {
+			mountMock.EXPECT().GetDevicePath(volumeID).Return("/dev/vdb", nil)
+			mountMock.EXPECT().GetDeviceSerial("/dev/vdb").Return(volumeID[:20], nil)
+
+			_, err := ns.NodeStageVolume(context.Background(), stageReq)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ns.inventory.list()).To(ConsistOf(And(
+				HaveField("VolumeID", volumeID),
+				HaveField("DevicePath", "/dev/vdb"),
+				HaveField("StagingTargetPath", "/staging/path"),
+			)))
+		})
+
+		It("should accept a device that does not report a serial", func() {
+			mountMock.EXPECT().GetDevicePath(volumeID).Return("/dev/vdb", nil)
+			mountMock.EXPECT().GetDeviceSerial("/dev/vdb").Return("", nil)
+
+			_, err := ns.NodeStageVolume(context.Background(), stageReq)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should refuse a device whose serial belongs to a different volume", func() {
+			mountMock.EXPECT().GetDevicePath(volumeID).Return("/dev/vdb", nil)
+			mountMock.EXPECT().GetDeviceSerial("/dev/vdb").Return("9f8e7d6c-5b4a-4392-8", nil)
+
+			_, err := ns.NodeStageVolume(context.Background(), stageReq)
+			Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+		})
+	})
+	Describe("applyVolumeMountGroup", func() {
+		hasVolumeMountGroup := func(d *Driver) bool {
+			return slices.ContainsFunc(d.nscap, func(c *csi.NodeServiceCapability) bool {
+				return c.GetRpc().GetType() == csi.NodeServiceCapability_RPC_VOLUME_MOUNT_GROUP
+			})
+		}
+
+		It("should only delegate the fsGroup if the policy isn't Kubelet", func() {
+			Expect(hasVolumeMountGroup(ns.Driver)).To(BeFalse())
+			Expect(hasVolumeMountGroup(NewDriver(&DriverOpts{FSGroupPolicy: FSGroupPolicyFile}))).To(BeTrue())
+			Expect(hasVolumeMountGroup(NewDriver(&DriverOpts{FSGroupPolicy: FSGroupPolicyNone}))).To(BeTrue())
+		})
+
+		It("should apply the volume mount group with the File policy", func() {
+			ns.Driver.fsGroupPolicy = FSGroupPolicyFile
+			mountMock.EXPECT().SetVolumeGroup("/staging/path", int64(2000)).Return(nil)
+
+			Expect(ns.applyVolumeMountGroup("volume-id", "/staging/path", "2000")).To(Succeed())
+		})
+
+		It("should ignore the volume mount group with the None policy", func() {
+			ns.Driver.fsGroupPolicy = FSGroupPolicyNone
+
+			Expect(ns.applyVolumeMountGroup("volume-id", "/staging/path", "2000")).To(Succeed())
+		})
+
+		It("should reject invalid group IDs", func() {
+			ns.Driver.fsGroupPolicy = FSGroupPolicyFile
+
+			err := ns.applyVolumeMountGroup("volume-id", "/staging/path", "staff")
+			Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		})
+	})
 	Describe("NodeUnstageVolume", func() {})
-	Describe("NodeGetInfo", func() {})
+	Describe("NodeGetInfo", func() {
+		It("should return the instance ID and zone from the metadata", func() {
+			metadataMock.EXPECT().GetInstanceID(gomock.Any()).Return("server-id", nil)
+			metadataMock.EXPECT().GetAvailabilityZone(gomock.Any()).Return("eu01-1", nil)
+
+			resp, err := ns.NodeGetInfo(context.Background(), &csi.NodeGetInfoRequest{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.GetNodeId()).To(Equal("server-id"))
+			Expect(resp.GetAccessibleTopology().GetSegments()).To(Equal(map[string]string{topologyKey: "eu01-1"}))
+		})
+
+		It("should publish the configured topology key in addition to the driver topology key", func() {
+			ns.Driver.topologyKey = corev1.LabelTopologyZone
+			metadataMock.EXPECT().GetInstanceID(gomock.Any()).Return("server-id", nil)
+			metadataMock.EXPECT().GetAvailabilityZone(gomock.Any()).Return("eu01-1", nil)
+
+			resp, err := ns.NodeGetInfo(context.Background(), &csi.NodeGetInfoRequest{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.GetAccessibleTopology().GetSegments()).To(Equal(map[string]string{
+				corev1.LabelTopologyZone: "eu01-1",
+				topologyKey:              "eu01-1",
+			}))
+		})
+
+		It("should prefer the metadata over the configured values", func() {
+			ns.Metadata = metadata.WithFallback(metadataMock, metadata.Static{InstanceID: "flag-id", AvailabilityZone: "eu01-2"})
+			metadataMock.EXPECT().GetInstanceID(gomock.Any()).Return("server-id", nil)
+			metadataMock.EXPECT().GetAvailabilityZone(gomock.Any()).Return("eu01-1", nil)
+
+			resp, err := ns.NodeGetInfo(context.Background(), &csi.NodeGetInfoRequest{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.GetNodeId()).To(Equal("server-id"))
+			Expect(resp.GetAccessibleTopology().GetSegments()).To(Equal(map[string]string{topologyKey: "eu01-1"}))
+		})
+
+		It("should use the configured values if the metadata is not available", func() {
+			ns.Metadata = metadata.WithFallback(metadataMock, metadata.Static{InstanceID: "flag-id", AvailabilityZone: "eu01-2"})
+			metadataMock.EXPECT().GetInstanceID(gomock.Any()).Return("", errors.New("metadata service not reachable"))
+			metadataMock.EXPECT().GetAvailabilityZone(gomock.Any()).Return("", errors.New("metadata service not reachable"))
+
+			resp, err := ns.NodeGetInfo(context.Background(), &csi.NodeGetInfoRequest{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.GetNodeId()).To(Equal("flag-id"))
+			Expect(resp.GetAccessibleTopology().GetSegments()).To(Equal(map[string]string{topologyKey: "eu01-2"}))
+		})
+
+		It("should fail without metadata and configured values", func() {
+			ns.Metadata = metadata.WithFallback(metadataMock, metadata.Static{AvailabilityZone: "eu01-2"})
+			metadataMock.EXPECT().GetInstanceID(gomock.Any()).Return("", errors.New("metadata service not reachable"))
+
+			_, err := ns.NodeGetInfo(context.Background(), &csi.NodeGetInfoRequest{})
+			Expect(status.Code(err)).To(Equal(codes.Internal))
+		})
+	})
 	Describe("NodeGetCapabilities", func() {})
-	Describe("NodeGetVolumeStats", func() {})
+	Describe("NodeGetVolumeStats", func() {
+		var statsReq *csi.NodeGetVolumeStatsRequest
+
+		BeforeEach(func() {
+			statsReq = &csi.NodeGetVolumeStatsRequest{VolumeId: "volume-id", VolumePath: GinkgoT().TempDir()}
+			mountMock.EXPECT().GetDeviceStats(statsReq.VolumePath).Return(&mount.DeviceStats{TotalBytes: 1000}, nil)
+		})
+
+		It("should report a healthy volume", func() {
+			mountMock.EXPECT().IsReadOnlyRemounted(statsReq.VolumePath).Return(false, nil)
+
+			resp, err := ns.NodeGetVolumeStats(context.Background(), statsReq)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.VolumeCondition.Abnormal).To(BeFalse())
+		})
+
+		It("should report an abnormal volume if the filesystem was remounted read-only", func() {
+			mountMock.EXPECT().IsReadOnlyRemounted(statsReq.VolumePath).Return(true, nil)
+
+			resp, err := ns.NodeGetVolumeStats(context.Background(), statsReq)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.VolumeCondition.Abnormal).To(BeTrue())
+			Expect(resp.VolumeCondition.Message).To(ContainSubstring("remounted read-only"))
+		})
+
+		It("should report an abnormal volume if remounting read-write fails", func() {
+			ns.Opts.RemountReadOnly = true
+			mountMock.EXPECT().IsReadOnlyRemounted(statsReq.VolumePath).Return(true, nil)
+			mountMock.EXPECT().RemountReadWrite(statsReq.VolumePath).Return(errors.New("injected error"))
+
+			resp, err := ns.NodeGetVolumeStats(context.Background(), statsReq)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.VolumeCondition.Abnormal).To(BeTrue())
+			Expect(resp.VolumeCondition.Message).To(ContainSubstring("injected error"))
+		})
+
+		It("should only remount read-write once per staging", func() {
+			ns.Opts.RemountReadOnly = true
+			mountMock.EXPECT().GetDeviceStats(statsReq.VolumePath).Return(&mount.DeviceStats{TotalBytes: 1000}, nil).Times(2)
+			mountMock.EXPECT().IsReadOnlyRemounted(statsReq.VolumePath).Return(true, nil).Times(3)
+			mountMock.EXPECT().RemountReadWrite(statsReq.VolumePath).Return(nil).Times(2)
+			mountMock.EXPECT().UnmountPath("/staging/target/path").Return(nil)
+
+			resp, err := ns.NodeGetVolumeStats(context.Background(), statsReq)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.VolumeCondition.Abnormal).To(BeFalse())
+
+			resp, err = ns.NodeGetVolumeStats(context.Background(), statsReq)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.VolumeCondition.Abnormal).To(BeTrue())
+			Expect(resp.VolumeCondition.Message).To(ContainSubstring("remounted read-only again"))
+
+			_, err = ns.NodeUnstageVolume(context.Background(), &csi.NodeUnstageVolumeRequest{
+				VolumeId: "volume-id", StagingTargetPath: "/staging/target/path",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			resp, err = ns.NodeGetVolumeStats(context.Background(), statsReq)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.VolumeCondition.Abnormal).To(BeFalse())
+		})
+	})
 	Describe("NodeExpandVolume", func() {})
 })
+
+var _ = DescribeTable("collectMountOptions",
+	func(fsType string, mntFlags []string, discard bool, expected []string) {
+		Expect(collectMountOptions(fsType, mntFlags, discard)).To(Equal(expected))
+	},
+	Entry("passes through mount flags", "ext4", []string{"noatime", "discard"}, false, []string{"noatime", "discard"}),
+	Entry("removes duplicate mount flags", "ext4", []string{"noatime", "noatime"}, false, []string{"noatime"}),
+	Entry("adds nouuid for xfs", "xfs", []string{"noatime"}, false, []string{"noatime", "nouuid"}),
+	Entry("adds discard if enabled by default", "ext4", nil, true, []string{"discard"}),
+	Entry("doesn't add discard if the volume opts out", "ext4", []string{"nodiscard"}, true, []string{"nodiscard"}),
+)
+
+var _ = DescribeTable("validateMountOptions",
+	func(mntFlags []string, valid bool) {
+		err := validateMountOptions(mntFlags)
+		if valid {
+			Expect(err).ToNot(HaveOccurred())
+		} else {
+			Expect(err).To(HaveOccurred())
+		}
+	},
+	Entry("common options", []string{"noatime", "discard", "data=ordered"}, true),
+	Entry("suid", []string{"suid"}, false),
+	Entry("dev", []string{"DEV"}, false),
+	Entry("remount", []string{"remount"}, false),
+	Entry("option smuggling another option", []string{"noatime,suid"}, false),
+	Entry("empty option", []string{""}, false),
+	Entry("SELinux context", []string{`context="system_u:object_r:container_file_t:s0:c0,c1"`}, true),
+	Entry("SELinux context without quotes", []string{"context=system_u:object_r:container_file_t:s0:c0,c1"}, false),
+	Entry("SELinux context smuggling another option", []string{`context="s0",suid`}, false),
+)
@@ -0,0 +1,59 @@
+package blockstorage
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+)
+
+const (
+	// manifestAllowedPerformanceClasses is the key of the allowed performance classes of the project in the manifest
+	// returned by GetPluginInfo. The classes of a zone are returned with the key suffixed by "/<zone>".
+	manifestAllowedPerformanceClasses = "allowedPerformanceClasses"
+)
+
+// allowedPerformanceClasses returns the performance classes that new volumes in the zone may use, nil if all classes
+// are allowed.
+func allowedPerformanceClasses(opts stackitconfig.BlockStorageOpts, zone string) []string {
+	if classes, ok := opts.AllowedPerformanceClassesByZone[zone]; ok {
+		return classes
+	}
+	return opts.AllowedPerformanceClasses
+}
+
+// checkPerformanceClass returns an InvalidArgument error if the performance class is not allowed in the zone.
+// If classes are restricted, the performance class must be set, because the default class of the API is unknown.
+func (cs *controllerServer) checkPerformanceClass(class *string, zone string) error {
+	allowed := allowedPerformanceClasses(cs.Opts, zone)
+	if len(allowed) == 0 {
+		return nil
+	}
+	if class == nil {
+		return status.Errorf(codes.InvalidArgument, "parameter type must be set to one of the allowed performance classes %v", allowed)
+	}
+	if !slices.Contains(allowed, *class) {
+		return status.Errorf(codes.InvalidArgument, "performance class %s is not allowed in zone %q, must be one of %v", *class, zone, allowed)
+	}
+	return nil
+}
+
+// performanceClassManifest returns the allowed performance classes as entries of the GetPluginInfo manifest, so
+// that cluster admins can inspect them.
+func performanceClassManifest(opts stackitconfig.BlockStorageOpts) map[string]string {
+	manifest := map[string]string{}
+	if len(opts.AllowedPerformanceClasses) > 0 {
+		manifest[manifestAllowedPerformanceClasses] = strings.Join(opts.AllowedPerformanceClasses, ",")
+	}
+	for _, zone := range slices.Sorted(maps.Keys(opts.AllowedPerformanceClassesByZone)) {
+		manifest[manifestAllowedPerformanceClasses+"/"+zone] = strings.Join(opts.AllowedPerformanceClassesByZone[zone], ",")
+	}
+	if len(manifest) == 0 {
+		return nil
+	}
+	return manifest
+}
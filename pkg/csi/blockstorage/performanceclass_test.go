@@ -0,0 +1,84 @@
+package blockstorage
+
+import (
+	"context"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/csi/util"
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+)
+
+var _ = Describe("Allowed performance classes", func() {
+	var (
+		d          *Driver
+		iaasClient *stackitclientmock.MockIaaSClient
+		opts       = stackitconfig.BlockStorageOpts{
+			AllowedPerformanceClasses:       []string{"storage_premium_perf1", "storage_premium_perf2"},
+			AllowedPerformanceClassesByZone: map[string][]string{"eu01-3": {"storage_premium_perf1"}},
+		}
+	)
+
+	BeforeEach(func() {
+		d = NewDriver(&DriverOpts{Endpoint: "tcp://127.0.0.1:10000", ClusterID: "cluster"})
+		iaasClient = stackitclientmock.NewMockIaaSClient(gomock.NewController(GinkgoT()))
+		d.SetupControllerService(iaasClient, opts)
+	})
+
+	createVolume := func(params map[string]string) error {
+		iaasClient.EXPECT().GetVolumesByName(gomock.Any(), "pv-1").Return(nil, nil)
+		_, err := d.cs.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+			Name: "pv-1",
+			VolumeCapabilities: []*csi.VolumeCapability{{
+				AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+				AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER},
+			}},
+			CapacityRange: &csi.CapacityRange{RequiredBytes: util.GIBIBYTE},
+			Parameters:    params,
+		})
+		return err
+	}
+
+	It("should reject performance classes that are not allowed in the project", func() {
+		err := createVolume(map[string]string{"type": "storage_premium_perf6", "availability": "eu01-1"})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		Expect(err).To(MatchError(ContainSubstring("performance class storage_premium_perf6 is not allowed")))
+	})
+
+	It("should reject performance classes that are not allowed in the zone", func() {
+		err := createVolume(map[string]string{"type": "storage_premium_perf2", "availability": "eu01-3"})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+
+	It("should require the performance class if classes are restricted", func() {
+		err := createVolume(map[string]string{"availability": "eu01-1"})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		Expect(err).To(MatchError(ContainSubstring("parameter type must be set")))
+	})
+
+	It("should create volumes with allowed performance classes", func() {
+		iaasClient.EXPECT().CreateVolume(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ context.Context, payload iaas.CreateVolumePayload) (*iaas.Volume, error) {
+				Expect(*payload.PerformanceClass).To(Equal("storage_premium_perf1"))
+				return &iaas.Volume{Id: new("volume-id"), AvailabilityZone: "eu01-3", Size: new(int64(1))}, nil
+			})
+		iaasClient.EXPECT().WaitVolumeTargetStatusWithCustomBackoff(gomock.Any(), "volume-id", gomock.Any(), gomock.Any()).Return(nil)
+		Expect(createVolume(map[string]string{"type": "storage_premium_perf1", "availability": "eu01-3"})).To(Succeed())
+	})
+
+	It("should return the allowed performance classes in the manifest of the plugin info", func() {
+		res, err := d.ids.GetPluginInfo(context.Background(), &csi.GetPluginInfoRequest{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.GetManifest()).To(Equal(map[string]string{
+			"allowedPerformanceClasses":        "storage_premium_perf1,storage_premium_perf2",
+			"allowedPerformanceClasses/eu01-3": "storage_premium_perf1",
+		}))
+	})
+})
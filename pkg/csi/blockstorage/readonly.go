@@ -0,0 +1,47 @@
+package blockstorage
+
+import (
+	"fmt"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"k8s.io/klog/v2"
+)
+
+// checkReadOnlyRemount detects filesystems the kernel remounted read-only after I/O errors, which otherwise leaves
+// the application unable to write without any visible error on the volume. If remountReadOnly is enabled, the
+// filesystem is remounted read-write once per staging, so that a filesystem that keeps failing is reported as abnormal
+// instead of being remounted over and over. It returns the condition of the volume mounted at path.
+func (ns *nodeServer) checkReadOnlyRemount(volumeID, path string) *csi.VolumeCondition {
+	remounted, err := ns.Mount.IsReadOnlyRemounted(path)
+	if err != nil {
+		klog.V(4).InfoS("Unable to check whether the filesystem was remounted read-only", "volumeID", volumeID, "path", path, "err", err)
+		return &csi.VolumeCondition{Message: "volume is healthy"}
+	}
+	if !remounted {
+		return &csi.VolumeCondition{Message: "volume is healthy"}
+	}
+
+	klog.InfoS("Filesystem was remounted read-only, probably after I/O errors", "volumeID", volumeID, "path", path)
+	if !ns.Opts.RemountReadOnly {
+		return &csi.VolumeCondition{
+			Abnormal: true,
+			Message:  "filesystem was remounted read-only, probably after I/O errors",
+		}
+	}
+
+	if _, attempted := ns.remountAttempts.LoadOrStore(volumeID, struct{}{}); attempted {
+		return &csi.VolumeCondition{
+			Abnormal: true,
+			Message:  "filesystem was remounted read-only again after it was remounted read-write, it must be checked manually",
+		}
+	}
+	if err := ns.Mount.RemountReadWrite(path); err != nil {
+		klog.ErrorS(err, "Failed to remount filesystem read-write", "volumeID", volumeID, "path", path)
+		return &csi.VolumeCondition{
+			Abnormal: true,
+			Message:  fmt.Sprintf("filesystem was remounted read-only, probably after I/O errors, and remounting it read-write failed: %v", err),
+		}
+	}
+	klog.InfoS("Remounted filesystem read-write", "volumeID", volumeID, "path", path)
+	return &csi.VolumeCondition{Message: "filesystem was remounted read-write after it was remounted read-only"}
+}
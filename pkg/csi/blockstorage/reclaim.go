@@ -0,0 +1,108 @@
+package blockstorage
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
+)
+
+// reclaimCheckInterval is the interval in which volumes pending deletion are checked for an expired grace period.
+const reclaimCheckInterval = 10 * time.Minute
+
+// volumeReclaimer defers the deletion of volumes by a grace period, so that the data of accidentally deleted PVCs can
+// be recovered. DeleteVolume labels the volume with the time after which it is deleted, see
+// stackitclient.PendingDeleteLabel, and reconcile deletes it once this time has passed.
+type volumeReclaimer struct {
+	instance    stackitclient.IaaSClient
+	gracePeriod time.Duration
+	now         func() time.Time
+}
+
+func newVolumeReclaimer(instance stackitclient.IaaSClient, gracePeriod time.Duration) *volumeReclaimer {
+	return &volumeReclaimer{
+		instance:    instance,
+		gracePeriod: gracePeriod,
+		now:         time.Now,
+	}
+}
+
+// markPendingDelete labels the volume for deletion after the grace period instead of deleting it. Volumes that are
+// already pending deletion keep their deadline.
+func (r *volumeReclaimer) markPendingDelete(ctx context.Context, volumeID string) error {
+	volume, err := r.instance.GetVolume(ctx, volumeID)
+	if err != nil {
+		if stackiterrors.IsNotFound(err) {
+			klog.V(3).InfoS("Volume is already deleted", "volumeID", volumeID)
+			return nil
+		}
+		return status.Errorf(codes.Internal, "DeleteVolume failed to get volume: %v", err)
+	}
+	if _, pending := volume.Labels[stackitclient.PendingDeleteLabel]; pending {
+		return nil
+	}
+	// The API would reject deleting an attached volume as well.
+	if volume.GetServerId() != "" {
+		return status.Errorf(codes.FailedPrecondition, "DeleteVolume volume %s is still attached to server %s", volumeID, volume.GetServerId())
+	}
+
+	deleteAfter := r.now().Add(r.gracePeriod)
+	// The API merges the labels of the update into the labels of the volume.
+	payload := iaas.UpdateVolumePayload{Labels: map[string]any{
+		stackitclient.PendingDeleteLabel: strconv.FormatInt(deleteAfter.Unix(), 10),
+	}}
+	if _, err := r.instance.UpdateVolume(ctx, volumeID, payload); err != nil {
+		return status.Errorf(codes.Internal, "DeleteVolume failed to label volume as pending deletion: %v", err)
+	}
+	klog.InfoS("Deferred deletion of volume", "volumeID", volumeID, "name", volume.GetName(), "deleteAfter", deleteAfter)
+	return nil
+}
+
+// reconcile deletes the volumes whose grace period is over.
+func (r *volumeReclaimer) reconcile(ctx context.Context) {
+	volumes, _, err := r.instance.ListVolumes(ctx, 0, "")
+	if err != nil {
+		klog.ErrorS(err, "Failed to list volumes pending deletion")
+		return
+	}
+	for i := range volumes {
+		volume := &volumes[i]
+		deleteAfter, pending := pendingDeleteAfter(volume)
+		if !pending || r.now().Before(deleteAfter) {
+			continue
+		}
+		// Volumes that were attached again, e.g. after being adopted, are not deleted.
+		if volume.GetServerId() != "" {
+			klog.V(2).InfoS("Not deleting attached volume pending deletion", "volumeID", volume.GetId(), "serverID", volume.GetServerId())
+			continue
+		}
+		if err := r.instance.DeleteVolume(ctx, volume.GetId()); err != nil && !stackiterrors.IsNotFound(err) {
+			klog.ErrorS(err, "Failed to delete volume after its reclaim grace period", "volumeID", volume.GetId())
+			continue
+		}
+		klog.InfoS("Deleted volume after its reclaim grace period", "volumeID", volume.GetId(), "name", volume.GetName())
+	}
+}
+
+// pendingDeleteAfter returns the time after which the volume is deleted and whether it is pending deletion at all.
+// Volumes with an invalid label are never deleted, because losing data is worse than keeping a volume too long.
+func pendingDeleteAfter(volume *iaas.Volume) (time.Time, bool) {
+	value, pending := volume.Labels[stackitclient.PendingDeleteLabel]
+	if !pending {
+		return time.Time{}, false
+	}
+	str, _ := value.(string)
+	seconds, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		klog.V(2).InfoS("Ignoring volume with invalid pending deletion label", "volumeID", volume.GetId(), "value", value)
+		return time.Time{}, false
+	}
+	return time.Unix(seconds, 0), true
+}
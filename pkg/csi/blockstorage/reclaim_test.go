@@ -0,0 +1,152 @@
+package blockstorage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stackitcloud/stackit-sdk-go/core/oapierror"
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	sharedcsi "github.com/stackitcloud/cloud-provider-stackit/pkg/csi"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/csi/util"
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/metadata"
+)
+
+var _ = Describe("Reclaim grace period", func() {
+	var (
+		iaasClient *stackitclientmock.MockIaaSClient
+		cs         *controllerServer
+		now        time.Time
+	)
+
+	BeforeEach(func() {
+		iaasClient = stackitclientmock.NewMockIaaSClient(gomock.NewController(GinkgoT()))
+		d := NewDriver(&DriverOpts{
+			Endpoint:  "tcp://127.0.0.1:10000",
+			ClusterID: "cluster",
+			PVLister:  corelisters.NewPersistentVolumeLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+		})
+		cs = NewControllerServer(d, iaasClient, stackitconfig.BlockStorageOpts{
+			ReclaimGracePeriod: metadata.Duration{Duration: 72 * time.Hour},
+		})
+		now = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		cs.reclaimer.now = func() time.Time { return now }
+	})
+
+	pendingLabel := func(t time.Time) map[string]any {
+		return map[string]any{stackitclient.PendingDeleteLabel: strconv.FormatInt(t.Unix(), 10)}
+	}
+
+	Describe("DeleteVolume", func() {
+		It("should label the volume instead of deleting it", func() {
+			iaasClient.EXPECT().GetVolume(gomock.Any(), "volume-id").Return(&iaas.Volume{Id: new("volume-id")}, nil)
+			iaasClient.EXPECT().UpdateVolume(gomock.Any(), "volume-id", iaas.UpdateVolumePayload{
+				Labels: pendingLabel(now.Add(72 * time.Hour)),
+			}).Return(&iaas.Volume{}, nil)
+
+			_, err := cs.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: "volume-id"})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should keep the deadline of volumes that are already pending deletion", func() {
+			iaasClient.EXPECT().GetVolume(gomock.Any(), "volume-id").Return(&iaas.Volume{
+				Id: new("volume-id"), Labels: pendingLabel(now),
+			}, nil)
+
+			_, err := cs.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: "volume-id"})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should succeed if the volume doesn't exist", func() {
+			iaasClient.EXPECT().GetVolume(gomock.Any(), "volume-id").Return(nil, &oapierror.GenericOpenAPIError{StatusCode: http.StatusNotFound})
+
+			_, err := cs.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: "volume-id"})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should fail for attached volumes", func() {
+			iaasClient.EXPECT().GetVolume(gomock.Any(), "volume-id").Return(&iaas.Volume{
+				Id: new("volume-id"), ServerId: new("server-id"),
+			}, nil)
+
+			_, err := cs.DeleteVolume(context.Background(), &csi.DeleteVolumeRequest{VolumeId: "volume-id"})
+			Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+		})
+	})
+
+	Describe("reconcile", func() {
+		It("should only delete unattached volumes whose grace period is over", func() {
+			iaasClient.EXPECT().ListVolumes(gomock.Any(), gomock.Any(), gomock.Any()).Return([]iaas.Volume{
+				{Id: new("expired"), Labels: pendingLabel(now.Add(-time.Minute))},
+				{Id: new("expired-not-found"), Labels: pendingLabel(now.Add(-time.Hour))},
+				{Id: new("pending"), Labels: pendingLabel(now.Add(time.Minute))},
+				{Id: new("attached"), ServerId: new("server-id"), Labels: pendingLabel(now.Add(-time.Minute))},
+				{Id: new("invalid"), Labels: map[string]any{stackitclient.PendingDeleteLabel: "tomorrow"}},
+				{Id: new("in-use")},
+			}, "", nil)
+			iaasClient.EXPECT().DeleteVolume(gomock.Any(), "expired").Return(nil)
+			iaasClient.EXPECT().DeleteVolume(gomock.Any(), "expired-not-found").Return(&oapierror.GenericOpenAPIError{StatusCode: http.StatusNotFound})
+
+			cs.reclaimer.reconcile(context.Background())
+		})
+
+		It("should continue after a failed deletion", func() {
+			iaasClient.EXPECT().ListVolumes(gomock.Any(), gomock.Any(), gomock.Any()).Return([]iaas.Volume{
+				{Id: new("first"), Labels: pendingLabel(now)},
+				{Id: new("second"), Labels: pendingLabel(now)},
+			}, "", nil)
+			iaasClient.EXPECT().DeleteVolume(gomock.Any(), "first").Return(errors.New("injected error"))
+			iaasClient.EXPECT().DeleteVolume(gomock.Any(), "second").Return(nil)
+
+			cs.reclaimer.reconcile(context.Background())
+		})
+	})
+
+	It("should recover a volume pending deletion by adopting it", func() {
+		iaasClient.EXPECT().GetVolumesByName(gomock.Any(), "pvc-new").Return([]iaas.Volume{}, nil)
+		iaasClient.EXPECT().ListVolumes(gomock.Any(), gomock.Any(), gomock.Any()).Return([]iaas.Volume{{
+			Id:               new("volume-id"),
+			Status:           new(stackitclient.VolumeAvailableStatus),
+			Size:             new(int64(10)),
+			AvailabilityZone: "eu01",
+			Labels: map[string]any{
+				pvcNamespaceLabel:                "default",
+				pvcNameLabel:                     "data",
+				stackitclient.PendingDeleteLabel: "1767225600",
+			},
+		}}, "", nil)
+		iaasClient.EXPECT().UpdateVolume(gomock.Any(), "volume-id", iaas.UpdateVolumePayload{
+			Name:   new("pvc-new"),
+			Labels: map[string]any{stackitclient.PendingDeleteLabel: nil},
+		}).Return(&iaas.Volume{}, nil)
+
+		_, err := cs.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+			Name:          "pvc-new",
+			CapacityRange: &csi.CapacityRange{RequiredBytes: 10 * util.GIBIBYTE},
+			VolumeCapabilities: []*csi.VolumeCapability{{
+				AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+				AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER},
+			}},
+			Parameters: map[string]string{
+				"adoptExisting":           "true",
+				sharedcsi.PvcNamespaceKey: "default",
+				sharedcsi.PvcNameKey:      "data",
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
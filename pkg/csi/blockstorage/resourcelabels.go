@@ -0,0 +1,58 @@
+package blockstorage
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	"k8s.io/klog/v2"
+
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+)
+
+// uidLength is the length of the UID of a Kubernetes object.
+const uidLength = 36
+
+// pvcUIDFromPVName returns the UID of the PVC a volume was provisioned for. The csi-provisioner names PVs
+// <prefix>-<UID of the PVC>, so it is derived from the name of the PV. It returns "" for other names.
+func pvcUIDFromPVName(pvName string) string {
+	if len(pvName) <= uidLength || pvName[len(pvName)-uidLength-1] != '-' {
+		return ""
+	}
+	uid := pvName[len(pvName)-uidLength:]
+	if uuid.Validate(uid) != nil {
+		return ""
+	}
+	return uid
+}
+
+// volumeResourceLabels returns the resource labels of a volume or of a backup of it, nil if resource labels are
+// disabled. pvName is the name of the PV, which is also the name of the volume.
+func (d *Driver) volumeResourceLabels(pvName string) map[string]string {
+	if d.resourceLabels == nil {
+		return nil
+	}
+	labels := stackitclient.WithResourceLabels(nil, d.resourceLabels)
+	if uid := pvcUIDFromPVName(pvName); uid != "" {
+		labels[stackitclient.PVCUIDLabel] = uid
+	}
+	return labels
+}
+
+// addMissingResourceLabels adds the resource labels to a volume created before resource labels were enabled. Errors are
+// only logged, the labels are added again on the next expansion or modification of the volume.
+func (cs *controllerServer) addMissingResourceLabels(ctx context.Context, volume *iaas.Volume) {
+	desired := map[string]*string{}
+	for key, value := range cs.Driver.volumeResourceLabels(volume.GetName()) {
+		desired[key] = new(value)
+	}
+	changes := labelChanges(volume.Labels, desired)
+	if len(changes) == 0 {
+		return
+	}
+	if _, err := cs.Instance.UpdateVolume(ctx, volume.GetId(), iaas.UpdateVolumePayload{Labels: changes}); err != nil {
+		klog.ErrorS(err, "Failed to add resource labels to volume", "volumeID", volume.GetId())
+		return
+	}
+	klog.V(2).InfoS("Added resource labels to volume", "volumeID", volume.GetId(), "changes", describeLabelChanges(changes))
+}
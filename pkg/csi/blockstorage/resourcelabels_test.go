@@ -0,0 +1,127 @@
+package blockstorage
+
+import (
+	"context"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	"go.uber.org/mock/gomock"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/csi/util"
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+)
+
+var _ = Describe("Resource labels", func() {
+	const (
+		pvName = "pvc-0d6f3c1e-9a8b-4c7d-8e6f-5a4b3c2d1e0f"
+		pvcUID = "0d6f3c1e-9a8b-4c7d-8e6f-5a4b3c2d1e0f"
+	)
+
+	var (
+		iaasClient *stackitclientmock.MockIaaSClient
+		cs         *controllerServer
+	)
+
+	BeforeEach(func() {
+		iaasClient = stackitclientmock.NewMockIaaSClient(gomock.NewController(GinkgoT()))
+		driver := NewDriver(&DriverOpts{ResourceLabels: map[string]string{
+			stackitclient.ClusterIDLabel: "my-cluster",
+			stackitclient.ManagedByLabel: "stackit-csi-plugin",
+		}})
+		cs = NewControllerServer(driver, iaasClient, stackitconfig.BlockStorageOpts{})
+	})
+
+	DescribeTable("pvcUIDFromPVName",
+		func(pvName, expected string) {
+			Expect(pvcUIDFromPVName(pvName)).To(Equal(expected))
+		},
+		Entry("default prefix", pvName, pvcUID),
+		Entry("custom prefix", "data-"+pvcUID, pvcUID),
+		Entry("no prefix", pvcUID, ""),
+		Entry("no UID", "pvc-0d6f3c1e-9a8b-4c7d-8e6f-5a4b3c2d1e0z", ""),
+		Entry("short name", "volume", ""),
+	)
+
+	It("should label new volumes", func() {
+		iaasClient.EXPECT().GetVolumesByName(gomock.Any(), pvName).Return([]iaas.Volume{}, nil)
+		iaasClient.EXPECT().CreateVolume(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, payload iaas.CreateVolumePayload) (*iaas.Volume, error) {
+			Expect(payload.Labels).To(Equal(map[string]any{
+				stackitclient.ClusterIDLabel: "my-cluster",
+				stackitclient.ManagedByLabel: "stackit-csi-plugin",
+				stackitclient.PVCUIDLabel:    pvcUID,
+			}))
+			return &iaas.Volume{Id: new("volume-id"), AvailabilityZone: "eu01", Size: new(int64(20))}, nil
+		})
+		iaasClient.EXPECT().WaitVolumeTargetStatusWithCustomBackoff(gomock.Any(), "volume-id", gomock.Any(), gomock.Any()).Return(nil)
+
+		_, err := cs.CreateVolume(context.Background(), &csi.CreateVolumeRequest{
+			Name: pvName,
+			VolumeCapabilities: []*csi.VolumeCapability{{
+				AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+				AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER},
+			}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should add missing labels when modifying volumes", func() {
+		iaasClient.EXPECT().GetVolume(gomock.Any(), "volume-id").Return(&iaas.Volume{
+			Id:     new("volume-id"),
+			Name:   new(pvName),
+			Labels: map[string]any{stackitclient.ManagedByLabel: "stackit-csi-plugin"},
+		}, nil)
+		iaasClient.EXPECT().UpdateVolume(gomock.Any(), "volume-id", iaas.UpdateVolumePayload{Labels: map[string]any{
+			stackitclient.ClusterIDLabel: "my-cluster",
+			stackitclient.PVCUIDLabel:    pvcUID,
+			"team":                       "a",
+		}}).Return(&iaas.Volume{}, nil)
+
+		_, err := cs.ControllerModifyVolume(context.Background(), &csi.ControllerModifyVolumeRequest{
+			VolumeId:          "volume-id",
+			MutableParameters: map[string]string{mutableParameterLabels: "team=a"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should add missing labels when expanding volumes", func() {
+		iaasClient.EXPECT().GetVolume(gomock.Any(), "volume-id").Return(&iaas.Volume{
+			Id:     new("volume-id"),
+			Name:   new(pvName),
+			Size:   new(int64(20)),
+			Labels: map[string]any{stackitclient.ManagedByLabel: "stackit-csi-plugin"},
+		}, nil)
+		iaasClient.EXPECT().UpdateVolume(gomock.Any(), "volume-id", iaas.UpdateVolumePayload{Labels: map[string]any{
+			stackitclient.ClusterIDLabel: "my-cluster",
+			stackitclient.PVCUIDLabel:    pvcUID,
+		}}).Return(&iaas.Volume{}, nil)
+
+		_, err := cs.ControllerExpandVolume(context.Background(), &csi.ControllerExpandVolumeRequest{
+			VolumeId:      "volume-id",
+			CapacityRange: &csi.CapacityRange{RequiredBytes: util.GIBIBYTE * 10},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should not allow modifying resource labels", func() {
+		_, err := parseMutableParameters(map[string]string{mutableParameterLabels: stackitclient.ClusterIDLabel + "=other"})
+		Expect(err).To(MatchError(ContainSubstring("managed by the driver")))
+	})
+
+	It("should label snapshots", func() {
+		iaasClient.EXPECT().GetVolume(gomock.Any(), "volume-id").Return(&iaas.Volume{Status: new(stackitclient.VolumeAvailableStatus)}, nil)
+		iaasClient.EXPECT().CreateSnapshot(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, payload iaas.CreateSnapshotPayload) (*iaas.Snapshot, error) {
+			Expect(payload.Labels).To(Equal(map[string]any{
+				stackitclient.ClusterIDLabel: "my-cluster",
+				stackitclient.ManagedByLabel: "stackit-csi-plugin",
+			}))
+			return &iaas.Snapshot{Id: new("snapshot-id")}, nil
+		})
+
+		_, err := cs.createSnapshot(context.Background(), "snapshot", "volume-id", map[string]string{})
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
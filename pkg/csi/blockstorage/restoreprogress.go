@@ -0,0 +1,163 @@
+package blockstorage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/metrics"
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
+)
+
+const (
+	EventReasonRestoreStarted   = "RestoreStarted"
+	EventReasonRestoreProgress  = "RestoreProgress"
+	EventReasonRestoreCompleted = "RestoreCompleted"
+	EventReasonRestoreFailed    = "RestoreFailed"
+)
+
+var (
+	// restoreProgressPollInterval is the interval in which the status of a volume restored from a backup is polled.
+	restoreProgressPollInterval = 30 * time.Second
+	// restoreProgressEventInterval is the interval in which a progress event is recorded if the status didn't change.
+	restoreProgressEventInterval = 5 * time.Minute
+)
+
+// restoreProgresses reports the progress of restoring volumes from backups as events on their PVCs.
+// Restores of large backups outlive the CreateVolume call waiting for them, so a restore is tracked until the volume
+// is available or failed, independently of the call that started it.
+type restoreProgresses struct {
+	driver   *Driver
+	instance stackitclient.IaaSClient
+
+	mu       sync.Mutex
+	restores map[string]*restoreProgress
+}
+
+// restoreProgress is the restore of a single volume. The IaaS API doesn't expose a percentage, so the status of the
+// volume and the elapsed time are reported.
+type restoreProgress struct {
+	volume   *iaas.Volume
+	backupID string
+	started  time.Time
+	// done stops polling once the restore is completed.
+	done chan struct{}
+}
+
+func newRestoreProgresses(d *Driver, instance stackitclient.IaaSClient) *restoreProgresses {
+	return &restoreProgresses{
+		driver:   d,
+		instance: instance,
+		restores: map[string]*restoreProgress{},
+	}
+}
+
+// track polls the status of the volume restored from the backup until it is available or failed, unless the restore
+// is already tracked. Resumed restores, e.g. of volumes found by a retried CreateVolume call after the controller
+// restarted, are tracked without recording that they started.
+func (r *restoreProgresses) track(volume *iaas.Volume, backupID string, resumed bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.restores[volume.GetId()]; ok {
+		return
+	}
+
+	p := &restoreProgress{
+		volume:   volume,
+		backupID: backupID,
+		started:  time.Now(),
+		done:     make(chan struct{}),
+	}
+	if resumed && !volume.GetCreatedAt().IsZero() {
+		p.started = volume.GetCreatedAt()
+	}
+	r.restores[volume.GetId()] = p
+	metrics.CSIBackupRestoresInFlight.Inc()
+	if resumed {
+		klog.V(4).InfoS("Resuming to track restore", "volumeID", volume.GetId(), "backupID", backupID)
+	} else {
+		r.driver.pvcEventf(volume, corev1.EventTypeNormal, EventReasonRestoreStarted,
+			"Restoring volume %s of %d GiB from backup %s", volume.GetId(), volume.GetSize(), backupID)
+	}
+
+	go r.poll(p)
+}
+
+// completed records that the volume is available, if its restore is tracked.
+func (r *restoreProgresses) completed(volumeID string) {
+	if p := r.finish(volumeID); p != nil {
+		close(p.done)
+		r.driver.pvcEventf(p.volume, corev1.EventTypeNormal, EventReasonRestoreCompleted,
+			"Restored volume %s from backup %s in %s", volumeID, p.backupID, p.elapsed())
+	}
+}
+
+// failed records that the volume failed, if its restore is tracked.
+func (r *restoreProgresses) failed(volumeID, reason string) {
+	if p := r.finish(volumeID); p != nil {
+		r.driver.pvcEventf(p.volume, corev1.EventTypeWarning, EventReasonRestoreFailed,
+			"Restoring volume %s from backup %s failed after %s: %s", volumeID, p.backupID, p.elapsed(), reason)
+	}
+}
+
+// finish stops tracking the restore of the volume and returns it, or nil if it isn't tracked.
+func (r *restoreProgresses) finish(volumeID string) *restoreProgress {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.restores[volumeID]
+	if !ok {
+		return nil
+	}
+	delete(r.restores, volumeID)
+	metrics.CSIBackupRestoresInFlight.Dec()
+	return p
+}
+
+func (r *restoreProgresses) poll(p *restoreProgress) {
+	ticker := time.NewTicker(restoreProgressPollInterval)
+	defer ticker.Stop()
+
+	volumeID := p.volume.GetId()
+	lastStatus := p.volume.GetStatus()
+	lastEvent := time.Now()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+
+		// The request timeout of the client bounds the call.
+		volume, err := r.instance.GetVolume(context.Background(), volumeID)
+		switch {
+		case stackiterrors.IsNotFound(err):
+			r.failed(volumeID, "the volume was deleted")
+			return
+		case err != nil:
+			klog.V(4).InfoS("Failed to get status of restored volume", "volumeID", volumeID, "err", err)
+			continue
+		case volume.GetStatus() == stackitclient.VolumeAvailableStatus || volume.GetStatus() == stackitclient.VolumeAttachedStatus:
+			r.completed(volumeID)
+			return
+		case stackitclient.IsVolumeErrorStatus(volume.GetStatus()):
+			r.failed(volumeID, "the volume is in error state "+volume.GetStatus())
+			return
+		}
+
+		if volume.GetStatus() == lastStatus && time.Since(lastEvent) < restoreProgressEventInterval {
+			continue
+		}
+		lastStatus, lastEvent = volume.GetStatus(), time.Now()
+		r.driver.pvcEventf(p.volume, corev1.EventTypeNormal, EventReasonRestoreProgress,
+			"Restoring volume %s from backup %s: status %s after %s", volumeID, p.backupID, lastStatus, p.elapsed())
+	}
+}
+
+func (p *restoreProgress) elapsed() time.Duration {
+	return time.Since(p.started).Round(time.Second)
+}
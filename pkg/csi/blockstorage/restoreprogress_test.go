@@ -0,0 +1,93 @@
+package blockstorage
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	"go.uber.org/mock/gomock"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/metrics"
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+)
+
+var _ = Describe("restoreProgress", func() {
+	var (
+		iaasClient *stackitclientmock.MockIaaSClient
+		recorder   *record.FakeRecorder
+		cs         *controllerServer
+		volume     *iaas.Volume
+	)
+
+	BeforeEach(func() {
+		iaasClient = stackitclientmock.NewMockIaaSClient(gomock.NewController(GinkgoT()))
+		recorder = record.NewFakeRecorder(10)
+		cs = NewControllerServer(NewDriver(&DriverOpts{EventRecorder: recorder}), iaasClient, stackitconfig.BlockStorageOpts{})
+		volume = &iaas.Volume{
+			Id:     new("volume-id"),
+			Size:   new(int64(100)),
+			Status: new("CREATING"),
+			Labels: map[string]any{pvcNamespaceLabel: "default", pvcNameLabel: "data"},
+		}
+
+		DeferCleanup(func(poll, event time.Duration) {
+			restoreProgressPollInterval, restoreProgressEventInterval = poll, event
+		}, restoreProgressPollInterval, restoreProgressEventInterval)
+		restoreProgressPollInterval = 10 * time.Millisecond
+		restoreProgressEventInterval = time.Hour
+	})
+
+	It("should record status changes and the completion", func() {
+		inFlight := testutil.ToFloat64(metrics.CSIBackupRestoresInFlight)
+		gomock.InOrder(
+			iaasClient.EXPECT().GetVolume(gomock.Any(), "volume-id").Return(&iaas.Volume{Status: new("CREATING")}, nil),
+			iaasClient.EXPECT().GetVolume(gomock.Any(), "volume-id").Return(&iaas.Volume{Status: new("RESTORING-BACKUP")}, nil).Times(5),
+			iaasClient.EXPECT().GetVolume(gomock.Any(), "volume-id").Return(&iaas.Volume{Status: new("AVAILABLE")}, nil),
+		)
+
+		cs.restoreProgress.track(volume, "backup-id", false)
+		Expect(recorder.Events).To(Receive(Equal("Normal RestoreStarted Restoring volume volume-id of 100 GiB from backup backup-id")))
+		Expect(testutil.ToFloat64(metrics.CSIBackupRestoresInFlight)).To(Equal(inFlight + 1))
+
+		Eventually(recorder.Events).Should(Receive(HavePrefix("Normal RestoreProgress Restoring volume volume-id from backup backup-id: status RESTORING-BACKUP after ")))
+		Eventually(recorder.Events).Should(Receive(HavePrefix("Normal RestoreCompleted Restored volume volume-id from backup backup-id in ")))
+		Expect(testutil.ToFloat64(metrics.CSIBackupRestoresInFlight)).To(Equal(inFlight))
+		Consistently(recorder.Events, 50*time.Millisecond).ShouldNot(Receive())
+	})
+
+	It("should keep tracking if the status can't be retrieved and record a failure only in an error state", func() {
+		inFlight := testutil.ToFloat64(metrics.CSIBackupRestoresInFlight)
+		gomock.InOrder(
+			iaasClient.EXPECT().GetVolume(gomock.Any(), "volume-id").Return(nil, errors.New("injected error")).Times(3),
+			iaasClient.EXPECT().GetVolume(gomock.Any(), "volume-id").Return(&iaas.Volume{Status: new("ERROR_RESTORING-BACKUP")}, nil),
+		)
+
+		cs.restoreProgress.track(volume, "backup-id", false)
+		Expect(recorder.Events).To(Receive(HavePrefix("Normal RestoreStarted")))
+
+		Eventually(recorder.Events).Should(Receive(MatchRegexp(
+			`^Warning RestoreFailed Restoring volume volume-id from backup backup-id failed after \d+s: the volume is in error state ERROR_RESTORING-BACKUP$`)))
+		Expect(testutil.ToFloat64(metrics.CSIBackupRestoresInFlight)).To(Equal(inFlight))
+	})
+
+	It("should resume tracking a restore once without recording that it started", func() {
+		iaasClient.EXPECT().GetVolume(gomock.Any(), "volume-id").Return(&iaas.Volume{Status: new("RESTORING-BACKUP")}, nil).AnyTimes()
+		volume.Status = new("RESTORING-BACKUP")
+		volume.CreatedAt = new(time.Now().Add(-time.Hour))
+
+		cs.restoreProgress.track(volume, "backup-id", true)
+		cs.restoreProgress.track(volume, "backup-id", true)
+		Consistently(recorder.Events, 50*time.Millisecond).ShouldNot(Receive())
+
+		cs.restoreProgress.completed("volume-id")
+		Expect(recorder.Events).To(Receive(MatchRegexp(
+			`^Normal RestoreCompleted Restored volume volume-id from backup backup-id in 1h0m\d+s$`)))
+		cs.restoreProgress.completed("volume-id")
+		Consistently(recorder.Events, 50*time.Millisecond).ShouldNot(Receive())
+	})
+})
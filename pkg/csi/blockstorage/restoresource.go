@@ -0,0 +1,34 @@
+package blockstorage
+
+import (
+	"slices"
+
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+)
+
+// restoresOf returns the sorted names of the volumes that are restored from the snapshot or backup sourceID.
+// vols are the volumes of the project.
+//
+// Until the volume is created, the restore is only known from cs.restores. Afterwards, the status of the volume is
+// used, so that restores that outlive the CreateVolume call or a restart of the controller are found as well.
+func (cs *controllerServer) restoresOf(sourceID string, vols []iaas.Volume) []string {
+	volumes := cs.restores.Volumes(sourceID)
+	for i := range vols {
+		if vols[i].Source != nil && vols[i].Source.GetId() == sourceID && volumeRestoring(&vols[i]) {
+			volumes = append(volumes, vols[i].GetName())
+		}
+	}
+	slices.Sort(volumes)
+	return slices.Compact(volumes)
+}
+
+// volumeRestoring returns whether the data of the volume is still copied from its source.
+func volumeRestoring(vol *iaas.Volume) bool {
+	switch vol.GetStatus() {
+	case stackitclient.VolumeCreatingStatus, stackitclient.VolumeRestoringStatus:
+		return true
+	}
+	return false
+}
@@ -170,6 +170,7 @@ var _ = Describe("CSI sanity test", Ordered, func() {
 					CreatedAt: new(time.Now()),
 					Size:      new(int64(10)), // 10 GiB
 					VolumeId:  payload.VolumeId,
+					Labels:    payload.Labels,
 				}
 				createdSnapshots[*newSnap.Id] = newSnap
 				return newSnap, nil
@@ -196,8 +197,12 @@ var _ = Describe("CSI sanity test", Ordered, func() {
 				limitFilter := filters["Limit"]
 				nameFilter := filters["Name"]
 				volumeIDFilter := filters["VolumeID"]
+				groupFilter, hasGroupFilter := filters["GroupSnapshotID"]
 
 				for _, value := range createdSnapshots {
+					if hasGroupFilter && value.GetLabels()[stackitclient.SnapshotGroupLabel] != groupFilter {
+						continue
+					}
 					if volumeIDFilter != "" {
 						if value.VolumeId == volumeIDFilter {
 							snapshots = append(snapshots, *value)
@@ -246,6 +251,7 @@ var _ = Describe("CSI sanity test", Ordered, func() {
 			iaasClient.EXPECT().WaitSnapshotReady(
 				gomock.Any(), // context
 				gomock.Any(), // snapshotID
+				gomock.Any(), // backoff
 			).Return(
 				new(stackitclient.SnapshotReadyStatus),
 				nil,
@@ -315,7 +321,14 @@ var _ = Describe("CSI sanity test", Ordered, func() {
 				if !ok {
 					return nil, &oapierror.GenericOpenAPIError{StatusCode: http.StatusNotFound}
 				}
-				return server, nil
+				res := *server
+				res.Volumes = nil
+				for volumeID, vol := range createdVolumes {
+					if vol.GetServerId() == instanceID {
+						res.Volumes = append(res.Volumes, volumeID)
+					}
+				}
+				return &res, nil
 			}).AnyTimes()
 
 			iaasClient.EXPECT().AttachVolume(
@@ -333,11 +346,28 @@ var _ = Describe("CSI sanity test", Ordered, func() {
 				return *vol.Id, nil
 			}).AnyTimes()
 
-			iaasClient.EXPECT().WaitDiskAttached(
+			iaasClient.EXPECT().UpdateVolume(
 				gomock.Any(), // context
-				gomock.Any(), // instanceID
 				gomock.Any(), // volumeID
-			).Return(nil).AnyTimes()
+				gomock.Any(), // payload
+			).DoAndReturn(func(_ context.Context, volumeID string, payload iaas.UpdateVolumePayload) (*iaas.Volume, error) {
+				vol, ok := createdVolumes[volumeID]
+				if !ok {
+					return nil, &oapierror.GenericOpenAPIError{StatusCode: http.StatusNotFound}
+				}
+				// The API merges the labels, a null value removes a label.
+				for key, value := range payload.Labels {
+					if vol.Labels == nil {
+						vol.Labels = map[string]any{}
+					}
+					if value == nil {
+						delete(vol.Labels, key)
+					} else {
+						vol.Labels[key] = value
+					}
+				}
+				return vol, nil
+			}).AnyTimes()
 
 			iaasClient.EXPECT().DetachVolume(
 				gomock.Any(), // context
@@ -357,6 +387,7 @@ var _ = Describe("CSI sanity test", Ordered, func() {
 				gomock.Any(), // context
 				gomock.Any(), // instanceID
 				gomock.Any(), // volumeID
+				gomock.Any(), // backoff
 			).Return(nil).AnyTimes()
 
 			// --- 5. Mock Metadata Service ---
@@ -402,6 +433,10 @@ var _ = Describe("CSI sanity test", Ordered, func() {
 				gomock.Any(), // volumeID
 			).Return(FakeDevicePath, nil).AnyTimes()
 
+			mountMock.EXPECT().GetDeviceSerial(
+				gomock.Any(), // devicePath
+			).Return("", nil).AnyTimes()
+
 			mountMock.EXPECT().GetDeviceStats(
 				gomock.Any(), // path
 			).DoAndReturn(func(_ string) (*mount.DeviceStats, error) {
@@ -411,6 +446,10 @@ var _ = Describe("CSI sanity test", Ordered, func() {
 				}, nil
 			}).AnyTimes()
 
+			mountMock.EXPECT().IsReadOnlyRemounted(
+				gomock.Any(), // path
+			).Return(false, nil).AnyTimes()
+
 			mountMock.EXPECT().GetMountFs(
 				gomock.Any(), // volumePath
 			).DoAndReturn(func(volumePath string) ([]byte, error) {
@@ -444,7 +483,8 @@ var _ = Describe("CSI sanity test", Ordered, func() {
 			mountMock.EXPECT().Mounter().Return(safeMounter).AnyTimes()
 
 			// --- Driver Setup & Run ---
-			driver.SetupControllerService(iaasClient)
+			driver.SetupControllerService(iaasClient, stackitconfig.BlockStorageOpts{})
+			driver.cs.attachQueue.initDelay = time.Millisecond
 			driver.SetupNodeService(mountMock, metadataMock, stackitconfig.BlockStorageOpts{})
 
 			go func() {
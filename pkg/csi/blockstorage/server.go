@@ -22,6 +22,7 @@ import (
 	"os"
 	"sync"
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 	"k8s.io/klog/v2"
 
@@ -31,7 +32,7 @@ import (
 // NonBlockingGRPCServer defines Non blocking GRPC server interfaces
 type NonBlockingGRPCServer interface {
 	// Start services at the endpoint
-	Start(endpoint string, ids csi.IdentityServer, cs csi.ControllerServer, ns csi.NodeServer)
+	Start(endpoint string, ids csi.IdentityServer, cs csi.ControllerServer, gcs csi.GroupControllerServer, ns csi.NodeServer)
 	// Waits for the service to stop
 	Wait()
 	// Stops the service gracefully
@@ -50,10 +51,10 @@ type nonBlockingGRPCServer struct {
 	server *grpc.Server
 }
 
-func (s *nonBlockingGRPCServer) Start(endpoint string, ids csi.IdentityServer, cs csi.ControllerServer, ns csi.NodeServer) {
+func (s *nonBlockingGRPCServer) Start(endpoint string, ids csi.IdentityServer, cs csi.ControllerServer, gcs csi.GroupControllerServer, ns csi.NodeServer) { //nolint:lll // looks weird when shortened
 	s.wg.Add(1)
 
-	go s.serve(endpoint, ids, cs, ns)
+	go s.serve(endpoint, ids, cs, gcs, ns)
 }
 
 func (s *nonBlockingGRPCServer) Wait() {
@@ -68,7 +69,7 @@ func (s *nonBlockingGRPCServer) ForceStop() {
 	s.server.Stop()
 }
 
-func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer, cs csi.ControllerServer, ns csi.NodeServer) {
+func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer, cs csi.ControllerServer, gcs csi.GroupControllerServer, ns csi.NodeServer) { //nolint:lll // looks weird when shortened
 	defer s.wg.Done()
 
 	proto, addr, err := ParseEndpoint(endpoint)
@@ -90,7 +91,9 @@ func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer, c
 	}
 
 	opts := []grpc.ServerOption{
-		grpc.UnaryInterceptor(logGRPC),
+		// otelgrpc starts a span for every call, continuing the trace of the sidecar if it is propagated.
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(logGRPC, metricsGRPC, traceGRPC),
 	}
 	server := grpc.NewServer(opts...)
 	s.server = server
@@ -101,14 +104,17 @@ func (s *nonBlockingGRPCServer) serve(endpoint string, ids csi.IdentityServer, c
 	if cs != nil {
 		csi.RegisterControllerServer(server, cs)
 	}
+	if gcs != nil {
+		csi.RegisterGroupControllerServer(server, gcs)
+	}
 	if ns != nil {
 		csi.RegisterNodeServer(server, ns)
 	}
 
-	klog.Infof("Listening for connections on address: %s", listener.Addr().String())
+	klog.InfoS("Listening for connections", "address", listener.Addr().String())
 
 	if err := server.Serve(listener); err != nil {
-		klog.Infof("Server stopped with: %v", err)
+		klog.InfoS("Server stopped", "err", err)
 		return
 	}
 }
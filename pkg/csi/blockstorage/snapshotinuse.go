@@ -0,0 +1,132 @@
+package blockstorage
+
+import (
+	"context"
+	"strconv"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+
+	sharedcsi "github.com/stackitcloud/cloud-provider-stackit/pkg/csi"
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
+)
+
+const (
+	// snapshotForceCreateParameter allows crash-consistent snapshots of volumes that are attached to a server.
+	snapshotForceCreateParameter = "force-create"
+
+	// SnapshotQuiescedAnnotation on a VolumeSnapshot tells the driver that the filesystem of the attached source
+	// volume was frozen before the VolumeSnapshot was created, e.g. by a pre-snapshot hook running fsfreeze, and is
+	// thawed after the snapshot is ready to use.
+	SnapshotQuiescedAnnotation = "snapshot.csi.stackit.cloud/quiesced"
+)
+
+// checkSnapshotSource ensures that the source volume of a new snapshot exists and, if it is attached to a server,
+// that the snapshot of the in-use volume was requested explicitly. Snapshots of attached volumes only contain the
+// data written to the volume, data cached by the filesystem or applications on the node is lost.
+func (cs *controllerServer) checkSnapshotSource(ctx context.Context, volumeID string, parameters map[string]string) error {
+	force, err := snapshotForced(parameters)
+	if err != nil {
+		return err
+	}
+	serverID, err := sourceVolumeServer(ctx, cs.Instance, volumeID)
+	if err != nil || serverID == nil {
+		return err
+	}
+
+	if force {
+		klog.V(3).InfoS("Creating crash-consistent snapshot of attached volume", "volumeID", volumeID, "serverID", *serverID)
+		return nil
+	}
+	quiesced, err := cs.snapshotQuiesced(ctx, parameters)
+	if err != nil {
+		return status.Errorf(codes.Internal, "Failed to get annotations of VolumeSnapshot: %v", err)
+	}
+	if quiesced {
+		klog.V(3).InfoS("Creating snapshot of quiesced attached volume", "volumeID", volumeID, "serverID", *serverID)
+		return nil
+	}
+
+	return status.Errorf(codes.FailedPrecondition,
+		"volume %s is attached to server %s, set %s: \"true\" in the VolumeSnapshotClass for a crash-consistent snapshot or annotate the VolumeSnapshot with %s: \"true\" after freezing the filesystem",
+		volumeID, *serverID, snapshotForceCreateParameter, SnapshotQuiescedAnnotation)
+}
+
+// checkGroupSnapshotSources applies the check of checkSnapshotSource to the source volumes of a group snapshot before
+// any snapshot of the group is created, so that no incomplete group is left behind. VolumeGroupSnapshots can't be
+// marked as quiesced, so snapshots of attached volumes must be allowed in the VolumeGroupSnapshotClass.
+func (gs *groupControllerServer) checkGroupSnapshotSources(ctx context.Context, volumeIDs []string, parameters map[string]string) error {
+	force, err := snapshotForced(parameters)
+	if err != nil {
+		return err
+	}
+	for _, volumeID := range volumeIDs {
+		serverID, err := sourceVolumeServer(ctx, gs.Instance, volumeID)
+		if err != nil {
+			return err
+		}
+		if serverID == nil {
+			continue
+		}
+		if !force {
+			return status.Errorf(codes.FailedPrecondition,
+				"volume %s is attached to server %s, set %s: \"true\" in the VolumeGroupSnapshotClass for crash-consistent snapshots",
+				volumeID, *serverID, snapshotForceCreateParameter)
+		}
+		klog.V(3).InfoS("Creating crash-consistent snapshot of attached volume for group snapshot", "volumeID", volumeID, "serverID", *serverID)
+	}
+	return nil
+}
+
+// snapshotForced returns whether snapshots of attached volumes are allowed by snapshotForceCreateParameter.
+func snapshotForced(parameters map[string]string) (bool, error) {
+	value, ok := parameters[snapshotForceCreateParameter]
+	if !ok {
+		return false, nil
+	}
+	force, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, status.Errorf(codes.InvalidArgument, "parameter %s must be of type boolean", snapshotForceCreateParameter)
+	}
+	return force, nil
+}
+
+// sourceVolumeServer returns the ID of the server the source volume of a new snapshot is attached to, or nil if it is
+// not attached.
+func sourceVolumeServer(ctx context.Context, instance stackitclient.IaaSClient, volumeID string) (*string, error) {
+	volume, err := instance.GetVolume(ctx, volumeID)
+	if err != nil {
+		if stackiterrors.IsNotFound(err) {
+			return nil, status.Errorf(codes.NotFound, "Source volume %s not found", volumeID)
+		}
+		return nil, status.Errorf(codes.Internal, "Failed to retrieve the source volume %s: %v", volumeID, err)
+	}
+	if volume.GetServerId() == "" && volume.GetStatus() != stackitclient.VolumeAttachedStatus {
+		return nil, nil
+	}
+	return new(volume.GetServerId()), nil
+}
+
+// snapshotQuiesced reports whether the VolumeSnapshot the snapshot is created for is annotated as quiesced.
+// The VolumeSnapshot is identified by the parameters the csi-snapshotter passes with --extra-create-metadata.
+func (cs *controllerServer) snapshotQuiesced(ctx context.Context, parameters map[string]string) (bool, error) {
+	if cs.Driver.snapshotAnnotations == nil {
+		return false, nil
+	}
+
+	namespace := parameters[sharedcsi.VolSnapshotNamespaceKey]
+	name := parameters[sharedcsi.VolSnapshotNameKey]
+	if namespace == "" || name == "" {
+		klog.V(4).InfoS("VolumeSnapshot unknown, check whether the --extra-create-metadata flag is set in csi-snapshotter")
+		return false, nil
+	}
+
+	annotations, err := cs.Driver.snapshotAnnotations(ctx, namespace, name)
+	if err != nil {
+		return false, err
+	}
+	quiesced, _ := strconv.ParseBool(annotations[SnapshotQuiescedAnnotation])
+	return quiesced, nil
+}
@@ -0,0 +1,111 @@
+package blockstorage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stackitcloud/stackit-sdk-go/core/oapierror"
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	sharedcsi "github.com/stackitcloud/cloud-provider-stackit/pkg/csi"
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+)
+
+var _ = Describe("Snapshots of attached volumes", func() {
+	var (
+		iaasClient  *stackitclientmock.MockIaaSClient
+		driver      *Driver
+		cs          *controllerServer
+		annotations map[string]string
+		parameters  map[string]string
+	)
+
+	attachedVolume := &iaas.Volume{Id: new("volume-id"), ServerId: new("server-id"), Status: new(stackitclient.VolumeAttachedStatus)}
+
+	BeforeEach(func() {
+		iaasClient = stackitclientmock.NewMockIaaSClient(gomock.NewController(GinkgoT()))
+		driver = NewDriver(&DriverOpts{})
+		cs = NewControllerServer(driver, iaasClient, stackitconfig.BlockStorageOpts{})
+		annotations = nil
+		parameters = map[string]string{
+			sharedcsi.VolSnapshotNamespaceKey: "default",
+			sharedcsi.VolSnapshotNameKey:      "data-snapshot",
+		}
+	})
+
+	check := func() error {
+		return cs.checkSnapshotSource(context.Background(), "volume-id", parameters)
+	}
+
+	It("should fail without creating a snapshot", func() {
+		iaasClient.EXPECT().ListSnapshots(gomock.Any(), gomock.Any()).Return([]iaas.Snapshot{}, "", nil)
+		iaasClient.EXPECT().GetVolume(gomock.Any(), "volume-id").Return(attachedVolume, nil)
+
+		_, err := cs.CreateSnapshot(context.Background(), &csi.CreateSnapshotRequest{
+			Name:           "snapshot",
+			SourceVolumeId: "volume-id",
+			Parameters:     parameters,
+		})
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+		Expect(err.Error()).To(ContainSubstring("volume volume-id is attached to server server-id"))
+	})
+
+	It("should allow snapshots of unattached volumes", func() {
+		iaasClient.EXPECT().GetVolume(gomock.Any(), "volume-id").Return(&iaas.Volume{Status: new(stackitclient.VolumeAvailableStatus)}, nil)
+		Expect(check()).To(Succeed())
+	})
+
+	It("should allow crash-consistent snapshots with force-create", func() {
+		parameters[snapshotForceCreateParameter] = "true"
+		iaasClient.EXPECT().GetVolume(gomock.Any(), "volume-id").Return(attachedVolume, nil)
+		Expect(check()).To(Succeed())
+	})
+
+	It("should reject an invalid force-create parameter", func() {
+		parameters[snapshotForceCreateParameter] = "yes please"
+		Expect(status.Code(check())).To(Equal(codes.InvalidArgument))
+	})
+
+	It("should return NotFound for unknown source volumes", func() {
+		iaasClient.EXPECT().GetVolume(gomock.Any(), "volume-id").Return(nil, &oapierror.GenericOpenAPIError{StatusCode: http.StatusNotFound})
+		Expect(status.Code(check())).To(Equal(codes.NotFound))
+	})
+
+	Context("with VolumeSnapshot annotations", func() {
+		var lookupErr error
+
+		BeforeEach(func() {
+			lookupErr = nil
+			driver.snapshotAnnotations = func(_ context.Context, namespace, name string) (map[string]string, error) {
+				Expect(namespace).To(Equal("default"))
+				Expect(name).To(Equal("data-snapshot"))
+				return annotations, lookupErr
+			}
+			iaasClient.EXPECT().GetVolume(gomock.Any(), "volume-id").Return(attachedVolume, nil)
+		})
+
+		It("should allow snapshots of quiesced volumes", func() {
+			annotations = map[string]string{SnapshotQuiescedAnnotation: "true"}
+			Expect(check()).To(Succeed())
+		})
+
+		It("should fail if the volume wasn't quiesced", func() {
+			annotations = map[string]string{SnapshotQuiescedAnnotation: "false"}
+			Expect(status.Code(check())).To(Equal(codes.FailedPrecondition))
+		})
+
+		It("should fail if the VolumeSnapshot can't be read", func() {
+			lookupErr = errors.New("injected error")
+			Expect(status.Code(check())).To(Equal(codes.Internal))
+		})
+	})
+})
@@ -0,0 +1,67 @@
+package blockstorage
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/metrics"
+)
+
+// defaultMaxQueuedSnapshots is the number of CreateSnapshot calls that wait for a slot if maxQueuedSnapshots isn't set.
+const defaultMaxQueuedSnapshots = 50
+
+// snapshotLimiter limits the CreateSnapshot calls that run concurrently, because creating many snapshots and backups
+// at once overloads the backup subsystem of the IaaS API. Calls exceeding the limit wait in a queue of limited length,
+// calls exceeding the queue fail with Aborted and are retried by the csi-snapshotter.
+type snapshotLimiter struct {
+	slots chan struct{}
+	queue chan struct{}
+}
+
+// newSnapshotLimiter returns a limiter for concurrency calls, or nil if concurrency isn't positive.
+func newSnapshotLimiter(concurrency, queueLength int) *snapshotLimiter {
+	if concurrency <= 0 {
+		return nil
+	}
+	if queueLength <= 0 {
+		queueLength = defaultMaxQueuedSnapshots
+	}
+	return &snapshotLimiter{
+		slots: make(chan struct{}, concurrency),
+		queue: make(chan struct{}, queueLength),
+	}
+}
+
+// acquire waits for a slot and returns the function that releases it. A nil limiter doesn't limit anything.
+func (l *snapshotLimiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	release := func() { <-l.slots }
+
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	select {
+	case l.queue <- struct{}{}:
+	default:
+		return nil, status.Errorf(codes.Aborted, "%d snapshots are already being created and %d are queued", cap(l.slots), cap(l.queue))
+	}
+	metrics.CSISnapshotOperationsQueued.Inc()
+	defer func() {
+		metrics.CSISnapshotOperationsQueued.Dec()
+		<-l.queue
+	}()
+
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	case <-ctx.Done():
+		return nil, status.Errorf(codes.Aborted, "timed out waiting in the queue of snapshots: %v", ctx.Err())
+	}
+}
@@ -0,0 +1,60 @@
+package blockstorage
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/metrics"
+)
+
+var _ = Describe("snapshotLimiter", func() {
+	It("should not limit anything without a concurrency", func() {
+		limiter := newSnapshotLimiter(0, 0)
+		Expect(limiter).To(BeNil())
+		release, err := limiter.acquire(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		release()
+	})
+
+	It("should queue calls exceeding the concurrency and abort calls exceeding the queue", func() {
+		limiter := newSnapshotLimiter(1, 1)
+		release, err := limiter.acquire(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+
+		queued := make(chan error)
+		go func() {
+			defer GinkgoRecover()
+			release, err := limiter.acquire(context.Background())
+			if err == nil {
+				release()
+			}
+			queued <- err
+		}()
+		Eventually(func() float64 { return testutil.ToFloat64(metrics.CSISnapshotOperationsQueued) }).Should(Equal(1.0))
+
+		_, err = limiter.acquire(context.Background())
+		Expect(status.Code(err)).To(Equal(codes.Aborted))
+
+		release()
+		Eventually(queued).Should(Receive(BeNil()))
+		Expect(testutil.ToFloat64(metrics.CSISnapshotOperationsQueued)).To(Equal(0.0))
+	})
+
+	It("should abort queued calls once their context is done", func() {
+		limiter := newSnapshotLimiter(1, 1)
+		release, err := limiter.acquire(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		defer release()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err = limiter.acquire(ctx)
+		Expect(status.Code(err)).To(Equal(codes.Aborted))
+		Expect(testutil.ToFloat64(metrics.CSISnapshotOperationsQueued)).To(Equal(0.0))
+	})
+})
@@ -5,15 +5,22 @@ import (
 	"fmt"
 	"strings"
 	"sync/atomic"
+	"time"
 
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/csi/util"
 	"github.com/stackitcloud/cloud-provider-stackit/pkg/csi/util/mount"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/metrics"
 	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
 	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
 	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/metadata"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/tracing"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/kubernetes-csi/csi-lib-utils/protosanitizer"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
 	"k8s.io/klog/v2"
 )
 
@@ -29,6 +36,16 @@ func NewControllerServiceCapability(rpcType csi.ControllerServiceCapability_RPC_
 	}
 }
 
+func NewGroupControllerServiceCapability(rpcType csi.GroupControllerServiceCapability_RPC_Type) *csi.GroupControllerServiceCapability {
+	return &csi.GroupControllerServiceCapability{
+		Type: &csi.GroupControllerServiceCapability_Rpc{
+			Rpc: &csi.GroupControllerServiceCapability_RPC{
+				Type: rpcType,
+			},
+		},
+	}
+}
+
 func NewNodeServiceCapability(rpcType csi.NodeServiceCapability_RPC_Type) *csi.NodeServiceCapability {
 	return &csi.NodeServiceCapability{
 		Type: &csi.NodeServiceCapability_Rpc{
@@ -44,11 +61,36 @@ func NewVolumeCapabilityAccessMode(mode csi.VolumeCapability_AccessMode_Mode) *c
 }
 
 //revive:disable:unexported-return
-func NewControllerServer(d *Driver, instance stackitclient.IaaSClient) *controllerServer {
-	return &controllerServer{
-		Driver:   d,
-		Instance: instance,
+func NewControllerServer(d *Driver, instance stackitclient.IaaSClient, opts stackitconfig.BlockStorageOpts) *controllerServer {
+	backoffs := newWaitBackoffs(opts.WaitBackoffs)
+	cs := &controllerServer{
+		Driver:         d,
+		Instance:       instance,
+		Opts:           opts,
+		operationLocks: util.NewOperationLocks(),
+		restores:       util.NewInFlightRestores(),
+		backoffs:       backoffs,
+		attachQueue:    newAttachQueue(instance, backoffs.attach, d.waitTimeout),
+		snapshots:      newSnapshotLimiter(opts.MaxConcurrentSnapshots, opts.MaxQueuedSnapshots),
+	}
+	cs.restoreProgress = newRestoreProgresses(d, instance)
+	if opts.ReclaimGracePeriod.Duration > 0 {
+		cs.reclaimer = newVolumeReclaimer(instance, opts.ReclaimGracePeriod.Duration)
+	}
+	return cs
+}
+
+func NewGroupControllerServer(d *Driver, instance stackitclient.IaaSClient, opts stackitconfig.BlockStorageOpts) *groupControllerServer {
+	gs := &groupControllerServer{
+		Driver:          d,
+		Instance:        instance,
+		snapshotBackoff: newWaitBackoffs(opts.WaitBackoffs).snapshot,
+	}
+	// Group snapshots share the concurrency limit of the snapshots of the controller service.
+	if d.cs != nil {
+		gs.snapshots = d.cs.snapshots
 	}
+	return gs
 }
 
 func NewIdentityServer(d *Driver) *identityServer {
@@ -63,14 +105,16 @@ func NewNodeServer(d *Driver, mountProvider mount.IMount, metadataProvider metad
 		Mount:    mountProvider,
 		Metadata: metadataProvider,
 		Opts:     opts,
+
+		inventory: newVolumeInventory(),
 	}
 }
 
 //revive:enable:unexported-return
 
-func RunServicesInitialized(endpoint string, ids csi.IdentityServer, cs csi.ControllerServer, ns csi.NodeServer) {
+func RunServicesInitialized(endpoint string, ids csi.IdentityServer, cs csi.ControllerServer, gcs csi.GroupControllerServer, ns csi.NodeServer) { //nolint:lll // looks weird when shortened
 	s := NewNonBlockingGRPCServer()
-	s.Start(endpoint, ids, cs, ns)
+	s.Start(endpoint, ids, cs, gcs, ns)
 	s.Wait()
 }
 
@@ -87,14 +131,44 @@ func ParseEndpoint(ep string) (proto, addr string, err error) {
 func logGRPC(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
 	callID := serverGRPCEndpointCallCounter.Add(1)
 
-	klog.V(3).Infof("[ID:%d] GRPC call: %s", callID, info.FullMethod)
-	klog.V(5).Infof("[ID:%d] GRPC request: %s", callID, protosanitizer.StripSecrets(req))
+	klog.V(3).InfoS("GRPC call", "callID", callID, "method", info.FullMethod)
+	klog.V(5).InfoS("GRPC request", "callID", callID, "request", protosanitizer.StripSecrets(req))
 	resp, err := handler(ctx, req)
 	if err != nil {
-		klog.Errorf("[ID:%d] GRPC error: %v", callID, err)
+		klog.ErrorS(err, "GRPC error", "callID", callID)
 	} else {
-		klog.V(5).Infof("[ID:%d] GRPC response: %s", callID, protosanitizer.StripSecrets(resp))
+		klog.V(5).InfoS("GRPC response", "callID", callID, "response", protosanitizer.StripSecrets(resp))
+	}
+
+	return resp, err
+}
+
+// traceGRPC adds the IDs of the volume, snapshot and node of a CSI call to the span of otelgrpc, if tracing is enabled.
+func traceGRPC(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	span := trace.SpanFromContext(ctx)
+	if span.IsRecording() {
+		if r, ok := req.(interface{ GetVolumeId() string }); ok && r.GetVolumeId() != "" {
+			span.SetAttributes(attribute.String(tracing.AttributeVolumeID, r.GetVolumeId()))
+		}
+		if r, ok := req.(interface{ GetSnapshotId() string }); ok && r.GetSnapshotId() != "" {
+			span.SetAttributes(attribute.String(tracing.AttributeSnapshotID, r.GetSnapshotId()))
+		}
+		if r, ok := req.(interface{ GetNodeId() string }); ok && r.GetNodeId() != "" {
+			span.SetAttributes(attribute.String(tracing.AttributeNodeID, r.GetNodeId()))
+		}
 	}
+	return handler(ctx, req)
+}
+
+// metricsGRPC records the duration of all CSI operations and the number of operations in flight by method,
+// e.g. to track the latency of attaching and staging volumes.
+func metricsGRPC(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	inFlight := metrics.CSIOperationsInFlight.WithLabelValues(info.FullMethod)
+	inFlight.Inc()
+	defer inFlight.Dec()
 
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	metrics.CSIOperationsSeconds.WithLabelValues(info.FullMethod, status.Code(err).String()).Observe(time.Since(start).Seconds())
 	return resp, err
 }
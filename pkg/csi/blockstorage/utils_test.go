@@ -0,0 +1,73 @@
+package blockstorage
+
+import (
+	"context"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/metrics"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/tracing"
+)
+
+var _ = Describe("metricsGRPC", func() {
+	const method = "/csi.v1.Node/NodeStageVolume"
+
+	sampleCount := func(code string) uint64 {
+		m := &dto.Metric{}
+		Expect(metrics.CSIOperationsSeconds.WithLabelValues(method, code).(prometheus.Metric).Write(m)).To(Succeed())
+		return m.GetHistogram().GetSampleCount()
+	}
+
+	BeforeEach(func() {
+		metrics.CSIOperationsSeconds.Reset()
+		metrics.CSIOperationsInFlight.Reset()
+	})
+
+	It("should record the duration by method and status code", func() {
+		info := &grpc.UnaryServerInfo{FullMethod: method}
+		_, err := metricsGRPC(context.Background(), nil, info, func(context.Context, any) (any, error) {
+			Expect(testutil.ToFloat64(metrics.CSIOperationsInFlight.WithLabelValues(method))).To(Equal(1.0))
+			return nil, nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = metricsGRPC(context.Background(), nil, info, func(context.Context, any) (any, error) {
+			return nil, status.Error(codes.NotFound, "volume not found")
+		})
+		Expect(err).To(HaveOccurred())
+
+		Expect(testutil.ToFloat64(metrics.CSIOperationsInFlight.WithLabelValues(method))).To(BeZero())
+		Expect(sampleCount("OK")).To(BeEquivalentTo(1))
+		Expect(sampleCount("NotFound")).To(BeEquivalentTo(1))
+	})
+})
+
+var _ = Describe("traceGRPC", func() {
+	It("should add the IDs of the request to the span", func() {
+		recorder := tracetest.NewSpanRecorder()
+		ctx, span := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test").
+			Start(context.Background(), "/csi.v1.Controller/ControllerPublishVolume")
+		req := &csi.ControllerPublishVolumeRequest{VolumeId: "volume-1", NodeId: "server-1"}
+		_, err := traceGRPC(ctx, req, &grpc.UnaryServerInfo{}, func(context.Context, any) (any, error) {
+			return nil, nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		span.End()
+
+		Expect(recorder.Ended()).To(HaveLen(1))
+		Expect(recorder.Ended()[0].Attributes()).To(ConsistOf(
+			attribute.String(tracing.AttributeVolumeID, "volume-1"),
+			attribute.String(tracing.AttributeNodeID, "server-1"),
+		))
+	})
+})
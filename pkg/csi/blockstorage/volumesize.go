@@ -0,0 +1,84 @@
+package blockstorage
+
+import (
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/csi/util"
+)
+
+// defaultVolumeSizeGiB is the size of volumes whose request has no required capacity, unless configured otherwise.
+const defaultVolumeSizeGiB = 1
+
+// volumeSize returns the size in GiB of a new volume for the capacity range of the request.
+//
+// The default size is used if the request has no required capacity. The StorageClass can override the default size
+// and lower the maximum size of the driver with the defaultSize and maxSize parameters, but can't raise it.
+func (cs *controllerServer) volumeSize(capRange *csi.CapacityRange, params *stackitParameterConfig) (int64, error) {
+	defaultSize := cs.Opts.DefaultVolumeSize
+	if defaultSize == 0 {
+		defaultSize = defaultVolumeSizeGiB
+	}
+	if params.DefaultSize != nil {
+		size, err := parseSizeParameter("defaultSize", *params.DefaultSize)
+		if err != nil {
+			return 0, err
+		}
+		defaultSize = util.RoundUpSize(size, util.GIBIBYTE)
+	}
+	maxSize := cs.Opts.MaxVolumeSize
+	if params.MaxSize != nil {
+		size, err := parseSizeParameter("maxSize", *params.MaxSize)
+		if err != nil {
+			return 0, err
+		}
+		if size < util.GIBIBYTE {
+			return 0, status.Errorf(codes.InvalidArgument, "invalid maxSize %q: must be at least 1Gi", *params.MaxSize)
+		}
+		if maxSize == 0 || size/util.GIBIBYTE < maxSize {
+			maxSize = size / util.GIBIBYTE
+		}
+	}
+
+	limitBytes := capRange.GetLimitBytes()
+	sizeGiB := util.RoundUpSize(capRange.GetRequiredBytes(), util.GIBIBYTE)
+	if sizeGiB == 0 {
+		sizeGiB = defaultSize
+		// Use the largest size within the limit if the default size doesn't fit.
+		if limitBytes > 0 && sizeGiB*util.GIBIBYTE > limitBytes {
+			sizeGiB = limitBytes / util.GIBIBYTE
+			if sizeGiB == 0 {
+				return 0, status.Errorf(codes.OutOfRange, "volume size limit of %d bytes is less than 1 GiB", limitBytes)
+			}
+		}
+	}
+	if limitBytes > 0 && sizeGiB*util.GIBIBYTE > limitBytes {
+		return 0, status.Errorf(codes.OutOfRange, "after round-up, volume size of %d GiB exceeds the limit of %d bytes", sizeGiB, limitBytes)
+	}
+	if err := checkMaxVolumeSize(sizeGiB, maxSize); err != nil {
+		return 0, err
+	}
+	return sizeGiB, nil
+}
+
+// checkMaxVolumeSize fails with OutOfRange if sizeGiB exceeds maxSizeGiB. A maximum of 0 is unlimited.
+func checkMaxVolumeSize(sizeGiB, maxSizeGiB int64) error {
+	if maxSizeGiB > 0 && sizeGiB > maxSizeGiB {
+		return status.Errorf(codes.OutOfRange, "volume size of %d GiB exceeds the maximum size of %d GiB", sizeGiB, maxSizeGiB)
+	}
+	return nil
+}
+
+// parseSizeParameter parses the quantity of a size parameter of the StorageClass, e.g. "100Gi", in bytes.
+func parseSizeParameter(name, value string) (int64, error) {
+	q, err := resource.ParseQuantity(value)
+	if err != nil {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid %s %q: %v", name, value, err)
+	}
+	if q.Sign() <= 0 {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid %s %q: must be positive", name, value)
+	}
+	return q.Value(), nil
+}
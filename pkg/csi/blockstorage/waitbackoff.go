@@ -0,0 +1,50 @@
+package blockstorage
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+)
+
+var (
+	// defaultCreateBackoff rechecks new volumes after 0s (immediate), 20s, 45.6s, 78.36s, 120.31s.
+	defaultCreateBackoff = wait.Backoff{Duration: 20 * time.Second, Factor: 1.28, Steps: 5}
+	// defaultAttachBackoff has no steps, the attach queue polls until the wait timeout expires.
+	defaultAttachBackoff = wait.Backoff{Duration: attachPollInitDelay, Factor: attachPollFactor}
+)
+
+// waitBackoffs are the backoffs of the controller's waiters with the overrides of the operator applied.
+type waitBackoffs struct {
+	create   wait.Backoff
+	attach   wait.Backoff
+	detach   wait.Backoff
+	expand   wait.Backoff
+	snapshot wait.Backoff
+}
+
+func newWaitBackoffs(opts stackitconfig.WaitBackoffs) waitBackoffs {
+	return waitBackoffs{
+		create:   withOverrides(defaultCreateBackoff, opts.Create),
+		attach:   withOverrides(defaultAttachBackoff, opts.Attach),
+		detach:   withOverrides(stackitclient.DefaultDiskDetachBackoff, opts.Detach),
+		expand:   withOverrides(stackitclient.DefaultVolumeStatusBackoff, opts.Expand),
+		snapshot: withOverrides(stackitclient.DefaultSnapshotReadyBackoff, opts.Snapshot),
+	}
+}
+
+// withOverrides returns the default backoff with the fields that are set in opts replaced.
+func withOverrides(backoff wait.Backoff, opts stackitconfig.WaitBackoff) wait.Backoff {
+	if opts.InitialDelay.Duration > 0 {
+		backoff.Duration = opts.InitialDelay.Duration
+	}
+	if opts.Factor > 0 {
+		backoff.Factor = opts.Factor
+	}
+	if opts.Steps > 0 {
+		backoff.Steps = opts.Steps
+	}
+	return backoff
+}
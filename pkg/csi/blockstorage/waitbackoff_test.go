@@ -0,0 +1,33 @@
+package blockstorage
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/metadata"
+)
+
+var _ = Describe("waitBackoffs", func() {
+	It("should use the defaults without overrides", func() {
+		backoffs := newWaitBackoffs(stackitconfig.WaitBackoffs{})
+		Expect(backoffs.create).To(Equal(defaultCreateBackoff))
+		Expect(backoffs.attach).To(Equal(defaultAttachBackoff))
+		Expect(backoffs.detach).To(Equal(stackitclient.DefaultDiskDetachBackoff))
+		Expect(backoffs.expand).To(Equal(stackitclient.DefaultVolumeStatusBackoff))
+		Expect(backoffs.snapshot).To(Equal(stackitclient.DefaultSnapshotReadyBackoff))
+	})
+
+	It("should only replace the fields that are set", func() {
+		backoffs := newWaitBackoffs(stackitconfig.WaitBackoffs{
+			Create:   stackitconfig.WaitBackoff{Steps: 8},
+			Snapshot: stackitconfig.WaitBackoff{InitialDelay: metadata.Duration{Duration: 5 * time.Second}, Factor: 2},
+		})
+		Expect(backoffs.create).To(Equal(wait.Backoff{Duration: 20 * time.Second, Factor: 1.28, Steps: 8}))
+		Expect(backoffs.snapshot).To(Equal(wait.Backoff{Duration: 5 * time.Second, Factor: 2, Steps: 10}))
+	})
+})
@@ -20,15 +20,27 @@ import (
 	"context"
 	"math/rand"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	corev1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
@@ -68,7 +80,24 @@ var (
 
 var (
 	// CSI controller options
-	pvcAnnotations bool
+	pvcAnnotations  bool
+	events          bool
+	backupSchedules bool
+	// volumeAdoption enables adopting retained volumes, which requires a PV lister
+	volumeAdoption bool
+	// snapshotAnnotations enables reading the annotations of VolumeSnapshots in CreateSnapshot
+	snapshotAnnotations bool
+	// leader election of the control loops of the controller service
+	leaderElection              bool
+	leaderElectionNamespace     string
+	leaderElectionName          string
+	leaderElectionLeaseDuration time.Duration
+	leaderElectionRenewDeadline time.Duration
+	leaderElectionRetryPeriod   time.Duration
+	// nodeFailoverTimeout enables detaching the volumes of failed nodes, 0 if disabled
+	nodeFailoverTimeout time.Duration
+	// attachmentCapacityThreshold enables checking the attachments of nodes against their limit, 0 if disabled
+	attachmentCapacityThreshold float64
 	// k8s client options
 	master          string
 	kubeconfig      string
@@ -85,44 +114,61 @@ func AddPVCFlags(cmd *cobra.Command) {
 	cmd.PersistentFlags().IntVar(&kubeAPIBurst, "kube-api-burst", 10, "Burst to use while communicating with the kubernetes apiserver.")
 	cmd.PersistentFlags().DurationVar(&minResyncPeriod, "min-resync-period", 12*time.Hour, "The resync period in reflectors will be random between MinResyncPeriod and 2*MinResyncPeriod.")
 
+	cmd.PersistentFlags().BoolVar(&events, "events", false, "Record events on the PVCs of volumes, e.g. when the mutable parameters of a volume were modified")
+	cmd.PersistentFlags().BoolVar(&backupSchedules, "backup-schedules", false, "Create and prune backups of PVCs according to the backup annotations of the PVCs and their StorageClasses")
+	cmd.PersistentFlags().BoolVar(&volumeAdoption, "volume-adoption", false, "Enable adopting retained volumes for StorageClasses with the adoptExisting parameter. Volumes that are still referenced by a PV are never adopted")
+	cmd.PersistentFlags().BoolVar(&snapshotAnnotations, "snapshot-annotations", false, "Enable support for VolumeSnapshot annotations in the controller's CreateSnapshot CSI method (enabling this flag requires enabling the --extra-create-metadata flag in csi-snapshotter)")
 	cmd.PersistentFlags().BoolVar(&pvcAnnotations, "pvc-annotations", false, "Enable support for PVC annotations in the controller's CreateVolume CSI method (enabling this flag requires enabling the --extra-create-metadata flag in csi-provisioner)")
+
+	cmd.PersistentFlags().BoolVar(&leaderElection, "leader-election", false, "Run the control loops of the controller service, e.g. scheduled backups and deferred deletions, only in the replica holding the leader election lease. The CSI calls are served by all replicas.")
+	cmd.PersistentFlags().StringVar(&leaderElectionNamespace, "leader-election-namespace", "kube-system", "Namespace of the leader election lease.")
+	cmd.PersistentFlags().StringVar(&leaderElectionName, "leader-election-name", "stackit-csi-plugin-controller", "Name of the leader election lease.")
+	cmd.PersistentFlags().DurationVar(&leaderElectionLeaseDuration, "leader-election-lease-duration", 15*time.Second, "Duration that standby replicas wait before taking over the lease of the leader.")
+	cmd.PersistentFlags().DurationVar(&leaderElectionRenewDeadline, "leader-election-renew-deadline", 10*time.Second, "Duration that the leader retries renewing the lease before it stops leading.")
+	cmd.PersistentFlags().DurationVar(&leaderElectionRetryPeriod, "leader-election-retry-period", 5*time.Second, "Duration between attempts to acquire or renew the lease.")
+
+	cmd.PersistentFlags().DurationVar(&nodeFailoverTimeout, "node-failover-timeout", 0, "Detach the volumes of nodes that are not ready for this duration and whose server is not running, so that their pods can fail over to other nodes. Disabled if 0.")
+	cmd.PersistentFlags().Float64Var(&attachmentCapacityThreshold, "attachment-capacity-threshold", 0, "Record an event on nodes whose servers have at least this fraction of the volume limit advertised in their CSINode attached, e.g. 0.8. Disabled if 0.")
 }
 
-func GetAZFromTopology(topologyKey string, requirement *csi.TopologyRequirement) string {
+// GetAZFromTopology returns the zone of the first preferred, then the first requisite topology that has one of the
+// topology keys. The keys are checked in order for each topology.
+func GetAZFromTopology(requirement *csi.TopologyRequirement, topologyKeys ...string) string {
 	var zone string
 	var exists bool
 
-	defer func() { klog.V(1).Infof("detected AZ from the topology: %s", zone) }()
-	klog.V(4).Infof("preferred topology requirement: %+v", requirement.GetPreferred())
-	klog.V(4).Infof("requisite topology requirement: %+v", requirement.GetRequisite())
+	defer func() { klog.V(1).InfoS("Detected AZ from the topology", "zone", zone) }()
+	klog.V(4).InfoS("Preferred topology requirement", "topology", requirement.GetPreferred())
+	klog.V(4).InfoS("Requisite topology requirement", "topology", requirement.GetRequisite())
 
 	for _, topology := range requirement.GetPreferred() {
-		zone, exists = topology.GetSegments()[topologyKey]
-		if exists {
-			return zone
+		for _, topologyKey := range topologyKeys {
+			zone, exists = topology.GetSegments()[topologyKey]
+			if exists {
+				return zone
+			}
 		}
 	}
 
 	for _, topology := range requirement.GetRequisite() {
-		zone, exists = topology.GetSegments()[topologyKey]
-		if exists {
-			return zone
+		for _, topologyKey := range topologyKeys {
+			zone, exists = topology.GetSegments()[topologyKey]
+			if exists {
+				return zone
+			}
 		}
 	}
 
 	return zone
 }
 
-func GetPVCLister() corev1.PersistentVolumeClaimLister {
-	if !pvcAnnotations {
-		return nil
-	}
-
+// kubeConfig returns the config for the Kubernetes API, which is created on first use.
+var kubeConfig = sync.OnceValue(func() *rest.Config {
 	// get the KUBECONFIG from env if specified (useful for local/debug cluster)
 	kubeconfigEnv := os.Getenv("KUBECONFIG")
 
 	if kubeconfigEnv != "" {
-		klog.Infof("Found KUBECONFIG environment variable set, using that..")
+		klog.InfoS("Found KUBECONFIG environment variable set, using it")
 		kubeconfig = kubeconfigEnv
 	}
 
@@ -133,14 +179,26 @@ func GetPVCLister() corev1.PersistentVolumeClaimLister {
 
 	config.QPS = kubeAPIQPS
 	config.Burst = kubeAPIBurst
+	return config
+})
 
+// kubeClient returns the client for the Kubernetes API, which is created on first use.
+var kubeClient = sync.OnceValue(func() kubernetes.Interface {
+	config := rest.CopyConfig(kubeConfig())
 	config.ContentType = runtime.ContentTypeProtobuf
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		klog.Fatalf("Failed to create client: %v", err)
 	}
+	return clientset
+})
+
+func GetPVCLister() corev1.PersistentVolumeClaimLister {
+	if !pvcAnnotations {
+		return nil
+	}
 
-	factory := informers.NewSharedInformerFactory(clientset, resyncPeriod(minResyncPeriod))
+	factory := informers.NewSharedInformerFactory(kubeClient(), resyncPeriod(minResyncPeriod))
 	ctx := context.TODO()
 	pvcInformer := factory.Core().V1().PersistentVolumeClaims().Informer()
 	go pvcInformer.Run(ctx.Done())
@@ -148,11 +206,216 @@ func GetPVCLister() corev1.PersistentVolumeClaimLister {
 		klog.Fatal("Error syncing PVC informer cache")
 	}
 
-	klog.Info("Successfully created PVC Annotations Lister")
+	klog.InfoS("Successfully created PVC Annotations Lister")
 
 	return factory.Core().V1().PersistentVolumeClaims().Lister()
 }
 
+// GetPVLister returns a lister of synced PVs to check whether a volume is still referenced before it is adopted, or nil
+// if volume adoption is disabled.
+func GetPVLister() corev1.PersistentVolumeLister {
+	if !volumeAdoption {
+		return nil
+	}
+
+	factory := informers.NewSharedInformerFactory(kubeClient(), resyncPeriod(minResyncPeriod))
+	ctx := context.TODO()
+	pvInformer := factory.Core().V1().PersistentVolumes().Informer()
+	go pvInformer.Run(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), pvInformer.HasSynced) {
+		klog.Fatal("Error syncing PV informer cache")
+	}
+
+	klog.InfoS("Successfully created PV Lister")
+
+	return factory.Core().V1().PersistentVolumes().Lister()
+}
+
+// GetBackupScheduleInformers returns a started informer factory with synced PVC, PV and StorageClass informers
+// for the backup scheduler, or nil if backup schedules are disabled.
+func GetBackupScheduleInformers() informers.SharedInformerFactory {
+	if !backupSchedules {
+		return nil
+	}
+
+	factory := informers.NewSharedInformerFactory(kubeClient(), resyncPeriod(minResyncPeriod))
+	factory.Core().V1().PersistentVolumeClaims().Informer()
+	factory.Core().V1().PersistentVolumes().Informer()
+	factory.Storage().V1().StorageClasses().Informer()
+
+	ctx := context.TODO()
+	factory.Start(ctx.Done())
+	for informerType, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			klog.Fatalf("Error syncing %v informer cache", informerType)
+		}
+	}
+
+	klog.InfoS("Successfully created backup schedule informers")
+
+	return factory
+}
+
+// NodeFailover configures detaching the volumes of failed nodes.
+type NodeFailover struct {
+	// Client taints the failed nodes.
+	Client kubernetes.Interface
+	// Informers provide the Nodes, PVs and VolumeAttachments.
+	Informers informers.SharedInformerFactory
+	// Timeout is the time a node must not be ready before its volumes are detached.
+	Timeout time.Duration
+}
+
+// GetNodeFailover returns the node failover options with started and synced informers, or nil if node failover is
+// disabled.
+func GetNodeFailover() *NodeFailover {
+	if nodeFailoverTimeout <= 0 {
+		return nil
+	}
+
+	factory := informers.NewSharedInformerFactory(kubeClient(), resyncPeriod(minResyncPeriod))
+	factory.Core().V1().Nodes().Informer()
+	factory.Core().V1().PersistentVolumes().Informer()
+	factory.Storage().V1().VolumeAttachments().Informer()
+
+	ctx := context.TODO()
+	factory.Start(ctx.Done())
+	for informerType, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			klog.Fatalf("Error syncing %v informer cache", informerType)
+		}
+	}
+
+	klog.InfoS("Successfully created node failover informers")
+
+	return &NodeFailover{Client: kubeClient(), Informers: factory, Timeout: nodeFailoverTimeout}
+}
+
+// AttachmentCapacity configures checking the volumes attached to the servers of nodes against the volume limit the
+// nodes advertise.
+type AttachmentCapacity struct {
+	// Informers provide the Nodes and CSINodes.
+	Informers informers.SharedInformerFactory
+	// Threshold is the fraction of the volume limit from which a node is near its capacity.
+	Threshold float64
+}
+
+// GetAttachmentCapacity returns the attachment capacity options with started and synced informers, or nil if the
+// check is disabled.
+func GetAttachmentCapacity() *AttachmentCapacity {
+	if attachmentCapacityThreshold <= 0 {
+		return nil
+	}
+
+	factory := informers.NewSharedInformerFactory(kubeClient(), resyncPeriod(minResyncPeriod))
+	factory.Core().V1().Nodes().Informer()
+	factory.Storage().V1().CSINodes().Informer()
+
+	ctx := context.TODO()
+	factory.Start(ctx.Done())
+	for informerType, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			klog.Fatalf("Error syncing %v informer cache", informerType)
+		}
+	}
+
+	klog.InfoS("Successfully created attachment capacity informers")
+
+	return &AttachmentCapacity{Informers: factory, Threshold: attachmentCapacityThreshold}
+}
+
+// RunFunc runs the control loops of the controller service until ctx is canceled.
+type RunFunc func(ctx context.Context)
+
+// LeaderElectionFunc runs run while this replica holds the leader election lease and cancels its context once the
+// lease is lost. It blocks until ctx is canceled.
+type LeaderElectionFunc func(ctx context.Context, run RunFunc)
+
+// GetLeaderElectionFunc returns a function that runs the control loops only in the leader, or nil if leader election
+// is disabled. Other than the leader election of the sidecars, losing the lease doesn't exit the process, because
+// all replicas keep serving CSI calls. The replica campaigns for the lease again instead.
+func GetLeaderElectionFunc() LeaderElectionFunc {
+	if !leaderElection {
+		return nil
+	}
+
+	id, err := os.Hostname()
+	if err != nil {
+		klog.Fatalf("Failed to get hostname for leader election: %v", err)
+	}
+	id = id + "_" + string(uuid.NewUUID())
+
+	lock, err := resourcelock.New(resourcelock.LeasesResourceLock, leaderElectionNamespace, leaderElectionName,
+		kubeClient().CoreV1(), kubeClient().CoordinationV1(), resourcelock.ResourceLockConfig{Identity: id})
+	if err != nil {
+		klog.Fatalf("Failed to create leader election lock: %v", err)
+	}
+
+	klog.InfoS("Successfully created leader election", "namespace", leaderElectionNamespace, "name", leaderElectionName, "identity", id)
+
+	return func(ctx context.Context, run RunFunc) {
+		for ctx.Err() == nil {
+			leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+				Lock:            lock,
+				LeaseDuration:   leaderElectionLeaseDuration,
+				RenewDeadline:   leaderElectionRenewDeadline,
+				RetryPeriod:     leaderElectionRetryPeriod,
+				ReleaseOnCancel: true,
+				Name:            leaderElectionName,
+				Callbacks: leaderelection.LeaderCallbacks{
+					OnStartedLeading: func(ctx context.Context) {
+						klog.InfoS("Acquired leadership, starting control loops", "identity", id)
+						run(ctx)
+					},
+					OnStoppedLeading: func() {
+						klog.InfoS("Lost leadership, stopped control loops", "identity", id)
+					},
+				},
+			})
+		}
+	}
+}
+
+// VolumeSnapshotAnnotationsFunc returns the annotations of the VolumeSnapshot with the given namespace and name.
+type VolumeSnapshotAnnotationsFunc func(ctx context.Context, namespace, name string) (map[string]string, error)
+
+var volumeSnapshotResource = schema.GroupVersionResource{Group: "snapshot.storage.k8s.io", Version: "v1", Resource: "volumesnapshots"}
+
+// GetVolumeSnapshotAnnotationsFunc returns a function reading the annotations of VolumeSnapshots from the API server,
+// or nil if VolumeSnapshot annotations are disabled. The dynamic client avoids depending on the snapshot API types.
+func GetVolumeSnapshotAnnotationsFunc() VolumeSnapshotAnnotationsFunc {
+	if !snapshotAnnotations {
+		return nil
+	}
+
+	client, err := dynamic.NewForConfig(kubeConfig())
+	if err != nil {
+		klog.Fatalf("Failed to create dynamic client: %v", err)
+	}
+
+	klog.InfoS("Successfully created VolumeSnapshot annotations getter")
+
+	return func(ctx context.Context, namespace, name string) (map[string]string, error) {
+		snapshot, err := client.Resource(volumeSnapshotResource).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return snapshot.GetAnnotations(), nil
+	}
+}
+
+// GetEventRecorder returns a recorder for events on the PVCs of volumes, or nil if events are disabled.
+func GetEventRecorder(component string) record.EventRecorder {
+	if !events {
+		return nil
+	}
+
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartStructuredLogging(0)
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeClient().CoreV1().Events("")})
+	return eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: component})
+}
+
 // GetPVCAnnotations returns PVC annotations for the given PVC name and
 // namespace stored in the params map.
 func GetPVCAnnotations(pvcLister corev1.PersistentVolumeClaimLister, params map[string]string) map[string]string {
@@ -163,13 +426,13 @@ func GetPVCAnnotations(pvcLister corev1.PersistentVolumeClaimLister, params map[
 	namespace := params[PvcNamespaceKey]
 	pvcName := params[PvcNameKey]
 	if namespace == "" || pvcName == "" {
-		klog.Errorf("Invalid namespace or PVC name (%s/%s), check whether the --extra-create-metadata flag is set in csi-provisioner", namespace, pvcName)
+		klog.ErrorS(nil, "Invalid namespace or PVC name, check whether the --extra-create-metadata flag is set in csi-provisioner", "pvc", klog.KRef(namespace, pvcName))
 		return nil
 	}
 
 	pvc, err := pvcLister.PersistentVolumeClaims(namespace).Get(pvcName)
 	if err != nil {
-		klog.Errorf("Failed to get PVC %s/%s: %v", namespace, pvcName, err)
+		klog.ErrorS(err, "Failed to get PVC", "pvc", klog.KRef(namespace, pvcName))
 		return nil
 	}
 
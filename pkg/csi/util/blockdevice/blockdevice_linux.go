@@ -27,6 +27,7 @@ import (
 	"golang.org/x/sys/unix"
 
 	"k8s.io/klog/v2"
+	"k8s.io/utils/exec"
 )
 
 // findBlockDeviceRescanPath Find the underlaying disk for a linked path such as /dev/disk/by-path/XXXX or /dev/mapper/XXXX
@@ -40,12 +41,86 @@ func findBlockDeviceRescanPath(path string) (string, error) {
 	// return just the last part
 	parts := strings.Split(devicePath, "/")
 	if len(parts) == 3 && strings.HasPrefix(parts[1], "dev") {
-		base := "/sys/block"
-		return filepath.EvalSymlinks(filepath.Join(base, parts[2], "device", "rescan"))
+		return filepath.EvalSymlinks(filepath.Join(sysBlockDir, parts[2], "device", "rescan"))
 	}
 	return "", fmt.Errorf("illegal path for device %s", devicePath)
 }
 
+// findBlockDeviceRescanPaths returns the rescan paths of the device on path. A multipath device is rescanned through
+// all of its paths, so its name is returned as well to resize the map afterwards.
+func findBlockDeviceRescanPaths(path string) (string, []string, error) {
+	multipathName, paths := multipathPathsAt(sysBlockDir, path)
+	if multipathName == "" {
+		rescanPath, err := findBlockDeviceRescanPath(path)
+		if err != nil {
+			return "", nil, err
+		}
+		return "", []string{rescanPath}, nil
+	}
+	if len(paths) == 0 {
+		return "", nil, fmt.Errorf("multipath device %s has no paths", path)
+	}
+
+	rescanPaths := make([]string, 0, len(paths))
+	for _, p := range paths {
+		rescanPath, err := findBlockDeviceRescanPath(filepath.Join(devDir, p))
+		if err != nil {
+			return "", nil, err
+		}
+		rescanPaths = append(rescanPaths, rescanPath)
+	}
+	return multipathName, rescanPaths, nil
+}
+
+// rescan rescans the device through its rescan paths and resizes the multipath map, if any.
+func rescan(multipathName string, rescanPaths []string) error {
+	for _, rescanPath := range rescanPaths {
+		if err := triggerRescan(rescanPath); err != nil {
+			return err
+		}
+	}
+	if multipathName == "" {
+		return nil
+	}
+
+	klog.V(4).InfoS("Resizing multipath device", "name", multipathName)
+	// multipathd accepts both the map name and the dm-N name.
+	if output, err := exec.New().Command("multipathd", "resize", "map", multipathName).CombinedOutput(); err != nil {
+		klog.ErrorS(err, "Error resizing multipath device", "name", multipathName, "output", string(output))
+		return err
+	}
+	return nil
+}
+
+// GetBlockDeviceSerial returns the serial reported by the kernel for the block device on the given path.
+// virtio-blk devices expose it as /sys/block/<dev>/serial, SCSI and NVMe devices as /sys/block/<dev>/device/serial.
+// An empty serial without an error is returned if the device does not report one.
+func GetBlockDeviceSerial(path string) (string, error) {
+	devicePath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	parts := strings.Split(devicePath, "/")
+	if len(parts) != 3 || !strings.HasPrefix(parts[1], "dev") {
+		return "", fmt.Errorf("illegal path for device %s", devicePath)
+	}
+
+	for _, serialPath := range []string{
+		filepath.Join("/sys/block", parts[2], "serial"),
+		filepath.Join("/sys/block", parts[2], "device", "serial"),
+	} {
+		serial, err := os.ReadFile(serialPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", fmt.Errorf("failed to read serial of %s: %v", devicePath, err)
+		}
+		return strings.TrimSpace(string(serial)), nil
+	}
+	return "", nil
+}
+
 // IsBlockDevice checks whether device on the path is a block device
 func IsBlockDevice(path string) (bool, error) {
 	var stat unix.Stat_t
@@ -72,14 +147,14 @@ func GetBlockDeviceSize(path string) (int64, error) {
 }
 
 func checkBlockDeviceSize(devicePath, deviceMountPath string, newSize int64) error {
-	klog.V(4).Infof("Detecting %q volume size", deviceMountPath)
+	klog.V(4).InfoS("Detecting volume size", "path", deviceMountPath)
 	size, err := GetBlockDeviceSize(devicePath)
 	size = util.RoundUpSize(size, util.GIBIBYTE)
 	if err != nil {
 		return err
 	}
 
-	klog.V(3).Infof("Detected %q volume size: %d", deviceMountPath, size)
+	klog.V(3).InfoS("Detected volume size", "path", deviceMountPath, "size", size)
 
 	if size < newSize {
 		return fmt.Errorf("current volume size is less than expected one: actual: %d, expected: %d", size, newSize)
@@ -89,10 +164,10 @@ func checkBlockDeviceSize(devicePath, deviceMountPath string, newSize int64) err
 }
 
 func triggerRescan(blockDeviceRescanPath string) error {
-	klog.V(4).Infof("Rescanning %q block device geometry", blockDeviceRescanPath)
+	klog.V(4).InfoS("Rescanning block device geometry", "path", blockDeviceRescanPath)
 	err := os.WriteFile(blockDeviceRescanPath, []byte{'1'}, 0666)
 	if err != nil {
-		klog.Errorf("Error rescanning new block device geometry: %v", err)
+		klog.ErrorS(err, "Error rescanning new block device geometry")
 		return err
 	}
 	return nil
@@ -100,7 +175,7 @@ func triggerRescan(blockDeviceRescanPath string) error {
 
 func RescanBlockDeviceGeometry(devicePath, deviceMountPath string, newSize int64) error {
 	if newSize == 0 {
-		klog.Error("newSize is empty, skipping the block device rescan")
+		klog.ErrorS(nil, "newSize is empty, skipping the block device rescan")
 		return nil
 	}
 
@@ -111,15 +186,15 @@ func RescanBlockDeviceGeometry(devicePath, deviceMountPath string, newSize int64
 	}
 
 	// don't fail if resolving doesn't work
-	blockDeviceRescanPath, err := findBlockDeviceRescanPath(devicePath)
+	multipathName, blockDeviceRescanPaths, err := findBlockDeviceRescanPaths(devicePath)
 	if err != nil {
-		klog.Errorf("Error resolving block device path from %q: %v", devicePath, err)
+		klog.ErrorS(err, "Error resolving block device path", "devicePath", devicePath)
 		// no need to run checkBlockDeviceSize second time here, return the saved error
 		return bdSizeErr
 	}
 
-	klog.V(3).Infof("Resolved block device path from %q to %q", devicePath, blockDeviceRescanPath)
-	err = triggerRescan(blockDeviceRescanPath)
+	klog.V(3).InfoS("Resolved block device path", "devicePath", devicePath, "paths", blockDeviceRescanPaths)
+	err = rescan(multipathName, blockDeviceRescanPaths)
 	if err != nil {
 		// no need to run checkBlockDeviceSize second time here, return the saved error
 		return bdSizeErr
@@ -129,13 +204,13 @@ func RescanBlockDeviceGeometry(devicePath, deviceMountPath string, newSize int64
 }
 
 func RescanDevice(devicePath string) error {
-	blockDeviceRescanPath, err := findBlockDeviceRescanPath(devicePath)
+	multipathName, blockDeviceRescanPaths, err := findBlockDeviceRescanPaths(devicePath)
 	if err != nil {
 		return fmt.Errorf("device does not have rescan path %s", devicePath)
 	}
 
-	klog.V(3).Infof("Resolved block device path from %q to %q", devicePath, blockDeviceRescanPath)
-	err = triggerRescan(blockDeviceRescanPath)
+	klog.V(3).InfoS("Resolved block device path", "devicePath", devicePath, "paths", blockDeviceRescanPaths)
+	err = rescan(multipathName, blockDeviceRescanPaths)
 	if err != nil {
 		return fmt.Errorf("error rescanning new block device geometry %s", devicePath)
 	}
@@ -32,6 +32,11 @@ func GetBlockDeviceSize(path string) (int64, error) {
 	return -1, errors.New("GetBlockDeviceSize is not implemented for this OS")
 }
 
+//nolint:revive // The unused parameters are here by design
+func GetBlockDeviceSerial(path string) (string, error) {
+	return "", errors.New("GetBlockDeviceSerial is not implemented for this OS")
+}
+
 //nolint:revive // The unused parameters are here by design
 func RescanBlockDeviceGeometry(devicePath, deviceMountPath string, newSize int64) error {
 	return errors.New("RescanBlockDeviceGeometry is not implemented for this OS")
@@ -0,0 +1,85 @@
+package blockdevice
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	sysBlockDir = "/sys/block"
+	devDir      = "/dev"
+
+	// multipathUUIDPrefix is the prefix of the device mapper UUID of multipath maps.
+	multipathUUIDPrefix = "mpath-"
+)
+
+// MultipathDevice returns the device mapper multipath device that holds the device on path, e.g. /dev/mapper/mpatha
+// for /dev/sda. An empty string is returned if the device isn't part of a multipath map.
+//
+// Mounting the multipath device instead of one of its paths keeps the mount working after a path failover.
+func MultipathDevice(path string) string {
+	return multipathDeviceAt(sysBlockDir, devDir, path)
+}
+
+func multipathDeviceAt(sysBlockDir, devDir, path string) string {
+	name, err := deviceName(path)
+	if err != nil {
+		klog.V(5).InfoS("Unable to resolve device for multipath detection", "path", path, "err", err)
+		return ""
+	}
+	holders, err := os.ReadDir(filepath.Join(sysBlockDir, name, "holders"))
+	if err != nil {
+		return ""
+	}
+	for _, holder := range holders {
+		if !isMultipath(sysBlockDir, holder.Name()) {
+			continue
+		}
+		multipathDevice := filepath.Join(devDir, holder.Name())
+		// The name in /dev/mapper is stable across reboots, unlike the dm-N name.
+		if mapName, err := os.ReadFile(filepath.Join(sysBlockDir, holder.Name(), "dm", "name")); err == nil && len(strings.TrimSpace(string(mapName))) > 0 {
+			multipathDevice = filepath.Join(devDir, "mapper", strings.TrimSpace(string(mapName)))
+		}
+		klog.V(4).InfoS("Found multipath device", "path", path, "multipathDevice", multipathDevice)
+		return multipathDevice
+	}
+	return ""
+}
+
+// multipathPathsAt returns the names of the paths of the multipath device on path, e.g. sda and sdb for
+// /dev/mapper/mpatha, and the name of the device mapper device. No paths are returned if the device isn't a
+// multipath device.
+func multipathPathsAt(sysBlockDir, path string) (string, []string) {
+	name, err := deviceName(path)
+	if err != nil || !isMultipath(sysBlockDir, name) {
+		return "", nil
+	}
+	slaves, err := os.ReadDir(filepath.Join(sysBlockDir, name, "slaves"))
+	if err != nil {
+		klog.V(4).InfoS("Unable to list the paths of multipath device", "path", path, "err", err)
+		return name, nil
+	}
+	paths := make([]string, 0, len(slaves))
+	for _, slave := range slaves {
+		paths = append(paths, slave.Name())
+	}
+	return name, paths
+}
+
+// isMultipath reports whether the device mapper device name in sysBlockDir is a multipath map.
+func isMultipath(sysBlockDir, name string) bool {
+	uuid, err := os.ReadFile(filepath.Join(sysBlockDir, name, "dm", "uuid"))
+	return err == nil && strings.HasPrefix(strings.TrimSpace(string(uuid)), multipathUUIDPrefix)
+}
+
+// deviceName returns the kernel name of the block device on path, e.g. sda for /dev/disk/by-id/scsi-...
+func deviceName(path string) (string, error) {
+	devicePath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Base(devicePath), nil
+}
@@ -0,0 +1,111 @@
+package blockdevice
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Multipath", func() {
+	var sysBlockDir, devDir string
+
+	BeforeEach(func() {
+		root := GinkgoT().TempDir()
+		sysBlockDir = filepath.Join(root, "sys", "block")
+		devDir = filepath.Join(root, "dev")
+		Expect(os.MkdirAll(filepath.Join(devDir, "disk", "by-id"), 0o755)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(devDir, "mapper"), 0o755)).To(Succeed())
+	})
+
+	writeFile := func(path, content string) {
+		Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(content+"\n"), 0o644)).To(Succeed())
+	}
+
+	// device creates the device node and its sysfs directory, linked into holders and slaves like the kernel does.
+	device := func(name string, holders ...string) {
+		writeFile(filepath.Join(devDir, name), "")
+		Expect(os.MkdirAll(filepath.Join(sysBlockDir, name, "holders"), 0o755)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(sysBlockDir, name, "slaves"), 0o755)).To(Succeed())
+		for _, holder := range holders {
+			Expect(os.Symlink(filepath.Join(sysBlockDir, holder), filepath.Join(sysBlockDir, name, "holders", holder))).To(Succeed())
+			Expect(os.Symlink(filepath.Join(sysBlockDir, name), filepath.Join(sysBlockDir, holder, "slaves", name))).To(Succeed())
+		}
+	}
+
+	dm := func(name, mapName, uuid string) {
+		device(name)
+		writeFile(filepath.Join(sysBlockDir, name, "dm", "name"), mapName)
+		writeFile(filepath.Join(sysBlockDir, name, "dm", "uuid"), uuid)
+		Expect(os.Symlink("../"+name, filepath.Join(devDir, "mapper", mapName))).To(Succeed())
+	}
+
+	byID := func(name, target string) string {
+		path := filepath.Join(devDir, "disk", "by-id", name)
+		Expect(os.Symlink("../../"+target, path)).To(Succeed())
+		return path
+	}
+
+	Describe("multipathDeviceAt", func() {
+		It("should return the multipath map holding the device", func() {
+			dm("dm-0", "mpatha", "mpath-3600a098038303053453f463045727a4d")
+			device("sda", "dm-0")
+			device("sdb", "dm-0")
+
+			path := byID("scsi-0QEMU_QEMU_HARDDISK_4a1e4c3e-5d2f-4a8b-9", "sda")
+			Expect(multipathDeviceAt(sysBlockDir, devDir, path)).To(Equal(filepath.Join(devDir, "mapper", "mpatha")))
+		})
+
+		It("should fall back to the dm-N name if the map has no name", func() {
+			dm("dm-0", "mpatha", "mpath-3600a098038303053453f463045727a4d")
+			Expect(os.Remove(filepath.Join(sysBlockDir, "dm-0", "dm", "name"))).To(Succeed())
+			device("sda", "dm-0")
+
+			Expect(multipathDeviceAt(sysBlockDir, devDir, filepath.Join(devDir, "sda"))).To(Equal(filepath.Join(devDir, "dm-0")))
+		})
+
+		It("should ignore device mapper devices that aren't multipath maps", func() {
+			dm("dm-0", "vg-lv", "LVM-Yp3o6V1e2f")
+			device("sda", "dm-0")
+
+			Expect(multipathDeviceAt(sysBlockDir, devDir, filepath.Join(devDir, "sda"))).To(BeEmpty())
+		})
+
+		It("should return nothing for devices without holders", func() {
+			device("vdb")
+
+			Expect(multipathDeviceAt(sysBlockDir, devDir, byID("virtio-4a1e4c3e-5d2f-4a8b-9", "vdb"))).To(BeEmpty())
+		})
+
+		It("should return nothing for devices that don't exist", func() {
+			Expect(multipathDeviceAt(sysBlockDir, devDir, filepath.Join(devDir, "vdc"))).To(BeEmpty())
+		})
+	})
+
+	Describe("multipathPathsAt", func() {
+		It("should return the paths of a multipath device", func() {
+			dm("dm-0", "mpatha", "mpath-3600a098038303053453f463045727a4d")
+			device("sda", "dm-0")
+			device("sdb", "dm-0")
+
+			name, paths := multipathPathsAt(sysBlockDir, filepath.Join(devDir, "mapper", "mpatha"))
+			Expect(name).To(Equal("dm-0"))
+			Expect(paths).To(ConsistOf("sda", "sdb"))
+		})
+
+		It("should return nothing for other devices", func() {
+			dm("dm-0", "vg-lv", "LVM-Yp3o6V1e2f")
+			device("sda", "dm-0")
+
+			name, paths := multipathPathsAt(sysBlockDir, filepath.Join(devDir, "mapper", "vg-lv"))
+			Expect(name).To(BeEmpty())
+			Expect(paths).To(BeEmpty())
+
+			name, paths = multipathPathsAt(sysBlockDir, filepath.Join(devDir, "sda"))
+			Expect(name).To(BeEmpty())
+			Expect(paths).To(BeEmpty())
+		})
+	})
+})
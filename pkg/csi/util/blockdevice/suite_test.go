@@ -0,0 +1,13 @@
+package blockdevice
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestBlockDevice(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Block Device Suite")
+}
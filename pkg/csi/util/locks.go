@@ -0,0 +1,39 @@
+package util
+
+import (
+	"sync"
+)
+
+// OperationLocks serializes operations on the same key, e.g. the name of a volume that is being created.
+// Unlike a mutex it never blocks, callers are expected to return codes.Aborted if a lock can't be acquired
+// and let the sidecar retry.
+type OperationLocks struct {
+	mux   sync.Mutex
+	locks map[string]struct{}
+}
+
+func NewOperationLocks() *OperationLocks {
+	return &OperationLocks{
+		locks: make(map[string]struct{}),
+	}
+}
+
+// TryAcquire locks the key and returns true, or returns false if an operation for the key is already in progress.
+func (l *OperationLocks) TryAcquire(key string) bool {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if _, ok := l.locks[key]; ok {
+		return false
+	}
+	l.locks[key] = struct{}{}
+	return true
+}
+
+// Release unlocks the key.
+func (l *OperationLocks) Release(key string) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	delete(l.locks, key)
+}
@@ -0,0 +1,19 @@
+package util
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OperationLocks", func() {
+	It("should only allow one operation per key", func() {
+		locks := NewOperationLocks()
+
+		Expect(locks.TryAcquire("volume-1")).To(BeTrue())
+		Expect(locks.TryAcquire("volume-1")).To(BeFalse())
+		Expect(locks.TryAcquire("volume-2")).To(BeTrue())
+
+		locks.Release("volume-1")
+		Expect(locks.TryAcquire("volume-1")).To(BeTrue())
+	})
+})
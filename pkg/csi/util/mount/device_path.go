@@ -0,0 +1,125 @@
+package mount
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	diskByIDDir = "/dev/disk/by-id"
+	sysBlockDir = "/sys/block"
+	devDir      = "/dev"
+
+	// serialLength is the number of characters of the volume ID hypervisors put into the serial of virtio-blk and
+	// NVMe devices.
+	serialLength = 20
+)
+
+// SerialMatchesVolume reports whether serial identifies volumeID.
+// Hypervisors truncate the serial to 20 characters (virtio, NVMe) or strip the dashes (wwn),
+// so the comparison is done on the dash-less, lower-cased forms.
+func SerialMatchesVolume(serial, volumeID string) bool {
+	normalize := func(s string) string {
+		return strings.ToLower(strings.ReplaceAll(s, "-", ""))
+	}
+	prefix := normalize(volumeID[:min(len(volumeID), serialLength)])
+	return prefix != "" && strings.Contains(normalize(serial), prefix)
+}
+
+// deviceCandidates returns the names udev creates in /dev/disk/by-id for the volume, ordered by preference.
+// The full volume ID is preferred over the truncated one, since it can't collide with another volume.
+func deviceCandidates(volumeID string) []string {
+	short := volumeID[:min(len(volumeID), serialLength)]
+	return []string{
+		// KVM virtio-blk (x86 flavors)
+		"virtio-" + volumeID,
+		"virtio-" + short,
+		// KVM virtio-scsi
+		"scsi-0QEMU_QEMU_HARDDISK_" + volumeID,
+		"scsi-0QEMU_QEMU_HARDDISK_" + short,
+		// KVM NVMe (arm64 flavors), the NVMe serial is limited to 20 characters
+		"nvme-QEMU_NVMe_Ctrl_" + short,
+		// ESXi
+		"wwn-0x" + strings.ReplaceAll(volumeID, "-", ""),
+	}
+}
+
+// findDevicePath looks up the device of the volume, trying in order:
+//  1. the well-known /dev/disk/by-id names of the supported hypervisors and bus types,
+//  2. any other whole-disk /dev/disk/by-id name ending in the serial, to support further vendors and models,
+//  3. the serials the kernel reports in /sys/block, for images without udev rules for the bus type.
+//
+// An empty string is returned if the device isn't found.
+func findDevicePath(byIDDir, sysBlockDir, devDir, volumeID string) string {
+	entries, err := os.ReadDir(byIDDir)
+	if err != nil {
+		klog.V(4).InfoS("ReadDir failed", "dir", byIDDir, "err", err)
+	}
+	names := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		names[entry.Name()] = true
+	}
+
+	for _, candidate := range deviceCandidates(volumeID) {
+		if names[candidate] {
+			devicePath := filepath.Join(byIDDir, candidate)
+			klog.V(4).InfoS("Found attached disk", "name", candidate, "devicePath", devicePath)
+			return devicePath
+		}
+	}
+
+	short := volumeID[:min(len(volumeID), serialLength)]
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.Contains(name, "-part") {
+			continue
+		}
+		if strings.HasSuffix(name, "_"+volumeID) || strings.HasSuffix(name, "_"+short) {
+			devicePath := filepath.Join(byIDDir, name)
+			klog.V(4).InfoS("Found attached disk by serial suffix", "name", name, "devicePath", devicePath)
+			return devicePath
+		}
+	}
+
+	disks, err := os.ReadDir(sysBlockDir)
+	if err != nil {
+		klog.V(4).InfoS("ReadDir failed", "dir", sysBlockDir, "err", err)
+		return ""
+	}
+	for _, disk := range disks {
+		serial, err := readSysBlockSerial(sysBlockDir, disk.Name())
+		if err != nil {
+			klog.V(5).InfoS("Unable to read serial of block device", "name", disk.Name(), "err", err)
+			continue
+		}
+		if serial != "" && SerialMatchesVolume(serial, volumeID) {
+			devicePath := filepath.Join(devDir, disk.Name())
+			klog.V(4).InfoS("Found attached disk by kernel serial", "name", disk.Name(), "serial", serial, "devicePath", devicePath)
+			return devicePath
+		}
+	}
+	return ""
+}
+
+// readSysBlockSerial returns the serial of the block device name in sysBlockDir.
+// virtio-blk devices expose it as <name>/serial, SCSI and NVMe devices as <name>/device/serial.
+func readSysBlockSerial(sysBlockDir, name string) (string, error) {
+	for _, serialPath := range []string{
+		filepath.Join(sysBlockDir, name, "serial"),
+		filepath.Join(sysBlockDir, name, "device", "serial"),
+	} {
+		serial, err := os.ReadFile(serialPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", fmt.Errorf("failed to read serial of %s: %w", name, err)
+		}
+		return strings.TrimSpace(string(serial)), nil
+	}
+	return "", nil
+}
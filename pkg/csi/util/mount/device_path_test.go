@@ -0,0 +1,98 @@
+package mount
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Device path", func() {
+	const volumeID = "4a1e4c3e-5d2f-4a8b-9c7d-0e1f2a3b4c5d"
+
+	var byIDDir, sysBlockDir string
+
+	BeforeEach(func() {
+		root := GinkgoT().TempDir()
+		byIDDir = filepath.Join(root, "by-id")
+		sysBlockDir = filepath.Join(root, "sys", "block")
+		mustMkdirAll(byIDDir)
+		mustMkdirAll(sysBlockDir)
+	})
+
+	link := func(names ...string) {
+		for _, name := range names {
+			Expect(os.Symlink("../../vdb", filepath.Join(byIDDir, name))).To(Succeed())
+		}
+	}
+
+	sysSerial := func(disk, file, serial string) {
+		path := filepath.Join(sysBlockDir, disk, file)
+		mustMkdirAll(filepath.Dir(path))
+		Expect(os.WriteFile(path, []byte(serial+"\n"), 0o644)).To(Succeed())
+	}
+
+	find := func() string {
+		return findDevicePath(byIDDir, sysBlockDir, "/dev", volumeID)
+	}
+
+	DescribeTable("should find the device by its /dev/disk/by-id name",
+		func(name string) {
+			link("ata-some-other-disk", name, name+"-part1")
+			Expect(find()).To(Equal(filepath.Join(byIDDir, name)))
+		},
+		Entry("virtio-blk with truncated serial", "virtio-4a1e4c3e-5d2f-4a8b-9"),
+		Entry("virtio-blk with full serial", "virtio-"+volumeID),
+		Entry("virtio-scsi with truncated serial", "scsi-0QEMU_QEMU_HARDDISK_4a1e4c3e-5d2f-4a8b-9"),
+		Entry("virtio-scsi with full serial", "scsi-0QEMU_QEMU_HARDDISK_"+volumeID),
+		Entry("QEMU NVMe", "nvme-QEMU_NVMe_Ctrl_4a1e4c3e-5d2f-4a8b-9"),
+		Entry("wwn", "wwn-0x4a1e4c3e5d2f4a8b9c7d0e1f2a3b4c5d"),
+		Entry("NVMe of another vendor", "nvme-Vendor_Model_4a1e4c3e-5d2f-4a8b-9"),
+		Entry("SCSI of another vendor", "scsi-SQEMU_QEMU_HARDDISK_"+volumeID),
+	)
+
+	It("should prefer the well-known names over names matched by suffix", func() {
+		link("nvme-Vendor_Model_4a1e4c3e-5d2f-4a8b-9", "virtio-4a1e4c3e-5d2f-4a8b-9")
+		Expect(find()).To(Equal(filepath.Join(byIDDir, "virtio-4a1e4c3e-5d2f-4a8b-9")))
+	})
+
+	It("should prefer the full volume ID over the truncated one", func() {
+		link("virtio-4a1e4c3e-5d2f-4a8b-9", "virtio-"+volumeID)
+		Expect(find()).To(Equal(filepath.Join(byIDDir, "virtio-"+volumeID)))
+	})
+
+	It("should not match partitions", func() {
+		link("nvme-Vendor_Model_4a1e4c3e-5d2f-4a8b-9-part1")
+		Expect(find()).To(BeEmpty())
+	})
+
+	It("should fall back to the virtio-blk serial reported by the kernel", func() {
+		link("nvme-eui.0123456789abcdef")
+		sysSerial("vda", "serial", "9f8e7d6c-5b4a-4392-8")
+		sysSerial("vdb", "serial", "4a1e4c3e-5d2f-4a8b-9")
+		Expect(find()).To(Equal("/dev/vdb"))
+	})
+
+	It("should fall back to the NVMe serial reported by the kernel", func() {
+		sysSerial("nvme0n1", "device/serial", "4a1e4c3e-5d2f-4a8b-9")
+		Expect(find()).To(Equal("/dev/nvme0n1"))
+	})
+
+	It("should return nothing if the device isn't attached", func() {
+		link("virtio-9f8e7d6c-5b4a-4392-8")
+		sysSerial("vda", "serial", "9f8e7d6c-5b4a-4392-8")
+		Expect(find()).To(BeEmpty())
+	})
+})
+
+var _ = DescribeTable("SerialMatchesVolume",
+	func(serial string, expected bool) {
+		Expect(SerialMatchesVolume(serial, "4a1e4c3e-5d2f-4a8b-9c7d-0e1f2a3b4c5d")).To(Equal(expected))
+	},
+	Entry("full volume ID", "4a1e4c3e-5d2f-4a8b-9c7d-0e1f2a3b4c5d", true),
+	Entry("virtio serial truncated to 20 characters", "4a1e4c3e-5d2f-4a8b-9", true),
+	Entry("wwn without dashes", "0x4a1e4c3e5d2f4a8b9c7d0e1f2a3b4c5d", true),
+	Entry("upper-cased serial", "4A1E4C3E-5D2F-4A8B-9", true),
+	Entry("serial of another volume", "9f8e7d6c-5b4a-4392-8", false),
+)
@@ -19,8 +19,6 @@ package mount
 import (
 	"fmt"
 	"os"
-	"path"
-	"slices"
 	"strings"
 	"time"
 
@@ -45,12 +43,16 @@ type IMount interface {
 	Mounter() *mount.SafeFormatAndMount
 	ScanForAttach(devicePath string) error
 	GetDevicePath(volumeID string) (string, error)
+	GetDeviceSerial(devicePath string) (string, error)
 	IsLikelyNotMountPointAttach(targetpath string) (bool, error)
 	UnmountPath(mountPath string) error
 	MakeFile(pathname string) error
 	MakeDir(pathname string) error
 	GetDeviceStats(path string) (*DeviceStats, error)
 	GetMountFs(path string) ([]byte, error)
+	IsReadOnlyRemounted(path string) (bool, error)
+	RemountReadWrite(path string) error
+	SetVolumeGroup(path string, gid int64) error
 }
 
 type DeviceStats struct {
@@ -113,7 +115,7 @@ func probeVolume() error {
 	cmd := executor.Command("udevadm", args...)
 	_, err := cmd.CombinedOutput()
 	if err != nil {
-		klog.V(3).Infof("error running udevadm trigger %v\n", err)
+		klog.V(3).InfoS("Error running udevadm trigger", "err", err)
 		return err
 	}
 	return nil
@@ -136,7 +138,7 @@ func (m *Mount) GetDevicePath(volumeID string) (string, error) {
 		// see issue https://github.com/kubernetes/cloud-provider-openstack/issues/705
 		if err := probeVolume(); err != nil {
 			// log the error, but continue. Might not happen in edge cases
-			klog.V(5).Infof("Unable to probe attached disk: %v", err)
+			klog.V(5).InfoS("Unable to probe attached disk", "err", err)
 		}
 		return false, nil
 	})
@@ -149,38 +151,23 @@ func (m *Mount) GetDevicePath(volumeID string) (string, error) {
 	return devicePath, nil
 }
 
-// GetDevicePathBySerialID returns the path of an attached block storage volume, specified by its id.
+// getDevicePathBySerialID returns the path of an attached block storage volume, specified by its id.
 func (m *Mount) getDevicePathBySerialID(volumeID string) string {
-	// Build a list of candidate device paths.
-	// Certain Nova drivers will set the disk serial ID, including the Cinder volume id.
-	candidateDeviceNodes := []string{
-		// KVM
-		fmt.Sprintf("virtio-%s", volumeID[:20]),
-		// KVM #852
-		fmt.Sprintf("virtio-%s", volumeID),
-		// KVM virtio-scsi
-		fmt.Sprintf("scsi-0QEMU_QEMU_HARDDISK_%s", volumeID[:20]),
-		// KVM virtio-scsi #852
-		fmt.Sprintf("scsi-0QEMU_QEMU_HARDDISK_%s", volumeID),
-		// ESXi
-		fmt.Sprintf("wwn-0x%s", strings.ReplaceAll(volumeID, "-", "")),
+	devicePath := findDevicePath(diskByIDDir, sysBlockDir, devDir, volumeID)
+	if devicePath == "" {
+		klog.V(4).InfoS("Failed to find device for the volume by serial ID", "volumeID", volumeID)
+		return ""
 	}
-
-	files, err := os.ReadDir("/dev/disk/by-id/")
-	if err != nil {
-		klog.V(4).Infof("ReadDir failed with error %v", err)
-	}
-
-	for _, f := range files {
-		if slices.Contains(candidateDeviceNodes, f.Name()) {
-			klog.V(4).Infof("Found disk attached as %q; full devicepath: %s\n",
-				f.Name(), path.Join("/dev/disk/by-id/", f.Name()))
-			return path.Join("/dev/disk/by-id/", f.Name())
-		}
+	if multipathDevice := blockdevice.MultipathDevice(devicePath); multipathDevice != "" {
+		return multipathDevice
 	}
+	return devicePath
+}
 
-	klog.V(4).Infof("Failed to find device for the volumeID: %q by serial ID", volumeID)
-	return ""
+// GetDeviceSerial returns the serial the kernel reports for the device on devicePath.
+// An empty string is returned if the device does not expose a serial.
+func (m *Mount) GetDeviceSerial(devicePath string) (string, error) {
+	return blockdevice.GetBlockDeviceSerial(devicePath)
 }
 
 // ScanForAttach
@@ -193,17 +180,17 @@ func (m *Mount) ScanForAttach(devicePath string) error {
 	for {
 		select {
 		case <-ticker.C:
-			klog.V(5).Infof("Checking Cinder disk %q is attached.", devicePath)
+			klog.V(5).InfoS("Checking if disk is attached", "devicePath", devicePath)
 			if err := probeVolume(); err != nil {
 				// log the error, but continue. Might not happen in edge cases
-				klog.V(5).Infof("Unable to probe attached disk: %v", err)
+				klog.V(5).InfoS("Unable to probe attached disk", "err", err)
 			}
 
 			exists, err := mount.PathExists(devicePath)
 			if exists && err == nil {
 				return nil
 			}
-			klog.V(3).Infof("Could not find attached Cinder disk %s", devicePath)
+			klog.V(3).InfoS("Could not find attached disk", "devicePath", devicePath)
 		case <-timer.C:
 			return fmt.Errorf("could not find attached Cinder disk %s. Timeout waiting for mount paths to be created", devicePath)
 		}
@@ -229,6 +216,29 @@ func (m *Mount) GetMountFs(volumePath string) ([]byte, error) {
 	return m.BaseMounter.Exec.Command("findmnt", args...).CombinedOutput()
 }
 
+// IsReadOnlyRemounted reports whether the filesystem mounted at path was remounted read-only by the kernel,
+// e.g. by ext4 with errors=remount-ro after I/O errors. The filesystem is then read-only while the mount itself is
+// still read-write.
+func (m *Mount) IsReadOnlyRemounted(path string) (bool, error) {
+	return readOnlyRemountedAt(procMountInfoPath, path)
+}
+
+// RemountReadWrite remounts the filesystem mounted at path read-write.
+func (m *Mount) RemountReadWrite(path string) error {
+	out, err := m.BaseMounter.Exec.Command("mount", "-o", "remount,rw", path).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("remounting %s read-write failed: %w, output: %s", path, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// SetVolumeGroup makes the filesystem mounted at path accessible to the group gid, like the kubelet does for the
+// fsGroup of a pod. The files are only changed if the root directory doesn't already belong to the group, which
+// avoids walking large volumes on every mount, e.g. after restoring a snapshot of a volume that was already prepared.
+func (m *Mount) SetVolumeGroup(path string, gid int64) error {
+	return setVolumeGroupAt(path, int(gid))
+}
+
 // UnmountPath
 func (m *Mount) UnmountPath(mountPath string) error {
 	return mount.CleanupMountPoint(mountPath, m.BaseMounter, false /* extensiveMountPointCheck */)
@@ -27,3 +27,13 @@ func CountFreePCIeSlots() (int64, error) {
 	// not implemented
 	return 0, nil
 }
+
+func ListLocalCSIFilesystemMounts(_ string) ([]string, error) {
+	// not implemented
+	return nil, nil
+}
+
+func CheckPrivileges() error {
+	// not implemented
+	return nil
+}
@@ -1,13 +1,21 @@
 package mount
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
 
 	"k8s.io/klog/v2"
+	"k8s.io/mount-utils"
 )
 
 var (
@@ -21,6 +29,12 @@ const (
 	volumeDataDir    = "data"
 	volumeDataFile   = "vol_data.json"
 	driverNameKey    = "driverName"
+
+	procMountInfoPath = "/proc/self/mountinfo"
+	procStatusPath    = "/proc/self/status"
+
+	// capSysAdmin is the bit of CAP_SYS_ADMIN in the capability sets, which is required to mount and unmount.
+	capSysAdmin = 21
 )
 
 func countFreePCIeSlotsAt(devicesPath string) (int64, error) {
@@ -36,7 +50,7 @@ func countFreePCIeSlotsAt(devicesPath string) (int64, error) {
 
 		classBuf, err := os.ReadFile(filepath.Join(devPath, "class"))
 		if err != nil {
-			klog.Errorf("failed to read PCI device class %s: %v", devPath, err)
+			klog.ErrorS(err, "Failed to read PCI device class", "path", devPath)
 			continue
 		}
 
@@ -46,7 +60,7 @@ func countFreePCIeSlotsAt(devicesPath string) (int64, error) {
 
 		children, err := filepath.Glob(filepath.Join(devPath, "????:??:??.?"))
 		if err != nil {
-			klog.Errorf("failed to glob PCI children for %s: %v", devPath, err)
+			klog.ErrorS(err, "Failed to glob PCI children", "path", devPath)
 			continue
 		}
 
@@ -75,14 +89,23 @@ func countLocalCSIVolumesAt(csiPluginDir, driverName string) (int64, error) {
 }
 
 func countLocalCSIFilesystemVolumesAt(driverPluginDir string) (int64, error) {
-	volumeMounts, err := filepath.Glob(filepath.Join(driverPluginDir, "*", globalMountDir))
+	volumeMounts, err := listLocalCSIFilesystemMountsAt(driverPluginDir)
 	if err != nil {
-		return 0, fmt.Errorf("failed to glob CSI volume mounts in %s: %w", driverPluginDir, err)
+		return 0, err
 	}
 
 	return int64(len(volumeMounts)), nil
 }
 
+func listLocalCSIFilesystemMountsAt(driverPluginDir string) ([]string, error) {
+	volumeMounts, err := filepath.Glob(filepath.Join(driverPluginDir, "*", globalMountDir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to glob CSI volume mounts in %s: %w", driverPluginDir, err)
+	}
+
+	return volumeMounts, nil
+}
+
 func countLocalCSIBlockVolumesAt(csiPluginDir, driverName string) (int64, error) {
 	volumeMetadataFiles, err := filepath.Glob(filepath.Join(csiPluginDir, volumeDevicesDir, "*", volumeDataDir, volumeDataFile))
 	if err != nil {
@@ -94,7 +117,7 @@ func countLocalCSIBlockVolumesAt(csiPluginDir, driverName string) (int64, error)
 	for _, metadataPath := range volumeMetadataFiles {
 		metadata, err := readCSIVolumeDeviceMetadata(metadataPath)
 		if err != nil {
-			klog.Errorf("failed to read CSI block volume metadata %s: %v", metadataPath, err)
+			klog.ErrorS(err, "Failed to read CSI block volume metadata", "path", metadataPath)
 			continue
 		}
 
@@ -119,3 +142,108 @@ func readCSIVolumeDeviceMetadata(metadataPath string) (map[string]string, error)
 
 	return metadata, nil
 }
+
+// readOnlyRemountedAt reports whether the filesystem mounted at path is read-only although the mount is read-write.
+// The last entry for path in mountInfoPath wins, since it is the one visible to the processes.
+func readOnlyRemountedAt(mountInfoPath, path string) (bool, error) {
+	infos, err := mount.ParseMountInfo(mountInfoPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse mountinfo: %w", err)
+	}
+
+	var found *mount.MountInfo
+	for i := range infos {
+		if infos[i].MountPoint == path {
+			found = &infos[i]
+		}
+	}
+	if found == nil {
+		return false, fmt.Errorf("%s is not a mount point", path)
+	}
+	return slices.Contains(found.SuperOptions, "ro") && !slices.Contains(found.MountOptions, "ro"), nil
+}
+
+const (
+	// volumeGroupMask is added to the mode of all files, directories additionally get volumeGroupDirMask.
+	volumeGroupMask    = 0o660
+	volumeGroupDirMask = os.ModeSetgid | 0o110
+)
+
+// setVolumeGroupAt changes the group of all files below root to gid and makes them group read-writable.
+// Directories get the setgid bit so that new files inherit the group. Nothing is changed if root already has
+// the group and mode, since the volume was prepared before.
+func setVolumeGroupAt(root string, gid int) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if hasVolumeGroup(info, gid) {
+		klog.V(4).InfoS("Volume already has the group, skipping ownership change", "path", root, "gid", gid)
+		return nil
+	}
+
+	klog.V(2).InfoS("Changing group of volume", "path", root, "gid", gid)
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := os.Lchown(path, -1, gid); err != nil {
+			return fmt.Errorf("failed to change group of %s: %w", path, err)
+		}
+		if d.Type()&os.ModeSymlink != 0 {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		mask := os.FileMode(volumeGroupMask)
+		if d.IsDir() {
+			mask |= volumeGroupDirMask
+		}
+		if err := os.Chmod(path, info.Mode()|mask); err != nil {
+			return fmt.Errorf("failed to change mode of %s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+func hasVolumeGroup(info os.FileInfo, gid int) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false
+	}
+	mask := os.FileMode(volumeGroupMask) | volumeGroupDirMask
+	return int(stat.Gid) == gid && info.Mode()&mask == mask
+}
+
+// checkPrivilegesAt returns an error if the effective capabilities in the process status file at statusPath lack
+// CAP_SYS_ADMIN. Without it, the node service fails to stage every volume with a permission error of mount.
+func checkPrivilegesAt(statusPath string) error {
+	f, err := os.Open(statusPath)
+	if err != nil {
+		return fmt.Errorf("failed to read capabilities: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		value, found := strings.CutPrefix(scanner.Text(), "CapEff:")
+		if !found {
+			continue
+		}
+		capabilities, err := strconv.ParseUint(strings.TrimSpace(value), 16, 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse effective capabilities %q: %w", value, err)
+		}
+		if capabilities&(1<<capSysAdmin) == 0 {
+			return fmt.Errorf("the node service lacks CAP_SYS_ADMIN (uid %d, effective capabilities %#x), "+
+				"which is required to mount volumes: run the node plugin container privileged", os.Geteuid(), capabilities)
+		}
+		return nil
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read capabilities: %w", err)
+	}
+	return errors.New("failed to read capabilities: no effective capabilities in " + statusPath)
+}
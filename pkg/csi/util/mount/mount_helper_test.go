@@ -6,6 +6,7 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/types"
 )
 
 var _ = Describe("Mount helpers", func() {
@@ -129,6 +130,9 @@ var _ = Describe("Mount helpers", func() {
 		})
 
 		It("skips malformed or unreadable block metadata files", func() {
+			if os.Geteuid() == 0 {
+				Skip("root can read files without read permission")
+			}
 			csiPluginDir := GinkgoT().TempDir()
 			mustWriteVolumeMetadata(csiPluginDir, "good-volume", "block-storage.csi.stackit.cloud")
 			malformedPath := filepath.Join(csiPluginDir, volumeDevicesDir, "malformed-volume", volumeDataDir, volumeDataFile)
@@ -179,6 +183,83 @@ func createPCIDevice(rootPath, deviceName, class string, children ...string) {
 	}
 }
 
+var _ = DescribeTable("readOnlyRemountedAt",
+	func(mountInfo string, expected bool) {
+		mountInfoPath := filepath.Join(GinkgoT().TempDir(), "mountinfo")
+		mustWriteFile(mountInfoPath, mountInfo)
+
+		remounted, err := readOnlyRemountedAt(mountInfoPath, "/staging")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(remounted).To(Equal(expected))
+	},
+	Entry("read-write filesystem",
+		"36 25 253:16 / /staging rw,relatime shared:1 - ext4 /dev/vdb rw\n", false),
+	Entry("filesystem remounted read-only after errors",
+		"36 25 253:16 / /staging rw,relatime shared:1 - ext4 /dev/vdb ro,errors=remount-ro\n", true),
+	Entry("read-only mount",
+		"36 25 253:16 / /staging ro,relatime shared:1 - ext4 /dev/vdb ro\n", false),
+	Entry("last mount on the path wins",
+		"36 25 253:16 / /staging rw,relatime shared:1 - ext4 /dev/vdb ro\n"+
+			"37 36 253:32 / /staging rw,relatime shared:2 - ext4 /dev/vdc rw\n", false),
+)
+
+var _ = DescribeTable("checkPrivilegesAt",
+	func(status string, matchErr types.GomegaMatcher) {
+		statusPath := filepath.Join(GinkgoT().TempDir(), "status")
+		mustWriteFile(statusPath, status)
+
+		Expect(checkPrivilegesAt(statusPath)).To(matchErr)
+	},
+	Entry("privileged container",
+		"Name:\tstackit-csi\nCapEff:\t000001ffffffffff\n", Succeed()),
+	Entry("only CAP_SYS_ADMIN",
+		"CapEff:\t0000000000200000\n", Succeed()),
+	Entry("unprivileged container",
+		"Name:\tstackit-csi\nCapEff:\t00000000a80425fb\n", MatchError(ContainSubstring("lacks CAP_SYS_ADMIN"))),
+	Entry("missing capabilities",
+		"Name:\tstackit-csi\n", MatchError(ContainSubstring("no effective capabilities"))),
+	Entry("malformed capabilities",
+		"CapEff:\tnot-hex\n", MatchError(ContainSubstring("failed to parse"))),
+)
+
+var _ = Describe("setVolumeGroupAt", func() {
+	var root string
+
+	BeforeEach(func() {
+		root = GinkgoT().TempDir()
+		Expect(os.Chmod(root, 0o755)).To(Succeed())
+		mustMkdirAll(filepath.Join(root, "dir"))
+		mustWriteFile(filepath.Join(root, "dir", "file"), "data")
+		Expect(os.Chmod(filepath.Join(root, "dir", "file"), 0o600)).To(Succeed())
+		Expect(os.Symlink("dir/file", filepath.Join(root, "link"))).To(Succeed())
+	})
+
+	mode := func(path string) os.FileMode {
+		GinkgoHelper()
+		info, err := os.Lstat(filepath.Join(root, path))
+		Expect(err).NotTo(HaveOccurred())
+		return info.Mode()
+	}
+
+	It("makes all files group read-writable and lets directories inherit the group", func() {
+		Expect(setVolumeGroupAt(root, os.Getgid())).To(Succeed())
+
+		Expect(mode("").Perm()).To(Equal(os.FileMode(0o775)))
+		Expect(mode("") & os.ModeSetgid).NotTo(BeZero())
+		Expect(mode("dir") & os.ModeSetgid).NotTo(BeZero())
+		Expect(mode("dir/file").Perm()).To(Equal(os.FileMode(0o660)))
+		Expect(mode("link") & os.ModeSymlink).NotTo(BeZero())
+	})
+
+	It("skips volumes whose root already has the group", func() {
+		Expect(setVolumeGroupAt(root, os.Getgid())).To(Succeed())
+		Expect(os.Chmod(filepath.Join(root, "dir", "file"), 0o600)).To(Succeed())
+
+		Expect(setVolumeGroupAt(root, os.Getgid())).To(Succeed())
+		Expect(mode("dir/file").Perm()).To(Equal(os.FileMode(0o600)))
+	})
+})
+
 func mustMkdirAll(path string) {
 	GinkgoHelper()
 	Expect(os.MkdirAll(path, 0o755)).To(Succeed())
@@ -37,3 +37,14 @@ func CountLocalCSIVolumes(driverName string) (int64, error) {
 	csiPluginDir := filepath.Join(kubeletDir, "plugins", "kubernetes.io", "csi")
 	return countLocalCSIVolumesAt(csiPluginDir, driverName)
 }
+
+// ListLocalCSIFilesystemMounts returns the staging paths of all filesystem volumes of the given driver.
+func ListLocalCSIFilesystemMounts(driverName string) ([]string, error) {
+	driverPluginDir := filepath.Join(kubeletDir, "plugins", "kubernetes.io", "csi", driverName)
+	return listLocalCSIFilesystemMountsAt(driverPluginDir)
+}
+
+// CheckPrivileges returns an error if the process lacks the privileges to mount volumes.
+func CheckPrivileges() error {
+	return checkPrivilegesAt(procStatusPath)
+}
@@ -55,6 +55,21 @@ func (mr *MockIMountMockRecorder) GetDevicePath(volumeID any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDevicePath", reflect.TypeOf((*MockIMount)(nil).GetDevicePath), volumeID)
 }
 
+// GetDeviceSerial mocks base method.
+func (m *MockIMount) GetDeviceSerial(devicePath string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeviceSerial", devicePath)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeviceSerial indicates an expected call of GetDeviceSerial.
+func (mr *MockIMountMockRecorder) GetDeviceSerial(devicePath any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeviceSerial", reflect.TypeOf((*MockIMount)(nil).GetDeviceSerial), devicePath)
+}
+
 // GetDeviceStats mocks base method.
 func (m *MockIMount) GetDeviceStats(path string) (*DeviceStats, error) {
 	m.ctrl.T.Helper()
@@ -100,6 +115,21 @@ func (mr *MockIMountMockRecorder) IsLikelyNotMountPointAttach(targetpath any) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsLikelyNotMountPointAttach", reflect.TypeOf((*MockIMount)(nil).IsLikelyNotMountPointAttach), targetpath)
 }
 
+// IsReadOnlyRemounted mocks base method.
+func (m *MockIMount) IsReadOnlyRemounted(path string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsReadOnlyRemounted", path)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsReadOnlyRemounted indicates an expected call of IsReadOnlyRemounted.
+func (mr *MockIMountMockRecorder) IsReadOnlyRemounted(path any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsReadOnlyRemounted", reflect.TypeOf((*MockIMount)(nil).IsReadOnlyRemounted), path)
+}
+
 // MakeDir mocks base method.
 func (m *MockIMount) MakeDir(pathname string) error {
 	m.ctrl.T.Helper()
@@ -142,6 +172,20 @@ func (mr *MockIMountMockRecorder) Mounter() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Mounter", reflect.TypeOf((*MockIMount)(nil).Mounter))
 }
 
+// RemountReadWrite mocks base method.
+func (m *MockIMount) RemountReadWrite(path string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemountReadWrite", path)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemountReadWrite indicates an expected call of RemountReadWrite.
+func (mr *MockIMountMockRecorder) RemountReadWrite(path any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemountReadWrite", reflect.TypeOf((*MockIMount)(nil).RemountReadWrite), path)
+}
+
 // ScanForAttach mocks base method.
 func (m *MockIMount) ScanForAttach(devicePath string) error {
 	m.ctrl.T.Helper()
@@ -156,6 +200,20 @@ func (mr *MockIMountMockRecorder) ScanForAttach(devicePath any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScanForAttach", reflect.TypeOf((*MockIMount)(nil).ScanForAttach), devicePath)
 }
 
+// SetVolumeGroup mocks base method.
+func (m *MockIMount) SetVolumeGroup(path string, gid int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetVolumeGroup", path, gid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetVolumeGroup indicates an expected call of SetVolumeGroup.
+func (mr *MockIMountMockRecorder) SetVolumeGroup(path, gid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetVolumeGroup", reflect.TypeOf((*MockIMount)(nil).SetVolumeGroup), path, gid)
+}
+
 // UnmountPath mocks base method.
 func (m *MockIMount) UnmountPath(mountPath string) error {
 	m.ctrl.T.Helper()
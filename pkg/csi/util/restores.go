@@ -0,0 +1,57 @@
+package util
+
+import (
+	"slices"
+	"sync"
+)
+
+// InFlightRestores tracks the volumes that are currently being restored from a snapshot or backup,
+// so that the source isn't deleted while a restore still depends on it. It only covers the time until the volume
+// is created, afterwards the restore is known from the status of the volume.
+type InFlightRestores struct {
+	mux      sync.Mutex
+	restores map[string]map[string]struct{}
+}
+
+func NewInFlightRestores() *InFlightRestores {
+	return &InFlightRestores{
+		restores: make(map[string]map[string]struct{}),
+	}
+}
+
+// Add marks volumeName as being restored from sourceID.
+func (r *InFlightRestores) Add(sourceID, volumeName string) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	volumes, ok := r.restores[sourceID]
+	if !ok {
+		volumes = make(map[string]struct{})
+		r.restores[sourceID] = volumes
+	}
+	volumes[volumeName] = struct{}{}
+}
+
+// Remove marks the restore of volumeName from sourceID as completed.
+func (r *InFlightRestores) Remove(sourceID, volumeName string) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	delete(r.restores[sourceID], volumeName)
+	if len(r.restores[sourceID]) == 0 {
+		delete(r.restores, sourceID)
+	}
+}
+
+// Volumes returns the sorted names of the volumes that are currently restored from sourceID.
+func (r *InFlightRestores) Volumes(sourceID string) []string {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	volumes := make([]string, 0, len(r.restores[sourceID]))
+	for name := range r.restores[sourceID] {
+		volumes = append(volumes, name)
+	}
+	slices.Sort(volumes)
+	return volumes
+}
@@ -0,0 +1,24 @@
+package util
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("InFlightRestores", func() {
+	It("should track restores per source", func() {
+		restores := NewInFlightRestores()
+		Expect(restores.Volumes("snapshot-1")).To(BeEmpty())
+
+		restores.Add("snapshot-1", "volume-b")
+		restores.Add("snapshot-1", "volume-a")
+		restores.Add("snapshot-2", "volume-c")
+		Expect(restores.Volumes("snapshot-1")).To(Equal([]string{"volume-a", "volume-b"}))
+		Expect(restores.Volumes("snapshot-2")).To(Equal([]string{"volume-c"}))
+
+		restores.Remove("snapshot-1", "volume-a")
+		restores.Remove("snapshot-1", "volume-b")
+		Expect(restores.Volumes("snapshot-1")).To(BeEmpty())
+		Expect(restores.Volumes("snapshot-2")).To(Equal([]string{"volume-c"}))
+	})
+})
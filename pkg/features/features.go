@@ -0,0 +1,38 @@
+// Package features contains the feature gates of the cloud controller manager and the CSI driver.
+// Both binaries register all gates in utilfeature.DefaultMutableFeatureGate and expose them via --feature-gates.
+package features
+
+import (
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	"k8s.io/component-base/featuregate"
+)
+
+const (
+	// TLSListeners allows services to request TLS passthrough and termination listeners via lb.stackit.cloud/tls-mode.
+	// TLS termination is not supported by the load balancer API yet.
+	TLSListeners featuregate.Feature = "TLSListeners"
+
+	// LocalTrafficPolicyTargets restricts the targets of services with externalTrafficPolicy Local to the nodes that
+	// run ready pods of the service. The targets are updated when the EndpointSlices of the service change.
+	LocalTrafficPolicyTargets featuregate.Feature = "LocalTrafficPolicyTargets"
+
+	// CrossZoneClone lets the CSI driver create volumes from snapshots and volumes in a different availability zone.
+	// Without it, such requests are rejected before calling the IaaS API.
+	CrossZoneClone featuregate.Feature = "CrossZoneClone"
+)
+
+var defaultFeatureGates = map[featuregate.Feature]featuregate.FeatureSpec{
+	TLSListeners:              {Default: false, PreRelease: featuregate.Alpha},
+	LocalTrafficPolicyTargets: {Default: false, PreRelease: featuregate.Alpha},
+	CrossZoneClone:            {Default: false, PreRelease: featuregate.Alpha},
+}
+
+func init() {
+	utilruntime.Must(AddFeatureGates(utilfeature.DefaultMutableFeatureGate))
+}
+
+// AddFeatureGates adds all feature gates of this repository to gate.
+func AddFeatureGates(gate featuregate.MutableFeatureGate) error {
+	return gate.Add(defaultFeatureGates)
+}
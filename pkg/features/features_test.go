@@ -0,0 +1,25 @@
+package features
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	"k8s.io/component-base/featuregate"
+)
+
+var _ = Describe("Feature gates", func() {
+	It("should register all gates disabled in the default gate", func() {
+		for feature := range defaultFeatureGates {
+			Expect(utilfeature.DefaultFeatureGate.Enabled(feature)).To(BeFalse(), string(feature))
+		}
+	})
+
+	It("should be toggled via the feature-gates flag", func() {
+		gate := featuregate.NewFeatureGate()
+		Expect(AddFeatureGates(gate)).To(Succeed())
+		Expect(gate.Set("TLSListeners=true")).To(Succeed())
+		Expect(gate.Enabled(TLSListeners)).To(BeTrue())
+		Expect(gate.Enabled(CrossZoneClone)).To(BeFalse())
+		Expect(gate.Set("UnknownFeature=true")).To(MatchError(ContainSubstring("unrecognized feature gate")))
+	})
+})
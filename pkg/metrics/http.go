@@ -11,10 +11,15 @@ import (
 )
 
 func NewInstrumentedHTTPClient(api string) *http.Client {
+	return NewInstrumentedHTTPClientWithTransport(api, http.DefaultTransport)
+}
+
+// NewInstrumentedHTTPClientWithTransport returns an instrumented client that sends requests via base.
+func NewInstrumentedHTTPClientWithTransport(api string, base http.RoundTripper) *http.Client {
 	return &http.Client{
 		Transport: &InstrumentedRoundTripper{
 			api:  api,
-			base: http.DefaultTransport,
+			base: base,
 		},
 	}
 }
@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+const (
+	serviceLabel      = "service"
+	loadBalancerLabel = "load_balancer"
+	statusLabel       = "status"
+	targetPoolLabel   = "target_pool"
+	planLabel         = "plan"
+)
+
+var (
+	loadBalancerStatusDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudProviderMetricPrefix, "", "load_balancer_status"),
+		"The status of the load balancer of a service as reported by the load balancer API, always 1",
+		[]string{namespaceLabel, serviceLabel, loadBalancerLabel, statusLabel}, nil,
+	)
+	loadBalancerTargetsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudProviderMetricPrefix, "", "load_balancer_targets"),
+		"The number of targets of a target pool of the load balancer of a service",
+		[]string{namespaceLabel, serviceLabel, loadBalancerLabel, targetPoolLabel}, nil,
+	)
+	loadBalancerPlanDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudProviderMetricPrefix, "", "load_balancer_plan_info"),
+		"The plan of the load balancer of a service, always 1",
+		[]string{namespaceLabel, serviceLabel, loadBalancerLabel, planLabel}, nil,
+	)
+	loadBalancerStatusLastUpdateDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(cloudProviderMetricPrefix, "", "load_balancer_status_last_update_timestamp_seconds"),
+		"The time the status of the load balancers was listed successfully the last time",
+		nil, nil,
+	)
+)
+
+// LoadBalancerState is the state of the load balancer of a service as reported by the load balancer API.
+type LoadBalancerState struct {
+	Namespace string
+	Service   string
+	Name      string
+	Status    string
+	PlanID    string
+	// Targets contains the number of targets by target pool name.
+	Targets map[string]int
+}
+
+// LoadBalancerStatusCollector exports the states of the load balancers of the cluster, so that dashboards can show
+// their health without relying on events. The states are listed periodically by Run instead of on every scrape,
+// to limit the calls to the load balancer API.
+type LoadBalancerStatusCollector struct {
+	mu         sync.Mutex
+	states     []LoadBalancerState
+	lastUpdate time.Time
+}
+
+// LoadBalancerStatus is part of the Exporter and only reports load balancers once Run is called.
+var LoadBalancerStatus = &LoadBalancerStatusCollector{}
+
+// Run lists the states of the load balancers with list every interval until ctx is done.
+// After a failed run, the states of the previous run are exported.
+func (c *LoadBalancerStatusCollector) Run(ctx context.Context, interval time.Duration, list func(context.Context) ([]LoadBalancerState, error)) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		states, err := list(ctx)
+		if err != nil {
+			klog.ErrorS(err, "Failed to list the status of load balancers")
+			return
+		}
+		c.Update(states, time.Now())
+	}, interval)
+}
+
+// Update replaces the exported states.
+func (c *LoadBalancerStatusCollector) Update(states []LoadBalancerState, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.states = states
+	c.lastUpdate = now
+}
+
+func (c *LoadBalancerStatusCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- loadBalancerStatusDesc
+	descs <- loadBalancerTargetsDesc
+	descs <- loadBalancerPlanDesc
+	descs <- loadBalancerStatusLastUpdateDesc
+}
+
+func (c *LoadBalancerStatusCollector) Collect(metrics chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lastUpdate.IsZero() {
+		return
+	}
+	metrics <- prometheus.MustNewConstMetric(loadBalancerStatusLastUpdateDesc, prometheus.GaugeValue, float64(c.lastUpdate.Unix()))
+	for _, state := range c.states {
+		metrics <- prometheus.MustNewConstMetric(loadBalancerStatusDesc, prometheus.GaugeValue, 1,
+			state.Namespace, state.Service, state.Name, state.Status)
+		metrics <- prometheus.MustNewConstMetric(loadBalancerPlanDesc, prometheus.GaugeValue, 1,
+			state.Namespace, state.Service, state.Name, state.PlanID)
+		for pool, targets := range state.Targets {
+			metrics <- prometheus.MustNewConstMetric(loadBalancerTargetsDesc, prometheus.GaugeValue, float64(targets),
+				state.Namespace, state.Service, state.Name, pool)
+		}
+	}
+}
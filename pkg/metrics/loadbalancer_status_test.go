@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var _ = Describe("LoadBalancerStatusCollector", func() {
+	It("should not export anything before the first update", func() {
+		Expect(testutil.CollectAndCount(&LoadBalancerStatusCollector{})).To(BeZero())
+	})
+
+	It("should export the states of the last update", func() {
+		c := &LoadBalancerStatusCollector{}
+		c.Update([]LoadBalancerState{{
+			Namespace: "default",
+			Service:   "echo",
+			Name:      "k8s-svc-uid-echo",
+			Status:    "STATUS_READY",
+			PlanID:    "p10",
+			Targets:   map[string]int{"http": 3},
+		}}, time.Unix(1700000000, 0))
+
+		Expect(testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP cloud_provider_stackit_load_balancer_plan_info The plan of the load balancer of a service, always 1
+# TYPE cloud_provider_stackit_load_balancer_plan_info gauge
+cloud_provider_stackit_load_balancer_plan_info{load_balancer="k8s-svc-uid-echo",namespace="default",plan="p10",service="echo"} 1
+# HELP cloud_provider_stackit_load_balancer_status The status of the load balancer of a service as reported by the load balancer API, always 1
+# TYPE cloud_provider_stackit_load_balancer_status gauge
+cloud_provider_stackit_load_balancer_status{load_balancer="k8s-svc-uid-echo",namespace="default",service="echo",status="STATUS_READY"} 1
+# HELP cloud_provider_stackit_load_balancer_status_last_update_timestamp_seconds The time the status of the load balancers was listed successfully the last time
+# TYPE cloud_provider_stackit_load_balancer_status_last_update_timestamp_seconds gauge
+cloud_provider_stackit_load_balancer_status_last_update_timestamp_seconds 1.7e+09
+# HELP cloud_provider_stackit_load_balancer_targets The number of targets of a target pool of the load balancer of a service
+# TYPE cloud_provider_stackit_load_balancer_targets gauge
+cloud_provider_stackit_load_balancer_targets{load_balancer="k8s-svc-uid-echo",namespace="default",service="echo",target_pool="http"} 3
+`))).To(Succeed())
+
+		// Deleted load balancers are no longer exported.
+		c.Update([]LoadBalancerState{}, time.Unix(1700000060, 0))
+		Expect(testutil.CollectAndCount(c)).To(Equal(1))
+	})
+})
@@ -10,9 +10,18 @@ const (
 	methodLabel               = "method"
 	codeLabel                 = "status_code"
 	operationLabel            = "op"
+	namespaceLabel            = "namespace"
+	pvcLabel                  = "persistentvolumeclaim"
+	nodeLabel                 = "node"
+	resourceLabel             = "resource"
+	resultLabel               = "result"
+	fieldLabel                = "field"
+	grpcMethodLabel           = "method"
+	grpcCodeLabel             = "grpc_status_code"
 
 	APINameLoadBalancer = "loadbalancer"
 	APINameIaaS         = "iaas"
+	APINameDNS          = "dns"
 )
 
 var (
@@ -37,6 +46,127 @@ var (
 		ConstLabels: nil,
 		Buckets:     nil,
 	}, []string{apiLabel, methodLabel, operationLabel, codeLabel})
+
+	CSINamespaceCapacityUsed = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   cloudProviderMetricPrefix,
+		Name:        "csi_namespace_capacity_used_gibibytes",
+		Help:        "The capacity in GiB of volumes provisioned for PVCs of a namespace with a capacity quota",
+		ConstLabels: nil,
+	}, []string{namespaceLabel})
+
+	CSINamespaceCapacityQuota = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   cloudProviderMetricPrefix,
+		Name:        "csi_namespace_capacity_quota_gibibytes",
+		Help:        "The configured capacity quota in GiB of a namespace",
+		ConstLabels: nil,
+	}, []string{namespaceLabel})
+
+	CSIScheduledBackupLastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   cloudProviderMetricPrefix,
+		Name:        "csi_scheduled_backup_last_success_timestamp_seconds",
+		Help:        "The time of the last scheduled backup of a PVC that was created successfully",
+		ConstLabels: nil,
+	}, []string{namespaceLabel, pvcLabel})
+
+	CSIScheduledBackupFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   cloudProviderMetricPrefix,
+		Name:        "csi_scheduled_backup_failures_total",
+		Help:        "The number of scheduled backups of a PVC that could not be created or pruned",
+		ConstLabels: nil,
+	}, []string{namespaceLabel, pvcLabel})
+
+	CSIScheduledBackups = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   cloudProviderMetricPrefix,
+		Name:        "csi_scheduled_backups",
+		Help:        "The number of scheduled backups retained for a PVC",
+		ConstLabels: nil,
+	}, []string{namespaceLabel, pvcLabel})
+
+	CSINodeAttachedVolumes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   cloudProviderMetricPrefix,
+		Name:        "csi_node_attached_volumes",
+		Help:        "The number of volumes attached to the server of a node, without its boot volume",
+		ConstLabels: nil,
+	}, []string{nodeLabel})
+
+	CSINodeVolumeLimit = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   cloudProviderMetricPrefix,
+		Name:        "csi_node_volume_limit",
+		Help:        "The number of volumes a node advertises in its CSINode that can be attached to it",
+		ConstLabels: nil,
+	}, []string{nodeLabel})
+
+	CSIOperationsSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace:   cloudProviderMetricPrefix,
+		Name:        "csi_operations_seconds",
+		Help:        "The duration of CSI operations by gRPC method and status code",
+		ConstLabels: nil,
+		// The operations wait for volumes to be attached or created, which can take minutes.
+		Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 15, 25, 50, 120, 300, 600},
+	}, []string{grpcMethodLabel, grpcCodeLabel})
+
+	CSIOperationsInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   cloudProviderMetricPrefix,
+		Name:        "csi_operations_in_flight",
+		Help:        "The number of CSI operations that are currently running by gRPC method",
+		ConstLabels: nil,
+	}, []string{grpcMethodLabel})
+
+	CSIBackupRestoresInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   cloudProviderMetricPrefix,
+		Name:        "csi_backup_restores_in_flight",
+		Help:        "The number of volumes that are currently restored from a backup",
+		ConstLabels: nil,
+	})
+
+	CSISnapshotOperationsQueued = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   cloudProviderMetricPrefix,
+		Name:        "csi_snapshot_operations_queued",
+		Help:        "The number of CreateSnapshot calls that wait for the concurrency limit of snapshots",
+		ConstLabels: nil,
+	})
+
+	LoadBalancerQuotaRemaining = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace:   cloudProviderMetricPrefix,
+		Name:        "load_balancer_quota_remaining",
+		Help:        "The number of load balancers that can still be created in the project before the quota is exhausted",
+		ConstLabels: nil,
+	})
+
+	LoadBalancerUpdates = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   cloudProviderMetricPrefix,
+		Name:        "load_balancer_updates_total",
+		Help:        "The number of load balancer updates by the field that differed from the specification, without indices",
+		ConstLabels: nil,
+	}, []string{fieldLabel})
+
+	LoadBalancerInvalidSpecs = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   cloudProviderMetricPrefix,
+		Name:        "load_balancer_invalid_spec_errors_total",
+		Help:        "The number of errors in the load balancer options of services found during reconciliations",
+		ConstLabels: nil,
+	})
+
+	OrphanGCOrphans = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   cloudProviderMetricPrefix,
+		Name:        "orphan_gc_orphans",
+		Help:        "The number of orphaned resources of the cluster found in the last run of the orphan collector",
+		ConstLabels: nil,
+	}, []string{resourceLabel})
+
+	OrphanGCDeletions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   cloudProviderMetricPrefix,
+		Name:        "orphan_gc_deletions_total",
+		Help:        "The number of orphaned resources the orphan collector tried to delete",
+		ConstLabels: nil,
+	}, []string{resourceLabel, resultLabel})
+
+	AuditEventsDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace:   cloudProviderMetricPrefix,
+		Name:        "audit_events_dropped_total",
+		Help:        "The number of audit events that were not sent to the webhook, because its queue was full or shut down",
+		ConstLabels: nil,
+	})
 )
 
 type Exporter struct {
@@ -60,10 +190,46 @@ func (e *Exporter) describeCloudProvider(descs chan<- *prometheus.Desc) {
 	HTTPRequestCount.Describe(descs)
 	HTTPErrorCount.Describe(descs)
 	HTTPRequestDurationHistogram.Describe(descs)
+	CSINamespaceCapacityUsed.Describe(descs)
+	CSINamespaceCapacityQuota.Describe(descs)
+	CSIScheduledBackupLastSuccess.Describe(descs)
+	CSIScheduledBackupFailures.Describe(descs)
+	CSIScheduledBackups.Describe(descs)
+	CSINodeAttachedVolumes.Describe(descs)
+	CSINodeVolumeLimit.Describe(descs)
+	CSIBackupRestoresInFlight.Describe(descs)
+	CSIOperationsSeconds.Describe(descs)
+	CSIOperationsInFlight.Describe(descs)
+	CSISnapshotOperationsQueued.Describe(descs)
+	LoadBalancerQuotaRemaining.Describe(descs)
+	LoadBalancerUpdates.Describe(descs)
+	LoadBalancerInvalidSpecs.Describe(descs)
+	OrphanGCOrphans.Describe(descs)
+	OrphanGCDeletions.Describe(descs)
+	AuditEventsDropped.Describe(descs)
+	LoadBalancerStatus.Describe(descs)
 }
 
 func (e *Exporter) collectCloudProvider(metrics chan<- prometheus.Metric) {
 	HTTPRequestCount.Collect(metrics)
 	HTTPErrorCount.Collect(metrics)
 	HTTPRequestDurationHistogram.Collect(metrics)
+	CSINamespaceCapacityUsed.Collect(metrics)
+	CSINamespaceCapacityQuota.Collect(metrics)
+	CSIScheduledBackupLastSuccess.Collect(metrics)
+	CSIScheduledBackupFailures.Collect(metrics)
+	CSIScheduledBackups.Collect(metrics)
+	CSINodeAttachedVolumes.Collect(metrics)
+	CSINodeVolumeLimit.Collect(metrics)
+	CSIBackupRestoresInFlight.Collect(metrics)
+	CSIOperationsSeconds.Collect(metrics)
+	CSIOperationsInFlight.Collect(metrics)
+	CSISnapshotOperationsQueued.Collect(metrics)
+	LoadBalancerQuotaRemaining.Collect(metrics)
+	LoadBalancerUpdates.Collect(metrics)
+	LoadBalancerInvalidSpecs.Collect(metrics)
+	OrphanGCOrphans.Collect(metrics)
+	OrphanGCDeletions.Collect(metrics)
+	AuditEventsDropped.Collect(metrics)
+	LoadBalancerStatus.Collect(metrics)
 }
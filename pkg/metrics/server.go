@@ -2,8 +2,13 @@ package metrics
 
 import (
 	"context"
+	"crypto/subtle"
 	"errors"
+	"fmt"
 	"net/http"
+	"net/http/pprof"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -11,22 +16,51 @@ import (
 	"k8s.io/klog/v2"
 )
 
-func Run(ctx context.Context, metricsAddr string) error {
+// pprofWriteTimeout allows CPU profiles and traces of up to 60 seconds.
+const pprofWriteTimeout = 65 * time.Second
+
+// ServerOptions configures optional handlers of the metrics server.
+type ServerOptions struct {
+	// EnablePprof registers the handlers of net/http/pprof under /debug/pprof/.
+	EnablePprof bool
+	// PprofToken protects the pprof handlers with a bearer token if not empty.
+	PprofToken string
+}
+
+// NewServerOptions returns the options for the pprof flags. The token is read from tokenFile if it is not empty.
+func NewServerOptions(enablePprof bool, tokenFile string) (ServerOptions, error) {
+	opts := ServerOptions{EnablePprof: enablePprof}
+	if tokenFile == "" {
+		return opts, nil
+	}
+	token, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return opts, fmt.Errorf("read pprof token file: %w", err)
+	}
+	opts.PprofToken = strings.TrimSpace(string(token))
+	if opts.PprofToken == "" {
+		return opts, fmt.Errorf("pprof token file %s is empty", tokenFile)
+	}
+	return opts, nil
+}
+
+func Run(ctx context.Context, metricsAddr string, opts ServerOptions) error {
 	if metricsAddr == "" {
 		return errors.New("metrics address is empty")
 	}
 
-	klog.Infof("Starting prometheus listener on address %s", metricsAddr)
-
-	mux := http.NewServeMux()
-	mux.Handle("/metrics", promhttp.Handler())
+	klog.InfoS("Starting prometheus listener", "address", metricsAddr, "pprof", opts.EnablePprof)
 
+	writeTimeout := 5 * time.Second
+	if opts.EnablePprof {
+		writeTimeout = pprofWriteTimeout
+	}
 	serv := &http.Server{
 		Addr:              metricsAddr,
-		Handler:           mux,
+		Handler:           newHandler(opts),
 		ReadTimeout:       5 * time.Second,
 		ReadHeaderTimeout: 5 * time.Second,
-		WriteTimeout:      5 * time.Second,
+		WriteTimeout:      writeTimeout,
 	}
 	g, gCtx := errgroup.WithContext(ctx)
 
@@ -39,9 +73,40 @@ func Run(ctx context.Context, metricsAddr string) error {
 
 	g.Go(func() error {
 		<-gCtx.Done()
-		klog.Info("Shutdown prometheus listener")
+		klog.InfoS("Shutdown prometheus listener")
 		return serv.Shutdown(gCtx)
 	})
 
 	return g.Wait()
 }
+
+func newHandler(opts ServerOptions) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	if opts.EnablePprof {
+		pprofMux := http.NewServeMux()
+		pprofMux.HandleFunc("/debug/pprof/", pprof.Index)
+		pprofMux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		pprofMux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		pprofMux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		pprofMux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		mux.Handle("/debug/pprof/", withBearerToken(opts.PprofToken, pprofMux))
+	}
+	return mux
+}
+
+// withBearerToken rejects requests without the token in the Authorization header. An empty token allows all requests.
+func withBearerToken(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
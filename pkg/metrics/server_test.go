@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Server", func() {
+	get := func(handler http.Handler, path, token string) int {
+		req := httptest.NewRequest(http.MethodGet, path, http.NoBody)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	It("should not serve pprof by default", func() {
+		handler := newHandler(ServerOptions{})
+		Expect(get(handler, "/metrics", "")).To(Equal(http.StatusOK))
+		Expect(get(handler, "/debug/pprof/", "")).To(Equal(http.StatusNotFound))
+	})
+
+	It("should serve pprof if enabled", func() {
+		handler := newHandler(ServerOptions{EnablePprof: true})
+		Expect(get(handler, "/debug/pprof/", "")).To(Equal(http.StatusOK))
+		Expect(get(handler, "/debug/pprof/goroutine", "")).To(Equal(http.StatusOK))
+	})
+
+	It("should require the token for pprof if set", func() {
+		handler := newHandler(ServerOptions{EnablePprof: true, PprofToken: "secret"})
+		Expect(get(handler, "/debug/pprof/heap", "")).To(Equal(http.StatusUnauthorized))
+		Expect(get(handler, "/debug/pprof/heap", "wrong")).To(Equal(http.StatusUnauthorized))
+		Expect(get(handler, "/debug/pprof/heap", "secret")).To(Equal(http.StatusOK))
+		Expect(get(handler, "/metrics", "")).To(Equal(http.StatusOK))
+	})
+
+	It("should read the token from a file", func() {
+		tokenFile := filepath.Join(GinkgoT().TempDir(), "token")
+		Expect(os.WriteFile(tokenFile, []byte("secret\n"), 0o600)).To(Succeed())
+
+		opts, err := NewServerOptions(true, tokenFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(opts).To(Equal(ServerOptions{EnablePprof: true, PprofToken: "secret"}))
+
+		Expect(os.WriteFile(tokenFile, []byte("\n"), 0o600)).To(Succeed())
+		_, err = NewServerOptions(true, tokenFile)
+		Expect(err).To(MatchError(ContainSubstring("is empty")))
+	})
+})
@@ -0,0 +1,236 @@
+// Package orphangc deletes load balancers and volumes of a cluster whose Service or PersistentVolume no longer exists,
+// e.g. after etcd was restored from a backup that predates their creation.
+package orphangc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/cmp"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/metrics"
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
+)
+
+const (
+	// ResourceLoadBalancer and ResourceVolume are the kinds of resources that are collected.
+	ResourceLoadBalancer = "load_balancer"
+	ResourceVolume       = "volume"
+
+	// DefaultMinAge is the default of Options.MinAge.
+	DefaultMinAge = time.Hour
+	// DefaultMaxDeletions is the default of Options.MaxDeletions.
+	DefaultMaxDeletions = 10
+)
+
+// Options configures the collector.
+type Options struct {
+	// ClusterID selects the resources of the cluster by their cluster-id label, see stackitclient.ResourceLabels.
+	ClusterID string
+	// DryRun only reports orphans without deleting them.
+	DryRun bool
+	// MinAge protects volumes that were just created, because the csi-provisioner creates the PersistentVolume
+	// only after the volume. Load balancers are always created after their Service.
+	MinAge time.Duration
+	// MaxDeletions aborts a run without deleting anything if more orphans are found, e.g. because the collector
+	// runs against the wrong cluster. Zero disables the limit.
+	MaxDeletions int
+}
+
+// Orphan is a load balancer or volume whose Service or PersistentVolume no longer exists.
+type Orphan struct {
+	Resource string
+	// ID is the name of a load balancer or the ID of a volume.
+	ID string
+	// Owner is the UID of the Service of a load balancer or the name of the PersistentVolume of a volume.
+	Owner string
+}
+
+// Collector finds and deletes orphaned resources of a cluster.
+type Collector struct {
+	lbClient   stackitclient.LoadBalancingClient
+	iaasClient stackitclient.IaaSClient
+	kubeClient kubernetes.Interface
+	opts       Options
+	now        func() time.Time
+}
+
+// NewCollector creates a collector for the resources labelled with opts.ClusterID.
+func NewCollector(
+	lbClient stackitclient.LoadBalancingClient,
+	iaasClient stackitclient.IaaSClient,
+	kubeClient kubernetes.Interface,
+	opts Options,
+) (*Collector, error) {
+	if opts.ClusterID == "" {
+		return nil, errors.New("the cluster ID must be set to select the resources of the cluster")
+	}
+	return &Collector{
+		lbClient:   lbClient,
+		iaasClient: iaasClient,
+		kubeClient: kubeClient,
+		opts:       opts,
+		now:        time.Now,
+	}, nil
+}
+
+// Run finds the orphans of the cluster and deletes them unless DryRun is set. It returns the orphans that were found.
+// Resources are listed before the Kubernetes objects, so that resources created during the run are never orphans.
+func (c *Collector) Run(ctx context.Context) ([]Orphan, error) {
+	lbs, err := c.lbClient.ListLoadBalancers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list load balancers: %w", err)
+	}
+	volumes, _, err := c.iaasClient.ListVolumes(ctx, 0, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volumes: %w", err)
+	}
+
+	services, err := c.kubeClient.CoreV1().Services(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+	serviceUIDs := map[types.UID]bool{}
+	for i := range services.Items {
+		serviceUIDs[services.Items[i].UID] = true
+	}
+	pvs, err := c.kubeClient.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list persistent volumes: %w", err)
+	}
+	volumeHandles := map[string]bool{}
+	var retained []string
+	for i := range pvs.Items {
+		if csi := pvs.Items[i].Spec.CSI; csi != nil {
+			volumeHandles[csi.VolumeHandle] = true
+			if pvs.Items[i].Spec.PersistentVolumeReclaimPolicy == corev1.PersistentVolumeReclaimRetain {
+				retained = append(retained, csi.VolumeHandle)
+			}
+		}
+	}
+	// Also in dry-run mode, because the label only protects volumes and later runs don't see the deleted PVs anymore.
+	if err := c.labelRetained(ctx, volumes, retained); err != nil {
+		return nil, err
+	}
+
+	orphans := c.orphanedLoadBalancers(lbs, serviceUIDs)
+	orphans = append(orphans, c.orphanedVolumes(volumes, volumeHandles)...)
+	for _, resource := range []string{ResourceLoadBalancer, ResourceVolume} {
+		metrics.OrphanGCOrphans.WithLabelValues(resource).Set(0)
+	}
+	for _, orphan := range orphans {
+		metrics.OrphanGCOrphans.WithLabelValues(orphan.Resource).Inc()
+		klog.InfoS("Found orphaned resource", "resource", orphan.Resource, "id", orphan.ID, "owner", orphan.Owner, "dryRun", c.opts.DryRun)
+	}
+
+	if c.opts.DryRun || len(orphans) == 0 {
+		return orphans, nil
+	}
+	if c.opts.MaxDeletions > 0 && len(orphans) > c.opts.MaxDeletions {
+		return orphans, fmt.Errorf("found %d orphans, which exceeds the maximum of %d deletions, check that the cluster ID %q belongs to this cluster",
+			len(orphans), c.opts.MaxDeletions, c.opts.ClusterID)
+	}
+
+	var errs []error
+	for _, orphan := range orphans {
+		if err := stackiterrors.IgnoreNotFound(c.delete(ctx, orphan)); err != nil {
+			metrics.OrphanGCDeletions.WithLabelValues(orphan.Resource, "failure").Inc()
+			errs = append(errs, fmt.Errorf("failed to delete %s %s: %w", orphan.Resource, orphan.ID, err))
+			continue
+		}
+		metrics.OrphanGCDeletions.WithLabelValues(orphan.Resource, "success").Inc()
+		klog.InfoS("Deleted orphaned resource", "resource", orphan.Resource, "id", orphan.ID, "owner", orphan.Owner)
+	}
+	return orphans, utilerrors.NewAggregate(errs)
+}
+
+// orphanedLoadBalancers returns the load balancers of the cluster whose Service doesn't exist anymore.
+// Load balancers without the service-uid label were created before resource labels were enabled and are skipped.
+func (c *Collector) orphanedLoadBalancers(lbs []loadbalancer.LoadBalancer, serviceUIDs map[types.UID]bool) []Orphan {
+	var orphans []Orphan
+	for i := range lbs {
+		labels := cmp.UnpackPtr(lbs[i].Labels)
+		uid := labels[stackitclient.ServiceUIDLabel]
+		if labels[stackitclient.ClusterIDLabel] != c.opts.ClusterID || uid == "" || serviceUIDs[types.UID(uid)] {
+			continue
+		}
+		orphans = append(orphans, Orphan{Resource: ResourceLoadBalancer, ID: cmp.UnpackPtr(lbs[i].Name), Owner: uid})
+	}
+	return orphans
+}
+
+// labelRetained labels the volumes of the cluster that are referenced by PersistentVolumes with the reclaim policy
+// Retain, so that they are not collected after the PersistentVolume was deleted.
+func (c *Collector) labelRetained(ctx context.Context, volumes []iaas.Volume, retained []string) error {
+	for i := range volumes {
+		volume := &volumes[i]
+		if clusterID, _ := volume.Labels[stackitclient.ClusterIDLabel].(string); clusterID != c.opts.ClusterID {
+			continue
+		}
+		if !slices.Contains(retained, volume.GetId()) || volume.Labels[stackitclient.ReclaimPolicyLabel] == string(corev1.PersistentVolumeReclaimRetain) {
+			continue
+		}
+		// The API merges the labels, so the other labels of the volume are kept.
+		labels := map[string]any{stackitclient.ReclaimPolicyLabel: string(corev1.PersistentVolumeReclaimRetain)}
+		if _, err := c.iaasClient.UpdateVolume(ctx, volume.GetId(), iaas.UpdateVolumePayload{Labels: labels}); err != nil {
+			return fmt.Errorf("failed to label retained volume %s: %w", volume.GetId(), err)
+		}
+		volume.Labels[stackitclient.ReclaimPolicyLabel] = string(corev1.PersistentVolumeReclaimRetain)
+	}
+	return nil
+}
+
+// orphanedVolumes returns the volumes of the cluster that are not referenced by a PersistentVolume.
+// Attached volumes, volumes younger than MinAge, volumes pending deletion by the CSI driver and volumes that must be
+// kept, because their PersistentVolume was retained or they can be adopted by a recreated PVC, are skipped.
+func (c *Collector) orphanedVolumes(volumes []iaas.Volume, volumeHandles map[string]bool) []Orphan {
+	var orphans []Orphan
+	for i := range volumes {
+		volume := &volumes[i]
+		if clusterID, _ := volume.Labels[stackitclient.ClusterIDLabel].(string); clusterID != c.opts.ClusterID {
+			continue
+		}
+		if volumeHandles[volume.GetId()] || volume.GetServerId() != "" {
+			continue
+		}
+		// The CSI driver deletes the volume once its reclaim grace period is over.
+		if _, pending := volume.Labels[stackitclient.PendingDeleteLabel]; pending {
+			continue
+		}
+		if volume.Labels[stackitclient.ReclaimPolicyLabel] == string(corev1.PersistentVolumeReclaimRetain) {
+			continue
+		}
+		// The CSI driver adopts the volume if the PVC is recreated, see the adoptExisting parameter.
+		_, namespaced := volume.Labels[stackitclient.PVCNamespaceLabel]
+		if _, named := volume.Labels[stackitclient.PVCNameLabel]; namespaced && named {
+			continue
+		}
+		if createdAt, ok := volume.GetCreatedAtOk(); !ok || c.now().Sub(*createdAt) < c.opts.MinAge {
+			continue
+		}
+		orphans = append(orphans, Orphan{Resource: ResourceVolume, ID: volume.GetId(), Owner: volume.GetName()})
+	}
+	return orphans
+}
+
+func (c *Collector) delete(ctx context.Context, orphan Orphan) error {
+	switch orphan.Resource {
+	case ResourceLoadBalancer:
+		return c.lbClient.DeleteLoadBalancer(ctx, orphan.ID)
+	case ResourceVolume:
+		return c.iaasClient.DeleteVolume(ctx, orphan.ID)
+	}
+	return fmt.Errorf("unknown resource %s", orphan.Resource)
+}
@@ -0,0 +1,189 @@
+package orphangc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+)
+
+var _ = Describe("NewCollector", func() {
+	It("should refuse an empty cluster ID", func() {
+		_, err := NewCollector(nil, nil, fake.NewClientset(), Options{})
+		Expect(err).To(MatchError(ContainSubstring("cluster ID must be set")))
+	})
+})
+
+var _ = Describe("Collector", func() {
+	const clusterID = "my-cluster"
+
+	var (
+		mockLBClient   *stackitclientmock.MockLoadBalancingClient
+		mockIaaSClient *stackitclientmock.MockIaaSClient
+		kubeClient     *fake.Clientset
+		collector      *Collector
+		opts           Options
+		now            time.Time
+		lbs            []loadbalancer.LoadBalancer
+		volumes        []iaas.Volume
+	)
+
+	lb := func(name, cluster, serviceUID string) loadbalancer.LoadBalancer {
+		return loadbalancer.LoadBalancer{
+			Name: new(name),
+			Labels: &map[string]string{
+				stackitclient.ClusterIDLabel:  cluster,
+				stackitclient.ServiceUIDLabel: serviceUID,
+			},
+		}
+	}
+
+	volume := func(id, cluster string, age time.Duration) iaas.Volume {
+		return iaas.Volume{
+			Id:        new(id),
+			Name:      new("pv-" + id),
+			CreatedAt: new(now.Add(-age)),
+			Labels:    map[string]any{stackitclient.ClusterIDLabel: cluster},
+		}
+	}
+
+	BeforeEach(func() {
+		ctrl := gomock.NewController(GinkgoT())
+		mockLBClient = stackitclientmock.NewMockLoadBalancingClient(ctrl)
+		mockIaaSClient = stackitclientmock.NewMockIaaSClient(ctrl)
+		kubeClient = fake.NewClientset(
+			&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "my-service", Namespace: "default", UID: "service-uid"}},
+			&corev1.PersistentVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "pv-used"},
+				Spec: corev1.PersistentVolumeSpec{PersistentVolumeSource: corev1.PersistentVolumeSource{
+					CSI: &corev1.CSIPersistentVolumeSource{Driver: "block-storage.csi.stackit.cloud", VolumeHandle: "used"},
+				}},
+			},
+			&corev1.PersistentVolume{
+				ObjectMeta: metav1.ObjectMeta{Name: "pv-retained"},
+				Spec: corev1.PersistentVolumeSpec{
+					PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimRetain,
+					PersistentVolumeSource: corev1.PersistentVolumeSource{
+						CSI: &corev1.CSIPersistentVolumeSource{Driver: "block-storage.csi.stackit.cloud", VolumeHandle: "retained"},
+					},
+				},
+			},
+		)
+		opts = Options{ClusterID: clusterID, MinAge: DefaultMinAge, MaxDeletions: DefaultMaxDeletions}
+		now = time.Now()
+
+		lbs = []loadbalancer.LoadBalancer{
+			lb("in-use", clusterID, "service-uid"),
+			lb("orphan", clusterID, "deleted-uid"),
+			lb("other-cluster", "other-cluster", "deleted-uid"),
+			{Name: new("unlabelled")},
+		}
+		attached := volume("attached", clusterID, 2*time.Hour)
+		attached.ServerId = new("server-id")
+		pending := volume("pending-delete", clusterID, 2*time.Hour)
+		pending.Labels[stackitclient.PendingDeleteLabel] = "1767225600"
+		retainedBefore := volume("retained-before", clusterID, 2*time.Hour)
+		retainedBefore.Labels[stackitclient.ReclaimPolicyLabel] = "Retain"
+		adoptable := volume("adoptable", clusterID, 2*time.Hour)
+		adoptable.Labels[stackitclient.PVCNamespaceLabel] = "default"
+		adoptable.Labels[stackitclient.PVCNameLabel] = "data"
+		volumes = []iaas.Volume{
+			volume("used", clusterID, 2*time.Hour),
+			volume("orphan", clusterID, 2*time.Hour),
+			volume("new", clusterID, time.Minute),
+			volume("other-cluster", "other-cluster", 2*time.Hour),
+			attached,
+			pending,
+			volume("retained", clusterID, 2*time.Hour),
+			retainedBefore,
+			adoptable,
+			{Id: new("unlabelled"), CreatedAt: new(now.Add(-2 * time.Hour))},
+		}
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		collector, err = NewCollector(mockLBClient, mockIaaSClient, kubeClient, opts)
+		Expect(err).NotTo(HaveOccurred())
+		collector.now = func() time.Time { return now }
+
+		mockLBClient.EXPECT().ListLoadBalancers(gomock.Any()).Return(lbs, nil)
+		mockIaaSClient.EXPECT().ListVolumes(gomock.Any(), gomock.Any(), gomock.Any()).Return(volumes, "", nil)
+		// The volume of the retained PV is protected once it is deleted, like the volume retained-before.
+		mockIaaSClient.EXPECT().UpdateVolume(gomock.Any(), "retained", iaas.UpdateVolumePayload{
+			Labels: map[string]any{stackitclient.ReclaimPolicyLabel: "Retain"},
+		}).Return(&iaas.Volume{}, nil)
+	})
+
+	It("should delete the load balancers and volumes of deleted services and persistent volumes", func() {
+		mockLBClient.EXPECT().DeleteLoadBalancer(gomock.Any(), "orphan").Return(nil)
+		mockIaaSClient.EXPECT().DeleteVolume(gomock.Any(), "orphan").Return(nil)
+
+		orphans, err := collector.Run(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(orphans).To(ConsistOf(
+			Orphan{Resource: ResourceLoadBalancer, ID: "orphan", Owner: "deleted-uid"},
+			Orphan{Resource: ResourceVolume, ID: "orphan", Owner: "pv-orphan"},
+		))
+	})
+
+	It("should continue deleting after an error", func() {
+		mockLBClient.EXPECT().DeleteLoadBalancer(gomock.Any(), "orphan").Return(errors.New("boom"))
+		mockIaaSClient.EXPECT().DeleteVolume(gomock.Any(), "orphan").Return(nil)
+
+		orphans, err := collector.Run(context.Background())
+		Expect(err).To(MatchError(ContainSubstring("boom")))
+		Expect(orphans).To(HaveLen(2))
+	})
+
+	Context("in dry-run mode", func() {
+		BeforeEach(func() {
+			opts.DryRun = true
+		})
+
+		It("should only report the orphans", func() {
+			orphans, err := collector.Run(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(orphans).To(HaveLen(2))
+		})
+	})
+
+	Context("with a smaller minimum age", func() {
+		BeforeEach(func() {
+			opts.MinAge = 0
+		})
+
+		It("should delete new volumes", func() {
+			mockLBClient.EXPECT().DeleteLoadBalancer(gomock.Any(), "orphan").Return(nil)
+			mockIaaSClient.EXPECT().DeleteVolume(gomock.Any(), "orphan").Return(nil)
+			mockIaaSClient.EXPECT().DeleteVolume(gomock.Any(), "new").Return(nil)
+
+			orphans, err := collector.Run(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(orphans).To(HaveLen(3))
+		})
+	})
+
+	Context("with more orphans than the maximum of deletions", func() {
+		BeforeEach(func() {
+			opts.MaxDeletions = 1
+		})
+
+		It("should not delete anything", func() {
+			orphans, err := collector.Run(context.Background())
+			Expect(err).To(MatchError(ContainSubstring("exceeds the maximum of 1 deletions")))
+			Expect(orphans).To(HaveLen(2))
+		})
+	})
+})
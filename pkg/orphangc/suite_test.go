@@ -0,0 +1,13 @@
+package orphangc
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestOrphanGC(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "OrphanGC Suite")
+}
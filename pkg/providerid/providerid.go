@@ -0,0 +1,89 @@
+// Package providerid builds and parses the providerIDs of STACKIT servers, see Node.Spec.ProviderID.
+package providerid
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// Scheme is the scheme of providerIDs set by the STACKIT cloud controller manager.
+	Scheme = "stackit"
+	// TODO(migration): remove old provider support after migration
+	oldScheme = "openstack"
+)
+
+var (
+	// canonicalRegexp matches stackit:///<availability zone>/<server ID>.
+	canonicalRegexp = regexp.MustCompile(`^` + Scheme + `:///([^/]+)/([^/]+)$`)
+	// legacyRegexp matches stackit://<server ID> and stackit:///<server ID>, which were set by earlier versions.
+	legacyRegexp = regexp.MustCompile(`^` + Scheme + `:///?([^/]+)$`)
+	// TODO(migration): remove old provider support after migration
+	oldRegexp = regexp.MustCompile(`^` + oldScheme + `://([^/]*)/([^/]+)$`)
+)
+
+// ProviderID is a parsed providerID.
+type ProviderID struct {
+	ServerID string
+	// AvailabilityZone is only known for the canonical format.
+	AvailabilityZone string
+	// Region is only known for the old regional openstack://<region>/<server ID> format.
+	Region string
+}
+
+// New returns the canonical providerID stackit:///<availability zone>/<server ID>.
+// Without an availability zone, it returns the legacy format stackit://<server ID>.
+func New(availabilityZone, serverID string) string {
+	if availabilityZone == "" {
+		return fmt.Sprintf("%s://%s", Scheme, serverID)
+	}
+	return fmt.Sprintf("%s:///%s/%s", Scheme, availabilityZone, serverID)
+}
+
+// Parse parses the canonical providerID and all formats that were used before:
+//   - stackit:///<availability zone>/<server ID> (canonical)
+//   - stackit://<server ID> and stackit:///<server ID>
+//   - <server ID>, e.g. set by kubelet --provider-id or used as CSI node ID
+//   - openstack:///<server ID> and openstack://<region>/<server ID>
+func Parse(providerID string) (ProviderID, error) {
+	if providerID == "" {
+		return ProviderID{}, fmt.Errorf("providerID must not be empty")
+	}
+
+	// https://github.com/kubernetes/kubernetes/issues/85731
+	if !strings.Contains(providerID, "://") {
+		if strings.Contains(providerID, "/") {
+			return ProviderID{}, fmt.Errorf("providerID %q is not a server ID and has no scheme", providerID)
+		}
+		return ProviderID{ServerID: providerID}, nil
+	}
+
+	switch {
+	case strings.HasPrefix(providerID, oldScheme+"://"):
+		matches := oldRegexp.FindStringSubmatch(providerID)
+		if matches == nil {
+			return ProviderID{}, fmt.Errorf("providerID %q didn't match expected format \"%s://region/InstanceID\"", providerID, oldScheme)
+		}
+		return ProviderID{ServerID: matches[2], Region: matches[1]}, nil
+	case strings.HasPrefix(providerID, Scheme+"://"):
+		if matches := canonicalRegexp.FindStringSubmatch(providerID); matches != nil {
+			return ProviderID{ServerID: matches[2], AvailabilityZone: matches[1]}, nil
+		}
+		if matches := legacyRegexp.FindStringSubmatch(providerID); matches != nil {
+			return ProviderID{ServerID: matches[1]}, nil
+		}
+		return ProviderID{}, fmt.Errorf("providerID %q didn't match expected format \"%s:///AvailabilityZone/InstanceID\"", providerID, Scheme)
+	default:
+		return ProviderID{}, fmt.Errorf("providerID %q has an unknown scheme", providerID)
+	}
+}
+
+// ServerID returns the server ID of a providerID in any of the formats accepted by Parse.
+func ServerID(providerID string) (string, error) {
+	parsed, err := Parse(providerID)
+	if err != nil {
+		return "", err
+	}
+	return parsed.ServerID, nil
+}
@@ -0,0 +1,57 @@
+package providerid
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("New", func() {
+	It("should return the canonical format", func() {
+		Expect(New("eu01-1", "hello-server")).To(Equal("stackit:///eu01-1/hello-server"))
+	})
+
+	It("should return the legacy format without availability zone", func() {
+		Expect(New("", "hello-server")).To(Equal("stackit://hello-server"))
+	})
+
+	It("should be parsed again", func() {
+		Expect(Parse(New("eu01-1", "hello-server"))).To(Equal(ProviderID{ServerID: "hello-server", AvailabilityZone: "eu01-1"}))
+	})
+})
+
+var _ = Describe("Parse", func() {
+	DescribeTable("supported formats",
+		func(providerID string, expected ProviderID) {
+			Expect(Parse(providerID)).To(Equal(expected))
+		},
+		Entry("canonical providerID", "stackit:///eu01-1/hello-server", ProviderID{ServerID: "hello-server", AvailabilityZone: "eu01-1"}),
+		Entry("new providerID", "stackit://hello-server", ProviderID{ServerID: "hello-server"}),
+		Entry("new providerID with empty host", "stackit:///hello-server", ProviderID{ServerID: "hello-server"}),
+		Entry("bare server ID", "hello-server", ProviderID{ServerID: "hello-server"}),
+		Entry("old providerID", "openstack:///hello-server", ProviderID{ServerID: "hello-server"}),
+		Entry("old regional providerID", "openstack://eu01/hello-server", ProviderID{ServerID: "hello-server", Region: "eu01"}),
+	)
+
+	DescribeTable("invalid providerIDs",
+		func(providerID string) {
+			_, err := Parse(providerID)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("empty", ""),
+		Entry("unknown scheme", "aws:///eu-central-1a/i-123"),
+		Entry("too many segments", "stackit:///eu01/1/hello-server"),
+		Entry("path without scheme", "eu01-1/hello-server"),
+		Entry("old providerID without server ID", "openstack://eu01/"),
+	)
+})
+
+var _ = Describe("ServerID", func() {
+	It("should return the server ID", func() {
+		Expect(ServerID("stackit:///eu01-1/hello-server")).To(Equal("hello-server"))
+	})
+
+	It("should fail for invalid providerIDs", func() {
+		_, err := ServerID("aws:///eu-central-1a/i-123")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,13 @@
+package providerid
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestProviderID(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ProviderID Suite")
+}
@@ -0,0 +1,417 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/metrics"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+	"k8s.io/klog/v2"
+)
+
+const (
+	AuditResultSuccess = "success"
+	AuditResultFailure = "failure"
+
+	auditWebhookTimeout = 5 * time.Second
+	// auditQueueSize bounds the events waiting to be sent to the webhook, further events are dropped.
+	auditQueueSize = 1000
+
+	// AuditShutdownTimeout bounds the time components wait in Auditor.Shutdown for the queued events to be sent.
+	AuditShutdownTimeout = 10 * time.Second
+)
+
+// AuditEvent describes a single mutating call to a STACKIT API.
+type AuditEvent struct {
+	Time      time.Time `json:"time"`
+	Caller    string    `json:"caller"`
+	Project   string    `json:"project"`
+	Region    string    `json:"region"`
+	Service   string    `json:"service"`
+	Operation string    `json:"operation"`
+	Resource  string    `json:"resource"`
+	// PayloadHash is the SHA-256 of the JSON encoded request payload. The payload itself is never recorded,
+	// since it can contain secrets like observability credentials.
+	PayloadHash string `json:"payloadHash,omitempty"`
+	Result      string `json:"result"`
+	Error       string `json:"error,omitempty"`
+}
+
+// Auditor records mutating API calls to the "audit" logger and, if configured, sends them to a webhook.
+// Events are sent to the webhook in the background, so that a slow webhook doesn't delay the API calls. Failing to
+// deliver an event to the webhook is logged but never fails the API call.
+type Auditor struct {
+	caller     string
+	projectID  string
+	region     string
+	webhookURL string
+	httpClient *http.Client
+	logger     klog.Logger
+	now        func() time.Time
+
+	// queue holds the events a single worker sends to the webhook, nil if no webhook is configured.
+	queue chan AuditEvent
+	// done is closed once the worker sent all events of the closed queue.
+	done chan struct{}
+	// mu guards closed, events must not be sent to the queue after it was closed by Shutdown.
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewAuditor returns an Auditor for the given component or nil if auditing is disabled.
+// If a webhook is configured, Shutdown must be called to send the queued events before exiting.
+func NewAuditor(opts stackitconfig.AuditOpts, caller, region, projectID string) *Auditor {
+	if !opts.Enabled {
+		return nil
+	}
+	a := &Auditor{
+		caller:     caller,
+		projectID:  projectID,
+		region:     region,
+		webhookURL: opts.WebhookURL,
+		httpClient: &http.Client{Timeout: auditWebhookTimeout},
+		logger:     klog.Background().WithName("audit"),
+		now:        time.Now,
+	}
+	if a.webhookURL != "" {
+		a.queue = make(chan AuditEvent, auditQueueSize)
+		a.done = make(chan struct{})
+		go a.run()
+	}
+	return a
+}
+
+// Shutdown stops accepting events for the webhook and waits until the queued events are sent or ctx is done.
+// Events recorded afterwards are only logged. It does nothing if a is nil or no webhook is configured.
+func (a *Auditor) Shutdown(ctx context.Context) error {
+	if a == nil || a.queue == nil {
+		return nil
+	}
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.queue)
+	}
+	a.mu.Unlock()
+
+	select {
+	case <-a.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%d audit events were not sent to the webhook: %w", len(a.queue), ctx.Err())
+	}
+}
+
+func (a *Auditor) run() {
+	defer close(a.done)
+	for event := range a.queue {
+		if err := a.send(context.Background(), event); err != nil {
+			a.logger.Error(err, "Failed to send audit event to webhook", "operation", event.Operation, "resource", event.Resource)
+		}
+	}
+}
+
+// enqueue queues the event for the webhook. The event is dropped if the queue is full or closed.
+func (a *Auditor) enqueue(event AuditEvent) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if !a.closed {
+		select {
+		case a.queue <- event:
+			return
+		default:
+		}
+	}
+	metrics.AuditEventsDropped.Inc()
+	a.logger.Info("Dropped audit event for webhook", "operation", event.Operation, "resource", event.Resource, "shutdown", a.closed)
+}
+
+func (a *Auditor) record(_ context.Context, service, operation, resource string, payload any, err error) {
+	event := AuditEvent{
+		Time:      a.now().UTC(),
+		Caller:    a.caller,
+		Project:   a.projectID,
+		Region:    a.region,
+		Service:   service,
+		Operation: operation,
+		Resource:  resource,
+		Result:    AuditResultSuccess,
+	}
+	if payload != nil {
+		event.PayloadHash = payloadHash(payload)
+	}
+	if err != nil {
+		event.Result = AuditResultFailure
+		event.Error = err.Error()
+	}
+
+	a.logger.Info("Cloud API call",
+		"caller", event.Caller,
+		"project", event.Project,
+		"region", event.Region,
+		"service", event.Service,
+		"operation", event.Operation,
+		"resource", event.Resource,
+		"payloadHash", event.PayloadHash,
+		"result", event.Result,
+		"error", event.Error,
+	)
+
+	if a.queue != nil {
+		a.enqueue(event)
+	}
+}
+
+func (a *Auditor) send(ctx context.Context, event AuditEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func payloadHash(payload any) string {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// NewAuditedIaaSClient records all mutating calls of client with auditor. It returns client if auditor is nil.
+func NewAuditedIaaSClient(client IaaSClient, auditor *Auditor) IaaSClient {
+	if auditor == nil {
+		return client
+	}
+	return &auditedIaaSClient{IaaSClient: client, auditor: auditor}
+}
+
+type auditedIaaSClient struct {
+	IaaSClient
+	auditor *Auditor
+}
+
+const auditServiceIaaS = "iaas"
+
+//nolint:gocritic // Payload is passed by value to match the shared IaaSClient interface.
+func (c *auditedIaaSClient) CreateSnapshot(ctx context.Context, payload iaas.CreateSnapshotPayload) (*iaas.Snapshot, error) {
+	snapshot, err := c.IaaSClient.CreateSnapshot(ctx, payload)
+	c.auditor.record(ctx, auditServiceIaaS, "CreateSnapshot", "snapshot/"+payload.GetName(), payload, err)
+	return snapshot, err
+}
+
+func (c *auditedIaaSClient) DeleteSnapshot(ctx context.Context, snapshotID string) error {
+	err := c.IaaSClient.DeleteSnapshot(ctx, snapshotID)
+	c.auditor.record(ctx, auditServiceIaaS, "DeleteSnapshot", "snapshot/"+snapshotID, nil, err)
+	return err
+}
+
+func (c *auditedIaaSClient) CreateBackup(ctx context.Context, name, volID, snapshotID string, tags map[string]string) (*iaas.Backup, error) {
+	backup, err := c.IaaSClient.CreateBackup(ctx, name, volID, snapshotID, tags)
+	payload := map[string]any{"name": name, "volumeID": volID, "snapshotID": snapshotID, "tags": tags}
+	c.auditor.record(ctx, auditServiceIaaS, "CreateBackup", "backup/"+name, payload, err)
+	return backup, err
+}
+
+func (c *auditedIaaSClient) DeleteBackup(ctx context.Context, backupID string) error {
+	err := c.IaaSClient.DeleteBackup(ctx, backupID)
+	c.auditor.record(ctx, auditServiceIaaS, "DeleteBackup", "backup/"+backupID, nil, err)
+	return err
+}
+
+//nolint:gocritic // Payload is passed by value to match the shared IaaSClient interface.
+func (c *auditedIaaSClient) CreateVolume(ctx context.Context, payload iaas.CreateVolumePayload) (*iaas.Volume, error) {
+	volume, err := c.IaaSClient.CreateVolume(ctx, payload)
+	c.auditor.record(ctx, auditServiceIaaS, "CreateVolume", "volume/"+payload.GetName(), payload, err)
+	return volume, err
+}
+
+func (c *auditedIaaSClient) DeleteVolume(ctx context.Context, volumeID string) error {
+	err := c.IaaSClient.DeleteVolume(ctx, volumeID)
+	c.auditor.record(ctx, auditServiceIaaS, "DeleteVolume", "volume/"+volumeID, nil, err)
+	return err
+}
+
+//nolint:gocritic // Payload is passed by value to match the shared IaaSClient interface.
+func (c *auditedIaaSClient) UpdateVolume(ctx context.Context, volumeID string, payload iaas.UpdateVolumePayload) (*iaas.Volume, error) {
+	volume, err := c.IaaSClient.UpdateVolume(ctx, volumeID, payload)
+	c.auditor.record(ctx, auditServiceIaaS, "UpdateVolume", "volume/"+volumeID, payload, err)
+	return volume, err
+}
+
+func (c *auditedIaaSClient) AttachVolume(ctx context.Context, serverID, volumeID string, payload iaas.AddVolumeToServerPayload) (string, error) {
+	id, err := c.IaaSClient.AttachVolume(ctx, serverID, volumeID, payload)
+	c.auditor.record(ctx, auditServiceIaaS, "AttachVolume", "server/"+serverID+"/volume/"+volumeID, payload, err)
+	return id, err
+}
+
+func (c *auditedIaaSClient) DetachVolume(ctx context.Context, serverID, volumeID string) error {
+	err := c.IaaSClient.DetachVolume(ctx, serverID, volumeID)
+	c.auditor.record(ctx, auditServiceIaaS, "DetachVolume", "server/"+serverID+"/volume/"+volumeID, nil, err)
+	return err
+}
+
+func (c *auditedIaaSClient) ExpandVolume(ctx context.Context, volumeID, volumeStatus string, payload iaas.ResizeVolumePayload) error {
+	err := c.IaaSClient.ExpandVolume(ctx, volumeID, volumeStatus, payload)
+	c.auditor.record(ctx, auditServiceIaaS, "ExpandVolume", "volume/"+volumeID, payload, err)
+	return err
+}
+
+func (c *auditedIaaSClient) CreateSecurityGroupRule(
+	ctx context.Context, securityGroupID string, payload iaas.CreateSecurityGroupRulePayload,
+) (*iaas.SecurityGroupRule, error) {
+	rule, err := c.IaaSClient.CreateSecurityGroupRule(ctx, securityGroupID, payload)
+	c.auditor.record(ctx, auditServiceIaaS, "CreateSecurityGroupRule", "security-group/"+securityGroupID, payload, err)
+	return rule, err
+}
+
+func (c *auditedIaaSClient) DeleteSecurityGroupRule(ctx context.Context, securityGroupID, ruleID string) error {
+	err := c.IaaSClient.DeleteSecurityGroupRule(ctx, securityGroupID, ruleID)
+	c.auditor.record(ctx, auditServiceIaaS, "DeleteSecurityGroupRule", "security-group/"+securityGroupID+"/rule/"+ruleID, nil, err)
+	return err
+}
+
+//nolint:gocritic // Payload is passed by value to match the shared IaaSClient interface.
+func (c *auditedIaaSClient) CreatePublicIP(ctx context.Context, payload iaas.CreatePublicIPPayload) (*iaas.PublicIp, error) {
+	publicIP, err := c.IaaSClient.CreatePublicIP(ctx, payload)
+	c.auditor.record(ctx, auditServiceIaaS, "CreatePublicIP", "public-ip/"+publicIP.GetId(), payload, err)
+	return publicIP, err
+}
+
+//nolint:gocritic // Payload is passed by value to match the shared IaaSClient interface.
+func (c *auditedIaaSClient) UpdatePublicIP(ctx context.Context, publicIPID string, payload iaas.UpdatePublicIPPayload) (*iaas.PublicIp, error) {
+	publicIP, err := c.IaaSClient.UpdatePublicIP(ctx, publicIPID, payload)
+	c.auditor.record(ctx, auditServiceIaaS, "UpdatePublicIP", "public-ip/"+publicIPID, payload, err)
+	return publicIP, err
+}
+
+func (c *auditedIaaSClient) DeletePublicIP(ctx context.Context, publicIPID string) error {
+	err := c.IaaSClient.DeletePublicIP(ctx, publicIPID)
+	c.auditor.record(ctx, auditServiceIaaS, "DeletePublicIP", "public-ip/"+publicIPID, nil, err)
+	return err
+}
+
+// NewAuditedLoadBalancingClient records all mutating calls of client with auditor. It returns client if auditor is nil.
+func NewAuditedLoadBalancingClient(client LoadBalancingClient, auditor *Auditor) LoadBalancingClient {
+	if auditor == nil {
+		return client
+	}
+	return &auditedLoadBalancingClient{LoadBalancingClient: client, auditor: auditor}
+}
+
+type auditedLoadBalancingClient struct {
+	LoadBalancingClient
+	auditor *Auditor
+}
+
+const auditServiceLoadBalancer = "loadbalancer"
+
+func (c *auditedLoadBalancingClient) CreateLoadBalancer(
+	ctx context.Context, payload *loadbalancer.CreateLoadBalancerPayload,
+) (*loadbalancer.LoadBalancer, error) {
+	lb, err := c.LoadBalancingClient.CreateLoadBalancer(ctx, payload)
+	c.auditor.record(ctx, auditServiceLoadBalancer, "CreateLoadBalancer", "load-balancer/"+payload.GetName(), payload, err)
+	return lb, err
+}
+
+func (c *auditedLoadBalancingClient) UpdateLoadBalancer(
+	ctx context.Context, lbName string, updates *loadbalancer.UpdateLoadBalancerPayload,
+) (*loadbalancer.LoadBalancer, error) {
+	lb, err := c.LoadBalancingClient.UpdateLoadBalancer(ctx, lbName, updates)
+	c.auditor.record(ctx, auditServiceLoadBalancer, "UpdateLoadBalancer", "load-balancer/"+lbName, updates, err)
+	return lb, err
+}
+
+func (c *auditedLoadBalancingClient) DeleteLoadBalancer(ctx context.Context, lbName string) error {
+	err := c.LoadBalancingClient.DeleteLoadBalancer(ctx, lbName)
+	c.auditor.record(ctx, auditServiceLoadBalancer, "DeleteLoadBalancer", "load-balancer/"+lbName, nil, err)
+	return err
+}
+
+func (c *auditedLoadBalancingClient) UpdateTargetPool(
+	ctx context.Context, name, targetPoolName string, payload loadbalancer.UpdateTargetPoolPayload,
+) error {
+	err := c.LoadBalancingClient.UpdateTargetPool(ctx, name, targetPoolName, payload)
+	c.auditor.record(ctx, auditServiceLoadBalancer, "UpdateTargetPool", "load-balancer/"+name+"/target-pool/"+targetPoolName, payload, err)
+	return err
+}
+
+func (c *auditedLoadBalancingClient) CreateCredentials(
+	ctx context.Context, payload loadbalancer.CreateCredentialsPayload,
+) (*loadbalancer.CreateCredentialsResponse, error) {
+	resp, err := c.LoadBalancingClient.CreateCredentials(ctx, payload)
+	c.auditor.record(ctx, auditServiceLoadBalancer, "CreateCredentials", "credentials/"+payload.GetDisplayName(), payload, err)
+	return resp, err
+}
+
+func (c *auditedLoadBalancingClient) UpdateCredentials(
+	ctx context.Context, credentialsRef string, payload loadbalancer.UpdateCredentialsPayload,
+) error {
+	err := c.LoadBalancingClient.UpdateCredentials(ctx, credentialsRef, payload)
+	c.auditor.record(ctx, auditServiceLoadBalancer, "UpdateCredentials", "credentials/"+credentialsRef, payload, err)
+	return err
+}
+
+func (c *auditedLoadBalancingClient) DeleteCredentials(ctx context.Context, credentialsRef string) error {
+	err := c.LoadBalancingClient.DeleteCredentials(ctx, credentialsRef)
+	c.auditor.record(ctx, auditServiceLoadBalancer, "DeleteCredentials", "credentials/"+credentialsRef, nil, err)
+	return err
+}
+
+// NewAuditedDNSClient records all mutating calls of client with auditor. It returns client if auditor is nil.
+func NewAuditedDNSClient(client DNSClient, auditor *Auditor) DNSClient {
+	if auditor == nil {
+		return client
+	}
+	return &auditedDNSClient{DNSClient: client, auditor: auditor}
+}
+
+type auditedDNSClient struct {
+	DNSClient
+	auditor *Auditor
+}
+
+const auditServiceDNS = "dns"
+
+func (c *auditedDNSClient) CreateRecordSet(ctx context.Context, zoneID string, recordSet DNSRecordSet) (*DNSRecordSet, error) {
+	created, err := c.DNSClient.CreateRecordSet(ctx, zoneID, recordSet)
+	c.auditor.record(ctx, auditServiceDNS, "CreateRecordSet", "zone/"+zoneID+"/rrset/"+recordSet.Name, recordSet, err)
+	return created, err
+}
+
+//nolint:gocritic // The record set is passed by value to match the shared DNSClient interface.
+func (c *auditedDNSClient) UpdateRecordSet(ctx context.Context, zoneID, recordSetID string, recordSet DNSRecordSet) error {
+	err := c.DNSClient.UpdateRecordSet(ctx, zoneID, recordSetID, recordSet)
+	c.auditor.record(ctx, auditServiceDNS, "UpdateRecordSet", "zone/"+zoneID+"/rrset/"+recordSetID, recordSet, err)
+	return err
+}
+
+func (c *auditedDNSClient) DeleteRecordSet(ctx context.Context, zoneID, recordSetID string) error {
+	err := c.DNSClient.DeleteRecordSet(ctx, zoneID, recordSetID)
+	c.auditor.record(ctx, auditServiceDNS, "DeleteRecordSet", "zone/"+zoneID+"/rrset/"+recordSetID, nil, err)
+	return err
+}
@@ -0,0 +1,175 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/metrics"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+)
+
+// fakeIaaSClient implements the methods used by the tests, all other methods panic.
+type fakeIaaSClient struct {
+	IaaSClient
+	err error
+}
+
+//nolint:gocritic // Payload is passed by value to match the shared IaaSClient interface.
+func (f *fakeIaaSClient) CreateVolume(_ context.Context, payload iaas.CreateVolumePayload) (*iaas.Volume, error) {
+	return &iaas.Volume{Id: new("volume-id"), Name: payload.Name}, f.err
+}
+
+func (f *fakeIaaSClient) DeleteVolume(_ context.Context, _ string) error {
+	return f.err
+}
+
+type fakeLoadBalancingClient struct {
+	LoadBalancingClient
+}
+
+func (f *fakeLoadBalancingClient) CreateCredentials(
+	_ context.Context, _ loadbalancer.CreateCredentialsPayload,
+) (*loadbalancer.CreateCredentialsResponse, error) {
+	return &loadbalancer.CreateCredentialsResponse{}, nil
+}
+
+var _ = Describe("Audit", func() {
+	var (
+		events  chan AuditEvent
+		server  *httptest.Server
+		auditor *Auditor
+	)
+
+	BeforeEach(func() {
+		events = make(chan AuditEvent, 10)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			Expect(r.Method).To(Equal(http.MethodPost))
+			var event AuditEvent
+			Expect(json.NewDecoder(r.Body).Decode(&event)).To(Succeed())
+			events <- event
+		}))
+		DeferCleanup(server.Close)
+
+		auditor = NewAuditor(stackitconfig.AuditOpts{Enabled: true, WebhookURL: server.URL}, "test", "eu01", "project-id")
+		auditor.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+		DeferCleanup(func() {
+			Expect(auditor.Shutdown(context.Background())).To(Succeed())
+		})
+	})
+
+	It("should be disabled by default", func() {
+		Expect(NewAuditor(stackitconfig.AuditOpts{}, "test", "eu01", "project-id")).To(BeNil())
+
+		client := &fakeIaaSClient{}
+		Expect(NewAuditedIaaSClient(client, nil)).To(BeIdenticalTo(client))
+	})
+
+	It("should record successful mutating calls", func() {
+		client := NewAuditedIaaSClient(&fakeIaaSClient{}, auditor)
+
+		payload := iaas.CreateVolumePayload{Name: new("my-volume"), Size: new(int64(10))}
+		volume, err := client.CreateVolume(context.Background(), payload)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*volume.Id).To(Equal("volume-id"))
+
+		var event AuditEvent
+		Eventually(events).Should(Receive(&event))
+		Expect(event).To(Equal(AuditEvent{
+			Time:        time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+			Caller:      "test",
+			Project:     "project-id",
+			Region:      "eu01",
+			Service:     "iaas",
+			Operation:   "CreateVolume",
+			Resource:    "volume/my-volume",
+			PayloadHash: payloadHash(payload),
+			Result:      AuditResultSuccess,
+		}))
+		Expect(event.PayloadHash).To(HaveLen(64))
+	})
+
+	It("should record failed mutating calls", func() {
+		client := NewAuditedIaaSClient(&fakeIaaSClient{err: errors.New("injected error")}, auditor)
+
+		Expect(client.DeleteVolume(context.Background(), "volume-id")).To(MatchError("injected error"))
+
+		var event AuditEvent
+		Eventually(events).Should(Receive(&event))
+		Expect(event.Operation).To(Equal("DeleteVolume"))
+		Expect(event.Resource).To(Equal("volume/volume-id"))
+		Expect(event.PayloadHash).To(BeEmpty())
+		Expect(event.Result).To(Equal(AuditResultFailure))
+		Expect(event.Error).To(Equal("injected error"))
+	})
+
+	It("should not record the payload of credentials", func() {
+		client := NewAuditedLoadBalancingClient(&fakeLoadBalancingClient{}, auditor)
+
+		_, err := client.CreateCredentials(context.Background(), loadbalancer.CreateCredentialsPayload{
+			DisplayName: new("my-credentials"),
+			Password:    new("secret"),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		var event AuditEvent
+		Eventually(events).Should(Receive(&event))
+		Expect(event.Service).To(Equal("loadbalancer"))
+		Expect(event.Resource).To(Equal("credentials/my-credentials"))
+		data, err := json.Marshal(event)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).NotTo(ContainSubstring("secret"))
+	})
+
+	It("should send the queued events on shutdown", func() {
+		client := NewAuditedIaaSClient(&fakeIaaSClient{}, auditor)
+
+		for range 3 {
+			Expect(client.DeleteVolume(context.Background(), "volume-id")).To(Succeed())
+		}
+		Expect(auditor.Shutdown(context.Background())).To(Succeed())
+		Expect(events).To(HaveLen(3))
+
+		// Events recorded after the shutdown are only logged.
+		dropped := testutil.ToFloat64(metrics.AuditEventsDropped)
+		Expect(client.DeleteVolume(context.Background(), "volume-id")).To(Succeed())
+		Expect(testutil.ToFloat64(metrics.AuditEventsDropped)).To(Equal(dropped + 1))
+		Expect(events).To(HaveLen(3))
+	})
+
+	It("should drop events without blocking the call if the queue is full", func() {
+		blocked := make(chan struct{})
+		server.Config.Handler = http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			<-blocked
+		})
+		DeferCleanup(func() { close(blocked) })
+		client := NewAuditedIaaSClient(&fakeIaaSClient{}, auditor)
+		dropped := testutil.ToFloat64(metrics.AuditEventsDropped)
+
+		// The worker takes one event from the queue and blocks sending it.
+		for range auditQueueSize + 2 {
+			Expect(client.DeleteVolume(context.Background(), "volume-id")).To(Succeed())
+		}
+		Expect(testutil.ToFloat64(metrics.AuditEventsDropped)).To(BeNumerically(">=", dropped+1))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		Expect(auditor.Shutdown(ctx)).To(MatchError(context.DeadlineExceeded))
+	})
+
+	It("should not fail the call if the webhook is unavailable", func() {
+		server.Close()
+		client := NewAuditedIaaSClient(&fakeIaaSClient{}, auditor)
+
+		Expect(client.DeleteVolume(context.Background(), "volume-id")).To(Succeed())
+	})
+})
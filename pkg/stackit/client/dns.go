@@ -0,0 +1,187 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+	"github.com/stackitcloud/stackit-sdk-go/core/auth"
+	sdkconfig "github.com/stackitcloud/stackit-sdk-go/core/config"
+	oapiError "github.com/stackitcloud/stackit-sdk-go/core/oapierror"
+)
+
+// DefaultDNSAPI is the URL of the STACKIT DNS API.
+const DefaultDNSAPI = "https://dns.api.stackit.cloud"
+
+// DNSClient manages the record sets of STACKIT DNS zones.
+type DNSClient interface {
+	// ListRecordSets returns the active record sets of the zone with the given name and type.
+	ListRecordSets(ctx context.Context, zoneID, name, recordType string) ([]DNSRecordSet, error)
+	CreateRecordSet(ctx context.Context, zoneID string, recordSet DNSRecordSet) (*DNSRecordSet, error)
+	UpdateRecordSet(ctx context.Context, zoneID, recordSetID string, recordSet DNSRecordSet) error
+	DeleteRecordSet(ctx context.Context, zoneID, recordSetID string) error
+}
+
+// DNSRecordSet is a record set of the STACKIT DNS API.
+type DNSRecordSet struct {
+	ID string `json:"id,omitempty"`
+	// Name is the fully qualified name of the record set, including the trailing dot.
+	Name    string      `json:"name"`
+	Type    string      `json:"type,omitempty"`
+	TTL     int64       `json:"ttl,omitempty"`
+	Comment string      `json:"comment,omitempty"`
+	Records []DNSRecord `json:"records"`
+	State   string      `json:"state,omitempty"`
+}
+
+type DNSRecord struct {
+	Content string `json:"content"`
+}
+
+// dnsRecordSetStateDeleted is the state of record sets that were deleted but are still listed by the API.
+const dnsRecordSetStateDeleted = "DELETE_SUCCEEDED"
+
+// dnsClient calls the REST API directly, because the STACKIT SDK used by this module doesn't contain a DNS client.
+// Authentication, endpoints and the HTTP client are configured with the options of the SDK like for the other APIs.
+type dnsClient struct {
+	httpClient *http.Client
+	baseURL    string
+	userAgent  string
+	projectID  string
+	timeouts   stackitconfig.APITimeouts
+}
+
+func NewDNSClient(projectID string, timeouts stackitconfig.APITimeouts, options []sdkconfig.ConfigurationOption) (DNSClient, error) {
+	cfg := &sdkconfig.Configuration{Servers: sdkconfig.ServerConfigurations{{URL: DefaultDNSAPI}}}
+	for _, option := range options {
+		if err := option(cfg); err != nil {
+			return nil, fmt.Errorf("configuring the client: %w", err)
+		}
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{}
+	}
+	roundTripper, err := auth.SetupAuth(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("setting up authentication: %w", err)
+	}
+	if cfg.Middleware != nil {
+		roundTripper = sdkconfig.ChainMiddleware(roundTripper, cfg.Middleware...)
+	}
+	httpClient := *cfg.HTTPClient
+	httpClient.Transport = roundTripper
+
+	return &dnsClient{
+		httpClient: &httpClient,
+		baseURL:    strings.TrimSuffix(cfg.Servers[0].URL, "/"),
+		userAgent:  cfg.UserAgent,
+		projectID:  projectID,
+		timeouts:   timeouts,
+	}, nil
+}
+
+func (d *dnsClient) ListRecordSets(ctx context.Context, zoneID, name, recordType string) ([]DNSRecordSet, error) {
+	query := url.Values{}
+	query.Set("name[eq]", name)
+	query.Set("type[eq]", recordType)
+	query.Set("state[neq]", dnsRecordSetStateDeleted)
+	var resp struct {
+		RRSets []DNSRecordSet `json:"rrSets"`
+	}
+	err := d.do(ctx, http.MethodGet, d.recordSetsPath(zoneID)+"?"+query.Encode(), nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	// The filters are applied again in case the API ignores them.
+	var recordSets []DNSRecordSet
+	for _, recordSet := range resp.RRSets {
+		if recordSet.Name == name && recordSet.Type == recordType && recordSet.State != dnsRecordSetStateDeleted {
+			recordSets = append(recordSets, recordSet)
+		}
+	}
+	return recordSets, nil
+}
+
+func (d *dnsClient) CreateRecordSet(ctx context.Context, zoneID string, recordSet DNSRecordSet) (*DNSRecordSet, error) {
+	var resp struct {
+		RRSet DNSRecordSet `json:"rrset"`
+	}
+	if err := d.do(ctx, http.MethodPost, d.recordSetsPath(zoneID), recordSet, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.RRSet, nil
+}
+
+func (d *dnsClient) UpdateRecordSet(ctx context.Context, zoneID, recordSetID string, recordSet DNSRecordSet) error {
+	// Name and type of a record set can't be changed.
+	payload := struct {
+		TTL     int64       `json:"ttl,omitempty"`
+		Comment string      `json:"comment,omitempty"`
+		Records []DNSRecord `json:"records"`
+	}{TTL: recordSet.TTL, Comment: recordSet.Comment, Records: recordSet.Records}
+	return d.do(ctx, http.MethodPatch, d.recordSetsPath(zoneID)+"/"+url.PathEscape(recordSetID), payload, nil)
+}
+
+func (d *dnsClient) DeleteRecordSet(ctx context.Context, zoneID, recordSetID string) error {
+	return d.do(ctx, http.MethodDelete, d.recordSetsPath(zoneID)+"/"+url.PathEscape(recordSetID), nil, nil)
+}
+
+func (d *dnsClient) recordSetsPath(zoneID string) string {
+	return fmt.Sprintf("/v1/projects/%s/zones/%s/rrsets", url.PathEscape(d.projectID), url.PathEscape(zoneID))
+}
+
+// do sends a request to the API and decodes the response into out, if it is not nil.
+// Errors of the API are returned as oapierror.GenericOpenAPIError like the errors of the SDK clients.
+func (d *dnsClient) do(ctx context.Context, method, path string, in, out any) error {
+	_, err := withResponseID(ctx, d.timeouts.Request.Duration, func(ctx context.Context) (any, error) {
+		var body io.Reader
+		if in != nil {
+			data, err := json.Marshal(in)
+			if err != nil {
+				return nil, err
+			}
+			body = bytes.NewReader(data)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, d.baseURL+path, body)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		if in != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if d.userAgent != "" {
+			req.Header.Set("User-Agent", d.userAgent)
+		}
+
+		resp, err := d.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		// withResponseID reads the headers of the captured response.
+		if captured, ok := ctx.Value(sdkconfig.ContextHTTPResponse).(**http.Response); ok {
+			*captured = resp
+		}
+
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode >= http.StatusMultipleChoices {
+			return nil, &oapiError.GenericOpenAPIError{StatusCode: resp.StatusCode, Body: data, ErrorMessage: resp.Status}
+		}
+		if out == nil || len(data) == 0 {
+			return nil, nil
+		}
+		return nil, json.Unmarshal(data, out)
+	})
+	return err
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	sdkconfig "github.com/stackitcloud/stackit-sdk-go/core/config"
+
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
+)
+
+var _ = Describe("DNSClient", func() {
+	type request struct {
+		method string
+		url    string
+		body   map[string]any
+	}
+
+	var (
+		server    *httptest.Server
+		requests  []request
+		status    int
+		response  string
+		dnsClient DNSClient
+	)
+
+	BeforeEach(func() {
+		requests = nil
+		status = http.StatusOK
+		response = ""
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req := request{method: r.Method, url: r.URL.String()}
+			data, _ := io.ReadAll(r.Body)
+			if len(data) > 0 {
+				Expect(json.Unmarshal(data, &req.body)).To(Succeed())
+			}
+			requests = append(requests, req)
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(response))
+		}))
+		DeferCleanup(server.Close)
+
+		var err error
+		dnsClient, err = NewDNSClient("project", stackitconfig.APITimeouts{},
+			[]sdkconfig.ConfigurationOption{sdkconfig.WithEndpoint(server.URL), sdkconfig.WithoutAuthentication()})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should list the active record sets with the name and type", func() {
+		response = `{"rrSets": [
+			{"id": "1", "name": "app.example.com.", "type": "A", "records": [{"content": "1.2.3.4"}]},
+			{"id": "2", "name": "app.example.com.", "type": "A", "state": "DELETE_SUCCEEDED"},
+			{"id": "3", "name": "other.example.com.", "type": "A"}
+		]}`
+
+		recordSets, err := dnsClient.ListRecordSets(context.Background(), "zone", "app.example.com.", "A")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recordSets).To(ConsistOf(DNSRecordSet{
+			ID: "1", Name: "app.example.com.", Type: "A", Records: []DNSRecord{{Content: "1.2.3.4"}},
+		}))
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].method).To(Equal(http.MethodGet))
+		Expect(requests[0].url).To(Equal(
+			"/v1/projects/project/zones/zone/rrsets?name%5Beq%5D=app.example.com.&state%5Bneq%5D=DELETE_SUCCEEDED&type%5Beq%5D=A"))
+	})
+
+	It("should create a record set", func() {
+		response = `{"rrset": {"id": "1", "name": "app.example.com."}}`
+
+		created, err := dnsClient.CreateRecordSet(context.Background(), "zone", DNSRecordSet{
+			Name: "app.example.com.", Type: "A", TTL: 60, Records: []DNSRecord{{Content: "1.2.3.4"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(created.ID).To(Equal("1"))
+		Expect(requests).To(ConsistOf(request{
+			method: http.MethodPost,
+			url:    "/v1/projects/project/zones/zone/rrsets",
+			body: map[string]any{
+				"name": "app.example.com.", "type": "A", "ttl": float64(60),
+				"records": []any{map[string]any{"content": "1.2.3.4"}},
+			},
+		}))
+	})
+
+	It("should only send the mutable fields in updates", func() {
+		Expect(dnsClient.UpdateRecordSet(context.Background(), "zone", "1", DNSRecordSet{
+			Name: "app.example.com.", Type: "A", TTL: 60, Comment: "owner", Records: []DNSRecord{{Content: "1.2.3.4"}},
+		})).To(Succeed())
+		Expect(requests).To(ConsistOf(request{
+			method: http.MethodPatch,
+			url:    "/v1/projects/project/zones/zone/rrsets/1",
+			body: map[string]any{
+				"ttl": float64(60), "comment": "owner",
+				"records": []any{map[string]any{"content": "1.2.3.4"}},
+			},
+		}))
+	})
+
+	It("should return API errors like the SDK clients", func() {
+		status = http.StatusNotFound
+		response = `{"message": "not found"}`
+
+		err := dnsClient.DeleteRecordSet(context.Background(), "zone", "1")
+		Expect(stackiterrors.IsNotFound(err)).To(BeTrue())
+		Expect(requests).To(ConsistOf(request{method: http.MethodDelete, url: "/v1/projects/project/zones/zone/rrsets/1"}))
+	})
+})
@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
 	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
@@ -40,26 +41,48 @@ type Factory interface {
 
 	// IaaS returns a STACKIT IaaS service client.
 	IaaS(options []sdkconfig.ConfigurationOption) (IaaSClient, error)
+
+	// DNS returns a STACKIT DNS service client.
+	DNS(options []sdkconfig.ConfigurationOption) (DNSClient, error)
 }
 
 type factory struct {
 	StackitRegion    string
 	StackitProjectID string
+	Timeouts         stackitconfig.APITimeouts
 }
 
-func New(region, projectID string) Factory {
+const (
+	DefaultRequestTimeout = 30 * time.Second
+	DefaultWaitTimeout    = 5 * time.Minute
+)
+
+// New returns a factory for clients in the given region and project.
+// Unset timeouts default to DefaultRequestTimeout and DefaultWaitTimeout.
+func New(region, projectID string, timeouts stackitconfig.APITimeouts) Factory {
+	if timeouts.Request.Duration == 0 {
+		timeouts.Request.Duration = DefaultRequestTimeout
+	}
+	if timeouts.Wait.Duration == 0 {
+		timeouts.Wait.Duration = DefaultWaitTimeout
+	}
 	return &factory{
 		StackitRegion:    region,
 		StackitProjectID: projectID,
+		Timeouts:         timeouts,
 	}
 }
 
 func (f factory) LoadBalancing(options []sdkconfig.ConfigurationOption) (LoadBalancingClient, error) {
-	return NewLoadBalancingClient(f.StackitRegion, f.StackitProjectID, withDefaultOptions(options))
+	return NewLoadBalancingClient(f.StackitRegion, f.StackitProjectID, f.Timeouts, withDefaultOptions(options))
 }
 
 func (f factory) IaaS(options []sdkconfig.ConfigurationOption) (IaaSClient, error) {
-	return NewIaaSClient(f.StackitRegion, f.StackitProjectID, withDefaultOptions(options))
+	return NewIaaSClient(f.StackitRegion, f.StackitProjectID, f.Timeouts, withDefaultOptions(options))
+}
+
+func (f factory) DNS(options []sdkconfig.ConfigurationOption) (DNSClient, error) {
+	return NewDNSClient(f.StackitProjectID, f.Timeouts, withDefaultOptions(options))
 }
 
 func withDefaultOptions(options []sdkconfig.ConfigurationOption) []sdkconfig.ConfigurationOption {
@@ -69,6 +69,31 @@ global:
 		})
 	})
 
+	Describe("API timeouts", func() {
+		It("should parse the API timeouts", func() {
+			cfg, err := GetConfig(strings.NewReader(`
+global:
+  apiTimeouts:
+    request: "10s"
+    wait: "10m"`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.Global.APITimeouts).To(Equal(stackitconfig.APITimeouts{
+				Request: metadata.Duration{Duration: 10 * time.Second},
+				Wait:    metadata.Duration{Duration: 10 * time.Minute},
+			}))
+		})
+
+		It("should default unset timeouts", func() {
+			f := New("eu01", "test-project", stackitconfig.APITimeouts{
+				Wait: metadata.Duration{Duration: time.Minute},
+			})
+			Expect(f.(*factory).Timeouts).To(Equal(stackitconfig.APITimeouts{
+				Request: metadata.Duration{Duration: DefaultRequestTimeout},
+				Wait:    metadata.Duration{Duration: time.Minute},
+			}))
+		})
+	})
+
 	Describe("GetConfigFromFile", func() {
 		var tempFile *os.File
 		var tempFilePath string
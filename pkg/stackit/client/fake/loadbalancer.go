@@ -0,0 +1,407 @@
+// Package fake implements in-memory fakes of the STACKIT API clients for unit tests.
+// Unlike the generated mocks, the fakes keep state between calls, so tests can drive whole lifecycles without
+// setting up expectations for every call.
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
+	oapiError "github.com/stackitcloud/stackit-sdk-go/core/oapierror"
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+)
+
+// The operations of the load balancing client, as passed to the error hooks.
+const (
+	OpCreateLoadBalancer = "CreateLoadBalancer"
+	OpGetLoadBalancer    = "GetLoadBalancer"
+	OpListLoadBalancers  = "ListLoadBalancers"
+	OpUpdateLoadBalancer = "UpdateLoadBalancer"
+	OpDeleteLoadBalancer = "DeleteLoadBalancer"
+	OpUpdateTargetPool   = "UpdateTargetPool"
+	OpGetQuota           = "GetQuota"
+	OpListPlans          = "ListPlans"
+	OpCreateCredentials  = "CreateCredentials"
+	OpListCredentials    = "ListCredentials"
+	OpUpdateCredentials  = "UpdateCredentials"
+	OpDeleteCredentials  = "DeleteCredentials"
+)
+
+var _ stackitclient.LoadBalancingClient = &LoadBalancingClient{}
+
+// LoadBalancingClient is an in-memory fake of stackitclient.LoadBalancingClient.
+// It is safe for concurrent use. Configure it before the first call.
+type LoadBalancingClient struct {
+	// Latency delays every call. Calls fail with the context error if the context is done earlier.
+	Latency time.Duration
+	// PendingGets is the number of GetLoadBalancer calls that return a created or updated load balancer in
+	// STATUS_PENDING, before it becomes STATUS_READY. Defaults to 0, i.e. load balancers are ready immediately.
+	PendingGets int
+	// Hook is called before every call with the operation and the name of the load balancer or the reference of
+	// the credentials, if any. If it returns an error, the call fails with it without changing any state.
+	Hook func(op, name string) error
+	// MaxLoadBalancers is the load balancer quota of the project. Creating more load balancers fails like in the
+	// real API. Defaults to 0, i.e. unlimited.
+	MaxLoadBalancers int32
+	// Plans are returned by ListPlans. Defaults to no plans.
+	Plans []loadbalancer.PlanDetails
+
+	mu            sync.Mutex
+	loadBalancers map[string]*loadbalancer.LoadBalancer
+	pendingGets   map[string]int
+	credentials   map[string]*loadbalancer.CredentialsResponse
+	failures      map[string][]error
+	calls         []string
+	nextID        int
+}
+
+// NewLoadBalancingClient returns a fake without load balancers and credentials.
+func NewLoadBalancingClient() *LoadBalancingClient {
+	return &LoadBalancingClient{
+		loadBalancers: map[string]*loadbalancer.LoadBalancer{},
+		pendingGets:   map[string]int{},
+		credentials:   map[string]*loadbalancer.CredentialsResponse{},
+		failures:      map[string][]error{},
+	}
+}
+
+// APIError returns an error like the ones returned by the API for the status code.
+func APIError(statusCode int, message string) error {
+	return stackiterrors.Classify(&oapiError.GenericOpenAPIError{
+		StatusCode:   statusCode,
+		Body:         []byte(message),
+		ErrorMessage: message,
+	})
+}
+
+// FailNext makes the next calls of op fail with errs, one error per call, e.g. to play back a conflict followed
+// by a quota error. The errors are returned before Hook is called.
+func (c *LoadBalancingClient) FailNext(op string, errs ...error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failures[op] = append(c.failures[op], errs...)
+}
+
+// AddLoadBalancer stores lb as if it had been created and became ready.
+func (c *LoadBalancingClient) AddLoadBalancer(lb *loadbalancer.LoadBalancer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lb = clone(lb)
+	if lb.Status == nil {
+		lb.Status = new(loadbalancer.LOADBALANCERSTATUS_STATUS_READY)
+	}
+	if lb.Version == nil {
+		lb.Version = new("1")
+	}
+	c.loadBalancers[lb.GetName()] = lb
+}
+
+// LoadBalancer returns a copy of the stored load balancer or nil if it doesn't exist.
+func (c *LoadBalancingClient) LoadBalancer(name string) *loadbalancer.LoadBalancer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return clone(c.loadBalancers[name])
+}
+
+// SetStatus changes the status of a stored load balancer, e.g. to simulate an error state.
+func (c *LoadBalancingClient) SetStatus(name string, status loadbalancer.LoadBalancerStatus, errs ...loadbalancer.LoadBalancerError) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if lb, ok := c.loadBalancers[name]; ok {
+		lb.Status = new(status)
+		lb.Errors = errs
+		delete(c.pendingGets, name)
+	}
+}
+
+// Credentials returns copies of all stored credentials.
+func (c *LoadBalancingClient) Credentials() []loadbalancer.CredentialsResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	res := make([]loadbalancer.CredentialsResponse, 0, len(c.credentials))
+	for _, creds := range c.credentials {
+		res = append(res, *clone(creds))
+	}
+	return res
+}
+
+// Calls returns all calls in order as "<operation> <name>", or only the operation if it has no name.
+func (c *LoadBalancingClient) Calls() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.calls)
+}
+
+// call records the call, waits for the latency and returns the injected error, if any.
+func (c *LoadBalancingClient) call(ctx context.Context, op, name string) error {
+	c.mu.Lock()
+	if name == "" {
+		c.calls = append(c.calls, op)
+	} else {
+		c.calls = append(c.calls, op+" "+name)
+	}
+	var err error
+	if failures := c.failures[op]; len(failures) > 0 {
+		err, c.failures[op] = failures[0], failures[1:]
+	}
+	c.mu.Unlock()
+
+	if c.Latency > 0 {
+		timer := time.NewTimer(c.Latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if c.Hook != nil {
+		return c.Hook(op, name)
+	}
+	return nil
+}
+
+// initialStatus returns the status of a created or updated load balancer. The caller must hold c.mu.
+func (c *LoadBalancingClient) initialStatus(name string) *loadbalancer.LoadBalancerStatus {
+	if c.PendingGets > 0 {
+		c.pendingGets[name] = c.PendingGets
+		return new(loadbalancer.LOADBALANCERSTATUS_STATUS_PENDING)
+	}
+	return new(loadbalancer.LOADBALANCERSTATUS_STATUS_READY)
+}
+
+func (c *LoadBalancingClient) CreateLoadBalancer(ctx context.Context, payload *loadbalancer.CreateLoadBalancerPayload) (*loadbalancer.LoadBalancer, error) {
+	name := payload.GetName()
+	if err := c.call(ctx, OpCreateLoadBalancer, name); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.loadBalancers[name]; ok {
+		return nil, APIError(http.StatusConflict, "load balancer already exists")
+	}
+	if c.MaxLoadBalancers > 0 && len(c.loadBalancers) >= int(c.MaxLoadBalancers) {
+		return nil, APIError(http.StatusForbidden, "quota exceeded for load balancers")
+	}
+	lb := convert[loadbalancer.LoadBalancer](payload)
+	lb.Status = c.initialStatus(name)
+	lb.Version = new("1")
+	c.nextID++
+	if lb.Options != nil && lb.Options.GetPrivateNetworkOnly() {
+		lb.PrivateAddress = new(fmt.Sprintf("10.0.0.%d", c.nextID))
+	} else if lb.ExternalAddress == nil {
+		lb.ExternalAddress = new(fmt.Sprintf("192.0.2.%d", c.nextID))
+		if lb.Options == nil {
+			lb.Options = &loadbalancer.LoadBalancerOptions{}
+		}
+		lb.Options.EphemeralAddress = new(true)
+	}
+	c.loadBalancers[name] = lb
+	return clone(lb), nil
+}
+
+func (c *LoadBalancingClient) GetLoadBalancer(ctx context.Context, name string) (*loadbalancer.LoadBalancer, error) {
+	if err := c.call(ctx, OpGetLoadBalancer, name); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lb, ok := c.loadBalancers[name]
+	if !ok {
+		return nil, APIError(http.StatusNotFound, "load balancer not found")
+	}
+	res := clone(lb)
+	if c.pendingGets[name] > 0 {
+		c.pendingGets[name]--
+	} else if lb.GetStatus() == loadbalancer.LOADBALANCERSTATUS_STATUS_PENDING {
+		delete(c.pendingGets, name)
+		lb.Status = new(loadbalancer.LOADBALANCERSTATUS_STATUS_READY)
+		res.Status = lb.Status
+	}
+	return res, nil
+}
+
+func (c *LoadBalancingClient) ListLoadBalancers(ctx context.Context) ([]loadbalancer.LoadBalancer, error) {
+	if err := c.call(ctx, OpListLoadBalancers, ""); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	res := make([]loadbalancer.LoadBalancer, 0, len(c.loadBalancers))
+	for _, lb := range c.loadBalancers {
+		res = append(res, *clone(lb))
+	}
+	return res, nil
+}
+
+func (c *LoadBalancingClient) UpdateLoadBalancer(ctx context.Context, name string, update *loadbalancer.UpdateLoadBalancerPayload) (*loadbalancer.LoadBalancer, error) {
+	if err := c.call(ctx, OpUpdateLoadBalancer, name); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lb, ok := c.loadBalancers[name]
+	if !ok {
+		return nil, APIError(http.StatusNotFound, "load balancer not found")
+	}
+	if update.Version != nil && update.GetVersion() != lb.GetVersion() {
+		return nil, APIError(http.StatusConflict, "version conflict")
+	}
+	version, _ := strconv.Atoi(lb.GetVersion())
+	updated := convert[loadbalancer.LoadBalancer](update)
+	updated.Name = lb.Name
+	updated.Status = c.initialStatus(name)
+	updated.Version = new(strconv.Itoa(version + 1))
+	updated.PrivateAddress = lb.PrivateAddress
+	if updated.ExternalAddress == nil {
+		updated.ExternalAddress = lb.ExternalAddress
+	}
+	c.loadBalancers[name] = updated
+	return clone(updated), nil
+}
+
+func (c *LoadBalancingClient) DeleteLoadBalancer(ctx context.Context, name string) error {
+	if err := c.call(ctx, OpDeleteLoadBalancer, name); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Deleting a load balancer that doesn't exist succeeds, like in the real API.
+	delete(c.loadBalancers, name)
+	delete(c.pendingGets, name)
+	return nil
+}
+
+func (c *LoadBalancingClient) UpdateTargetPool(ctx context.Context, name, targetPoolName string, payload loadbalancer.UpdateTargetPoolPayload) error {
+	if err := c.call(ctx, OpUpdateTargetPool, name); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lb, ok := c.loadBalancers[name]
+	if !ok {
+		return APIError(http.StatusNotFound, "load balancer not found")
+	}
+	i := slices.IndexFunc(lb.TargetPools, func(p loadbalancer.TargetPool) bool { return p.GetName() == targetPoolName })
+	if i < 0 {
+		return APIError(http.StatusNotFound, "target pool not found")
+	}
+	lb.TargetPools[i] = *convert[loadbalancer.TargetPool](payload)
+	return nil
+}
+
+// GetQuota returns MaxLoadBalancers and the number of stored load balancers and credentials.
+// Without quota, the maximum is reported as 1000.
+func (c *LoadBalancingClient) GetQuota(ctx context.Context) (*loadbalancer.GetQuotaResponse, error) {
+	if err := c.call(ctx, OpGetQuota, ""); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	maxLoadBalancers := c.MaxLoadBalancers
+	if maxLoadBalancers == 0 {
+		maxLoadBalancers = 1000
+	}
+	return &loadbalancer.GetQuotaResponse{
+		MaxLoadBalancers:  new(maxLoadBalancers),
+		UsedLoadBalancers: new(int32(len(c.loadBalancers))),
+		MaxCredentials:    new(int32(1000)),
+		UsedCredentials:   new(int32(len(c.credentials))),
+	}, nil
+}
+
+func (c *LoadBalancingClient) ListPlans(ctx context.Context) ([]loadbalancer.PlanDetails, error) {
+	if err := c.call(ctx, OpListPlans, ""); err != nil {
+		return nil, err
+	}
+	return slices.Clone(c.Plans), nil
+}
+
+func (c *LoadBalancingClient) CreateCredentials(ctx context.Context, payload loadbalancer.CreateCredentialsPayload) (*loadbalancer.CreateCredentialsResponse, error) {
+	if err := c.call(ctx, OpCreateCredentials, ""); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nextID++
+	creds := &loadbalancer.CredentialsResponse{
+		CredentialsRef: new(fmt.Sprintf("credentials-%d", c.nextID)),
+		DisplayName:    payload.DisplayName,
+		Username:       payload.Username,
+	}
+	c.credentials[creds.GetCredentialsRef()] = creds
+	return &loadbalancer.CreateCredentialsResponse{Credential: clone(creds)}, nil
+}
+
+func (c *LoadBalancingClient) ListCredentials(ctx context.Context) (*loadbalancer.ListCredentialsResponse, error) {
+	if err := c.call(ctx, OpListCredentials, ""); err != nil {
+		return nil, err
+	}
+	return &loadbalancer.ListCredentialsResponse{Credentials: c.Credentials()}, nil
+}
+
+func (c *LoadBalancingClient) UpdateCredentials(ctx context.Context, credentialsRef string, payload loadbalancer.UpdateCredentialsPayload) error {
+	if err := c.call(ctx, OpUpdateCredentials, credentialsRef); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	creds, ok := c.credentials[credentialsRef]
+	if !ok {
+		return APIError(http.StatusNotFound, "credentials not found")
+	}
+	creds.DisplayName = payload.DisplayName
+	creds.Username = payload.Username
+	return nil
+}
+
+func (c *LoadBalancingClient) DeleteCredentials(ctx context.Context, credentialsRef string) error {
+	if err := c.call(ctx, OpDeleteCredentials, credentialsRef); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, lb := range c.loadBalancers {
+		if lb.Options != nil && lb.Options.Observability != nil && lb.Options.Observability.Metrics != nil &&
+			lb.Options.Observability.Metrics.GetCredentialsRef() == credentialsRef {
+			return APIError(http.StatusBadRequest, "credentials are still referenced by load balancer "+lb.GetName())
+		}
+	}
+	if _, ok := c.credentials[credentialsRef]; !ok {
+		return APIError(http.StatusNotFound, "credentials not found")
+	}
+	delete(c.credentials, credentialsRef)
+	return nil
+}
+
+// convert copies the fields of from to a new T with the same JSON representation,
+// e.g. a payload to the load balancer it creates.
+func convert[T any](from any) *T {
+	data, err := json.Marshal(from)
+	if err != nil {
+		panic(fmt.Sprintf("failed to marshal %T: %v", from, err))
+	}
+	to := new(T)
+	if err := json.Unmarshal(data, to); err != nil {
+		panic(fmt.Sprintf("failed to unmarshal %T: %v", to, err))
+	}
+	return to
+}
+
+func clone[T any](v *T) *T {
+	if v == nil {
+		return nil
+	}
+	return convert[T](v)
+}
@@ -0,0 +1,125 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+)
+
+var _ = Describe("LoadBalancingClient", func() {
+	var (
+		ctx    context.Context
+		client *LoadBalancingClient
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client = NewLoadBalancingClient()
+	})
+
+	It("should create a load balancer that becomes ready after the pending gets", func() {
+		client.PendingGets = 2
+		lb, err := client.CreateLoadBalancer(ctx, &loadbalancer.CreateLoadBalancerPayload{Name: new("lb")})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lb.GetStatus()).To(Equal(loadbalancer.LOADBALANCERSTATUS_STATUS_PENDING))
+		Expect(lb.GetExternalAddress()).NotTo(BeEmpty())
+
+		for range 2 {
+			lb, err = client.GetLoadBalancer(ctx, "lb")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lb.GetStatus()).To(Equal(loadbalancer.LOADBALANCERSTATUS_STATUS_PENDING))
+		}
+		lb, err = client.GetLoadBalancer(ctx, "lb")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lb.GetStatus()).To(Equal(loadbalancer.LOADBALANCERSTATUS_STATUS_READY))
+	})
+
+	It("should reject updates with an outdated version", func() {
+		client.AddLoadBalancer(&loadbalancer.LoadBalancer{Name: new("lb")})
+
+		lb, err := client.UpdateLoadBalancer(ctx, "lb", &loadbalancer.UpdateLoadBalancerPayload{Version: new("1")})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lb.GetVersion()).To(Equal("2"))
+
+		_, err = client.UpdateLoadBalancer(ctx, "lb", &loadbalancer.UpdateLoadBalancerPayload{Version: new("1")})
+		Expect(err).To(MatchError(stackiterrors.ErrConflict))
+	})
+
+	It("should reject load balancers exceeding the quota", func() {
+		client.MaxLoadBalancers = 1
+		_, err := client.CreateLoadBalancer(ctx, &loadbalancer.CreateLoadBalancerPayload{Name: new("lb")})
+		Expect(err).NotTo(HaveOccurred())
+
+		quota, err := client.GetQuota(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(quota.GetUsedLoadBalancers()).To(BeEquivalentTo(1))
+		Expect(quota.GetMaxLoadBalancers()).To(BeEquivalentTo(1))
+
+		_, err = client.CreateLoadBalancer(ctx, &loadbalancer.CreateLoadBalancerPayload{Name: new("other")})
+		Expect(err).To(MatchError(stackiterrors.ErrQuotaExceeded))
+	})
+
+	It("should return not found errors like the API", func() {
+		_, err := client.GetLoadBalancer(ctx, "lb")
+		Expect(stackiterrors.IsNotFound(err)).To(BeTrue())
+		Expect(client.DeleteLoadBalancer(ctx, "lb")).To(Succeed())
+	})
+
+	It("should play back injected errors in order", func() {
+		client.FailNext(OpGetLoadBalancer, APIError(http.StatusForbidden, "quota exceeded"), errors.New("connection reset"))
+		client.AddLoadBalancer(&loadbalancer.LoadBalancer{Name: new("lb")})
+
+		_, err := client.GetLoadBalancer(ctx, "lb")
+		Expect(err).To(MatchError(stackiterrors.ErrQuotaExceeded))
+		_, err = client.GetLoadBalancer(ctx, "lb")
+		Expect(err).To(MatchError("connection reset"))
+		_, err = client.GetLoadBalancer(ctx, "lb")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.Calls()).To(Equal([]string{"GetLoadBalancer lb", "GetLoadBalancer lb", "GetLoadBalancer lb"}))
+	})
+
+	It("should fail calls for which the hook returns an error without changing the state", func() {
+		client.Hook = func(op, _ string) error {
+			if op == OpCreateLoadBalancer {
+				return errors.New("injected error")
+			}
+			return nil
+		}
+
+		_, err := client.CreateLoadBalancer(ctx, &loadbalancer.CreateLoadBalancerPayload{Name: new("lb")})
+		Expect(err).To(MatchError("injected error"))
+		Expect(client.LoadBalancer("lb")).To(BeNil())
+	})
+
+	It("should honor the context while waiting for the latency", func() {
+		client.Latency = time.Minute
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+
+		_, err := client.ListLoadBalancers(ctx)
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+
+	It("should refuse to delete credentials that are still in use", func() {
+		res, err := client.CreateCredentials(ctx, loadbalancer.CreateCredentialsPayload{DisplayName: new("creds")})
+		Expect(err).NotTo(HaveOccurred())
+		ref := res.Credential.GetCredentialsRef()
+		client.AddLoadBalancer(&loadbalancer.LoadBalancer{
+			Name: new("lb"),
+			Options: &loadbalancer.LoadBalancerOptions{Observability: &loadbalancer.LoadbalancerOptionObservability{
+				Metrics: &loadbalancer.LoadbalancerOptionMetrics{CredentialsRef: new(ref)},
+			}},
+		})
+
+		Expect(client.DeleteCredentials(ctx, ref)).To(MatchError(stackiterrors.ErrValidation))
+		Expect(client.DeleteLoadBalancer(ctx, "lb")).To(Succeed())
+		Expect(client.DeleteCredentials(ctx, ref)).To(Succeed())
+		Expect(client.Credentials()).To(BeEmpty())
+	})
+})
@@ -3,20 +3,35 @@ package client
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
 	"github.com/stackitcloud/stackit-sdk-go/core/runtime"
 	sdkWait "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api/wait"
+	"k8s.io/klog/v2"
 )
 
-func withResponseID[T any](ctx context.Context, call func(context.Context) (T, error)) (T, error) {
+// withTimeout returns a context that is canceled after timeout. A timeout of zero disables it.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+func withResponseID[T any](ctx context.Context, timeout time.Duration, call func(context.Context) (T, error)) (T, error) {
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+
 	var httpResp *http.Response
 	ctx = runtime.WithCaptureHTTPResponse(ctx, &httpResp)
 
 	resp, err := call(ctx)
 	if err != nil {
 		var zero T
+		err = stackiterrors.Classify(err)
 		if httpResp != nil {
+			err = stackiterrors.WithRetryAfter(err, httpResp.Header.Get("Retry-After"), time.Now())
 			reqID := httpResp.Header.Get(sdkWait.XRequestIDHeader)
 			return zero, stackiterrors.WrapErrorWithResponseID(err, reqID)
 		}
@@ -25,3 +40,18 @@ func withResponseID[T any](ctx context.Context, call func(context.Context) (T, e
 
 	return resp, nil
 }
+
+// withLabelSelector lists resources with the label selector, so that the API only returns matching resources. If the
+// API rejects the selector, the resources are listed without it. Callers must filter the results in any case.
+func withLabelSelector[T any](ctx context.Context, timeout time.Duration, selector string, list func(context.Context, string) (T, error)) (T, error) {
+	resp, err := withResponseID(ctx, timeout, func(ctx context.Context) (T, error) {
+		return list(ctx, selector)
+	})
+	if selector == "" || !stackiterrors.IsInvalidError(err) {
+		return resp, err
+	}
+	klog.V(4).InfoS("API rejected label selector, filtering the resources locally", "labelSelector", selector, "err", err)
+	return withResponseID(ctx, timeout, func(ctx context.Context) (T, error) {
+		return list(ctx, "")
+	})
+}
@@ -7,7 +7,7 @@ import (
 	"slices"
 	"time"
 
-	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
 	sdkconfig "github.com/stackitcloud/stackit-sdk-go/core/config"
 	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -19,18 +19,23 @@ type iaasClient struct {
 	Client    iaas.DefaultAPI
 	projectID string
 	region    string
+	timeouts  stackitconfig.APITimeouts
 }
 
 type IaaSClient interface {
 	GetServer(ctx context.Context, serverID string) (*iaas.Server, error)
 	GetServerWithDetails(ctx context.Context, serverID string) (*iaas.Server, error)
 	ListServers(ctx context.Context) (*[]iaas.Server, error)
+	GetAffinityGroup(ctx context.Context, affinityGroupID string) (*iaas.AffinityGroup, error)
+
+	GetProject(ctx context.Context) (*iaas.Project, error)
+	GetNetwork(ctx context.Context, networkID string) (*iaas.Network, error)
 
 	CreateSnapshot(ctx context.Context, payload iaas.CreateSnapshotPayload) (*iaas.Snapshot, error)
 	ListSnapshots(ctx context.Context, filters map[string]string) ([]iaas.Snapshot, string, error)
 	DeleteSnapshot(ctx context.Context, snapshotID string) error
 	GetSnapshot(ctx context.Context, snapshotID string) (*iaas.Snapshot, error)
-	WaitSnapshotReady(ctx context.Context, snapshotID string) (*string, error)
+	WaitSnapshotReady(ctx context.Context, snapshotID string, backoff *wait.Backoff) (*string, error)
 
 	CreateBackup(ctx context.Context, name, volID, snapshotID string, tags map[string]string) (*iaas.Backup, error)
 	ListBackups(ctx context.Context, filters map[string]string) ([]iaas.Backup, error)
@@ -40,6 +45,7 @@ type IaaSClient interface {
 
 	CreateVolume(ctx context.Context, payload iaas.CreateVolumePayload) (*iaas.Volume, error)
 	DeleteVolume(ctx context.Context, volumeID string) error
+	UpdateVolume(ctx context.Context, volumeID string, payload iaas.UpdateVolumePayload) (*iaas.Volume, error)
 	AttachVolume(ctx context.Context, serverID, volumeID string, payload iaas.AddVolumeToServerPayload) (string, error)
 	DetachVolume(ctx context.Context, serverID, volumeID string) error
 	GetVolume(ctx context.Context, volumeID string) (*iaas.Volume, error)
@@ -47,24 +53,38 @@ type IaaSClient interface {
 	ListVolumes(ctx context.Context, _ int, _ string) ([]iaas.Volume, string, error)
 	ExpandVolume(ctx context.Context, volumeID, volumeStatus string, payload iaas.ResizeVolumePayload) error
 	WaitVolumeTargetStatus(ctx context.Context, volumeID string, tStatus []string) error
-	WaitDiskAttached(ctx context.Context, instanceID, volumeID string) error
-	WaitDiskDetached(ctx context.Context, instanceID, volumeID string) error
+	WaitDiskDetached(ctx context.Context, instanceID, volumeID string, backoff *wait.Backoff) error
 	WaitVolumeTargetStatusWithCustomBackoff(ctx context.Context, volumeID string, tStatus []string, backoff *wait.Backoff) error
+
+	GetVolumePerformanceClass(ctx context.Context, name string) (*iaas.VolumePerformanceClass, error)
+
+	ListSecurityGroupRules(ctx context.Context, securityGroupID string) ([]iaas.SecurityGroupRule, error)
+	CreateSecurityGroupRule(ctx context.Context, securityGroupID string, payload iaas.CreateSecurityGroupRulePayload) (*iaas.SecurityGroupRule, error)
+	DeleteSecurityGroupRule(ctx context.Context, securityGroupID, ruleID string) error
+
+	// ListPublicIPs returns the public IPs of the project that match the label selector, e.g. "key=value,other=value".
+	ListPublicIPs(ctx context.Context, labelSelector string) ([]iaas.PublicIp, error)
+	CreatePublicIP(ctx context.Context, payload iaas.CreatePublicIPPayload) (*iaas.PublicIp, error)
+	UpdatePublicIP(ctx context.Context, publicIPID string, payload iaas.UpdatePublicIPPayload) (*iaas.PublicIp, error)
+	DeletePublicIP(ctx context.Context, publicIPID string) error
 }
 
 const (
-	VolumeAvailableStatus    = "AVAILABLE"
-	VolumeAttachedStatus     = "ATTACHED"
-	operationFinishInitDelay = 1 * time.Second
-	operationFinishFactor    = 1.1
-	operationFinishSteps     = 10
-	diskAttachInitDelay      = 1 * time.Second
-	diskAttachFactor         = 1.2
-	diskAttachSteps          = 15
-	diskDetachInitDelay      = 1 * time.Second
-	diskDetachFactor         = 1.2
-	diskDetachSteps          = 13
-	VolumeDescription        = "Created by STACKIT CSI driver"
+	VolumeAvailableStatus = "AVAILABLE"
+	VolumeAttachedStatus  = "ATTACHED"
+	// VolumeCreatingStatus and VolumeRestoringStatus are the statuses of volumes whose data is still copied from
+	// their source.
+	VolumeCreatingStatus  = "CREATING"
+	VolumeRestoringStatus = "RESTORING-BACKUP"
+	VolumeDescription     = "Created by STACKIT CSI driver"
+)
+
+// Default backoffs of the waiters. The CSI driver allows operators to override them, see
+// stackitconfig.WaitBackoffs.
+var (
+	DefaultVolumeStatusBackoff  = wait.Backoff{Duration: 1 * time.Second, Factor: 1.1, Steps: 10}
+	DefaultDiskDetachBackoff    = wait.Backoff{Duration: 1 * time.Second, Factor: 1.2, Steps: 13}
+	DefaultSnapshotReadyBackoff = wait.Backoff{Duration: 1 * time.Second, Factor: 1.2, Steps: 10}
 )
 
 const (
@@ -79,11 +99,10 @@ const (
 
 const (
 	SnapshotReadyStatus = "AVAILABLE"
-	snapReadyDuration   = 1 * time.Second
-	snapReadyFactor     = 1.2
-	snapReadySteps      = 10
 
 	SnapshotType = "type"
+	// SnapshotGroupLabel is the snapshot label holding the ID of the volume group snapshot it belongs to.
+	SnapshotGroupLabel = "volume-group-snapshot-id"
 )
 
 type VolumeSourceTypes string
@@ -96,7 +115,12 @@ const (
 
 var volumeErrorStates = [...]string{"ERROR", "ERROR_BACKING-UP", "ERROR_DELETING", "ERROR_RESIZING", "ERROR_RESTORING-BACKUP", "ERROR_KMS-ENCRYPTION-PARAMS"}
 
-func NewIaaSClient(region, projectID string, options []sdkconfig.ConfigurationOption) (IaaSClient, error) {
+// IsVolumeErrorStatus returns whether the status is one of the error states of volumes.
+func IsVolumeErrorStatus(status string) bool {
+	return slices.Contains(volumeErrorStates[:], status)
+}
+
+func NewIaaSClient(region, projectID string, timeouts stackitconfig.APITimeouts, options []sdkconfig.ConfigurationOption) (IaaSClient, error) {
 	apiClient, err := iaas.NewAPIClient(options...)
 	if err != nil {
 		return nil, err
@@ -105,23 +129,24 @@ func NewIaaSClient(region, projectID string, options []sdkconfig.ConfigurationOp
 		Client:    apiClient.DefaultAPI,
 		projectID: projectID,
 		region:    region,
+		timeouts:  timeouts,
 	}, nil
 }
 
 func (i *iaasClient) GetServer(ctx context.Context, serverID string) (*iaas.Server, error) {
-	return withResponseID(ctx, func(ctx context.Context) (*iaas.Server, error) {
+	return withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (*iaas.Server, error) {
 		return i.Client.GetServer(ctx, i.projectID, i.region, serverID).Execute()
 	})
 }
 
 func (i *iaasClient) GetServerWithDetails(ctx context.Context, serverID string) (*iaas.Server, error) {
-	return withResponseID(ctx, func(ctx context.Context) (*iaas.Server, error) {
+	return withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (*iaas.Server, error) {
 		return i.Client.GetServer(ctx, i.projectID, i.region, serverID).Details(true).Execute()
 	})
 }
 
 func (i *iaasClient) ListServers(ctx context.Context) (*[]iaas.Server, error) {
-	return withResponseID(ctx, func(ctx context.Context) (*[]iaas.Server, error) {
+	return withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (*[]iaas.Server, error) {
 		resp, err := i.Client.ListServers(ctx, i.projectID, i.region).Details(true).Execute()
 		if err != nil {
 			return nil, err
@@ -131,9 +156,27 @@ func (i *iaasClient) ListServers(ctx context.Context) (*[]iaas.Server, error) {
 	})
 }
 
+func (i *iaasClient) GetAffinityGroup(ctx context.Context, affinityGroupID string) (*iaas.AffinityGroup, error) {
+	return withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (*iaas.AffinityGroup, error) {
+		return i.Client.GetAffinityGroup(ctx, i.projectID, i.region, affinityGroupID).Execute()
+	})
+}
+
+func (i *iaasClient) GetProject(ctx context.Context) (*iaas.Project, error) {
+	return withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (*iaas.Project, error) {
+		return i.Client.GetProjectDetails(ctx, i.projectID).Execute()
+	})
+}
+
+func (i *iaasClient) GetNetwork(ctx context.Context, networkID string) (*iaas.Network, error) {
+	return withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (*iaas.Network, error) {
+		return i.Client.GetNetwork(ctx, i.projectID, i.region, networkID).Execute()
+	})
+}
+
 //nolint:gocritic // Payload is passed by value to match the shared IaaSClient interface.
 func (i *iaasClient) CreateSnapshot(ctx context.Context, payload iaas.CreateSnapshotPayload) (*iaas.Snapshot, error) {
-	return withResponseID(ctx, func(ctx context.Context) (*iaas.Snapshot, error) {
+	return withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (*iaas.Snapshot, error) {
 		return i.Client.
 			CreateSnapshot(ctx, i.projectID, i.region).
 			CreateSnapshotPayload(payload).
@@ -142,9 +185,14 @@ func (i *iaasClient) CreateSnapshot(ctx context.Context, payload iaas.CreateSnap
 }
 
 func (i *iaasClient) ListSnapshots(ctx context.Context, filters map[string]string) ([]iaas.Snapshot, string, error) {
-	resp, err := withResponseID(ctx, func(ctx context.Context) (*iaas.SnapshotListResponse, error) {
-		return i.Client.ListSnapshotsInProject(ctx, i.projectID, i.region).Execute()
-	})
+	resp, err := withLabelSelector(ctx, i.timeouts.Request.Duration, labelSelector(filters),
+		func(ctx context.Context, selector string) (*iaas.SnapshotListResponse, error) {
+			req := i.Client.ListSnapshotsInProject(ctx, i.projectID, i.region)
+			if selector != "" {
+				req = req.LabelSelector(selector)
+			}
+			return req.Execute()
+		})
 	if err != nil {
 		return nil, "", err
 	}
@@ -155,26 +203,27 @@ func (i *iaasClient) ListSnapshots(ctx context.Context, filters map[string]strin
 }
 
 func (i *iaasClient) DeleteSnapshot(ctx context.Context, snapshotID string) error {
-	_, err := withResponseID(ctx, func(ctx context.Context) (any, error) {
+	_, err := withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (any, error) {
 		return nil, i.Client.DeleteSnapshot(ctx, i.projectID, i.region, snapshotID).Execute()
 	})
 	return err
 }
 
 func (i *iaasClient) GetSnapshot(ctx context.Context, snapshotID string) (*iaas.Snapshot, error) {
-	return withResponseID(ctx, func(ctx context.Context) (*iaas.Snapshot, error) {
+	return withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (*iaas.Snapshot, error) {
 		return i.Client.GetSnapshot(ctx, i.projectID, i.region, snapshotID).Execute()
 	})
 }
 
-func (i *iaasClient) WaitSnapshotReady(ctx context.Context, snapshotID string) (*string, error) {
-	backoff := wait.Backoff{
-		Duration: snapReadyDuration,
-		Factor:   snapReadyFactor,
-		Steps:    snapReadySteps,
+// WaitSnapshotReady waits until the snapshot is ready. A nil backoff uses DefaultSnapshotReadyBackoff.
+func (i *iaasClient) WaitSnapshotReady(ctx context.Context, snapshotID string, backoff *wait.Backoff) (*string, error) {
+	if backoff == nil {
+		backoff = new(DefaultSnapshotReadyBackoff)
 	}
 
-	err := wait.ExponentialBackoff(backoff, func() (bool, error) {
+	waitCtx, cancel := withTimeout(ctx, i.timeouts.Wait.Duration)
+	defer cancel()
+	err := wait.ExponentialBackoffWithContext(waitCtx, *backoff, func(ctx context.Context) (bool, error) {
 		ready, err := i.snapshotIsReady(ctx, snapshotID)
 		if err != nil {
 			return false, err
@@ -197,7 +246,7 @@ func (i *iaasClient) WaitSnapshotReady(ctx context.Context, snapshotID string) (
 }
 
 func (i *iaasClient) snapshotIsReady(ctx context.Context, snapshotID string) (bool, error) {
-	snapshot, err := withResponseID(ctx, func(ctx context.Context) (*iaas.Snapshot, error) {
+	snapshot, err := withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (*iaas.Snapshot, error) {
 		return i.Client.GetSnapshot(ctx, i.projectID, i.region, snapshotID).Execute()
 	})
 	if err != nil {
@@ -213,7 +262,7 @@ func (i *iaasClient) CreateBackup(ctx context.Context, name, volID, snapshotID s
 		return nil, err
 	}
 
-	return withResponseID(ctx, func(ctx context.Context) (*iaas.Backup, error) {
+	return withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (*iaas.Backup, error) {
 		return i.Client.
 			CreateBackup(ctx, i.projectID, i.region).
 			CreateBackupPayload(payload).
@@ -257,9 +306,14 @@ func BuildCreateBackupPayload(name, volID, snapshotID string, tags map[string]st
 }
 
 func (i *iaasClient) ListBackups(ctx context.Context, filters map[string]string) ([]iaas.Backup, error) {
-	resp, err := withResponseID(ctx, func(ctx context.Context) (*iaas.BackupListResponse, error) {
-		return i.Client.ListBackups(ctx, i.projectID, i.region).Execute()
-	})
+	resp, err := withLabelSelector(ctx, i.timeouts.Request.Duration, labelSelector(filters),
+		func(ctx context.Context, selector string) (*iaas.BackupListResponse, error) {
+			req := i.Client.ListBackups(ctx, i.projectID, i.region)
+			if selector != "" {
+				req = req.LabelSelector(selector)
+			}
+			return req.Execute()
+		})
 	if err != nil {
 		return nil, err
 	}
@@ -270,21 +324,21 @@ func (i *iaasClient) ListBackups(ctx context.Context, filters map[string]string)
 }
 
 func (i *iaasClient) DeleteBackup(ctx context.Context, backupID string) error {
-	_, err := withResponseID(ctx, func(ctx context.Context) (any, error) {
+	_, err := withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (any, error) {
 		return nil, i.Client.DeleteBackup(ctx, i.projectID, i.region, backupID).Execute()
 	})
 	return err
 }
 
 func (i *iaasClient) GetBackup(ctx context.Context, backupID string) (*iaas.Backup, error) {
-	return withResponseID(ctx, func(ctx context.Context) (*iaas.Backup, error) {
+	return withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (*iaas.Backup, error) {
 		return i.Client.GetBackup(ctx, i.projectID, i.region, backupID).Execute()
 	})
 }
 
 func (i *iaasClient) WaitBackupReady(ctx context.Context, backupID string, snapshotSize int64, backupMaxDurationSecondsPerGB int) (*string, error) {
 	duration := time.Duration(int64(backupMaxDurationSecondsPerGB)*snapshotSize + backupBaseDurationSeconds)
-	err := i.waitBackupReadyWithContext(backupID, duration)
+	err := i.waitBackupReadyWithContext(ctx, backupID, duration)
 	if errors.Is(err, context.DeadlineExceeded) {
 		err = fmt.Errorf("timeout, Backup %s is still not Ready: %w", backupID, err)
 	}
@@ -297,8 +351,10 @@ func (i *iaasClient) WaitBackupReady(ctx context.Context, backupID string, snaps
 	return new("Failed to get backup status"), err
 }
 
-func (i *iaasClient) waitBackupReadyWithContext(backupID string, duration time.Duration) error {
-	ctx, cancel := context.WithTimeout(context.Background(), duration*time.Second)
+// waitBackupReadyWithContext waits for the backup to be ready. Backups of large volumes take longer than the wait
+// timeout, so the deadline is derived from the size of the backup instead.
+func (i *iaasClient) waitBackupReadyWithContext(ctx context.Context, backupID string, duration time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, duration*time.Second)
 	defer cancel()
 	var done bool
 	var err error
@@ -341,7 +397,7 @@ func (i *iaasClient) backupIsReady(ctx context.Context, backupID string) (bool,
 func (i *iaasClient) CreateVolume(ctx context.Context, payload iaas.CreateVolumePayload) (*iaas.Volume, error) {
 	payload.Description = new(VolumeDescription)
 
-	return withResponseID(ctx, func(ctx context.Context) (*iaas.Volume, error) {
+	return withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (*iaas.Volume, error) {
 		return i.Client.CreateVolume(ctx, i.projectID, i.region).CreateVolumePayload(payload).Execute()
 	})
 }
@@ -355,12 +411,19 @@ func (i *iaasClient) DeleteVolume(ctx context.Context, volumeID string) error {
 		return fmt.Errorf("cannot delete the volume %q, it's still attached to a node", volumeID)
 	}
 
-	_, err = withResponseID(ctx, func(ctx context.Context) (any, error) {
+	_, err = withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (any, error) {
 		return nil, i.Client.DeleteVolume(ctx, i.projectID, i.region, volumeID).Execute()
 	})
 	return err
 }
 
+//nolint:gocritic // Payload is passed by value to match the shared IaaSClient interface.
+func (i *iaasClient) UpdateVolume(ctx context.Context, volumeID string, payload iaas.UpdateVolumePayload) (*iaas.Volume, error) {
+	return withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (*iaas.Volume, error) {
+		return i.Client.UpdateVolume(ctx, i.projectID, i.region, volumeID).UpdateVolumePayload(payload).Execute()
+	})
+}
+
 func (i *iaasClient) AttachVolume(ctx context.Context, serverID, volumeID string, payload iaas.AddVolumeToServerPayload) (string, error) {
 	volume, err := i.GetVolume(ctx, volumeID)
 	if err != nil {
@@ -368,11 +431,11 @@ func (i *iaasClient) AttachVolume(ctx context.Context, serverID, volumeID string
 	}
 
 	if volume.ServerId != nil && serverID == *volume.ServerId {
-		klog.V(4).Infof("Disk %s is already attached to instance %s", volumeID, serverID)
+		klog.V(4).InfoS("Disk is already attached to instance", "volumeID", volumeID, "serverID", serverID)
 		return *volume.Id, nil
 	}
 
-	_, err = withResponseID(ctx, func(ctx context.Context) (any, error) {
+	_, err = withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (any, error) {
 		return i.Client.
 			AddVolumeToServer(ctx, i.projectID, i.region, serverID, volumeID).
 			AddVolumeToServerPayload(payload).
@@ -386,13 +449,80 @@ func (i *iaasClient) AttachVolume(ctx context.Context, serverID, volumeID string
 }
 
 func (i *iaasClient) GetVolume(ctx context.Context, volumeID string) (*iaas.Volume, error) {
-	return withResponseID(ctx, func(ctx context.Context) (*iaas.Volume, error) {
+	return withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (*iaas.Volume, error) {
 		return i.Client.GetVolume(ctx, i.projectID, i.region, volumeID).Execute()
 	})
 }
 
+func (i *iaasClient) GetVolumePerformanceClass(ctx context.Context, name string) (*iaas.VolumePerformanceClass, error) {
+	return withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (*iaas.VolumePerformanceClass, error) {
+		return i.Client.GetVolumePerformanceClass(ctx, i.projectID, i.region, name).Execute()
+	})
+}
+
+func (i *iaasClient) ListSecurityGroupRules(ctx context.Context, securityGroupID string) ([]iaas.SecurityGroupRule, error) {
+	resp, err := withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (*iaas.SecurityGroupRuleListResponse, error) {
+		return i.Client.ListSecurityGroupRules(ctx, i.projectID, i.region, securityGroupID).Execute()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Items, nil
+}
+
+func (i *iaasClient) CreateSecurityGroupRule(
+	ctx context.Context, securityGroupID string, payload iaas.CreateSecurityGroupRulePayload,
+) (*iaas.SecurityGroupRule, error) {
+	return withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (*iaas.SecurityGroupRule, error) {
+		return i.Client.CreateSecurityGroupRule(ctx, i.projectID, i.region, securityGroupID).CreateSecurityGroupRulePayload(payload).Execute()
+	})
+}
+
+func (i *iaasClient) DeleteSecurityGroupRule(ctx context.Context, securityGroupID, ruleID string) error {
+	_, err := withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (any, error) {
+		return nil, i.Client.DeleteSecurityGroupRule(ctx, i.projectID, i.region, securityGroupID, ruleID).Execute()
+	})
+	return err
+}
+
+func (i *iaasClient) ListPublicIPs(ctx context.Context, labelSelector string) ([]iaas.PublicIp, error) {
+	resp, err := withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (*iaas.PublicIpListResponse, error) {
+		req := i.Client.ListPublicIPs(ctx, i.projectID, i.region)
+		if labelSelector != "" {
+			req = req.LabelSelector(labelSelector)
+		}
+		return req.Execute()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Items, nil
+}
+
+//nolint:gocritic // Payload is passed by value like the other payloads of the IaaSClient interface.
+func (i *iaasClient) CreatePublicIP(ctx context.Context, payload iaas.CreatePublicIPPayload) (*iaas.PublicIp, error) {
+	return withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (*iaas.PublicIp, error) {
+		return i.Client.CreatePublicIP(ctx, i.projectID, i.region).CreatePublicIPPayload(payload).Execute()
+	})
+}
+
+//nolint:gocritic // Payload is passed by value like the other payloads of the IaaSClient interface.
+func (i *iaasClient) UpdatePublicIP(ctx context.Context, publicIPID string, payload iaas.UpdatePublicIPPayload) (*iaas.PublicIp, error) {
+	return withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (*iaas.PublicIp, error) {
+		return i.Client.UpdatePublicIP(ctx, i.projectID, i.region, publicIPID).UpdatePublicIPPayload(payload).Execute()
+	})
+}
+
+func (i *iaasClient) DeletePublicIP(ctx context.Context, publicIPID string) error {
+	_, err := withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (any, error) {
+		return nil, i.Client.DeletePublicIP(ctx, i.projectID, i.region, publicIPID).Execute()
+	})
+	return err
+}
+
+// GetVolumesByName returns the volumes with the name. The API can't filter volumes by name, so all volumes are listed.
 func (i *iaasClient) GetVolumesByName(ctx context.Context, volName string) ([]iaas.Volume, error) {
-	resp, err := withResponseID(ctx, func(ctx context.Context) (*iaas.VolumeListResponse, error) {
+	resp, err := withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (*iaas.VolumeListResponse, error) {
 		return i.Client.ListVolumes(ctx, i.projectID, i.region).Execute()
 	})
 	if err != nil {
@@ -407,7 +537,7 @@ func (i *iaasClient) GetVolumesByName(ctx context.Context, volName string) ([]ia
 
 func (i *iaasClient) ListVolumes(ctx context.Context, _ int, _ string) ([]iaas.Volume, string, error) {
 	// TODO: Add support for pagination when IaaS adds it
-	resp, err := withResponseID(ctx, func(ctx context.Context) (*iaas.VolumeListResponse, error) {
+	resp, err := withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (*iaas.VolumeListResponse, error) {
 		return i.Client.ListVolumes(ctx, i.projectID, i.region).Execute()
 	})
 	if err != nil {
@@ -420,7 +550,7 @@ func (i *iaasClient) ListVolumes(ctx context.Context, _ int, _ string) ([]iaas.V
 func (i *iaasClient) ExpandVolume(ctx context.Context, volumeID, volumeStatus string, payload iaas.ResizeVolumePayload) error {
 	switch volumeStatus {
 	case VolumeAttachedStatus, VolumeAvailableStatus:
-		_, err := withResponseID(ctx, func(ctx context.Context) (any, error) {
+		_, err := withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (any, error) {
 			return nil, i.Client.
 				ResizeVolume(ctx, i.projectID, i.region, volumeID).
 				ResizeVolumePayload(payload).
@@ -434,13 +564,9 @@ func (i *iaasClient) ExpandVolume(ctx context.Context, volumeID, volumeStatus st
 }
 
 func (i *iaasClient) WaitVolumeTargetStatus(ctx context.Context, volumeID string, tStatus []string) error {
-	backoff := wait.Backoff{
-		Duration: operationFinishInitDelay,
-		Factor:   operationFinishFactor,
-		Steps:    operationFinishSteps,
-	}
-
-	waitErr := wait.ExponentialBackoff(backoff, func() (bool, error) {
+	waitCtx, cancel := withTimeout(ctx, i.timeouts.Wait.Duration)
+	defer cancel()
+	waitErr := wait.ExponentialBackoffWithContext(waitCtx, DefaultVolumeStatusBackoff, func(ctx context.Context) (bool, error) {
 		vol, err := i.GetVolume(ctx, volumeID)
 		if err != nil {
 			return false, err
@@ -463,38 +589,15 @@ func (i *iaasClient) WaitVolumeTargetStatus(ctx context.Context, volumeID string
 	return waitErr
 }
 
-func (i *iaasClient) WaitDiskAttached(ctx context.Context, instanceID, volumeID string) error {
-	backoff := wait.Backoff{
-		Duration: diskAttachInitDelay,
-		Factor:   diskAttachFactor,
-		Steps:    diskAttachSteps,
+// WaitDiskDetached waits until the volume is detached from the server. A nil backoff uses DefaultDiskDetachBackoff.
+func (i *iaasClient) WaitDiskDetached(ctx context.Context, instanceID, volumeID string, backoff *wait.Backoff) error {
+	if backoff == nil {
+		backoff = new(DefaultDiskDetachBackoff)
 	}
 
-	err := wait.ExponentialBackoff(backoff, func() (bool, error) {
-		attached, err := i.diskIsAttached(ctx, instanceID, volumeID)
-		if err != nil && !stackiterrors.IsNotFound(err) {
-			// if this is a race condition indicate the volume is deleted
-			// during sleep phase, ignore the error and return attach=false
-			return false, err
-		}
-		return attached, nil
-	})
-
-	if wait.Interrupted(err) {
-		err = fmt.Errorf("volume %q failed to be attached within the allowed time", volumeID)
-	}
-
-	return err
-}
-
-func (i *iaasClient) WaitDiskDetached(ctx context.Context, instanceID, volumeID string) error {
-	backoff := wait.Backoff{
-		Duration: diskDetachInitDelay,
-		Factor:   diskDetachFactor,
-		Steps:    diskDetachSteps,
-	}
-
-	err := wait.ExponentialBackoff(backoff, func() (bool, error) {
+	waitCtx, cancel := withTimeout(ctx, i.timeouts.Wait.Duration)
+	defer cancel()
+	err := wait.ExponentialBackoffWithContext(waitCtx, *backoff, func(ctx context.Context) (bool, error) {
 		attached, err := i.diskIsAttached(ctx, instanceID, volumeID)
 		if err != nil {
 			return false, err
@@ -516,7 +619,7 @@ func (i *iaasClient) DetachVolume(ctx context.Context, serverID, volumeID string
 	}
 
 	if *volume.Status == VolumeAvailableStatus {
-		klog.V(2).Infof("Volume: %s has been detached from compute: %s ", *volume.Id, serverID)
+		klog.V(2).InfoS("Volume has been detached from server", "volumeID", *volume.Id, "serverID", serverID)
 		return nil
 	}
 
@@ -525,7 +628,7 @@ func (i *iaasClient) DetachVolume(ctx context.Context, serverID, volumeID string
 	}
 
 	if volume.ServerId != nil && *volume.ServerId == serverID {
-		_, err := withResponseID(ctx, func(ctx context.Context) (any, error) {
+		_, err := withResponseID(ctx, i.timeouts.Request.Duration, func(ctx context.Context) (any, error) {
 			err := i.Client.RemoveVolumeFromServer(ctx, i.projectID, i.region, serverID, volumeID).Execute()
 			if err != nil {
 				return nil, fmt.Errorf("failed to detach volume %s from compute %s : %w", *volume.Name, serverID, err)
@@ -536,14 +639,16 @@ func (i *iaasClient) DetachVolume(ctx context.Context, serverID, volumeID string
 			return err
 		}
 
-		klog.V(2).Infof("Successfully detached volume: %s from compute: %s", *volume.Id, serverID)
+		klog.V(2).InfoS("Successfully detached volume from server", "volumeID", *volume.Id, "serverID", serverID)
 	}
 
 	return nil
 }
 
 func (i *iaasClient) WaitVolumeTargetStatusWithCustomBackoff(ctx context.Context, volumeID string, tStatus []string, backoff *wait.Backoff) error {
-	waitErr := wait.ExponentialBackoff(*backoff, func() (bool, error) {
+	waitCtx, cancel := withTimeout(ctx, i.timeouts.Wait.Duration)
+	defer cancel()
+	waitErr := wait.ExponentialBackoffWithContext(waitCtx, *backoff, func(ctx context.Context) (bool, error) {
 		vol, err := i.GetVolume(ctx, volumeID)
 		if err != nil {
 			return false, err
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -81,6 +82,20 @@ var _ = Describe("Server", func() {
 			Expect(*items[0].Id).To(Equal("id-1"))
 		})
 	})
+
+	Context("GetAffinityGroup", func() {
+		It("returns the affinity group", func() {
+			mockIaaSClient.EXPECT().
+				GetAffinityGroup(gomock.Any(), gomock.Any(), gomock.Any(), "group-id").
+				Return(iaas.ApiGetAffinityGroupRequest{ApiService: mockIaaSClient})
+			mockIaaSClient.EXPECT().GetAffinityGroupExecute(gomock.Any()).
+				Return(&iaas.AffinityGroup{Id: new("group-id"), Name: "workers", Policy: "soft-anti-affinity"}, nil)
+
+			group, err := client.GetAffinityGroup(context.Background(), "group-id")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(group.Policy).To(Equal("soft-anti-affinity"))
+		})
+	})
 })
 
 var _ = Describe("Snapshot", func() {
@@ -250,7 +265,7 @@ var _ = Describe("Snapshot", func() {
 				Return(iaas.ApiGetSnapshotRequest{ApiService: mockIaaSClient}).AnyTimes()
 			mockIaaSClient.EXPECT().GetSnapshotExecute(gomock.Any()).Return(&iaas.Snapshot{Id: new(snapshotID), Status: new("AVAILABLE")}, nil).AnyTimes()
 
-			status, err := client.WaitSnapshotReady(context.Background(), snapshotID)
+			status, err := client.WaitSnapshotReady(context.Background(), snapshotID, nil)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(*status).To(Equal("AVAILABLE"))
 		})
@@ -261,7 +276,7 @@ var _ = Describe("Snapshot", func() {
 				Return(iaas.ApiGetSnapshotRequest{ApiService: mockIaaSClient}).AnyTimes()
 			mockIaaSClient.EXPECT().GetSnapshotExecute(gomock.Any()).Return(nil, fmt.Errorf("api error")).AnyTimes()
 
-			status, err := client.WaitSnapshotReady(context.Background(), snapshotID)
+			status, err := client.WaitSnapshotReady(context.Background(), snapshotID, nil)
 			Expect(err).To(HaveOccurred())
 			Expect(status).ToNot(BeNil())
 			Expect(*status).To(Equal("Failed to get Snapshot status"))
@@ -407,6 +422,25 @@ var _ = Describe("Backup", func() {
 			Expect(*backups[0].Id).To(Equal("id-1"))
 		})
 
+		It("falls back to filtering locally if the API rejects the label selector", func() {
+			mockIaaSClient.EXPECT().
+				ListBackups(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(iaas.ApiListBackupsRequest{ApiService: mockIaaSClient}).Times(2)
+			gomock.InOrder(
+				mockIaaSClient.EXPECT().ListBackupsExecute(gomock.Any()).Return(nil, &oapiError.GenericOpenAPIError{StatusCode: http.StatusBadRequest}),
+				mockIaaSClient.EXPECT().ListBackupsExecute(gomock.Any()).Return(&iaas.BackupListResponse{
+					Items: []iaas.Backup{
+						{Id: new("id-1"), Labels: map[string]any{"scheduled": "true"}},
+						{Id: new("id-2")},
+					},
+				}, nil),
+			)
+
+			backups, err := client.ListBackups(context.Background(), map[string]string{LabelFilter("scheduled"): "true"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(backups).To(ConsistOf(HaveField("Id", HaveValue(Equal("id-1")))))
+		})
+
 		It("returns error when list API fails", func() {
 			mockIaaSClient.EXPECT().
 				ListBackups(gomock.Any(), gomock.Any(), gomock.Any()).
@@ -526,6 +560,17 @@ var _ = Describe("Volume", func() {
 			Expect(*vol.Name).To(Equal("test-vol"))
 		})
 
+		It("GetVolumePerformanceClass returns the performance class", func() {
+			mockIaaSClient.EXPECT().GetVolumePerformanceClass(gomock.Any(), gomock.Any(), gomock.Any(), "storage_premium_perf4").
+				Return(iaas.ApiGetVolumePerformanceClassRequest{ApiService: mockIaaSClient})
+			mockIaaSClient.EXPECT().GetVolumePerformanceClassExecute(gomock.Any()).
+				Return(&iaas.VolumePerformanceClass{Name: "storage_premium_perf4", Iops: new(int64(4000))}, nil)
+
+			class, err := client.GetVolumePerformanceClass(context.Background(), "storage_premium_perf4")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*class.Iops).To(Equal(int64(4000)))
+		})
+
 		It("DeleteVolume fails if volume is still attached (diskIsUsed logic)", func() {
 			mockIaaSClient.EXPECT().GetVolume(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
 				Return(iaas.ApiGetVolumeRequest{ApiService: mockIaaSClient})
@@ -537,6 +582,18 @@ var _ = Describe("Volume", func() {
 		})
 	})
 
+	Context("UpdateVolume", func() {
+		It("should update the volume", func() {
+			mockIaaSClient.EXPECT().UpdateVolume(gomock.Any(), gomock.Any(), gomock.Any(), volumeID).
+				Return(iaas.ApiUpdateVolumeRequest{ApiService: mockIaaSClient})
+			mockIaaSClient.EXPECT().UpdateVolumeExecute(gomock.Any()).Return(&iaas.Volume{Id: new(volumeID), Name: new("new-name")}, nil)
+
+			volume, err := client.UpdateVolume(context.Background(), volumeID, iaas.UpdateVolumePayload{Name: new("new-name")})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*volume.Name).To(Equal("new-name"))
+		})
+	})
+
 	Context("Attach/Detach Volume", func() {
 		It("AttachVolume calls API when not already attached", func() {
 			mockIaaSClient.EXPECT().GetVolume(gomock.Any(), gomock.Any(), gomock.Any(), volumeID).
@@ -593,7 +650,7 @@ var _ = Describe("Volume", func() {
 			Expect(err).ToNot(HaveOccurred())
 		})
 
-		It("WaitDiskAttached returns error on timeout", func() {
+		It("WaitDiskDetached returns error on timeout", func() {
 			mockIaaSClient.EXPECT().
 				GetVolume(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
 				Return(iaas.ApiGetVolumeRequest{
@@ -601,8 +658,164 @@ var _ = Describe("Volume", func() {
 				})
 			mockIaaSClient.EXPECT().GetVolumeExecute(gomock.Any()).Return(nil, fmt.Errorf("timeout"))
 
-			err := client.WaitDiskAttached(context.Background(), serverID, volumeID)
+			err := client.WaitDiskDetached(context.Background(), serverID, volumeID, nil)
 			Expect(err).To(HaveOccurred())
 		})
+
+		It("WaitDiskDetached returns error when the wait timeout expires", func() {
+			client.timeouts.Wait.Duration = 100 * time.Millisecond
+			mockIaaSClient.EXPECT().
+				GetVolume(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(iaas.ApiGetVolumeRequest{ApiService: mockIaaSClient}).AnyTimes()
+			mockIaaSClient.EXPECT().GetVolumeExecute(gomock.Any()).Return(&iaas.Volume{Id: new(volumeID), ServerId: new(serverID)}, nil).AnyTimes()
+
+			start := time.Now()
+			err := client.WaitDiskDetached(context.Background(), serverID, volumeID, nil)
+			Expect(err).To(MatchError(ContainSubstring("failed to detach within the allowed time")))
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		})
+	})
+
+	Context("Timeouts", func() {
+		It("passes the request timeout as deadline to the API call", func() {
+			client.timeouts.Request.Duration = time.Minute
+			mockIaaSClient.EXPECT().
+				GetVolume(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, _, _, _ string) iaas.ApiGetVolumeRequest {
+					deadline, ok := ctx.Deadline()
+					Expect(ok).To(BeTrue())
+					Expect(time.Until(deadline)).To(BeNumerically("~", time.Minute, 5*time.Second))
+					return iaas.ApiGetVolumeRequest{ApiService: mockIaaSClient}
+				})
+			mockIaaSClient.EXPECT().GetVolumeExecute(gomock.Any()).Return(&iaas.Volume{Id: new(volumeID)}, nil)
+
+			_, err := client.GetVolume(context.Background(), volumeID)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("does not set a deadline without request timeout", func() {
+			mockIaaSClient.EXPECT().
+				GetVolume(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, _, _, _ string) iaas.ApiGetVolumeRequest {
+					_, ok := ctx.Deadline()
+					Expect(ok).To(BeFalse())
+					return iaas.ApiGetVolumeRequest{ApiService: mockIaaSClient}
+				})
+			mockIaaSClient.EXPECT().GetVolumeExecute(gomock.Any()).Return(&iaas.Volume{Id: new(volumeID)}, nil)
+
+			_, err := client.GetVolume(context.Background(), volumeID)
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("SecurityGroupRule", func() {
+	var (
+		mockCtrl       *gomock.Controller
+		mockIaaSClient *mock.MockDefaultAPI
+		client         *iaasClient
+	)
+
+	const (
+		securityGroupID = "sg-uuid-123"
+		ruleID          = "rule-uuid-123"
+	)
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockIaaSClient = mock.NewMockDefaultAPI(mockCtrl)
+
+		client = &iaasClient{
+			Client: mockIaaSClient,
+		}
+	})
+
+	AfterEach(func() {
+		mockCtrl.Finish()
+	})
+
+	It("lists the rules of a security group", func() {
+		mockIaaSClient.EXPECT().ListSecurityGroupRules(gomock.Any(), gomock.Any(), gomock.Any(), securityGroupID).
+			Return(iaas.ApiListSecurityGroupRulesRequest{ApiService: mockIaaSClient})
+		mockIaaSClient.EXPECT().ListSecurityGroupRulesExecute(gomock.Any()).
+			Return(&iaas.SecurityGroupRuleListResponse{Items: []iaas.SecurityGroupRule{{Id: new(ruleID)}}}, nil)
+
+		rules, err := client.ListSecurityGroupRules(context.Background(), securityGroupID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rules).To(HaveLen(1))
+	})
+
+	It("creates a security group rule", func() {
+		mockIaaSClient.EXPECT().CreateSecurityGroupRule(gomock.Any(), gomock.Any(), gomock.Any(), securityGroupID).
+			Return(iaas.ApiCreateSecurityGroupRuleRequest{ApiService: mockIaaSClient})
+		mockIaaSClient.EXPECT().CreateSecurityGroupRuleExecute(gomock.Any()).Return(&iaas.SecurityGroupRule{Id: new(ruleID)}, nil)
+
+		rule, err := client.CreateSecurityGroupRule(context.Background(), securityGroupID, iaas.CreateSecurityGroupRulePayload{Direction: "ingress"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*rule.Id).To(Equal(ruleID))
+	})
+
+	It("returns the error if deleting a security group rule fails", func() {
+		mockIaaSClient.EXPECT().DeleteSecurityGroupRule(gomock.Any(), gomock.Any(), gomock.Any(), securityGroupID, ruleID).
+			Return(iaas.ApiDeleteSecurityGroupRuleRequest{ApiService: mockIaaSClient})
+		mockIaaSClient.EXPECT().DeleteSecurityGroupRuleExecute(gomock.Any()).Return(&oapiError.GenericOpenAPIError{StatusCode: http.StatusNotFound})
+
+		err := client.DeleteSecurityGroupRule(context.Background(), securityGroupID, ruleID)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("PublicIP", func() {
+	var (
+		mockCtrl       *gomock.Controller
+		mockIaaSClient *mock.MockDefaultAPI
+		client         *iaasClient
+	)
+
+	const publicIPID = "ip-uuid-123"
+
+	BeforeEach(func() {
+		mockCtrl = gomock.NewController(GinkgoT())
+		mockIaaSClient = mock.NewMockDefaultAPI(mockCtrl)
+		client = &iaasClient{Client: mockIaaSClient}
+	})
+
+	It("lists the public IPs matching the label selector", func() {
+		mockIaaSClient.EXPECT().ListPublicIPs(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(iaas.ApiListPublicIPsRequest{ApiService: mockIaaSClient})
+		mockIaaSClient.EXPECT().ListPublicIPsExecute(gomock.Any()).
+			Return(&iaas.PublicIpListResponse{Items: []iaas.PublicIp{{Id: new(publicIPID)}}}, nil)
+
+		ips, err := client.ListPublicIPs(context.Background(), "k8s-service-name=my-service")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ips).To(ConsistOf(HaveField("Id", HaveValue(Equal(publicIPID)))))
+	})
+
+	It("creates a public IP", func() {
+		mockIaaSClient.EXPECT().CreatePublicIP(gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(iaas.ApiCreatePublicIPRequest{ApiService: mockIaaSClient})
+		mockIaaSClient.EXPECT().CreatePublicIPExecute(gomock.Any()).Return(&iaas.PublicIp{Id: new(publicIPID)}, nil)
+
+		publicIP, err := client.CreatePublicIP(context.Background(), iaas.CreatePublicIPPayload{Labels: map[string]any{"key": "value"}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(publicIP.Id).To(HaveValue(Equal(publicIPID)))
+	})
+
+	It("updates the labels of a public IP", func() {
+		mockIaaSClient.EXPECT().UpdatePublicIP(gomock.Any(), gomock.Any(), gomock.Any(), publicIPID).
+			Return(iaas.ApiUpdatePublicIPRequest{ApiService: mockIaaSClient})
+		mockIaaSClient.EXPECT().UpdatePublicIPExecute(gomock.Any()).Return(&iaas.PublicIp{Id: new(publicIPID)}, nil)
+
+		_, err := client.UpdatePublicIP(context.Background(), publicIPID, iaas.UpdatePublicIPPayload{Labels: map[string]any{"key": "value"}})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("returns the error if deleting a public IP fails", func() {
+		mockIaaSClient.EXPECT().DeletePublicIP(gomock.Any(), gomock.Any(), gomock.Any(), publicIPID).
+			Return(iaas.ApiDeletePublicIPRequest{ApiService: mockIaaSClient})
+		mockIaaSClient.EXPECT().DeletePublicIPExecute(gomock.Any()).Return(&oapiError.GenericOpenAPIError{StatusCode: http.StatusConflict})
+
+		err := client.DeletePublicIP(context.Background(), publicIPID)
+		Expect(err).To(HaveOccurred())
 	})
 })
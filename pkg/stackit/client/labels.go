@@ -0,0 +1,62 @@
+package client
+
+import (
+	"maps"
+
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+)
+
+// Resource labels identify the cluster and component that created a resource, e.g. for cost attribution or to find
+// resources left behind by deleted clusters. Observability credentials of load balancers can't be labeled, because the
+// load balancer API has no labels for them. They are identified by the cluster ID prefix of their display name instead.
+const (
+	// ClusterIDLabel holds the ID of the cluster, see stackitconfig.GlobalOpts.ClusterID.
+	ClusterIDLabel = "cluster-id"
+	// ManagedByLabel holds the name of the component that created the resource.
+	ManagedByLabel = "managed-by"
+	// ServiceUIDLabel holds the UID of the service of a load balancer.
+	ServiceUIDLabel = "service-uid"
+	// PVCUIDLabel holds the UID of the PVC of a volume or backup.
+	PVCUIDLabel = "pvc-uid"
+)
+
+// PVCNamespaceLabel and PVCNameLabel hold the namespace and name of the PVC a volume was provisioned for. The CSI
+// driver adopts volumes with these labels when their PVC is recreated, so other cleanups must leave them alone.
+const (
+	PVCNamespaceLabel = "pvc-namespace"
+	PVCNameLabel      = "pvc-name"
+)
+
+// ReclaimPolicyLabel records the reclaim policy of the PersistentVolume of a volume once its policy is Retain, because
+// it isn't known anymore after the PersistentVolume was deleted. Retained volumes must never be deleted automatically.
+const ReclaimPolicyLabel = "reclaim-policy"
+
+// PendingDeleteLabel marks volumes whose deletion the CSI driver deferred by the reclaim grace period. It holds the
+// Unix time after which the volume is deleted. Other cleanups must leave these volumes alone.
+const PendingDeleteLabel = "pending-delete-after"
+
+// ResourceLabels returns the labels that component adds to all resources it creates,
+// or nil if resource labels are disabled.
+func ResourceLabels(opts stackitconfig.GlobalOpts, component string) map[string]string {
+	if !opts.ResourceLabels {
+		return nil
+	}
+	labels := map[string]string{ManagedByLabel: component}
+	if opts.ClusterID != "" {
+		labels[ClusterIDLabel] = opts.ClusterID
+	}
+	return labels
+}
+
+// WithResourceLabels returns a copy of labels with the resource labels added. The resource labels take precedence.
+func WithResourceLabels(labels, resourceLabels map[string]string) map[string]string {
+	if len(resourceLabels) == 0 {
+		return labels
+	}
+	result := maps.Clone(labels)
+	if result == nil {
+		result = make(map[string]string, len(resourceLabels))
+	}
+	maps.Copy(result, resourceLabels)
+	return result
+}
@@ -0,0 +1,35 @@
+package client
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+)
+
+var _ = Describe("Resource labels", func() {
+	It("should be disabled by default", func() {
+		Expect(ResourceLabels(stackitconfig.GlobalOpts{ClusterID: "my-cluster"}, "component")).To(BeNil())
+	})
+
+	It("should contain the cluster ID and component", func() {
+		Expect(ResourceLabels(stackitconfig.GlobalOpts{ClusterID: "my-cluster", ResourceLabels: true}, "component")).To(Equal(map[string]string{
+			ClusterIDLabel: "my-cluster",
+			ManagedByLabel: "component",
+		}))
+		Expect(ResourceLabels(stackitconfig.GlobalOpts{ResourceLabels: true}, "component")).To(Equal(map[string]string{
+			ManagedByLabel: "component",
+		}))
+	})
+
+	It("should add the resource labels without modifying the labels", func() {
+		labels := map[string]string{"team": "a", ManagedByLabel: "other"}
+		Expect(WithResourceLabels(labels, map[string]string{ManagedByLabel: "component"})).To(Equal(map[string]string{
+			"team":         "a",
+			ManagedByLabel: "component",
+		}))
+		Expect(labels).To(HaveKeyWithValue(ManagedByLabel, "other"))
+		Expect(WithResourceLabels(nil, map[string]string{ManagedByLabel: "component"})).To(HaveLen(1))
+		Expect(WithResourceLabels(labels, nil)).To(Equal(labels))
+	})
+})
@@ -2,8 +2,10 @@ package client
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/google/uuid"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
 	sdkconfig "github.com/stackitcloud/stackit-sdk-go/core/config"
 	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
 )
@@ -11,11 +13,19 @@ import (
 type LoadBalancingClient interface {
 	CreateLoadBalancer(ctx context.Context, payload *loadbalancer.CreateLoadBalancerPayload) (*loadbalancer.LoadBalancer, error)
 	GetLoadBalancer(ctx context.Context, id string) (*loadbalancer.LoadBalancer, error)
+	// ListLoadBalancers returns all load balancers of the project, following all pages.
+	ListLoadBalancers(ctx context.Context) ([]loadbalancer.LoadBalancer, error)
 	UpdateLoadBalancer(ctx context.Context, lbName string, updates *loadbalancer.UpdateLoadBalancerPayload) (*loadbalancer.LoadBalancer, error)
 	DeleteLoadBalancer(ctx context.Context, lbName string) error
 	UpdateTargetPool(ctx context.Context, name, targetPoolName string, payload loadbalancer.UpdateTargetPoolPayload) error
+	// GetQuota returns the maximum and used number of load balancers and credentials of the project.
+	GetQuota(ctx context.Context) (*loadbalancer.GetQuotaResponse, error)
+	// ListPlans returns the plans of the region, including the maximum number of concurrent connections of each plan.
+	ListPlans(ctx context.Context) ([]loadbalancer.PlanDetails, error)
 
 	CreateCredentials(ctx context.Context, payload loadbalancer.CreateCredentialsPayload) (*loadbalancer.CreateCredentialsResponse, error)
+	// ListCredentials returns all credentials of the project in a single response, because the API doesn't support
+	// pages for credentials. Prefer ForEachCredentials to iterate over them.
 	ListCredentials(ctx context.Context) (*loadbalancer.ListCredentialsResponse, error)
 	UpdateCredentials(ctx context.Context, credentialsRef string, payload loadbalancer.UpdateCredentialsPayload) error
 	DeleteCredentials(ctx context.Context, credentialsRef string) error
@@ -25,9 +35,10 @@ type loadBalancingClient struct {
 	Client    loadbalancer.DefaultAPI
 	projectID string
 	region    string
+	timeouts  stackitconfig.APITimeouts
 }
 
-func NewLoadBalancingClient(region, projectID string, options []sdkconfig.ConfigurationOption) (LoadBalancingClient, error) {
+func NewLoadBalancingClient(region, projectID string, timeouts stackitconfig.APITimeouts, options []sdkconfig.ConfigurationOption) (LoadBalancingClient, error) {
 	apiClient, err := loadbalancer.NewAPIClient(options...)
 	if err != nil {
 		return nil, err
@@ -36,11 +47,12 @@ func NewLoadBalancingClient(region, projectID string, options []sdkconfig.Config
 		Client:    apiClient.DefaultAPI,
 		projectID: projectID,
 		region:    region,
+		timeouts:  timeouts,
 	}, nil
 }
 
 func (l *loadBalancingClient) CreateLoadBalancer(ctx context.Context, payload *loadbalancer.CreateLoadBalancerPayload) (*loadbalancer.LoadBalancer, error) {
-	return withResponseID(ctx, func(ctx context.Context) (*loadbalancer.LoadBalancer, error) {
+	return withResponseID(ctx, l.timeouts.Request.Duration, func(ctx context.Context) (*loadbalancer.LoadBalancer, error) {
 		return l.Client.
 			CreateLoadBalancer(ctx, l.projectID, l.region).
 			CreateLoadBalancerPayload(*payload).
@@ -50,7 +62,7 @@ func (l *loadBalancingClient) CreateLoadBalancer(ctx context.Context, payload *l
 }
 
 func (l *loadBalancingClient) DeleteLoadBalancer(ctx context.Context, lbName string) error {
-	_, err := withResponseID(ctx, func(ctx context.Context) (map[string]any, error) {
+	_, err := withResponseID(ctx, l.timeouts.Request.Duration, func(ctx context.Context) (map[string]any, error) {
 		return l.Client.
 			DeleteLoadBalancer(ctx, l.projectID, l.region, lbName).
 			Execute()
@@ -59,15 +71,37 @@ func (l *loadBalancingClient) DeleteLoadBalancer(ctx context.Context, lbName str
 }
 
 func (l *loadBalancingClient) GetLoadBalancer(ctx context.Context, lbName string) (*loadbalancer.LoadBalancer, error) {
-	return withResponseID(ctx, func(ctx context.Context) (*loadbalancer.LoadBalancer, error) {
+	return withResponseID(ctx, l.timeouts.Request.Duration, func(ctx context.Context) (*loadbalancer.LoadBalancer, error) {
 		return l.Client.
 			GetLoadBalancer(ctx, l.projectID, l.region, lbName).
 			Execute()
 	})
 }
 
+func (l *loadBalancingClient) ListLoadBalancers(ctx context.Context) ([]loadbalancer.LoadBalancer, error) {
+	var lbs []loadbalancer.LoadBalancer
+	var pageID *string
+	for {
+		res, err := withResponseID(ctx, l.timeouts.Request.Duration, func(ctx context.Context) (*loadbalancer.ListLoadBalancersResponse, error) {
+			req := l.Client.ListLoadBalancers(ctx, l.projectID, l.region)
+			if pageID != nil {
+				req = req.PageId(*pageID)
+			}
+			return req.Execute()
+		})
+		if err != nil {
+			return nil, err
+		}
+		lbs = append(lbs, res.LoadBalancers...)
+		if res.NextPageId == nil || *res.NextPageId == "" {
+			return lbs, nil
+		}
+		pageID = res.NextPageId
+	}
+}
+
 func (l *loadBalancingClient) UpdateLoadBalancer(ctx context.Context, lbName string, updates *loadbalancer.UpdateLoadBalancerPayload) (*loadbalancer.LoadBalancer, error) {
-	return withResponseID(ctx, func(ctx context.Context) (*loadbalancer.LoadBalancer, error) {
+	return withResponseID(ctx, l.timeouts.Request.Duration, func(ctx context.Context) (*loadbalancer.LoadBalancer, error) {
 		return l.Client.
 			UpdateLoadBalancer(ctx, l.projectID, l.region, lbName).
 			UpdateLoadBalancerPayload(*updates).
@@ -76,7 +110,7 @@ func (l *loadBalancingClient) UpdateLoadBalancer(ctx context.Context, lbName str
 }
 
 func (l *loadBalancingClient) UpdateTargetPool(ctx context.Context, name, targetPoolName string, payload loadbalancer.UpdateTargetPoolPayload) error {
-	_, err := withResponseID(ctx, func(ctx context.Context) (*loadbalancer.TargetPool, error) {
+	_, err := withResponseID(ctx, l.timeouts.Request.Duration, func(ctx context.Context) (*loadbalancer.TargetPool, error) {
 		return l.Client.
 			UpdateTargetPool(ctx, l.projectID, l.region, name, targetPoolName).
 			UpdateTargetPoolPayload(payload).
@@ -86,7 +120,7 @@ func (l *loadBalancingClient) UpdateTargetPool(ctx context.Context, name, target
 }
 
 func (l *loadBalancingClient) CreateCredentials(ctx context.Context, payload loadbalancer.CreateCredentialsPayload) (*loadbalancer.CreateCredentialsResponse, error) {
-	return withResponseID(ctx, func(ctx context.Context) (*loadbalancer.CreateCredentialsResponse, error) {
+	return withResponseID(ctx, l.timeouts.Request.Duration, func(ctx context.Context) (*loadbalancer.CreateCredentialsResponse, error) {
 		return l.Client.
 			CreateCredentials(ctx, l.projectID, l.region).
 			CreateCredentialsPayload(payload).
@@ -95,16 +129,55 @@ func (l *loadBalancingClient) CreateCredentials(ctx context.Context, payload loa
 	})
 }
 
+func (l *loadBalancingClient) GetQuota(ctx context.Context) (*loadbalancer.GetQuotaResponse, error) {
+	return withResponseID(ctx, l.timeouts.Request.Duration, func(ctx context.Context) (*loadbalancer.GetQuotaResponse, error) {
+		return l.Client.
+			GetQuota(ctx, l.projectID, l.region).
+			Execute()
+	})
+}
+
+func (l *loadBalancingClient) ListPlans(ctx context.Context) ([]loadbalancer.PlanDetails, error) {
+	resp, err := withResponseID(ctx, l.timeouts.Request.Duration, func(ctx context.Context) (*loadbalancer.ListPlansResponse, error) {
+		return l.Client.
+			ListPlans(ctx, l.region).
+			Execute()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.ValidPlans, nil
+}
+
 func (l *loadBalancingClient) ListCredentials(ctx context.Context) (*loadbalancer.ListCredentialsResponse, error) {
-	return withResponseID(ctx, func(ctx context.Context) (*loadbalancer.ListCredentialsResponse, error) {
+	return withResponseID(ctx, l.timeouts.Request.Duration, func(ctx context.Context) (*loadbalancer.ListCredentialsResponse, error) {
 		return l.Client.
 			ListCredentials(ctx, l.projectID, l.region).
 			Execute()
 	})
 }
 
+// ForEachCredentials calls fn for each credentials of the project until fn returns an error or ctx is done.
+// Callers don't depend on how the credentials are fetched, so that they page through them without changes once the
+// API supports pages for credentials like for load balancers. Until then, all credentials are fetched at once.
+func ForEachCredentials(ctx context.Context, client LoadBalancingClient, fn func(credentials *loadbalancer.CredentialsResponse) error) error {
+	res, err := client.ListCredentials(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list credentials: %w", err)
+	}
+	for i := range res.Credentials {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(&res.Credentials[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (l *loadBalancingClient) UpdateCredentials(ctx context.Context, credentialsRef string, payload loadbalancer.UpdateCredentialsPayload) error {
-	_, err := withResponseID(ctx, func(ctx context.Context) (*loadbalancer.UpdateCredentialsResponse, error) {
+	_, err := withResponseID(ctx, l.timeouts.Request.Duration, func(ctx context.Context) (*loadbalancer.UpdateCredentialsResponse, error) {
 		return l.Client.
 			UpdateCredentials(ctx, l.projectID, l.region, credentialsRef).
 			UpdateCredentialsPayload(payload).
@@ -114,7 +187,7 @@ func (l *loadBalancingClient) UpdateCredentials(ctx context.Context, credentials
 }
 
 func (l *loadBalancingClient) DeleteCredentials(ctx context.Context, credentialsRef string) error {
-	_, err := withResponseID(ctx, func(ctx context.Context) (map[string]any, error) {
+	_, err := withResponseID(ctx, l.timeouts.Request.Duration, func(ctx context.Context) (map[string]any, error) {
 		return l.Client.
 			DeleteCredentials(ctx, l.projectID, l.region, credentialsRef).
 			Execute()
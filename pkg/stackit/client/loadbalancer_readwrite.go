@@ -0,0 +1,39 @@
+package client
+
+import (
+	"context"
+
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+)
+
+// NewReadWriteLoadBalancingClient returns a client that sends reads to read and all other calls to write, e.g. to use
+// separate credentials and rate limits for both.
+func NewReadWriteLoadBalancingClient(read, write LoadBalancingClient) LoadBalancingClient {
+	return &readWriteLoadBalancingClient{LoadBalancingClient: write, read: read}
+}
+
+type readWriteLoadBalancingClient struct {
+	// LoadBalancingClient is used for writes.
+	LoadBalancingClient
+	read LoadBalancingClient
+}
+
+func (c *readWriteLoadBalancingClient) GetLoadBalancer(ctx context.Context, id string) (*loadbalancer.LoadBalancer, error) {
+	return c.read.GetLoadBalancer(ctx, id)
+}
+
+func (c *readWriteLoadBalancingClient) ListLoadBalancers(ctx context.Context) ([]loadbalancer.LoadBalancer, error) {
+	return c.read.ListLoadBalancers(ctx)
+}
+
+func (c *readWriteLoadBalancingClient) GetQuota(ctx context.Context) (*loadbalancer.GetQuotaResponse, error) {
+	return c.read.GetQuota(ctx)
+}
+
+func (c *readWriteLoadBalancingClient) ListPlans(ctx context.Context) ([]loadbalancer.PlanDetails, error) {
+	return c.read.ListPlans(ctx)
+}
+
+func (c *readWriteLoadBalancingClient) ListCredentials(ctx context.Context) (*loadbalancer.ListCredentialsResponse, error) {
+	return c.read.ListCredentials(ctx)
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+)
+
+// recordingLoadBalancingClient records the calls of the tests, all other methods panic.
+type recordingLoadBalancingClient struct {
+	LoadBalancingClient
+	calls []string
+}
+
+func (r *recordingLoadBalancingClient) GetLoadBalancer(_ context.Context, _ string) (*loadbalancer.LoadBalancer, error) {
+	r.calls = append(r.calls, "GetLoadBalancer")
+	return &loadbalancer.LoadBalancer{}, nil
+}
+
+func (r *recordingLoadBalancingClient) ListCredentials(_ context.Context) (*loadbalancer.ListCredentialsResponse, error) {
+	r.calls = append(r.calls, "ListCredentials")
+	return &loadbalancer.ListCredentialsResponse{}, nil
+}
+
+func (r *recordingLoadBalancingClient) DeleteLoadBalancer(_ context.Context, _ string) error {
+	r.calls = append(r.calls, "DeleteLoadBalancer")
+	return nil
+}
+
+var _ = Describe("NewReadWriteLoadBalancingClient", func() {
+	It("should send reads and writes to their clients", func() {
+		read, write := &recordingLoadBalancingClient{}, &recordingLoadBalancingClient{}
+		client := NewReadWriteLoadBalancingClient(read, write)
+
+		_, err := client.GetLoadBalancer(context.Background(), "my-lb")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = client.ListCredentials(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.DeleteLoadBalancer(context.Background(), "my-lb")).To(Succeed())
+
+		Expect(read.calls).To(Equal([]string{"GetLoadBalancer", "ListCredentials"}))
+		Expect(write.calls).To(Equal([]string{"DeleteLoadBalancer"}))
+	})
+})
@@ -2,6 +2,7 @@ package client
 
 import (
 	"context"
+	"errors"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -59,6 +60,38 @@ var _ = Describe("LoadBalancer", func() {
 			Expect(*lb.Name).To(Equal(lbName))
 		})
 
+		It("ListLoadBalancers follows all pages", func() {
+			mockLBClient.EXPECT().
+				ListLoadBalancers(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(loadbalancer.ApiListLoadBalancersRequest{ApiService: mockLBClient}).Times(2)
+			gomock.InOrder(
+				mockLBClient.EXPECT().ListLoadBalancersExecute(gomock.Any()).Return(&loadbalancer.ListLoadBalancersResponse{
+					LoadBalancers: []loadbalancer.LoadBalancer{{Name: new(lbName)}},
+					NextPageId:    new("page-2"),
+				}, nil),
+				mockLBClient.EXPECT().ListLoadBalancersExecute(gomock.Any()).Return(&loadbalancer.ListLoadBalancersResponse{
+					LoadBalancers: []loadbalancer.LoadBalancer{{Name: new("other-lb")}},
+				}, nil),
+			)
+
+			lbs, err := client.ListLoadBalancers(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lbs).To(HaveLen(2))
+		})
+
+		It("ListPlans returns the valid plans", func() {
+			mockLBClient.EXPECT().
+				ListPlans(gomock.Any(), gomock.Any()).
+				Return(loadbalancer.ApiListPlansRequest{ApiService: mockLBClient})
+			mockLBClient.EXPECT().ListPlansExecute(gomock.Any()).Return(&loadbalancer.ListPlansResponse{
+				ValidPlans: []loadbalancer.PlanDetails{{PlanId: new("p10"), MaxConnections: new(int32(10000))}},
+			}, nil)
+
+			plans, err := client.ListPlans(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(plans).To(ConsistOf(HaveField("PlanId", HaveValue(Equal("p10")))))
+		})
+
 		It("UpdateLoadBalancer calls API successfully", func() {
 			mockLBClient.EXPECT().
 				UpdateLoadBalancer(gomock.Any(), gomock.Any(), gomock.Any(), lbName).
@@ -118,6 +151,48 @@ var _ = Describe("LoadBalancer", func() {
 			Expect(resp.Credentials).To(HaveLen(1))
 		})
 
+		It("ForEachCredentials calls the callback for all credentials until it fails", func() {
+			mockLBClient.EXPECT().
+				ListCredentials(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(loadbalancer.ApiListCredentialsRequest{ApiService: mockLBClient}).Times(2)
+			mockLBClient.EXPECT().ListCredentialsExecute(gomock.Any()).Return(&loadbalancer.ListCredentialsResponse{
+				Credentials: []loadbalancer.CredentialsResponse{{DisplayName: new("cred-1")}, {DisplayName: new("cred-2")}},
+			}, nil).Times(2)
+
+			var names []string
+			Expect(ForEachCredentials(context.Background(), client, func(credentials *loadbalancer.CredentialsResponse) error {
+				names = append(names, *credentials.DisplayName)
+				return nil
+			})).To(Succeed())
+			Expect(names).To(Equal([]string{"cred-1", "cred-2"}))
+
+			errTest := errors.New("test error")
+			names = nil
+			Expect(ForEachCredentials(context.Background(), client, func(credentials *loadbalancer.CredentialsResponse) error {
+				names = append(names, *credentials.DisplayName)
+				return errTest
+			})).To(MatchError(errTest))
+			Expect(names).To(Equal([]string{"cred-1"}))
+		})
+
+		It("ForEachCredentials stops once the context is done", func() {
+			mockLBClient.EXPECT().
+				ListCredentials(gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(loadbalancer.ApiListCredentialsRequest{ApiService: mockLBClient})
+			mockLBClient.EXPECT().ListCredentialsExecute(gomock.Any()).Return(&loadbalancer.ListCredentialsResponse{
+				Credentials: []loadbalancer.CredentialsResponse{{DisplayName: new("cred-1")}, {DisplayName: new("cred-2")}},
+			}, nil)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			calls := 0
+			Expect(ForEachCredentials(ctx, client, func(*loadbalancer.CredentialsResponse) error {
+				calls++
+				cancel()
+				return nil
+			})).To(MatchError(context.Canceled))
+			Expect(calls).To(Equal(1))
+		})
+
 		It("DeleteCredentials calls API successfully", func() {
 			mockLBClient.EXPECT().
 				DeleteCredentials(gomock.Any(), gomock.Any(), gomock.Any(), "cred-ref").
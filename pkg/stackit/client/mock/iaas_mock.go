@@ -120,6 +120,84 @@ func (c *MockIaaSClientCreateBackupCall) DoAndReturn(f func(context.Context, str
 	return c
 }
 
+// CreatePublicIP mocks base method.
+func (m *MockIaaSClient) CreatePublicIP(ctx context.Context, payload v2api.CreatePublicIPPayload) (*v2api.PublicIp, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePublicIP", ctx, payload)
+	ret0, _ := ret[0].(*v2api.PublicIp)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePublicIP indicates an expected call of CreatePublicIP.
+func (mr *MockIaaSClientMockRecorder) CreatePublicIP(ctx, payload any) *MockIaaSClientCreatePublicIPCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePublicIP", reflect.TypeOf((*MockIaaSClient)(nil).CreatePublicIP), ctx, payload)
+	return &MockIaaSClientCreatePublicIPCall{Call: call}
+}
+
+// MockIaaSClientCreatePublicIPCall wrap *gomock.Call
+type MockIaaSClientCreatePublicIPCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockIaaSClientCreatePublicIPCall) Return(arg0 *v2api.PublicIp, arg1 error) *MockIaaSClientCreatePublicIPCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockIaaSClientCreatePublicIPCall) Do(f func(context.Context, v2api.CreatePublicIPPayload) (*v2api.PublicIp, error)) *MockIaaSClientCreatePublicIPCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockIaaSClientCreatePublicIPCall) DoAndReturn(f func(context.Context, v2api.CreatePublicIPPayload) (*v2api.PublicIp, error)) *MockIaaSClientCreatePublicIPCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// CreateSecurityGroupRule mocks base method.
+func (m *MockIaaSClient) CreateSecurityGroupRule(ctx context.Context, securityGroupID string, payload v2api.CreateSecurityGroupRulePayload) (*v2api.SecurityGroupRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSecurityGroupRule", ctx, securityGroupID, payload)
+	ret0, _ := ret[0].(*v2api.SecurityGroupRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSecurityGroupRule indicates an expected call of CreateSecurityGroupRule.
+func (mr *MockIaaSClientMockRecorder) CreateSecurityGroupRule(ctx, securityGroupID, payload any) *MockIaaSClientCreateSecurityGroupRuleCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSecurityGroupRule", reflect.TypeOf((*MockIaaSClient)(nil).CreateSecurityGroupRule), ctx, securityGroupID, payload)
+	return &MockIaaSClientCreateSecurityGroupRuleCall{Call: call}
+}
+
+// MockIaaSClientCreateSecurityGroupRuleCall wrap *gomock.Call
+type MockIaaSClientCreateSecurityGroupRuleCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockIaaSClientCreateSecurityGroupRuleCall) Return(arg0 *v2api.SecurityGroupRule, arg1 error) *MockIaaSClientCreateSecurityGroupRuleCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockIaaSClientCreateSecurityGroupRuleCall) Do(f func(context.Context, string, v2api.CreateSecurityGroupRulePayload) (*v2api.SecurityGroupRule, error)) *MockIaaSClientCreateSecurityGroupRuleCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockIaaSClientCreateSecurityGroupRuleCall) DoAndReturn(f func(context.Context, string, v2api.CreateSecurityGroupRulePayload) (*v2api.SecurityGroupRule, error)) *MockIaaSClientCreateSecurityGroupRuleCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // CreateSnapshot mocks base method.
 func (m *MockIaaSClient) CreateSnapshot(ctx context.Context, payload v2api.CreateSnapshotPayload) (*v2api.Snapshot, error) {
 	m.ctrl.T.Helper()
@@ -236,6 +314,82 @@ func (c *MockIaaSClientDeleteBackupCall) DoAndReturn(f func(context.Context, str
 	return c
 }
 
+// DeletePublicIP mocks base method.
+func (m *MockIaaSClient) DeletePublicIP(ctx context.Context, publicIPID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePublicIP", ctx, publicIPID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePublicIP indicates an expected call of DeletePublicIP.
+func (mr *MockIaaSClientMockRecorder) DeletePublicIP(ctx, publicIPID any) *MockIaaSClientDeletePublicIPCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePublicIP", reflect.TypeOf((*MockIaaSClient)(nil).DeletePublicIP), ctx, publicIPID)
+	return &MockIaaSClientDeletePublicIPCall{Call: call}
+}
+
+// MockIaaSClientDeletePublicIPCall wrap *gomock.Call
+type MockIaaSClientDeletePublicIPCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockIaaSClientDeletePublicIPCall) Return(arg0 error) *MockIaaSClientDeletePublicIPCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockIaaSClientDeletePublicIPCall) Do(f func(context.Context, string) error) *MockIaaSClientDeletePublicIPCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockIaaSClientDeletePublicIPCall) DoAndReturn(f func(context.Context, string) error) *MockIaaSClientDeletePublicIPCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// DeleteSecurityGroupRule mocks base method.
+func (m *MockIaaSClient) DeleteSecurityGroupRule(ctx context.Context, securityGroupID, ruleID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSecurityGroupRule", ctx, securityGroupID, ruleID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSecurityGroupRule indicates an expected call of DeleteSecurityGroupRule.
+func (mr *MockIaaSClientMockRecorder) DeleteSecurityGroupRule(ctx, securityGroupID, ruleID any) *MockIaaSClientDeleteSecurityGroupRuleCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSecurityGroupRule", reflect.TypeOf((*MockIaaSClient)(nil).DeleteSecurityGroupRule), ctx, securityGroupID, ruleID)
+	return &MockIaaSClientDeleteSecurityGroupRuleCall{Call: call}
+}
+
+// MockIaaSClientDeleteSecurityGroupRuleCall wrap *gomock.Call
+type MockIaaSClientDeleteSecurityGroupRuleCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockIaaSClientDeleteSecurityGroupRuleCall) Return(arg0 error) *MockIaaSClientDeleteSecurityGroupRuleCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockIaaSClientDeleteSecurityGroupRuleCall) Do(f func(context.Context, string, string) error) *MockIaaSClientDeleteSecurityGroupRuleCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockIaaSClientDeleteSecurityGroupRuleCall) DoAndReturn(f func(context.Context, string, string) error) *MockIaaSClientDeleteSecurityGroupRuleCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // DeleteSnapshot mocks base method.
 func (m *MockIaaSClient) DeleteSnapshot(ctx context.Context, snapshotID string) error {
 	m.ctrl.T.Helper()
@@ -388,6 +542,45 @@ func (c *MockIaaSClientExpandVolumeCall) DoAndReturn(f func(context.Context, str
 	return c
 }
 
+// GetAffinityGroup mocks base method.
+func (m *MockIaaSClient) GetAffinityGroup(ctx context.Context, affinityGroupID string) (*v2api.AffinityGroup, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAffinityGroup", ctx, affinityGroupID)
+	ret0, _ := ret[0].(*v2api.AffinityGroup)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAffinityGroup indicates an expected call of GetAffinityGroup.
+func (mr *MockIaaSClientMockRecorder) GetAffinityGroup(ctx, affinityGroupID any) *MockIaaSClientGetAffinityGroupCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAffinityGroup", reflect.TypeOf((*MockIaaSClient)(nil).GetAffinityGroup), ctx, affinityGroupID)
+	return &MockIaaSClientGetAffinityGroupCall{Call: call}
+}
+
+// MockIaaSClientGetAffinityGroupCall wrap *gomock.Call
+type MockIaaSClientGetAffinityGroupCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockIaaSClientGetAffinityGroupCall) Return(arg0 *v2api.AffinityGroup, arg1 error) *MockIaaSClientGetAffinityGroupCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockIaaSClientGetAffinityGroupCall) Do(f func(context.Context, string) (*v2api.AffinityGroup, error)) *MockIaaSClientGetAffinityGroupCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockIaaSClientGetAffinityGroupCall) DoAndReturn(f func(context.Context, string) (*v2api.AffinityGroup, error)) *MockIaaSClientGetAffinityGroupCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // GetBackup mocks base method.
 func (m *MockIaaSClient) GetBackup(ctx context.Context, backupID string) (*v2api.Backup, error) {
 	m.ctrl.T.Helper()
@@ -427,6 +620,84 @@ func (c *MockIaaSClientGetBackupCall) DoAndReturn(f func(context.Context, string
 	return c
 }
 
+// GetNetwork mocks base method.
+func (m *MockIaaSClient) GetNetwork(ctx context.Context, networkID string) (*v2api.Network, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNetwork", ctx, networkID)
+	ret0, _ := ret[0].(*v2api.Network)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNetwork indicates an expected call of GetNetwork.
+func (mr *MockIaaSClientMockRecorder) GetNetwork(ctx, networkID any) *MockIaaSClientGetNetworkCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNetwork", reflect.TypeOf((*MockIaaSClient)(nil).GetNetwork), ctx, networkID)
+	return &MockIaaSClientGetNetworkCall{Call: call}
+}
+
+// MockIaaSClientGetNetworkCall wrap *gomock.Call
+type MockIaaSClientGetNetworkCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockIaaSClientGetNetworkCall) Return(arg0 *v2api.Network, arg1 error) *MockIaaSClientGetNetworkCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockIaaSClientGetNetworkCall) Do(f func(context.Context, string) (*v2api.Network, error)) *MockIaaSClientGetNetworkCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockIaaSClientGetNetworkCall) DoAndReturn(f func(context.Context, string) (*v2api.Network, error)) *MockIaaSClientGetNetworkCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// GetProject mocks base method.
+func (m *MockIaaSClient) GetProject(ctx context.Context) (*v2api.Project, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProject", ctx)
+	ret0, _ := ret[0].(*v2api.Project)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProject indicates an expected call of GetProject.
+func (mr *MockIaaSClientMockRecorder) GetProject(ctx any) *MockIaaSClientGetProjectCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProject", reflect.TypeOf((*MockIaaSClient)(nil).GetProject), ctx)
+	return &MockIaaSClientGetProjectCall{Call: call}
+}
+
+// MockIaaSClientGetProjectCall wrap *gomock.Call
+type MockIaaSClientGetProjectCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockIaaSClientGetProjectCall) Return(arg0 *v2api.Project, arg1 error) *MockIaaSClientGetProjectCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockIaaSClientGetProjectCall) Do(f func(context.Context) (*v2api.Project, error)) *MockIaaSClientGetProjectCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockIaaSClientGetProjectCall) DoAndReturn(f func(context.Context) (*v2api.Project, error)) *MockIaaSClientGetProjectCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // GetServer mocks base method.
 func (m *MockIaaSClient) GetServer(ctx context.Context, serverID string) (*v2api.Server, error) {
 	m.ctrl.T.Helper()
@@ -583,6 +854,45 @@ func (c *MockIaaSClientGetVolumeCall) DoAndReturn(f func(context.Context, string
 	return c
 }
 
+// GetVolumePerformanceClass mocks base method.
+func (m *MockIaaSClient) GetVolumePerformanceClass(ctx context.Context, name string) (*v2api.VolumePerformanceClass, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVolumePerformanceClass", ctx, name)
+	ret0, _ := ret[0].(*v2api.VolumePerformanceClass)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVolumePerformanceClass indicates an expected call of GetVolumePerformanceClass.
+func (mr *MockIaaSClientMockRecorder) GetVolumePerformanceClass(ctx, name any) *MockIaaSClientGetVolumePerformanceClassCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVolumePerformanceClass", reflect.TypeOf((*MockIaaSClient)(nil).GetVolumePerformanceClass), ctx, name)
+	return &MockIaaSClientGetVolumePerformanceClassCall{Call: call}
+}
+
+// MockIaaSClientGetVolumePerformanceClassCall wrap *gomock.Call
+type MockIaaSClientGetVolumePerformanceClassCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockIaaSClientGetVolumePerformanceClassCall) Return(arg0 *v2api.VolumePerformanceClass, arg1 error) *MockIaaSClientGetVolumePerformanceClassCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockIaaSClientGetVolumePerformanceClassCall) Do(f func(context.Context, string) (*v2api.VolumePerformanceClass, error)) *MockIaaSClientGetVolumePerformanceClassCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockIaaSClientGetVolumePerformanceClassCall) DoAndReturn(f func(context.Context, string) (*v2api.VolumePerformanceClass, error)) *MockIaaSClientGetVolumePerformanceClassCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // GetVolumesByName mocks base method.
 func (m *MockIaaSClient) GetVolumesByName(ctx context.Context, volName string) ([]v2api.Volume, error) {
 	m.ctrl.T.Helper()
@@ -661,6 +971,84 @@ func (c *MockIaaSClientListBackupsCall) DoAndReturn(f func(context.Context, map[
 	return c
 }
 
+// ListPublicIPs mocks base method.
+func (m *MockIaaSClient) ListPublicIPs(ctx context.Context, labelSelector string) ([]v2api.PublicIp, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPublicIPs", ctx, labelSelector)
+	ret0, _ := ret[0].([]v2api.PublicIp)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPublicIPs indicates an expected call of ListPublicIPs.
+func (mr *MockIaaSClientMockRecorder) ListPublicIPs(ctx, labelSelector any) *MockIaaSClientListPublicIPsCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPublicIPs", reflect.TypeOf((*MockIaaSClient)(nil).ListPublicIPs), ctx, labelSelector)
+	return &MockIaaSClientListPublicIPsCall{Call: call}
+}
+
+// MockIaaSClientListPublicIPsCall wrap *gomock.Call
+type MockIaaSClientListPublicIPsCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockIaaSClientListPublicIPsCall) Return(arg0 []v2api.PublicIp, arg1 error) *MockIaaSClientListPublicIPsCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockIaaSClientListPublicIPsCall) Do(f func(context.Context, string) ([]v2api.PublicIp, error)) *MockIaaSClientListPublicIPsCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockIaaSClientListPublicIPsCall) DoAndReturn(f func(context.Context, string) ([]v2api.PublicIp, error)) *MockIaaSClientListPublicIPsCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// ListSecurityGroupRules mocks base method.
+func (m *MockIaaSClient) ListSecurityGroupRules(ctx context.Context, securityGroupID string) ([]v2api.SecurityGroupRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSecurityGroupRules", ctx, securityGroupID)
+	ret0, _ := ret[0].([]v2api.SecurityGroupRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSecurityGroupRules indicates an expected call of ListSecurityGroupRules.
+func (mr *MockIaaSClientMockRecorder) ListSecurityGroupRules(ctx, securityGroupID any) *MockIaaSClientListSecurityGroupRulesCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSecurityGroupRules", reflect.TypeOf((*MockIaaSClient)(nil).ListSecurityGroupRules), ctx, securityGroupID)
+	return &MockIaaSClientListSecurityGroupRulesCall{Call: call}
+}
+
+// MockIaaSClientListSecurityGroupRulesCall wrap *gomock.Call
+type MockIaaSClientListSecurityGroupRulesCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockIaaSClientListSecurityGroupRulesCall) Return(arg0 []v2api.SecurityGroupRule, arg1 error) *MockIaaSClientListSecurityGroupRulesCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockIaaSClientListSecurityGroupRulesCall) Do(f func(context.Context, string) ([]v2api.SecurityGroupRule, error)) *MockIaaSClientListSecurityGroupRulesCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockIaaSClientListSecurityGroupRulesCall) DoAndReturn(f func(context.Context, string) ([]v2api.SecurityGroupRule, error)) *MockIaaSClientListSecurityGroupRulesCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // ListServers mocks base method.
 func (m *MockIaaSClient) ListServers(ctx context.Context) (*[]v2api.Server, error) {
 	m.ctrl.T.Helper()
@@ -780,95 +1168,135 @@ func (c *MockIaaSClientListVolumesCall) DoAndReturn(f func(context.Context, int,
 	return c
 }
 
-// WaitBackupReady mocks base method.
-func (m *MockIaaSClient) WaitBackupReady(ctx context.Context, backupID string, snapshotSize int64, backupMaxDurationSecondsPerGB int) (*string, error) {
+// UpdatePublicIP mocks base method.
+func (m *MockIaaSClient) UpdatePublicIP(ctx context.Context, publicIPID string, payload v2api.UpdatePublicIPPayload) (*v2api.PublicIp, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "WaitBackupReady", ctx, backupID, snapshotSize, backupMaxDurationSecondsPerGB)
-	ret0, _ := ret[0].(*string)
+	ret := m.ctrl.Call(m, "UpdatePublicIP", ctx, publicIPID, payload)
+	ret0, _ := ret[0].(*v2api.PublicIp)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// WaitBackupReady indicates an expected call of WaitBackupReady.
-func (mr *MockIaaSClientMockRecorder) WaitBackupReady(ctx, backupID, snapshotSize, backupMaxDurationSecondsPerGB any) *MockIaaSClientWaitBackupReadyCall {
+// UpdatePublicIP indicates an expected call of UpdatePublicIP.
+func (mr *MockIaaSClientMockRecorder) UpdatePublicIP(ctx, publicIPID, payload any) *MockIaaSClientUpdatePublicIPCall {
 	mr.mock.ctrl.T.Helper()
-	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitBackupReady", reflect.TypeOf((*MockIaaSClient)(nil).WaitBackupReady), ctx, backupID, snapshotSize, backupMaxDurationSecondsPerGB)
-	return &MockIaaSClientWaitBackupReadyCall{Call: call}
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePublicIP", reflect.TypeOf((*MockIaaSClient)(nil).UpdatePublicIP), ctx, publicIPID, payload)
+	return &MockIaaSClientUpdatePublicIPCall{Call: call}
 }
 
-// MockIaaSClientWaitBackupReadyCall wrap *gomock.Call
-type MockIaaSClientWaitBackupReadyCall struct {
+// MockIaaSClientUpdatePublicIPCall wrap *gomock.Call
+type MockIaaSClientUpdatePublicIPCall struct {
 	*gomock.Call
 }
 
 // Return rewrite *gomock.Call.Return
-func (c *MockIaaSClientWaitBackupReadyCall) Return(arg0 *string, arg1 error) *MockIaaSClientWaitBackupReadyCall {
+func (c *MockIaaSClientUpdatePublicIPCall) Return(arg0 *v2api.PublicIp, arg1 error) *MockIaaSClientUpdatePublicIPCall {
 	c.Call = c.Call.Return(arg0, arg1)
 	return c
 }
 
 // Do rewrite *gomock.Call.Do
-func (c *MockIaaSClientWaitBackupReadyCall) Do(f func(context.Context, string, int64, int) (*string, error)) *MockIaaSClientWaitBackupReadyCall {
+func (c *MockIaaSClientUpdatePublicIPCall) Do(f func(context.Context, string, v2api.UpdatePublicIPPayload) (*v2api.PublicIp, error)) *MockIaaSClientUpdatePublicIPCall {
 	c.Call = c.Call.Do(f)
 	return c
 }
 
 // DoAndReturn rewrite *gomock.Call.DoAndReturn
-func (c *MockIaaSClientWaitBackupReadyCall) DoAndReturn(f func(context.Context, string, int64, int) (*string, error)) *MockIaaSClientWaitBackupReadyCall {
+func (c *MockIaaSClientUpdatePublicIPCall) DoAndReturn(f func(context.Context, string, v2api.UpdatePublicIPPayload) (*v2api.PublicIp, error)) *MockIaaSClientUpdatePublicIPCall {
 	c.Call = c.Call.DoAndReturn(f)
 	return c
 }
 
-// WaitDiskAttached mocks base method.
-func (m *MockIaaSClient) WaitDiskAttached(ctx context.Context, instanceID, volumeID string) error {
+// UpdateVolume mocks base method.
+func (m *MockIaaSClient) UpdateVolume(ctx context.Context, volumeID string, payload v2api.UpdateVolumePayload) (*v2api.Volume, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "WaitDiskAttached", ctx, instanceID, volumeID)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "UpdateVolume", ctx, volumeID, payload)
+	ret0, _ := ret[0].(*v2api.Volume)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// WaitDiskAttached indicates an expected call of WaitDiskAttached.
-func (mr *MockIaaSClientMockRecorder) WaitDiskAttached(ctx, instanceID, volumeID any) *MockIaaSClientWaitDiskAttachedCall {
+// UpdateVolume indicates an expected call of UpdateVolume.
+func (mr *MockIaaSClientMockRecorder) UpdateVolume(ctx, volumeID, payload any) *MockIaaSClientUpdateVolumeCall {
 	mr.mock.ctrl.T.Helper()
-	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitDiskAttached", reflect.TypeOf((*MockIaaSClient)(nil).WaitDiskAttached), ctx, instanceID, volumeID)
-	return &MockIaaSClientWaitDiskAttachedCall{Call: call}
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateVolume", reflect.TypeOf((*MockIaaSClient)(nil).UpdateVolume), ctx, volumeID, payload)
+	return &MockIaaSClientUpdateVolumeCall{Call: call}
 }
 
-// MockIaaSClientWaitDiskAttachedCall wrap *gomock.Call
-type MockIaaSClientWaitDiskAttachedCall struct {
+// MockIaaSClientUpdateVolumeCall wrap *gomock.Call
+type MockIaaSClientUpdateVolumeCall struct {
 	*gomock.Call
 }
 
 // Return rewrite *gomock.Call.Return
-func (c *MockIaaSClientWaitDiskAttachedCall) Return(arg0 error) *MockIaaSClientWaitDiskAttachedCall {
-	c.Call = c.Call.Return(arg0)
+func (c *MockIaaSClientUpdateVolumeCall) Return(arg0 *v2api.Volume, arg1 error) *MockIaaSClientUpdateVolumeCall {
+	c.Call = c.Call.Return(arg0, arg1)
 	return c
 }
 
 // Do rewrite *gomock.Call.Do
-func (c *MockIaaSClientWaitDiskAttachedCall) Do(f func(context.Context, string, string) error) *MockIaaSClientWaitDiskAttachedCall {
+func (c *MockIaaSClientUpdateVolumeCall) Do(f func(context.Context, string, v2api.UpdateVolumePayload) (*v2api.Volume, error)) *MockIaaSClientUpdateVolumeCall {
 	c.Call = c.Call.Do(f)
 	return c
 }
 
 // DoAndReturn rewrite *gomock.Call.DoAndReturn
-func (c *MockIaaSClientWaitDiskAttachedCall) DoAndReturn(f func(context.Context, string, string) error) *MockIaaSClientWaitDiskAttachedCall {
+func (c *MockIaaSClientUpdateVolumeCall) DoAndReturn(f func(context.Context, string, v2api.UpdateVolumePayload) (*v2api.Volume, error)) *MockIaaSClientUpdateVolumeCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// WaitBackupReady mocks base method.
+func (m *MockIaaSClient) WaitBackupReady(ctx context.Context, backupID string, snapshotSize int64, backupMaxDurationSecondsPerGB int) (*string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaitBackupReady", ctx, backupID, snapshotSize, backupMaxDurationSecondsPerGB)
+	ret0, _ := ret[0].(*string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WaitBackupReady indicates an expected call of WaitBackupReady.
+func (mr *MockIaaSClientMockRecorder) WaitBackupReady(ctx, backupID, snapshotSize, backupMaxDurationSecondsPerGB any) *MockIaaSClientWaitBackupReadyCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitBackupReady", reflect.TypeOf((*MockIaaSClient)(nil).WaitBackupReady), ctx, backupID, snapshotSize, backupMaxDurationSecondsPerGB)
+	return &MockIaaSClientWaitBackupReadyCall{Call: call}
+}
+
+// MockIaaSClientWaitBackupReadyCall wrap *gomock.Call
+type MockIaaSClientWaitBackupReadyCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockIaaSClientWaitBackupReadyCall) Return(arg0 *string, arg1 error) *MockIaaSClientWaitBackupReadyCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockIaaSClientWaitBackupReadyCall) Do(f func(context.Context, string, int64, int) (*string, error)) *MockIaaSClientWaitBackupReadyCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockIaaSClientWaitBackupReadyCall) DoAndReturn(f func(context.Context, string, int64, int) (*string, error)) *MockIaaSClientWaitBackupReadyCall {
 	c.Call = c.Call.DoAndReturn(f)
 	return c
 }
 
 // WaitDiskDetached mocks base method.
-func (m *MockIaaSClient) WaitDiskDetached(ctx context.Context, instanceID, volumeID string) error {
+func (m *MockIaaSClient) WaitDiskDetached(ctx context.Context, instanceID, volumeID string, backoff *wait.Backoff) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "WaitDiskDetached", ctx, instanceID, volumeID)
+	ret := m.ctrl.Call(m, "WaitDiskDetached", ctx, instanceID, volumeID, backoff)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // WaitDiskDetached indicates an expected call of WaitDiskDetached.
-func (mr *MockIaaSClientMockRecorder) WaitDiskDetached(ctx, instanceID, volumeID any) *MockIaaSClientWaitDiskDetachedCall {
+func (mr *MockIaaSClientMockRecorder) WaitDiskDetached(ctx, instanceID, volumeID, backoff any) *MockIaaSClientWaitDiskDetachedCall {
 	mr.mock.ctrl.T.Helper()
-	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitDiskDetached", reflect.TypeOf((*MockIaaSClient)(nil).WaitDiskDetached), ctx, instanceID, volumeID)
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitDiskDetached", reflect.TypeOf((*MockIaaSClient)(nil).WaitDiskDetached), ctx, instanceID, volumeID, backoff)
 	return &MockIaaSClientWaitDiskDetachedCall{Call: call}
 }
 
@@ -884,30 +1312,30 @@ func (c *MockIaaSClientWaitDiskDetachedCall) Return(arg0 error) *MockIaaSClientW
 }
 
 // Do rewrite *gomock.Call.Do
-func (c *MockIaaSClientWaitDiskDetachedCall) Do(f func(context.Context, string, string) error) *MockIaaSClientWaitDiskDetachedCall {
+func (c *MockIaaSClientWaitDiskDetachedCall) Do(f func(context.Context, string, string, *wait.Backoff) error) *MockIaaSClientWaitDiskDetachedCall {
 	c.Call = c.Call.Do(f)
 	return c
 }
 
 // DoAndReturn rewrite *gomock.Call.DoAndReturn
-func (c *MockIaaSClientWaitDiskDetachedCall) DoAndReturn(f func(context.Context, string, string) error) *MockIaaSClientWaitDiskDetachedCall {
+func (c *MockIaaSClientWaitDiskDetachedCall) DoAndReturn(f func(context.Context, string, string, *wait.Backoff) error) *MockIaaSClientWaitDiskDetachedCall {
 	c.Call = c.Call.DoAndReturn(f)
 	return c
 }
 
 // WaitSnapshotReady mocks base method.
-func (m *MockIaaSClient) WaitSnapshotReady(ctx context.Context, snapshotID string) (*string, error) {
+func (m *MockIaaSClient) WaitSnapshotReady(ctx context.Context, snapshotID string, backoff *wait.Backoff) (*string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "WaitSnapshotReady", ctx, snapshotID)
+	ret := m.ctrl.Call(m, "WaitSnapshotReady", ctx, snapshotID, backoff)
 	ret0, _ := ret[0].(*string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // WaitSnapshotReady indicates an expected call of WaitSnapshotReady.
-func (mr *MockIaaSClientMockRecorder) WaitSnapshotReady(ctx, snapshotID any) *MockIaaSClientWaitSnapshotReadyCall {
+func (mr *MockIaaSClientMockRecorder) WaitSnapshotReady(ctx, snapshotID, backoff any) *MockIaaSClientWaitSnapshotReadyCall {
 	mr.mock.ctrl.T.Helper()
-	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitSnapshotReady", reflect.TypeOf((*MockIaaSClient)(nil).WaitSnapshotReady), ctx, snapshotID)
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitSnapshotReady", reflect.TypeOf((*MockIaaSClient)(nil).WaitSnapshotReady), ctx, snapshotID, backoff)
 	return &MockIaaSClientWaitSnapshotReadyCall{Call: call}
 }
 
@@ -923,13 +1351,13 @@ func (c *MockIaaSClientWaitSnapshotReadyCall) Return(arg0 *string, arg1 error) *
 }
 
 // Do rewrite *gomock.Call.Do
-func (c *MockIaaSClientWaitSnapshotReadyCall) Do(f func(context.Context, string) (*string, error)) *MockIaaSClientWaitSnapshotReadyCall {
+func (c *MockIaaSClientWaitSnapshotReadyCall) Do(f func(context.Context, string, *wait.Backoff) (*string, error)) *MockIaaSClientWaitSnapshotReadyCall {
 	c.Call = c.Call.Do(f)
 	return c
 }
 
 // DoAndReturn rewrite *gomock.Call.DoAndReturn
-func (c *MockIaaSClientWaitSnapshotReadyCall) DoAndReturn(f func(context.Context, string) (*string, error)) *MockIaaSClientWaitSnapshotReadyCall {
+func (c *MockIaaSClientWaitSnapshotReadyCall) DoAndReturn(f func(context.Context, string, *wait.Backoff) (*string, error)) *MockIaaSClientWaitSnapshotReadyCall {
 	c.Call = c.Call.DoAndReturn(f)
 	return c
 }
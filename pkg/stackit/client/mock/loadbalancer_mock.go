@@ -234,6 +234,45 @@ func (c *MockLoadBalancingClientGetLoadBalancerCall) DoAndReturn(f func(context.
 	return c
 }
 
+// GetQuota mocks base method.
+func (m *MockLoadBalancingClient) GetQuota(ctx context.Context) (*v2api.GetQuotaResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetQuota", ctx)
+	ret0, _ := ret[0].(*v2api.GetQuotaResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetQuota indicates an expected call of GetQuota.
+func (mr *MockLoadBalancingClientMockRecorder) GetQuota(ctx any) *MockLoadBalancingClientGetQuotaCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetQuota", reflect.TypeOf((*MockLoadBalancingClient)(nil).GetQuota), ctx)
+	return &MockLoadBalancingClientGetQuotaCall{Call: call}
+}
+
+// MockLoadBalancingClientGetQuotaCall wrap *gomock.Call
+type MockLoadBalancingClientGetQuotaCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockLoadBalancingClientGetQuotaCall) Return(arg0 *v2api.GetQuotaResponse, arg1 error) *MockLoadBalancingClientGetQuotaCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockLoadBalancingClientGetQuotaCall) Do(f func(context.Context) (*v2api.GetQuotaResponse, error)) *MockLoadBalancingClientGetQuotaCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockLoadBalancingClientGetQuotaCall) DoAndReturn(f func(context.Context) (*v2api.GetQuotaResponse, error)) *MockLoadBalancingClientGetQuotaCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // ListCredentials mocks base method.
 func (m *MockLoadBalancingClient) ListCredentials(ctx context.Context) (*v2api.ListCredentialsResponse, error) {
 	m.ctrl.T.Helper()
@@ -273,6 +312,84 @@ func (c *MockLoadBalancingClientListCredentialsCall) DoAndReturn(f func(context.
 	return c
 }
 
+// ListLoadBalancers mocks base method.
+func (m *MockLoadBalancingClient) ListLoadBalancers(ctx context.Context) ([]v2api.LoadBalancer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListLoadBalancers", ctx)
+	ret0, _ := ret[0].([]v2api.LoadBalancer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListLoadBalancers indicates an expected call of ListLoadBalancers.
+func (mr *MockLoadBalancingClientMockRecorder) ListLoadBalancers(ctx any) *MockLoadBalancingClientListLoadBalancersCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLoadBalancers", reflect.TypeOf((*MockLoadBalancingClient)(nil).ListLoadBalancers), ctx)
+	return &MockLoadBalancingClientListLoadBalancersCall{Call: call}
+}
+
+// MockLoadBalancingClientListLoadBalancersCall wrap *gomock.Call
+type MockLoadBalancingClientListLoadBalancersCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockLoadBalancingClientListLoadBalancersCall) Return(arg0 []v2api.LoadBalancer, arg1 error) *MockLoadBalancingClientListLoadBalancersCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockLoadBalancingClientListLoadBalancersCall) Do(f func(context.Context) ([]v2api.LoadBalancer, error)) *MockLoadBalancingClientListLoadBalancersCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockLoadBalancingClientListLoadBalancersCall) DoAndReturn(f func(context.Context) ([]v2api.LoadBalancer, error)) *MockLoadBalancingClientListLoadBalancersCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// ListPlans mocks base method.
+func (m *MockLoadBalancingClient) ListPlans(ctx context.Context) ([]v2api.PlanDetails, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPlans", ctx)
+	ret0, _ := ret[0].([]v2api.PlanDetails)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPlans indicates an expected call of ListPlans.
+func (mr *MockLoadBalancingClientMockRecorder) ListPlans(ctx any) *MockLoadBalancingClientListPlansCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPlans", reflect.TypeOf((*MockLoadBalancingClient)(nil).ListPlans), ctx)
+	return &MockLoadBalancingClientListPlansCall{Call: call}
+}
+
+// MockLoadBalancingClientListPlansCall wrap *gomock.Call
+type MockLoadBalancingClientListPlansCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockLoadBalancingClientListPlansCall) Return(arg0 []v2api.PlanDetails, arg1 error) *MockLoadBalancingClientListPlansCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockLoadBalancingClientListPlansCall) Do(f func(context.Context) ([]v2api.PlanDetails, error)) *MockLoadBalancingClientListPlansCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockLoadBalancingClientListPlansCall) DoAndReturn(f func(context.Context) ([]v2api.PlanDetails, error)) *MockLoadBalancingClientListPlansCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // UpdateCredentials mocks base method.
 func (m *MockLoadBalancingClient) UpdateCredentials(ctx context.Context, credentialsRef string, payload v2api.UpdateCredentialsPayload) error {
 	m.ctrl.T.Helper()
@@ -41,6 +41,21 @@ func (m *MockFactory) EXPECT() *MockFactoryMockRecorder {
 	return m.recorder
 }
 
+// DNS mocks base method.
+func (m *MockFactory) DNS(options []config.ConfigurationOption) (client.DNSClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DNS", options)
+	ret0, _ := ret[0].(client.DNSClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DNS indicates an expected call of DNS.
+func (mr *MockFactoryMockRecorder) DNS(options any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DNS", reflect.TypeOf((*MockFactory)(nil).DNS), options)
+}
+
 // IaaS mocks base method.
 func (m *MockFactory) IaaS(options []config.ConfigurationOption) (client.IaaSClient, error) {
 	m.ctrl.T.Helper()
@@ -0,0 +1,35 @@
+package client
+
+import (
+	"net/http"
+
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+	sdkconfig "github.com/stackitcloud/stackit-sdk-go/core/config"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// WithRateLimit returns an option that limits the API calls of a client with a token bucket, so that calls wait for
+// a token instead of running into the rate limit of the API. Token requests are not limited.
+// It returns no options if the rate limit is disabled.
+func WithRateLimit(opts stackitconfig.RateLimitOpts) []sdkconfig.ConfigurationOption {
+	if opts.QPS <= 0 {
+		return nil
+	}
+	burst := max(opts.Burst, 1)
+	limiter := flowcontrol.NewTokenBucketRateLimiter(float32(opts.QPS), burst)
+	return []sdkconfig.ConfigurationOption{sdkconfig.WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+		return &rateLimitedTransport{next: next, limiter: limiter}
+	})}
+}
+
+type rateLimitedTransport struct {
+	next    http.RoundTripper
+	limiter flowcontrol.RateLimiter
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
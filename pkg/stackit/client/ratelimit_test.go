@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+	sdkconfig "github.com/stackitcloud/stackit-sdk-go/core/config"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+var _ = Describe("WithRateLimit", func() {
+	It("should not limit calls by default", func() {
+		Expect(WithRateLimit(stackitconfig.RateLimitOpts{})).To(BeEmpty())
+	})
+
+	It("should make calls wait for a token", func() {
+		options := WithRateLimit(stackitconfig.RateLimitOpts{QPS: 0.1, Burst: 1})
+		Expect(options).To(HaveLen(1))
+		cfg := &sdkconfig.Configuration{}
+		Expect(options[0](cfg)).To(Succeed())
+		Expect(cfg.Middleware).To(HaveLen(1))
+
+		calls := 0
+		transport := cfg.Middleware[0](roundTripperFunc(func(*http.Request) (*http.Response, error) {
+			calls++
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		}))
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://example.com", http.NoBody)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = transport.RoundTrip(req)
+		Expect(err).NotTo(HaveOccurred())
+		// The next token is only available after 10s.
+		_, err = transport.RoundTrip(req)
+		Expect(err).To(HaveOccurred())
+		Expect(calls).To(Equal(1))
+	})
+})
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/metrics"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+	sdkconfig "github.com/stackitcloud/stackit-sdk-go/core/config"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// NewTransport returns the transport for all STACKIT API calls including the token requests.
+// It trusts the CA bundle and uses the proxy configured in endpoints.
+func NewTransport(endpoints stackitconfig.APIEndpoints) (http.RoundTripper, error) {
+	if endpoints.CABundle == "" && endpoints.ProxyURL == "" {
+		return http.DefaultTransport, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if endpoints.ProxyURL != "" {
+		proxyURL, err := url.Parse(endpoints.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		if proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q: scheme and host are required", endpoints.ProxyURL)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if endpoints.CABundle != "" {
+		pem, err := os.ReadFile(endpoints.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("CA bundle does not contain any PEM encoded certificates")
+		}
+		transport.TLSClientConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			RootCAs:    pool,
+		}
+	}
+
+	return transport, nil
+}
+
+// ConfigurationOptions returns the SDK options for a client of the API apiName.
+// If endpoint is not empty, it overrides the default URL of the API.
+func ConfigurationOptions(apiName, endpoint string, endpoints stackitconfig.APIEndpoints) ([]sdkconfig.ConfigurationOption, error) {
+	transport, err := NewTransport(endpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every API call is a span of the reconciliation or CSI call in its context, if tracing is enabled.
+	transport = otelhttp.NewTransport(transport, otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return fmt.Sprintf("%s %s", apiName, r.Method)
+	}))
+	opts := []sdkconfig.ConfigurationOption{
+		sdkconfig.WithHTTPClient(metrics.NewInstrumentedHTTPClientWithTransport(apiName, transport)),
+	}
+	if endpoint != "" {
+		opts = append(opts, sdkconfig.WithEndpoint(endpoint))
+	}
+	if endpoints.TokenAPI != "" {
+		opts = append(opts, sdkconfig.WithTokenEndpoint(endpoints.TokenAPI))
+	}
+	return opts, nil
+}
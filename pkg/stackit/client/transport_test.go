@@ -0,0 +1,94 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+)
+
+var _ = Describe("NewTransport", func() {
+	It("should use the default transport without CA bundle and proxy", func() {
+		transport, err := NewTransport(stackitconfig.APIEndpoints{IaasAPI: "https://iaas.example.com"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(transport).To(BeIdenticalTo(http.DefaultTransport))
+	})
+
+	It("should use the configured proxy", func() {
+		transport, err := NewTransport(stackitconfig.APIEndpoints{ProxyURL: "http://proxy.example.com:3128"})
+		Expect(err).NotTo(HaveOccurred())
+
+		req, _ := http.NewRequest(http.MethodGet, "https://iaas.api.stackit.cloud", http.NoBody)
+		proxyURL, err := transport.(*http.Transport).Proxy(req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(proxyURL.String()).To(Equal("http://proxy.example.com:3128"))
+	})
+
+	It("should reject proxy URLs without scheme", func() {
+		_, err := NewTransport(stackitconfig.APIEndpoints{ProxyURL: "proxy.example.com"})
+		Expect(err).To(MatchError(ContainSubstring("invalid proxy URL")))
+	})
+
+	It("should trust the certificates of the CA bundle", func() {
+		caFile := filepath.Join(GinkgoT().TempDir(), "ca.pem")
+		Expect(os.WriteFile(caFile, selfSignedCertificate(), 0o600)).To(Succeed())
+
+		transport, err := NewTransport(stackitconfig.APIEndpoints{CABundle: caFile})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(transport.(*http.Transport).TLSClientConfig.RootCAs).NotTo(BeNil())
+	})
+
+	It("should fail if the CA bundle contains no certificates", func() {
+		caFile := filepath.Join(GinkgoT().TempDir(), "ca.pem")
+		Expect(os.WriteFile(caFile, []byte("not a certificate"), 0o600)).To(Succeed())
+
+		_, err := NewTransport(stackitconfig.APIEndpoints{CABundle: caFile})
+		Expect(err).To(MatchError(ContainSubstring("does not contain any PEM encoded certificates")))
+	})
+
+	It("should fail if the CA bundle does not exist", func() {
+		_, err := NewTransport(stackitconfig.APIEndpoints{CABundle: "/does/not/exist"})
+		Expect(err).To(MatchError(ContainSubstring("failed to read CA bundle")))
+	})
+})
+
+var _ = Describe("ConfigurationOptions", func() {
+	It("should add endpoint overrides", func() {
+		opts, err := ConfigurationOptions("iaas", "https://iaas.example.com", stackitconfig.APIEndpoints{TokenAPI: "https://token.example.com"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(opts).To(HaveLen(3))
+	})
+
+	It("should only configure the HTTP client without overrides", func() {
+		opts, err := ConfigurationOptions("iaas", "", stackitconfig.APIEndpoints{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(opts).To(HaveLen(1))
+	})
+})
+
+func selfSignedCertificate() []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
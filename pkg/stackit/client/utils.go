@@ -1,9 +1,52 @@
 package client
 
 import (
+	"maps"
+	"slices"
+	"strings"
+
 	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
 )
 
+// labelFilterPrefix prefixes the filters of ListSnapshots and ListBackups that match a label instead of a field,
+// see LabelFilter.
+const labelFilterPrefix = "Label:"
+
+// LabelFilter returns the key of a filter of ListSnapshots and ListBackups that matches the label key. Unlike other
+// filters, label filters are applied by the API, which reduces the size of the responses in big projects.
+func LabelFilter(key string) string {
+	return labelFilterPrefix + key
+}
+
+// filterLabel returns the label that holds the value of the filter key, if any.
+func filterLabel(key string) (string, bool) {
+	if key == "GroupSnapshotID" {
+		return SnapshotGroupLabel, true
+	}
+	return strings.CutPrefix(key, labelFilterPrefix)
+}
+
+// labelSelector returns the label selector of all filters that match labels, "" if there are none.
+func labelSelector(filters map[string]string) string {
+	var requirements []string
+	for _, key := range slices.Sorted(maps.Keys(filters)) {
+		if label, ok := filterLabel(key); ok {
+			requirements = append(requirements, label+"="+filters[key])
+		}
+	}
+	return strings.Join(requirements, ",")
+}
+
+// matchesLabelFilters returns whether the labels match all filters that match labels.
+func matchesLabelFilters(labels map[string]any, filters map[string]string) bool {
+	for key, value := range filters {
+		if label, ok := filterLabel(key); ok && labels[label] != value {
+			return false
+		}
+	}
+	return true
+}
+
 func LabelsFromTags(tags map[string]string) map[string]any {
 	l := make(map[string]any, len(tags))
 	for key, value := range tags {
@@ -49,6 +92,9 @@ func FilterSnapshots(snapshots []iaas.Snapshot, filters map[string]string) []iaa
 		if val, ok := filters["Name"]; ok && val != obj.GetName() {
 			continue
 		}
+		if !matchesLabelFilters(obj.GetLabels(), filters) {
+			continue
+		}
 		filteredSnapshots = append(filteredSnapshots, obj)
 	}
 
@@ -73,6 +119,9 @@ func FilterBackups(backups []iaas.Backup, filters map[string]string) []iaas.Back
 		if val, ok := filters["Name"]; ok && val != obj.GetName() {
 			continue
 		}
+		if !matchesLabelFilters(obj.GetLabels(), filters) {
+			continue
+		}
 		filteredBackups = append(filteredBackups, obj)
 	}
 
@@ -57,6 +57,30 @@ var _ = Describe("Filter", func() {
 			Expect(*result[0].Name).To(Equal("backup-1"))
 			Expect(*result[1].Name).To(Equal("backup-3"))
 		})
+
+		It("should filter by label", func() {
+			backups[1].Labels = map[string]any{"scheduled": "true"}
+			filters[LabelFilter("scheduled")] = "true"
+			result := FilterBackups(backups, filters)
+			Expect(result).To(HaveLen(1))
+			Expect(*result[0].Name).To(Equal("backup-2"))
+		})
+	})
+
+	Describe("labelSelector", func() {
+		It("should select the labels of all label filters", func() {
+			Expect(labelSelector(map[string]string{
+				"Name":            "backup-1",
+				LabelFilter("b"):  "2",
+				LabelFilter("a"):  "1",
+				"GroupSnapshotID": "group-1",
+			})).To(Equal(SnapshotGroupLabel + "=group-1,a=1,b=2"))
+		})
+
+		It("should be empty without label filters", func() {
+			Expect(labelSelector(map[string]string{"Name": "backup-1"})).To(BeEmpty())
+			Expect(labelSelector(nil)).To(BeEmpty())
+		})
 	})
 
 	Describe("FilterVolumes", func() {
@@ -98,7 +122,7 @@ var _ = Describe("Filter", func() {
 			snapshots = []iaas.Snapshot{
 				{Status: new("available"), VolumeId: "vol-1", Name: new("snapshot-1")},
 				{Status: new("error"), VolumeId: "vol-2", Name: new("snapshot-2")},
-				{Status: new("available"), VolumeId: "vol-1", Name: new("snapshot-3")},
+				{Status: new("available"), VolumeId: "vol-1", Name: new("snapshot-3"), Labels: map[string]any{SnapshotGroupLabel: "group-1"}},
 			}
 			filters = make(map[string]string)
 		})
@@ -130,6 +154,13 @@ var _ = Describe("Filter", func() {
 			Expect(*result[0].Name).To(Equal("snapshot-1"))
 		})
 
+		It("should filter by GroupSnapshotID", func() {
+			filters["GroupSnapshotID"] = "group-1"
+			result := FilterSnapshots(snapshots, filters)
+			Expect(result).To(HaveLen(1))
+			Expect(*result[0].Name).To(Equal("snapshot-3"))
+		})
+
 		It("should filter by multiple criteria", func() {
 			filters["Status"] = "available"
 			filters["VolumeID"] = "vol-1"
@@ -5,14 +5,46 @@ import (
 )
 
 type GlobalOpts struct {
-	ProjectID    string       `yaml:"projectId"`
-	Region       string       `yaml:"region"`
-	APIEndpoints APIEndpoints `yaml:"apiEndpoints"`
+	ProjectID string `yaml:"projectId"`
+	Region    string `yaml:"region"`
+	// ClusterID identifies the cluster among all clusters in the project.
+	// It prefixes the display names of observability credentials, so that clusters don't clean up each other's credentials.
+	ClusterID string `yaml:"clusterId"`
+	// ResourceLabels labels all load balancers, volumes, snapshots and backups with the cluster ID, the component
+	// that created them and the UID of their service or PVC, e.g. for cost attribution.
+	ResourceLabels bool         `yaml:"resourceLabels"`
+	APIEndpoints   APIEndpoints `yaml:"apiEndpoints"`
+	APITimeouts    APITimeouts  `yaml:"apiTimeouts"`
+	Audit          AuditOpts    `yaml:"audit"`
+}
+
+// APITimeouts bounds the time spent in calls to the STACKIT APIs, so that stuck requests don't block the sync loops.
+type APITimeouts struct {
+	// Request is the timeout of a single API call. Defaults to 30s.
+	Request metadata.Duration `yaml:"request"`
+	// Wait is the timeout of waiting for a resource to reach a state, e.g. a volume to be attached. Defaults to 5m.
+	Wait metadata.Duration `yaml:"wait"`
+}
+
+// AuditOpts configures the audit log of all mutating calls to the STACKIT APIs.
+type AuditOpts struct {
+	// Enabled logs every create, update and delete call to the "audit" logger.
+	Enabled bool `yaml:"enabled"`
+	// WebhookURL optionally receives every audit event as JSON in a POST request.
+	WebhookURL string `yaml:"webhookUrl"`
 }
 
 type APIEndpoints struct {
 	IaasAPI         string `yaml:"iaasApi"`
 	LoadBalancerAPI string `yaml:"loadBalancerApi"`
+	DNSAPI          string `yaml:"dnsApi"`
+	// TokenAPI overrides the endpoint used to exchange the service account key for an access token.
+	TokenAPI string `yaml:"tokenApi"`
+	// CABundle is the path to a PEM file with additional CA certificates that are trusted for all API calls,
+	// e.g. for gateways with a private PKI.
+	CABundle string `yaml:"caBundle"`
+	// ProxyURL is the HTTP proxy used for all API calls. If empty, the proxy environment variables are used.
+	ProxyURL string `yaml:"proxyUrl"`
 }
 
 type CCMConfig struct {
@@ -20,6 +52,15 @@ type CCMConfig struct {
 	Metadata     metadata.Opts    `yaml:"metadata"`
 	LoadBalancer LoadBalancerOpts `yaml:"loadBalancer"`
 	Instance     InstanceOpts     `yaml:"instance"`
+	DNS          DNSOpts          `yaml:"dns"`
+}
+
+// DNSOpts configures the dns controller, which registers the IPs of load balancers in a STACKIT DNS zone.
+type DNSOpts struct {
+	// ZoneID is the ID of the zone in which the records are created. The dns controller requires it.
+	ZoneID string `yaml:"zoneId"`
+	// TTL is the time to live of the records in seconds. Defaults to 60.
+	TTL int64 `yaml:"ttl"`
 }
 
 type InstanceOpts struct {
@@ -27,11 +68,94 @@ type InstanceOpts struct {
 	// It can contain either the network name or ID.
 	// Can be used in mulit-network scenario to indicate which NIC is the primary one.
 	DefaultNetwork string `yaml:"defaultNetwork"`
+	// NetworkPriority contains network names or IDs in the order in which the addresses of their NICs are
+	// published as node addresses. NICs of other networks follow in alphabetical order of their network name.
+	// The first internal IP is used by kubelet and as load balancer target, so both agree on the primary NIC.
+	NetworkPriority []string `yaml:"networkPriority"`
 }
 
 type LoadBalancerOpts struct {
-	NetworkID   string            `yaml:"networkId"`
-	ExtraLabels map[string]string `yaml:"extraLabels"`
+	// NetworkID is the network of the listeners and targets of load balancers. In clusters spanning multiple
+	// networks, it is the network of nodes whose network is unknown.
+	NetworkID string `yaml:"networkId"`
+	// NetworkAutoDetection uses the network of the primary NIC of nodes as their network, which the
+	// node-metadata-labels controller records in the stackit.cloud/network-id label.
+	NetworkAutoDetection bool `yaml:"networkAutoDetection"`
+	// NodePoolLabel is the label containing the node pool of a node. Defaults to worker.gardener.cloud/pool.
+	NodePoolLabel string `yaml:"nodePoolLabel"`
+	// NodePoolNetworks maps node pools to the ID of the network of their nodes.
+	// It takes precedence over NetworkAutoDetection.
+	NodePoolNetworks map[string]string `yaml:"nodePoolNetworks"`
+	ExtraLabels      map[string]string `yaml:"extraLabels"`
+	// NodeSecurityGroupID is the ID of a security group attached to all nodes.
+	// If set, the CCM opens only the node ports used by its load balancers in this security group.
+	NodeSecurityGroupID string `yaml:"nodeSecurityGroupId"`
+	// PlanRecommendation emits events that recommend a different service plan based on the load of a load balancer.
+	PlanRecommendation PlanRecommendationOpts `yaml:"planRecommendation"`
+	// MaxListenersPerPlan limits the number of listeners of load balancers by their plan ID, e.g. to reject port
+	// ranges that exceed the limits of the plan before calling the API. Plans without an entry are only limited by the
+	// maximum number of listeners of a load balancer.
+	// Automatic plans start with the smallest plan that allows the listeners of the load balancer.
+	MaxListenersPerPlan map[string]int `yaml:"maxListenersPerPlan"`
+	// MaxTargetsPerPlan is the number of targets per target pool that a plan is sized for by plan ID. Automatic plans
+	// start with the smallest plan that allows the targets of the load balancer. Plans without an entry are not limited.
+	MaxTargetsPerPlan map[string]int `yaml:"maxTargetsPerPlan"`
+	// DeregistrationDelay is the default time nodes are kept as targets after they were removed from a load balancer,
+	// e.g. because they are drained or excluded from load balancers. Disabled by default.
+	DeregistrationDelay metadata.Duration `yaml:"deregistrationDelay"`
+	// ExcludeNotReadyNodes removes nodes without a ready condition from the targets of load balancers.
+	ExcludeNotReadyNodes bool `yaml:"excludeNotReadyNodes"`
+	// ExcludeUnschedulableNodes removes cordoned nodes from the targets of load balancers.
+	ExcludeUnschedulableNodes bool `yaml:"excludeUnschedulableNodes"`
+	// RecreateDelay is the time between detecting a change of an immutable field of a service with the annotation
+	// lb.stackit.cloud/allow-recreate and deleting its load balancer to recreate it. Defaults to 5m.
+	RecreateDelay metadata.Duration `yaml:"recreateDelay"`
+	// PublishSpec renders the desired load balancer of each service into a ConfigMap in the namespace of the service
+	// on every reconciliation, so that changes can be reviewed and alerted on before or after they are applied.
+	PublishSpec bool `yaml:"publishSpec"`
+	// APIClients separates the credentials and rate limits of read and write calls to the load balancer API.
+	APIClients LoadBalancerAPIClients `yaml:"apiClients"`
+}
+
+// LoadBalancerAPIClients configures separate clients for reading and writing load balancers, so that heavy read
+// traffic, e.g. of status checks, can't starve writes, and writes can be made with a dedicated service account.
+// If neither is configured, all calls share one client.
+type LoadBalancerAPIClients struct {
+	// Read is used for getting and listing load balancers and credentials and the quota.
+	Read APIClientOpts `yaml:"read"`
+	// Write is used for all other calls.
+	Write APIClientOpts `yaml:"write"`
+}
+
+// APIClientOpts configures the credentials and the rate limit of an API client.
+type APIClientOpts struct {
+	// ServiceAccountKeyPath is the path to the key of the service account of the client.
+	// Defaults to the credentials of the environment.
+	ServiceAccountKeyPath string `yaml:"serviceAccountKeyPath"`
+	// RateLimit limits the calls of the client.
+	RateLimit RateLimitOpts `yaml:"rateLimit"`
+}
+
+// RateLimitOpts configures a token bucket rate limiter.
+type RateLimitOpts struct {
+	// QPS is the number of calls per second, unlimited if 0.
+	QPS float64 `yaml:"qps"`
+	// Burst is the number of calls that can be made at once. Defaults to 1.
+	Burst int `yaml:"burst"`
+}
+
+// PlanRecommendationOpts configures the plan recommendations of load balancers.
+// The plan is never changed automatically.
+type PlanRecommendationOpts struct {
+	Enabled bool `yaml:"enabled"`
+	// PrometheusURL is the base URL of a Prometheus compatible query API that stores the metrics of the load balancers,
+	// e.g. the Observability instance the load balancers push their metrics to.
+	PrometheusURL string `yaml:"prometheusUrl"`
+	// ConnectionsQuery is a PromQL query that returns the peak number of concurrent connections of a load balancer.
+	// The placeholder {{name}} is replaced by the name of the load balancer.
+	ConnectionsQuery string `yaml:"connectionsQuery"`
+	// Interval is the minimum time between two recommendations for the same load balancer. Defaults to 1h.
+	Interval metadata.Duration `yaml:"interval"`
 }
 
 type CSIConfig struct {
@@ -42,4 +166,89 @@ type CSIConfig struct {
 
 type BlockStorageOpts struct {
 	RescanOnResize bool `yaml:"rescanOnResize"`
+	// Discard mounts filesystems with online discard unless the volume's mount options contain nodiscard.
+	Discard bool `yaml:"discard"`
+	// FstrimInterval enables running fstrim on all staged filesystems periodically, e.g. "24h".
+	// This is an alternative to Discard that avoids the overhead of discarding blocks on every delete.
+	FstrimInterval metadata.Duration `yaml:"fstrimInterval"`
+	// NamespaceQuotas limits the total capacity in GiB of the volumes provisioned for PVCs in a namespace.
+	// Requires the csi-provisioner to run with --extra-create-metadata.
+	NamespaceQuotas map[string]int64 `yaml:"namespaceQuotas"`
+	// DefaultVolumeSize is the size in GiB of volumes whose PVC requests no capacity, 1 if 0.
+	DefaultVolumeSize int64 `yaml:"defaultVolumeSize"`
+	// MaxVolumeSize is the maximum size in GiB of volumes that can be created or expanded, unlimited if 0.
+	MaxVolumeSize int64 `yaml:"maxVolumeSize"`
+	// RemountReadOnly remounts filesystems read-write that the kernel remounted read-only after I/O errors.
+	// Without it, such volumes are only reported with an abnormal volume condition.
+	RemountReadOnly bool `yaml:"remountReadOnly"`
+	// FsckOnStage checks existing filesystems with fsck before they are staged and fails staging if they are
+	// corrupted.
+	FsckOnStage bool `yaml:"fsckOnStage"`
+	// ReclaimGracePeriod defers the deletion of volumes, e.g. "72h". DeleteVolume only labels the volume as pending
+	// deletion and the controller deletes it after the grace period, so that the data of accidentally deleted PVCs can
+	// be recovered until then.
+	ReclaimGracePeriod metadata.Duration `yaml:"reclaimGracePeriod"`
+	// MaxConcurrentSnapshots limits the snapshots and backups that are created concurrently, unlimited if 0.
+	MaxConcurrentSnapshots int `yaml:"maxConcurrentSnapshots"`
+	// MaxQueuedSnapshots is the number of snapshots that wait for the concurrency limit before CreateSnapshot fails
+	// with Aborted, 50 if 0.
+	MaxQueuedSnapshots int `yaml:"maxQueuedSnapshots"`
+	// WaitBackoffs configures how often the controller checks whether volumes and snapshots reached the expected
+	// status after an operation.
+	WaitBackoffs WaitBackoffs `yaml:"waitBackoffs"`
+	// DisabledCapabilities are not advertised by the controller service and the calls that need them fail, e.g. to
+	// disable snapshots in projects without backup quota.
+	DisabledCapabilities []ControllerCapability `yaml:"disabledCapabilities"`
+	// AllowedPerformanceClasses restricts the performance classes of new volumes in the project, all classes are
+	// allowed if empty.
+	AllowedPerformanceClasses []string `yaml:"allowedPerformanceClasses"`
+	// AllowedPerformanceClassesByZone replaces AllowedPerformanceClasses for new volumes in an availability zone.
+	AllowedPerformanceClassesByZone map[string][]string `yaml:"allowedPerformanceClassesByZone"`
+}
+
+// ControllerCapability is a feature of the CSI controller service that can be disabled.
+type ControllerCapability string
+
+const (
+	// ControllerCapabilitySnapshots covers creating and listing snapshots, backups and volume group snapshots.
+	ControllerCapabilitySnapshots ControllerCapability = "snapshots"
+	// ControllerCapabilityExpansion covers expanding volumes.
+	ControllerCapabilityExpansion ControllerCapability = "expansion"
+	// ControllerCapabilityCloning covers creating volumes from other volumes.
+	ControllerCapabilityCloning ControllerCapability = "cloning"
+	// ControllerCapabilityModification covers modifying volumes with a VolumeAttributesClass.
+	ControllerCapabilityModification ControllerCapability = "modification"
+)
+
+// ControllerCapabilities are the valid values of BlockStorageOpts.DisabledCapabilities.
+var ControllerCapabilities = []ControllerCapability{
+	ControllerCapabilitySnapshots,
+	ControllerCapabilityExpansion,
+	ControllerCapabilityCloning,
+	ControllerCapabilityModification,
+}
+
+// WaitBackoffs configures the backoffs of the CSI controller's waiters per operation.
+type WaitBackoffs struct {
+	// Create waits for new volumes to become available.
+	Create WaitBackoff `yaml:"create"`
+	// Attach waits for volumes to be attached to a server.
+	Attach WaitBackoff `yaml:"attach"`
+	// Detach waits for volumes to be detached from a server.
+	Detach WaitBackoff `yaml:"detach"`
+	// Expand waits for resized volumes to become available again.
+	Expand WaitBackoff `yaml:"expand"`
+	// Snapshot waits for snapshots to become ready.
+	Snapshot WaitBackoff `yaml:"snapshot"`
+}
+
+// WaitBackoff is an exponential backoff. Unset fields keep the default of the operation.
+type WaitBackoff struct {
+	// InitialDelay is the delay before the second check.
+	InitialDelay metadata.Duration `yaml:"initialDelay"`
+	// Factor multiplies the delay after every check.
+	Factor float64 `yaml:"factor"`
+	// Steps is the number of checks before the operation fails. Waiting is also limited by apiTimeouts.wait, which
+	// is the only limit for attach.
+	Steps int `yaml:"steps"`
 }
@@ -0,0 +1,55 @@
+package metadata
+
+import (
+	"context"
+
+	"k8s.io/klog/v2"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/labels"
+)
+
+// Static is the metadata of the current host, passed explicitly e.g. by flags.
+// It allows running the node service on hosts without metadata service or config drive.
+type Static struct {
+	InstanceID       string
+	AvailabilityZone string
+	Flavor           string
+}
+
+type fallbackMetadata struct {
+	provider IMetadata
+	static   Static
+}
+
+// WithFallback returns an IMetadata that queries provider first and returns the non-empty values of static
+// if provider fails. Without static values, it returns provider unchanged.
+func WithFallback(provider IMetadata, static Static) IMetadata {
+	if static == (Static{}) {
+		return provider
+	}
+	return &fallbackMetadata{provider: provider, static: static}
+}
+
+func (f *fallbackMetadata) GetInstanceID(ctx context.Context) (string, error) {
+	return fallback(ctx, "instance ID", f.provider.GetInstanceID, f.static.InstanceID)
+}
+
+func (f *fallbackMetadata) GetAvailabilityZone(ctx context.Context) (string, error) {
+	return fallback(ctx, "availability zone", f.provider.GetAvailabilityZone, labels.Sanitize(f.static.AvailabilityZone))
+}
+
+func (f *fallbackMetadata) GetFlavor(ctx context.Context) (string, error) {
+	return fallback(ctx, "flavor", f.provider.GetFlavor, f.static.Flavor)
+}
+
+func fallback(ctx context.Context, name string, get func(context.Context) (string, error), static string) (string, error) {
+	value, err := get(ctx)
+	if err == nil && value != "" {
+		return value, nil
+	}
+	if static == "" {
+		return value, err
+	}
+	klog.V(4).InfoS("Metadata not available, using the configured value", "key", name, "value", static, "err", err)
+	return static, nil
+}
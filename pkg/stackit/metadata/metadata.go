@@ -182,7 +182,7 @@ func getFromConfigDrive(metadataVersion string) (*Metadata, error) {
 	mntdir := os.TempDir()
 	defer os.Remove(mntdir)
 
-	klog.V(4).Infof("Attempting to mount configdrive %s on %s", dev, mntdir)
+	klog.V(4).InfoS("Attempting to mount configdrive", "device", dev, "path", mntdir)
 
 	mounter := mount.GetMountProvider().Mounter()
 	err := mounter.Mount(dev, mntdir, "iso9660", []string{"ro"})
@@ -194,7 +194,7 @@ func getFromConfigDrive(metadataVersion string) (*Metadata, error) {
 	}
 	defer func() { _ = mounter.Unmount(mntdir) }()
 
-	klog.V(4).Infof("Configdrive mounted on %s", mntdir)
+	klog.V(4).InfoS("Configdrive mounted", "path", mntdir)
 
 	configDrivePath := getConfigDrivePath(metadataVersion)
 	f, err := os.Open(
@@ -216,7 +216,7 @@ func noProxyHTTPClient() *http.Client {
 // TODO: Try to fetch InstanceType from config drive as well as backup?
 func getInstanceTypeFromMetadataURL(ctx context.Context, metadataVersion string) (string, error) {
 	url := fmt.Sprintf(InstanceTypeURLTemplate, metadataVersion)
-	klog.V(4).Infof("Attempting to fetch instance-type from %s, ignoring proxy settings", url)
+	klog.V(4).InfoS("Attempting to fetch instance-type, ignoring proxy settings", "url", url)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
 	if err != nil {
 		return "", fmt.Errorf("error creating request to %s: %v", url, err)
@@ -241,7 +241,7 @@ func getInstanceTypeFromMetadataURL(ctx context.Context, metadataVersion string)
 func getFromMetadataService(ctx context.Context, metadataVersion string) (*Metadata, error) {
 	// Try to get JSON from metadata server.
 	metadataURL := getMetadataURL(metadataVersion)
-	klog.V(4).Infof("Attempting to fetch metadata from %s, ignoring proxy settings", metadataURL)
+	klog.V(4).InfoS("Attempting to fetch metadata, ignoring proxy settings", "url", metadataURL)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataURL, http.NoBody)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request to %s: %v", metadataURL, err)
@@ -270,7 +270,7 @@ func GetDevicePath(ctx context.Context, volumeID string) (string, error) {
 	// relying on the metadata service.
 	instanceMetadata, err := getFromMetadataService(ctx, defaultMetadataVersion)
 	if err != nil {
-		klog.Errorf("Could not retrieve instance metadata: %v", err)
+		klog.ErrorS(err, "Could not retrieve instance metadata")
 		return "", fmt.Errorf("could not retrieve instance metadata: %v", err)
 	}
 
@@ -279,22 +279,19 @@ func GetDevicePath(ctx context.Context, volumeID string) (string, error) {
 			continue
 		}
 
-		klog.V(4).Infof(
-			"Found disk metadata for volumeID %q. Bus: %q, Address: %q",
-			volumeID, device.Bus, device.Address)
+		klog.V(4).InfoS("Found disk metadata", "volumeID", volumeID, "bus", device.Bus, "address", device.Address)
 
 		diskPattern := fmt.Sprintf("/dev/disk/by-path/*-%s-%s", device.Bus, device.Address)
 		diskPaths, err := filepath.Glob(diskPattern)
 		if err != nil {
 			newError := fmt.Errorf("could not retrieve disk path for volumeID: %q. Error filepath.Glob(%q): %w",
 				volumeID, diskPattern, err)
-			klog.Error(newError)
+			klog.ErrorS(err, "Could not retrieve disk path", "volumeID", volumeID, "pattern", diskPattern)
 			return "", newError
 		}
 
 		if len(diskPaths) != 1 || diskPaths[0] == "" {
-			klog.Warningf("Unexpected disk path result for volumeID %q: found %d paths: %v",
-				volumeID, len(diskPaths), diskPaths)
+			klog.InfoS("Unexpected disk path result", "volumeID", volumeID, "paths", diskPaths)
 			return "", fmt.Errorf("unexpected disk path result for volumeID %q: found %d paths", volumeID, len(diskPaths))
 		}
 
@@ -302,7 +299,7 @@ func GetDevicePath(ctx context.Context, volumeID string) (string, error) {
 	}
 
 	err = fmt.Errorf("could not retrieve device metadata for volumeID: %q", volumeID)
-	klog.Error(err)
+	klog.ErrorS(err, "Could not retrieve device metadata", "volumeID", volumeID)
 	return "", err
 }
 
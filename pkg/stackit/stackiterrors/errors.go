@@ -4,16 +4,116 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	oapiError "github.com/stackitcloud/stackit-sdk-go/core/oapierror"
 	"github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api/wait"
 )
 
-const tooManyDiskDevicesMessageFragment = "maximum allowed number of disk devices"
+const (
+	tooManyDiskDevicesMessageFragment = "maximum allowed number of disk devices"
+	quotaMessageFragment              = "quota"
+)
 
 var ErrNotFound = errors.New("failed to find object")
 
+// The following errors classify API errors, see Classify. Use errors.Is to check for them.
+var (
+	// ErrQuotaExceeded means that the request would exceed a quota of the project.
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	// ErrValidation means that the API rejected the request because it is invalid.
+	ErrValidation = errors.New("validation failed")
+	// ErrConflict means that the request conflicts with the current state of the resource,
+	// e.g. because the resource was changed concurrently.
+	ErrConflict = errors.New("conflict")
+	// ErrUnavailable means that the API is temporarily unavailable, e.g. during a maintenance window.
+	ErrUnavailable = errors.New("service unavailable")
+)
+
+// classifiedError adds one of the classification errors to an API error without changing its message.
+type classifiedError struct {
+	err   error
+	class error
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() []error {
+	return []error{e.err, e.class}
+}
+
+// Classify returns err so that errors.Is reports whether it is an ErrQuotaExceeded, ErrValidation, ErrConflict or
+// ErrUnavailable.
+// Errors that don't fall into one of these classes are returned unchanged.
+func Classify(err error) error {
+	oAPIError, ok := genericOpenAPIError(err)
+	if !ok {
+		return err
+	}
+
+	var class error
+	switch {
+	case (oAPIError.StatusCode == http.StatusForbidden || oAPIError.StatusCode == http.StatusBadRequest) &&
+		strings.Contains(strings.ToLower(string(oAPIError.Body)), quotaMessageFragment):
+		class = ErrQuotaExceeded
+	case oAPIError.StatusCode == http.StatusBadRequest || oAPIError.StatusCode == http.StatusUnprocessableEntity:
+		class = ErrValidation
+	case oAPIError.StatusCode == http.StatusConflict || oAPIError.StatusCode == http.StatusPreconditionFailed:
+		class = ErrConflict
+	case oAPIError.StatusCode == http.StatusServiceUnavailable:
+		class = ErrUnavailable
+	default:
+		return err
+	}
+	return &classifiedError{err: err, class: class}
+}
+
+// retryAfterError adds the delay that the API asked for in the Retry-After header to an error.
+type retryAfterError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *retryAfterError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryAfterError) Unwrap() error {
+	return e.err
+}
+
+// WithRetryAfter adds the delay of a Retry-After header to an ErrUnavailable error, see RetryAfter.
+// Other errors and headers that are neither a number of seconds nor an HTTP date are returned unchanged.
+func WithRetryAfter(err error, header string, now time.Time) error {
+	if !errors.Is(err, ErrUnavailable) || header == "" {
+		return err
+	}
+
+	var retryAfter time.Duration
+	if seconds, parseErr := strconv.Atoi(header); parseErr == nil {
+		retryAfter = time.Duration(seconds) * time.Second
+	} else if date, parseErr := http.ParseTime(header); parseErr == nil {
+		retryAfter = date.Sub(now)
+	} else {
+		return err
+	}
+	return &retryAfterError{err: err, retryAfter: max(retryAfter, time.Second)}
+}
+
+// RetryAfter returns the delay after which the API asked to retry a request that failed because it is unavailable.
+// It returns false if the API didn't send a Retry-After header.
+func RetryAfter(err error) (time.Duration, bool) {
+	var retryAfterErr *retryAfterError
+	if !errors.As(err, &retryAfterErr) {
+		return 0, false
+	}
+	return retryAfterErr.retryAfter, true
+}
+
 func IsNotFound(err error) bool {
 	oAPIError, ok := genericOpenAPIError(err)
 	if !ok {
@@ -23,6 +123,16 @@ func IsNotFound(err error) bool {
 	return oAPIError.StatusCode == http.StatusNotFound
 }
 
+// IsUnauthorized returns true if the API rejected the credentials or they lack the permissions for the request.
+func IsUnauthorized(err error) bool {
+	oAPIError, ok := genericOpenAPIError(err)
+	if !ok {
+		return false
+	}
+
+	return oAPIError.StatusCode == http.StatusUnauthorized || oAPIError.StatusCode == http.StatusForbidden
+}
+
 func IsTooManyDevicesError(err error) bool {
 	oAPIError, ok := genericOpenAPIError(err)
 	if !ok {
@@ -33,6 +143,20 @@ func IsTooManyDevicesError(err error) bool {
 		strings.Contains(string(oAPIError.Body), tooManyDiskDevicesMessageFragment)
 }
 
+// IsConflict returns true if the request conflicts with the current state of the resource, e.g. because the resource
+// was changed concurrently.
+func IsConflict(err error) bool {
+	if errors.Is(err, ErrConflict) {
+		return true
+	}
+	oAPIError, ok := genericOpenAPIError(err)
+	if !ok {
+		return false
+	}
+
+	return oAPIError.StatusCode == http.StatusConflict
+}
+
 func IgnoreNotFound(err error) error {
 	if IsNotFound(err) {
 		return nil
@@ -61,6 +185,20 @@ func IsInvalidError(err error) bool {
 	return oAPIError.StatusCode == http.StatusBadRequest
 }
 
+// IsAmbiguousError returns true if the error leaves it open whether the request was processed by the API,
+// e.g. because the connection broke or timed out, or the API failed with a server error.
+func IsAmbiguousError(err error) bool {
+	if err == nil {
+		return false
+	}
+	oAPIError, ok := genericOpenAPIError(err)
+	if !ok {
+		return true
+	}
+
+	return oAPIError.StatusCode >= http.StatusInternalServerError
+}
+
 func genericOpenAPIError(err error) (*oapiError.GenericOpenAPIError, bool) {
 	var oAPIError *oapiError.GenericOpenAPIError
 	if ok := errors.As(err, &oAPIError); !ok {
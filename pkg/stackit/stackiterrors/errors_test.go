@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -42,6 +43,32 @@ var _ = Describe("Errors", func() {
 		})
 	})
 
+	Describe("IsUnauthorized", func() {
+		It("should return true for unauthorized and forbidden errors", func() {
+			Expect(IsUnauthorized(&oapiError.GenericOpenAPIError{StatusCode: http.StatusUnauthorized})).To(BeTrue())
+			Expect(IsUnauthorized(&oapiError.GenericOpenAPIError{StatusCode: http.StatusForbidden})).To(BeTrue())
+		})
+
+		It("should return false for other errors", func() {
+			Expect(IsUnauthorized(&oapiError.GenericOpenAPIError{StatusCode: http.StatusNotFound})).To(BeFalse())
+			Expect(IsUnauthorized(errors.New("some error"))).To(BeFalse())
+			Expect(IsUnauthorized(nil)).To(BeFalse())
+		})
+	})
+
+	Describe("IsConflict", func() {
+		It("should return true for conflicts", func() {
+			Expect(IsConflict(&oapiError.GenericOpenAPIError{StatusCode: http.StatusConflict})).To(BeTrue())
+			Expect(IsConflict(fmt.Errorf("wrapped: %w", ErrConflict))).To(BeTrue())
+		})
+
+		It("should return false for other errors", func() {
+			Expect(IsConflict(&oapiError.GenericOpenAPIError{StatusCode: http.StatusNotFound})).To(BeFalse())
+			Expect(IsConflict(errors.New("some error"))).To(BeFalse())
+			Expect(IsConflict(nil)).To(BeFalse())
+		})
+	})
+
 	Describe("IgnoreNotFound", func() {
 		Context("when error is a NotFound error", func() {
 			It("should return nil", func() {
@@ -123,4 +150,110 @@ var _ = Describe("Errors", func() {
 			})
 		})
 	})
+
+	Describe("IsAmbiguousError", func() {
+		Context("when error is a server error", func() {
+			It("should return true", func() {
+				err := &oapiError.GenericOpenAPIError{StatusCode: http.StatusGatewayTimeout}
+				Expect(IsAmbiguousError(err)).To(BeTrue())
+			})
+		})
+
+		Context("when error is a client error", func() {
+			It("should return false", func() {
+				err := &oapiError.GenericOpenAPIError{StatusCode: http.StatusConflict}
+				Expect(IsAmbiguousError(err)).To(BeFalse())
+			})
+		})
+
+		Context("when error is not an OAPI error", func() {
+			It("should return true", func() {
+				err := errors.New("connection reset by peer")
+				Expect(IsAmbiguousError(err)).To(BeTrue())
+			})
+		})
+
+		Context("when error is nil", func() {
+			It("should return false", func() {
+				Expect(IsAmbiguousError(nil)).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("Classify", func() {
+		DescribeTable("should classify API errors",
+			func(statusCode int, body string, expected error) {
+				err := Classify(&oapiError.GenericOpenAPIError{StatusCode: statusCode, Body: []byte(body)})
+				for _, class := range []error{ErrQuotaExceeded, ErrValidation, ErrConflict, ErrUnavailable} {
+					Expect(errors.Is(err, class)).To(Equal(class == expected), "class %q", class)
+				}
+			},
+			Entry("quota exceeded", http.StatusForbidden, `{"message":"Project quota exceeded"}`, ErrQuotaExceeded),
+			Entry("quota exceeded as bad request", http.StatusBadRequest, `{"message":"quota for load balancers reached"}`, ErrQuotaExceeded),
+			Entry("validation", http.StatusBadRequest, `{"message":"invalid port"}`, ErrValidation),
+			Entry("unprocessable entity", http.StatusUnprocessableEntity, "", ErrValidation),
+			Entry("conflict", http.StatusConflict, "", ErrConflict),
+			Entry("service unavailable", http.StatusServiceUnavailable, "", ErrUnavailable),
+			Entry("forbidden", http.StatusForbidden, `{"message":"access denied"}`, nil),
+			Entry("server error", http.StatusInternalServerError, "", nil),
+		)
+
+		It("should keep the message and the underlying API error", func() {
+			apiErr := &oapiError.GenericOpenAPIError{StatusCode: http.StatusConflict, ErrorMessage: "409 Conflict"}
+			err := Classify(fmt.Errorf("update: %w", apiErr))
+			Expect(err.Error()).To(Equal("update: " + apiErr.Error()))
+			var target *oapiError.GenericOpenAPIError
+			Expect(errors.As(err, &target)).To(BeTrue())
+			Expect(target).To(BeIdenticalTo(apiErr))
+		})
+
+		It("should return other errors unchanged", func() {
+			err := errors.New("connection refused")
+			Expect(Classify(err)).To(BeIdenticalTo(err))
+			Expect(Classify(nil)).To(Succeed())
+		})
+	})
+
+	Describe("WithRetryAfter", func() {
+		var (
+			now         time.Time
+			unavailable error
+		)
+
+		BeforeEach(func() {
+			now = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+			unavailable = Classify(&oapiError.GenericOpenAPIError{StatusCode: http.StatusServiceUnavailable})
+		})
+
+		It("should parse a number of seconds", func() {
+			err := WithRetryAfter(unavailable, "120", now)
+			Expect(err).To(MatchError(ErrUnavailable))
+			Expect(err.Error()).To(Equal(unavailable.Error()))
+			retryAfter, ok := RetryAfter(fmt.Errorf("get: %w", err))
+			Expect(ok).To(BeTrue())
+			Expect(retryAfter).To(Equal(2 * time.Minute))
+		})
+
+		It("should parse an HTTP date", func() {
+			retryAfter, _ := RetryAfter(WithRetryAfter(unavailable, now.Add(time.Hour).Format(http.TimeFormat), now))
+			Expect(retryAfter).To(Equal(time.Hour))
+		})
+
+		It("should retry after at least a second", func() {
+			retryAfter, _ := RetryAfter(WithRetryAfter(unavailable, now.Add(-time.Hour).Format(http.TimeFormat), now))
+			Expect(retryAfter).To(Equal(time.Second))
+		})
+
+		It("should ignore invalid and missing headers", func() {
+			Expect(WithRetryAfter(unavailable, "soon", now)).To(BeIdenticalTo(unavailable))
+			Expect(WithRetryAfter(unavailable, "", now)).To(BeIdenticalTo(unavailable))
+			_, ok := RetryAfter(unavailable)
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should ignore other errors", func() {
+			err := Classify(&oapiError.GenericOpenAPIError{StatusCode: http.StatusTooManyRequests})
+			Expect(WithRetryAfter(err, "120", now)).To(BeIdenticalTo(err))
+		})
+	})
 })
@@ -0,0 +1,196 @@
+// Package validation checks a cloud-config against the STACKIT APIs before it is rolled out,
+// e.g. in the CI pipeline that bootstraps a cluster.
+package validation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/stackiterrors"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/metrics"
+)
+
+// Result is the outcome of a single check.
+type Result struct {
+	Name string
+	Err  error
+	// Skipped is set if the check did not run because an earlier check failed.
+	Skipped bool
+}
+
+// Report collects the results of all checks in the order they ran.
+// The checks are ordered from basic to specific, so once a check failed the remaining checks are skipped.
+type Report struct {
+	Results []Result
+}
+
+// Check runs check unless an earlier check failed and records its result.
+// It returns whether the check passed.
+func (r *Report) Check(name string, check func() error) bool {
+	if r.Failed() {
+		r.Results = append(r.Results, Result{Name: name, Skipped: true})
+		return false
+	}
+	err := check()
+	r.Results = append(r.Results, Result{Name: name, Err: err})
+	return err == nil
+}
+
+// Failed returns whether any check failed.
+func (r *Report) Failed() bool {
+	for _, result := range r.Results {
+		if result.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Print writes a line per check and a summary to w.
+func (r *Report) Print(w io.Writer) {
+	failed := 0
+	for _, result := range r.Results {
+		switch {
+		case result.Skipped:
+			fmt.Fprintf(w, "[SKIP] %s\n", result.Name)
+		case result.Err != nil:
+			failed++
+			fmt.Fprintf(w, "[FAIL] %s: %v\n", result.Name, result.Err)
+		default:
+			fmt.Fprintf(w, "[PASS] %s\n", result.Name)
+		}
+	}
+	if failed > 0 {
+		fmt.Fprintf(w, "\ncloud-config is invalid: %d of %d checks failed\n", failed, len(r.Results))
+		return
+	}
+	fmt.Fprintf(w, "\ncloud-config is valid: all %d checks passed\n", len(r.Results))
+}
+
+// GlobalOpts validates the options that both binaries require.
+func GlobalOpts(opts stackitconfig.GlobalOpts) error {
+	if opts.ProjectID == "" {
+		return errors.New("projectId must be set")
+	}
+	if opts.Region == "" {
+		return errors.New("region must be set")
+	}
+	return nil
+}
+
+// BlockStorageOpts validates the options of the CSI driver that don't require the API.
+func BlockStorageOpts(opts stackitconfig.BlockStorageOpts) error {
+	for _, c := range opts.DisabledCapabilities {
+		if !slices.Contains(stackitconfig.ControllerCapabilities, c) {
+			return fmt.Errorf("invalid capability %q in disabledCapabilities, must be one of %v", c, stackitconfig.ControllerCapabilities)
+		}
+	}
+	for zone, classes := range opts.AllowedPerformanceClassesByZone {
+		if len(classes) == 0 {
+			return fmt.Errorf("allowedPerformanceClassesByZone of zone %q must not be empty", zone)
+		}
+	}
+	if opts.DefaultVolumeSize < 0 || opts.MaxVolumeSize < 0 {
+		return fmt.Errorf("defaultVolumeSize and maxVolumeSize must not be negative")
+	}
+	if opts.MaxVolumeSize > 0 && opts.DefaultVolumeSize > opts.MaxVolumeSize {
+		return fmt.Errorf("defaultVolumeSize %d GiB must not exceed maxVolumeSize %d GiB", opts.DefaultVolumeSize, opts.MaxVolumeSize)
+	}
+	return nil
+}
+
+// CheckRead runs a read-only API call as a check and explains the common errors.
+func CheckRead(r *Report, name, resource string, read func() error) bool {
+	return r.Check(name, func() error {
+		return explain(read(), resource)
+	})
+}
+
+// CheckProject checks the credentials and that the configured project exists by reading the project.
+func CheckProject(ctx context.Context, r *Report, client stackitclient.IaaSClient, projectID string) bool {
+	return CheckRead(r, fmt.Sprintf("credentials can read project %q", projectID), "project", func() error {
+		_, err := client.GetProject(ctx)
+		return err
+	})
+}
+
+// CheckNetwork checks that the configured network exists in the project.
+func CheckNetwork(ctx context.Context, r *Report, client stackitclient.IaaSClient, networkID string) bool {
+	return CheckRead(r, fmt.Sprintf("network %q exists", networkID), "network", func() error {
+		_, err := client.GetNetwork(ctx, networkID)
+		return err
+	})
+}
+
+// explain adds a hint about the likely cause to common API errors.
+func explain(err error, resource string) error {
+	switch {
+	case err == nil:
+		return nil
+	case stackiterrors.IsNotFound(err):
+		return fmt.Errorf("%s not found: %w", resource, err)
+	case stackiterrors.IsUnauthorized(err):
+		return fmt.Errorf("credentials are invalid or lack permissions: %w", err)
+	default:
+		return err
+	}
+}
+
+// CSI validates the cloud-config of the CSI driver at path.
+func CSI(ctx context.Context, path string) *Report {
+	r := &Report{}
+
+	var cfg stackitconfig.CSIConfig
+	r.Check("cloud-config can be parsed", func() (err error) {
+		cfg, err = stackitclient.GetConfigFromFile(path)
+		return err
+	})
+	r.Check("global options are set", func() error {
+		return GlobalOpts(cfg.Global)
+	})
+	r.Check("blockStorage options are valid", func() error {
+		return BlockStorageOpts(cfg.BlockStorage)
+	})
+
+	var client stackitclient.IaaSClient
+	r.Check("IaaS API client can be created", func() (err error) {
+		client, err = newIaaSClient(cfg.Global)
+		return err
+	})
+	CheckProject(ctx, r, client, cfg.Global.ProjectID)
+	CheckRead(r, "credentials can list volumes", "volumes", func() error {
+		_, _, err := client.ListVolumes(ctx, 0, "")
+		return err
+	})
+	for _, class := range performanceClasses(cfg.BlockStorage) {
+		CheckRead(r, fmt.Sprintf("performance class %q exists", class), "performance class", func() error {
+			_, err := client.GetVolumePerformanceClass(ctx, class)
+			return err
+		})
+	}
+	return r
+}
+
+// performanceClasses returns all allowed performance classes of the project and its zones.
+func performanceClasses(opts stackitconfig.BlockStorageOpts) []string {
+	classes := slices.Clone(opts.AllowedPerformanceClasses)
+	for _, zoneClasses := range opts.AllowedPerformanceClassesByZone {
+		classes = append(classes, zoneClasses...)
+	}
+	slices.Sort(classes)
+	return slices.Compact(classes)
+}
+
+func newIaaSClient(opts stackitconfig.GlobalOpts) (stackitclient.IaaSClient, error) {
+	iaasOpts, err := stackitclient.ConfigurationOptions(metrics.APINameIaaS, opts.APIEndpoints.IaasAPI, opts.APIEndpoints)
+	if err != nil {
+		return nil, err
+	}
+	return stackitclient.New(opts.Region, opts.ProjectID, opts.APITimeouts).IaaS(iaasOpts)
+}
@@ -0,0 +1,118 @@
+package validation_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	stackitclientmock "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client/mock"
+	oapiError "github.com/stackitcloud/stackit-sdk-go/core/oapierror"
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	"go.uber.org/mock/gomock"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/validation"
+)
+
+var _ = Describe("Report", func() {
+	It("should skip the checks after a failed check", func() {
+		r := &validation.Report{}
+		Expect(r.Check("first", func() error { return nil })).To(BeTrue())
+		Expect(r.Check("second", func() error { return errors.New("injected error") })).To(BeFalse())
+		Expect(r.Check("third", func() error {
+			Fail("check must not run")
+			return nil
+		})).To(BeFalse())
+
+		Expect(r.Failed()).To(BeTrue())
+		Expect(r.Results).To(Equal([]validation.Result{
+			{Name: "first"},
+			{Name: "second", Err: errors.New("injected error")},
+			{Name: "third", Skipped: true},
+		}))
+
+		var out bytes.Buffer
+		r.Print(&out)
+		Expect(out.String()).To(Equal(`[PASS] first
+[FAIL] second: injected error
+[SKIP] third
+
+cloud-config is invalid: 1 of 3 checks failed
+`))
+	})
+
+	It("should print a summary if all checks passed", func() {
+		r := &validation.Report{}
+		r.Check("first", func() error { return nil })
+		Expect(r.Failed()).To(BeFalse())
+
+		var out bytes.Buffer
+		r.Print(&out)
+		Expect(out.String()).To(HaveSuffix("cloud-config is valid: all 1 checks passed\n"))
+	})
+})
+
+var _ = Describe("API checks", func() {
+	var (
+		ctx    context.Context
+		client *stackitclientmock.MockIaaSClient
+		r      *validation.Report
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		client = stackitclientmock.NewMockIaaSClient(gomock.NewController(GinkgoT()))
+		r = &validation.Report{}
+	})
+
+	It("should pass if the project can be read", func() {
+		client.EXPECT().GetProject(ctx).Return(&iaas.Project{}, nil)
+		Expect(validation.CheckProject(ctx, r, client, "my-project")).To(BeTrue())
+		Expect(r.Results).To(ConsistOf(validation.Result{Name: `credentials can read project "my-project"`}))
+	})
+
+	It("should explain rejected credentials", func() {
+		client.EXPECT().GetProject(ctx).Return(nil, &oapiError.GenericOpenAPIError{StatusCode: http.StatusUnauthorized})
+		Expect(validation.CheckProject(ctx, r, client, "my-project")).To(BeFalse())
+		Expect(r.Results[0].Err).To(MatchError(ContainSubstring("credentials are invalid or lack permissions")))
+	})
+
+	It("should explain a missing network", func() {
+		client.EXPECT().GetNetwork(ctx, "my-network").Return(nil, &oapiError.GenericOpenAPIError{StatusCode: http.StatusNotFound})
+		Expect(validation.CheckNetwork(ctx, r, client, "my-network")).To(BeFalse())
+		Expect(r.Results[0].Err).To(MatchError(ContainSubstring("network not found")))
+	})
+})
+
+var _ = Describe("CSI", func() {
+	writeConfig := func(content string) string {
+		path := filepath.Join(GinkgoT().TempDir(), "cloud-config.yaml")
+		Expect(os.WriteFile(path, []byte(content), 0o600)).To(Succeed())
+		return path
+	}
+
+	It("should fail if the cloud-config can't be read", func() {
+		r := validation.CSI(context.Background(), filepath.Join(GinkgoT().TempDir(), "missing.yaml"))
+		Expect(r.Failed()).To(BeTrue())
+		Expect(r.Results[0].Err).To(HaveOccurred())
+		Expect(r.Results[1:]).To(HaveEach(HaveField("Skipped", BeTrue())))
+	})
+
+	It("should fail if required options are missing", func() {
+		r := validation.CSI(context.Background(), writeConfig("global:\n  projectId: my-project\n"))
+		Expect(r.Results[0].Err).NotTo(HaveOccurred())
+		Expect(r.Results[1].Err).To(MatchError("region must be set"))
+		Expect(r.Results[2:]).To(HaveEach(HaveField("Skipped", BeTrue())))
+	})
+
+	It("should fail if a disabled capability is unknown", func() {
+		r := validation.CSI(context.Background(), writeConfig(
+			"global:\n  projectId: my-project\n  region: eu01\nblockStorage:\n  disabledCapabilities: [snapshots, cloning, backups]\n"))
+		Expect(r.Results[1].Err).NotTo(HaveOccurred())
+		Expect(r.Results[2].Err).To(MatchError(ContainSubstring(`invalid capability "backups"`)))
+	})
+})
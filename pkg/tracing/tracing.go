@@ -0,0 +1,126 @@
+// Package tracing exports OpenTelemetry traces of the reconciliations of the CCM, the CSI calls and the calls to the
+// STACKIT APIs via OTLP. Tracing is disabled unless an OTLP endpoint is configured.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/pflag"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/klog/v2"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/version"
+)
+
+const (
+	tracerName = "github.com/stackitcloud/cloud-provider-stackit"
+
+	// The standard environment variables of the OTLP exporter, which enable tracing as well.
+	envEndpoint       = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	envTracesEndpoint = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+
+	defaultSamplingRatio = 0.1
+)
+
+// Attribute keys of the spans.
+const (
+	AttributeProjectID        = "stackit.project_id"
+	AttributeServiceNamespace = "k8s.service.namespace"
+	AttributeServiceName      = "k8s.service.name"
+	AttributeServiceUID       = "k8s.service.uid"
+	AttributeLoadBalancer     = "stackit.load_balancer.name"
+	AttributeVolumeID         = "stackit.volume.id"
+	AttributeSnapshotID       = "stackit.snapshot.id"
+	AttributeNodeID           = "stackit.server.id"
+)
+
+// Options configures the export of traces.
+type Options struct {
+	// Endpoint is the URL of the OTLP gRPC receiver, e.g. http://otel-collector:4317.
+	Endpoint string
+	// SamplingRatio is the fraction of traces that are sampled if the caller didn't sample the trace already.
+	SamplingRatio float64
+}
+
+// AddFlags adds the tracing flags to fs.
+func (o *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.Endpoint, "tracing-endpoint", "",
+		"URL of the OTLP gRPC receiver traces are exported to, e.g. http://otel-collector:4317. "+
+			"Tracing is disabled if empty, unless "+envEndpoint+" or "+envTracesEndpoint+" is set.")
+	fs.Float64Var(&o.SamplingRatio, "tracing-sampling-ratio", defaultSamplingRatio,
+		"Fraction of traces that are sampled, between 0 and 1. Traces sampled by the caller are always recorded.")
+}
+
+// Enabled returns whether traces are exported.
+func (o *Options) Enabled() bool {
+	return o.Endpoint != "" || os.Getenv(envEndpoint) != "" || os.Getenv(envTracesEndpoint) != ""
+}
+
+// Setup installs a global tracer provider that exports the traces of serviceName if tracing is enabled.
+// attrs are added to the resource of all spans, e.g. the project ID.
+// The returned function flushes the remaining spans and must be called before the process exits.
+// Further settings of the exporter, e.g. headers or TLS certificates, are read from the OTEL_EXPORTER_OTLP_*
+// environment variables.
+func Setup(ctx context.Context, o Options, serviceName string, attrs ...attribute.KeyValue) (func(context.Context) error, error) {
+	if !o.Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+	if o.SamplingRatio < 0 || o.SamplingRatio > 1 {
+		return nil, fmt.Errorf("tracing sampling ratio must be between 0 and 1, got %v", o.SamplingRatio)
+	}
+
+	var exporterOpts []otlptracegrpc.Option
+	if o.Endpoint != "" {
+		exporterOpts = append(exporterOpts, otlptracegrpc.WithEndpointURL(o.Endpoint))
+	}
+	exporter, err := otlptracegrpc.New(ctx, exporterOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(semconv.ServiceName(serviceName), semconv.ServiceVersion(version.Version)),
+		resource.WithAttributes(attrs...),
+	)
+	if err != nil && !errors.Is(err, resource.ErrPartialResource) {
+		return nil, fmt.Errorf("failed to create tracing resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(o.SamplingRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	klog.InfoS("Exporting traces", "endpoint", o.Endpoint, "samplingRatio", o.SamplingRatio)
+
+	return provider.Shutdown, nil
+}
+
+// Start starts a span with the global tracer provider. Without Setup, the span is not recorded.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, opts...)
+}
+
+// End records err on span, if any, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
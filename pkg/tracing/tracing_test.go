@@ -0,0 +1,64 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var _ = Describe("Tracing", func() {
+	Describe("Setup", func() {
+		BeforeEach(func() {
+			GinkgoT().Setenv(envEndpoint, "")
+			GinkgoT().Setenv(envTracesEndpoint, "")
+		})
+
+		It("should not export traces without endpoint", func() {
+			o := Options{SamplingRatio: defaultSamplingRatio}
+			Expect(o.Enabled()).To(BeFalse())
+			shutdown, err := Setup(context.Background(), o, "test")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(shutdown(context.Background())).To(Succeed())
+		})
+
+		It("should be enabled by the environment", func() {
+			GinkgoT().Setenv(envTracesEndpoint, "http://otel-collector:4317")
+			Expect((&Options{}).Enabled()).To(BeTrue())
+		})
+
+		It("should reject an invalid sampling ratio", func() {
+			_, err := Setup(context.Background(), Options{Endpoint: "http://otel-collector:4317", SamplingRatio: 2}, "test")
+			Expect(err).To(MatchError(ContainSubstring("sampling ratio")))
+		})
+	})
+
+	Describe("End", func() {
+		var recorder *tracetest.SpanRecorder
+
+		BeforeEach(func() {
+			recorder = tracetest.NewSpanRecorder()
+			previous := otel.GetTracerProvider()
+			otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+			DeferCleanup(func() { otel.SetTracerProvider(previous) })
+		})
+
+		It("should record errors", func() {
+			_, span := Start(context.Background(), "failing")
+			End(span, errors.New("boom"))
+			_, span = Start(context.Background(), "succeeding")
+			End(span, nil)
+
+			spans := recorder.Ended()
+			Expect(spans).To(HaveLen(2))
+			Expect(spans[0].Status().Code).To(Equal(codes.Error))
+			Expect(spans[0].Status().Description).To(Equal("boom"))
+			Expect(spans[1].Status().Code).To(Equal(codes.Unset))
+		})
+	})
+})
@@ -0,0 +1,209 @@
+package fakeapi_test
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	sharedcsi "github.com/stackitcloud/cloud-provider-stackit/pkg/csi"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/csi/blockstorage"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/metrics"
+	stackitclient "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/client"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/metadata"
+)
+
+const (
+	availabilityZone = "eu01-1"
+	topologyKey      = "topology.block-storage.csi.stackit.cloud/zone"
+)
+
+var _ = Describe("CSI controller", Ordered, func() {
+	var controller csi.ControllerClient
+
+	BeforeAll(func() {
+		controller = startController(stackitconfig.BlockStorageOpts{})
+	})
+
+	It("should provision, attach, detach and delete a volume", func(ctx SpecContext) {
+		serverID := server.AddServer("node-a", availabilityZone)
+
+		var created *csi.CreateVolumeResponse
+		Eventually(func(g Gomega) {
+			var err error
+			created, err = controller.CreateVolume(ctx, &csi.CreateVolumeRequest{
+				Name:               "pvc-6f1d2c3b",
+				CapacityRange:      &csi.CapacityRange{RequiredBytes: 10 << 30},
+				VolumeCapabilities: []*csi.VolumeCapability{mountCapability()},
+				AccessibilityRequirements: &csi.TopologyRequirement{
+					Preferred: []*csi.Topology{{Segments: map[string]string{topologyKey: availabilityZone}}},
+				},
+			})
+			g.Expect(err).NotTo(HaveOccurred())
+		}).Should(Succeed(), "the driver should serve requests once its socket exists")
+
+		volumeID := created.Volume.VolumeId
+		vol, found := server.Volume(volumeID)
+		Expect(found).To(BeTrue())
+		Expect(vol.GetName()).To(Equal("pvc-6f1d2c3b"))
+		Expect(vol.GetSize()).To(BeEquivalentTo(10))
+		Expect(vol.AvailabilityZone).To(Equal(availabilityZone))
+
+		By("creating the same volume again")
+		again, err := controller.CreateVolume(ctx, &csi.CreateVolumeRequest{
+			Name:               "pvc-6f1d2c3b",
+			CapacityRange:      &csi.CapacityRange{RequiredBytes: 10 << 30},
+			VolumeCapabilities: []*csi.VolumeCapability{mountCapability()},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(again.Volume.VolumeId).To(Equal(volumeID))
+		Expect(server.Volumes()).To(HaveLen(1))
+
+		By("attaching the volume")
+		_, err = controller.ControllerPublishVolume(ctx, &csi.ControllerPublishVolumeRequest{
+			VolumeId:         volumeID,
+			NodeId:           serverID,
+			VolumeCapability: mountCapability(),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		vol, _ = server.Volume(volumeID)
+		Expect(vol.GetServerId()).To(Equal(serverID))
+
+		By("refusing to delete an attached volume")
+		_, err = controller.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: volumeID})
+		Expect(err).To(HaveOccurred())
+
+		By("detaching the volume")
+		_, err = controller.ControllerUnpublishVolume(ctx, &csi.ControllerUnpublishVolumeRequest{
+			VolumeId: volumeID,
+			NodeId:   serverID,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		vol, _ = server.Volume(volumeID)
+		Expect(vol.ServerId).To(BeNil())
+
+		By("deleting the volume")
+		_, err = controller.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: volumeID})
+		Expect(err).NotTo(HaveOccurred())
+		_, found = server.Volume(volumeID)
+		Expect(found).To(BeFalse())
+	})
+
+	It("should fail to attach a volume to an unknown server", func(ctx SpecContext) {
+		created, err := controller.CreateVolume(ctx, &csi.CreateVolumeRequest{
+			Name:               "pvc-0a9e8d7c",
+			CapacityRange:      &csi.CapacityRange{RequiredBytes: 1 << 30},
+			VolumeCapabilities: []*csi.VolumeCapability{mountCapability()},
+			AccessibilityRequirements: &csi.TopologyRequirement{
+				Preferred: []*csi.Topology{{Segments: map[string]string{topologyKey: availabilityZone}}},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(func(ctx SpecContext) {
+			_, err := controller.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: created.Volume.VolumeId})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		_, err = controller.ControllerPublishVolume(ctx, &csi.ControllerPublishVolumeRequest{
+			VolumeId:         created.Volume.VolumeId,
+			NodeId:           "0e4b7a3f-9c1d-4f2e-8a6b-5d3c2b1a0f9e",
+			VolumeCapability: mountCapability(),
+		})
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+	})
+})
+
+var _ = Describe("CSI controller with a reclaim grace period", Ordered, func() {
+	var controller csi.ControllerClient
+
+	BeforeAll(func() {
+		controller = startController(stackitconfig.BlockStorageOpts{
+			ReclaimGracePeriod: metadata.Duration{Duration: 72 * time.Hour},
+		})
+	})
+
+	It("should keep the labels of volumes pending deletion and adopt them again", func(ctx SpecContext) {
+		parameters := map[string]string{
+			sharedcsi.PvcNamespaceKey: "default",
+			sharedcsi.PvcNameKey:      "data",
+			"adoptExisting":           "true",
+		}
+		var created *csi.CreateVolumeResponse
+		Eventually(func(g Gomega) {
+			var err error
+			created, err = controller.CreateVolume(ctx, &csi.CreateVolumeRequest{
+				Name:               "pvc-3c5e7a9b",
+				CapacityRange:      &csi.CapacityRange{RequiredBytes: 1 << 30},
+				VolumeCapabilities: []*csi.VolumeCapability{mountCapability()},
+				Parameters:         parameters,
+			})
+			g.Expect(err).NotTo(HaveOccurred())
+		}).Should(Succeed(), "the driver should serve requests once its socket exists")
+		volumeID := created.Volume.VolumeId
+		DeferCleanup(server.RemoveVolume, volumeID)
+
+		By("deferring the deletion of the volume")
+		_, err := controller.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: volumeID})
+		Expect(err).NotTo(HaveOccurred())
+		vol, found := server.Volume(volumeID)
+		Expect(found).To(BeTrue())
+		Expect(vol.Labels).To(HaveKey(stackitclient.PendingDeleteLabel))
+		Expect(vol.Labels).To(HaveKeyWithValue("pvc-namespace", "default"))
+		Expect(vol.Labels).To(HaveKeyWithValue("pvc-name", "data"))
+
+		By("adopting the volume for the recreated PVC")
+		adopted, err := controller.CreateVolume(ctx, &csi.CreateVolumeRequest{
+			Name:               "pvc-8d2f4b6a",
+			CapacityRange:      &csi.CapacityRange{RequiredBytes: 1 << 30},
+			VolumeCapabilities: []*csi.VolumeCapability{mountCapability()},
+			Parameters:         parameters,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(adopted.Volume.VolumeId).To(Equal(volumeID))
+		vol, _ = server.Volume(volumeID)
+		Expect(vol.GetName()).To(Equal("pvc-8d2f4b6a"))
+		Expect(vol.Labels).NotTo(HaveKey(stackitclient.PendingDeleteLabel))
+		Expect(vol.Labels).To(HaveKeyWithValue("pvc-namespace", "default"))
+		Expect(vol.Labels).To(HaveKeyWithValue("pvc-name", "data"))
+	})
+})
+
+// startController starts the controller service of a driver against the fake API and returns a client for it.
+// The gRPC server of the driver can't be stopped, so it is started once for all specs of a container.
+func startController(blockStorageOpts stackitconfig.BlockStorageOpts) csi.ControllerClient {
+	endpoints := stackitconfig.APIEndpoints{IaasAPI: server.URL}
+	opts, err := stackitclient.ConfigurationOptions(metrics.APINameIaaS, server.URL, endpoints)
+	Expect(err).NotTo(HaveOccurred())
+	iaasClient, err := stackitclient.New(region, projectID, stackitconfig.APITimeouts{}).IaaS(opts)
+	Expect(err).NotTo(HaveOccurred())
+
+	socket := filepath.Join(GinkgoT().TempDir(), "csi.sock")
+	driver := blockstorage.NewDriver(&blockstorage.DriverOpts{
+		ClusterID: "e2e",
+		Endpoint:  "unix://" + socket,
+		PVLister:  corelisters.NewPersistentVolumeLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+	})
+	driver.SetupControllerService(iaasClient, blockStorageOpts)
+	go driver.Run()
+
+	conn, err := grpc.NewClient("unix://"+socket, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	Expect(err).NotTo(HaveOccurred())
+	DeferCleanup(conn.Close)
+	return csi.NewControllerClient(conn)
+}
+
+func mountCapability() *csi.VolumeCapability {
+	return &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+		AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER},
+	}
+}
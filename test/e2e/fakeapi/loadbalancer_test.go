@@ -0,0 +1,201 @@
+package fakeapi_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	restclient "k8s.io/client-go/rest"
+	cloudprovider "k8s.io/cloud-provider"
+	"k8s.io/cloud-provider/api"
+
+	"github.com/stackitcloud/cloud-provider-stackit/pkg/ccm"
+	stackitconfig "github.com/stackitcloud/cloud-provider-stackit/pkg/stackit/config"
+)
+
+const clusterName = "e2e"
+
+var _ = Describe("Load balancer reconciliation", func() {
+	var (
+		lbProvider cloudprovider.LoadBalancer
+		service    *corev1.Service
+		nodes      []*corev1.Node
+		lbName     string
+	)
+
+	BeforeEach(func() {
+		cfg := stackitconfig.CCMConfig{
+			Global: stackitconfig.GlobalOpts{
+				ProjectID: projectID,
+				Region:    region,
+				APIEndpoints: stackitconfig.APIEndpoints{
+					IaasAPI:         server.URL,
+					LoadBalancerAPI: server.URL,
+				},
+			},
+			LoadBalancer: stackitconfig.LoadBalancerOpts{NetworkID: server.AddNetwork("my-network")},
+		}
+		cloud, err := ccm.NewCloudControllerManager(&cfg, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		var ok bool
+		lbProvider, ok = cloud.LoadBalancer()
+		Expect(ok).To(BeTrue())
+
+		service = &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "my-service",
+				Namespace: "default",
+				UID:       "6c9a5d2e-0f1b-4a3c-8d7e-2b4f6a8c0e1d",
+			},
+			Spec: corev1.ServiceSpec{
+				Type: corev1.ServiceTypeLoadBalancer,
+				Ports: []corev1.ServicePort{
+					{Name: "http", Protocol: corev1.ProtocolTCP, Port: 80, NodePort: 30080},
+				},
+			},
+		}
+		stop := make(chan struct{})
+		DeferCleanup(func() { close(stop) })
+		// The service exists in the cluster, because the CCM persists its reconcile backoff in the service annotations.
+		cloud.Initialize(fakeClientBuilder{fake.NewClientset(service)}, stop)
+
+		nodes = []*corev1.Node{newNode("node-a", "10.1.0.1"), newNode("node-b", "10.1.0.2")}
+		lbName = lbProvider.GetLoadBalancerName(context.Background(), clusterName, service)
+	})
+
+	It("should create, update and delete a load balancer", func(ctx SpecContext) {
+		status, err := lbProvider.EnsureLoadBalancer(ctx, clusterName, service, nodes)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Ingress).To(HaveLen(1))
+
+		lb, found := server.LoadBalancer(lbName)
+		Expect(found).To(BeTrue())
+		Expect(status.Ingress[0].IP).To(Equal(lb.GetExternalAddress()))
+		Expect(lb.Listeners).To(HaveLen(1))
+		Expect(lb.Listeners[0].GetPort()).To(BeEquivalentTo(80))
+		Expect(lb.TargetPools).To(HaveLen(1))
+		Expect(lb.TargetPools[0].GetTargetPort()).To(BeEquivalentTo(30080))
+		Expect(lb.TargetPools[0].Targets).To(HaveLen(2))
+
+		By("reconciling an unchanged service without updating the load balancer")
+		_, err = lbProvider.EnsureLoadBalancer(ctx, clusterName, service, nodes)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(server.Requests()).NotTo(ContainElement("PUT /load-balancers/" + lbName))
+
+		By("adding a port")
+		service.Spec.Ports = append(service.Spec.Ports, corev1.ServicePort{Name: "https", Protocol: corev1.ProtocolTCP, Port: 443, NodePort: 30443})
+		_, err = lbProvider.EnsureLoadBalancer(ctx, clusterName, service, nodes)
+		Expect(err).NotTo(HaveOccurred())
+		lb, _ = server.LoadBalancer(lbName)
+		Expect(lb.GetVersion()).To(Equal("2"))
+		Expect(lb.Listeners).To(HaveLen(2))
+		Expect(lb.TargetPools).To(HaveLen(2))
+
+		By("removing a node")
+		Expect(lbProvider.UpdateLoadBalancer(ctx, clusterName, service, nodes[:1])).To(Succeed())
+		lb, _ = server.LoadBalancer(lbName)
+		for _, pool := range lb.TargetPools {
+			Expect(pool.Targets).To(HaveLen(1))
+			Expect(pool.Targets[0].GetIp()).To(Equal("10.1.0.1"))
+		}
+
+		By("deleting the service")
+		Expect(lbProvider.EnsureLoadBalancerDeleted(ctx, clusterName, service)).To(Succeed())
+		_, found = server.LoadBalancer(lbName)
+		Expect(found).To(BeFalse())
+		_, exists, err := lbProvider.GetLoadBalancer(ctx, clusterName, service)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exists).To(BeFalse())
+	})
+
+	It("should reject changes to immutable fields", func(ctx SpecContext) {
+		_, err := lbProvider.EnsureLoadBalancer(ctx, clusterName, service, nodes)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(func(ctx SpecContext) {
+			Expect(lbProvider.EnsureLoadBalancerDeleted(ctx, clusterName, service)).To(Succeed())
+		})
+
+		service.Annotations = map[string]string{"lb.stackit.cloud/internal-lb": "true"}
+		_, err = lbProvider.EnsureLoadBalancer(ctx, clusterName, service, nodes)
+		Expect(err).To(MatchError(ContainSubstring("cannot be fulfilled")))
+		lb, _ := server.LoadBalancer(lbName)
+		Expect(lb.GetVersion()).To(Equal("1"))
+	})
+
+	It("should wait for the load balancer to become ready", func(ctx SpecContext) {
+		_, err := lbProvider.EnsureLoadBalancer(ctx, clusterName, service, nodes)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(func(ctx SpecContext) {
+			Expect(lbProvider.EnsureLoadBalancerDeleted(ctx, clusterName, service)).To(Succeed())
+		})
+
+		server.SetLoadBalancerStatus(lbName, loadbalancer.LOADBALANCERSTATUS_STATUS_PENDING)
+		_, err = lbProvider.EnsureLoadBalancer(ctx, clusterName, service, nodes)
+		Expect(err).To(MatchError(ContainSubstring("waiting for load balancer to become ready")))
+
+		server.SetLoadBalancerStatus(lbName, loadbalancer.LOADBALANCERSTATUS_STATUS_READY)
+		_, err = lbProvider.EnsureLoadBalancer(ctx, clusterName, service, nodes)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should not create a load balancer if the quota is exhausted", func(ctx SpecContext) {
+		server.SetLoadBalancerQuota(0)
+		DeferCleanup(server.SetLoadBalancerQuota, int32(10))
+		before := len(server.Requests())
+
+		_, err := lbProvider.EnsureLoadBalancer(ctx, clusterName, service, nodes)
+		Expect(err).To(MatchError(ContainSubstring("0/0 load balancers used")))
+		requests := server.Requests()[before:]
+		Expect(requests).To(ContainElement("GET /quota"))
+		Expect(requests).NotTo(ContainElement("POST /load-balancers"))
+	})
+
+	It("should retry after the delay suggested by the API during a maintenance", func(ctx SpecContext) {
+		server.SetMaintenance(2 * time.Minute)
+		DeferCleanup(server.SetMaintenance, time.Duration(0))
+
+		_, err := lbProvider.EnsureLoadBalancer(ctx, clusterName, service, nodes)
+		var retryErr *api.RetryError
+		Expect(err).To(BeAssignableToTypeOf(retryErr))
+		retryErr = err.(*api.RetryError)
+		Expect(retryErr.RetryAfter()).To(Equal(2 * time.Minute))
+		Expect(retryErr.Error()).To(ContainSubstring("cloud API in maintenance"))
+	})
+})
+
+func newNode(name, ip string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: ip}},
+		},
+	}
+}
+
+// fakeClientBuilder hands out the same fake clientset to all controllers.
+type fakeClientBuilder struct {
+	client kubernetes.Interface
+}
+
+func (b fakeClientBuilder) Config(string) (*restclient.Config, error) {
+	return &restclient.Config{}, nil
+}
+
+func (b fakeClientBuilder) ConfigOrDie(string) *restclient.Config {
+	return &restclient.Config{}
+}
+
+func (b fakeClientBuilder) Client(string) (kubernetes.Interface, error) {
+	return b.client, nil
+}
+
+func (b fakeClientBuilder) ClientOrDie(string) kubernetes.Interface {
+	return b.client
+}
@@ -0,0 +1,32 @@
+package fakeapi_test
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/stackitcloud/cloud-provider-stackit/test/fakeapi"
+)
+
+const (
+	projectID = "5a8b4a0c-6e07-4d8b-9c2d-0a5e3f7b9d11"
+	region    = "eu01"
+)
+
+var server *fakeapi.Server
+
+func TestFakeAPI(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Fake API E2E Suite")
+}
+
+var _ = BeforeSuite(func() {
+	server = fakeapi.NewServer(projectID, region)
+	DeferCleanup(server.Close)
+
+	// The SDK clients fall back to the token flow if no service account key is configured.
+	Expect(os.Setenv("STACKIT_SERVICE_ACCOUNT_TOKEN", fakeapi.Token)).To(Succeed())
+	DeferCleanup(os.Unsetenv, "STACKIT_SERVICE_ACCOUNT_TOKEN")
+})
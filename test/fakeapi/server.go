@@ -0,0 +1,694 @@
+// Package fakeapi implements the parts of the STACKIT load balancer and IaaS APIs that are used by the CCM and the
+// CSI driver. All state is kept in memory, so tests can drive the real clients against it and inspect the result.
+package fakeapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	iaas "github.com/stackitcloud/stackit-sdk-go/services/iaas/v2api"
+	loadbalancer "github.com/stackitcloud/stackit-sdk-go/services/loadbalancer/v2api"
+)
+
+const (
+	// Token is the bearer token the server expects, set it in STACKIT_SERVICE_ACCOUNT_TOKEN for the SDK clients.
+	Token = "fake-api-token"
+
+	volumeStatusAvailable = "AVAILABLE"
+	volumeStatusAttached  = "ATTACHED"
+
+	defaultMaxLoadBalancers = 10
+	defaultMaxCredentials   = 10
+)
+
+// Server is an in-memory fake of the STACKIT load balancer and IaaS APIs of a single project and region.
+type Server struct {
+	*httptest.Server
+
+	ProjectID string
+	Region    string
+
+	mu            sync.Mutex
+	loadBalancers map[string]*loadbalancer.LoadBalancer
+	credentials   map[string]*loadbalancer.CredentialsResponse
+	volumes       map[string]*iaas.Volume
+	snapshots     map[string]*iaas.Snapshot
+	servers       map[string]*iaas.Server
+	networks      map[string]*iaas.Network
+	requests      []string
+	nextAddress   int
+
+	maxLoadBalancers int32
+	// maintenance is the Retry-After delay while the API is in maintenance, zero otherwise
+	maintenance time.Duration
+}
+
+// NewServer starts a fake API server. Call Close when done.
+func NewServer(projectID, region string) *Server {
+	s := &Server{
+		ProjectID:     projectID,
+		Region:        region,
+		loadBalancers: map[string]*loadbalancer.LoadBalancer{},
+		credentials:   map[string]*loadbalancer.CredentialsResponse{},
+		volumes:       map[string]*iaas.Volume{},
+		snapshots:     map[string]*iaas.Snapshot{},
+		servers:       map[string]*iaas.Server{},
+		networks:      map[string]*iaas.Network{},
+
+		maxLoadBalancers: defaultMaxLoadBalancers,
+	}
+
+	mux := http.NewServeMux()
+	prefix := fmt.Sprintf("/v2/projects/%s/regions/%s", projectID, region)
+	handle := func(pattern string, handler func(w http.ResponseWriter, r *http.Request)) {
+		method, path, _ := strings.Cut(pattern, " ")
+		mux.HandleFunc(method+" "+prefix+path, handler)
+	}
+
+	handle("POST /load-balancers", s.createLoadBalancer)
+	handle("GET /load-balancers", s.listLoadBalancers)
+	handle("GET /load-balancers/{name}", s.getLoadBalancer)
+	handle("PUT /load-balancers/{name}", s.updateLoadBalancer)
+	handle("DELETE /load-balancers/{name}", s.deleteLoadBalancer)
+	handle("PUT /load-balancers/{name}/target-pools/{pool}", s.updateTargetPool)
+	handle("GET /quota", s.getQuota)
+	handle("POST /credentials", s.createCredentials)
+	handle("GET /credentials", s.listCredentials)
+	handle("PUT /credentials/{ref}", s.updateCredentials)
+	handle("DELETE /credentials/{ref}", s.deleteCredentials)
+
+	handle("POST /volumes", s.createVolume)
+	handle("GET /volumes", s.listVolumes)
+	handle("GET /volumes/{id}", s.getVolume)
+	handle("PATCH /volumes/{id}", s.updateVolume)
+	handle("DELETE /volumes/{id}", s.deleteVolume)
+	handle("POST /volumes/{id}/resize", s.resizeVolume)
+	handle("GET /servers/{id}", s.getServer)
+	handle("GET /networks/{id}", s.getNetwork)
+	handle("PUT /servers/{id}/volume-attachments/{volumeID}", s.attachVolume)
+	handle("DELETE /servers/{id}/volume-attachments/{volumeID}", s.detachVolume)
+	handle("POST /snapshots", s.createSnapshot)
+	handle("GET /snapshots", s.listSnapshots)
+	handle("GET /snapshots/{id}", s.getSnapshot)
+	handle("DELETE /snapshots/{id}", s.deleteSnapshot)
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+Token {
+			writeError(w, http.StatusUnauthorized, "invalid token")
+			return
+		}
+		s.mu.Lock()
+		s.requests = append(s.requests, r.Method+" "+strings.TrimPrefix(r.URL.Path, prefix))
+		maintenance := s.maintenance
+		s.mu.Unlock()
+		if maintenance > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(maintenance.Seconds())))
+			writeError(w, http.StatusServiceUnavailable, "API in maintenance")
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	return s
+}
+
+// Requests returns all requests served so far as "METHOD /path", relative to the project and region.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+// LoadBalancer returns a copy of the load balancer with the given name.
+func (s *Server) LoadBalancer(name string) (loadbalancer.LoadBalancer, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lb, found := s.loadBalancers[name]
+	if !found {
+		return loadbalancer.LoadBalancer{}, false
+	}
+	return clone(*lb), true
+}
+
+// Credentials returns copies of all observability credentials.
+func (s *Server) Credentials() []loadbalancer.CredentialsResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make([]loadbalancer.CredentialsResponse, 0, len(s.credentials))
+	for _, c := range s.credentials {
+		res = append(res, *c)
+	}
+	return res
+}
+
+// SetLoadBalancerStatus changes the status of a load balancer, e.g. to simulate a load balancer that is not ready yet.
+func (s *Server) SetLoadBalancerStatus(name string, status loadbalancer.LoadBalancerStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if lb, found := s.loadBalancers[name]; found {
+		lb.Status = new(status)
+	}
+}
+
+// SetLoadBalancerQuota changes the maximum number of load balancers in the project. Defaults to 10.
+func (s *Server) SetLoadBalancerQuota(maxLoadBalancers int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxLoadBalancers = maxLoadBalancers
+}
+
+// SetMaintenance lets all requests fail with 503 and the Retry-After header retryAfter, like during a maintenance
+// window of the API. Zero ends the maintenance.
+func (s *Server) SetMaintenance(retryAfter time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maintenance = retryAfter
+}
+
+// Volume returns a copy of the volume with the given ID.
+func (s *Server) Volume(id string) (iaas.Volume, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	vol, found := s.volumes[id]
+	if !found {
+		return iaas.Volume{}, false
+	}
+	return clone(*vol), true
+}
+
+// Volumes returns copies of all volumes.
+func (s *Server) Volumes() []iaas.Volume {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make([]iaas.Volume, 0, len(s.volumes))
+	for _, vol := range s.volumes {
+		res = append(res, clone(*vol))
+	}
+	return res
+}
+
+// RemoveVolume removes a volume regardless of its state, e.g. a volume whose deletion the CSI driver deferred.
+func (s *Server) RemoveVolume(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.volumes, id)
+}
+
+// AddServer registers a server that volumes can be attached to and returns its ID.
+func (s *Server) AddServer(name, availabilityZone string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := uuid.NewString()
+	s.servers[id] = &iaas.Server{Id: new(id), Name: name, AvailabilityZone: new(availabilityZone), Status: new("ACTIVE")}
+	return id
+}
+
+// AddNetwork registers a network that load balancers can be created in and returns its ID.
+func (s *Server) AddNetwork(name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := uuid.NewString()
+	s.networks[id] = &iaas.Network{Id: id, Name: name, Status: "CREATED"}
+	return id
+}
+
+func (s *Server) createLoadBalancer(w http.ResponseWriter, r *http.Request) {
+	var lb loadbalancer.LoadBalancer
+	if !decode(w, r, &lb) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name := lb.GetName()
+	if _, found := s.loadBalancers[name]; found {
+		writeError(w, http.StatusConflict, "load balancer already exists")
+		return
+	}
+	if len(s.loadBalancers) >= int(s.maxLoadBalancers) {
+		writeError(w, http.StatusForbidden, "quota exceeded for load balancers")
+		return
+	}
+	lb.Region = new(s.Region)
+	lb.Status = new(loadbalancer.LOADBALANCERSTATUS_STATUS_READY)
+	lb.Version = new("1")
+	s.nextAddress++
+	if lb.Options != nil && lb.Options.GetPrivateNetworkOnly() {
+		lb.PrivateAddress = new(fmt.Sprintf("10.0.0.%d", s.nextAddress))
+	} else if lb.ExternalAddress == nil {
+		lb.ExternalAddress = new(fmt.Sprintf("192.0.2.%d", s.nextAddress))
+		if lb.Options == nil {
+			lb.Options = &loadbalancer.LoadBalancerOptions{}
+		}
+		lb.Options.EphemeralAddress = new(true)
+	}
+	s.loadBalancers[name] = &lb
+	writeJSON(w, http.StatusOK, lb)
+}
+
+func (s *Server) listLoadBalancers(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := loadbalancer.ListLoadBalancersResponse{LoadBalancers: []loadbalancer.LoadBalancer{}}
+	for _, lb := range s.loadBalancers {
+		res.LoadBalancers = append(res.LoadBalancers, *lb)
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) getLoadBalancer(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lb, found := s.loadBalancers[r.PathValue("name")]
+	if !found {
+		writeError(w, http.StatusNotFound, "load balancer not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, lb)
+}
+
+func (s *Server) updateLoadBalancer(w http.ResponseWriter, r *http.Request) {
+	var update loadbalancer.LoadBalancer
+	if !decode(w, r, &update) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lb, found := s.loadBalancers[r.PathValue("name")]
+	if !found {
+		writeError(w, http.StatusNotFound, "load balancer not found")
+		return
+	}
+	if update.Version != nil && update.GetVersion() != lb.GetVersion() {
+		writeError(w, http.StatusConflict, "version conflict")
+		return
+	}
+	version, _ := strconv.Atoi(lb.GetVersion())
+	update.Name = lb.Name
+	update.Region = lb.Region
+	update.Status = lb.Status
+	update.Version = new(strconv.Itoa(version + 1))
+	update.PrivateAddress = lb.PrivateAddress
+	if update.ExternalAddress == nil {
+		update.ExternalAddress = lb.ExternalAddress
+	}
+	s.loadBalancers[lb.GetName()] = &update
+	writeJSON(w, http.StatusOK, update)
+}
+
+func (s *Server) deleteLoadBalancer(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Deleting a load balancer that doesn't exist succeeds, like in the real API.
+	delete(s.loadBalancers, r.PathValue("name"))
+	writeJSON(w, http.StatusOK, map[string]any{})
+}
+
+func (s *Server) updateTargetPool(w http.ResponseWriter, r *http.Request) {
+	var pool loadbalancer.TargetPool
+	if !decode(w, r, &pool) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lb, found := s.loadBalancers[r.PathValue("name")]
+	if !found {
+		writeError(w, http.StatusNotFound, "load balancer not found")
+		return
+	}
+	i := slices.IndexFunc(lb.TargetPools, func(p loadbalancer.TargetPool) bool { return p.GetName() == r.PathValue("pool") })
+	if i < 0 {
+		writeError(w, http.StatusNotFound, "target pool not found")
+		return
+	}
+	lb.TargetPools[i] = pool
+	writeJSON(w, http.StatusOK, pool)
+}
+
+func (s *Server) getQuota(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, loadbalancer.GetQuotaResponse{
+		ProjectId:         new(s.ProjectID),
+		Region:            new(s.Region),
+		MaxLoadBalancers:  new(s.maxLoadBalancers),
+		UsedLoadBalancers: new(int32(len(s.loadBalancers))),
+		MaxCredentials:    new(int32(defaultMaxCredentials)),
+		UsedCredentials:   new(int32(len(s.credentials))),
+	})
+}
+
+func (s *Server) createCredentials(w http.ResponseWriter, r *http.Request) {
+	var payload loadbalancer.CreateCredentialsPayload
+	if !decode(w, r, &payload) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ref := "credentials-" + uuid.NewString()[:8]
+	c := &loadbalancer.CredentialsResponse{
+		CredentialsRef: new(ref),
+		DisplayName:    payload.DisplayName,
+		Username:       payload.Username,
+		Region:         new(s.Region),
+	}
+	s.credentials[ref] = c
+	writeJSON(w, http.StatusOK, loadbalancer.CreateCredentialsResponse{Credential: c})
+}
+
+func (s *Server) listCredentials(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := loadbalancer.ListCredentialsResponse{Credentials: []loadbalancer.CredentialsResponse{}}
+	for _, c := range s.credentials {
+		res.Credentials = append(res.Credentials, *c)
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) updateCredentials(w http.ResponseWriter, r *http.Request) {
+	var payload loadbalancer.UpdateCredentialsPayload
+	if !decode(w, r, &payload) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, found := s.credentials[r.PathValue("ref")]
+	if !found {
+		writeError(w, http.StatusNotFound, "credentials not found")
+		return
+	}
+	c.DisplayName = payload.DisplayName
+	c.Username = payload.Username
+	writeJSON(w, http.StatusOK, loadbalancer.UpdateCredentialsResponse{Credential: c})
+}
+
+func (s *Server) deleteCredentials(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ref := r.PathValue("ref")
+	for _, lb := range s.loadBalancers {
+		if lb.Options != nil && lb.Options.Observability != nil && lb.Options.Observability.Metrics != nil &&
+			lb.Options.Observability.Metrics.GetCredentialsRef() == ref {
+			writeError(w, http.StatusBadRequest, "credentials are still referenced by load balancer "+lb.GetName())
+			return
+		}
+	}
+	if _, found := s.credentials[ref]; !found {
+		writeError(w, http.StatusNotFound, "credentials not found")
+		return
+	}
+	delete(s.credentials, ref)
+	writeJSON(w, http.StatusOK, map[string]any{})
+}
+
+func (s *Server) createVolume(w http.ResponseWriter, r *http.Request) {
+	var payload iaas.CreateVolumePayload
+	if !decode(w, r, &payload) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := uuid.NewString()
+	vol := &iaas.Volume{
+		Id:               new(id),
+		Name:             payload.Name,
+		Description:      payload.Description,
+		AvailabilityZone: payload.AvailabilityZone,
+		Size:             payload.Size,
+		PerformanceClass: payload.PerformanceClass,
+		Labels:           payload.Labels,
+		Source:           payload.Source,
+		Encrypted:        new(payload.EncryptionParameters != nil),
+		Status:           new(volumeStatusAvailable),
+	}
+	if vol.Source != nil {
+		switch vol.Source.Type {
+		case "snapshot":
+			snap, found := s.snapshots[vol.Source.Id]
+			if !found {
+				writeError(w, http.StatusNotFound, "source snapshot not found")
+				return
+			}
+			vol.Size = new(max(vol.GetSize(), snap.GetSize()))
+		case "volume":
+			src, found := s.volumes[vol.Source.Id]
+			if !found {
+				writeError(w, http.StatusNotFound, "source volume not found")
+				return
+			}
+			vol.Size = new(max(vol.GetSize(), src.GetSize()))
+		}
+	}
+	s.volumes[id] = vol
+	writeJSON(w, http.StatusCreated, vol)
+}
+
+func (s *Server) listVolumes(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := iaas.VolumeListResponse{Items: []iaas.Volume{}}
+	for _, vol := range s.volumes {
+		res.Items = append(res.Items, *vol)
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) getVolume(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	vol, found := s.volumes[r.PathValue("id")]
+	if !found {
+		writeError(w, http.StatusNotFound, "volume not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, vol)
+}
+
+// updateVolume merges the labels of the payload into the labels of the volume like the API, a null value removes a
+// label.
+func (s *Server) updateVolume(w http.ResponseWriter, r *http.Request) {
+	var payload iaas.UpdateVolumePayload
+	if !decode(w, r, &payload) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	vol, found := s.volumes[r.PathValue("id")]
+	if !found {
+		writeError(w, http.StatusNotFound, "volume not found")
+		return
+	}
+	if payload.Name != nil {
+		vol.Name = payload.Name
+	}
+	if payload.Description != nil {
+		vol.Description = payload.Description
+	}
+	for key, value := range payload.Labels {
+		if vol.Labels == nil {
+			vol.Labels = map[string]any{}
+		}
+		if value == nil {
+			delete(vol.Labels, key)
+		} else {
+			vol.Labels[key] = value
+		}
+	}
+	writeJSON(w, http.StatusOK, vol)
+}
+
+func (s *Server) deleteVolume(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	vol, found := s.volumes[r.PathValue("id")]
+	if !found {
+		writeError(w, http.StatusNotFound, "volume not found")
+		return
+	}
+	if vol.GetServerId() != "" {
+		writeError(w, http.StatusConflict, "volume is attached")
+		return
+	}
+	delete(s.volumes, r.PathValue("id"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) resizeVolume(w http.ResponseWriter, r *http.Request) {
+	var payload iaas.ResizeVolumePayload
+	if !decode(w, r, &payload) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	vol, found := s.volumes[r.PathValue("id")]
+	if !found {
+		writeError(w, http.StatusNotFound, "volume not found")
+		return
+	}
+	if payload.Size < vol.GetSize() {
+		writeError(w, http.StatusBadRequest, "volumes can't be shrunk")
+		return
+	}
+	vol.Size = new(payload.Size)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) getServer(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	server, found := s.servers[r.PathValue("id")]
+	if !found {
+		writeError(w, http.StatusNotFound, "server not found")
+		return
+	}
+	res := *server
+	res.Volumes = nil
+	for id, vol := range s.volumes {
+		if vol.GetServerId() == server.GetId() {
+			res.Volumes = append(res.Volumes, id)
+		}
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) getNetwork(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	network, found := s.networks[r.PathValue("id")]
+	if !found {
+		writeError(w, http.StatusNotFound, "network not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, network)
+}
+
+func (s *Server) attachVolume(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	serverID, volumeID := r.PathValue("id"), r.PathValue("volumeID")
+	server, found := s.servers[serverID]
+	if !found {
+		writeError(w, http.StatusNotFound, "server not found")
+		return
+	}
+	vol, found := s.volumes[volumeID]
+	if !found {
+		writeError(w, http.StatusNotFound, "volume not found")
+		return
+	}
+	if vol.GetServerId() != "" && vol.GetServerId() != serverID {
+		writeError(w, http.StatusConflict, "volume is attached to a different server")
+		return
+	}
+	if vol.AvailabilityZone != server.GetAvailabilityZone() {
+		writeError(w, http.StatusBadRequest, "volume and server are in different availability zones")
+		return
+	}
+	vol.ServerId = new(serverID)
+	vol.Status = new(volumeStatusAttached)
+	writeJSON(w, http.StatusOK, iaas.VolumeAttachment{ServerId: new(serverID), VolumeId: new(volumeID)})
+}
+
+func (s *Server) detachVolume(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	vol, found := s.volumes[r.PathValue("volumeID")]
+	if !found || vol.GetServerId() != r.PathValue("id") {
+		writeError(w, http.StatusNotFound, "volume attachment not found")
+		return
+	}
+	vol.ServerId = nil
+	vol.Status = new(volumeStatusAvailable)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) createSnapshot(w http.ResponseWriter, r *http.Request) {
+	var payload iaas.CreateSnapshotPayload
+	if !decode(w, r, &payload) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	vol, found := s.volumes[payload.VolumeId]
+	if !found {
+		writeError(w, http.StatusNotFound, "volume not found")
+		return
+	}
+	id := uuid.NewString()
+	snap := &iaas.Snapshot{
+		Id:               new(id),
+		Name:             payload.Name,
+		Labels:           payload.Labels,
+		VolumeId:         payload.VolumeId,
+		Size:             vol.Size,
+		AvailabilityZone: new(vol.AvailabilityZone),
+		Status:           new(volumeStatusAvailable),
+	}
+	s.snapshots[id] = snap
+	writeJSON(w, http.StatusCreated, snap)
+}
+
+func (s *Server) listSnapshots(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := iaas.SnapshotListResponse{Items: []iaas.Snapshot{}}
+	for _, snap := range s.snapshots {
+		res.Items = append(res.Items, *snap)
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) getSnapshot(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snap, found := s.snapshots[r.PathValue("id")]
+	if !found {
+		writeError(w, http.StatusNotFound, "snapshot not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, snap)
+}
+
+func (s *Server) deleteSnapshot(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, found := s.snapshots[r.PathValue("id")]; !found {
+		writeError(w, http.StatusNotFound, "snapshot not found")
+		return
+	}
+	delete(s.snapshots, r.PathValue("id"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	writeJSON(w, code, map[string]any{"code": code, "message": message})
+}
+
+// clone returns a deep copy, so callers can't modify the state of the server.
+func clone[T any](v T) T {
+	var c T
+	data, _ := json.Marshal(v)
+	_ = json.Unmarshal(data, &c)
+	return c
+}